@@ -0,0 +1,96 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"sort"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/value"
+)
+
+// newAggrSeriesSet merges the partial aggregates of the shards into the final ones, the series
+// with the same labels are combined by op at every timestamp. A timestamp stays stale only if
+// it's stale in all the partial aggregates having it.
+func newAggrSeriesSet(sets []SeriesSet, op backendpb.AggrOp) SeriesSet {
+	if op != backendpb.AggrOp_AggrSum {
+		closeSeriesSets(sets...)
+		return errSeriesSet{err: errors.Errorf("unsupported aggregation %s", op)}
+	}
+
+	type aggregate struct {
+		labels labels.Labels
+		values map[int64]float64
+	}
+
+	var (
+		aggregates = make(map[string]*aggregate)
+		keys       []string
+	)
+
+	for _, set := range sets {
+		for set.Next() {
+			s := set.At()
+			lbls := s.Labels()
+
+			key := lbls.String()
+			aggr, found := aggregates[key]
+			if !found {
+				aggr = &aggregate{labels: lbls, values: make(map[int64]float64)}
+				aggregates[key] = aggr
+				keys = append(keys, key)
+			}
+
+			it := s.Iterator()
+			for it.Next() {
+				t, v := it.At()
+				if sum, found := aggr.values[t]; !found || value.IsStaleNaN(sum) {
+					aggr.values[t] = v
+				} else if !value.IsStaleNaN(v) {
+					aggr.values[t] = sum + v
+				}
+			}
+			if err := it.Err(); err != nil {
+				return errSeriesSet{err: err}
+			}
+		}
+		if err := set.Err(); err != nil {
+			return errSeriesSet{err: err}
+		}
+	}
+
+	sort.Strings(keys)
+
+	series := make([]Series, 0, len(keys))
+	for _, key := range keys {
+		aggr := aggregates[key]
+
+		samples := make([]pb.Point, 0, len(aggr.values))
+		for t, v := range aggr.values {
+			samples = append(samples, pb.Point{T: t, V: v})
+		}
+		sort.Slice(samples, func(i, j int) bool {
+			return samples[i].T < samples[j].T
+		})
+
+		series = append(series, &concreteSeries{labels: aggr.labels, samples: samples})
+	}
+
+	return &concreteSeriesSet{series: series}
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrShardUnavailable is returned at once for the queries to a shard whose circuit breaker is open.
+var ErrShardUnavailable = errors.New("shard unavailable, too many consecutive failures")
+
+const defaultBreakerCooldown = 10 * time.Second
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker stops the queries to a shard after threshold consecutive failures (open), until
+// cooldown passes. Then a single probe query is let through (half-open), the breaker is closed
+// again if it succeeds, otherwise it's open for another cooldown.
+type circuitBreaker struct {
+	mtx       sync.Mutex
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	threshold int
+	cooldown  time.Duration
+	metrics   *breakerMetrics
+}
+
+func newCircuitBreaker(shardID string, threshold int, cooldown time.Duration) *circuitBreaker {
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		metrics:   newBreakerMetrics(shardID),
+	}
+}
+
+// allow reports whether a query may be sent to the shard, the caller must report its result
+// by done if it's allowed.
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			break
+		}
+		b.setState(breakerHalfOpen)
+		return true
+	case breakerHalfOpen: // the probe is in flight
+	default:
+		return true
+	}
+
+	b.metrics.rejected.Inc()
+	return false
+}
+
+// done records the result of a query allowed.
+func (b *circuitBreaker) done(failed bool, now time.Time) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if !failed {
+		b.failures = 0
+		b.setState(breakerClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = now
+		b.setState(breakerOpen)
+	}
+}
+
+func (b *circuitBreaker) setState(state breakerState) {
+	b.state = state
+	b.metrics.state.Set(float64(state))
+}
+
+// breakerFailure reports whether err counts as a failure of the shard, a query cancelled by the
+// caller or refused as a bad request says nothing about the health of the shard.
+func breakerFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() == context.Canceled {
+		return false
+	}
+	return pb.Retryable(err)
+}
+
+var breakers sync.Map // shard id -> *circuitBreaker
+
+// shardBreaker returns the circuit breaker of the shard, nil if it's disabled.
+func shardBreaker(shardID string) *circuitBreaker {
+	cfg := queryConfig()
+	if cfg.BreakerThreshold <= 0 {
+		return nil
+	}
+
+	if b, found := breakers.Load(shardID); found {
+		return b.(*circuitBreaker)
+	}
+	b, _ := breakers.LoadOrStore(shardID, newCircuitBreaker(shardID, cfg.BreakerThreshold, time.Duration(cfg.BreakerCooldown)))
+	return b.(*circuitBreaker)
+}
+
+var (
+	breakerStates   *prometheus.GaugeVec
+	breakerRejected *prometheus.CounterVec
+)
+
+type breakerMetrics struct {
+	state    prometheus.Gauge
+	rejected prometheus.Counter
+}
+
+func init() {
+	breakerStates = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "baudtime",
+		Subsystem: "shard_breaker",
+		Name:      "state",
+		Help:      "State of the circuit breaker of the queries to a shard, 0 closed, 1 open and 2 half-open.",
+	}, []string{"shard"})
+	breakerRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "baudtime",
+		Subsystem: "shard_breaker",
+		Name:      "rejected_total",
+		Help:      "Total number of queries to a shard failed fast by its open circuit breaker.",
+	}, []string{"shard"})
+
+	prometheus.MustRegister(breakerStates, breakerRejected)
+}
+
+func newBreakerMetrics(shardID string) *breakerMetrics {
+	return &breakerMetrics{
+		state:    breakerStates.WithLabelValues(shardID),
+		rejected: breakerRejected.WithLabelValues(shardID),
+	}
+}
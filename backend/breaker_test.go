@@ -0,0 +1,127 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/util/toml"
+	"github.com/baudtime/baudtime/vars"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker("breaker_test", 3, time.Minute)
+	now := time.Unix(1000, 0)
+
+	wantState := func(state breakerState) {
+		t.Helper()
+		if b.state != state {
+			t.Fatalf("want %v, got %v", state, b.state)
+		}
+	}
+
+	// a success resets the consecutive failures
+	for _, failed := range []bool{true, true, false, true, true} {
+		if !b.allow(now) {
+			t.Fatalf("want queries allowed while closed")
+		}
+		b.done(failed, now)
+	}
+	wantState(breakerClosed)
+
+	b.allow(now)
+	b.done(true, now)
+	wantState(breakerOpen)
+
+	if b.allow(now.Add(59 * time.Second)) {
+		t.Fatalf("want queries rejected in the cooldown")
+	}
+
+	// a single probe is let through after the cooldown
+	now = now.Add(time.Minute)
+	if !b.allow(now) {
+		t.Fatalf("want a probe allowed after the cooldown")
+	}
+	wantState(breakerHalfOpen)
+	if b.allow(now) {
+		t.Fatalf("want queries rejected while the probe is in flight")
+	}
+
+	b.done(true, now)
+	wantState(breakerOpen)
+	if b.allow(now.Add(time.Second)) {
+		t.Fatalf("want another cooldown after a failed probe")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow(now) {
+		t.Fatalf("want a probe allowed after the cooldown")
+	}
+	b.done(false, now)
+	wantState(breakerClosed)
+	if !b.allow(now) || b.failures != 0 {
+		t.Fatalf("want the breaker reset by a succeeded probe")
+	}
+
+	m := new(dto.Metric)
+	b.metrics.rejected.Write(m)
+	if rejected := m.Counter.GetValue(); rejected != 3 {
+		t.Fatalf("want 3 queries rejected, got %v", rejected)
+	}
+}
+
+func TestShardBreaker(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	vars.Cfg.Gateway = &vars.GatewayConfig{}
+	if shardBreaker("s1") != nil {
+		t.Fatalf("want no breaker without a threshold")
+	}
+
+	vars.Cfg.Gateway.Query = vars.QueryConfig{BreakerThreshold: 2, BreakerCooldown: toml.Duration(time.Second)}
+	b := shardBreaker("s1")
+	if b == nil || b.threshold != 2 || b.cooldown != time.Second || shardBreaker("s1") != b {
+		t.Fatalf("want a breaker per shard as configured, got %+v", b)
+	}
+	breakers.Delete("s1")
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{ctx: context.Background(), err: nil, want: false},
+		{ctx: context.Background(), err: errors.New("connection refused"), want: true},
+		{ctx: context.Background(), err: &pb.ResponseError{Code: pb.ErrorCode_BadRequest, Message: "bad matchers"}, want: false},
+		{ctx: canceled, err: context.Canceled, want: false},
+	}
+	for i, test := range tests {
+		if got := breakerFailure(test.ctx, test.err); got != test.want {
+			t.Fatalf("case %d: want %v, got %v", i, test.want, got)
+		}
+	}
+}
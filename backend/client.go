@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"github.com/baudtime/baudtime/msg"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/baudtime/baudtime/backend/storage"
 	"github.com/baudtime/baudtime/meta"
@@ -28,6 +30,7 @@ import (
 	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
 	"github.com/baudtime/baudtime/tcp"
 	"github.com/baudtime/baudtime/tcp/client"
+	"github.com/baudtime/baudtime/util"
 	"github.com/baudtime/baudtime/vars"
 	"github.com/hashicorp/go-multierror"
 	"github.com/opentracing/opentracing-go"
@@ -37,11 +40,36 @@ import (
 type Client interface {
 	Select(ctx context.Context, req *backendpb.SelectRequest) (*backendpb.SelectResponse, error)
 	LabelValues(ctx context.Context, req *backendpb.LabelValuesRequest) (*pb.LabelValuesResponse, error)
+	LabelNames(ctx context.Context, req *backendpb.LabelNamesRequest) (*backendpb.LabelNamesResponse, error)
 	Add(ctx context.Context, req *backendpb.AddRequest) error
 	Close() error
 	Name() string
 }
 
+// ShardError is the error met querying a node of a shard, Addr is empty if no node was available.
+type ShardError struct {
+	ShardID string
+	Addr    string
+	Err     error
+}
+
+func (e *ShardError) Error() string {
+	if e.Addr == "" {
+		return fmt.Sprintf("shard=%s: %v", e.ShardID, e.Err)
+	}
+	return fmt.Sprintf("shard=%s addr=%s: %v", e.ShardID, e.Addr, e.Err)
+}
+
+// Unwrap returns the underlying error, e.g. a net.Error to check whether to reconnect.
+func (e *ShardError) Unwrap() error {
+	return e.Err
+}
+
+// Cause makes errors.Cause return the underlying error.
+func (e *ShardError) Cause() error {
+	return e.Err
+}
+
 type clientFactory struct {
 	clients *sync.Map
 }
@@ -49,7 +77,7 @@ type clientFactory struct {
 func (factory *clientFactory) getClient(address string) (*client.Client, error) {
 	cli, found := factory.clients.Load(address)
 	if !found {
-		newCli := client.NewBackendClient("backend_cli_+"+address, address, vars.Cfg.Gateway.ConnNumPerBackend)
+		newCli := client.NewBackendClient("backend_cli_+"+address, address, vars.Cfg.Gateway.ConnNumPerBackend, vars.Cfg.Gateway.MaxIdleConnPerBackend)
 		if cli, found = factory.clients.LoadOrStore(address, newCli); found {
 			_ = newCli.Close()
 		}
@@ -71,12 +99,124 @@ var defaultFactory = &clientFactory{
 	clients: new(sync.Map),
 }
 
+func init() {
+	meta.OnMasterChanged(evictOldMaster)
+}
+
+// evictOldMaster closes the pooled connections to the old master of a shard, the requests
+// to the shard shouldn't go on being sent over them while it's being demoted or fenced.
+func evictOldMaster(shardID string, oldMaster *meta.Node) {
+	defaultFactory.destroy(oldMaster.Addr())
+}
+
 type ShardClient struct {
 	shardID      string
 	localStorage *storage.Storage
 }
 
-func (c *ShardClient) exeQuery(query func(node *meta.Node) (resp msg.Message, err error)) (resp msg.Message, err error) {
+type slaveReadKey struct{}
+
+// WithSlaveRead returns a context making the queries with it read from the slaves of the shards,
+// the master of a shard is read only if none of its fresh slaves responds.
+func WithSlaveRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, slaveReadKey{}, true)
+}
+
+func slaveReadFromContext(ctx context.Context) bool {
+	slaveRead, _ := ctx.Value(slaveReadKey{}).(bool)
+	return slaveRead
+}
+
+// slaveReadRR rotates the slaves read from.
+var slaveReadRR uint32
+
+// freshSlaves returns the slaves lagging behind the master no more than maxLag (0 means unlimited),
+// rotated in round robin. Without a master, the lag is relative to the freshest slave.
+func freshSlaves(master *meta.Node, slaves []*meta.Node, maxLag time.Duration) []*meta.Node {
+	if len(slaves) == 0 {
+		return nil
+	}
+
+	var latest int64
+	if master != nil {
+		latest = master.MaxT
+	} else {
+		for _, slave := range slaves {
+			latest = util.Max(latest, slave.MaxT)
+		}
+	}
+
+	fresh := make([]*meta.Node, 0, len(slaves))
+	for _, slave := range slaves {
+		if maxLag <= 0 || latest-slave.MaxT <= int64(maxLag/time.Millisecond) {
+			fresh = append(fresh, slave)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	start := int(atomic.AddUint32(&slaveReadRR, 1) % uint32(len(fresh)))
+	return append(fresh[start:], fresh[:start]...)
+}
+
+// readFromSlaves runs query on the fresh slaves one by one until one succeeds, the slaves erroring
+// are skipped and the master is the last resort.
+func readFromSlaves(master *meta.Node, slaves []*meta.Node, maxLag time.Duration, query func(node *meta.Node) (msg.Message, error)) (resp msg.Message, err error) {
+	var multiErr error
+
+	for _, slave := range freshSlaves(master, slaves, maxLag) {
+		if resp, err = query(slave); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		} else {
+			return
+		}
+	}
+
+	if master != nil {
+		if resp, err = query(master); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		} else {
+			return
+		}
+	}
+
+	if multiErr == nil {
+		multiErr = errors.New("no available data node")
+	}
+	return nil, multiErr
+}
+
+// exeQuery runs query on the nodes of the shard, it fails fast if the circuit breaker of the shard is open.
+func (c *ShardClient) exeQuery(ctx context.Context, query func(node *meta.Node) (resp msg.Message, err error)) (msg.Message, error) {
+	breaker := shardBreaker(c.shardID)
+	if breaker == nil {
+		return c.exeQueryOnNodes(ctx, query)
+	}
+
+	if !breaker.allow(time.Now()) {
+		return nil, &ShardError{ShardID: c.shardID, Err: ErrShardUnavailable}
+	}
+	resp, err := c.exeQueryOnNodes(ctx, query)
+	breaker.done(breakerFailure(ctx, err), time.Now())
+	return resp, err
+}
+
+func (c *ShardClient) exeQueryOnNodes(ctx context.Context, query func(node *meta.Node) (resp msg.Message, err error)) (resp msg.Message, err error) {
+	query = c.identifyErrors(query)
+
+	if slaveReadFromContext(ctx) {
+		master := meta.GetMaster(c.shardID)
+		if resp, err = readFromSlaves(master, meta.GetSlaves(c.shardID), time.Duration(queryConfig().SlaveMaxLag), query); err != nil {
+			meta.FailoverIfNeeded(master)
+			if _, ok := err.(*multierror.Error); !ok {
+				err = &ShardError{ShardID: c.shardID, Err: err}
+			}
+			return nil, err
+		}
+		return
+	}
+
 	var multiErr error
 
 	master := meta.GetMaster(c.shardID)
@@ -105,7 +245,18 @@ func (c *ShardClient) exeQuery(query func(node *meta.Node) (resp msg.Message, er
 	if multiErr != nil {
 		return nil, multiErr
 	} else {
-		return nil, errors.Errorf("shard %v has no available data node", c.shardID)
+		return nil, &ShardError{ShardID: c.shardID, Err: errors.New("no available data node")}
+	}
+}
+
+// identifyErrors wraps the errors of query in ShardError, telling the shard and node erroring.
+func (c *ShardClient) identifyErrors(query func(node *meta.Node) (msg.Message, error)) func(node *meta.Node) (msg.Message, error) {
+	return func(node *meta.Node) (msg.Message, error) {
+		resp, err := query(node)
+		if err != nil {
+			return nil, &ShardError{ShardID: c.shardID, Addr: node.Addr(), Err: err}
+		}
+		return resp, nil
 	}
 }
 
@@ -127,10 +278,13 @@ func (c *ShardClient) Select(ctx context.Context, req *backendpb.SelectRequest)
 		req.SpanCtx = carrier.Bytes()
 	}
 
-	resp, err := c.exeQuery(func(node *meta.Node) (msg.Message, error) {
+	resp, err := c.exeQuery(ctx, func(node *meta.Node) (msg.Message, error) {
 		if c.localStorage != nil && node.IP == vars.LocalIP && node.Port == vars.Cfg.TcpPort {
-			if resp := c.localStorage.HandleSelectReq(req); resp.Status != pb.StatusCode_Succeed {
-				return nil, errors.Errorf("select error on %s, err:%s", node.Addr(), resp.ErrorMsg)
+			localReq := *req
+			localReq.CompactPoints = false // nothing to save without a connection in between
+
+			if resp := c.localStorage.HandleSelectReq(&localReq); resp.Status != pb.StatusCode_Succeed {
+				return nil, errors.Errorf("select error, err:%s", resp.ErrorMsg)
 			} else {
 				return resp, nil
 			}
@@ -145,7 +299,14 @@ func (c *ShardClient) Select(ctx context.Context, req *backendpb.SelectRequest)
 				return nil, err
 			}
 
-			if _, ok := resp.(*backendpb.SelectResponse); !ok {
+			switch r := resp.(type) {
+			case *backendpb.SelectResponse:
+			case *pb.GeneralResponse:
+				if err := r.Err(); err != nil {
+					return nil, err
+				}
+				return nil, tcp.BadMsgTypeError
+			default:
 				return nil, tcp.BadMsgTypeError
 			}
 			return resp, nil
@@ -174,10 +335,10 @@ func (c *ShardClient) LabelValues(ctx context.Context, req *backendpb.LabelValue
 		req.SpanCtx = carrier.Bytes()
 	}
 
-	resp, err := c.exeQuery(func(node *meta.Node) (msg.Message, error) {
+	resp, err := c.exeQuery(ctx, func(node *meta.Node) (msg.Message, error) {
 		if c.localStorage != nil && node.IP == vars.LocalIP && node.Port == vars.Cfg.TcpPort {
 			if resp := c.localStorage.HandleLabelValuesReq(req); resp.Status != pb.StatusCode_Succeed {
-				return nil, errors.Errorf("select error on %s, err:%s", node.Addr(), resp.ErrorMsg)
+				return nil, errors.Errorf("select error, err:%s", resp.ErrorMsg)
 			} else {
 				return resp, nil
 			}
@@ -192,7 +353,14 @@ func (c *ShardClient) LabelValues(ctx context.Context, req *backendpb.LabelValue
 				return nil, err
 			}
 
-			if _, ok := resp.(*pb.LabelValuesResponse); !ok {
+			switch r := resp.(type) {
+			case *pb.LabelValuesResponse:
+			case *pb.GeneralResponse:
+				if err := r.Err(); err != nil {
+					return nil, err
+				}
+				return nil, tcp.BadMsgTypeError
+			default:
 				return nil, tcp.BadMsgTypeError
 			}
 			return resp, nil
@@ -204,6 +372,60 @@ func (c *ShardClient) LabelValues(ctx context.Context, req *backendpb.LabelValue
 	return resp.(*pb.LabelValuesResponse), nil
 }
 
+func (c *ShardClient) LabelNames(ctx context.Context, req *backendpb.LabelNamesRequest) (*backendpb.LabelNamesResponse, error) {
+	if req == nil {
+		return nil, nil
+	}
+
+	if parentSpan, ok := ctx.Value("span").(opentracing.Span); ok {
+		syncRequest := opentracing.StartSpan("syncRequest", opentracing.ChildOf(parentSpan.Context()))
+		syncRequest.SetTag("shard", c.shardID)
+		defer syncRequest.Finish()
+
+		carrier := new(bytes.Buffer)
+		syncRequest.Tracer().Inject(syncRequest.Context(), opentracing.Binary, carrier)
+		req.SpanCtx = carrier.Bytes()
+	}
+
+	resp, err := c.exeQuery(ctx, func(node *meta.Node) (msg.Message, error) {
+		if c.localStorage != nil && node.IP == vars.LocalIP && node.Port == vars.Cfg.TcpPort {
+			if resp := c.localStorage.HandleLabelNamesReq(req); resp.Status != pb.StatusCode_Succeed {
+				return nil, errors.Errorf("label names error, err:%s", resp.ErrorMsg)
+			} else {
+				return resp, nil
+			}
+		} else {
+			cli, err := defaultFactory.getClient(node.Addr())
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := cli.SyncRequest(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+
+			switch r := resp.(type) {
+			case *backendpb.LabelNamesResponse:
+			case *pb.GeneralResponse:
+				if err := r.Err(); err != nil {
+					return nil, err
+				}
+				return nil, tcp.BadMsgTypeError
+			default:
+				return nil, tcp.BadMsgTypeError
+			}
+			return resp, nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*backendpb.LabelNamesResponse), nil
+}
+
+// Add sends the batch to the master of the shard, and waits for its ack until ctx is done unless
+// the ack level of req is AckAsync.
 func (c *ShardClient) Add(ctx context.Context, req *backendpb.AddRequest) (err error) {
 	if req == nil {
 		return
@@ -215,13 +437,34 @@ func (c *ShardClient) Add(ctx context.Context, req *backendpb.AddRequest) (err e
 	}
 
 	if c.localStorage != nil && master.IP == vars.LocalIP && master.Port == vars.Cfg.TcpPort {
-		return c.localStorage.HandleAddReq(req)
+		if c.localStorage.Left() {
+			return storage.ErrLeftCluster
+		}
+		err = c.localStorage.HandleAddReq(req)
+		if req.Ack != backendpb.AckLevel_AckAsync {
+			return c.localStorage.Acknowledge(ctx, req, err).Err()
+		}
+		return err
 	}
 
 	var cli *client.Client
 	if cli, err = defaultFactory.getClient(master.Addr()); err == nil {
-		if err = cli.AsyncRequest(req, nil); err == nil {
-			return
+		if req.Ack == backendpb.AckLevel_AckAsync {
+			if err = cli.AsyncRequest(req, nil); err == nil {
+				return
+			}
+		} else {
+			var resp msg.Message
+			if resp, err = cli.SyncRequest(ctx, req); err == nil {
+				switch r := resp.(type) {
+				case *backendpb.AddResponse:
+					return r.Err()
+				case *pb.GeneralResponse:
+					return r.Err()
+				default:
+					return tcp.BadMsgTypeError
+				}
+			}
 		}
 	}
 
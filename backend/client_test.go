@@ -0,0 +1,173 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/meta"
+	"github.com/baudtime/baudtime/msg"
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/vars"
+	pkgerrors "github.com/pkg/errors"
+)
+
+func TestReadFromSlaves(t *testing.T) {
+	node := func(ip string, maxT int64) *meta.Node {
+		return &meta.Node{IP: ip, Port: "8088", MaxT: maxT}
+	}
+	master := node("10.0.0.1", 100000)
+	slaves := []*meta.Node{node("10.0.0.2", 99000), node("10.0.0.3", 40000), node("10.0.0.4", 98000)}
+
+	tests := []struct {
+		master *meta.Node
+		maxLag time.Duration
+		down   map[string]bool
+		want   string
+		tried  int
+	}{
+		// a fresh slave answers
+		{master: master, maxLag: 5 * time.Second, down: map[string]bool{}, want: "slave", tried: 1},
+		// the erroring slaves are skipped, the stale one is never tried
+		{master: master, maxLag: 5 * time.Second, down: map[string]bool{"10.0.0.2": true, "10.0.0.4": true}, want: "10.0.0.1", tried: 3},
+		// no lag bound, the stale slave answers
+		{master: master, down: map[string]bool{"10.0.0.2": true, "10.0.0.4": true}, want: "10.0.0.3", tried: 2},
+		// every node is down
+		{master: master, maxLag: 5 * time.Second, down: map[string]bool{"10.0.0.1": true, "10.0.0.2": true, "10.0.0.4": true}, want: "", tried: 3},
+		// no master, lag is relative to the freshest slave
+		{master: nil, maxLag: 5 * time.Second, down: map[string]bool{"10.0.0.2": true, "10.0.0.4": true}, want: "", tried: 2},
+	}
+
+	for i, test := range tests {
+		tried := 0
+		resp, err := readFromSlaves(test.master, slaves, test.maxLag, func(n *meta.Node) (msg.Message, error) {
+			tried++
+			if test.down[n.IP] {
+				return nil, errors.New(n.IP + " is down")
+			}
+			return &pb.GeneralResponse{Message: n.IP}, nil
+		})
+
+		if tried != test.tried {
+			t.Fatalf("case %d: want %d nodes tried, got %d", i, test.tried, tried)
+		}
+		if test.want == "" {
+			if err == nil {
+				t.Fatalf("case %d: expected error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case %d: unexpected error %v", i, err)
+		}
+
+		got := resp.(*pb.GeneralResponse).Message
+		switch test.want {
+		case "slave":
+			if got == master.IP {
+				t.Fatalf("case %d: want a slave read, got the master", i)
+			}
+		default:
+			if got != test.want {
+				t.Fatalf("case %d: want %s, got %s", i, test.want, got)
+			}
+		}
+	}
+}
+
+func TestFreshSlaves_RoundRobin(t *testing.T) {
+	slaves := []*meta.Node{{IP: "10.0.0.2", MaxT: 1000}, {IP: "10.0.0.3", MaxT: 1000}}
+
+	firsts := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		firsts[freshSlaves(nil, slaves, 0)[0].IP]++
+	}
+	if firsts["10.0.0.2"] != 5 || firsts["10.0.0.3"] != 5 {
+		t.Fatalf("slaves are not read in round robin: %v", firsts)
+	}
+}
+
+func TestShardClient_IdentifyErrors(t *testing.T) {
+	c := &ShardClient{shardID: "s3"}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	query := c.identifyErrors(func(n *meta.Node) (msg.Message, error) {
+		if n.IP == "10.0.0.4" {
+			return nil, refused
+		}
+		return &pb.GeneralResponse{Message: n.IP}, nil
+	})
+
+	_, err := query(&meta.Node{IP: "10.0.0.4", Port: "8080"})
+	if err == nil || err.Error() != "shard=s3 addr=10.0.0.4:8080: dial tcp: connection refused" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, ok := pkgerrors.Cause(err).(net.Error); !ok {
+		t.Fatalf("want the net.Error preserved, got %T", pkgerrors.Cause(err))
+	}
+	if unwrapped := err.(*ShardError).Unwrap(); unwrapped != refused {
+		t.Fatalf("want the net.Error unwrapped, got %v", unwrapped)
+	}
+
+	if resp, err := query(&meta.Node{IP: "10.0.0.5", Port: "8080"}); err != nil || resp.(*pb.GeneralResponse).Message != "10.0.0.5" {
+		t.Fatalf("unexpected response %v, err %v", resp, err)
+	}
+
+	// every failed node shows up in the error of the shard
+	_, err = readFromSlaves(&meta.Node{IP: "10.0.0.4", Port: "8080"}, []*meta.Node{{IP: "10.0.0.4", Port: "8081"}}, 0, c.identifyErrors(func(*meta.Node) (msg.Message, error) {
+		return nil, refused
+	}))
+	for _, want := range []string{"shard=s3 addr=10.0.0.4:8080", "shard=s3 addr=10.0.0.4:8081"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("want %q in the error, got %v", want, err)
+		}
+	}
+}
+
+func TestEvictOldMaster(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	vars.Cfg.Gateway = &vars.GatewayConfig{ConnNumPerBackend: 2}
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	oldMaster := &meta.Node{ShardID: "s1", IP: "10.0.0.1", Port: "8088"}
+	stale, _ := defaultFactory.getClient(oldMaster.Addr())
+	other, _ := defaultFactory.getClient("10.0.0.2:8088")
+	defer defaultFactory.destroy("10.0.0.2:8088")
+
+	evictOldMaster(oldMaster.ShardID, oldMaster)
+
+	if _, found := defaultFactory.clients.Load(oldMaster.Addr()); found {
+		t.Fatalf("want the client to the old master evicted")
+	}
+	if err := stale.AsyncRequest(&pb.GeneralResponse{}, nil); err == nil {
+		t.Fatalf("want the pooled connections to the old master closed")
+	}
+	if cli, _ := defaultFactory.getClient("10.0.0.2:8088"); cli != other {
+		t.Fatalf("want the client to another node kept")
+	}
+
+	fresh, _ := defaultFactory.getClient(oldMaster.Addr())
+	defer defaultFactory.destroy(oldMaster.Addr())
+	if fresh == stale {
+		t.Fatalf("want a new client to the node once it's evicted")
+	}
+}
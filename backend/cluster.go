@@ -0,0 +1,87 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	stdtime "time"
+
+	"github.com/baudtime/baudtime/backend/storage"
+	"github.com/baudtime/baudtime/meta"
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// cluster is what the fanout knows of the shards, i.e. how the queries and series are routed to them,
+// what their nodes report and how they're asked. It's the meta of the cluster, see metaCluster.
+type cluster interface {
+	shardIDByLabels(t stdtime.Time, l []pb.Label, hash uint64) (string, error)
+	shardIDsByMetric(matchers ...*labels.Matcher) ([]string, error)
+	shardIDsByTimeSpan(from, to stdtime.Time, matchers ...*labels.Matcher) ([]string, error)
+	shardIDsByHash(from, to stdtime.Time, metric string, hash uint64) ([]string, error)
+	// latestTime returns the time of the latest data the master of a shard reported.
+	latestTime(shardID string) (int64, bool)
+	// series returns the series reported by the nodes of a shard as its cardinality hint, 0 if not reported.
+	series(shardID string) uint64
+	// client returns the client of a shard, which asks its local storage if not nil.
+	client(shardID string, localStorage *storage.Storage) Client
+}
+
+// metaCluster routes by meta.Router() and tells what the nodes reported to the meta.
+type metaCluster struct{}
+
+func (metaCluster) shardIDByLabels(t stdtime.Time, l []pb.Label, hash uint64) (string, error) {
+	return meta.Router().GetShardIDByLabels(t, l, hash)
+}
+
+func (metaCluster) shardIDsByMetric(matchers ...*labels.Matcher) ([]string, error) {
+	return meta.Router().GetShardIDsByMetric(matchers...)
+}
+
+func (metaCluster) shardIDsByTimeSpan(from, to stdtime.Time, matchers ...*labels.Matcher) ([]string, error) {
+	return meta.Router().GetShardIDsByTimeSpan(from, to, matchers...)
+}
+
+func (metaCluster) shardIDsByHash(from, to stdtime.Time, metric string, hash uint64) ([]string, error) {
+	return meta.Router().GetShardIDsByHash(from, to, metric, hash)
+}
+
+func (metaCluster) latestTime(shardID string) (int64, bool) {
+	master := meta.GetMaster(shardID)
+	if master == nil || master.MaxT == 0 {
+		return 0, false
+	}
+	return master.MaxT, true
+}
+
+func (metaCluster) series(shardID string) uint64 {
+	var n uint64
+	if master := meta.GetMaster(shardID); master != nil {
+		n = master.SeriesNum
+	}
+	for _, slave := range meta.GetSlaves(shardID) {
+		if slave.SeriesNum > n {
+			n = slave.SeriesNum
+		}
+	}
+	return n
+}
+
+func (metaCluster) client(shardID string, localStorage *storage.Storage) Client {
+	return &ShardClient{
+		shardID:      shardID,
+		localStorage: localStorage,
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"math"
+	stdtime "time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// ErrQueryTooExpensive is returned by a select whose estimated cost is above QueryConfig.MaxSelectCost.
+var ErrQueryTooExpensive = errors.New("query too expensive")
+
+const (
+	// defaultShardSeries is taken as the series of a shard none of whose nodes reports them.
+	defaultShardSeries = 100000
+	// exactMetricFraction is the part of the series of a shard taken to be selected by an exact metric name.
+	exactMetricFraction = 0.01
+)
+
+// selectCost estimates the cost of a select as the series hours it may scan, i.e. the series the nodes of c
+// report of the shards it's routed to times the hours of [mint, maxt]. Only a fraction of the series count if the metric name is exact,
+// while a selector like {__name__=~".+"} counts them all.
+func selectCost(c cluster, shardIDs []string, mint, maxt int64, matchers ...*labels.Matcher) float64 {
+	hours := math.Max((float64(maxt)-float64(mint))/float64(stdtime.Hour/stdtime.Millisecond), 1)
+
+	fraction := 1.0
+	for _, m := range matchers {
+		if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+			fraction = exactMetricFraction
+		}
+	}
+
+	var series float64
+	for _, shardID := range shardIDs {
+		if shardID == "" {
+			continue
+		}
+
+		n := c.series(shardID)
+		if n == 0 {
+			n = defaultShardSeries
+		}
+		series += float64(n)
+	}
+
+	return series * fraction * hours
+}
+
+// checkSelectCost returns an ErrQueryTooExpensive if the estimated cost of a select is above the configured budget,
+// so that an accidental broad query is refused before any shard is asked.
+func checkSelectCost(c cluster, shardIDs []string, mint, maxt int64, matchers ...*labels.Matcher) error {
+	budget := queryConfig().MaxSelectCost
+	if budget <= 0 {
+		return nil
+	}
+
+	if cost := selectCost(c, shardIDs, mint, maxt, matchers...); cost > float64(budget) {
+		return errors.Wrapf(ErrQueryTooExpensive, "estimated cost %.0f series hours of %v is above the budget %d, narrow the selector or the time range",
+			cost, matchers, budget)
+	}
+	return nil
+}
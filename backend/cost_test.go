@@ -0,0 +1,93 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/vars"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+func TestFanoutQuerier_SelectCost(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	vars.Cfg.Gateway = &vars.GatewayConfig{Query: vars.QueryConfig{MaxSelectCost: 1000000}}
+	cluster := &fakeCluster{
+		byTimeSpan: func(time.Time, time.Time, ...*labels.Matcher) ([]string, error) {
+			return []string{"1", "2", ""}, nil
+		},
+		seriesOf: func(shardID string) uint64 {
+			if shardID == "1" {
+				return 5000
+			}
+			return 0 // not reported
+		},
+	}
+
+	broad, err := labels.NewMatcher(labels.MatchRegexp, labels.MetricName, ".+")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scoped, err := labels.NewMatcher(labels.MatchEqual, labels.MetricName, "up")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const hour, mint = int64(time.Hour / time.Millisecond), int64(1560000000000)
+
+	tests := []struct {
+		matcher  *labels.Matcher
+		maxt     int64
+		wantCost float64
+		rejected bool
+	}{
+		{matcher: broad, maxt: mint + 30*24*hour, wantCost: (5000 + defaultShardSeries) * 30 * 24, rejected: true},
+		{matcher: broad, maxt: mint + hour, wantCost: 5000 + defaultShardSeries},
+		{matcher: broad, maxt: mint, wantCost: 5000 + defaultShardSeries}, // an instant counts as an hour
+		{matcher: scoped, maxt: mint + 30*24*hour, wantCost: (5000 + defaultShardSeries) * exactMetricFraction * 30 * 24},
+	}
+
+	for _, test := range tests {
+		if cost := selectCost(cluster, []string{"1", "2", ""}, mint, test.maxt, test.matcher); cost != test.wantCost {
+			t.Fatalf("%v over [%d, %d]: want cost %v, got %v", test.matcher, mint, test.maxt, test.wantCost, cost)
+		}
+
+		err := checkSelectCost(cluster, []string{"1", "2", ""}, mint, test.maxt, test.matcher)
+		if rejected := errors.Cause(err) == ErrQueryTooExpensive; rejected != test.rejected {
+			t.Fatalf("%v over [%d, %d]: want rejected %v, got %v", test.matcher, mint, test.maxt, test.rejected, err)
+		}
+	}
+
+	q := &fanoutQuerier{ctx: context.Background(), mint: mint, maxt: mint + 30*24*hour, cluster: cluster}
+	if _, _, err := q.Select(&SelectParams{}, broad); errors.Cause(err) != ErrQueryTooExpensive {
+		t.Fatalf("want a too broad select rejected, got %v", err)
+	}
+	if q.Querier != nil {
+		t.Fatalf("expected no shard asked for a rejected select")
+	}
+
+	vars.Cfg.Gateway.Query.MaxSelectCost = 0
+	if err := checkSelectCost(cluster, []string{"1", "2"}, mint, mint+30*24*hour, broad); err != nil {
+		t.Fatalf("unexpected rejection without a budget: %v", err)
+	}
+}
@@ -17,50 +17,164 @@ package backend
 import (
 	"container/heap"
 	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
 	"strings"
 	"sync"
+	stdtime "time"
 
 	"github.com/baudtime/baudtime/backend/storage"
 	"github.com/baudtime/baudtime/meta"
 	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/tcp/client"
+	"github.com/baudtime/baudtime/util"
 	"github.com/baudtime/baudtime/util/time"
+	"github.com/baudtime/baudtime/vars"
 	"github.com/hashicorp/go-multierror"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/value"
 )
 
+const startTimeCacheTTL = 30 * stdtime.Second
+
 type Fanout struct {
-	localStorage *storage.Storage
+	localStorages localStorages
+	cluster       cluster
+
+	startTimeMtx    sync.Mutex
+	startTime       int64
+	startTimeExpire stdtime.Time
 }
 
 // NewFanout returns a new fan-out Backend, which proxies reads and writes
-// through to multiple underlying storages.
+// through to multiple underlying storages. localStorage, if not nil, is the storage
+// of the process, which serves whichever shard the process is a node of without a remote hop.
 func NewFanout(localStorage *storage.Storage) *Fanout {
 	return &Fanout{
-		localStorage: localStorage,
+		localStorages: localStorages{fallback: localStorage},
+		cluster:       metaCluster{},
+	}
+}
+
+// NewFanoutOfStorages is NewFanout for a process hosting several shards, the storage of each
+// of them by shard ID. The shards not among them are read from and written to remotely.
+func NewFanoutOfStorages(storages map[string]*storage.Storage) *Fanout {
+	byShard := make(map[string]*storage.Storage, len(storages))
+	for shardID, s := range storages {
+		if s != nil {
+			byShard[shardID] = s
+		}
+	}
+	return &Fanout{
+		localStorages: localStorages{byShard: byShard},
+		cluster:       metaCluster{},
+	}
+}
+
+// localStorages are the storages hosted by the process, which the shards found in it are read from
+// and written to through in process rather than over a connection to the process itself.
+type localStorages struct {
+	byShard  map[string]*storage.Storage
+	fallback *storage.Storage // the only storage of the process, serving whichever shard it's a node of
+}
+
+// of returns the local storage of a shard, nil if the shard isn't hosted by the process.
+func (s localStorages) of(shardID string) *storage.Storage {
+	if local, found := s.byShard[shardID]; found {
+		return local
+	}
+	return s.fallback
+}
+
+// all returns every local storage once.
+func (s localStorages) all() []*storage.Storage {
+	var all []*storage.Storage
+	if s.fallback != nil {
+		all = append(all, s.fallback)
+	}
+	for _, local := range s.byShard {
+		if local != s.fallback {
+			all = append(all, local)
+		}
 	}
+	return all
 }
 
+// Querier returns a querier of the shards in [mint, maxt]. If the query is traced, i.e. ctx carries a span,
+// the querier has a span of its own until it's closed, with the spans of its selects and their shards under it.
 func (f *Fanout) Querier(ctx context.Context, mint, maxt int64) (Querier, error) {
-	return &fanoutQuerier{
-		ctx:          ctx,
-		mint:         mint,
-		maxt:         maxt,
-		localStorage: f.localStorage,
-	}, nil
+	span, ctx := startSpan(ctx, "fanoutQuerier", opentracing.Tag{Key: "mint", Value: mint}, opentracing.Tag{Key: "maxt", Value: maxt})
+
+	q := &fanoutQuerier{
+		ctx:           ctx,
+		mint:          mint,
+		maxt:          maxt,
+		localStorages: f.localStorages,
+		cluster:       f.cluster,
+		budget:        newResponseBudget(),
+		span:          span,
+	}
+	if queryConfig().SnapshotRead {
+		q.readTs = util.Min(maxt, time.FromTime(stdtime.Now()))
+	}
+	return q, nil
 }
 
 // StartTime implements the Backend interface.
 func (f *Fanout) StartTime() (int64, error) {
 	// StartTime of a fanout should be the earliest StartTime of all its storages,
-	// both primary and secondaries.
-	return int64(model.Latest), nil
+	// it's calculated from the min time reported by the nodes and cached for a while.
+	f.startTimeMtx.Lock()
+	defer f.startTimeMtx.Unlock()
+
+	if now := stdtime.Now(); now.After(f.startTimeExpire) {
+		f.startTime = shardsStartTime(meta.AllShards())
+		f.startTimeExpire = now.Add(startTimeCacheTTL)
+	}
+
+	return f.startTime, nil
+}
+
+// shardsStartTime returns the earliest min time reported by the given shards, model.Latest if there is none.
+func shardsStartTime(shards map[string]*meta.Shard) int64 {
+	startTime := int64(model.Latest)
+
+	for _, shard := range shards {
+		if shard == nil {
+			continue
+		}
+
+		nodes := shard.Slaves
+		if shard.Master != nil {
+			nodes = []*meta.Node{shard.Master}
+		}
+
+		for _, node := range nodes {
+			minT := node.MinT
+			if minT == 0 { // not reported, it may hold data of any time
+				minT = int64(model.Earliest)
+			}
+			if minT < startTime {
+				startTime = minT
+			}
+		}
+	}
+
+	return startTime
 }
 
-// Close closes the storage and all its underlying resources.
+// Close closes the local storages and all their underlying resources.
 func (f *Fanout) Close() (err error) {
-	if f.localStorage != nil {
-		err = f.localStorage.Close()
+	for _, s := range f.localStorages.all() {
+		if closeErr := s.Close(); closeErr != nil {
+			err = closeErr
+		}
 	}
 	return
 }
@@ -69,78 +183,204 @@ type fanoutQuerier struct {
 	sync.Once
 	ctx        context.Context
 	mint, maxt int64
+	readTs     int64 // pinned at the start of the query if QueryConfig.SnapshotRead, 0 otherwise
 	Querier
-	localStorage *storage.Storage
+	localStorages localStorages
+	cluster       cluster
+	budget        *responseBudget  // the series read from the shards by all the selects are accounted against, nil if unlimited
+	span          opentracing.Span // finished on close, nil if the query isn't traced
+}
+
+func (q *fanoutQuerier) Select(params *SelectParams, matchers ...*labels.Matcher) (_ SeriesSet, _ Warnings, err error) {
+	span, ctx := startSpan(q.ctx, "fanoutSelect")
+	defer func() {
+		finishSpan(span, err)
+	}()
+
+	matchers, satisfiable := util.NormalizeMatchers(matchers)
+	if !satisfiable {
+		return emptySeriesSet, nil, nil
+	}
+	if span != nil {
+		span.SetTag("matchers", fmt.Sprint(matchers))
+	}
+
+	shardIDs, err := q.cluster.shardIDsByTimeSpan(time.Time(q.mint), time.Time(q.maxt), matchers...)
+	if err != nil {
+		return emptySeriesSet, nil, err
+	}
+	if !anyShard(shardIDs) {
+		return emptySeriesSet, nil, nil
+	}
+	if err = checkSelectCost(q.cluster, shardIDs, q.mint, q.maxt, matchers...); err != nil {
+		return emptySeriesSet, nil, err
+	}
+	if span != nil {
+		span.SetTag("shards", len(shardIDs))
+	}
+
+	q.Querier = NewMergeQuerier(ctx, q.shardQueriers(ctx, shardIDs, q.snapshotMaxt(shardIDs)))
+	set, warnings, err := q.Querier.Select(params, matchers...)
+	if err != nil {
+		return set, warnings, err
+	}
+	return newResolutionSeriesSet(set, params.Resolution, params.Downsample), warnings, nil
 }
 
-func (q *fanoutQuerier) Select(params *SelectParams, matchers ...*labels.Matcher) (SeriesSet, error) {
-	shardIDs, err := meta.Router().GetShardIDsByTimeSpan(time.Time(q.mint), time.Time(q.maxt), matchers...)
+// SelectByHash only asks the shards the series of the hash is written to in [mint, maxt], which are
+// resolved by the hash the same way as writing the series does.
+func (q *fanoutQuerier) SelectByHash(metric string, hash uint64) (SeriesSet, error) {
+	shardIDs, err := q.cluster.shardIDsByHash(time.Time(q.mint), time.Time(q.maxt), metric, hash)
 	if err != nil {
 		return emptySeriesSet, err
 	}
+	if !anyShard(shardIDs) {
+		return emptySeriesSet, nil
+	}
+
+	matcher, err := labels.NewMatcher(labels.MatchEqual, labels.MetricName, metric)
+	if err != nil {
+		return emptySeriesSet, err
+	}
+
+	q.Querier = NewMergeQuerier(q.ctx, q.shardQueriers(q.ctx, shardIDs, q.snapshotMaxt(shardIDs)))
+	set, _, err := q.Querier.Select(&SelectParams{Hash: hash}, matcher)
+	return set, err
+}
+
+// LabelValues only asks the shards the metric is routed to in [mint, maxt] if the matchers scope the route,
+// e.g. by an exact metric name, the ones it has ever been routed to if the span is unbounded, otherwise all the shards.
+func (q *fanoutQuerier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, error) {
+	shardIDs, err := q.labelValuesShardIDs(matchers...)
+	if err != nil {
+		return nil, err
+	}
+	if !anyShard(shardIDs) {
+		return nil, nil
+	}
+
+	q.Querier = NewMergeQuerier(q.ctx, q.shardQueriers(q.ctx, shardIDs, q.maxt))
+	return q.Querier.LabelValues(name, matchers...)
+}
+
+func (q *fanoutQuerier) labelValuesShardIDs(matchers ...*labels.Matcher) ([]string, error) {
+	if q.mint == math.MinInt64 || q.maxt == math.MaxInt64 {
+		shardIDs, err := q.cluster.shardIDsByMetric(matchers...)
+		if err == meta.ErrNoExactMetricName {
+			return allShardIDs(), nil
+		}
+		return shardIDs, err
+	}
+	return q.cluster.shardIDsByTimeSpan(time.Time(q.mint), time.Time(q.maxt), matchers...)
+}
+
+func (q *fanoutQuerier) LabelNames() ([]string, error) {
+	shardIDs := allShardIDs()
+	if !anyShard(shardIDs) {
+		return nil, nil
+	}
+
+	q.Querier = NewMergeQuerier(q.ctx, q.shardQueriers(q.ctx, shardIDs, q.maxt))
+	return q.Querier.LabelNames()
+}
+
+// anyShard tells whether a query is routed to any shard, the empty shard IDs are the days without a route.
+func anyShard(shardIDs []string) bool {
+	for _, shardID := range shardIDs {
+		if shardID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotMaxt returns the maxt all the shards of a select are asked for, the read timestamp pinned at
+// the start of the query lowered to the latest data reported by them, so that they answer as of the
+// same instant while none is asked beyond the data it has. It's q.maxt if the read isn't pinned.
+func (q *fanoutQuerier) snapshotMaxt(shardIDs []string) int64 {
+	if q.readTs == 0 {
+		return q.maxt
+	}
 
+	maxt := q.readTs
+	for _, shardID := range shardIDs {
+		if shardID == "" {
+			continue
+		}
+		if latest, reported := q.cluster.latestTime(shardID); reported && latest < maxt {
+			maxt = latest
+		}
+	}
+	return maxt
+}
+
+func (q *fanoutQuerier) shardQueriers(ctx context.Context, shardIDs []string, maxt int64) []Querier {
 	queriers := make([]Querier, 0, len(shardIDs))
 	for _, shardID := range shardIDs {
 		if shardID == "" {
 			continue
 		}
 
-		queriers = append(queriers, &querier{
-			ctx:  q.ctx,
-			mint: q.mint,
-			maxt: q.maxt,
-			client: &ShardClient{
-				shardID:      shardID,
-				localStorage: q.localStorage,
-			},
-		})
+		shardQuerier := &querier{
+			ctx:     ctx,
+			mint:    q.mint,
+			maxt:    maxt,
+			shardID: shardID,
+			client:  q.cluster.client(shardID, q.localStorages.of(shardID)),
+			timeout: stdtime.Duration(queryConfig().PerShardTimeout),
+			compact: queryConfig().CompactPoints,
+		}
+		if q.budget == nil {
+			queriers = append(queriers, shardQuerier)
+			continue
+		}
+
+		accounted := &accountedQuerier{Querier: shardQuerier, shardID: shardID, budget: q.budget}
+		shardQuerier.ctx = client.WithFrameCheck(ctx, accounted.checkFrame)
+		queriers = append(queriers, accounted)
 	}
 
-	q.Querier = NewMergeQuerier(queriers)
-	return q.Querier.Select(params, matchers...)
+	return queriers
 }
 
-func (q *fanoutQuerier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, error) {
+func allShardIDs() []string {
 	allShards := meta.AllShards()
 
-	queriers := make([]Querier, 0, len(allShards))
+	shardIDs := make([]string, 0, len(allShards))
 	for shardID := range allShards {
-		if shardID == "" {
-			continue
-		}
-
-		queriers = append(queriers, &querier{
-			ctx:  q.ctx,
-			mint: q.mint,
-			maxt: q.maxt,
-			client: &ShardClient{
-				shardID:      shardID,
-				localStorage: q.localStorage,
-			},
-		})
+		shardIDs = append(shardIDs, shardID)
 	}
 
-	q.Querier = NewMergeQuerier(queriers)
-	return q.Querier.LabelValues(name, matchers...)
+	return shardIDs
 }
 
-func (q *fanoutQuerier) Close() error {
+func (q *fanoutQuerier) Close() (err error) {
 	if q.Querier != nil {
-		return q.Querier.Close()
+		err = q.Querier.Close()
 	}
-	return nil
+	if q.span != nil {
+		q.span.Finish()
+		q.span = nil
+	}
+	return err
 }
 
 // mergeQuerier implements Querier.
 type mergeQuerier struct {
-	queriers []Querier
+	ctx             context.Context
+	queriers        []Querier
+	concurrency     int
+	partialResponse bool
+
+	closeMtx sync.Mutex
+	closed   map[int]bool // indexes of the queriers closed by a failed select, not to be closed again
 }
 
 // NewMergeQuerier returns a new Querier that merges results of input queriers.
 // NB NewMergeQuerier will return NoopQuerier if no queriers are passed to it,
 // and will filter NoopQueriers from its arguments, in order to reduce overhead
 // when only one querier is passed.
-func NewMergeQuerier(queriers []Querier) Querier {
+func NewMergeQuerier(ctx context.Context, queriers []Querier) Querier {
 	filtered := make([]Querier, 0, len(queriers))
 	for _, querier := range queriers {
 		if querier != NoopQuerier() {
@@ -154,70 +394,160 @@ func NewMergeQuerier(queriers []Querier) Querier {
 	case 1:
 		return filtered[0]
 	default:
+		cfg := queryConfig()
 		return &mergeQuerier{
-			queriers: filtered,
+			ctx:             ctx,
+			queriers:        filtered,
+			concurrency:     cfg.MaxConcurrency,
+			partialResponse: cfg.PartialResponse,
 		}
 	}
 }
 
 // Select returns a set of series that matches the given label matchers.
-func (q *mergeQuerier) Select(params *SelectParams, matchers ...*labels.Matcher) (SeriesSet, error) {
+// It gives up waiting for the outstanding queriers once the context is done.
+// If partial response is allowed, failed queriers only result in a warning as long as one succeeded,
+// but the ones failed for a response too large, which fails the select anyway.
+// When it fails, the series sets and queriers answered are closed, the outstanding ones once they answer.
+// A failed querier is closed as soon as it answers.
+func (q *mergeQuerier) Select(params *SelectParams, matchers ...*labels.Matcher) (SeriesSet, Warnings, error) {
+	type selectResult struct {
+		idx      int
+		set      SeriesSet
+		warnings Warnings
+		err      error
+	}
+
 	var (
 		multiErr   error
-		mtx        sync.Mutex
+		failed     int
+		tooLarge   bool
+		warnings   Warnings
 		seriesSets = make([]SeriesSet, len(q.queriers))
-		wg         sync.WaitGroup
+		answered   = make([]int, 0, len(q.queriers))
+		resultCh   = make(chan selectResult, len(q.queriers))
 	)
 
-	for i, querier := range q.queriers {
-		wg.Add(1)
-		go func(idx int, q Querier) {
-			defer wg.Done()
-
-			set, err := q.Select(params, matchers...)
-			if err != nil {
-				mtx.Lock()
-				multiErr = multierror.Append(multiErr, err)
-				mtx.Unlock()
-				return
+	launched := q.goEach(func(idx int, q Querier) {
+		set, wrn, err := q.Select(params, matchers...)
+		resultCh <- selectResult{idx, set, wrn, err}
+	})
+
+	for received := 0; received < launched; received++ {
+		select {
+		case r := <-resultCh:
+			answered = append(answered, r.idx)
+			warnings = append(warnings, r.warnings...)
+			if r.err != nil {
+				failed++
+				tooLarge = tooLarge || errors.Cause(r.err) == ErrResponseTooLarge
+				multiErr = multierror.Append(multiErr, r.err)
+				closeSeriesSets(r.set)
+				q.closeQueriers(r.idx)
+			} else {
+				seriesSets[r.idx] = r.set
 			}
-			seriesSets[idx] = set
-		}(i, querier)
+		case <-q.ctx.Done():
+			closeSeriesSets(seriesSets...)
+			q.closeQueriers(answered...)
+			go func(outstanding int) {
+				for ; outstanding > 0; outstanding-- {
+					r := <-resultCh
+					closeSeriesSets(r.set)
+					q.closeQueriers(r.idx)
+				}
+			}(launched - received)
+			return nil, nil, q.ctx.Err()
+		}
+	}
+
+	if launched < len(q.queriers) {
+		closeSeriesSets(seriesSets...)
+		q.closeQueriers(answered...)
+		return nil, nil, q.ctx.Err()
 	}
-	wg.Wait()
 
 	if multiErr != nil {
-		return nil, multiErr
+		if !q.partialResponse || failed == len(q.queriers) || tooLarge {
+			closeSeriesSets(seriesSets...)
+			q.closeQueriers(answered...)
+			return nil, nil, multiErr
+		}
+
+		warnings = append(warnings, errors.Wrapf(multiErr, "%d of %d shards responded", len(q.queriers)-failed, len(q.queriers)))
+
+		responded := seriesSets[:0]
+		for _, set := range seriesSets {
+			if set != nil {
+				responded = append(responded, set)
+			}
+		}
+		seriesSets = responded
+	}
+
+	if params.Aggregation != nil {
+		return newAggrSeriesSet(seriesSets, params.Aggregation.Op), warnings, nil
+	}
+	set, wrn := newMergeSeriesSet(seriesSets, labels.Compare, q.partialResponse)
+	return set, append(warnings, wrn...), nil
+}
+
+// closeSeriesSets releases the resources held by series sets which implement io.Closer.
+func closeSeriesSets(sets ...SeriesSet) {
+	for _, set := range sets {
+		if closer, ok := set.(io.Closer); ok {
+			closer.Close()
+		}
 	}
-	return NewMergeSeriesSet(seriesSets), nil
 }
 
 // LabelValues returns all potential values for a label name.
+// If the context has a LabelValuesPage, the pages of the underlying queriers are merged into one.
 func (q *mergeQuerier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, error) {
+	values, err := q.mergeStrings(func(querier Querier) ([]string, error) {
+		return querier.LabelValues(name, matchers...)
+	})
+	if page, paged := labelValuesPageFromContext(q.ctx); paged && err == nil {
+		values = page.Cut(values)
+	}
+	return values, err
+}
+
+// LabelNames returns all the unique label names present in the underlying queriers.
+func (q *mergeQuerier) LabelNames() ([]string, error) {
+	return q.mergeStrings(func(querier Querier) ([]string, error) {
+		return querier.LabelNames()
+	})
+}
+
+func (q *mergeQuerier) mergeStrings(get func(Querier) ([]string, error)) ([]string, error) {
+	type stringsResult struct {
+		values []string
+		err    error
+	}
+
 	var (
 		multiErr error
 		results  [][]string
-		mtx      sync.Mutex
-		wg       sync.WaitGroup
+		resultCh = make(chan stringsResult, len(q.queriers))
 	)
 
-	for _, querier := range q.queriers {
-		wg.Add(1)
-		go func(q Querier) {
-			defer wg.Done()
+	launched := q.goEach(func(_ int, q Querier) {
+		values, err := get(q)
+		resultCh <- stringsResult{values, err}
+	})
 
-			values, err := q.LabelValues(name, matchers...)
+	for received := 0; received < launched; received++ {
+		if r := <-resultCh; r.err != nil {
+			multiErr = multierror.Append(multiErr, r.err)
+		} else {
+			results = append(results, sortedUnique(r.values))
+		}
+	}
 
-			mtx.Lock()
-			if err != nil {
-				multiErr = multierror.Append(multiErr, err)
-			} else {
-				results = append(results, values)
-			}
-			mtx.Unlock()
-		}(querier)
+	if launched < len(q.queriers) {
+		multiErr = multierror.Append(multiErr, q.ctx.Err())
 	}
-	wg.Wait()
 
 	if multiErr != nil {
 		return nil, multiErr
@@ -226,6 +556,71 @@ func (q *mergeQuerier) LabelValues(name string, matchers ...*labels.Matcher) ([]
 	return mergeStringSlices(results), nil
 }
 
+// goEach calls f in a new goroutine for every querier, with at most q.concurrency of them
+// running at the same time. It stops launching once the context is done and returns the
+// number of goroutines launched.
+func (q *mergeQuerier) goEach(f func(idx int, q Querier)) int {
+	var sem chan struct{}
+	if q.concurrency > 0 && q.concurrency < len(q.queriers) {
+		sem = make(chan struct{}, q.concurrency)
+	}
+
+	for i, querier := range q.queriers {
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-q.ctx.Done():
+				return i
+			}
+		}
+
+		go func(idx int, q Querier) {
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			f(idx, q)
+		}(i, querier)
+	}
+
+	return len(q.queriers)
+}
+
+func queryConfig() vars.QueryConfig {
+	if vars.Cfg.Gateway != nil {
+		return vars.Cfg.Gateway.Query
+	}
+	return vars.QueryConfig{}
+}
+
+// sortedUnique returns the sorted and deduplicated values, the shards aren't required to
+// return them in order, while merging them relies on it. values is left untouched.
+func sortedUnique(values []string) []string {
+	sorted, unique := true, true
+	for i := 1; i < len(values) && sorted; i++ {
+		switch strings.Compare(values[i-1], values[i]) {
+		case 0:
+			unique = false
+		case 1:
+			sorted = false
+		}
+	}
+	if sorted && unique {
+		return values
+	}
+
+	result := append([]string(nil), values...)
+	sort.Strings(result)
+
+	n := 1
+	for _, v := range result[1:] {
+		if v != result[n-1] {
+			result[n] = v
+			n++
+		}
+	}
+	return result[:n]
+}
+
 func mergeStringSlices(ss [][]string) []string {
 	switch len(ss) {
 	case 0:
@@ -267,10 +662,31 @@ func mergeTwoStringSlices(a, b []string) []string {
 
 // Close releases the resources of the Querier.
 func (q *mergeQuerier) Close() error {
+	idxs := make([]int, len(q.queriers))
+	for i := range idxs {
+		idxs[i] = i
+	}
+	return q.closeQueriers(idxs...)
+}
+
+// closeQueriers closes the queriers of idxs which are not closed yet.
+func (q *mergeQuerier) closeQueriers(idxs ...int) error {
+	q.closeMtx.Lock()
+	defer q.closeMtx.Unlock()
+
+	if q.closed == nil {
+		q.closed = make(map[int]bool, len(q.queriers))
+	}
+
 	// TODO return multiple errors?
 	var lastErr error
-	for _, querier := range q.queriers {
-		if err := querier.Close(); err != nil {
+	for _, idx := range idxs {
+		if q.closed[idx] {
+			continue
+		}
+		q.closed[idx] = true
+
+		if err := q.queriers[idx].Close(); err != nil {
 			lastErr = err
 		}
 	}
@@ -281,50 +697,128 @@ func (q *mergeQuerier) Close() error {
 type mergeSeriesSet struct {
 	currentLabels labels.Labels
 	currentSets   []SeriesSet
-	heap          seriesSetHeap
+	heap          *seriesSetHeap
 	sets          []SeriesSet
+	stats         mergeStats
 }
 
 // NewMergeSeriesSet returns a new series set that merges (deduplicates)
 // series returned by the input series sets when iterating.
 func NewMergeSeriesSet(sets []SeriesSet) SeriesSet {
+	set, _ := newMergeSeriesSet(sets, labels.Compare, false)
+	return set
+}
+
+// NewMergeSeriesSetWithCompare is NewMergeSeriesSet, besides the series are emitted in the order of compare
+// instead of the full label sets. The ties of compare are broken by the full label sets, so that the series
+// with equal label sets meet and are deduplicated. The input series sets, which are sorted by the full label
+// sets, are read out and sorted that way first, so it holds all their series at once.
+func NewMergeSeriesSetWithCompare(sets []SeriesSet, compare func(a, b labels.Labels) int) SeriesSet {
+	sorted := make([]SeriesSet, 0, len(sets))
+	for _, set := range sets {
+		sorted = append(sorted, sortSeriesSet(set, compare))
+	}
+	set, _ := newMergeSeriesSet(sorted, compare, false)
+	return set
+}
+
+// sortSeriesSet reads set out and sorts its series by compare, the ties by their full label sets.
+func sortSeriesSet(set SeriesSet, compare func(a, b labels.Labels) int) SeriesSet {
+	var series []Series
+	for set.Next() {
+		series = append(series, set.At())
+	}
+	if err := set.Err(); err != nil {
+		return errSeriesSet{err: err}
+	}
+
+	sort.SliceStable(series, func(i, j int) bool {
+		a, b := series[i].Labels(), series[j].Labels()
+		if c := compare(a, b); c != 0 {
+			return c < 0
+		}
+		return labels.Compare(a, b) < 0
+	})
+	return &concreteSeriesSet{series: series}
+}
+
+// newMergeSeriesSet merges the series sets, failing fast if one of them fails on pre-advance.
+// If partial is true, the failed sets are dropped with a warning instead, as long as one of them succeeded,
+// but a response too large always fails.
+// The statistics of the merge are observed by the merge metrics once it runs out of series.
+func newMergeSeriesSet(sets []SeriesSet, compare func(a, b labels.Labels) int, partial bool) (SeriesSet, Warnings) {
 	if len(sets) == 1 {
-		return sets[0]
+		return sets[0], nil
 	}
+	start := stdtime.Now()
+
+	var (
+		multiErr error
+		warnings Warnings
+		advanced = make([]SeriesSet, 0, len(sets))
+	)
 
 	// Sets need to be pre-advanced, so we can introspect the label of the
 	// series under the cursor.
-	var h seriesSetHeap
+	h := &seriesSetHeap{compare: compare}
 	for _, set := range sets {
 		if set.Next() {
-			heap.Push(&h, set)
+			heap.Push(h, set)
+		} else if err := set.Err(); err != nil {
+			if !partial || errors.Cause(err) == ErrResponseTooLarge {
+				closeSeriesSets(sets...)
+				return errSeriesSet{err: err}, nil
+			}
+			closeSeriesSets(set)
+			multiErr = multierror.Append(multiErr, err)
+			continue
 		}
+		advanced = append(advanced, set)
 	}
-	return &mergeSeriesSet{
+
+	if multiErr != nil {
+		if len(advanced) == 0 {
+			return errSeriesSet{err: multiErr}, nil
+		}
+		warnings = append(warnings, errors.Wrapf(multiErr, "%d of %d series sets succeeded", len(advanced), len(sets)))
+	}
+
+	set := &mergeSeriesSet{
 		heap: h,
-		sets: sets,
+		sets: advanced,
 	}
+	set.stats.sets = len(advanced)
+	set.stats.depth(h.Len())
+	set.stats.elapsed = stdtime.Since(start)
+	return set, warnings
 }
 
 func (c *mergeSeriesSet) Next() bool {
+	start := stdtime.Now()
+
 	// Firstly advance all the current series sets.  If any of them have run out
 	// we can drop them, otherwise they should be inserted back into the heap.
 	for _, set := range c.currentSets {
 		if set.Next() {
-			heap.Push(&c.heap, set)
+			heap.Push(c.heap, set)
 		}
 	}
-	if len(c.heap) == 0 {
+	c.stats.depth(c.heap.Len())
+	if c.heap.Len() == 0 {
+		c.stats.elapsed += stdtime.Since(start)
+		c.stats.observe()
 		return false
 	}
+	c.stats.series++
 
 	// Now, pop items of the heap that have equal label sets.
 	c.currentSets = nil
-	c.currentLabels = c.heap[0].At().Labels()
-	for len(c.heap) > 0 && labels.Equal(c.currentLabels, c.heap[0].At().Labels()) {
-		set := heap.Pop(&c.heap).(SeriesSet)
+	c.currentLabels = c.heap.sets[0].At().Labels()
+	for c.heap.Len() > 0 && labels.Equal(c.currentLabels, c.heap.sets[0].At().Labels()) {
+		set := heap.Pop(c.heap).(SeriesSet)
 		c.currentSets = append(c.currentSets, set)
 	}
+	c.stats.elapsed += stdtime.Since(start)
 	return true
 }
 
@@ -351,25 +845,37 @@ func (c *mergeSeriesSet) Err() error {
 	return nil
 }
 
-type seriesSetHeap []SeriesSet
+// CompareByLabel returns a comparison of label sets by the value of the label name.
+func CompareByLabel(name string) func(a, b labels.Labels) int {
+	return func(a, b labels.Labels) int {
+		return strings.Compare(a.Get(name), b.Get(name))
+	}
+}
+
+type seriesSetHeap struct {
+	sets    []SeriesSet
+	compare func(a, b labels.Labels) int
+}
 
-func (h seriesSetHeap) Len() int      { return len(h) }
-func (h seriesSetHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *seriesSetHeap) Len() int      { return len(h.sets) }
+func (h *seriesSetHeap) Swap(i, j int) { h.sets[i], h.sets[j] = h.sets[j], h.sets[i] }
 
-func (h seriesSetHeap) Less(i, j int) bool {
-	a, b := h[i].At().Labels(), h[j].At().Labels()
+func (h *seriesSetHeap) Less(i, j int) bool {
+	a, b := h.sets[i].At().Labels(), h.sets[j].At().Labels()
+	if c := h.compare(a, b); c != 0 {
+		return c < 0
+	}
 	return labels.Compare(a, b) < 0
 }
 
 func (h *seriesSetHeap) Push(x interface{}) {
-	*h = append(*h, x.(SeriesSet))
+	h.sets = append(h.sets, x.(SeriesSet))
 }
 
 func (h *seriesSetHeap) Pop() interface{} {
-	old := *h
-	n := len(old)
-	x := old[n-1]
-	*h = old[0 : n-1]
+	n := len(h.sets)
+	x := h.sets[n-1]
+	h.sets = h.sets[0 : n-1]
 	return x
 }
 
@@ -387,12 +893,18 @@ func (m *mergeSeries) Iterator() SeriesIterator {
 	for _, s := range m.series {
 		iterators = append(iterators, s.Iterator())
 	}
-	return newMergeIterator(iterators)
+	it := newMergeIterator(iterators).(*mergeIterator)
+	it.collapseStale = queryConfig().CollapseStaleMarkers
+	return it
 }
 
+// mergeIterator merges the samples of the iterators in time order, the ones at the same time are deduplicated.
+// Of the samples at the same time, a real value wins over a staleness marker.
 type mergeIterator struct {
-	iterators []SeriesIterator
-	h         seriesIteratorHeap
+	iterators     []SeriesIterator
+	h             seriesIteratorHeap
+	collapseStale bool // skip the staleness markers following a staleness marker
+	stale         bool // whether the current sample is a staleness marker
 }
 
 func newMergeIterator(iterators []SeriesIterator) SeriesIterator {
@@ -402,7 +914,32 @@ func newMergeIterator(iterators []SeriesIterator) SeriesIterator {
 	}
 }
 
+// Seek advances the iterators behind t only when seeking forward past the current sample, keeping
+// the heap incrementally. The iterators exhausted before have no sample after the current one, so
+// they are left out. Otherwise the heap is rebuilt by seeking all the iterators.
 func (c *mergeIterator) Seek(t int64) bool {
+	ok := c.seek(t)
+	c.stale = ok && c.atStale()
+	return ok
+}
+
+func (c *mergeIterator) seek(t int64) bool {
+	if len(c.h) > 0 {
+		if ct, _ := c.h[0].At(); t > ct {
+			for len(c.h) > 0 {
+				if it, _ := c.h[0].At(); it >= t {
+					break
+				}
+				if c.h[0].Seek(t) {
+					heap.Fix(&c.h, 0)
+				} else {
+					heap.Pop(&c.h)
+				}
+			}
+			return len(c.h) > 0
+		}
+	}
+
 	c.h = seriesIteratorHeap{}
 	for _, iter := range c.iterators {
 		if iter.Seek(t) {
@@ -417,8 +954,15 @@ func (c *mergeIterator) At() (t int64, v float64) {
 		panic("mergeIterator.At() called after .Next() returned false.")
 	}
 
-	// TODO do I need to dedupe or just merge?
-	return c.h[0].At()
+	t, v = c.h[0].At()
+	if value.IsStaleNaN(v) {
+		for _, iter := range c.h[1:] {
+			if it, iv := iter.At(); it == t && !value.IsStaleNaN(iv) {
+				return it, iv
+			}
+		}
+	}
+	return t, v
 }
 
 func (c *mergeIterator) Next() bool {
@@ -428,19 +972,37 @@ func (c *mergeIterator) Next() bool {
 				heap.Push(&c.h, iter)
 			}
 		}
-		return len(c.h) > 0
+	} else if len(c.h) > 0 {
+		c.advance()
+	} else {
+		return false
 	}
 
-	if len(c.h) == 0 {
-		return false
+	for c.collapseStale && c.stale && len(c.h) > 0 && c.atStale() {
+		c.advance()
 	}
+	c.stale = len(c.h) > 0 && c.atStale()
+	return len(c.h) > 0
+}
 
-	iter := heap.Pop(&c.h).(SeriesIterator)
-	if iter.Next() {
-		heap.Push(&c.h, iter)
+// advance moves all the iterators at the current time to their next samples.
+func (c *mergeIterator) advance() {
+	t, _ := c.h[0].At()
+	for len(c.h) > 0 {
+		if it, _ := c.h[0].At(); it != t {
+			break
+		}
+		if c.h[0].Next() {
+			heap.Fix(&c.h, 0)
+		} else {
+			heap.Pop(&c.h)
+		}
 	}
+}
 
-	return len(c.h) > 0
+func (c *mergeIterator) atStale() bool {
+	_, v := c.At()
+	return value.IsStaleNaN(v)
 }
 
 func (c *mergeIterator) Err() error {
@@ -476,26 +1038,82 @@ func (h *seriesIteratorHeap) Pop() interface{} {
 }
 
 func (f *Fanout) Appender() (Appender, error) {
-	return &fanoutAppender{
-		appenders:    make(map[string]*appender),
-		localStorage: f.localStorage,
-	}, nil
+	fanoutApp := &fanoutAppender{
+		appenders:     make(map[string]*appender),
+		localStorages: f.localStorages,
+		cluster:       f.cluster,
+	}
+	if vars.Cfg.Gateway != nil && vars.Cfg.Gateway.Appender != nil && vars.Cfg.Gateway.Appender.StrictOrder {
+		fanoutApp.lastAdded = seriesHashMap{}
+	}
+	return fanoutApp, nil
+}
+
+// ErrOutOfOrder is the cause of an *OutOfOrderError.
+var ErrOutOfOrder = errors.New("out of order sample")
+
+// OutOfOrderError is returned by adding a sample earlier than the last one added of the same
+// series before the flush, if the appender is strict about the order.
+type OutOfOrderError struct {
+	Labels []pb.Label
+	T      int64 // timestamp of the sample rejected
+	LastT  int64 // timestamp of the last sample added
+}
+
+func (e *OutOfOrderError) Error() string {
+	return fmt.Sprintf("%v of series %s at %d, earlier than the last one at %d", ErrOutOfOrder, util.ProtoToLabels(e.Labels), e.T, e.LastT)
+}
+
+func (e *OutOfOrderError) Cause() error {
+	return ErrOutOfOrder
 }
 
 type fanoutAppender struct {
-	appenders    map[string]*appender
-	localStorage *storage.Storage
+	appenders     map[string]*appender
+	localStorages localStorages
+	cluster       cluster
+	lastAdded     seriesHashMap // the last sample added of every series till the flush, nil if the order isn't checked
 }
 
+// Add sorts a copy of l by name before routing it, so that a series is routed to the same shard
+// whatever the order its labels are given in, hash is computed again if l is reordered.
+// A series breaking the label limits is rejected before being routed, so is a sample out of order
+// if the appender is strict about the order.
 func (fanoutApp *fanoutAppender) Add(l []pb.Label, t int64, v float64, hash uint64) error {
-	shardID, err := meta.Router().GetShardIDByLabels(time.Time(t), l, hash)
+	return fanoutApp.AddWithExemplar(l, t, v, hash, nil)
+}
+
+// AddWithExemplar is Add, the exemplar is routed with the sample to the shard of its series.
+func (fanoutApp *fanoutAppender) AddWithExemplar(l []pb.Label, t int64, v float64, hash uint64, e *pb.Exemplar) error {
+	// the labels are kept till the flush, so a copy is sorted and kept rather than the caller's slice
+	l = append([]pb.Label(nil), l...)
+
+	reordered, err := util.SortLabels(l)
+	if err != nil {
+		return err
+	}
+	if err = checkLabelLimit(l); err != nil {
+		return err
+	}
+	if reordered {
+		hash = util.HashLabels(l)
+	}
+
+	var last *pb.Series
+	if fanoutApp.lastAdded != nil {
+		if last = fanoutApp.lastAdded.get(hash, l); last != nil && t < last.Points[0].T {
+			return &OutOfOrderError{Labels: l, T: t, LastT: last.Points[0].T}
+		}
+	}
+
+	shardID, err := fanoutApp.cluster.shardIDByLabels(time.Time(t), l, hash)
 	if err != nil {
 		return err
 	}
 
 	app, found := fanoutApp.appenders[shardID]
 	if !found {
-		app, err = newAppender(shardID, fanoutApp.localStorage)
+		app, err = newAppender(shardID, fanoutApp.localStorages.of(shardID))
 		if err != nil {
 			return err
 		}
@@ -503,14 +1121,107 @@ func (fanoutApp *fanoutAppender) Add(l []pb.Label, t int64, v float64, hash uint
 		fanoutApp.appenders[shardID] = app
 	}
 
-	return app.Add(l, t, v, hash)
+	if err = app.add(l, pb.Point{T: t, V: v, E: e}, hash); err != nil {
+		return err
+	}
+
+	if last != nil {
+		last.Points[0].T = t
+	} else if fanoutApp.lastAdded != nil {
+		fanoutApp.lastAdded.set(hash, &pb.Series{Labels: l, Points: []pb.Point{{T: t}}})
+	}
+	return nil
 }
 
+// Flush sends the samples added to the shards at the ack level of the config.
 func (fanoutApp *fanoutAppender) Flush() error {
+	return fanoutApp.FlushWithResult().Err()
+}
+
+// FlushWithResult is Flush, but tells the outcome of every shard rather than the errors only.
+func (fanoutApp *fanoutAppender) FlushWithResult() *FlushResult {
+	return fanoutApp.flush(func(app *appender) error {
+		return app.flushLocked(app.ack)
+	})
+}
+
+// FlushWithAck is Flush, but waits for ack replicas of every shard to apply the samples, see ParseAckLevel.
+func (fanoutApp *fanoutAppender) FlushWithAck(ack backendpb.AckLevel) error {
+	return fanoutApp.flush(func(app *appender) error {
+		return app.flushLocked(ack)
+	}).Err()
+}
+
+// flush calls flush with the mutex of every appender having samples buffered held.
+func (fanoutApp *fanoutAppender) flush(flush func(app *appender) error) *FlushResult {
+	for hash := range fanoutApp.lastAdded {
+		fanoutApp.lastAdded.del(hash)
+	}
+
+	result := new(FlushResult)
+	for shardID, app := range fanoutApp.appenders {
+		// the batches queued of a shard are replayed by the next batch flushed to it, or in the background.
+		app.mtx.Lock()
+		if len(app.series) == 0 {
+			app.mtx.Unlock()
+			continue
+		}
+
+		shard := ShardFlush{ShardID: shardID}
+		shard.MinT, shard.MaxT, shard.Samples = app.series.span()
+		shard.Err = flush(app)
+		app.mtx.Unlock()
+		result.Shards = append(result.Shards, shard)
+	}
+	sort.Slice(result.Shards, func(i, j int) bool {
+		return result.Shards[i].ShardID < result.Shards[j].ShardID
+	})
+	return result
+}
+
+// FlushResult is the outcome of a flush of every shard the samples are routed to, so that the
+// samples of the shards failed can be retried alone rather than the whole batch.
+type FlushResult struct {
+	Shards []ShardFlush // sorted by shard id
+}
+
+// ShardFlush is the outcome of flushing the samples of a shard.
+type ShardFlush struct {
+	ShardID string
+	MinT    int64 // the earliest timestamp of the samples
+	MaxT    int64 // the latest timestamp of the samples
+	Samples int
+	Err     error // nil if the shard applied the samples
+}
+
+// Succeeded returns the ids of the shards which applied their samples.
+func (r *FlushResult) Succeeded() []string {
+	var shardIDs []string
+	for _, shard := range r.Shards {
+		if shard.Err == nil {
+			shardIDs = append(shardIDs, shard.ShardID)
+		}
+	}
+	return shardIDs
+}
+
+// Failed returns the ids of the shards which failed to apply their samples.
+func (r *FlushResult) Failed() []string {
+	var shardIDs []string
+	for _, shard := range r.Shards {
+		if shard.Err != nil {
+			shardIDs = append(shardIDs, shard.ShardID)
+		}
+	}
+	return shardIDs
+}
+
+// Err returns the errors of the shards failed, nil if none failed.
+func (r *FlushResult) Err() error {
 	var multiErr error
-	for _, app := range fanoutApp.appenders {
-		if err := app.Flush(); err != nil {
-			multiErr = multierror.Append(multiErr, err)
+	for _, shard := range r.Shards {
+		if shard.Err != nil {
+			multiErr = multierror.Append(multiErr, errors.Wrapf(shard.Err, "shard %s", shard.ShardID))
 		}
 	}
 	return multiErr
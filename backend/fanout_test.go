@@ -0,0 +1,1027 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/backend/storage"
+	"github.com/baudtime/baudtime/meta"
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/util/toml"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/tsdb"
+)
+
+// fakeCluster routes, reports and asks the shards by its funcs, the meta of the cluster for the ones not set.
+type fakeCluster struct {
+	metaCluster
+	byLabels   func(t time.Time, l []pb.Label, hash uint64) (string, error)
+	byMetric   func(matchers ...*labels.Matcher) ([]string, error)
+	byTimeSpan func(from, to time.Time, matchers ...*labels.Matcher) ([]string, error)
+	latest     func(shardID string) (int64, bool)
+	seriesOf   func(shardID string) uint64
+	newClient  func(shardID string, localStorage *storage.Storage) Client
+}
+
+func (c *fakeCluster) shardIDByLabels(t time.Time, l []pb.Label, hash uint64) (string, error) {
+	if c.byLabels == nil {
+		return c.metaCluster.shardIDByLabels(t, l, hash)
+	}
+	return c.byLabels(t, l, hash)
+}
+
+func (c *fakeCluster) shardIDsByMetric(matchers ...*labels.Matcher) ([]string, error) {
+	if c.byMetric == nil {
+		return c.metaCluster.shardIDsByMetric(matchers...)
+	}
+	return c.byMetric(matchers...)
+}
+
+func (c *fakeCluster) shardIDsByTimeSpan(from, to time.Time, matchers ...*labels.Matcher) ([]string, error) {
+	if c.byTimeSpan == nil {
+		return c.metaCluster.shardIDsByTimeSpan(from, to, matchers...)
+	}
+	return c.byTimeSpan(from, to, matchers...)
+}
+
+func (c *fakeCluster) latestTime(shardID string) (int64, bool) {
+	if c.latest == nil {
+		return c.metaCluster.latestTime(shardID)
+	}
+	return c.latest(shardID)
+}
+
+func (c *fakeCluster) series(shardID string) uint64 {
+	if c.seriesOf == nil {
+		return c.metaCluster.series(shardID)
+	}
+	return c.seriesOf(shardID)
+}
+
+func (c *fakeCluster) client(shardID string, localStorage *storage.Storage) Client {
+	if c.newClient == nil {
+		return c.metaCluster.client(shardID, localStorage)
+	}
+	return c.newClient(shardID, localStorage)
+}
+
+// fakeQuerier implements Querier with canned results.
+type fakeQuerier struct {
+	block  chan struct{}
+	set    SeriesSet
+	values []string
+	names  []string
+	err    error
+}
+
+func (q *fakeQuerier) Select(*SelectParams, ...*labels.Matcher) (SeriesSet, Warnings, error) {
+	if q.block != nil {
+		<-q.block
+	}
+	if q.err != nil {
+		return nil, nil, q.err
+	}
+	if q.set == nil {
+		return EmptySeriesSet(), nil, nil
+	}
+	return q.set, nil, nil
+}
+
+func (q *fakeQuerier) LabelValues(string, ...*labels.Matcher) ([]string, error) {
+	return q.values, q.err
+}
+
+func (q *fakeQuerier) LabelNames() ([]string, error) {
+	return q.names, q.err
+}
+
+func (q *fakeQuerier) Close() error {
+	return nil
+}
+
+func TestMergeQuerier_LabelNames(t *testing.T) {
+	q := NewMergeQuerier(context.Background(), []Querier{
+		&fakeQuerier{names: []string{"__name__", "instance", "job"}},
+		&fakeQuerier{},
+		&fakeQuerier{names: []string{"__name__", "idc", "job"}},
+	})
+
+	names, err := q.LabelNames()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"__name__", "idc", "instance", "job"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("want %v, got %v", want, names)
+	}
+
+	q = NewMergeQuerier(context.Background(), []Querier{
+		&fakeQuerier{names: []string{"__name__"}},
+		&fakeQuerier{err: errors.New("shard down")},
+	})
+
+	if _, err = q.LabelNames(); err == nil {
+		t.Fatalf("expected error from failed shard")
+	}
+}
+
+func TestMergeQuerier_LabelValuesUnsorted(t *testing.T) {
+	tests := []struct {
+		shards [][]string
+		want   []string
+	}{
+		{
+			shards: [][]string{{"c", "a", "b"}, {"d", "b", "a"}, {"e", "c"}},
+			want:   []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			shards: [][]string{{"b", "a", "b", "a"}, {"a"}},
+			want:   []string{"a", "b"},
+		},
+		{
+			shards: [][]string{{"a", "a", "c"}, {}, {"c", "b"}, {"b"}},
+			want:   []string{"a", "b", "c"},
+		},
+	}
+
+	for i, test := range tests {
+		var queriers []Querier
+		for _, values := range test.shards {
+			queriers = append(queriers, &fakeQuerier{values: values})
+		}
+
+		values, err := NewMergeQuerier(context.Background(), queriers).LabelValues("instance")
+		if err != nil {
+			t.Fatalf("case %d: unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(values, test.want) {
+			t.Fatalf("case %d: want %v, got %v", i, test.want, values)
+		}
+	}
+
+	shard := []string{"b", "a", "b"}
+	sortedUnique(shard)
+	if !reflect.DeepEqual(shard, []string{"b", "a", "b"}) {
+		t.Fatalf("the values of the shard are modified: %v", shard)
+	}
+}
+
+// pagedQuerier is a shard returning the page of its sorted values asked by ctx.
+type pagedQuerier struct {
+	fakeQuerier
+	ctx context.Context
+}
+
+func (q *pagedQuerier) LabelValues(string, ...*labels.Matcher) ([]string, error) {
+	if page, ok := labelValuesPageFromContext(q.ctx); ok {
+		return page.Cut(q.values), nil
+	}
+	return q.values, nil
+}
+
+func TestMergeQuerier_LabelValuesPaged(t *testing.T) {
+	shards := [][]string{{"a", "c", "e", "g"}, {"b", "c", "d"}, {"a", "f", "g", "h"}, {}}
+
+	var (
+		got   []string
+		pages int
+		after string
+	)
+	for {
+		ctx := WithLabelValuesPage(context.Background(), LabelValuesPage{After: after, Limit: 3})
+
+		var queriers []Querier
+		for _, values := range shards {
+			queriers = append(queriers, &pagedQuerier{fakeQuerier: fakeQuerier{values: values}, ctx: ctx})
+		}
+
+		values, err := NewMergeQuerier(ctx, queriers).LabelValues("instance")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(values) > 3 {
+			t.Fatalf("page %d exceeds the limit: %v", pages, values)
+		}
+		if len(values) == 0 {
+			break
+		}
+		got = append(got, values...)
+		after = values[len(values)-1]
+		pages++
+	}
+
+	if want := []string{"a", "b", "c", "d", "e", "f", "g", "h"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	if pages != 3 {
+		t.Fatalf("want 3 pages, got %d", pages)
+	}
+}
+
+func TestMergeQuerier_SelectCancel(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := NewMergeQuerier(ctx, []Querier{
+		&fakeQuerier{},
+		&fakeQuerier{block: block},
+	})
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, _, err := q.Select(&SelectParams{})
+	if err != context.Canceled {
+		t.Fatalf("want %v, got %v", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("select returned after %v, should be abandoned on cancel", elapsed)
+	}
+}
+
+func TestMergeQuerier_SelectPartialResponse(t *testing.T) {
+	set := &concreteSeriesSet{series: []Series{
+		&concreteSeries{labels: labels.FromStrings("__name__", "up", "instance", "a")},
+	}}
+
+	for _, partial := range []bool{false, true} {
+		q := &mergeQuerier{
+			ctx: context.Background(),
+			queriers: []Querier{
+				&fakeQuerier{set: set},
+				&fakeQuerier{err: errors.New("shard timeout")},
+			},
+			partialResponse: partial,
+		}
+		set.cur = 0
+
+		ss, warnings, err := q.Select(&SelectParams{})
+		if !partial {
+			if err == nil {
+				t.Fatalf("expected error without partial response")
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("unexpected error with partial response: %v", err)
+		}
+		if len(warnings) != 1 {
+			t.Fatalf("want 1 warning, got %v", warnings)
+		}
+		if !ss.Next() || ss.At().Labels().Get("instance") != "a" || ss.Next() {
+			t.Fatalf("unexpected series set from the responded shard")
+		}
+	}
+}
+
+// closingQuerier counts the closes of itself and of the series set it returns, which may come with an error.
+type closingQuerier struct {
+	fakeQuerier
+	closes    int32
+	setCloses int32
+}
+
+type closingSeriesSet struct {
+	SeriesSet
+	closes *int32
+}
+
+func (s *closingSeriesSet) Close() error {
+	atomic.AddInt32(s.closes, 1)
+	return nil
+}
+
+func (q *closingQuerier) Select(*SelectParams, ...*labels.Matcher) (SeriesSet, Warnings, error) {
+	if q.block != nil {
+		<-q.block
+	}
+	return &closingSeriesSet{SeriesSet: EmptySeriesSet(), closes: &q.setCloses}, nil, q.err
+}
+
+func (q *closingQuerier) Close() error {
+	atomic.AddInt32(&q.closes, 1)
+	return nil
+}
+
+func TestMergeQuerier_SelectCloseOnError(t *testing.T) {
+	assertClosed := func(queriers []*closingQuerier) {
+		for i, q := range queriers {
+			if closes, setCloses := atomic.LoadInt32(&q.closes), atomic.LoadInt32(&q.setCloses); closes != 1 || setCloses != 1 {
+				t.Fatalf("querier %d: want it and its set closed once, got %d and %d closes", i, closes, setCloses)
+			}
+		}
+	}
+
+	// one shard fails
+	queriers := []*closingQuerier{{}, {fakeQuerier: fakeQuerier{err: errors.New("shard down")}}, {}}
+	q := &mergeQuerier{ctx: context.Background()}
+	for _, querier := range queriers {
+		q.queriers = append(q.queriers, querier)
+	}
+	if _, _, err := q.Select(&SelectParams{}); err == nil {
+		t.Fatalf("expected error from the failed shard")
+	}
+	assertClosed(queriers)
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertClosed(queriers) // not closed twice
+
+	// the query is canceled while a shard is outstanding
+	block := make(chan struct{})
+	queriers = []*closingQuerier{{}, {fakeQuerier: fakeQuerier{block: block}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	q = &mergeQuerier{ctx: ctx}
+	for _, querier := range queriers {
+		q.queriers = append(q.queriers, querier)
+	}
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, _, err := q.Select(&SelectParams{}); err != context.Canceled {
+		t.Fatalf("want %v, got %v", context.Canceled, err)
+	}
+	close(block)
+	for deadline := time.Now().Add(5 * time.Second); atomic.LoadInt32(&queriers[1].closes) == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	assertClosed(queriers)
+}
+
+func TestMergeQuerier_SelectAggregated(t *testing.T) {
+	stale := math.Float64frombits(value.StaleNaN)
+	partial := func(job string, points ...pb.Point) Series {
+		return &concreteSeries{labels: labels.FromStrings("job", job), samples: points}
+	}
+
+	q := &mergeQuerier{
+		ctx: context.Background(),
+		queriers: []Querier{
+			&fakeQuerier{set: &concreteSeriesSet{series: []Series{
+				partial("a", pb.Point{T: 0, V: 1}, pb.Point{T: 15, V: 2}, pb.Point{T: 30, V: stale}),
+				partial("b", pb.Point{T: 0, V: 5}),
+			}}},
+			&fakeQuerier{set: &concreteSeriesSet{series: []Series{
+				partial("a", pb.Point{T: 0, V: 10}, pb.Point{T: 15, V: stale}, pb.Point{T: 30, V: 3}, pb.Point{T: 45, V: stale}),
+			}}},
+		},
+	}
+
+	set, _, err := q.Select(&SelectParams{Aggregation: &backendpb.Aggregation{Op: backendpb.AggrOp_AggrSum, Grouping: []string{"job"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]pb.Point{
+		"a": {{T: 0, V: 11}, {T: 15, V: 2}, {T: 30, V: 3}, {T: 45, V: stale}},
+		"b": {{T: 0, V: 5}},
+	}
+
+	n := 0
+	for ; set.Next(); n++ {
+		s := set.At()
+		job := s.Labels().Get("job")
+
+		var got []pb.Point
+		for it := s.Iterator(); it.Next(); {
+			t, v := it.At()
+			got = append(got, pb.Point{T: t, V: v})
+		}
+
+		if len(got) != len(want[job]) {
+			t.Fatalf("job %s: want %v, got %v", job, want[job], got)
+		}
+		for i, p := range want[job] {
+			if got[i].T != p.T || math.Float64bits(got[i].V) != math.Float64bits(p.V) {
+				t.Fatalf("job %s: want %v, got %v", job, want[job], got)
+			}
+		}
+	}
+	if err = set.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("want 2 series, got %d", n)
+	}
+}
+
+// goroutineQuerier records the peak number of goroutines while selecting.
+type goroutineQuerier struct {
+	peak *int64
+}
+
+func (q *goroutineQuerier) Select(*SelectParams, ...*labels.Matcher) (SeriesSet, Warnings, error) {
+	n := int64(runtime.NumGoroutine())
+	for {
+		peak := atomic.LoadInt64(q.peak)
+		if n <= peak || atomic.CompareAndSwapInt64(q.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	return EmptySeriesSet(), nil, nil
+}
+
+func (q *goroutineQuerier) LabelValues(string, ...*labels.Matcher) ([]string, error) { return nil, nil }
+func (q *goroutineQuerier) LabelNames() ([]string, error)                            { return nil, nil }
+func (q *goroutineQuerier) Close() error                                             { return nil }
+
+func BenchmarkMergeQuerier_Select(b *testing.B) {
+	const shardNum = 256
+
+	for _, concurrency := range []int{0, 16} {
+		b.Run(fmt.Sprintf("max_concurrency=%d", concurrency), func(b *testing.B) {
+			var peak int64
+
+			queriers := make([]Querier, shardNum)
+			for i := range queriers {
+				queriers[i] = &goroutineQuerier{peak: &peak}
+			}
+			q := &mergeQuerier{ctx: context.Background(), queriers: queriers, concurrency: concurrency}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := q.Select(&SelectParams{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			b.Logf("peak goroutines: %d", atomic.LoadInt64(&peak))
+		})
+	}
+}
+
+func TestMergeSeriesSet_CompareByLabel(t *testing.T) {
+	series := func(instance, job string, points ...pb.Point) Series {
+		return &concreteSeries{labels: labels.FromStrings("instance", instance, "job", job), samples: points}
+	}
+
+	// sorted by the full label sets as the shards answer, they're emitted by job and then the full label sets
+	set := NewMergeSeriesSetWithCompare([]SeriesSet{
+		&concreteSeriesSet{series: []Series{
+			series("a", "z", pb.Point{T: 0, V: 3}),
+			series("b", "x", pb.Point{T: 0, V: 1}),
+			series("c", "y", pb.Point{T: 0, V: 2}),
+		}},
+		&concreteSeriesSet{series: []Series{
+			series("a", "y", pb.Point{T: 0, V: 5}),
+			series("a", "z", pb.Point{T: 15, V: 6}),
+			series("c", "x", pb.Point{T: 0, V: 4}),
+		}},
+		&concreteSeriesSet{series: []Series{
+			series("b", "x", pb.Point{T: 15, V: 8}),
+			series("d", "a", pb.Point{T: 0, V: 7}),
+		}},
+	}, CompareByLabel("job"))
+
+	want := []struct {
+		instance, job string
+		points        int
+	}{
+		{"d", "a", 1},
+		{"b", "x", 2},
+		{"c", "x", 1},
+		{"a", "y", 1},
+		{"c", "y", 1},
+		{"a", "z", 2},
+	}
+
+	n := 0
+	for ; set.Next(); n++ {
+		if n >= len(want) {
+			t.Fatalf("too many series: %v", set.At().Labels())
+		}
+		s := set.At()
+		if s.Labels().Get("instance") != want[n].instance || s.Labels().Get("job") != want[n].job {
+			t.Fatalf("series %d: want instance %s job %s, got %v", n, want[n].instance, want[n].job, s.Labels())
+		}
+
+		points := 0
+		for it := s.Iterator(); it.Next(); {
+			points++
+		}
+		if points != want[n].points {
+			t.Fatalf("series %d: want %d points, got %d", n, want[n].points, points)
+		}
+	}
+	if err := set.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != len(want) {
+		t.Fatalf("want %d series, got %d", len(want), n)
+	}
+}
+
+func TestMergeSeriesSet_PreAdvanceError(t *testing.T) {
+	shardErr := errors.New("shard down")
+	newSets := func() []SeriesSet {
+		return []SeriesSet{
+			&concreteSeriesSet{series: []Series{
+				&concreteSeries{labels: labels.FromStrings("instance", "a")},
+			}},
+			errSeriesSet{err: shardErr},
+			&concreteSeriesSet{series: []Series{
+				&concreteSeries{labels: labels.FromStrings("instance", "b")},
+			}},
+		}
+	}
+
+	set := NewMergeSeriesSet(newSets())
+	if set.Next() {
+		t.Fatalf("expected no series after a set failed, got %v", set.At().Labels())
+	}
+	if err := set.Err(); err != shardErr {
+		t.Fatalf("want error %v, got %v", shardErr, err)
+	}
+
+	set, warnings := newMergeSeriesSet(newSets(), labels.Compare, true)
+	if len(warnings) != 1 {
+		t.Fatalf("want 1 warning, got %v", warnings)
+	}
+	var instances []string
+	for set.Next() {
+		instances = append(instances, set.At().Labels().Get("instance"))
+	}
+	if err := set.Err(); err != nil {
+		t.Fatalf("unexpected error with partial sets: %v", err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(instances, want) {
+		t.Fatalf("want %v, got %v", want, instances)
+	}
+
+	set, warnings = newMergeSeriesSet([]SeriesSet{errSeriesSet{err: shardErr}, errSeriesSet{err: shardErr}}, labels.Compare, true)
+	if set.Next() || set.Err() == nil || len(warnings) != 0 {
+		t.Fatalf("expected error when all the sets failed")
+	}
+}
+
+func TestMergeIterator_Seek(t *testing.T) {
+	newIterator := func() SeriesIterator {
+		return newMergeIterator([]SeriesIterator{
+			newConcreteSeriersIterator(&concreteSeries{samples: []pb.Point{{T: 0, V: 0}, {T: 30, V: 3}, {T: 60, V: 6}}}),
+			newConcreteSeriersIterator(&concreteSeries{samples: []pb.Point{{T: 10, V: 1}, {T: 40, V: 4}}}),
+			newConcreteSeriersIterator(&concreteSeries{samples: []pb.Point{{T: 20, V: 2}, {T: 50, V: 5}, {T: 70, V: 7}}}),
+		})
+	}
+
+	tests := []struct {
+		seeks []int64
+		want  []int64 // -1 if the seek returns false
+	}{
+		{seeks: []int64{0, 5, 25, 25, 41, 65, 70, 71}, want: []int64{0, 10, 30, 30, 50, 70, 70, -1}},
+		{seeks: []int64{45, 15, 35, 0}, want: []int64{50, 20, 40, 0}},
+		{seeks: []int64{80, 55, 100, 10}, want: []int64{-1, 60, -1, 10}},
+	}
+
+	for i, test := range tests {
+		it := newIterator()
+		for j, seek := range test.seeks {
+			got := int64(-1)
+			if it.Seek(seek) {
+				got, _ = it.At()
+			}
+			if got != test.want[j] {
+				t.Fatalf("case %d: seek %d, want %d, got %d", i, seek, test.want[j], got)
+			}
+		}
+	}
+
+	// next after seeks goes on from where the seek landed
+	it := newIterator()
+	if !it.Seek(15) || !it.Seek(35) {
+		t.Fatalf("unexpected seek failure")
+	}
+	var got []int64
+	for ok := true; ok; ok = it.Next() {
+		ts, _ := it.At()
+		got = append(got, ts)
+	}
+	if want := []int64{40, 50, 60, 70}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestMergeIterator_Staleness(t *testing.T) {
+	stale := math.Float64frombits(value.StaleNaN)
+	newIterator := func(collapseStale bool) SeriesIterator {
+		it := newMergeIterator([]SeriesIterator{
+			newConcreteSeriersIterator(&concreteSeries{samples: []pb.Point{{T: 0, V: 1}, {T: 15, V: stale}, {T: 30, V: stale}, {T: 45, V: stale}, {T: 60, V: 6}}}),
+			newConcreteSeriersIterator(&concreteSeries{samples: []pb.Point{{T: 0, V: 1}, {T: 15, V: 2}, {T: 30, V: stale}}}),
+			newConcreteSeriersIterator(&concreteSeries{samples: []pb.Point{{T: 15, V: stale}, {T: 45, V: stale}, {T: 75, V: stale}}}),
+		}).(*mergeIterator)
+		it.collapseStale = collapseStale
+		return it
+	}
+
+	tests := []struct {
+		collapseStale bool
+		want          []pb.Point
+	}{
+		{
+			collapseStale: false,
+			want:          []pb.Point{{T: 0, V: 1}, {T: 15, V: 2}, {T: 30, V: stale}, {T: 45, V: stale}, {T: 60, V: 6}, {T: 75, V: stale}},
+		},
+		{
+			collapseStale: true,
+			want:          []pb.Point{{T: 0, V: 1}, {T: 15, V: 2}, {T: 30, V: stale}, {T: 60, V: 6}, {T: 75, V: stale}},
+		},
+	}
+
+	for _, test := range tests {
+		var got []pb.Point
+		for it := newIterator(test.collapseStale); it.Next(); {
+			ts, v := it.At()
+			got = append(got, pb.Point{T: ts, V: v})
+		}
+
+		if len(got) != len(test.want) {
+			t.Fatalf("collapse %v: want %v, got %v", test.collapseStale, test.want, got)
+		}
+		for i, p := range test.want {
+			if got[i].T != p.T || math.Float64bits(got[i].V) != math.Float64bits(p.V) {
+				t.Fatalf("collapse %v: want %v, got %v", test.collapseStale, test.want, got)
+			}
+		}
+	}
+
+	// the real value wins over the staleness marker at the time sought too
+	it := newIterator(false)
+	if !it.Seek(10) {
+		t.Fatalf("unexpected seek failure")
+	}
+	if ts, v := it.At(); ts != 15 || v != 2 {
+		t.Fatalf("want 2 at 15, got %v at %d", v, ts)
+	}
+}
+
+func BenchmarkMergeIterator_Seek(b *testing.B) {
+	const (
+		iteratorNum = 64
+		sampleNum   = 1000
+		step        = 15
+	)
+
+	series := make([]*concreteSeries, iteratorNum)
+	for i := range series {
+		samples := make([]pb.Point, 0, sampleNum)
+		for j := 0; j < sampleNum; j++ {
+			samples = append(samples, pb.Point{T: int64(j*iteratorNum+i) * step, V: float64(j)})
+		}
+		series[i] = &concreteSeries{samples: samples}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		iterators := make([]SeriesIterator, iteratorNum)
+		for j, s := range series {
+			iterators[j] = newConcreteSeriersIterator(s)
+		}
+		it := newMergeIterator(iterators)
+		for t := int64(0); it.Seek(t); t += step * iteratorNum / 4 {
+		}
+	}
+}
+
+func TestFanoutQuerier_LabelValuesShardIDs(t *testing.T) {
+	var from, to time.Time
+	cluster := &fakeCluster{
+		byMetric: func(...*labels.Matcher) ([]string, error) {
+			return []string{"shard-1", "shard-2", "shard-3"}, nil
+		},
+		byTimeSpan: func(f, t time.Time, _ ...*labels.Matcher) ([]string, error) {
+			from, to = f, t
+			return []string{"shard-2"}, nil
+		},
+	}
+
+	matcher, err := labels.NewMatcher(labels.MatchEqual, labels.MetricName, "up")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		mint, maxt int64
+		want       int
+	}{
+		{mint: 1560000000000, maxt: 1560003600000, want: 1},
+		{mint: math.MinInt64, maxt: math.MaxInt64, want: 3},
+		{mint: 1560000000000, maxt: math.MaxInt64, want: 3},
+	}
+
+	for _, test := range tests {
+		q := &fanoutQuerier{ctx: context.Background(), mint: test.mint, maxt: test.maxt, cluster: cluster}
+		shardIDs, err := q.labelValuesShardIDs(matcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(shardIDs) != test.want {
+			t.Fatalf("[%d, %d]: want %d shards, got %v", test.mint, test.maxt, test.want, shardIDs)
+		}
+	}
+
+	if from.UnixNano()/int64(time.Millisecond) != 1560000000000 || to.UnixNano()/int64(time.Millisecond) != 1560003600000 {
+		t.Fatalf("unexpected time span [%v, %v]", from, to)
+	}
+}
+
+func TestFanoutQuerier_NoShard(t *testing.T) {
+	cluster := &fakeCluster{
+		byMetric: func(...*labels.Matcher) ([]string, error) {
+			return nil, nil
+		},
+		byTimeSpan: func(time.Time, time.Time, ...*labels.Matcher) ([]string, error) {
+			return []string{"", ""}, nil
+		},
+	}
+
+	matcher, err := labels.NewMatcher(labels.MatchEqual, labels.MetricName, "up")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := &fanoutQuerier{ctx: context.Background(), mint: 1560000000000, maxt: 1560003600000, cluster: cluster}
+	set, warnings, err := q.Select(&SelectParams{}, matcher)
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("unexpected error %v or warnings %v", err, warnings)
+	}
+	if set == nil || set.Next() || set.Err() != nil {
+		t.Fatalf("expected an empty series set")
+	}
+	if q.Querier != nil {
+		t.Fatalf("expected no querier built without a shard")
+	}
+
+	for _, q := range []*fanoutQuerier{
+		{ctx: context.Background(), mint: 1560000000000, maxt: 1560003600000, cluster: cluster},
+		{ctx: context.Background(), mint: math.MinInt64, maxt: math.MaxInt64, cluster: cluster},
+	} {
+		values, err := q.LabelValues("instance", matcher)
+		if err != nil || len(values) != 0 {
+			t.Fatalf("unexpected values %v or error %v", values, err)
+		}
+		if q.Querier != nil {
+			t.Fatalf("expected no querier built without a shard")
+		}
+	}
+}
+
+func TestFanoutQuerier_UnsatisfiableMatchers(t *testing.T) {
+	var routed []*labels.Matcher
+	cluster := &fakeCluster{byTimeSpan: func(_, _ time.Time, matchers ...*labels.Matcher) ([]string, error) {
+		routed = matchers
+		return nil, nil
+	}}
+
+	up, err := labels.NewMatcher(labels.MatchEqual, labels.MetricName, "up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := labels.NewMatcher(labels.MatchEqual, "job", "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mysql, err := labels.NewMatcher(labels.MatchEqual, "job", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := &fanoutQuerier{ctx: context.Background(), mint: 1560000000000, maxt: 1560003600000, cluster: cluster}
+	set, _, err := q.Select(&SelectParams{}, up, node, mysql)
+	if err != nil || set.Next() {
+		t.Fatalf("want an empty series set, got error %v", err)
+	}
+	if routed != nil {
+		t.Fatalf("expected an unsatisfiable select not routed")
+	}
+
+	if _, _, err = q.Select(&SelectParams{}, up, node, node); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(routed, []*labels.Matcher{up, node}) {
+		t.Fatalf("want the duplicate matcher dropped before routing, got %v", routed)
+	}
+}
+
+func TestShardsStartTime(t *testing.T) {
+	shards := map[string]*meta.Shard{
+		"1": {Master: &meta.Node{ShardID: "1", MinT: 1560000000000}},
+		"2": {Master: &meta.Node{ShardID: "2", MinT: 1550000000000}, Slaves: []*meta.Node{{ShardID: "2", MinT: 1540000000000}}},
+	}
+	if got := shardsStartTime(shards); got != 1550000000000 {
+		t.Fatalf("want %d, got %d", int64(1550000000000), got)
+	}
+
+	shards["3"] = &meta.Shard{Slaves: []*meta.Node{{ShardID: "3"}}}
+	if got := shardsStartTime(shards); got != int64(model.Earliest) {
+		t.Fatalf("node without min time reported should be treated as earliest, got %d", got)
+	}
+
+	if got := shardsStartTime(nil); got != int64(model.Latest) {
+		t.Fatalf("want %d for no shard, got %d", int64(model.Latest), got)
+	}
+}
+
+func TestFanoutQuerier_SnapshotRead(t *testing.T) {
+	gateway, storageCfg := vars.Cfg.Gateway, vars.Cfg.Storage
+	defer func() {
+		vars.Cfg.Gateway, vars.Cfg.Storage = gateway, storageCfg
+	}()
+	vars.Cfg.Storage = &vars.StorageConfig{TSDB: vars.TSDBConfig{LookbackDelta: toml.Duration(time.Second)}}
+
+	// shard-2 has taken more of an ongoing ingest than shard-1 has
+	latest := map[string]int64{"shard-1": 2000, "shard-2": 5000}
+	clients := make(map[string]Client)
+	for shardID, maxt := range latest {
+		dir, err := ioutil.TempDir("", "snapshotread")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		db, err := tsdb.Open(dir, nil, nil, &tsdb.Options{BlockRanges: []int64{7200000}, NoLockfile: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		c := storageClient{storage: storage.New(db)}
+		s := &pb.Series{Labels: []pb.Label{{Name: "__name__", Value: "up"}, {Name: "shard", Value: shardID}}}
+		for ts := int64(1000); ts <= maxt; ts += 1000 {
+			s.Points = append(s.Points, pb.Point{T: ts, V: 1})
+		}
+		if err = c.Add(context.Background(), &backendpb.AddRequest{Series: []*pb.Series{s}}); err != nil {
+			t.Fatal(err)
+		}
+		clients[shardID] = c
+	}
+
+	cluster := &fakeCluster{
+		byTimeSpan: func(time.Time, time.Time, ...*labels.Matcher) ([]string, error) {
+			return []string{"shard-1", "shard-2"}, nil
+		},
+		latest: func(shardID string) (int64, bool) {
+			maxt, found := latest[shardID]
+			return maxt, found
+		},
+		newClient: func(shardID string, _ *storage.Storage) Client {
+			return clients[shardID]
+		},
+	}
+
+	matcher, err := labels.NewMatcher(labels.MatchEqual, labels.MetricName, "up")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		snapshot bool
+		maxt     int64
+		want     map[string]int64 // shard => the time of the last point
+	}{
+		{snapshot: false, maxt: 10000, want: map[string]int64{"shard-1": 2000, "shard-2": 5000}},
+		// both shards answer as of the latest data of shard-1
+		{snapshot: true, maxt: 10000, want: map[string]int64{"shard-1": 2000, "shard-2": 2000}},
+		// the pinned timestamp is no later than the query's maxt
+		{snapshot: true, maxt: 1500, want: map[string]int64{"shard-1": 1000, "shard-2": 1000}},
+	}
+
+	for i, test := range tests {
+		vars.Cfg.Gateway = &vars.GatewayConfig{Query: vars.QueryConfig{SnapshotRead: test.snapshot}}
+
+		fanout := NewFanout(nil)
+		fanout.cluster = cluster
+		q, err := fanout.Querier(context.Background(), 0, test.maxt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set, _, err := q.Select(&SelectParams{}, matcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := make(map[string]int64)
+		for set.Next() {
+			series := set.At()
+			it := series.Iterator()
+			for it.Next() {
+				ts, _ := it.At()
+				got[series.Labels().Get("shard")] = ts
+			}
+		}
+		if set.Err() != nil {
+			t.Fatal(set.Err())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("case %d: want the last points %v, got %v", i, test.want, got)
+		}
+		q.Close()
+	}
+}
+
+func TestFanout_LocalStorages(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+	vars.Cfg.Gateway = &vars.GatewayConfig{}
+
+	storage1, storage2, single := new(storage.Storage), new(storage.Storage), new(storage.Storage)
+	shardIDs := []string{"shard-1", "shard-2", "shard-3"}
+
+	tests := []struct {
+		fanout *Fanout
+		want   map[string]*storage.Storage // shard => the local storage it's read from and written to
+	}{
+		{
+			fanout: NewFanoutOfStorages(map[string]*storage.Storage{"shard-1": storage1, "shard-2": storage2}),
+			want:   map[string]*storage.Storage{"shard-1": storage1, "shard-2": storage2, "shard-3": nil},
+		},
+		{
+			fanout: NewFanout(single),
+			want:   map[string]*storage.Storage{"shard-1": single, "shard-2": single, "shard-3": single},
+		},
+		{
+			fanout: NewFanout(nil),
+			want:   map[string]*storage.Storage{"shard-1": nil, "shard-2": nil, "shard-3": nil},
+		},
+	}
+
+	matcher, err := labels.NewMatcher(labels.MatchEqual, labels.MetricName, "up")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range tests {
+		read := make(map[string]*storage.Storage)
+		cluster := &fakeCluster{
+			byTimeSpan: func(time.Time, time.Time, ...*labels.Matcher) ([]string, error) {
+				return shardIDs, nil
+			},
+			newClient: func(shardID string, localStorage *storage.Storage) Client {
+				read[shardID] = localStorage
+				return &selectClient{}
+			},
+		}
+		test.fanout.cluster = cluster
+
+		q, err := test.fanout.Querier(context.Background(), 0, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err = q.Select(&SelectParams{}, matcher); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(read, test.want) {
+			t.Fatalf("case %d: want the shards read from the local storages %v, got %v", i, test.want, read)
+		}
+
+		app, err := test.fanout.Appender()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, shardID := range shardIDs {
+			cluster.byLabels = func(time.Time, []pb.Label, uint64) (string, error) {
+				return shardID, nil
+			}
+			if err = app.Add([]pb.Label{{Name: "__name__", Value: "up"}, {Name: "shard", Value: shardID}}, 1000, 1, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for shardID, want := range test.want {
+			written := app.(*fanoutAppender).appenders[shardID].client.(*ShardClient).localStorage
+			if written != want {
+				t.Fatalf("case %d: want %s written to the local storage %p, got %p", i, shardID, want, written)
+			}
+		}
+	}
+}
@@ -16,6 +16,7 @@ package backend
 import (
 	"context"
 	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
 	"github.com/prometheus/prometheus/pkg/labels"
 )
 
@@ -41,25 +42,81 @@ type Queryable interface {
 // Querier provides reading access to time series data.
 type Querier interface {
 	// Select returns a set of series that matches the given label matchers.
-	//Select(*SelectParams, ...*labels.Matcher) (SeriesSet, error)
-	Select(*SelectParams, ...*labels.Matcher) (SeriesSet, error)
+	Select(*SelectParams, ...*labels.Matcher) (SeriesSet, Warnings, error)
 	// LabelValues returns all potential values for a label name.
 	LabelValues(string, ...*labels.Matcher) ([]string, error)
+	// LabelNames returns all the unique label names present in the storage.
+	LabelNames() ([]string, error)
 
 	// Close releases the resources of the Querier.
 	Close() error
 }
 
+// HashQuerier is a Querier which selects a series by its hash rather than by matchers, e.g. to drill
+// down into a series of an aggregated view.
+type HashQuerier interface {
+	Querier
+	// SelectByHash returns the series of metric whose hash is util.HashLabels of its labels sorted by name,
+	// the same as Appender.Add takes. The set is empty if no such series is found.
+	SelectByHash(metric string, hash uint64) (SeriesSet, error)
+}
+
+// Warnings holds the non fatal errors met by a query, e.g. some shards didn't respond.
+type Warnings []error
+
 // Appender provides batched appends against a storage.
 type Appender interface {
+	// Add appends a sample of series l, hash must be util.HashLabels of l sorted by name.
 	Add(l []pb.Label, t int64, v float64, hash uint64) error
 	Flush() error
 }
 
+// AckAppender is an Appender whose flushes may wait for other than the configured ack level.
+type AckAppender interface {
+	Appender
+	FlushWithAck(ack backendpb.AckLevel) error
+}
+
+// ExemplarAppender is an Appender which takes an exemplar with a sample, e.g. the trace it was observed in.
+type ExemplarAppender interface {
+	Appender
+	// AddWithExemplar is Add, e is sent with the sample to its shard if not nil.
+	AddWithExemplar(l []pb.Label, t int64, v float64, hash uint64, e *pb.Exemplar) error
+}
+
+// ResultAppender is an Appender whose flushes tell which shards applied the samples and which failed.
+type ResultAppender interface {
+	Appender
+	FlushWithResult() *FlushResult
+}
+
 // SelectParams specifies parameters passed to data selections.
 type SelectParams struct {
-	Step int64  // Query step size in milliseconds.
-	Func string // String representation of surrounding function or aggregation.
+	Step      int64  // Query step size in milliseconds.
+	Func      string // String representation of surrounding function or aggregation.
+	SlaveRead bool   // Read from the slaves of the shards, falling back to the masters.
+
+	// Aggregation is pushed down to the shards if set, the series selected are the partial
+	// aggregates of the shards, which are merged by the fanout querier.
+	Aggregation *backendpb.Aggregation
+
+	// ValueBounds drops the samples out of it at the scan of the shards if set, so that they don't
+	// cross the wire, nil means no filter.
+	ValueBounds *backendpb.ValueBounds
+
+	// Resolution reduces the samples of the series selected to at most one per bucket of it in milliseconds,
+	// by Downsample. It's meant for long ranges shown in coarse steps, 0 keeps the full resolution.
+	// The remote read of the gateway sets it if asked in the url.
+	Resolution int64
+	Downsample DownsampleFunc
+
+	// Hash selects the series of the hash only, see HashQuerier, 0 selects all the series matched.
+	Hash uint64
+
+	// RawChunks asks the shards for the series in the chunks of their storages, which can be passed through
+	// as they are, see ChunkSeries. It's meant for copying the data of a shard, e.g. to another one or a backup.
+	// ValueBounds doesn't apply to the chunks, neither does Aggregation nor Step.
+	RawChunks bool
 }
 
 // SeriesSet contains a set of series.
@@ -78,6 +135,15 @@ type Series interface {
 	Iterator() SeriesIterator
 }
 
+// ChunkSeries is a Series selected with SelectParams.RawChunks, the chunks of which can be passed through
+// without decoding them, while its iterator decodes the samples of them in the time range selected.
+type ChunkSeries interface {
+	Series
+
+	// Chunks returns the chunks of the series in the xor encoding, which may hold samples out of the time range selected.
+	Chunks() []pb.Chunk
+}
+
 // SeriesIterator iterates over the data of a time series.
 type SeriesIterator interface {
 	// Seek advances the iterator forward to the value at or after
@@ -119,6 +185,16 @@ func (e errSeriesSet) Err() error {
 
 var emptySeriesSet = errSeriesSet{}
 
+// errSeriesIterator implements SeriesIterator, just returning an error.
+type errSeriesIterator struct {
+	err error
+}
+
+func (errSeriesIterator) Seek(t int64) bool    { return false }
+func (errSeriesIterator) At() (int64, float64) { return 0, 0 }
+func (errSeriesIterator) Next() bool           { return false }
+func (e errSeriesIterator) Err() error         { return e.err }
+
 // EmptySeriesSet returns a series set that's always empty.
 func EmptySeriesSet() SeriesSet {
 	return emptySeriesSet
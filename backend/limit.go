@@ -0,0 +1,80 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"fmt"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// checkLabelLimit returns a BadRequest error if the labels of a series break the limits
+// configured, so that a buggy client can't blow up the cardinality of the index.
+func checkLabelLimit(l []pb.Label) error {
+	if vars.Cfg.Gateway == nil {
+		return nil
+	}
+	limit := &vars.Cfg.Gateway.LabelLimit
+
+	if limit.MaxLabelNum > 0 && len(l) > limit.MaxLabelNum {
+		return labelLimitError(l, "%d labels, more than the limit %d", len(l), limit.MaxLabelNum)
+	}
+
+	for _, lbl := range l {
+		if limit.MaxNameLength > 0 && len(lbl.Name) > limit.MaxNameLength {
+			return labelLimitError(l, "label name %q longer than the limit %d", lbl.Name, limit.MaxNameLength)
+		}
+		if limit.MaxValueLength > 0 && len(lbl.Value) > limit.MaxValueLength {
+			return labelLimitError(l, "value of label %q longer than the limit %d", lbl.Name, limit.MaxValueLength)
+		}
+
+		if lbl.Name == labels.MetricName {
+			continue
+		}
+		if len(limit.AllowedNames) > 0 && !contains(limit.AllowedNames, lbl.Name) {
+			return labelLimitError(l, "label name %q not allowed", lbl.Name)
+		}
+		if contains(limit.DeniedNames, lbl.Name) {
+			return labelLimitError(l, "label name %q denied", lbl.Name)
+		}
+	}
+
+	return nil
+}
+
+func labelLimitError(l []pb.Label, format string, args ...interface{}) error {
+	var metricName string
+	for _, lbl := range l {
+		if lbl.Name == labels.MetricName {
+			metricName = lbl.Value
+		}
+	}
+	return &pb.ResponseError{
+		Code:    pb.ErrorCode_BadRequest,
+		Message: fmt.Sprintf("series of %q rejected: ", metricName) + fmt.Sprintf(format, args...),
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/vars"
+)
+
+func TestCheckLabelLimit(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	up := func(lbls ...pb.Label) []pb.Label {
+		return append([]pb.Label{{Name: "__name__", Value: "up"}}, lbls...)
+	}
+
+	tests := []struct {
+		limit   vars.LabelLimitConfig
+		lbls    []pb.Label
+		wantErr string
+	}{
+		{limit: vars.LabelLimitConfig{}, lbls: up(pb.Label{Name: "instance", Value: strings.Repeat("x", 1000)})},
+		{limit: vars.LabelLimitConfig{MaxLabelNum: 2}, lbls: up(pb.Label{Name: "job", Value: "node"})},
+		{limit: vars.LabelLimitConfig{MaxLabelNum: 2}, lbls: up(pb.Label{Name: "instance", Value: "1"}, pb.Label{Name: "job", Value: "node"}), wantErr: "3 labels"},
+		{limit: vars.LabelLimitConfig{MaxNameLength: 8}, lbls: up(pb.Label{Name: "instance", Value: "1"})},
+		{limit: vars.LabelLimitConfig{MaxNameLength: 8}, lbls: up(pb.Label{Name: "request_id", Value: "1"}), wantErr: `label name "request_id"`},
+		{limit: vars.LabelLimitConfig{MaxValueLength: 4}, lbls: up(pb.Label{Name: "job", Value: "node"})},
+		{limit: vars.LabelLimitConfig{MaxValueLength: 4}, lbls: up(pb.Label{Name: "job", Value: "prometheus"}), wantErr: `value of label "job"`},
+		{limit: vars.LabelLimitConfig{AllowedNames: []string{"instance", "job"}}, lbls: up(pb.Label{Name: "job", Value: "node"})},
+		{limit: vars.LabelLimitConfig{AllowedNames: []string{"instance", "job"}}, lbls: up(pb.Label{Name: "user", Value: "1"}), wantErr: `label name "user" not allowed`},
+		{limit: vars.LabelLimitConfig{DeniedNames: []string{"user"}}, lbls: up(pb.Label{Name: "job", Value: "node"})},
+		{limit: vars.LabelLimitConfig{DeniedNames: []string{"user"}}, lbls: up(pb.Label{Name: "user", Value: "1"}), wantErr: `label name "user" denied`},
+	}
+
+	for i, test := range tests {
+		vars.Cfg.Gateway = &vars.GatewayConfig{LabelLimit: test.limit}
+
+		err := checkLabelLimit(test.lbls)
+		if test.wantErr == "" {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error %v", i, err)
+			}
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) || !strings.Contains(err.Error(), `series of "up" rejected`) {
+			t.Fatalf("case %d: want error about %s, got %v", i, test.wantErr, err)
+		}
+		if pb.Retryable(err) {
+			t.Fatalf("case %d: want a bad request, got %v", i, err)
+		}
+	}
+}
+
+func TestFanoutAppender_AddLabelLimit(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	vars.Cfg.Gateway = &vars.GatewayConfig{LabelLimit: vars.LabelLimitConfig{MaxLabelNum: 1}}
+	routed := false
+	cluster := &fakeCluster{byLabels: func(time.Time, []pb.Label, uint64) (string, error) {
+		routed = true
+		return "", nil
+	}}
+
+	app := &fanoutAppender{appenders: make(map[string]*appender), cluster: cluster}
+	err := app.Add([]pb.Label{{Name: "job", Value: "node"}, {Name: "__name__", Value: "up"}}, 0, 1, 0)
+	if err == nil || routed {
+		t.Fatalf("want the series rejected before being routed, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"context"
+	"math"
+
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// ListMetrics returns the names of the metrics known to the cluster, sorted. They're listed by routed, e.g.
+// meta.ListMetrics from the routes in the meta store, unless full is set, the store fails or doesn't hold them all,
+// i.e. none is routed or a partition label is configured, then the values of __name__ are asked of all the shards
+// through q, e.g. a Fanout.
+func ListMetrics(ctx context.Context, routed func() ([]string, error), q Queryable, full bool) ([]string, error) {
+	if !full && (vars.Cfg.Gateway == nil || vars.Cfg.Gateway.Route.PartitionLabel == "") {
+		metricNames, err := routed()
+		if err == nil && len(metricNames) > 0 {
+			return metricNames, nil
+		}
+		if err != nil {
+			level.Warn(vars.Logger).Log("msg", "failed to list the routed metrics, ask the shards instead", "err", err)
+		}
+	}
+
+	querier, err := q.Querier(ctx, math.MinInt64, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	defer querier.Close()
+
+	return querier.LabelValues(labels.MetricName)
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log"
+)
+
+// fakeQueryable hands out its querier, recording the span asked for.
+type fakeQueryable struct {
+	querier    Querier
+	mint, maxt int64
+	asked      bool
+}
+
+func (q *fakeQueryable) Querier(ctx context.Context, mint, maxt int64) (Querier, error) {
+	q.mint, q.maxt, q.asked = mint, maxt, true
+	return q.querier, nil
+}
+
+func TestListMetrics(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	gatewayCfg := vars.Cfg.Gateway
+	defer func() {
+		vars.Cfg.Gateway = gatewayCfg
+	}()
+
+	stored := []string{"node_load1", "up", "up_time"}
+	tests := []struct {
+		name           string
+		routed         []string
+		routedErr      error
+		full           bool
+		partitionLabel string
+		want           []string
+		wantShards     bool
+	}{
+		{name: "routed", routed: []string{"up"}, want: []string{"up"}},
+		{name: "full", routed: []string{"up"}, full: true, want: stored, wantShards: true},
+		{name: "none routed", want: stored, wantShards: true},
+		{name: "store failed", routedErr: errors.New("etcd down"), want: stored, wantShards: true},
+		{name: "partitioned", routed: []string{"up"}, partitionLabel: "tenant", want: stored, wantShards: true},
+	}
+
+	for _, test := range tests {
+		routed := func() ([]string, error) {
+			return test.routed, test.routedErr
+		}
+		vars.Cfg.Gateway = &vars.GatewayConfig{}
+		vars.Cfg.Gateway.Route.PartitionLabel = test.partitionLabel
+
+		q := &fakeQueryable{querier: &fakeQuerier{values: stored}}
+		got, err := ListMetrics(context.Background(), routed, q, test.full)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("%s: want %v, got %v", test.name, test.want, got)
+		}
+		if q.asked != test.wantShards {
+			t.Fatalf("%s: want shards asked %v, got %v", test.name, test.wantShards, q.asked)
+		}
+		if q.asked && (q.mint != math.MinInt64 || q.maxt != math.MaxInt64) {
+			t.Fatalf("%s: want the shards asked of all time, got [%d, %d]", test.name, q.mint, q.maxt)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	mergeSets      prometheus.Histogram
+	mergeHeapDepth prometheus.Histogram
+	mergedSeries   prometheus.Histogram
+	mergeDuration  prometheus.Histogram
+)
+
+func init() {
+	mergeSets = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "baudtime",
+		Subsystem: "merge",
+		Name:      "series_sets",
+		Help:      "Number of series sets merged by a query.",
+		Buckets:   prometheus.ExponentialBuckets(2, 2, 10),
+	})
+	mergeHeapDepth = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "baudtime",
+		Subsystem: "merge",
+		Name:      "heap_depth_max",
+		Help:      "Max number of series sets in the heap of a merge at the same time.",
+		Buckets:   prometheus.ExponentialBuckets(2, 2, 10),
+	})
+	mergedSeries = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "baudtime",
+		Subsystem: "merge",
+		Name:      "series",
+		Help:      "Number of series emitted by a merge, the ones of the same labels from different sets count once.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+	})
+	mergeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "baudtime",
+		Subsystem: "merge",
+		Name:      "duration_seconds",
+		Help:      "Time spent merging the series sets of a query, not counting the time the series are consumed in.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	prometheus.MustRegister(mergeSets, mergeHeapDepth, mergedSeries, mergeDuration)
+}
+
+// mergeStats are the statistics of a merge of series sets, they're observed once when the merge runs out of series.
+type mergeStats struct {
+	sets     int
+	maxDepth int
+	series   int
+	elapsed  time.Duration
+	observed bool
+}
+
+func (s *mergeStats) depth(n int) {
+	if n > s.maxDepth {
+		s.maxDepth = n
+	}
+}
+
+func (s *mergeStats) observe() {
+	if s.observed {
+		return
+	}
+	s.observed = true
+
+	mergeSets.Observe(float64(s.sets))
+	mergeHeapDepth.Observe(float64(s.maxDepth))
+	mergedSeries.Observe(float64(s.series))
+	mergeDuration.Observe(s.elapsed.Seconds())
+}
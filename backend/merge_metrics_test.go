@@ -0,0 +1,68 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+func TestMergeSeriesSet_Metrics(t *testing.T) {
+	histogram := func(h prometheus.Histogram) (count uint64, sum float64) {
+		m := new(dto.Metric)
+		h.Write(m)
+		return m.Histogram.GetSampleCount(), m.Histogram.GetSampleSum()
+	}
+	series := func(instances ...string) SeriesSet {
+		set := &concreteSeriesSet{}
+		for _, instance := range instances {
+			set.series = append(set.series, &concreteSeries{labels: labels.FromStrings("__name__", "up", "instance", instance)})
+		}
+		return set
+	}
+
+	metrics := []prometheus.Histogram{mergeSets, mergeHeapDepth, mergedSeries, mergeDuration}
+	counts, sums := make([]uint64, len(metrics)), make([]float64, len(metrics))
+	for i, h := range metrics {
+		counts[i], sums[i] = histogram(h)
+	}
+
+	set, _ := newMergeSeriesSet([]SeriesSet{series("a", "b"), series("b", "c"), series("d"), series()}, labels.Compare, false)
+	var merged int
+	for set.Next() {
+		merged++
+		if i, _ := histogram(mergedSeries); i != counts[2] {
+			t.Fatalf("expected no observation before the merge runs out of series")
+		}
+	}
+	set.Next() // observed once only
+	if merged != 4 {
+		t.Fatalf("want 4 series merged, got %d", merged)
+	}
+
+	for i, want := range []float64{4, 3, 4} { // the empty set is merged too, but never in the heap
+		count, sum := histogram(metrics[i])
+		if count != counts[i]+1 || sum-sums[i] != want {
+			t.Fatalf("metric %d: want one observation of %v, got %d of %v", i, want, count-counts[i], sum-sums[i])
+		}
+	}
+	if count, sum := histogram(mergeDuration); count != counts[3]+1 || sum < sums[3] {
+		t.Fatalf("want one observation of the merge duration, got %d", count-counts[3])
+	}
+}
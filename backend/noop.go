@@ -25,14 +25,18 @@ func NoopQuerier() Querier {
 	return noopQuerier{}
 }
 
-func (noopQuerier) Select(*SelectParams, ...*labels.Matcher) (SeriesSet, error) {
-	return NoopSeriesSet(), nil
+func (noopQuerier) Select(*SelectParams, ...*labels.Matcher) (SeriesSet, Warnings, error) {
+	return NoopSeriesSet(), nil, nil
 }
 
 func (noopQuerier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, error) {
 	return nil, nil
 }
 
+func (noopQuerier) LabelNames() ([]string, error) {
+	return nil, nil
+}
+
 func (noopQuerier) Close() error {
 	return nil
 }
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"context"
+	"sort"
+)
+
+// LabelValuesPage is a page of the sorted label values, at most Limit of the ones after After.
+// After is the last value of the previous page, so the pages of the shards merge into one without
+// duplicates, and the paging goes on even if values are added or gone in between.
+type LabelValuesPage struct {
+	After string
+	Limit int // 0 means no limit
+}
+
+type labelValuesPageKey struct{}
+
+// WithLabelValuesPage returns a context making the label values queries with it return the page only.
+func WithLabelValuesPage(ctx context.Context, page LabelValuesPage) context.Context {
+	return context.WithValue(ctx, labelValuesPageKey{}, page)
+}
+
+func labelValuesPageFromContext(ctx context.Context) (LabelValuesPage, bool) {
+	page, ok := ctx.Value(labelValuesPageKey{}).(LabelValuesPage)
+	return page, ok
+}
+
+// Cut returns the page out of the sorted values.
+func (page LabelValuesPage) Cut(values []string) []string {
+	if page.After != "" {
+		values = values[sort.Search(len(values), func(i int) bool { return values[i] > page.After }):]
+	}
+	if page.Limit > 0 && len(values) > page.Limit {
+		values = values[:page.Limit]
+	}
+	return values
+}
@@ -18,12 +18,15 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/baudtime/baudtime/msg/pb"
 	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
 	"github.com/baudtime/baudtime/util"
+	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/value"
 )
 
 // QueryableClient returns a Queryable which queries the given
@@ -43,23 +46,90 @@ func QueryableClient(c Client) Queryable {
 type querier struct {
 	ctx        context.Context
 	mint, maxt int64
+	shardID    string // the shard the client asks, only to tag the spans, empty if unknown
 	client     Client
+	timeout    time.Duration // timeout of every request, 0 means no limit other than ctx
+	compact    bool          // ask for the points in chunks, see QueryConfig.CompactPoints
 }
 
 // Select implements Querier and uses the given matchers to read series
 // sets from the Client.
-func (q *querier) Select(selectParams *SelectParams, matchers ...*labels.Matcher) (SeriesSet, error) {
+func (q *querier) Select(selectParams *SelectParams, matchers ...*labels.Matcher) (_ SeriesSet, _ Warnings, err error) {
+	span, ctx := startSpan(q.ctx, "shardSelect", opentracing.Tag{Key: "shard", Value: q.shardID})
+	defer func() {
+		finishSpan(span, err)
+	}()
+
 	selectRequest := &backendpb.SelectRequest{
-		Mint:     q.mint,
-		Maxt:     q.maxt,
-		Interval: selectParams.Step,
-		Matchers: util.MatchersToProto(matchers),
+		Mint:          q.mint,
+		Maxt:          q.maxt,
+		Interval:      selectParams.Step,
+		Matchers:      util.MatchersToProto(matchers),
+		CompactPoints: q.compact,
+		Aggregation:   selectParams.Aggregation,
+		ValueBounds:   selectParams.ValueBounds,
+		Hash:          selectParams.Hash,
+		RawChunks:     selectParams.RawChunks,
 	}
-	res, err := q.client.Select(q.ctx, selectRequest)
+
+	ctx, cancel := q.requestContext(ctx)
+	defer cancel()
+
+	if selectParams.SlaveRead {
+		ctx = WithSlaveRead(ctx)
+	}
+
+	res, err := q.client.Select(ctx, selectRequest)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// filtered again for the storages not knowing the hash
+	if hash := selectParams.Hash; hash != 0 {
+		kept := res.Series[:0]
+		for _, s := range res.Series {
+			if util.HashLabels(s.Labels) == hash {
+				kept = append(kept, s)
+			} else {
+				pb.PutSeries(s)
+			}
+		}
+		res.Series = kept
+	}
+	if span != nil {
+		span.SetTag("series", len(res.Series))
+		span.SetTag("samples", seriesSamples(res.Series))
+	}
+	if selectParams.RawChunks {
+		set, err := FromChunksResult(res, q.mint, q.maxt)
+		if err != nil {
+			return nil, nil, err
+		}
+		return set, nil, nil
 	}
-	return FromQueryResult(res), nil
+
+	// filtered again for the storages not knowing the bounds, it's cheap on filtered points
+	if bounds := selectParams.ValueBounds; bounds != nil {
+		kept := res.Series[:0]
+		for i, s := range res.Series {
+			if err = s.Expand(); err != nil {
+				res.Series = append(kept, res.Series[i:]...)
+				res.Release()
+				return nil, nil, err
+			}
+			if s.Points = bounds.Filter(s.Points); len(s.Points) > 0 {
+				kept = append(kept, s)
+			} else {
+				pb.PutSeries(s)
+			}
+		}
+		res.Series = kept
+	}
+	set, err := FromQueryResult(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	return set, nil, nil
 }
 
 // LabelValues implements Querier and is a noop.
@@ -68,37 +138,208 @@ func (q *querier) LabelValues(name string, matchers ...*labels.Matcher) ([]strin
 		Name:     name,
 		Matchers: util.MatchersToProto(matchers),
 	}
-	res, err := q.client.LabelValues(q.ctx, labelValuesRequest)
+
+	page, paged := labelValuesPageFromContext(q.ctx)
+	if paged {
+		labelValuesRequest.Limit = uint32(page.Limit)
+		labelValuesRequest.After = page.After
+	}
+
+	ctx, cancel := q.requestContext(q.ctx)
+	defer cancel()
+
+	res, err := q.client.LabelValues(ctx, labelValuesRequest)
 	if err != nil {
 		return nil, err
 	}
+	if paged { // cut again for the storages not knowing the page
+		return page.Cut(sortedUnique(res.Values)), nil
+	}
 	return res.Values, nil
 }
 
+// LabelNames implements Querier and returns all label names from the Client.
+func (q *querier) LabelNames() ([]string, error) {
+	ctx, cancel := q.requestContext(q.ctx)
+	defer cancel()
+
+	res, err := q.client.LabelNames(ctx, &backendpb.LabelNamesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return res.Names, nil
+}
+
 // Close implements Querier and is a noop.
 func (q *querier) Close() error {
 	return nil
 }
 
-// FromQueryResult unpacks a QueryResult proto.
-func FromQueryResult(res *backendpb.SelectResponse) SeriesSet {
+// requestContext returns ctx, which is q.ctx or derived from it, limited to the timeout of a request.
+func (q *querier) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if q.timeout > 0 {
+		return context.WithTimeout(ctx, q.timeout)
+	}
+	return ctx, func() {}
+}
+
+// FromQueryResult unpacks a QueryResult proto, the series of res are released to the pool
+// with their points taken out, so res must not be used after. A failed res is returned as
+// an error, while a succeeded one without series is an empty series set.
+func FromQueryResult(res *backendpb.SelectResponse) (SeriesSet, error) {
+	defer res.Release()
+
+	if res.Status != pb.StatusCode_Succeed {
+		return nil, fmt.Errorf("select failed: %s", res.ErrorMsg)
+	}
+	if len(res.Series) == 0 {
+		return emptySeriesSet, nil
+	}
+
 	series := make([]Series, 0, len(res.Series))
 	for _, ts := range res.Series {
+		if err := ts.Expand(); err != nil {
+			return nil, err
+		}
+
 		lbls := util.ProtoToLabels(ts.Labels)
 		if err := validateLabelsAndMetricName(lbls); err != nil {
-			return errSeriesSet{err: err}
+			return nil, err
 		}
 
 		series = append(series, &concreteSeries{
 			labels:  lbls,
 			samples: ts.Points,
 		})
+		ts.Points = nil
 	}
 	//TODO
 	//sort.Sort(byLabel(series))
 	return &concreteSeriesSet{
 		series: series,
+	}, nil
+}
+
+// FromChunksResult unpacks a select response asked for raw chunks into a series set of ChunkSeries,
+// whose iterators decode the samples in [mint, maxt]. The series answered in points, by the storages not
+// knowing raw chunks, are put in chunks. res is released the same as by FromQueryResult.
+func FromChunksResult(res *backendpb.SelectResponse, mint, maxt int64) (SeriesSet, error) {
+	defer res.Release()
+
+	if res.Status != pb.StatusCode_Succeed {
+		return nil, fmt.Errorf("select failed: %s", res.ErrorMsg)
+	}
+	if len(res.Series) == 0 {
+		return emptySeriesSet, nil
+	}
+
+	series := make([]Series, 0, len(res.Series))
+	for _, ts := range res.Series {
+		lbls := util.ProtoToLabels(ts.Labels)
+		if err := validateLabelsAndMetricName(lbls); err != nil {
+			return nil, err
+		}
+
+		chunks := ts.Chunks
+		if len(chunks) == 0 {
+			if err := ts.Expand(); err != nil {
+				return nil, err
+			}
+			var err error
+			if chunks, err = pb.EncodeChunks(ts.Points); err != nil {
+				return nil, err
+			}
+		}
+
+		series = append(series, &rawChunkSeries{
+			labels: lbls,
+			chunks: chunks,
+			mint:   mint,
+			maxt:   maxt,
+		})
+		ts.Chunks = nil
+	}
+	return &concreteSeriesSet{
+		series: series,
+	}, nil
+}
+
+// StreamSeriesSet returns a SeriesSet decoding a select response frame by frame, next is called
+// for the following frame once the current one is exhausted, until a frame without HasMore.
+// Closing it reads and drops the rest of the frames.
+func StreamSeriesSet(next func() (*backendpb.SelectResponse, error)) SeriesSet {
+	return &streamSeriesSet{next: next, idx: -1}
+}
+
+// streamSeriesSet implements SeriesSet.
+type streamSeriesSet struct {
+	next  func() (*backendpb.SelectResponse, error)
+	frame *backendpb.SelectResponse
+	idx   int
+	cur   Series
+	done  bool
+	err   error
+}
+
+func (s *streamSeriesSet) Next() bool {
+	for s.err == nil {
+		if s.frame != nil && s.idx+1 < len(s.frame.Series) {
+			s.idx++
+
+			ts := s.frame.Series[s.idx]
+			if s.err = ts.Expand(); s.err != nil {
+				return false
+			}
+
+			lbls := util.ProtoToLabels(ts.Labels)
+			if s.err = validateLabelsAndMetricName(lbls); s.err != nil {
+				return false
+			}
+
+			s.cur = &concreteSeries{
+				labels:  lbls,
+				samples: ts.Points,
+			}
+			return true
+		}
+
+		if s.done {
+			return false
+		}
+
+		s.readFrame()
+	}
+	return false
+}
+
+func (s *streamSeriesSet) readFrame() {
+	s.frame, s.err = s.next()
+	s.idx = -1
+
+	if s.err != nil {
+		s.done = true
+		return
 	}
+
+	s.done = !s.frame.HasMore
+	if s.frame.Status != pb.StatusCode_Succeed {
+		s.err = fmt.Errorf("select failed: %s", s.frame.ErrorMsg)
+	}
+}
+
+func (s *streamSeriesSet) At() Series {
+	return s.cur
+}
+
+func (s *streamSeriesSet) Err() error {
+	return s.err
+}
+
+func (s *streamSeriesSet) Close() error {
+	for !s.done {
+		s.readFrame()
+	}
+	return nil
 }
 
 // validateLabelsAndMetricName validates the label names/values and metric names returned from remote read.
@@ -156,6 +397,38 @@ func (c *concreteSeries) Iterator() SeriesIterator {
 	return newConcreteSeriersIterator(c)
 }
 
+// rawChunkSeries implements ChunkSeries.
+type rawChunkSeries struct {
+	labels     labels.Labels
+	chunks     []pb.Chunk
+	mint, maxt int64
+}
+
+func (c *rawChunkSeries) Labels() labels.Labels {
+	return labels.New(c.labels...)
+}
+
+func (c *rawChunkSeries) Chunks() []pb.Chunk {
+	return c.chunks
+}
+
+// Iterator decodes the samples in [mint, maxt] of the chunks, the staleness markers are left out
+// the same as they are of the series selected in points.
+func (c *rawChunkSeries) Iterator() SeriesIterator {
+	points, err := pb.DecodeChunks(c.chunks, c.mint, c.maxt)
+	if err != nil {
+		return errSeriesIterator{err: err}
+	}
+
+	samples := points[:0]
+	for _, p := range points {
+		if !value.IsStaleNaN(p.V) {
+			samples = append(samples, p)
+		}
+	}
+	return newConcreteSeriersIterator(&concreteSeries{labels: c.labels, samples: samples})
+}
+
 // concreteSeriesIterator implements SeriesIterator.
 type concreteSeriesIterator struct {
 	cur    int
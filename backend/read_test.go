@@ -0,0 +1,516 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/backend/storage"
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/util"
+	"github.com/baudtime/baudtime/util/toml"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/tsdb"
+)
+
+func makeSeries(from, to, pointNum int) []*pb.Series {
+	series := make([]*pb.Series, 0, to-from)
+	for i := from; i < to; i++ {
+		s := &pb.Series{
+			Labels: []pb.Label{
+				{Name: "__name__", Value: "test_metric"},
+				{Name: "instance", Value: strconv.Itoa(i)},
+			},
+			Points: make([]pb.Point, pointNum),
+		}
+		for j := range s.Points {
+			s.Points[j] = pb.Point{T: int64(j) * 15000, V: float64(j)}
+		}
+		series = append(series, s)
+	}
+	return series
+}
+
+// frameReader returns the frames of a select response with seriesNum series, each frame is
+// marshaled and unmarshaled just like being read from a connection.
+func frameReader(seriesNum, seriesPerFrame, pointNum int) func() (*backendpb.SelectResponse, error) {
+	sent := 0
+	return func() (*backendpb.SelectResponse, error) {
+		to := sent + seriesPerFrame
+		if to > seriesNum {
+			to = seriesNum
+		}
+
+		b, err := (&backendpb.SelectResponse{
+			Series:  makeSeries(sent, to, pointNum),
+			HasMore: to < seriesNum,
+		}).Marshal()
+		if err != nil {
+			return nil, err
+		}
+		sent = to
+
+		resp := new(backendpb.SelectResponse)
+		return resp, resp.Unmarshal(b)
+	}
+}
+
+// selectClient answers selects with canned series, like a storage not knowing the value bounds.
+type selectClient struct {
+	Client
+	req    *backendpb.SelectRequest
+	series []*pb.Series
+}
+
+func (c *selectClient) Select(ctx context.Context, req *backendpb.SelectRequest) (*backendpb.SelectResponse, error) {
+	c.req = req
+	return &backendpb.SelectResponse{Series: c.series}, nil
+}
+
+func TestQuerier_SelectValueBounds(t *testing.T) {
+	stale := math.Float64frombits(value.StaleNaN)
+	client := &selectClient{series: []*pb.Series{
+		{
+			Labels: []pb.Label{{Name: "__name__", Value: "errors"}, {Name: "instance", Value: "1"}},
+			Points: []pb.Point{{T: 1, V: 0}, {T: 2, V: 3}, {T: 3, V: math.NaN()}, {T: 4, V: 10}, {T: 5, V: 11}, {T: 6, V: stale}},
+		},
+		{
+			Labels: []pb.Label{{Name: "__name__", Value: "errors"}, {Name: "instance", Value: "2"}},
+			Points: []pb.Point{{T: 1, V: 0}, {T: 2, V: -1}},
+		},
+	}}
+	q := &querier{ctx: context.Background(), mint: 1, maxt: 6, client: client}
+
+	bounds := &backendpb.ValueBounds{Min: 1, Max: 10}
+	set, _, err := q.Select(&SelectParams{ValueBounds: bounds})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.req.ValueBounds != bounds {
+		t.Fatalf("want bounds pushed down, got %v", client.req.ValueBounds)
+	}
+
+	var series int
+	for set.Next() {
+		series++
+		var got []int64
+		for it := set.At().Iterator(); it.Next(); {
+			ts, v := it.At()
+			if !bounds.Contains(v) {
+				t.Fatalf("point (%d, %v) out of bounds", ts, v)
+			}
+			got = append(got, ts)
+		}
+		// NaN is dropped, the stale marker is kept
+		if len(got) != 3 || got[0] != 2 || got[1] != 4 || got[2] != 6 {
+			t.Fatalf("unexpected points at %v", got)
+		}
+	}
+	if series != 1 {
+		t.Fatalf("want the series without any point in bounds dropped, got %d series", series)
+	}
+}
+
+func TestStreamSeriesSet(t *testing.T) {
+	set := StreamSeriesSet(frameReader(25, 10, 3))
+
+	n := 0
+	for set.Next() {
+		s := set.At()
+		if got := s.Labels().Get("instance"); got != strconv.Itoa(n) {
+			t.Fatalf("series %d: unexpected instance %s", n, got)
+		}
+
+		points := 0
+		for it := s.Iterator(); it.Next(); {
+			points++
+		}
+		if points != 3 {
+			t.Fatalf("series %d: want 3 points, got %d", n, points)
+		}
+		n++
+	}
+
+	if err := set.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 25 {
+		t.Fatalf("want 25 series, got %d", n)
+	}
+
+	set = StreamSeriesSet(func() (*backendpb.SelectResponse, error) {
+		return &backendpb.SelectResponse{Status: pb.StatusCode_Failed, ErrorMsg: "storage down"}, nil
+	})
+	if set.Next() || set.Err() == nil {
+		t.Fatalf("expected error for failed response")
+	}
+}
+
+func TestFromQueryResult_Release(t *testing.T) {
+	decode := func(from int) *backendpb.SelectResponse {
+		b, err := (&backendpb.SelectResponse{Series: makeSeries(from, from+5, 3)}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := new(backendpb.SelectResponse)
+		if err = resp.UnmarshalPooled(b); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	set, err := FromQueryResult(decode(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the released series are reused, the points handed to the set must stay intact
+	another := decode(100)
+	for _, s := range another.Series {
+		for i := range s.Points {
+			s.Points[i].V = -1
+		}
+	}
+
+	n := 0
+	for ; set.Next(); n++ {
+		s := set.At()
+		if got := s.Labels().Get("instance"); got != strconv.Itoa(n) {
+			t.Fatalf("series %d: unexpected instance %s", n, got)
+		}
+		j := 0
+		for it := s.Iterator(); it.Next(); j++ {
+			if _, v := it.At(); v != float64(j) {
+				t.Fatalf("series %d: want value %d at %d, got %v", n, j, j, v)
+			}
+		}
+	}
+	if n != 5 {
+		t.Fatalf("want 5 series, got %d", n)
+	}
+}
+
+func TestFromQueryResult(t *testing.T) {
+	if _, err := FromQueryResult(&backendpb.SelectResponse{Status: pb.StatusCode_Failed, ErrorMsg: "storage down"}); err == nil {
+		t.Fatalf("expected error for failed response")
+	}
+
+	set, err := FromQueryResult(&backendpb.SelectResponse{Status: pb.StatusCode_Succeed})
+	if err != nil {
+		t.Fatalf("unexpected error for empty response: %v", err)
+	}
+	if set != emptySeriesSet {
+		t.Fatalf("want the empty series set, got %v", set)
+	}
+
+	set, err = FromQueryResult(&backendpb.SelectResponse{Status: pb.StatusCode_Succeed, Series: makeSeries(0, 3, 2)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n := 0
+	for ; set.Next(); n++ {
+		if got := set.At().Labels().Get("instance"); got != strconv.Itoa(n) {
+			t.Fatalf("series %d: unexpected instance %s", n, got)
+		}
+	}
+	if n != 3 || set.Err() != nil {
+		t.Fatalf("want 3 series without error, got %d, %v", n, set.Err())
+	}
+}
+
+// BenchmarkSelect_1MSamples compares the peak heap of materializing a 1M samples select
+// response against streaming it by frames.
+func BenchmarkSelect_1MSamples(b *testing.B) {
+	const (
+		seriesNum = 10000
+		pointNum  = 100
+	)
+
+	consume := func(b *testing.B, set SeriesSet) (peak uint64) {
+		var ms runtime.MemStats
+		for n := 0; set.Next(); n++ {
+			for it := set.At().Iterator(); it.Next(); {
+			}
+			if n%500 == 0 {
+				runtime.ReadMemStats(&ms)
+				if ms.HeapInuse > peak {
+					peak = ms.HeapInuse
+				}
+			}
+		}
+		if err := set.Err(); err != nil {
+			b.Fatal(err)
+		}
+		return peak
+	}
+
+	b.Run("materialized", func(b *testing.B) {
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			runtime.GC()
+			res, err := frameReader(seriesNum, seriesNum, pointNum)()
+			if err != nil {
+				b.Fatal(err)
+			}
+			set, err := FromQueryResult(res)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if p := consume(b, set); p > peak {
+				peak = p
+			}
+		}
+		b.Logf("peak heap in use: %d MB", peak>>20)
+	})
+
+	b.Run("streamed", func(b *testing.B) {
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			runtime.GC()
+			if p := consume(b, StreamSeriesSet(frameReader(seriesNum, 100, pointNum))); p > peak {
+				peak = p
+			}
+		}
+		b.Logf("peak heap in use: %d MB", peak>>20)
+	})
+}
+
+// storageClient sends the requests to a storage in process.
+type storageClient struct {
+	Client
+	storage *storage.Storage
+}
+
+func (c storageClient) Add(ctx context.Context, req *backendpb.AddRequest) error {
+	return c.storage.HandleAddReq(req)
+}
+
+func (c storageClient) Select(ctx context.Context, req *backendpb.SelectRequest) (*backendpb.SelectResponse, error) {
+	return c.storage.HandleSelectReq(req), nil
+}
+
+func TestQuerier_SelectByHash(t *testing.T) {
+	storageCfg := vars.Cfg.Storage
+	defer func() {
+		vars.Cfg.Storage = storageCfg
+	}()
+	vars.Cfg.Storage = &vars.StorageConfig{TSDB: vars.TSDBConfig{LookbackDelta: toml.Duration(time.Second)}}
+
+	dir, err := ioutil.TempDir("", "selectbyhash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := tsdb.Open(dir, nil, nil, &tsdb.Options{BlockRanges: []int64{7200000}, NoLockfile: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	client := storageClient{storage: storage.New(db)}
+
+	// the hashes the series are routed by on the write path
+	hashes := make(map[string]uint64)
+	cluster := &fakeCluster{byLabels: func(_ time.Time, l []pb.Label, hash uint64) (string, error) {
+		hashes[util.ProtoToLabels(l).Get("instance")] = hash
+		return "shard-1", nil
+	}}
+	app := &fanoutAppender{appenders: map[string]*appender{
+		"shard-1": {client: client, series: seriesHashMap{}, retryNum: 1},
+	}, cluster: cluster}
+	for i, instance := range []string{"1", "2", "3"} {
+		for _, ts := range []int64{1000, 2000} {
+			// the labels are unsorted, so the hash is computed by the appender
+			l := []pb.Label{{Name: "job", Value: "node"}, {Name: "instance", Value: instance}, {Name: "__name__", Value: "up"}}
+			if err = app.Add(l, ts, float64(i), 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err = app.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	q := &querier{ctx: context.Background(), mint: 1000, maxt: 2000, client: client}
+	metric, err := labels.NewMatcher(labels.MatchEqual, labels.MetricName, "up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for instance, hash := range hashes {
+		set, _, err := q.Select(&SelectParams{Hash: hash}, metric)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !set.Next() {
+			t.Fatalf("instance %s: series of hash %d not found", instance, hash)
+		}
+		series := set.At()
+		if series.Labels().Get("instance") != instance || util.HashPromLabels(series.Labels()) != hash {
+			t.Fatalf("instance %s: want the series of hash %d, got %v", instance, hash, series.Labels())
+		}
+		var points int
+		for it := series.Iterator(); it.Next(); points++ {
+		}
+		if points != 2 || set.Next() {
+			t.Fatalf("instance %s: want one series of 2 points, got %d points", instance, points)
+		}
+	}
+
+	set, _, err := q.Select(&SelectParams{Hash: 1}, metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Next() {
+		t.Fatalf("want no series of an unknown hash, got %v", set.At().Labels())
+	}
+
+	// a storage not knowing the hash answers every series of the metric, they're filtered again.
+	var all []*pb.Series
+	for instance := range hashes {
+		all = append(all, &pb.Series{
+			Labels: []pb.Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: instance}, {Name: "job", Value: "node"}},
+			Points: []pb.Point{{T: 1000, V: 1}},
+		})
+	}
+	q.client = &selectClient{series: all}
+	if set, _, err = q.Select(&SelectParams{Hash: hashes["2"]}, metric); err != nil {
+		t.Fatal(err)
+	}
+	if !set.Next() || set.At().Labels().Get("instance") != "2" || set.Next() {
+		t.Fatal("want the series of the hash only")
+	}
+}
+
+func TestQuerier_SelectRawChunks(t *testing.T) {
+	storageCfg := vars.Cfg.Storage
+	defer func() {
+		vars.Cfg.Storage = storageCfg
+	}()
+	vars.Cfg.Storage = &vars.StorageConfig{TSDB: vars.TSDBConfig{LookbackDelta: toml.Duration(time.Second)}}
+
+	openDB := func(dir string) *tsdb.DB {
+		db, err := tsdb.Open(dir, nil, nil, &tsdb.Options{BlockRanges: []int64{7200000}, NoLockfile: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	add := func(db *tsdb.DB, from, to int64) {
+		var series []*pb.Series
+		for _, instance := range []string{"1", "2"} {
+			s := &pb.Series{Labels: []pb.Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: instance}}}
+			for ts := from; ts < to; ts += 15000 {
+				v := float64(ts / 15000)
+				if ts == from+15000 {
+					v = math.Float64frombits(value.StaleNaN)
+				}
+				s.Points = append(s.Points, pb.Point{T: ts, V: v})
+			}
+			series = append(series, s)
+		}
+		if err := storage.New(db).HandleAddReq(&backendpb.AddRequest{Series: series}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "rawchunks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the first hour is persisted in a block, the second is in the head
+	db := openDB(dir + "/head")
+	add(db, 0, 3600000)
+	if err = db.Snapshot(dir+"/blocks", true); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db = openDB(dir + "/blocks")
+	defer db.Close()
+	if len(db.Blocks()) == 0 {
+		t.Fatal("want the first hour in a block")
+	}
+	add(db, 3600000, 7200000)
+
+	client := storageClient{storage: storage.New(db)}
+	matcher, err := labels.NewMatcher(labels.MatchEqual, labels.MetricName, "up")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, span := range [][2]int64{{0, 7200000}, {1800000, 5400000}, {100000, 200000}, {4000000, 5000000}} {
+		q := &querier{ctx: context.Background(), mint: span[0], maxt: span[1], client: client}
+
+		points, _, err := q.Select(&SelectParams{}, matcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks, _, err := q.Select(&SelectParams{RawChunks: true}, matcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for points.Next() {
+			if !chunks.Next() {
+				t.Fatalf("%v: want the series %v in chunks", span, points.At().Labels())
+			}
+			want, got := points.At(), chunks.At()
+			if !labels.Equal(want.Labels(), got.Labels()) {
+				t.Fatalf("%v: want the series %v, got %v", span, want.Labels(), got.Labels())
+			}
+
+			chunkSeries, ok := got.(ChunkSeries)
+			if !ok || len(chunkSeries.Chunks()) == 0 {
+				t.Fatalf("%v: want the series %v selected in chunks", span, got.Labels())
+			}
+			// the chunks of the block are passed as they are, with the samples out of the span
+			if persisted := span[1] < 3600000; persisted && chunkSeries.Chunks()[0].Mint >= span[0] {
+				t.Fatalf("%v: want the chunk of the block as it is, got the one of [%d, %d]", span, chunkSeries.Chunks()[0].Mint, chunkSeries.Chunks()[0].Maxt)
+			}
+
+			wantIt, gotIt := want.Iterator(), got.Iterator()
+			n := 0
+			for ; wantIt.Next(); n++ {
+				if !gotIt.Next() {
+					t.Fatalf("%v: %v: want %d points, got %d", span, want.Labels(), n+1, n)
+				}
+				wt, wv := wantIt.At()
+				gt, gv := gotIt.At()
+				if wt != gt || wv != gv {
+					t.Fatalf("%v: %v: want the point (%d, %f), got (%d, %f)", span, want.Labels(), wt, wv, gt, gv)
+				}
+			}
+			if gotIt.Next() || gotIt.Err() != nil || n == 0 {
+				t.Fatalf("%v: %v: want the same %d points decoded from the chunks, %v", span, want.Labels(), n, gotIt.Err())
+			}
+		}
+		if chunks.Next() || points.Err() != nil || chunks.Err() != nil {
+			t.Fatalf("%v: want the same series, %v, %v", span, points.Err(), chunks.Err())
+		}
+	}
+}
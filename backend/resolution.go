@@ -0,0 +1,200 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/value"
+)
+
+// DownsampleFunc tells how the samples of a bucket are reduced to one, see SelectParams.Resolution.
+type DownsampleFunc int
+
+const (
+	// DownsampleLast keeps the last sample of a bucket.
+	DownsampleLast DownsampleFunc = iota
+	// DownsampleAvg averages the values of a bucket, at the time of its last sample.
+	DownsampleAvg
+)
+
+func (f DownsampleFunc) String() string {
+	switch f {
+	case DownsampleAvg:
+		return "avg"
+	default:
+		return "last"
+	}
+}
+
+// ParseDownsample returns the DownsampleFunc of the name, as its String gives.
+func ParseDownsample(name string) (DownsampleFunc, error) {
+	switch name {
+	case "last":
+		return DownsampleLast, nil
+	case "avg":
+		return DownsampleAvg, nil
+	default:
+		return 0, errors.Errorf("unknown downsample %q", name)
+	}
+}
+
+// resolutionSeriesSet reduces the samples of the series of a set to at most one per bucket of resolution.
+type resolutionSeriesSet struct {
+	SeriesSet
+	resolution int64
+	downsample DownsampleFunc
+}
+
+func newResolutionSeriesSet(set SeriesSet, resolution int64, downsample DownsampleFunc) SeriesSet {
+	if resolution <= 0 {
+		return set
+	}
+	return &resolutionSeriesSet{SeriesSet: set, resolution: resolution, downsample: downsample}
+}
+
+func (s *resolutionSeriesSet) At() Series {
+	return &resolutionSeries{Series: s.SeriesSet.At(), resolution: s.resolution, downsample: s.downsample}
+}
+
+// Close releases the underlying set if it implements io.Closer.
+func (s *resolutionSeriesSet) Close() error {
+	if closer, ok := s.SeriesSet.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+type resolutionSeries struct {
+	Series
+	resolution int64
+	downsample DownsampleFunc
+}
+
+func (s *resolutionSeries) Iterator() SeriesIterator {
+	return newResolutionIterator(s.Series.Iterator(), s.resolution, s.downsample)
+}
+
+// resolutionIterator emits at most one sample per bucket of resolution milliseconds, the buckets are aligned
+// to multiples of resolution. The sample of a bucket is at the time of its last sample, so that it never gets
+// out of the time range selected. NaN values are left out of an average unless all the values of the bucket
+// are NaN, and a bucket ending in a staleness marker yields the marker, so that the end of a series is kept.
+type resolutionIterator struct {
+	it         SeriesIterator
+	resolution int64
+	downsample DownsampleFunc
+
+	t         int64
+	v         float64
+	valid     bool // t and v are the sample of a bucket
+	ahead     bool // it is at the first sample of the bucket after the current one
+	exhausted bool // it has no sample left
+}
+
+func newResolutionIterator(it SeriesIterator, resolution int64, downsample DownsampleFunc) *resolutionIterator {
+	return &resolutionIterator{it: it, resolution: resolution, downsample: downsample}
+}
+
+func (r *resolutionIterator) Seek(t int64) bool {
+	if r.valid && r.t >= t {
+		return true
+	}
+	if r.exhausted && !r.ahead {
+		r.valid = false
+		return false
+	}
+
+	start := bucketStart(t, r.resolution)
+	if r.valid && start <= r.t { // the samples up to the current one are reduced already
+		start = r.t + 1
+	}
+	if !r.it.Seek(start) {
+		r.exhausted, r.ahead, r.valid = true, false, false
+		return false
+	}
+	for {
+		r.bucket()
+		if r.t >= t {
+			return true
+		}
+		if !r.ahead {
+			r.valid = false
+			return false
+		}
+	}
+}
+
+func (r *resolutionIterator) At() (int64, float64) {
+	return r.t, r.v
+}
+
+func (r *resolutionIterator) Next() bool {
+	if !r.ahead {
+		if r.exhausted || !r.it.Next() {
+			r.exhausted, r.valid = true, false
+			return false
+		}
+	}
+	r.bucket()
+	return true
+}
+
+// bucket reduces the samples of the bucket of the current sample of it, leaving it at the first sample of the next bucket.
+func (r *resolutionIterator) bucket() {
+	t, v := r.it.At()
+	end := bucketStart(t, r.resolution) + r.resolution
+
+	var (
+		sum float64
+		n   int
+	)
+	for {
+		r.t, r.v = t, v
+		if !math.IsNaN(v) {
+			sum += v
+			n++
+		}
+
+		if !r.it.Next() {
+			r.exhausted, r.ahead = true, false
+			break
+		}
+		if t, v = r.it.At(); t >= end {
+			r.ahead = true
+			break
+		}
+	}
+
+	if r.downsample == DownsampleAvg && n > 0 && !value.IsStaleNaN(r.v) {
+		r.v = sum / float64(n)
+	}
+	r.valid = true
+}
+
+func (r *resolutionIterator) Err() error {
+	return r.it.Err()
+}
+
+// bucketStart returns the start of the bucket of resolution t falls in, t may be negative.
+func bucketStart(t, resolution int64) int64 {
+	m := t % resolution
+	if m < 0 {
+		m += resolution
+	}
+	return t - m
+}
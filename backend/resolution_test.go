@@ -0,0 +1,144 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/value"
+)
+
+func TestResolutionIterator(t *testing.T) {
+	stale := math.Float64frombits(value.StaleNaN)
+	points := func(tvs ...float64) []pb.Point {
+		var ps []pb.Point
+		for i := 0; i < len(tvs); i += 2 {
+			ps = append(ps, pb.Point{T: int64(tvs[i]), V: tvs[i+1]})
+		}
+		return ps
+	}
+
+	tests := []struct {
+		name       string
+		samples    []pb.Point
+		downsample DownsampleFunc
+		want       []pb.Point
+	}{
+		{"empty", nil, DownsampleLast, nil},
+		{"last", points(0, 1, 3, 2, 9, 3, 10, 4, 25, 5, 29, 6), DownsampleLast, points(9, 3, 10, 4, 29, 6)},
+		{"avg", points(0, 1, 3, 2, 9, 3, 10, 4, 25, 5, 29, 6), DownsampleAvg, points(9, 2, 10, 4, 29, 5.5)},
+		{"negative time", points(-11, 1, -10, 2, -1, 3, 0, 4), DownsampleLast, points(-11, 1, -1, 3, 0, 4)},
+		{"avg skips nan", points(1, 1, 2, math.NaN(), 3, 3, 11, math.NaN()), DownsampleAvg, points(3, 2, 11, math.NaN())},
+		{"last keeps nan", points(1, 1, 2, math.NaN()), DownsampleLast, points(2, math.NaN())},
+		{"stale marker ends bucket", points(1, 1, 2, 3, 5, stale, 12, 4), DownsampleAvg, points(5, stale, 12, 4)},
+		{"stale marker mid bucket", points(1, 1, 5, stale, 7, 3), DownsampleAvg, points(7, 2)},
+	}
+
+	for _, test := range tests {
+		it := newResolutionIterator(newConcreteSeriersIterator(&concreteSeries{samples: test.samples}), 10, test.downsample)
+
+		var got []pb.Point
+		for it.Next() {
+			t, v := it.At()
+			got = append(got, pb.Point{T: t, V: v})
+		}
+		if it.Next() {
+			t.Fatalf("%s: Next after the end", test.name)
+		}
+		if !equalPoints(got, test.want) {
+			t.Fatalf("%s: want %v, got %v", test.name, test.want, got)
+		}
+	}
+}
+
+// equalPoints compares points telling a staleness marker from other NaNs.
+func equalPoints(a, b []pb.Point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].T != b[i].T || math.Float64bits(a[i].V) != math.Float64bits(b[i].V) &&
+			!(math.IsNaN(a[i].V) && math.IsNaN(b[i].V) && !value.IsStaleNaN(a[i].V) && !value.IsStaleNaN(b[i].V)) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestResolutionIterator_Seek(t *testing.T) {
+	series := &concreteSeries{samples: []pb.Point{{T: 1, V: 1}, {T: 5, V: 2}, {T: 12, V: 3}, {T: 18, V: 4}, {T: 31, V: 5}}}
+
+	tests := []struct {
+		seeks []int64
+		want  []int64 // time of the sample after each seek, -1 if none
+	}{
+		{seeks: []int64{0}, want: []int64{5}},
+		{seeks: []int64{5, 5}, want: []int64{5, 5}},
+		{seeks: []int64{6}, want: []int64{18}},
+		{seeks: []int64{6, 3, 19}, want: []int64{18, 18, 31}},
+		{seeks: []int64{20, 32}, want: []int64{31, -1}},
+		{seeks: []int64{40}, want: []int64{-1}},
+	}
+
+	for _, test := range tests {
+		it := newResolutionIterator(series.Iterator(), 10, DownsampleLast)
+		for i, seek := range test.seeks {
+			ok := it.Seek(seek)
+			if got, _ := it.At(); ok != (test.want[i] >= 0) || ok && got != test.want[i] {
+				t.Fatalf("seeks %v: want %d after seeking %d, got %d (%v)", test.seeks, test.want[i], seek, got, ok)
+			}
+		}
+	}
+
+	// Next goes on from the bucket seeked
+	it := newResolutionIterator(series.Iterator(), 10, DownsampleLast)
+	if !it.Seek(6) || !it.Next() {
+		t.Fatalf("expected samples after seeking")
+	}
+	if got, _ := it.At(); got != 31 || it.Next() {
+		t.Fatalf("want the last bucket after the one seeked, got %d", got)
+	}
+}
+
+func TestResolutionSeriesSet(t *testing.T) {
+	set := newResolutionSeriesSet(&concreteSeriesSet{series: []Series{
+		&concreteSeries{labels: labels.FromStrings("__name__", "up"), samples: []pb.Point{{T: 1, V: 1}, {T: 2, V: 3}, {T: 11, V: 5}}},
+	}}, 10, DownsampleAvg)
+
+	if !set.Next() {
+		t.Fatalf("expected a series")
+	}
+	var got []pb.Point
+	for it := set.At().Iterator(); it.Next(); {
+		t, v := it.At()
+		got = append(got, pb.Point{T: t, V: v})
+	}
+	if want := []pb.Point{{T: 2, V: 2}, {T: 11, V: 5}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	if set.Next() {
+		t.Fatalf("expected a single series")
+	}
+
+	unchanged := &concreteSeriesSet{}
+	if newResolutionSeriesSet(unchanged, 0, DownsampleAvg) != SeriesSet(unchanged) {
+		t.Fatalf("expected the set untouched without a resolution")
+	}
+}
@@ -0,0 +1,244 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"io"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/tcp"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// ErrResponseTooLarge is returned by a query whose series read from the shards are above
+// QueryConfig.MaxResponseBytes or QueryConfig.MaxResponseSeries.
+var ErrResponseTooLarge = errors.New("response too large")
+
+var pointSize = int64(unsafe.Sizeof(pb.Point{}))
+
+// responseBudget accounts the series and bytes read from all the shards of a query against the configured caps.
+// It's shared by the selects of the query, so that they can't hold more than the caps in the gateway together.
+type responseBudget struct {
+	maxBytes  int64
+	maxSeries int64
+	bytes     int64
+	series    int64
+	exceeded  int32
+}
+
+// newResponseBudget returns a budget of the configured caps, nil if none is configured.
+func newResponseBudget() *responseBudget {
+	cfg := queryConfig()
+	if cfg.MaxResponseBytes == 0 && cfg.MaxResponseSeries <= 0 {
+		return nil
+	}
+	return &responseBudget{
+		maxBytes:  int64(cfg.MaxResponseBytes),
+		maxSeries: int64(cfg.MaxResponseSeries),
+	}
+}
+
+// add accounts n bytes of series, an ErrResponseTooLarge is returned once a cap is hit.
+func (b *responseBudget) add(n int64, series int64) error {
+	if atomic.LoadInt32(&b.exceeded) == 1 {
+		return ErrResponseTooLarge
+	}
+
+	bytes, series := atomic.AddInt64(&b.bytes, n), atomic.AddInt64(&b.series, series)
+	if b.maxBytes > 0 && bytes > b.maxBytes {
+		b.exceed()
+		return errors.Wrapf(ErrResponseTooLarge, "more than %d bytes read from the shards", b.maxBytes)
+	}
+	if b.maxSeries > 0 && series > b.maxSeries {
+		b.exceed()
+		return errors.Wrapf(ErrResponseTooLarge, "more than %d series read from the shards", b.maxSeries)
+	}
+	return nil
+}
+
+func (b *responseBudget) exceed() {
+	if atomic.CompareAndSwapInt32(&b.exceeded, 0, 1) {
+		responsesTooLarge.Inc()
+	}
+}
+
+func (b *responseBudget) isExceeded() bool {
+	return atomic.LoadInt32(&b.exceeded) == 1
+}
+
+// accountedQuerier accounts the series selected from a shard against the budget of the query. The responses
+// read through a connection are accounted by checkFrame before they are decoded, the others, e.g. of a local
+// storage, as their series are read through.
+type accountedQuerier struct {
+	Querier
+	shardID string
+	budget  *responseBudget
+	framed  int32 // set once a response is accounted by checkFrame
+}
+
+// checkFrame accounts a select response of the shard by the size of its proto and the number of its series,
+// before it's decoded. Once the budget is exceeded, the connection it's read through is closed by the client.
+func (q *accountedQuerier) checkFrame(msgType tcp.MsgType, proto []byte) error {
+	if msgType != tcp.BackendSelectResponseType {
+		return nil
+	}
+
+	series, err := backendpb.CountSeries(proto)
+	if err != nil {
+		return nil // left to the decoding to fail
+	}
+	atomic.StoreInt32(&q.framed, 1)
+
+	return errors.Wrapf(q.budget.add(int64(len(proto)), int64(series)), "shard %s", q.shardID)
+}
+
+func (q *accountedQuerier) Select(params *SelectParams, matchers ...*labels.Matcher) (SeriesSet, Warnings, error) {
+	if q.budget.isExceeded() {
+		return nil, nil, ErrResponseTooLarge
+	}
+
+	set, warnings, err := q.Querier.Select(params, matchers...)
+	if err != nil {
+		return set, warnings, err
+	}
+	return &accountedSeriesSet{SeriesSet: set, shardID: q.shardID, budget: q.budget, framed: atomic.LoadInt32(&q.framed) == 1}, warnings, nil
+}
+
+// accountedSeriesSet accounts every series as it's read through, once the budget of the query is exceeded
+// by any of its shards, it fails and closes the underlying series set, dropping the series not read yet.
+type accountedSeriesSet struct {
+	SeriesSet
+	shardID  string
+	budget   *responseBudget
+	framed   bool  // the series are accounted by the frames they came in already
+	bytes    int64 // read from the shard
+	err      error
+	closed   bool
+	observed bool
+}
+
+func (s *accountedSeriesSet) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	if s.budget.isExceeded() {
+		s.fail(ErrResponseTooLarge)
+		return false
+	}
+	if !s.SeriesSet.Next() {
+		s.observe()
+		return false
+	}
+
+	n := seriesBytes(s.SeriesSet.At())
+	s.bytes += n
+	if s.framed {
+		return true
+	}
+	if err := s.budget.add(n, 1); err != nil {
+		s.fail(err)
+		return false
+	}
+	return true
+}
+
+func (s *accountedSeriesSet) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.SeriesSet.Err()
+}
+
+func (s *accountedSeriesSet) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if closer, ok := s.SeriesSet.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (s *accountedSeriesSet) fail(err error) {
+	s.err = errors.Wrapf(err, "shard %s", s.shardID)
+	s.observe()
+	s.Close()
+}
+
+func (s *accountedSeriesSet) observe() {
+	if s.observed {
+		return
+	}
+	s.observed = true
+
+	shardResponseBytes.WithLabelValues(s.shardID).Add(float64(s.bytes))
+}
+
+// seriesBytes estimates the memory a series read from a shard takes, i.e. its labels and points, or chunks.
+// The points of the series other than the ones decoded from a shard response aren't known, only the labels count.
+func seriesBytes(s Series) int64 {
+	var (
+		lbls   labels.Labels
+		points int
+	)
+	var n int64
+	switch c := s.(type) {
+	case *concreteSeries:
+		lbls, points = c.labels, len(c.samples)
+	case *rawChunkSeries:
+		lbls = c.labels
+		for _, chk := range c.chunks {
+			n += int64(len(chk.Data))
+		}
+	default:
+		lbls = s.Labels()
+	}
+
+	n += int64(points) * pointSize
+	for _, l := range lbls {
+		n += int64(len(l.Name) + len(l.Value))
+	}
+	return n
+}
+
+var (
+	shardResponseBytes *prometheus.CounterVec
+	responsesTooLarge  prometheus.Counter
+)
+
+func init() {
+	shardResponseBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "baudtime",
+		Subsystem: "query",
+		Name:      "shard_response_bytes_total",
+		Help:      "Estimated bytes of the series read from a shard by the queries.",
+	}, []string{"shard"})
+	responsesTooLarge = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "baudtime",
+		Subsystem: "query",
+		Name:      "responses_too_large_total",
+		Help:      "Total number of queries aborted for reading more series or bytes from the shards than allowed.",
+	})
+
+	prometheus.MustRegister(shardResponseBytes, responsesTooLarge)
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/tcp"
+	"github.com/baudtime/baudtime/util/toml"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// bigSeriesSet returns a series set of n series of the shard, each of which has points points.
+func bigSeriesSet(shard string, n, points int) *concreteSeriesSet {
+	set := &concreteSeriesSet{}
+	for i := 0; i < n; i++ {
+		set.series = append(set.series, &concreteSeries{
+			labels:  labels.FromStrings("__name__", "up", "instance", fmt.Sprintf("%s-%03d", shard, i)),
+			samples: make([]pb.Point, points),
+		})
+	}
+	return set
+}
+
+func TestMergeQuerier_ResponseTooLarge(t *testing.T) {
+	bak := vars.Cfg.Gateway
+	defer func() { vars.Cfg.Gateway = bak }()
+
+	seriesBytes := seriesBytes(bigSeriesSet("shard-1", 1, 100).series[0])
+
+	tests := []struct {
+		query    vars.QueryConfig
+		tooLarge bool
+	}{
+		{query: vars.QueryConfig{}},
+		{query: vars.QueryConfig{MaxResponseBytes: toml.Size(30 * seriesBytes)}},
+		{query: vars.QueryConfig{MaxResponseSeries: 30}},
+		{query: vars.QueryConfig{MaxResponseBytes: toml.Size(15 * seriesBytes)}, tooLarge: true},
+		{query: vars.QueryConfig{MaxResponseSeries: 15}, tooLarge: true},
+		{query: vars.QueryConfig{MaxResponseSeries: 15, PartialResponse: true}, tooLarge: true},
+	}
+
+	for i, test := range tests {
+		vars.Cfg.Gateway = &vars.GatewayConfig{Query: test.query}
+		budget := newResponseBudget()
+		if (budget == nil) != (test.query.MaxResponseBytes == 0 && test.query.MaxResponseSeries == 0) {
+			t.Fatalf("case %d: unexpected budget %v", i, budget)
+		}
+
+		var (
+			shards   []*closingQuerier
+			queriers []Querier
+		)
+		for _, shardID := range []string{"shard-1", "shard-2", "shard-3"} {
+			shard := &closingQuerier{}
+			shards = append(shards, shard)
+
+			var q Querier = &fakeQuerier{set: &closingSeriesSet{SeriesSet: bigSeriesSet(shardID, 10, 100), closes: &shard.setCloses}}
+			if budget != nil {
+				q = &accountedQuerier{Querier: q, shardID: shardID, budget: budget}
+			}
+			queriers = append(queriers, q)
+		}
+
+		set, _, err := NewMergeQuerier(context.Background(), queriers).Select(&SelectParams{})
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+
+		read := 0
+		for set.Next() {
+			read++
+		}
+
+		if !test.tooLarge {
+			if set.Err() != nil || read != 30 {
+				t.Fatalf("case %d: want all the 30 series, got %d, %v", i, read, set.Err())
+			}
+			continue
+		}
+
+		if errors.Cause(set.Err()) != ErrResponseTooLarge {
+			t.Fatalf("case %d: want %v, got %v", i, ErrResponseTooLarge, set.Err())
+		}
+		if read > 15 {
+			t.Fatalf("case %d: %d series read beyond the cap", i, read)
+		}
+		// the series of shard-1 are all read before the cap is hit, the rest are dropped
+		for j, shard := range shards[1:] {
+			if shard.setCloses != 1 {
+				t.Fatalf("case %d: the series set of shard %d closed %d times, want once", i, j+1, shard.setCloses)
+			}
+		}
+
+		// the following selects of the query fail at once
+		if _, _, err = queriers[0].Select(&SelectParams{}); err != ErrResponseTooLarge {
+			t.Fatalf("case %d: want %v of a select after the cap is hit, got %v", i, ErrResponseTooLarge, err)
+		}
+	}
+}
+
+func TestAccountedQuerier_CheckFrame(t *testing.T) {
+	bak := vars.Cfg.Gateway
+	defer func() { vars.Cfg.Gateway = bak }()
+	vars.Cfg.Gateway = &vars.GatewayConfig{Query: vars.QueryConfig{MaxResponseSeries: 15}}
+
+	frame := func(n int) []byte {
+		resp := &backendpb.SelectResponse{Status: pb.StatusCode_Succeed}
+		for i := 0; i < n; i++ {
+			resp.Series = append(resp.Series, &pb.Series{Labels: []pb.Label{{Name: "__name__", Value: "up"}}})
+		}
+		b, err := resp.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	budget := newResponseBudget()
+	q1 := &accountedQuerier{Querier: &fakeQuerier{set: bigSeriesSet("shard-1", 10, 100)}, shardID: "shard-1", budget: budget}
+	q2 := &accountedQuerier{Querier: &fakeQuerier{set: bigSeriesSet("shard-2", 10, 100)}, shardID: "shard-2", budget: budget}
+
+	if err := q1.checkFrame(tcp.GeneralResponseType, []byte("not a select response")); err != nil {
+		t.Fatalf("want a frame of another type let through, got %v", err)
+	}
+	if err := q1.checkFrame(tcp.BackendSelectResponseType, frame(10)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the series of a frame accounted aren't accounted again as they are read through
+	set, _, err := q1.Select(&SelectParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for set.Next() {
+	}
+	if set.Err() != nil || budget.series != 10 {
+		t.Fatalf("want 10 series accounted once, got %d, %v", budget.series, set.Err())
+	}
+
+	// the frame of another shard over the cap is refused before it's decoded
+	if err = q2.checkFrame(tcp.BackendSelectResponseType, frame(10)); errors.Cause(err) != ErrResponseTooLarge {
+		t.Fatalf("want %v, got %v", ErrResponseTooLarge, err)
+	}
+}
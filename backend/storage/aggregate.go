@@ -0,0 +1,106 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"math"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	tm "github.com/baudtime/baudtime/util/time"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/tsdb"
+	"github.com/prometheus/tsdb/labels"
+)
+
+// aggrGroup is the partial aggregate of the series sharing the same grouping labels.
+type aggrGroup struct {
+	series *pb.Series
+	seen   bool // whether any series of the group has a sample at the current step
+}
+
+// selectAggregated selects the series like selectVectors, but returns their aggregates by the
+// grouping labels at every step instead. The points of an aggregate are at the steps rather
+// than at the samples, a stale marker follows the last point of a run, so that a step without
+// any sample isn't filled with the previous one in the lookback delta of the query engine.
+// The samples out of bounds are left out of the aggregates.
+func selectAggregated(q tsdb.Querier, matchers []*backendpb.Matcher, it *tm.TimestampIter, aggr *backendpb.Aggregation, bounds *backendpb.ValueBounds) ([]*pb.Series, error) {
+	if aggr.Op != backendpb.AggrOp_AggrSum {
+		return nil, errors.Errorf("unsupported aggregation %s", aggr.Op)
+	}
+
+	ms, err := ProtoToMatchers(matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		groups  = make(map[string]*aggrGroup)
+		ordered []*aggrGroup
+		buf     = make(labels.Labels, 0, len(aggr.Grouping))
+	)
+
+	for it.Next() {
+		ts := it.At()
+
+		err = eachVectorAt(q, ms, ts, func(lbls labels.Labels, _ int64, v float64) {
+			if !bounds.Contains(v) {
+				return
+			}
+
+			buf = buf[:0]
+			for _, name := range aggr.Grouping {
+				if val := lbls.Get(name); val != "" {
+					buf = append(buf, labels.Label{Name: name, Value: val})
+				}
+			}
+
+			key := buf.String()
+			g, found := groups[key]
+			if !found {
+				sorted := labels.New(buf...)
+				g = &aggrGroup{series: &pb.Series{Labels: LabelsToProto(sorted)}}
+				groups[key] = g
+				ordered = append(ordered, g)
+			}
+
+			if g.seen {
+				g.series.Points[len(g.series.Points)-1].V += v
+			} else {
+				g.series.Points = append(g.series.Points, pb.Point{T: ts, V: v})
+				g.seen = true
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, g := range ordered {
+			if g.seen {
+				g.seen = false
+			} else if points := g.series.Points; !value.IsStaleNaN(points[len(points)-1].V) {
+				g.series.Points = append(points, pb.Point{T: ts, V: math.Float64frombits(value.StaleNaN)})
+			}
+		}
+	}
+
+	series := make([]*pb.Series, 0, len(ordered))
+	for _, g := range ordered {
+		series = append(series, g.series)
+	}
+	return series, nil
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	tm "github.com/baudtime/baudtime/util/time"
+	"github.com/baudtime/baudtime/util/toml"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/tsdb"
+	"github.com/prometheus/tsdb/labels"
+)
+
+func TestSelectAggregated(t *testing.T) {
+	storageCfg := vars.Cfg.Storage
+	vars.Cfg.Storage = &vars.StorageConfig{TSDB: vars.TSDBConfig{LookbackDelta: toml.Duration(time.Second)}}
+	defer func() {
+		vars.Cfg.Storage = storageCfg
+	}()
+
+	dir, err := ioutil.TempDir("", "aggregate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := tsdb.Open(dir, nil, nil, &tsdb.Options{BlockRanges: []int64{7200000}, NoLockfile: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const base, step = int64(1000000), int64(15000)
+
+	app := db.Appender()
+	samples := []struct {
+		lbls  labels.Labels
+		steps int
+		v     float64
+	}{
+		{lbls: labels.FromStrings("__name__", "up", "job", "a", "instance", "1"), steps: 3, v: 1},
+		{lbls: labels.FromStrings("__name__", "up", "job", "a", "instance", "2"), steps: 2, v: 10},
+		{lbls: labels.FromStrings("__name__", "up", "job", "b", "instance", "3"), steps: 1, v: 5},
+		{lbls: labels.FromStrings("__name__", "down", "job", "a", "instance", "1"), steps: 5, v: 100},
+	}
+	for _, s := range samples {
+		for i := 0; i < s.steps; i++ {
+			if _, err = app.Add(s.lbls, base+int64(i)*step, s.v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err = app.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := db.Querier(base-1000, base+4*step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	stale := math.Float64frombits(value.StaleNaN)
+	matchers := []*backendpb.Matcher{{Type: backendpb.MatchType_MatchEqual, Name: "__name__", Value: "up"}}
+
+	tests := []struct {
+		grouping []string
+		bounds   *backendpb.ValueBounds
+		want     map[string][]pb.Point
+	}{
+		{
+			grouping: []string{"job"},
+			want: map[string][]pb.Point{
+				"{job=a}": {{T: base, V: 11}, {T: base + step, V: 11}, {T: base + 2*step, V: 1}, {T: base + 3*step, V: stale}},
+				"{job=b}": {{T: base, V: 5}, {T: base + step, V: stale}},
+			},
+		},
+		{
+			grouping: nil,
+			want: map[string][]pb.Point{
+				"{}": {{T: base, V: 16}, {T: base + step, V: 11}, {T: base + 2*step, V: 1}, {T: base + 3*step, V: stale}},
+			},
+		},
+		{
+			// the samples below 6 are left out, a group without any sample left has no series
+			grouping: []string{"job"},
+			bounds:   &backendpb.ValueBounds{Min: 6, Max: math.Inf(1)},
+			want: map[string][]pb.Point{
+				"{job=a}": {{T: base, V: 10}, {T: base + step, V: 10}, {T: base + 2*step, V: stale}},
+			},
+		},
+	}
+
+	for i, test := range tests {
+		series, err := selectAggregated(q, matchers, tm.NewTimestampIter(base, base+4*step, step), &backendpb.Aggregation{Op: backendpb.AggrOp_AggrSum, Grouping: test.grouping}, test.bounds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(series) != len(test.want) {
+			t.Fatalf("case %d: want %d series, got %d", i, len(test.want), len(series))
+		}
+
+		for _, s := range series {
+			lbls := toString(s.Labels)
+			want, found := test.want[lbls]
+			if !found || len(s.Points) != len(want) {
+				t.Fatalf("case %d: unexpected series %s %v", i, lbls, s.Points)
+			}
+			for j, p := range want {
+				if s.Points[j].T != p.T || math.Float64bits(s.Points[j].V) != math.Float64bits(p.V) {
+					t.Fatalf("case %d: series %s point %d: want %v, got %v", i, lbls, j, p, s.Points[j])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"sort"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/prometheus/tsdb"
+	"github.com/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/tsdb/labels"
+)
+
+// chunkSeries are the series selected in chunks, by their labels.
+type chunkSeries struct {
+	byLabels map[string]int
+	lsets    []labels.Labels
+	series   []*pb.Series
+}
+
+func (s *chunkSeries) add(lset labels.Labels, chunks []pb.Chunk) {
+	key := lset.String()
+	i, found := s.byLabels[key]
+	if !found {
+		i = len(s.series)
+		s.byLabels[key] = i
+		s.lsets = append(s.lsets, append(labels.Labels(nil), lset...))
+		s.series = append(s.series, &pb.Series{Labels: LabelsToProto(lset)})
+	}
+	s.series[i].Chunks = append(s.series[i].Chunks, chunks...)
+}
+
+func (s *chunkSeries) Len() int           { return len(s.series) }
+func (s *chunkSeries) Less(i, j int) bool { return labels.Compare(s.lsets[i], s.lsets[j]) < 0 }
+func (s *chunkSeries) Swap(i, j int) {
+	s.lsets[i], s.lsets[j] = s.lsets[j], s.lsets[i]
+	s.series[i], s.series[j] = s.series[j], s.series[i]
+}
+
+// selectChunks selects the series in [mint, maxt] in chunks rather than points, sorted by their labels.
+// The chunks of the persisted blocks are passed as they are, with the samples out of [mint, maxt] in them,
+// while the ones of the head, still being appended to, are encoded from the samples in [mint, maxt].
+// If hash isn't 0, only the series of it are selected.
+func selectChunks(db *tsdb.DB, matchers []*backendpb.Matcher, mint, maxt int64, hash uint64) ([]*pb.Series, error) {
+	ms, err := ProtoToMatchers(matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := &chunkSeries{byLabels: make(map[string]int)}
+
+	for _, b := range db.Blocks() {
+		if !b.OverlapsClosedInterval(mint, maxt) {
+			continue
+		}
+		if err = selectBlockChunks(b, ms, mint, maxt, hash, selected); err != nil {
+			return nil, err
+		}
+	}
+
+	if head := db.Head(); maxt >= head.MinTime() {
+		if err = selectHeadChunks(head, ms, mint, maxt, hash, selected); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Sort(selected)
+	return selected.series, nil
+}
+
+func selectBlockChunks(b *tsdb.Block, ms []labels.Matcher, mint, maxt int64, hash uint64, selected *chunkSeries) error {
+	ir, err := b.Index()
+	if err != nil {
+		return err
+	}
+	defer ir.Close()
+
+	cr, err := b.Chunks()
+	if err != nil {
+		return err
+	}
+	defer cr.Close()
+
+	tr, err := b.Tombstones()
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+
+	set, err := tsdb.LookupChunkSeries(ir, tr, ms...)
+	if err != nil {
+		return err
+	}
+
+	for set.Next() {
+		lset, metas, deleted := set.At()
+		if hash != 0 && lset.Hash() != hash {
+			continue
+		}
+
+		var chunks []pb.Chunk
+		for _, meta := range metas {
+			if meta.MaxTime < mint || meta.MinTime > maxt {
+				continue
+			}
+
+			c, err := cr.Chunk(meta.Ref)
+			if err != nil {
+				return err
+			}
+			if chunks, err = appendRawChunk(chunks, c, meta.MinTime, meta.MaxTime, deleted); err != nil {
+				return err
+			}
+		}
+		if len(chunks) > 0 {
+			selected.add(lset, chunks)
+		}
+	}
+	return set.Err()
+}
+
+// appendRawChunk appends the chunk of [mint, maxt] as it is, copied out of the block being read. The ones which
+// are partly deleted or in another encoding are encoded again from their samples left.
+func appendRawChunk(chunks []pb.Chunk, c chunkenc.Chunk, mint, maxt int64, deleted tsdb.Intervals) ([]pb.Chunk, error) {
+	overlapped := false
+	for _, itv := range deleted {
+		if itv.Maxt >= mint && itv.Mint <= maxt {
+			overlapped = true
+		}
+	}
+
+	if c.Encoding() == chunkenc.EncXOR && !overlapped {
+		return append(chunks, pb.Chunk{Mint: mint, Maxt: maxt, Data: append([]byte(nil), c.Bytes()...)}), nil
+	}
+
+	var points []pb.Point
+	it := c.Iterator(nil)
+	for it.Next() {
+		t, v := it.At()
+		if !isDeleted(t, deleted) {
+			points = append(points, pb.Point{T: t, V: v})
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	encoded, err := pb.EncodeChunks(points)
+	if err != nil {
+		return nil, err
+	}
+	return append(chunks, encoded...), nil
+}
+
+func isDeleted(t int64, deleted tsdb.Intervals) bool {
+	for _, itv := range deleted {
+		if t >= itv.Mint && t <= itv.Maxt {
+			return true
+		}
+	}
+	return false
+}
+
+func selectHeadChunks(head *tsdb.Head, ms []labels.Matcher, mint, maxt int64, hash uint64, selected *chunkSeries) error {
+	if hmin := head.MinTime(); hmin > mint {
+		mint = hmin
+	}
+
+	q, err := tsdb.NewBlockQuerier(head, mint, maxt)
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	set, err := q.Select(ms...)
+	if err != nil {
+		return err
+	}
+
+	var points []pb.Point
+	for set.Next() {
+		series := set.At()
+		if hash != 0 && series.Labels().Hash() != hash {
+			continue
+		}
+
+		points = points[:0]
+		it := series.Iterator()
+		for it.Next() {
+			t, v := it.At()
+			points = append(points, pb.Point{T: t, V: v})
+		}
+		if err = it.Err(); err != nil {
+			return err
+		}
+
+		chunks, err := pb.EncodeChunks(points)
+		if err != nil {
+			return err
+		}
+		if len(chunks) > 0 {
+			selected.add(series.Labels(), chunks)
+		}
+	}
+	return set.Err()
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CompactionWatcher tells whether the tsdb is compacting, it's passed to tsdb.Open as the metrics registerer
+// and keeps the gauge tsdb sets while populating a block. The other metrics are dropped as with a nil registerer.
+type CompactionWatcher struct {
+	reg *prometheus.Registry
+}
+
+const populatingBlockMetric = `"prometheus_tsdb_compaction_populating_block"`
+
+func NewCompactionWatcher() *CompactionWatcher {
+	return &CompactionWatcher{reg: prometheus.NewRegistry()}
+}
+
+func (w *CompactionWatcher) Register(c prometheus.Collector) error {
+	if g, ok := c.(prometheus.Gauge); ok && strings.Contains(g.Desc().String(), populatingBlockMetric) {
+		return w.reg.Register(g)
+	}
+	return nil
+}
+
+func (w *CompactionWatcher) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := w.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (w *CompactionWatcher) Unregister(c prometheus.Collector) bool {
+	return w.reg.Unregister(c)
+}
+
+// Compacting reports whether a block is being populated by a compaction, it's false for a nil watcher.
+func (w *CompactionWatcher) Compacting() bool {
+	if w == nil {
+		return false
+	}
+
+	mfs, err := w.reg.Gather()
+	if err != nil {
+		return false
+	}
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			if m.GetGauge().GetValue() > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCompactionWatcher(t *testing.T) {
+	var nilWatcher *CompactionWatcher
+	if nilWatcher.Compacting() {
+		t.Fatalf("want a nil watcher never compacting")
+	}
+
+	w := NewCompactionWatcher()
+	populating := prometheus.NewGauge(prometheus.GaugeOpts{Name: "prometheus_tsdb_compaction_populating_block"})
+	w.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "prometheus_tsdb_compactions_total"}), populating)
+
+	if w.Compacting() {
+		t.Fatalf("want not compacting before a block is populated")
+	}
+	populating.Set(1)
+	if !w.Compacting() {
+		t.Fatalf("want compacting while a block is populated")
+	}
+	populating.Set(0)
+	if w.Compacting() {
+		t.Fatalf("want not compacting once the block is populated")
+	}
+}
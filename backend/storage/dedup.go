@@ -0,0 +1,83 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+type writerSeq struct {
+	seq      uint64
+	lastSeen time.Time
+}
+
+// seqWindow remembers the last batch applied of each writer, so that a batch retried by
+// a writer is dropped if it has been applied already. Writers quiet for longer than the
+// window are forgotten.
+// The window isn't replicated as such, a slave having its own window fills it with the batches fed
+// by the master, which keep their writer and seq, so a batch retried on a slave promoted is dropped.
+// The batches in the blocks a slave syncs when it joins aren't known to it, nor are the batches of a
+// master whose slave has no window, they're written again if they're retried after a failover.
+type seqWindow struct {
+	mtx     sync.Mutex
+	window  time.Duration
+	writers map[string]*writerSeq
+	lastGC  time.Time
+}
+
+func newSeqWindow(window time.Duration) *seqWindow {
+	return &seqWindow{
+		window:  window,
+		writers: make(map[string]*writerSeq),
+		lastGC:  time.Now(),
+	}
+}
+
+// applied reports whether the batch seq of writer has been applied.
+func (w *seqWindow) applied(writer string, seq uint64) bool {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	ws, found := w.writers[writer]
+	return found && seq <= ws.seq && time.Since(ws.lastSeen) < w.window
+}
+
+// record marks the batch seq of writer applied.
+func (w *seqWindow) record(writer string, seq uint64) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	now := time.Now()
+	if now.Sub(w.lastGC) >= w.window {
+		for k, ws := range w.writers {
+			if now.Sub(ws.lastSeen) >= w.window {
+				delete(w.writers, k)
+			}
+		}
+		w.lastGC = now
+	}
+
+	ws, found := w.writers[writer]
+	if !found {
+		w.writers[writer] = &writerSeq{seq: seq, lastSeen: now}
+		return
+	}
+	if seq > ws.seq {
+		ws.seq = seq
+	}
+	ws.lastSeen = now
+}
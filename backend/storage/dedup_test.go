@@ -0,0 +1,146 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/util/syn"
+	"github.com/prometheus/tsdb"
+	"github.com/prometheus/tsdb/labels"
+)
+
+// fakeStorage records the samples committed, with the seq of the batch they came in.
+type fakeStorage struct {
+	applied    map[int64]uint64 // sample timestamp -> seq
+	seq        uint64
+	failCommit bool
+}
+
+type fakeAppender struct {
+	s       *fakeStorage
+	pending []int64
+}
+
+func (app *fakeAppender) Add(l labels.Labels, t int64, v float64) (uint64, error) {
+	if t < 0 {
+		return 0, tsdb.ErrOutOfOrderSample
+	}
+	app.pending = append(app.pending, t)
+	return 0, nil
+}
+
+func (app *fakeAppender) AddFast(ref uint64, t int64, v float64) error {
+	return errors.New("not implemented")
+}
+
+func (app *fakeAppender) Commit() error {
+	if app.s.failCommit {
+		return errors.New("disk full")
+	}
+	for _, t := range app.pending {
+		app.s.applied[t] = app.s.seq
+	}
+	return nil
+}
+
+func (app *fakeAppender) Rollback() error {
+	return nil
+}
+
+func TestHandleAddReq_Dedup(t *testing.T) {
+	s := &fakeStorage{applied: make(map[int64]uint64)}
+	handler := &AddReqHandler{
+		appender: func() tsdb.Appender { return &fakeAppender{s: s} },
+		addStat:  &AddStat{},
+		symbolsK: syn.NewMap(16, syn.StringHash),
+		symbolsV: syn.NewMap(16, syn.StringHash),
+		applied:  newSeqWindow(time.Minute),
+	}
+
+	batch := func(seq uint64, ts ...int64) *backendpb.AddRequest {
+		req := &backendpb.AddRequest{WriterID: "gateway-1", Seq: seq}
+		for _, t := range ts {
+			req.Series = append(req.Series, &pb.Series{
+				Labels: []pb.Label{{Name: "__name__", Value: "up"}},
+				Points: []pb.Point{{T: t, V: 1}},
+			})
+		}
+		return req
+	}
+	send := func(req *backendpb.AddRequest) {
+		s.seq = req.Seq
+		handler.HandleAddReq(req)
+	}
+
+	// the batch is partially applied, a sample is out of order, and the ack is lost
+	send(batch(1, 1000, -1, 2000))
+	if len(s.applied) != 2 {
+		t.Fatalf("want 2 samples applied, got %v", s.applied)
+	}
+
+	// the retry, maybe after a failover, is dropped
+	s.applied = make(map[int64]uint64)
+	send(batch(1, 1000, -1, 2000))
+	if len(s.applied) != 0 {
+		t.Fatalf("want the retried batch dropped, got %v", s.applied)
+	}
+	if handler.addStat.Duplicated != 1 {
+		t.Fatalf("want 1 duplicated batch, got %d", handler.addStat.Duplicated)
+	}
+
+	// a batch failed to commit is applied on retry
+	s.failCommit = true
+	send(batch(2, 3000))
+	s.failCommit = false
+	send(batch(2, 3000))
+	if seq, found := s.applied[3000]; !found || seq != 2 {
+		t.Fatalf("want the retried batch applied after a failed commit, got %v", s.applied)
+	}
+
+	// batches of other writers or without seq are not deduplicated
+	send(&backendpb.AddRequest{WriterID: "gateway-2", Seq: 2, Series: batch(0, 4000).Series})
+	send(batch(0, 5000))
+	send(batch(0, 5000))
+	if _, found := s.applied[4000]; !found {
+		t.Fatalf("want the batch of another writer applied")
+	}
+	if _, found := s.applied[5000]; !found {
+		t.Fatalf("want the batch without seq applied")
+	}
+}
+
+func TestSeqWindow_Expire(t *testing.T) {
+	w := newSeqWindow(10 * time.Millisecond)
+	w.record("gateway-1", 5)
+	if !w.applied("gateway-1", 4) || !w.applied("gateway-1", 5) || w.applied("gateway-1", 6) {
+		t.Fatalf("unexpected applied batches")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if w.applied("gateway-1", 5) {
+		t.Fatalf("want the writer forgotten out of the window")
+	}
+
+	w.record("gateway-2", 1)
+	if _, found := w.writers["gateway-1"]; found {
+		t.Fatalf("want quiet writers collected")
+	}
+}
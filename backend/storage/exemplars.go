@@ -0,0 +1,99 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"sync"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/prometheus/tsdb/labels"
+)
+
+// Exemplar is an exemplar written with a sample of the series of Labels.
+type Exemplar struct {
+	Labels labels.Labels
+	pb.Exemplar
+}
+
+// exemplarRing keeps the latest exemplars written, the oldest one is overwritten by a new one once it's full.
+// The tsdb has no place for exemplars, so they're kept in memory only and lost on restart.
+type exemplarRing struct {
+	mtx       sync.RWMutex
+	exemplars []Exemplar
+	next      int
+	full      bool
+}
+
+// newExemplarRing returns a ring of capacity exemplars, nil if capacity isn't positive, which drops them all.
+func newExemplarRing(capacity int) *exemplarRing {
+	if capacity <= 0 {
+		return nil
+	}
+	return &exemplarRing{exemplars: make([]Exemplar, capacity)}
+}
+
+func (r *exemplarRing) add(exemplars ...Exemplar) {
+	if r == nil || len(exemplars) == 0 {
+		return
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, e := range exemplars {
+		r.exemplars[r.next] = e
+		if r.next++; r.next == len(r.exemplars) {
+			r.next, r.full = 0, true
+		}
+	}
+}
+
+// Select returns the exemplars in [mint, maxt] of the series matching all the matchers, in the order they're written.
+func (r *exemplarRing) Select(mint, maxt int64, matchers ...labels.Matcher) []Exemplar {
+	if r == nil {
+		return nil
+	}
+
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	var (
+		selected []Exemplar
+		oldest   = 0
+		n        = r.next
+	)
+	if r.full {
+		oldest, n = r.next, len(r.exemplars)
+	}
+
+	for i := 0; i < n; i++ {
+		e := r.exemplars[(oldest+i)%len(r.exemplars)]
+		if e.T < mint || e.T > maxt || !matchAll(e.Labels, matchers) {
+			continue
+		}
+		selected = append(selected, e)
+	}
+	return selected
+}
+
+func matchAll(lset labels.Labels, matchers []labels.Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(lset.Get(m.Name())) {
+			return false
+		}
+	}
+	return true
+}
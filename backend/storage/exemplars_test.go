@@ -0,0 +1,79 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/util/syn"
+	"github.com/prometheus/tsdb"
+	"github.com/prometheus/tsdb/labels"
+)
+
+func TestHandleAddReq_Exemplars(t *testing.T) {
+	s := &fakeStorage{applied: make(map[int64]uint64)}
+	handler := &AddReqHandler{
+		appender:  func() tsdb.Appender { return &fakeAppender{s: s} },
+		addStat:   &AddStat{},
+		symbolsK:  syn.NewMap(16, syn.StringHash),
+		symbolsV:  syn.NewMap(16, syn.StringHash),
+		exemplars: newExemplarRing(3),
+	}
+
+	exemplar := func(trace string, t int64) *pb.Exemplar {
+		return &pb.Exemplar{Labels: []pb.Label{{Name: "trace_id", Value: trace}}, Value: 1, T: t}
+	}
+	send := func(job string, points ...pb.Point) {
+		handler.HandleAddReq(&backendpb.AddRequest{Series: []*pb.Series{{
+			Labels: []pb.Label{{Name: "__name__", Value: "latency"}, {Name: "job", Value: job}},
+			Points: points,
+		}}})
+	}
+	traces := func(exemplars []Exemplar) []string {
+		var ids []string
+		for _, e := range exemplars {
+			ids = append(ids, e.Labels.Get("job")+":"+e.Exemplar.Labels[0].Value)
+		}
+		return ids
+	}
+
+	send("api", pb.Point{T: 1000, V: 1, E: exemplar("t1", 1000)}, pb.Point{T: 2000, V: 1}, pb.Point{T: 3000, V: 1, E: exemplar("t2", 3000)})
+	send("db", pb.Point{T: -1, V: 1, E: exemplar("dropped", -1)}) // out of order, not written
+	send("db", pb.Point{T: 4000, V: 1, E: exemplar("t3", 4000)})
+
+	all := handler.Exemplars(0, 10000)
+	if got := traces(all); len(got) != 3 || got[0] != "api:t1" || got[1] != "api:t2" || got[2] != "db:t3" {
+		t.Fatalf("want the exemplars of the samples written, got %v", got)
+	}
+	if got := traces(handler.Exemplars(2000, 10000, labels.NewEqualMatcher("job", "api"))); len(got) != 1 || got[0] != "api:t2" {
+		t.Fatalf("want the exemplars selected by time and series, got %v", got)
+	}
+
+	// the oldest is overwritten once full
+	send("db", pb.Point{T: 5000, V: 1, E: exemplar("t4", 5000)})
+	if got := traces(handler.Exemplars(0, 10000)); len(got) != 3 || got[0] != "api:t2" || got[2] != "db:t4" {
+		t.Fatalf("want the oldest exemplar dropped, got %v", got)
+	}
+
+	// dropped without a ring
+	handler.exemplars = nil
+	send("db", pb.Point{T: 6000, V: 1, E: exemplar("t5", 6000)})
+	if got := handler.Exemplars(0, 10000); got != nil {
+		t.Fatalf("want no exemplar kept, got %v", got)
+	}
+}
@@ -52,7 +52,7 @@ type Heartbeat struct {
 
 func (h *Heartbeat) start() {
 	if h.masterCli == nil {
-		h.masterCli = client.NewBackendClient("rpl_s2m", h.masterAddr, 2)
+		h.masterCli = client.NewReplicationClient("rpl_s2m", h.masterAddr, 2)
 	}
 
 	heartbeat := &backendpb.SyncHeartbeat{
@@ -47,6 +47,9 @@ type ReplicateManager struct {
 	heartbeat          *Heartbeat
 	lastTRecvHeartbeat atomic.Value
 	sync.RWMutex
+
+	// sendToSlave sends the batches to be acknowledged by a quorum to the slaves, see ReplicateToQuorum.
+	sendToSlave func(ctx context.Context, slaveAddr string, request *backendpb.AddRequest) error
 }
 
 func NewReplicateManager(db *tsdb.DB) *ReplicateManager {
@@ -58,6 +61,7 @@ func NewReplicateManager(db *tsdb.DB) *ReplicateManager {
 	return &ReplicateManager{
 		id:          id,
 		sampleFeeds: new(sync.Map),
+		sendToSlave: sendToSlave,
 		db:          db,
 	}
 }
@@ -83,6 +87,7 @@ func (mgr *ReplicateManager) HandleWriteReq(request []byte) {
 			feed.(*sampleFeed).Close()
 			mgr.sampleFeeds.Delete(name)
 		}
+		closeQuorumClient(name)
 		level.Warn(Logger).Log("msg", "slave was removed", "slaveAddr", name)
 	}
 
@@ -298,7 +303,7 @@ func (mgr *ReplicateManager) HandleSlaveOfCmd(slaveOfCmd *backendpb.SlaveOfComma
 		} else if mgr.heartbeat.masterAddr == slaveOfCmd.MasterAddr {
 			return &pb.GeneralResponse{Status: pb.StatusCode_Succeed, Message: "already my slave"}
 		} else {
-			return &pb.GeneralResponse{Status: pb.StatusCode_Failed, Message: "already an slave of some other master, clean dirty data first"}
+			return &pb.GeneralResponse{Status: pb.StatusCode_Failed, Code: pb.ErrorCode_BadRequest, Message: "already an slave of some other master, clean dirty data first"}
 		}
 	} else {
 		if slaveOfCmd.MasterAddr == "" { //slaveof no one
@@ -333,7 +338,7 @@ func (mgr *ReplicateManager) HandleSlaveOfCmd(slaveOfCmd *backendpb.SlaveOfComma
 }
 
 func (mgr *ReplicateManager) syncHandshake(masterAddr string, slaveOfNoOne bool) (*backendpb.SyncHandshakeAck, error) {
-	masterCli := client.NewBackendClient("rpl_s2m", masterAddr, 2)
+	masterCli := client.NewReplicationClient("rpl_s2m", masterAddr, 2)
 	defer masterCli.Close()
 
 	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package replication
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/tcp"
+	"github.com/baudtime/baudtime/tcp/client"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+const quorumTimeout = 10 * time.Second
+
+// quorumClients are the connections to the slaves the batches to be acknowledged by a quorum
+// are sent over, they're apart from the sample feeds which don't tell whether a batch is applied.
+var quorumClients sync.Map //map[string]*client.Client
+
+// sendToSlave sends a batch to a slave over its quorum client and waits for it to be applied.
+func sendToSlave(ctx context.Context, slaveAddr string, request *backendpb.AddRequest) error {
+	cli, found := quorumClients.Load(slaveAddr)
+	if !found {
+		cli, _ = quorumClients.LoadOrStore(slaveAddr, client.NewBackendClient("rpl_quorum", slaveAddr, 1, 1))
+	}
+
+	resp, err := cli.(*client.Client).SyncRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	switch r := resp.(type) {
+	case *backendpb.AddResponse:
+		return r.Err()
+	case *pb.GeneralResponse:
+		return r.Err()
+	default:
+		return tcp.BadMsgTypeError
+	}
+}
+
+func closeQuorumClient(slaveAddr string) {
+	if cli, found := quorumClients.Load(slaveAddr); found {
+		quorumClients.Delete(slaveAddr)
+		cli.(*client.Client).Close()
+	}
+}
+
+// ReplicateToQuorum sends the batch applied by the master to the slaves being fed, and waits for a
+// majority of them to apply it too. It's sent besides the sample feeds, the slaves drop the one coming
+// later by the writer and seq of the batch only if their dedup_window is set, otherwise it's written
+// again and its samples fail as out of order or amended. The slaves not being fed yet, e.g. syncing the blocks,
+// don't count, so a master without any slave fed is a quorum by itself.
+// acked and replicas are the numbers of the replicas having applied the batch and asked to, the master included.
+func (mgr *ReplicateManager) ReplicateToQuorum(ctx context.Context, request *backendpb.AddRequest) (acked int, replicas int, err error) {
+	var slaveAddrs []string
+	mgr.sampleFeeds.Range(func(name, _ interface{}) bool {
+		slaveAddrs = append(slaveAddrs, name.(string))
+		return true
+	})
+
+	replicas = 1 + len(slaveAddrs)
+	if len(slaveAddrs) == 0 {
+		return 1, replicas, nil
+	}
+	need := len(slaveAddrs)/2 + 1
+
+	ctx, cancel := context.WithTimeout(ctx, quorumTimeout)
+	defer cancel()
+
+	slaveReq := *request
+	slaveReq.Ack = backendpb.AckLevel_AckOne
+
+	errCh := make(chan error, len(slaveAddrs))
+	for _, slaveAddr := range slaveAddrs {
+		go func(slaveAddr string) {
+			err := mgr.sendToSlave(ctx, slaveAddr, &slaveReq)
+			errCh <- errors.Wrapf(err, "slave %s", slaveAddr)
+		}(slaveAddr)
+	}
+
+	var multiErr error
+	for failed := 0; acked < need && failed <= len(slaveAddrs)-need; {
+		if err := <-errCh; err != nil {
+			failed++
+			multiErr = multierror.Append(multiErr, err)
+		} else {
+			acked++
+		}
+	}
+
+	if acked < need {
+		return 1 + acked, replicas, errors.Errorf("%d of %d slaves applied the batch, %d needed: %v", acked, len(slaveAddrs), need, multiErr)
+	}
+	return 1 + acked, replicas, nil
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package replication
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+)
+
+// fakeSlaves are the slaves being fed, applying the batches unless they're down or slow.
+type fakeSlaves struct {
+	mtx     sync.Mutex
+	down    map[string]bool
+	slow    map[string]bool
+	applied map[string][]backendpb.AckLevel
+}
+
+func (s *fakeSlaves) send(ctx context.Context, slaveAddr string, request *backendpb.AddRequest) error {
+	if s.slow[slaveAddr] {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if s.down[slaveAddr] {
+		return errors.New("connection refused")
+	}
+
+	s.mtx.Lock()
+	s.applied[slaveAddr] = append(s.applied[slaveAddr], request.Ack)
+	s.mtx.Unlock()
+	return nil
+}
+
+func TestReplicateToQuorum(t *testing.T) {
+	tests := []struct {
+		slaves       []string
+		down, slow   []string
+		wantErr      bool
+		wantReplicas int
+		minAcked     int
+	}{
+		{slaves: nil, wantReplicas: 1, minAcked: 1},
+		{slaves: []string{"s1"}, wantReplicas: 2, minAcked: 2},
+		{slaves: []string{"s1"}, down: []string{"s1"}, wantErr: true, wantReplicas: 2, minAcked: 1},
+		{slaves: []string{"s1", "s2", "s3"}, down: []string{"s2"}, wantReplicas: 4, minAcked: 3},
+		{slaves: []string{"s1", "s2", "s3"}, slow: []string{"s3"}, wantReplicas: 4, minAcked: 3},
+		{slaves: []string{"s1", "s2", "s3"}, down: []string{"s1"}, slow: []string{"s3"}, wantErr: true, wantReplicas: 4, minAcked: 2},
+	}
+
+	for i, test := range tests {
+		slaves := &fakeSlaves{down: map[string]bool{}, slow: map[string]bool{}, applied: map[string][]backendpb.AckLevel{}}
+		for _, addr := range test.down {
+			slaves.down[addr] = true
+		}
+		for _, addr := range test.slow {
+			slaves.slow[addr] = true
+		}
+		mgr := &ReplicateManager{sampleFeeds: new(sync.Map), sendToSlave: slaves.send}
+		for _, addr := range test.slaves {
+			mgr.sampleFeeds.Store(addr, &sampleFeed{})
+		}
+
+		request := &backendpb.AddRequest{WriterID: "w", Seq: 1, Ack: backendpb.AckLevel_AckQuorum}
+		ctx := context.Background()
+		if len(test.slow) > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+		}
+
+		acked, replicas, err := mgr.ReplicateToQuorum(ctx, request)
+		if (err != nil) != test.wantErr {
+			t.Fatalf("case %d: want error %v, got %v", i, test.wantErr, err)
+		}
+		if replicas != test.wantReplicas || acked < test.minAcked || acked > replicas {
+			t.Fatalf("case %d: want %d replicas and at least %d acked, got %d of %d", i, test.wantReplicas, test.minAcked, acked, replicas)
+		}
+		if request.Ack != backendpb.AckLevel_AckQuorum {
+			t.Fatalf("case %d: the request is modified", i)
+		}
+
+		slaves.mtx.Lock()
+		for addr, acks := range slaves.applied {
+			if len(acks) != 1 || acks[0] != backendpb.AckLevel_AckOne {
+				t.Fatalf("case %d: slave %s applied %v, want the batch acked by one", i, addr, acks)
+			}
+		}
+		slaves.mtx.Unlock()
+	}
+}
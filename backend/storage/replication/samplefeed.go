@@ -234,8 +234,8 @@ func (h *handOff) reconnect() error {
 
 	var msgCodec tcp.MsgCodec
 
-	closeWrite := &pb.ConnCtrl{pb.CtrlCode_CloseWrite}
-	buf := make([]byte, 1+binary.MaxVarintLen64+closeWrite.Size())
+	closeWrite := &pb.ConnCtrl{Code: pb.CtrlCode_CloseWrite}
+	buf := make([]byte, 2+binary.MaxVarintLen64+closeWrite.Size())
 
 	n, err := msgCodec.Encode(tcp.Message{Message: closeWrite}, buf)
 	if err != nil {
@@ -17,10 +17,12 @@ package storage
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"math"
 	"net/http"
 	"reflect"
+	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -32,6 +34,7 @@ import (
 	"github.com/baudtime/baudtime/util/syn"
 	tm "github.com/baudtime/baudtime/util/time"
 	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log/level"
 	"github.com/hashicorp/go-multierror"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
@@ -51,51 +54,19 @@ func selectVectors(q tsdb.Querier, matchers []*backendpb.Matcher, it *tm.Timesta
 	sMap := make(map[string]*pb.Series, 0)
 
 	for it.Next() {
-		ts := it.At()
-
-		set, err := q.Select(ms...)
-		if err != nil {
-			return nil, err
-		}
-
-		for set.Next() {
-			curSeries := set.At()
-			it := NewBufferIterator(curSeries.Iterator(), tm.DurationMilliSec(vars.Cfg.Storage.TSDB.LookbackDelta))
-			var t int64
-			var v float64
-
-			ok := it.Seek(ts)
-			if !ok {
-				err = it.Err()
-				if err != nil {
-					return nil, err
-				}
-			}
-			if ok {
-				t, v = it.Values()
-			}
-
-			peek := 1
-			if !ok || t > ts {
-				t, v, ok = it.PeekBack(peek)
-				peek++
-				if !ok || t < ts-tm.DurationMilliSec(vars.Cfg.Storage.TSDB.LookbackDelta) {
-					continue
-				}
-			}
-			if value.IsStaleNaN(v) {
-				continue
-			}
-
-			lblStr := curSeries.Labels().String()
+		err = eachVectorAt(q, ms, it.At(), func(lbls labels.Labels, t int64, v float64) {
+			lblStr := lbls.String()
 			if s, ok := sMap[lblStr]; !ok {
 				sMap[lblStr] = &pb.Series{
-					Labels: LabelsToProto(curSeries.Labels()),
+					Labels: LabelsToProto(lbls),
 					Points: []pb.Point{{V: v, T: t}},
 				}
 			} else {
 				s.Points = append(s.Points, pb.Point{V: v, T: t})
 			}
+		})
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -106,6 +77,49 @@ func selectVectors(q tsdb.Querier, matchers []*backendpb.Matcher, it *tm.Timesta
 	return series, nil
 }
 
+// eachVectorAt calls f with the sample of every series selected by ms, which is the latest
+// one not later than ts in the lookback delta, just like an instant vector at ts.
+func eachVectorAt(q tsdb.Querier, ms []labels.Matcher, ts int64, f func(lbls labels.Labels, t int64, v float64)) error {
+	set, err := q.Select(ms...)
+	if err != nil {
+		return err
+	}
+
+	for set.Next() {
+		curSeries := set.At()
+		it := NewBufferIterator(curSeries.Iterator(), tm.DurationMilliSec(vars.Cfg.Storage.TSDB.LookbackDelta))
+		var t int64
+		var v float64
+
+		ok := it.Seek(ts)
+		if !ok {
+			err = it.Err()
+			if err != nil {
+				return err
+			}
+		}
+		if ok {
+			t, v = it.Values()
+		}
+
+		peek := 1
+		if !ok || t > ts {
+			t, v, ok = it.PeekBack(peek)
+			peek++
+			if !ok || t < ts-tm.DurationMilliSec(vars.Cfg.Storage.TSDB.LookbackDelta) {
+				continue
+			}
+		}
+		if value.IsStaleNaN(v) {
+			continue
+		}
+
+		f(curSeries.Labels(), t, v)
+	}
+
+	return set.Err()
+}
+
 func selectNoInterval(q tsdb.Querier, matchers []*backendpb.Matcher, mint, maxt int64) ([]*pb.Series, error) {
 	ms, err := ProtoToMatchers(matchers)
 	if err != nil {
@@ -165,28 +179,99 @@ func selectNoInterval(q tsdb.Querier, matchers []*backendpb.Matcher, mint, maxt
 	return series, nil
 }
 
+var ErrLeftCluster = &pb.ResponseError{Code: pb.ErrorCode_NotLeader, Message: "node has left the cluster"}
+
 type Storage struct {
 	*tsdb.DB
 	*AddReqHandler
 	ReplicateManager *replication.ReplicateManager
+	Compaction       *CompactionWatcher // nil if the db is opened without it, no compaction is reported then
+	left             uint32
+	draining         uint32
+	querying         int64
 }
 
 func New(db *tsdb.DB) *Storage {
+	addReqHandler := &AddReqHandler{
+		appender: db.Appender,
+		addStat:  &AddStat{},
+		symbolsK: syn.NewMap(1024, syn.StringHash),
+		symbolsV: syn.NewMap(1<<14, syn.StringHash),
+	}
+	if vars.Cfg.Storage != nil && vars.Cfg.Storage.DedupWindow > 0 {
+		addReqHandler.applied = newSeqWindow(time.Duration(vars.Cfg.Storage.DedupWindow))
+	}
+	if vars.Cfg.Storage != nil {
+		addReqHandler.exemplars = newExemplarRing(vars.Cfg.Storage.MaxExemplars)
+	}
+
 	return &Storage{
-		DB: db,
-		AddReqHandler: &AddReqHandler{
-			appender: db.Appender,
-			addStat:  &AddStat{},
-			symbolsK: syn.NewMap(1024, syn.StringHash),
-			symbolsV: syn.NewMap(1<<14, syn.StringHash),
-		},
+		DB:               db,
+		AddReqHandler:    addReqHandler,
 		ReplicateManager: replication.NewReplicateManager(db),
 	}
 }
 
+// filterSeries drops the points out of bounds, and the series left without any point.
+func filterSeries(series []*pb.Series, bounds *backendpb.ValueBounds) []*pb.Series {
+	if bounds == nil {
+		return series
+	}
+
+	kept := series[:0]
+	for _, s := range series {
+		if s.Points = bounds.Filter(s.Points); len(s.Points) > 0 {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// compactSeries puts the points of series into chunks, for the requests negotiating it.
+func compactSeries(series []*pb.Series) error {
+	for _, s := range series {
+		if err := s.Compact(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashQuerier selects the series whose hash is hash only, the hash of tsdb labels is the one of
+// util.HashLabels, which the series are routed by on the write path.
+type hashQuerier struct {
+	tsdb.Querier
+	hash uint64
+}
+
+func (q hashQuerier) Select(ms ...labels.Matcher) (tsdb.SeriesSet, error) {
+	set, err := q.Querier.Select(ms...)
+	if err != nil {
+		return nil, err
+	}
+	return &hashSeriesSet{SeriesSet: set, hash: q.hash}, nil
+}
+
+type hashSeriesSet struct {
+	tsdb.SeriesSet
+	hash uint64
+}
+
+func (s *hashSeriesSet) Next() bool {
+	for s.SeriesSet.Next() {
+		if s.SeriesSet.At().Labels().Hash() == s.hash {
+			return true
+		}
+	}
+	return false
+}
+
 func (storage *Storage) HandleSelectReq(request *backendpb.SelectRequest) *backendpb.SelectResponse {
 	queryResponse := &backendpb.SelectResponse{Status: pb.StatusCode_Failed}
 
+	atomic.AddInt64(&storage.querying, 1)
+	defer atomic.AddInt64(&storage.querying, -1)
+
 	var span opentracing.Span
 	wireContext, err := opentracing.GlobalTracer().Extract(opentracing.Binary, bytes.NewBuffer(request.SpanCtx))
 	if err != nil {
@@ -203,6 +288,23 @@ func (storage *Storage) HandleSelectReq(request *backendpb.SelectRequest) *backe
 		span.Finish()
 	}()
 
+	if request.RawChunks {
+		if request.Mint > request.Maxt || request.Interval != 0 || request.Aggregation != nil {
+			queryResponse.ErrorMsg = "parameter error, raw chunks are only for selects without interval or aggregation"
+			return queryResponse
+		}
+
+		series, err := selectChunks(storage.DB, request.Matchers, request.Mint, request.Maxt, request.Hash)
+		if err != nil {
+			queryResponse.ErrorMsg = err.Error()
+			return queryResponse
+		}
+
+		queryResponse.Status = pb.StatusCode_Succeed
+		queryResponse.Series = series
+		return queryResponse
+	}
+
 	if (request.Mint == request.Maxt && request.Interval == 0) || (request.Mint < request.Maxt && request.Interval > 0) {
 		q, err := storage.DB.Querier(request.Mint-tm.DurationMilliSec(vars.Cfg.Storage.TSDB.LookbackDelta), request.Maxt)
 		if err != nil {
@@ -210,31 +312,58 @@ func (storage *Storage) HandleSelectReq(request *backendpb.SelectRequest) *backe
 			return queryResponse
 		}
 		defer q.Close()
+		if request.Hash != 0 {
+			q = hashQuerier{Querier: q, hash: request.Hash}
+		}
 
-		series, err := selectVectors(q, request.Matchers, tm.NewTimestampIter(request.Mint, request.Maxt, request.Interval))
+		var series []*pb.Series
+		if request.Aggregation != nil {
+			series, err = selectAggregated(q, request.Matchers, tm.NewTimestampIter(request.Mint, request.Maxt, request.Interval), request.Aggregation, request.ValueBounds)
+		} else {
+			series, err = selectVectors(q, request.Matchers, tm.NewTimestampIter(request.Mint, request.Maxt, request.Interval))
+			series = filterSeries(series, request.ValueBounds)
+		}
 		if err != nil {
 			queryResponse.ErrorMsg = err.Error()
 			return queryResponse
 		}
 
+		if request.CompactPoints {
+			if err = compactSeries(series); err != nil {
+				queryResponse.ErrorMsg = err.Error()
+				return queryResponse
+			}
+		}
+
 		queryResponse.Status = pb.StatusCode_Succeed
 		queryResponse.Series = series
 		return queryResponse
 	}
 
-	if request.Mint < request.Maxt && request.Interval == 0 {
+	if request.Mint < request.Maxt && request.Interval == 0 && request.Aggregation == nil {
 		q, err := storage.DB.Querier(request.Mint-tm.DurationMilliSec(vars.Cfg.Storage.TSDB.LookbackDelta), request.Maxt)
 		if err != nil {
 			queryResponse.ErrorMsg = err.Error()
 			return queryResponse
 		}
 		defer q.Close()
+		if request.Hash != 0 {
+			q = hashQuerier{Querier: q, hash: request.Hash}
+		}
 
 		series, err := selectNoInterval(q, request.Matchers, request.Mint, request.Maxt)
 		if err != nil {
 			queryResponse.ErrorMsg = err.Error()
 			return queryResponse
 		}
+		series = filterSeries(series, request.ValueBounds)
+
+		if request.CompactPoints {
+			if err = compactSeries(series); err != nil {
+				queryResponse.ErrorMsg = err.Error()
+				return queryResponse
+			}
+		}
 
 		queryResponse.Status = pb.StatusCode_Succeed
 		queryResponse.Series = series
@@ -248,6 +377,9 @@ func (storage *Storage) HandleSelectReq(request *backendpb.SelectRequest) *backe
 func (storage *Storage) HandleLabelValuesReq(request *backendpb.LabelValuesRequest) *pb.LabelValuesResponse {
 	queryResponse := &pb.LabelValuesResponse{Status: pb.StatusCode_Failed}
 
+	atomic.AddInt64(&storage.querying, 1)
+	defer atomic.AddInt64(&storage.querying, -1)
+
 	var span opentracing.Span
 	wireContext, err := opentracing.GlobalTracer().Extract(opentracing.Binary, bytes.NewBuffer(request.SpanCtx))
 	if err != nil {
@@ -285,11 +417,135 @@ func (storage *Storage) HandleLabelValuesReq(request *backendpb.LabelValuesReque
 		return queryResponse
 	}
 
+	if (request.After != "" || request.Limit > 0) && !sort.StringsAreSorted(values) {
+		sort.Strings(values)
+	}
+	if request.After != "" {
+		values = values[sort.SearchStrings(values, request.After):]
+		if len(values) > 0 && values[0] == request.After {
+			values = values[1:]
+		}
+	}
+	if request.Limit > 0 && len(values) > int(request.Limit) {
+		values = values[:request.Limit]
+	}
+
 	queryResponse.Status = pb.StatusCode_Succeed
 	queryResponse.Values = values
 	return queryResponse
 }
 
+func (storage *Storage) HandleLabelNamesReq(request *backendpb.LabelNamesRequest) *backendpb.LabelNamesResponse {
+	queryResponse := &backendpb.LabelNamesResponse{Status: pb.StatusCode_Failed}
+
+	atomic.AddInt64(&storage.querying, 1)
+	defer atomic.AddInt64(&storage.querying, -1)
+
+	var span opentracing.Span
+	wireContext, err := opentracing.GlobalTracer().Extract(opentracing.Binary, bytes.NewBuffer(request.SpanCtx))
+	if err != nil {
+		span = opentracing.StartSpan("storage_labelNames")
+	} else {
+		span = opentracing.StartSpan("storage_labelNames", opentracing.ChildOf(wireContext))
+	}
+	defer func() {
+		if queryResponse.Status == pb.StatusCode_Succeed {
+			span.SetTag("namesNum", len(queryResponse.Names))
+		} else {
+			span.SetTag("errorMsg", queryResponse.ErrorMsg)
+		}
+		span.Finish()
+	}()
+
+	q, err := storage.DB.Querier(math.MinInt64, math.MaxInt64)
+	if err != nil {
+		queryResponse.ErrorMsg = err.Error()
+		return queryResponse
+	}
+	defer q.Close()
+
+	names, err := q.LabelNames()
+	if err != nil {
+		queryResponse.ErrorMsg = err.Error()
+		return queryResponse
+	}
+
+	queryResponse.Status = pb.StatusCode_Succeed
+	queryResponse.Names = names
+	return queryResponse
+}
+
+// LeaveCluster makes the storage refuse new writes, it's called before the node deregisters.
+func (storage *Storage) LeaveCluster() {
+	atomic.StoreUint32(&storage.left, 1)
+}
+
+func (storage *Storage) Left() bool {
+	return atomic.LoadUint32(&storage.left) == 1
+}
+
+// Drain makes the shard of the storage not picked for new routes once the node info is reported,
+// it still serves reads and writes of the series routed to it. undo takes it back.
+// It's kept in the meta store, see RestoreDraining, so that a restart doesn't undo it.
+func (storage *Storage) Drain(undo bool) error {
+	if err := meta.Drain(storage.addr(), !undo); err != nil {
+		return err
+	}
+	storage.setDraining(!undo)
+	return nil
+}
+
+// RestoreDraining drains the storage again if it's been drained before it restarted.
+func (storage *Storage) RestoreDraining() error {
+	draining, err := meta.Draining(storage.addr())
+	if err != nil {
+		return err
+	}
+	storage.setDraining(draining)
+	return nil
+}
+
+const (
+	restoreDrainingRetryInterval    = time.Second      // initial interval between restoring the draining
+	restoreDrainingMaxRetryInterval = 30 * time.Second // the doubled interval is capped to it
+)
+
+// KeepRestoringDraining restores the draining in the background, retrying with backoff till the
+// meta store is reachable, so that the node starts even if etcd is down.
+func (storage *Storage) KeepRestoringDraining() {
+	go func() {
+		interval := restoreDrainingRetryInterval
+		for {
+			err := storage.RestoreDraining()
+			if err == nil {
+				return
+			}
+			level.Error(vars.Logger).Log("msg", "failed to restore the draining of the storage, retry later", "interval", interval, "err", err)
+
+			time.Sleep(interval)
+			if interval *= 2; interval > restoreDrainingMaxRetryInterval {
+				interval = restoreDrainingMaxRetryInterval
+			}
+		}
+	}()
+}
+
+func (storage *Storage) setDraining(draining bool) {
+	if draining {
+		atomic.StoreUint32(&storage.draining, 1)
+	} else {
+		atomic.StoreUint32(&storage.draining, 0)
+	}
+}
+
+func (storage *Storage) addr() string {
+	return meta.Node{IP: vars.LocalIP, Port: vars.Cfg.TcpPort}.Addr()
+}
+
+func (storage *Storage) Draining() bool {
+	return atomic.LoadUint32(&storage.draining) == 1
+}
+
 func (storage *Storage) Info() (meta.Node, *AddStat, error) {
 	diskUsage, err := disk.Usage(vars.Cfg.Storage.TSDB.Path)
 	if err != nil {
@@ -308,12 +564,72 @@ func (storage *Storage) Info() (meta.Node, *AddStat, error) {
 		ShardID:    storage.ReplicateManager.RelationID(),
 		IP:         vars.LocalIP,
 		Port:       vars.Cfg.TcpPort,
-		DiskFree:   uint64(math.Round(float64(diskUsage.Free) / 1073741824.0)), //GB
+		DiskFree:   uint64(math.Round(float64(diskUsage.Free) / 1073741824.0)),  //GB
+		DiskTotal:  uint64(math.Round(float64(diskUsage.Total) / 1073741824.0)), //GB
+		Weight:     vars.Cfg.Storage.Weight,
 		MasterIP:   masterIP,
 		MasterPort: masterPort,
+		MinT:       storage.minTime(),
+		MaxT:       storage.maxTime(),
+		Draining:   storage.Draining(),
+		SeriesNum:  storage.DB.Head().NumSeries(),
 	}, storage.addStat, nil
 }
 
+// HandleInfoCmd reports the version, role, uptime and sample counts of the storage for a detailed info command.
+func (storage *Storage) HandleInfoCmd(cmd *pb.Info) *pb.InfoResponse {
+	node, addStat, err := storage.Info()
+	if err != nil {
+		return &pb.InfoResponse{Status: pb.StatusCode_Failed, ErrorMsg: err.Error()}
+	}
+
+	role, masterAddr := "master", ""
+	if node.MasterIP != "" || node.MasterPort != "" {
+		role, masterAddr = "slave", node.MasterIP+":"+node.MasterPort
+	}
+
+	return &pb.InfoResponse{
+		Status:             pb.StatusCode_Succeed,
+		Version:            vars.Version,
+		ShardID:            node.ShardID,
+		Role:               role,
+		Addr:               node.Addr(),
+		MasterAddr:         masterAddr,
+		StartTime:          tm.FromTime(vars.StartTime),
+		MinT:               node.MinT,
+		MaxT:               node.MaxT,
+		DiskFree:           node.DiskFree,
+		SeriesNum:          node.SeriesNum,
+		SamplesReceived:    atomic.LoadUint64(&addStat.Received),
+		SamplesSucceed:     atomic.LoadUint64(&addStat.Succeed),
+		SamplesFailed:      atomic.LoadUint64(&addStat.Failed),
+		SamplesOutOfOrder:  atomic.LoadUint64(&addStat.OutOfOrder),
+		SamplesOutOfBounds: atomic.LoadUint64(&addStat.OutOfBounds),
+		SamplesDuplicated:  atomic.LoadUint64(&addStat.Duplicated),
+		Compacting:         storage.Compaction.Compacting(),
+		Load:               atomic.LoadInt64(&storage.querying),
+	}
+}
+
+// maxTime returns the latest timestamp appended to the storage, 0 if it's empty.
+func (storage *Storage) maxTime() int64 {
+	if maxT := storage.DB.Head().MaxTime(); maxT != math.MinInt64 {
+		return maxT
+	}
+	if blocks := storage.DB.Blocks(); len(blocks) > 0 {
+		return blocks[len(blocks)-1].Meta().MaxTime
+	}
+	return 0
+}
+
+// minTime returns the earliest timestamp in the storage, math.MaxInt64 if it's empty.
+func (storage *Storage) minTime() int64 {
+	if blocks := storage.DB.Blocks(); len(blocks) > 0 {
+		return blocks[0].Meta().MinTime
+	}
+	return storage.DB.Head().MinTime()
+}
+
 func (storage *Storage) Close() (err error) {
 	err = multierror.Append(err, storage.ReplicateManager.Close(), storage.DB.Close())
 	return
@@ -375,29 +691,55 @@ type AddStat struct {
 	OutOfOrder  uint64
 	AmendSample uint64
 	OutOfBounds uint64
+	Duplicated  uint64
 }
 
+// ErrHistogramUnsupported is returned for the native histogram points of an add request, the storage can't keep them.
+var ErrHistogramUnsupported = errors.New("native histogram samples are not supported by the storage")
+
 type AddReqHandler struct {
-	appender func() tsdb.Appender
-	addStat  *AddStat
-	symbolsK *syn.Map
-	symbolsV *syn.Map
+	appender  func() tsdb.Appender
+	addStat   *AddStat
+	symbolsK  *syn.Map
+	symbolsV  *syn.Map
+	applied   *seqWindow    // nil if deduplication is disabled
+	exemplars *exemplarRing // the exemplars written with the samples, nil if they're dropped
 }
 
 func (addReqHandler *AddReqHandler) HandleAddReq(request *backendpb.AddRequest) error {
+	dedup := addReqHandler.applied != nil && request.WriterID != "" && request.Seq > 0
+	if dedup && addReqHandler.applied.applied(request.WriterID, request.Seq) {
+		atomic.AddUint64(&addReqHandler.addStat.Duplicated, 1)
+		return nil
+	}
+
 	var multiErr error
 	var app = addReqHandler.appender()
+	var exemplars []Exemplar
+	var histogramRejected bool
 
 	for _, series := range request.Series {
 
 		var ref uint64
+		var lset labels.Labels
 		for _, p := range series.Points {
 			var err error
 
+			if p.H != nil {
+				// the tsdb stores float samples only, a histogram must not be taken as its V
+				atomic.AddUint64(&addReqHandler.addStat.Received, 1)
+				atomic.AddUint64(&addReqHandler.addStat.Failed, 1)
+				if !histogramRejected {
+					multiErr = multierror.Append(multiErr, ErrHistogramUnsupported)
+					histogramRejected = true
+				}
+				continue
+			}
+
 			if ref != 0 {
 				err = app.AddFast(ref, p.T, p.V)
 			} else {
-				lset := make([]labels.Label, len(series.Labels))
+				lset = make([]labels.Label, len(series.Labels))
 
 				for i, lb := range series.Labels {
 					if symbol, found := addReqHandler.symbolsK.Get(lb.Name); found {
@@ -421,6 +763,9 @@ func (addReqHandler *AddReqHandler) HandleAddReq(request *backendpb.AddRequest)
 			atomic.AddUint64(&addReqHandler.addStat.Received, 1)
 			if err == nil {
 				atomic.AddUint64(&addReqHandler.addStat.Succeed, 1)
+				if p.E != nil && addReqHandler.exemplars != nil {
+					exemplars = append(exemplars, Exemplar{Labels: lset, Exemplar: *p.E})
+				}
 			} else {
 				atomic.AddUint64(&addReqHandler.addStat.Failed, 1)
 				switch errors.Cause(err) {
@@ -439,7 +784,35 @@ func (addReqHandler *AddReqHandler) HandleAddReq(request *backendpb.AddRequest)
 
 	if err := app.Commit(); err != nil {
 		multiErr = multierror.Append(multiErr, err)
+	} else {
+		addReqHandler.exemplars.add(exemplars...)
+		if dedup {
+			// the samples failed above are not written even if the batch is retried
+			addReqHandler.applied.record(request.WriterID, request.Seq)
+		}
 	}
 
 	return multiErr
 }
+
+// Exemplars returns the exemplars kept in [mint, maxt] of the series matching all the matchers, oldest first.
+func (addReqHandler *AddReqHandler) Exemplars(mint, maxt int64, matchers ...labels.Matcher) []Exemplar {
+	return addReqHandler.exemplars.Select(mint, maxt, matchers...)
+}
+
+// Acknowledge returns the reply to the request applied with err, at the ack level of the request. At AckQuorum,
+// the batch applied is sent to the slaves till a majority of them apply it, see ReplicateToQuorum.
+func (storage *Storage) Acknowledge(ctx context.Context, request *backendpb.AddRequest, err error) *backendpb.AddResponse {
+	if err != nil {
+		return &backendpb.AddResponse{Status: pb.StatusCode_Failed, Code: pb.CodeOf(err), Message: err.Error(), Replicas: 1}
+	}
+	if request.Ack != backendpb.AckLevel_AckQuorum {
+		return &backendpb.AddResponse{Status: pb.StatusCode_Succeed, Acked: 1, Replicas: 1}
+	}
+
+	acked, replicas, err := storage.ReplicateManager.ReplicateToQuorum(ctx, request)
+	if err != nil {
+		return &backendpb.AddResponse{Status: pb.StatusCode_Failed, Message: err.Error(), Acked: uint32(acked), Replicas: uint32(replicas)}
+	}
+	return &backendpb.AddResponse{Status: pb.StatusCode_Succeed, Acked: uint32(acked), Replicas: uint32(replicas)}
+}
@@ -19,7 +19,9 @@ import (
 	"fmt"
 	"github.com/baudtime/baudtime/msg/pb"
 	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/util/syn"
 	"github.com/baudtime/baudtime/vars"
+	"github.com/hashicorp/go-multierror"
 	"github.com/prometheus/tsdb"
 	"github.com/prometheus/tsdb/labels"
 	"testing"
@@ -62,7 +64,7 @@ func TestAPI_Bench(t *testing.T) {
 	for i := 0; i < 20000000; i++ {
 		now := time.Now().UnixNano() / 1e6
 		req.Series[0].Points = []pb.Point{
-			{now - 2, 5},
+			{T: now - 2, V: 5},
 		}
 
 		err = handler.HandleAddReq(req)
@@ -102,7 +104,7 @@ func TestAPI_Bench_Orig(t *testing.T) {
 	for i := 0; i < 20000000; i++ {
 		now := time.Now().UnixNano() / 1e6
 
-		p := pb.Point{now - 2, 5}
+		p := pb.Point{T: now - 2, V: 5}
 		_, err = app.Add(lb, p.T, p.V)
 		if err != nil {
 			errNo++
@@ -112,3 +114,29 @@ func TestAPI_Bench_Orig(t *testing.T) {
 
 	fmt.Printf("ops:%d", int64(total)/int64(time.Since(begin).Seconds()))
 }
+
+func TestHandleAddReq_Histogram(t *testing.T) {
+	s := &fakeStorage{applied: make(map[int64]uint64)}
+	handler := &AddReqHandler{
+		appender: func() tsdb.Appender { return &fakeAppender{s: s} },
+		addStat:  &AddStat{},
+		symbolsK: syn.NewMap(16, syn.StringHash),
+		symbolsV: syn.NewMap(16, syn.StringHash),
+	}
+
+	err := handler.HandleAddReq(&backendpb.AddRequest{Series: []*pb.Series{{
+		Labels: []pb.Label{{Name: "__name__", Value: "latency"}},
+		Points: []pb.Point{{T: 1000, V: 1}, {T: 2000, H: &pb.Histogram{Count: 3, Sum: 1.5}}, {T: 3000, H: &pb.Histogram{Count: 4}}},
+	}}})
+
+	merr, ok := err.(*multierror.Error)
+	if !ok || len(merr.Errors) != 1 || merr.Errors[0] != ErrHistogramUnsupported {
+		t.Fatalf("want the histograms rejected once, got %v", err)
+	}
+	if len(s.applied) != 1 || s.applied[1000] != 0 {
+		t.Fatalf("want only the float sample written, got %v", s.applied)
+	}
+	if handler.addStat.Received != 3 || handler.addStat.Failed != 2 {
+		t.Fatalf("want 2 of 3 samples failed, got %+v", *handler.addStat)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// startSpan starts a span of a query as a child of the span ctx carries under the key "span", the way the
+// gateway passes it down, and returns ctx carrying the new span in place of its parent, so that the spans
+// started further down, e.g. by ShardClient, nest under it. Unless a tracer is registered and ctx carries
+// a span, it's a noop returning a nil span and ctx as it is, so an untraced query costs a lookup or two.
+func startSpan(ctx context.Context, operationName string, tags ...opentracing.Tag) (opentracing.Span, context.Context) {
+	if !opentracing.IsGlobalTracerRegistered() {
+		return nil, ctx
+	}
+	parentSpan, ok := ctx.Value("span").(opentracing.Span)
+	if !ok {
+		return nil, ctx
+	}
+
+	opts := make([]opentracing.StartSpanOption, 0, len(tags)+1)
+	opts = append(opts, opentracing.ChildOf(parentSpan.Context()))
+	for _, tag := range tags {
+		opts = append(opts, tag)
+	}
+
+	span := opentracing.StartSpan(operationName, opts...)
+	return span, context.WithValue(ctx, "span", span)
+}
+
+// finishSpan finishes a span started by startSpan, tagged as failed if err isn't nil. A nil span is ignored.
+func finishSpan(span opentracing.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.Error(err))
+	}
+	span.Finish()
+}
+
+// seriesSamples returns how many samples the series carry, the ones in chunks are counted by the
+// headers of the chunks rather than decoded.
+func seriesSamples(series []*pb.Series) (samples int) {
+	for _, s := range series {
+		samples += len(s.Points)
+		if len(s.Chunk) >= 2 {
+			samples += int(binary.BigEndian.Uint16(s.Chunk))
+		}
+		for _, c := range s.Chunks {
+			if len(c.Data) >= 2 {
+				samples += int(binary.BigEndian.Uint16(c.Data))
+			}
+		}
+	}
+	return samples
+}
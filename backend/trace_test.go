@@ -0,0 +1,200 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/backend/storage"
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// testTracer records the spans started, with their tags and parents.
+type testTracer struct {
+	mtx   sync.Mutex
+	spans []*testSpan
+}
+
+type testSpanContext struct {
+	id int
+}
+
+func (testSpanContext) ForeachBaggageItem(func(k, v string) bool) {}
+
+type testSpan struct {
+	opentracing.Span
+	tracer   *testTracer
+	ctx      testSpanContext
+	name     string
+	parent   int // id of the parent span, 0 if it's a root
+	tags     map[string]interface{}
+	finished bool
+}
+
+func (t *testTracer) StartSpan(name string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var o opentracing.StartSpanOptions
+	for _, opt := range opts {
+		opt.Apply(&o)
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	span := &testSpan{tracer: t, ctx: testSpanContext{id: len(t.spans) + 1}, name: name, tags: make(map[string]interface{})}
+	for k, v := range o.Tags {
+		span.tags[k] = v
+	}
+	for _, ref := range o.References {
+		span.parent = ref.ReferencedContext.(testSpanContext).id
+	}
+	t.spans = append(t.spans, span)
+	return span
+}
+
+func (t *testTracer) Inject(opentracing.SpanContext, interface{}, interface{}) error {
+	return errors.New("not implemented")
+}
+
+func (t *testTracer) Extract(interface{}, interface{}) (opentracing.SpanContext, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (t *testTracer) named(name string) (spans []*testSpan) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for _, span := range t.spans {
+		if span.name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func (s *testSpan) Context() opentracing.SpanContext { return s.ctx }
+
+func (s *testSpan) SetTag(key string, value interface{}) opentracing.Span {
+	s.tracer.mtx.Lock()
+	s.tags[key] = value
+	s.tracer.mtx.Unlock()
+	return s
+}
+
+func (s *testSpan) Finish() {
+	s.tracer.mtx.Lock()
+	s.finished = true
+	s.tracer.mtx.Unlock()
+}
+
+func TestFanoutQuerier_Tracing(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	// the series are released by the querier, so each select is answered with new ones
+	seriesOf := func(shardID string) []*pb.Series {
+		switch shardID {
+		case "shard-1":
+			return []*pb.Series{
+				{Labels: []pb.Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: "1"}}, Points: []pb.Point{{T: 1000, V: 1}, {T: 2000, V: 1}}},
+				{Labels: []pb.Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: "2"}}, Points: []pb.Point{{T: 1000, V: 0}}},
+			}
+		case "shard-2":
+			compacted := &pb.Series{
+				Labels: []pb.Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: "3"}},
+				Points: []pb.Point{{T: 1000, V: 1}, {T: 2000, V: 1}, {T: 3000, V: 1}},
+			}
+			if err := compacted.Compact(); err != nil {
+				t.Fatal(err)
+			}
+			return []*pb.Series{compacted}
+		}
+		return nil
+	}
+	wantSeries := map[string]int{"shard-1": 2, "shard-2": 1, "shard-3": 0}
+	wantSamples := map[string]int{"shard-1": 3, "shard-2": 3, "shard-3": 0}
+
+	fanout := NewFanout(nil)
+	fanout.cluster = &fakeCluster{
+		byTimeSpan: func(time.Time, time.Time, ...*labels.Matcher) ([]string, error) {
+			return []string{"shard-1", "shard-2", "shard-3"}, nil
+		},
+		newClient: func(shardID string, _ *storage.Storage) Client {
+			return &selectClient{series: seriesOf(shardID)}
+		},
+	}
+
+	matcher, err := labels.NewMatcher(labels.MatchEqual, labels.MetricName, "up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := func(ctx context.Context) {
+		q, err := fanout.Querier(ctx, 0, 10000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set, _, err := q.Select(&SelectParams{}, matcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for set.Next() {
+		}
+		if err = set.Err(); err != nil {
+			t.Fatal(err)
+		}
+		q.Close()
+	}
+
+	// no tracer registered
+	query(context.WithValue(context.Background(), "span", opentracing.StartSpan("query")))
+
+	tracer := new(testTracer)
+	opentracing.SetGlobalTracer(tracer)
+
+	// the query isn't traced
+	query(context.Background())
+	if len(tracer.spans) != 0 {
+		t.Fatalf("want no span of an untraced query, got %d", len(tracer.spans))
+	}
+
+	root := tracer.StartSpan("query")
+	query(context.WithValue(context.Background(), "span", root))
+
+	querierSpans, selectSpans, shardSpans := tracer.named("fanoutQuerier"), tracer.named("fanoutSelect"), tracer.named("shardSelect")
+	if len(querierSpans) != 1 || len(selectSpans) != 1 || len(shardSpans) != len(wantSeries) {
+		t.Fatalf("want a querier span, a select span and a span per shard, got %d, %d and %d", len(querierSpans), len(selectSpans), len(shardSpans))
+	}
+	if querierSpans[0].parent != root.Context().(testSpanContext).id || !querierSpans[0].finished {
+		t.Fatalf("want the querier span finished under the query, got %+v", querierSpans[0])
+	}
+	if selectSpans[0].parent != querierSpans[0].ctx.id || !selectSpans[0].finished || selectSpans[0].tags["shards"] != len(wantSeries) {
+		t.Fatalf("want the select span of %d shards finished under the querier, got %+v", len(wantSeries), selectSpans[0])
+	}
+
+	for _, span := range shardSpans {
+		shardID, _ := span.tags["shard"].(string)
+		if span.parent != selectSpans[0].ctx.id || !span.finished {
+			t.Fatalf("want the span of %s finished under the select, got %+v", shardID, span)
+		}
+		if span.tags["series"] != wantSeries[shardID] || span.tags["samples"] != wantSamples[shardID] {
+			t.Fatalf("want %d series and %d samples of %s, got %v", wantSeries[shardID], wantSamples[shardID], shardID, span.tags)
+		}
+	}
+}
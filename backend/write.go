@@ -17,11 +17,19 @@ package backend
 
 import (
 	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/baudtime/baudtime/backend/storage"
 	"github.com/baudtime/baudtime/msg/pb"
 	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/util/redo"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log/level"
 	"github.com/pkg/errors"
-	"sync"
+	uuid "github.com/satori/go.uuid"
 )
 
 var (
@@ -65,6 +73,25 @@ func (m seriesHashMap) set(hash uint64, s *pb.Series) {
 	m[hash] = append(ss, s)
 }
 
+// span returns the earliest and the latest timestamps of the points in m and the number of them.
+func (m seriesHashMap) span() (minT, maxT int64, n int) {
+	minT, maxT = math.MaxInt64, math.MinInt64
+	for _, ss := range m {
+		for _, s := range ss {
+			for _, p := range s.Points {
+				if p.T < minT {
+					minT = p.T
+				}
+				if p.T > maxT {
+					maxT = p.T
+				}
+			}
+			n += len(s.Points)
+		}
+	}
+	return
+}
+
 func (m seriesHashMap) del(hash uint64) {
 	if ss, found := m[hash]; found {
 		delete(m, hash)
@@ -73,8 +100,44 @@ func (m seriesHashMap) del(hash uint64) {
 }
 
 type appender struct {
-	client Client
-	series seriesHashMap
+	mtx           sync.Mutex // guards the batch, which may be flushed by the age timer
+	client        Client
+	series        seriesHashMap
+	retryNum      int
+	retryInterval time.Duration
+	batchSize     int           // samples buffered before being flushed automatically, 0 disables it
+	batchInterval time.Duration // how long a sample may be buffered before being flushed automatically, 0 disables it
+	buffered      int
+	firstBuffered time.Time
+	ageTimer      *time.Timer // flushes the batch once it's batchInterval old, nil till a sample is buffered
+	writerID      string      // identifies the appender to the storage to deduplicate retried batches
+	seq           uint64
+	ack           backendpb.AckLevel
+	ackTimeout    time.Duration
+	queue         *writeQueue // batches of the shard failed retryably, nil if they aren't queued
+}
+
+const defaultAckTimeout = 10 * time.Second
+
+// ParseAckLevel parses the ack level of the appender config, AckAsync if s is empty, as storages
+// older than the acks never reply to a batch.
+//
+// AckAsync sends a batch without waiting, a batch lost with a master going down or rejected is
+// not told. AckOne waits for the master to apply it, which may still be lost if the master dies
+// before feeding its slaves. AckQuorum waits for a majority of the slaves too, so a batch acked
+// survives a slave promoted, at the cost of the latency of the slowest slave of the majority.
+// The slaves write the batch a second time when it comes with the sample feed, unless their
+// dedup_window is set.
+func ParseAckLevel(s string) (backendpb.AckLevel, error) {
+	switch s {
+	case "", "async":
+		return backendpb.AckLevel_AckAsync, nil
+	case "one":
+		return backendpb.AckLevel_AckOne, nil
+	case "quorum":
+		return backendpb.AckLevel_AckQuorum, nil
+	}
+	return backendpb.AckLevel_AckAsync, errors.Errorf("invalid ack level %q", s)
 }
 
 func newAppender(shardID string, localStorage *storage.Storage) (*appender, error) {
@@ -82,16 +145,59 @@ func newAppender(shardID string, localStorage *storage.Storage) (*appender, erro
 		return nil, errors.New("invalid backend shard id")
 	}
 
-	return &appender{
+	app := &appender{
 		client: &ShardClient{
 			shardID:      shardID,
 			localStorage: localStorage,
 		},
-		series: seriesHashMap{},
-	}, nil
+		series:     seriesHashMap{},
+		retryNum:   1,
+		writerID:   strings.Replace(uuid.NewV4().String(), "-", "", -1),
+		ack:        backendpb.AckLevel_AckAsync,
+		ackTimeout: defaultAckTimeout,
+	}
+
+	var err error
+	if app.queue, err = shardWriteQueue(shardID); err != nil {
+		return nil, err
+	}
+
+	if vars.Cfg.Gateway != nil && vars.Cfg.Gateway.Appender != nil {
+		cfg := vars.Cfg.Gateway.Appender
+		if cfg.RetryNum > 1 {
+			app.retryNum = cfg.RetryNum
+			app.retryInterval = time.Duration(cfg.RetryInterval)
+		}
+		app.batchSize = cfg.AutoFlushSamples
+		app.batchInterval = time.Duration(cfg.AutoFlushAge)
+
+		ack, err := ParseAckLevel(cfg.AckLevel)
+		if err != nil {
+			return nil, err
+		}
+		app.ack = ack
+		if cfg.AckTimeout > 0 {
+			app.ackTimeout = time.Duration(cfg.AckTimeout)
+		}
+	}
+
+	if app.queue != nil {
+		// not app itself, which would be kept as long as the queue
+		replayer := &appender{client: app.client, ackTimeout: app.ackTimeout}
+		app.queue.replayInBackground(queueReplayInterval, replayer.resend)
+	}
+
+	return app, nil
 }
 
 func (app *appender) Add(l []pb.Label, t int64, v float64, hash uint64) error {
+	return app.add(l, pb.Point{T: t, V: v}, hash)
+}
+
+func (app *appender) add(l []pb.Label, p pb.Point, hash uint64) error {
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+
 	s := app.series.get(hash, l)
 	if s == nil {
 		s = &pb.Series{
@@ -100,18 +206,88 @@ func (app *appender) Add(l []pb.Label, t int64, v float64, hash uint64) error {
 		}
 		app.series.set(hash, s)
 	}
-	s.Points = append(s.Points, pb.Point{T: t, V: v})
+	s.Points = append(s.Points, p)
+
+	if app.buffered == 0 && app.batchInterval > 0 {
+		app.firstBuffered = time.Now()
+		if app.ageTimer == nil {
+			app.ageTimer = time.AfterFunc(app.batchInterval, app.flushOld)
+		} else {
+			app.ageTimer.Reset(app.batchInterval)
+		}
+	}
+	app.buffered++
+
+	// flush automatically once the batch is large or old enough, so that many small
+	// writes go to the shard in fewer and larger messages.
+	if (app.batchSize > 0 && app.buffered >= app.batchSize) ||
+		(app.batchInterval > 0 && time.Since(app.firstBuffered) >= app.batchInterval) {
+		return app.flushLocked(app.ack)
+	}
 	return nil
 }
 
+// flushOld flushes the batch if it's batchInterval old, so that it's sent though nothing is added after it.
+func (app *appender) flushOld() {
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+
+	if app.buffered == 0 || time.Since(app.firstBuffered) < app.batchInterval {
+		return
+	}
+	if err := app.flushLocked(app.ack); err != nil {
+		level.Warn(vars.Logger).Log("msg", "failed to flush the batch buffered too long", "err", err)
+	}
+}
+
 func (app *appender) Flush() error {
+	return app.flush(app.ack)
+}
+
+func (app *appender) flush(ack backendpb.AckLevel) error {
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+
+	return app.flushLocked(ack)
+}
+
+// flushLocked sends the batch and waits for ack replicas of the shard to apply it. If the batches of
+// the shard are queued, the ones queued are sent first, and the batch joins them rather than
+// failing if it can't be sent. mtx must be held.
+func (app *appender) flushLocked(ack backendpb.AckLevel) error {
+	if len(app.series) == 0 {
+		return nil
+	}
+	app.buffered = 0
+	if app.ageTimer != nil {
+		app.ageTimer.Stop()
+	}
+
 	series := seriesPool.Get().([]*pb.Series)
 
 	for k, ss := range app.series {
 		series = append(series, ss...)
 		app.series.del(k)
 	}
-	err := app.client.Add(context.TODO(), &backendpb.AddRequest{Series: series})
+	// every attempt resolves the master of the shard again, so the batch goes to
+	// the newly promoted one if a failover happened in between. A bad request is
+	// not retried as it fails anyway. A retried batch keeps its seq, so the storage
+	// drops it if it has been applied.
+	app.seq++
+	request := &backendpb.AddRequest{Series: series, WriterID: app.writerID, Seq: app.seq, Ack: ack}
+	var err error
+	if app.queue != nil && !app.queue.empty() {
+		err = app.queue.replay(app.resend)
+	}
+	if err == nil {
+		err = redo.RetryWithBackoff(app.retryInterval, app.retryNum, func() (bool, error) {
+			err := app.send(request)
+			return err != nil && pb.Retryable(err), err
+		})
+	}
+	if err != nil && app.queue != nil && pb.Retryable(err) {
+		err = app.enqueue(request)
+	}
 
 	for _, s := range series {
 		s.Labels = nil
@@ -124,3 +300,33 @@ func (app *appender) Flush() error {
 	}
 	return nil
 }
+
+func (app *appender) send(request *backendpb.AddRequest) error {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if app.ackTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, app.ackTimeout)
+	}
+	defer cancel()
+
+	return app.client.Add(ctx, request)
+}
+
+// resend sends a batch queued once, the queue keeps it if it fails retryably.
+func (app *appender) resend(batch []byte) error {
+	request := new(backendpb.AddRequest)
+	if err := request.Unmarshal(batch); err != nil {
+		level.Warn(vars.Logger).Log("msg", "drop undecodable queued batch", "err", err)
+		return nil
+	}
+	return app.send(request)
+}
+
+// enqueue queues the batch after the ones queued of the shard, it keeps its writer and seq, so the
+// storage drops it if an attempt failed has applied it.
+func (app *appender) enqueue(request *backendpb.AddRequest) error {
+	batch, err := request.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal batch")
+	}
+	return app.queue.push(batch)
+}
@@ -0,0 +1,547 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/util"
+	"github.com/baudtime/baudtime/vars"
+)
+
+// fakeClient implements Client, its Add fails with err until failNum attempts have been made.
+type fakeClient struct {
+	failNum  int
+	err      error
+	attempts int
+	seqs     []uint64
+	acks     []backendpb.AckLevel
+	added    []*pb.Series
+}
+
+func (c *fakeClient) Select(ctx context.Context, req *backendpb.SelectRequest) (*backendpb.SelectResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeClient) LabelValues(ctx context.Context, req *backendpb.LabelValuesRequest) (*pb.LabelValuesResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeClient) LabelNames(ctx context.Context, req *backendpb.LabelNamesRequest) (*backendpb.LabelNamesResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeClient) Add(ctx context.Context, req *backendpb.AddRequest) error {
+	c.attempts++
+	c.seqs = append(c.seqs, req.Seq)
+	c.acks = append(c.acks, req.Ack)
+	if c.attempts <= c.failNum {
+		if c.err != nil {
+			return c.err
+		}
+		return errors.New("master is down")
+	}
+	c.added = append(c.added, req.Series...)
+	return nil
+}
+
+func (c *fakeClient) Close() error {
+	return nil
+}
+
+func (c *fakeClient) Name() string {
+	return "fake"
+}
+
+func TestAppender_FlushRetry(t *testing.T) {
+	tests := []struct {
+		failNum      int
+		err          error
+		retryNum     int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{failNum: 0, retryNum: 1, wantErr: false, wantAttempts: 1},
+		{failNum: 1, retryNum: 1, wantErr: true, wantAttempts: 1},
+		{failNum: 2, retryNum: 3, wantErr: false, wantAttempts: 3},
+		{failNum: 100, retryNum: 3, wantErr: true, wantAttempts: 3},
+		{failNum: 1, err: &pb.ResponseError{Code: pb.ErrorCode_NotLeader}, retryNum: 3, wantErr: false, wantAttempts: 2},
+		{failNum: 100, err: &pb.ResponseError{Code: pb.ErrorCode_BadRequest}, retryNum: 3, wantErr: true, wantAttempts: 1},
+	}
+
+	for _, test := range tests {
+		cli := &fakeClient{failNum: test.failNum, err: test.err}
+		app := &appender{
+			client:        cli,
+			series:        seriesHashMap{},
+			retryNum:      test.retryNum,
+			retryInterval: time.Millisecond,
+		}
+
+		lbls := []pb.Label{{Name: "__name__", Value: "test_metric"}}
+		if err := app.Add(lbls, 1000, 1, 1); err != nil {
+			t.Fatalf("unexpected add error: %v", err)
+		}
+
+		err := app.Flush()
+		if (err != nil) != test.wantErr {
+			t.Fatalf("fail %d times with %d retries: want error %v, got %v", test.failNum, test.retryNum, test.wantErr, err)
+		}
+		if cli.attempts != test.wantAttempts {
+			t.Fatalf("want %d attempts, got %d", test.wantAttempts, cli.attempts)
+		}
+		if !test.wantErr && len(cli.added) != 1 {
+			t.Fatalf("want 1 series flushed, got %d", len(cli.added))
+		}
+	}
+}
+
+func TestFanoutAppender_FlushWithAck(t *testing.T) {
+	cfgBak := vars.Cfg.Gateway
+	defer func() {
+		vars.Cfg.Gateway = cfgBak
+	}()
+
+	for _, s := range []string{"async", "one", "", "quorum", "all"} {
+		want, err := ParseAckLevel(s)
+		if s == "all" {
+			if err == nil {
+				t.Fatalf("expected error parsing ack level %q", s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		vars.Cfg.Gateway = &vars.GatewayConfig{Appender: &vars.AppenderConfig{AckLevel: s}}
+		app, err := newAppender("shard-1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cli := &fakeClient{}
+		app.client = cli
+
+		fanoutApp := &fanoutAppender{appenders: map[string]*appender{"shard-1": app}}
+		lbls := []pb.Label{{Name: "__name__", Value: "test_metric"}}
+
+		app.Add(lbls, 1000, 1, 1)
+		if err = fanoutApp.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		for _, ack := range []backendpb.AckLevel{backendpb.AckLevel_AckQuorum, backendpb.AckLevel_AckAsync} {
+			app.Add(lbls, 2000, 1, 1)
+			if err = fanoutApp.FlushWithAck(ack); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if acks := []backendpb.AckLevel{want, backendpb.AckLevel_AckQuorum, backendpb.AckLevel_AckAsync}; !reflect.DeepEqual(cli.acks, acks) {
+			t.Fatalf("ack level %q: want %v, got %v", s, acks, cli.acks)
+		}
+	}
+
+	vars.Cfg.Gateway = &vars.GatewayConfig{Appender: &vars.AppenderConfig{AckLevel: "all"}}
+	if _, err := newAppender("shard-1", nil); err == nil {
+		t.Fatalf("expected error creating an appender with an invalid ack level")
+	}
+}
+
+func TestFanoutAppender_FlushWithResult(t *testing.T) {
+	clients := map[string]*fakeClient{
+		"shard-1": {},
+		"shard-2": {failNum: 100, err: &pb.ResponseError{Code: pb.ErrorCode_BadRequest, Message: "disk full"}},
+		"shard-3": {},
+		"shard-4": {},
+	}
+	fanoutApp := &fanoutAppender{appenders: make(map[string]*appender)}
+	for shardID, cli := range clients {
+		fanoutApp.appenders[shardID] = &appender{client: cli, series: seriesHashMap{}, retryNum: 1}
+	}
+
+	lbls := func(name string) []pb.Label {
+		return []pb.Label{{Name: "__name__", Value: name}}
+	}
+	fanoutApp.appenders["shard-1"].Add(lbls("a"), 1000, 1, 1)
+	fanoutApp.appenders["shard-1"].Add(lbls("a"), 3000, 1, 1)
+	fanoutApp.appenders["shard-2"].Add(lbls("b"), 2000, 1, 2)
+	fanoutApp.appenders["shard-2"].Add(lbls("c"), 1500, 1, 3)
+	fanoutApp.appenders["shard-3"].Add(lbls("d"), 4000, 1, 4)
+	// nothing added to shard-4
+
+	result := fanoutApp.FlushWithResult()
+
+	want := []ShardFlush{
+		{ShardID: "shard-1", MinT: 1000, MaxT: 3000, Samples: 2},
+		{ShardID: "shard-2", MinT: 1500, MaxT: 2000, Samples: 2},
+		{ShardID: "shard-3", MinT: 4000, MaxT: 4000, Samples: 1},
+	}
+	if len(result.Shards) != len(want) {
+		t.Fatalf("want %d shards flushed, got %v", len(want), result.Shards)
+	}
+	for i, shard := range result.Shards {
+		got := shard
+		got.Err = nil
+		if got != want[i] {
+			t.Fatalf("want %v, got %v", want[i], got)
+		}
+	}
+
+	if succeeded := result.Succeeded(); !reflect.DeepEqual(succeeded, []string{"shard-1", "shard-3"}) {
+		t.Fatalf("unexpected shards succeeded %v", succeeded)
+	}
+	if failed := result.Failed(); !reflect.DeepEqual(failed, []string{"shard-2"}) {
+		t.Fatalf("unexpected shards failed %v", failed)
+	}
+	if err := result.Err(); err == nil || !strings.Contains(err.Error(), "shard-2") || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("want the error of shard-2, got %v", err)
+	}
+	if len(clients["shard-1"].added) != 1 || len(clients["shard-3"].added) != 1 || len(clients["shard-2"].added) != 0 {
+		t.Fatalf("want the series of the shards succeeded applied only")
+	}
+
+	// retry the samples of the failed shard alone
+	clients["shard-2"].failNum = 0
+	fanoutApp.appenders["shard-2"].Add(lbls("b"), 2000, 1, 2)
+	fanoutApp.appenders["shard-2"].Add(lbls("c"), 1500, 1, 3)
+	if err := fanoutApp.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(clients["shard-1"].added) != 1 || len(clients["shard-2"].added) != 2 {
+		t.Fatalf("want only the samples of shard-2 written again")
+	}
+}
+
+func TestFanoutAppender_LabelOrder(t *testing.T) {
+	cluster := &fakeCluster{byLabels: func(_ time.Time, l []pb.Label, hash uint64) (string, error) {
+		return strconv.FormatUint(hash%16, 10), nil
+	}}
+
+	app := &fanoutAppender{appenders: make(map[string]*appender), cluster: cluster}
+	hasher := util.NewHasher()
+
+	inputs := [][]pb.Label{
+		{{Name: "__name__", Value: "up"}, {Name: "instance", Value: "a"}, {Name: "job", Value: "node"}},
+		{{Name: "job", Value: "node"}, {Name: "__name__", Value: "up"}, {Name: "instance", Value: "a"}},
+		{{Name: "instance", Value: "a"}, {Name: "job", Value: "node"}, {Name: "__name__", Value: "up"}},
+	}
+	for i, l := range inputs {
+		if err := app.Add(l, int64(i)*1000, 1, hasher.Hash(l)); err != nil {
+			t.Fatalf("input %d: unexpected error: %v", i, err)
+		}
+	}
+
+	if len(app.appenders) != 1 {
+		t.Fatalf("want the series routed to 1 shard, got %d", len(app.appenders))
+	}
+	for _, shardApp := range app.appenders {
+		if len(shardApp.series) != 1 {
+			t.Fatalf("want 1 series, got %d", len(shardApp.series))
+		}
+		for _, ss := range shardApp.series {
+			if len(ss) != 1 || len(ss[0].Points) != len(inputs) {
+				t.Fatalf("want all the points in 1 series, got %v", ss)
+			}
+			if ss[0].Labels[0].Name != "__name__" || ss[0].Labels[2].Name != "job" {
+				t.Fatalf("want labels sorted by name, got %v", ss[0].Labels)
+			}
+		}
+	}
+
+	dup := []pb.Label{{Name: "job", Value: "node"}, {Name: "__name__", Value: "up"}, {Name: "job", Value: "api"}}
+	if err := app.Add(dup, 0, 1, hasher.Hash(dup)); err == nil {
+		t.Fatalf("expected an error for duplicate label names")
+	}
+}
+
+// wireClient is a fakeClient receiving the requests over the wire, i.e. marshaled and unmarshaled.
+type wireClient struct {
+	fakeClient
+	received []*backendpb.AddRequest
+}
+
+func (c *wireClient) Add(ctx context.Context, req *backendpb.AddRequest) error {
+	b, err := req.Marshal()
+	if err != nil {
+		return err
+	}
+	received := new(backendpb.AddRequest)
+	if err = received.Unmarshal(b); err != nil {
+		return err
+	}
+	c.received = append(c.received, received)
+	return nil
+}
+
+func TestFanoutAppender_AddWithExemplar(t *testing.T) {
+	cluster := &fakeCluster{byLabels: func(_ time.Time, l []pb.Label, hash uint64) (string, error) {
+		return "shard-" + l[0].Value, nil
+	}}
+
+	clients := map[string]*wireClient{"shard-a": {}, "shard-b": {}}
+	fanoutApp := &fanoutAppender{appenders: make(map[string]*appender), cluster: cluster}
+	for shardID, cli := range clients {
+		fanoutApp.appenders[shardID] = &appender{client: cli, series: seriesHashMap{}, retryNum: 1}
+	}
+	var app ExemplarAppender = fanoutApp
+
+	exemplar := &pb.Exemplar{Labels: []pb.Label{{Name: "trace_id", Value: "4bf92f3577b34da6"}}, Value: 0.25, T: 1500}
+	a, b := []pb.Label{{Name: "__name__", Value: "a"}}, []pb.Label{{Name: "__name__", Value: "b"}}
+	if err := app.AddWithExemplar(a, 1000, 1, util.HashLabels(a), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.AddWithExemplar(a, 2000, 0.25, util.HashLabels(a), exemplar); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Add(b, 2000, 1, util.HashLabels(b)); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	received := clients["shard-a"].received
+	if len(received) != 1 || len(received[0].Series) != 1 {
+		t.Fatalf("want 1 series sent to shard-a, got %v", received)
+	}
+	points := received[0].Series[0].Points
+	if len(points) != 2 || points[0].E != nil || !reflect.DeepEqual(points[1].E, exemplar) {
+		t.Fatalf("want the exemplar sent with the second sample only, got %v", points)
+	}
+
+	received = clients["shard-b"].received
+	if len(received) != 1 || received[0].Series[0].Points[0].E != nil {
+		t.Fatalf("want no exemplar sent to shard-b, got %v", received)
+	}
+}
+
+func TestFanoutAppender_StrictOrder(t *testing.T) {
+	cluster := &fakeCluster{byLabels: func(time.Time, []pb.Label, uint64) (string, error) {
+		return "shard-1", nil
+	}}
+
+	up := []pb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "node"}}
+	down := []pb.Label{{Name: "__name__", Value: "down"}, {Name: "job", Value: "node"}}
+
+	newFanoutAppender := func(strict bool) *fanoutAppender {
+		app := &fanoutAppender{appenders: map[string]*appender{
+			"shard-1": {client: &fakeClient{}, series: seriesHashMap{}, retryNum: 1},
+		}, cluster: cluster}
+		if strict {
+			app.lastAdded = seriesHashMap{}
+		}
+		return app
+	}
+
+	// in order, a sample of another series in between doesn't matter
+	app := newFanoutAppender(true)
+	for _, add := range []struct {
+		l []pb.Label
+		t int64
+	}{{up, 1000}, {down, 3000}, {up, 2000}, {up, 2000}, {down, 4000}} {
+		if err := app.Add(add.l, add.t, 1, util.HashLabels(add.l)); err != nil {
+			t.Fatalf("unexpected error adding %v at %d: %v", add.l, add.t, err)
+		}
+	}
+
+	err := app.Add(up, 1500, 1, util.HashLabels(up))
+	e, ok := err.(*OutOfOrderError)
+	if !ok || e.Cause() != ErrOutOfOrder || e.T != 1500 || e.LastT != 2000 || e.Labels[0].Value != "up" {
+		t.Fatalf("want an out of order error of up at 1500, got %v", err)
+	}
+	if points := app.appenders["shard-1"].series.get(util.HashLabels(up), up).Points; len(points) != 3 {
+		t.Fatalf("want the out of order sample not added, got %v", points)
+	}
+
+	// the order is checked till the flush
+	if err = app.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err = app.Add(up, 1500, 1, util.HashLabels(up)); err != nil {
+		t.Fatalf("unexpected error after flush: %v", err)
+	}
+
+	app = newFanoutAppender(false)
+	for _, ts := range []int64{2000, 1000} {
+		if err = app.Add(up, ts, 1, util.HashLabels(up)); err != nil {
+			t.Fatalf("unexpected error of an appender not strict about the order: %v", err)
+		}
+	}
+}
+
+func TestAppender_FlushSeq(t *testing.T) {
+	cli := &fakeClient{failNum: 1}
+	app := &appender{client: cli, series: seriesHashMap{}, retryNum: 3, retryInterval: time.Millisecond, writerID: "gateway-1"}
+
+	lbls := []pb.Label{{Name: "__name__", Value: "test_metric"}}
+	for i := 0; i < 2; i++ {
+		if err := app.Add(lbls, int64(i)*1000, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := app.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(cli.seqs) != 3 || cli.seqs[0] != 1 || cli.seqs[1] != 1 || cli.seqs[2] != 2 {
+		t.Fatalf("want a retried batch to keep its seq, got %v", cli.seqs)
+	}
+}
+
+func TestAppender_AutoFlush(t *testing.T) {
+	lbls := []pb.Label{{Name: "__name__", Value: "test_metric"}}
+
+	cli := &fakeClient{}
+	app := &appender{client: cli, series: seriesHashMap{}, retryNum: 1, batchSize: 3}
+	for i := 0; i < 7; i++ {
+		if err := app.Add(lbls, int64(i)*1000, 1, 1); err != nil {
+			t.Fatalf("unexpected add error: %v", err)
+		}
+	}
+	if cli.attempts != 2 {
+		t.Fatalf("want 2 batches sent automatically, got %d", cli.attempts)
+	}
+	if err := app.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if cli.attempts != 3 {
+		t.Fatalf("want the rest sent by flush and nothing sent for an empty flush, got %d messages", cli.attempts)
+	}
+
+	// sent by the timer once old enough, though nothing is added after it
+	cli = &fakeClient{}
+	app = &appender{client: cli, series: seriesHashMap{}, retryNum: 1, batchInterval: 10 * time.Millisecond}
+	sent := func() int {
+		app.mtx.Lock()
+		defer app.mtx.Unlock()
+		return cli.attempts
+	}
+	for round := 1; round <= 2; round++ {
+		if err := app.Add(lbls, int64(round)*1000, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := app.Add(lbls, int64(round)*1000+500, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 100 && sent() < round; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		app.mtx.Lock()
+		if cli.attempts != round || len(cli.added[round-1].Points) != 2 {
+			app.mtx.Unlock()
+			t.Fatalf("round %d: want the old batch sent by the timer, got %d messages", round, cli.attempts)
+		}
+		app.mtx.Unlock()
+	}
+}
+
+// BenchmarkAppender_Batching compares the number of messages sent to the shards when
+// the caller flushes after every small write against flushing automatically by size.
+func BenchmarkAppender_Batching(b *testing.B) {
+	const (
+		shardNum       = 16
+		seriesNum      = 10000
+		seriesPerWrite = 10
+	)
+
+	lbls := make([][]pb.Label, seriesNum)
+	for i := range lbls {
+		lbls[i] = []pb.Label{{Name: "__name__", Value: "test_metric"}, {Name: "instance", Value: strconv.Itoa(i)}}
+	}
+
+	run := func(b *testing.B, batchSize int, flushPerWrite bool) {
+		var messages int
+		for i := 0; i < b.N; i++ {
+			clients := make([]*fakeClient, shardNum)
+			apps := make([]*appender, shardNum)
+			for j := range apps {
+				clients[j] = &fakeClient{}
+				apps[j] = &appender{client: clients[j], series: seriesHashMap{}, retryNum: 1, batchSize: batchSize}
+			}
+
+			for j, l := range lbls {
+				app := apps[j%shardNum]
+				if err := app.Add(l, int64(i), 1, uint64(j)); err != nil {
+					b.Fatal(err)
+				}
+				if flushPerWrite && (j+1)%seriesPerWrite == 0 {
+					for _, app := range apps {
+						if err := app.Flush(); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+			}
+			for _, app := range apps {
+				if err := app.Flush(); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			for _, cli := range clients {
+				messages += cli.attempts
+			}
+		}
+		b.Logf("%d messages per %d samples", messages/b.N, seriesNum)
+	}
+
+	b.Run("flush per write", func(b *testing.B) {
+		run(b, 0, true)
+	})
+	b.Run("auto flush", func(b *testing.B) {
+		run(b, 300, false)
+	})
+}
+
+func TestFanoutAppender_AddKeepsCallerLabels(t *testing.T) {
+	cluster := &fakeCluster{byLabels: func(time.Time, []pb.Label, uint64) (string, error) {
+		return "shard-1", nil
+	}}
+	app := &fanoutAppender{appenders: map[string]*appender{
+		"shard-1": {client: &fakeClient{}, series: seriesHashMap{}, retryNum: 1},
+	}, cluster: cluster, lastAdded: seriesHashMap{}}
+
+	l := []pb.Label{{Name: "job", Value: "node"}, {Name: "__name__", Value: "up"}}
+	if err := app.Add(l, 1000, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if l[0].Name != "job" || l[1].Name != "__name__" {
+		t.Fatalf("want the caller's labels left unsorted, got %v", l)
+	}
+
+	// the caller reuses its slice for another series
+	l[0].Value, l[1].Value = "other", "down"
+
+	up := []pb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "node"}}
+	hash := util.HashLabels(up)
+	if s := app.appenders["shard-1"].series.get(hash, up); s == nil || s.Labels[0].Value != "up" {
+		t.Fatalf("want the series of up kept, got %v", s)
+	}
+	if s := app.lastAdded.get(hash, up); s == nil || s.Labels[1].Value != "node" {
+		t.Fatalf("want the last sample of up kept, got %v", s)
+	}
+}
@@ -0,0 +1,385 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/util/os/fileutil"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrWriteQueueFull is returned for a batch which can't be queued as the queue of its shard is
+	// full and nothing may be spilled.
+	ErrWriteQueueFull = errors.New("write queue of the shard is full")
+	// ErrSpillFull is returned for a batch which can't be queued as the batches spilled of its shard
+	// reach the size cap.
+	ErrSpillFull = errors.New("batches spilled of the shard reach the size cap")
+)
+
+const (
+	queueReplayInterval = 5 * time.Second
+	defaultMaxSpillSize = 1 << 30
+	spillSegmentSize    = 64 << 20
+	spillHeaderSize     = 8 // the length and the crc32 of the batch of a record
+)
+
+var (
+	writeQueues sync.Map
+	castagnoli  = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// writeQueue keeps the batches of a shard failed to be sent, so that they're sent again, oldest first,
+// before the new ones once the shard recovers. Up to size batches are kept in memory, the ones beyond
+// are spilled to the numbered segment files in dir, which are replayed after a restart too.
+//
+// A batch spilled is a record of its length, its crc32 and itself. On replay, a record whose crc32
+// mismatches is skipped, and a partial one, left by a crash in the middle of a spill, ends its segment.
+// A segment is removed once replayed, and a batch replayed again after a restart is dropped by the
+// storage if it has been applied.
+//
+// The batches are replayed by the next batch flushed to the shard, and in the background every
+// queueReplayInterval, so that they aren't left queued while nothing is written to the shard.
+type writeQueue struct {
+	replayMtx sync.Mutex // one replay at a time, the batches are sent without holding mtx
+	replayer  sync.Once
+	mtx       sync.Mutex
+	mem       [][]byte
+	size      int
+	dir       string // empty if nothing may be spilled
+	maxBytes  int64
+	segments  []int    // oldest first
+	spilled   int64    // bytes of the segments
+	offset    int64    // of the next record to replay in the oldest segment
+	w         *os.File // the newest segment, appended to, nil till something is spilled
+	wSize     int64
+}
+
+// shardWriteQueue returns the write queue of the shard, shared by the appenders of it, nil if
+// queueing is disabled.
+func shardWriteQueue(shardID string) (*writeQueue, error) {
+	if vars.Cfg.Gateway == nil || vars.Cfg.Gateway.Appender == nil {
+		return nil, nil
+	}
+	cfg := vars.Cfg.Gateway.Appender
+	if cfg.QueueSize <= 0 && cfg.SpillDir == "" {
+		return nil, nil
+	}
+
+	if q, found := writeQueues.Load(shardID); found {
+		return q.(*writeQueue), nil
+	}
+
+	var dir string
+	if cfg.SpillDir != "" {
+		dir = filepath.Join(cfg.SpillDir, shardID)
+	}
+	q, err := newWriteQueue(cfg.QueueSize, dir, int64(cfg.MaxSpillSize))
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := writeQueues.LoadOrStore(shardID, q)
+	return actual.(*writeQueue), nil
+}
+
+// newWriteQueue returns a queue keeping up to size batches in memory and spilling the ones beyond
+// to dir, up to maxBytes. The segments left in dir are queued first.
+func newWriteQueue(size int, dir string, maxBytes int64) (*writeQueue, error) {
+	q := &writeQueue{size: size, dir: dir, maxBytes: maxBytes}
+	if q.maxBytes <= 0 {
+		q.maxBytes = defaultMaxSpillSize
+	}
+	if dir == "" {
+		return q, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create spill dir")
+	}
+	names, err := fileutil.ReadDirNames(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read spill dir")
+	}
+	for _, name := range names {
+		seq, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		fi, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to stat spilled segment")
+		}
+		q.segments = append(q.segments, seq)
+		q.spilled += fi.Size()
+	}
+	sort.Ints(q.segments)
+	// nothing is appended to the segments left, as a partial record at the end of the last one
+	// would swallow the records after it.
+	return q, nil
+}
+
+func (q *writeQueue) segmentPath(seq int) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%08d", seq))
+}
+
+func (q *writeQueue) empty() bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	return len(q.mem) == 0 && len(q.segments) == 0
+}
+
+// push queues the batch after the ones queued.
+func (q *writeQueue) push(batch []byte) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	// once a batch is spilled, the following ones are too till it's replayed, to keep them in order.
+	if len(q.segments) == 0 && len(q.mem) < q.size {
+		q.mem = append(q.mem, batch)
+		return nil
+	}
+	if q.dir == "" {
+		return ErrWriteQueueFull
+	}
+	return q.spill(batch)
+}
+
+func (q *writeQueue) spill(batch []byte) error {
+	n := int64(spillHeaderSize + len(batch))
+	if q.spilled+n > q.maxBytes {
+		return ErrSpillFull
+	}
+
+	if q.w == nil || q.wSize >= spillSegmentSize {
+		if q.w != nil {
+			q.w.Close()
+			q.w = nil
+		}
+		seq := 0
+		if len(q.segments) > 0 {
+			seq = q.segments[len(q.segments)-1] + 1
+		}
+		f, err := os.OpenFile(q.segmentPath(seq), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			return errors.Wrap(err, "failed to create spilled segment")
+		}
+		q.segments = append(q.segments, seq)
+		q.w, q.wSize = f, 0
+	}
+
+	record := make([]byte, n)
+	binary.BigEndian.PutUint32(record, uint32(len(batch)))
+	binary.BigEndian.PutUint32(record[4:], crc32.Checksum(batch, castagnoli))
+	copy(record[spillHeaderSize:], batch)
+
+	_, err := q.w.Write(record)
+	if err == nil {
+		err = fileutil.Fdatasync(q.w)
+	}
+	if err != nil {
+		// a partial record ends the segment on replay, so the next batch goes to a new one.
+		q.w.Close()
+		q.w = nil
+		return errors.Wrap(err, "failed to spill batch")
+	}
+	q.wSize += n
+	q.spilled += n
+	return nil
+}
+
+// replay sends the batches queued, oldest first, till one fails retryably, which is left queued with
+// the ones after it. A batch failed otherwise is dropped, as it fails anyway. The batches pushed while
+// replaying are queued after the ones being sent.
+func (q *writeQueue) replay(send func(batch []byte) error) error {
+	q.replayMtx.Lock()
+	defer q.replayMtx.Unlock()
+
+	for {
+		q.mtx.Lock()
+		if len(q.mem) == 0 {
+			q.mtx.Unlock()
+			break
+		}
+		batch := q.mem[0]
+		q.mtx.Unlock()
+
+		if err := resend(send, batch); err != nil {
+			return err
+		}
+
+		q.mtx.Lock()
+		q.mem[0] = nil
+		q.mem = q.mem[1:]
+		q.mtx.Unlock()
+	}
+	for {
+		q.mtx.Lock()
+		if len(q.segments) == 0 {
+			q.mtx.Unlock()
+			break
+		}
+		seq := q.segments[0]
+		q.mtx.Unlock()
+
+		if err := q.replaySegment(seq, send); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayInBackground starts replaying the batches queued every interval by send, once for the queue.
+func (q *writeQueue) replayInBackground(interval time.Duration, send func(batch []byte) error) {
+	q.replayer.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				if q.empty() {
+					continue
+				}
+				if err := q.replay(send); err != nil {
+					level.Debug(vars.Logger).Log("msg", "batches queued not replayed yet", "err", err)
+				}
+			}
+		}()
+	})
+}
+
+func resend(send func(batch []byte) error, batch []byte) error {
+	err := send(batch)
+	if err != nil && !pb.Retryable(err) {
+		level.Warn(vars.Logger).Log("msg", "drop queued batch", "err", err)
+		return nil
+	}
+	return err
+}
+
+// replaySegment sends the batches of the oldest segment from the offset, and removes the segment once
+// all of them are sent. The segment may be the one being spilled to, the records spilled while it's read
+// are read too before it's removed.
+func (q *writeQueue) replaySegment(seq int, send func(batch []byte) error) error {
+	path := q.segmentPath(seq)
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to open spilled segment")
+	}
+	defer f.Close()
+
+	header := make([]byte, spillHeaderSize)
+	for stalled := false; ; {
+		offset := q.offset
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return errors.Wrap(err, "failed to seek spilled segment")
+		}
+		if err = q.replayRecords(path, bufio.NewReader(f), header, send); err != nil {
+			return err
+		}
+
+		q.mtx.Lock()
+		// spilled to while being read, it's read again unless nothing more can be read twice in a row, e.g. a corrupt tail
+		if q.w != nil && len(q.segments) == 1 && q.wSize > q.offset && !(stalled && q.offset == offset) {
+			stalled = q.offset == offset
+			q.mtx.Unlock()
+			continue
+		}
+		if q.w != nil && len(q.segments) == 1 {
+			q.w.Close()
+			q.w = nil
+		}
+		err = q.removeSegment(path)
+		q.mtx.Unlock()
+		return err
+	}
+}
+
+// replayRecords sends the batches read by r from the offset till the end of the segment or a partial record,
+// a corrupt record is skipped.
+func (q *writeQueue) replayRecords(path string, r *bufio.Reader, header []byte, send func(batch []byte) error) error {
+	for {
+		if _, err := io.ReadFull(r, header); err == io.EOF {
+			return nil
+		} else if err == io.ErrUnexpectedEOF {
+			if !q.spilling(q.offset + spillHeaderSize) {
+				level.Warn(vars.Logger).Log("msg", "skip partial spilled record", "segment", path, "offset", q.offset)
+			}
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, "failed to read spilled segment")
+		}
+
+		length := int64(binary.BigEndian.Uint32(header))
+		if length > q.maxBytes {
+			level.Warn(vars.Logger).Log("msg", "skip corrupt spilled segment tail", "segment", path, "offset", q.offset)
+			return nil
+		}
+		batch := make([]byte, length)
+		if _, err := io.ReadFull(r, batch); err == io.EOF || err == io.ErrUnexpectedEOF {
+			if !q.spilling(q.offset + spillHeaderSize + length) {
+				level.Warn(vars.Logger).Log("msg", "skip partial spilled record", "segment", path, "offset", q.offset)
+			}
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, "failed to read spilled segment")
+		}
+
+		if crc32.Checksum(batch, castagnoli) != binary.BigEndian.Uint32(header[4:]) {
+			level.Warn(vars.Logger).Log("msg", "skip corrupt spilled record", "segment", path, "offset", q.offset)
+		} else if err := resend(send, batch); err != nil {
+			return err
+		}
+		q.offset += spillHeaderSize + length
+	}
+}
+
+// spilling tells whether the oldest segment is the one being spilled to, which has end bytes of records
+// at least, i.e. a partial record read at the end of it is being spilled rather than left by a crash.
+func (q *writeQueue) spilling(end int64) bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	return q.w != nil && len(q.segments) == 1 && q.wSize >= end
+}
+
+func (q *writeQueue) removeSegment(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to stat spilled segment")
+	}
+	if err := os.Remove(path); err != nil {
+		return errors.Wrap(err, "failed to remove spilled segment")
+	}
+	q.spilled -= fi.Size()
+	q.segments = q.segments[1:]
+	q.offset = 0
+	return nil
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log"
+)
+
+func TestWriteQueue_Spill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "writequeue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q, err := newWriteQueue(2, dir, 3*(spillHeaderSize+5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, batch := range []string{"batch", "batch", "batch", "batch", "batch"} {
+		if err := q.push([]byte(batch)); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+	if err := q.push([]byte("batch")); err != ErrSpillFull {
+		t.Fatalf("expected %v beyond the size cap, got %v", ErrSpillFull, err)
+	}
+	if len(q.mem) != 2 {
+		t.Fatalf("expected 2 batches in memory, got %d", len(q.mem))
+	}
+	fi, err := os.Stat(filepath.Join(dir, "00000000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 3*(spillHeaderSize+5) || q.spilled != fi.Size() {
+		t.Fatalf("expected 3 records spilled, got %d bytes, %d counted", fi.Size(), q.spilled)
+	}
+
+	q, err = newWriteQueue(1, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.push([]byte("batch")); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if err := q.push([]byte("batch")); err != ErrWriteQueueFull {
+		t.Fatalf("expected %v without a spill dir, got %v", ErrWriteQueueFull, err)
+	}
+}
+
+func TestAppender_ReplayAfterRecovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "writequeue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q, err := newWriteQueue(1, dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := &fakeClient{failNum: 3}
+	app := &appender{client: cli, series: seriesHashMap{}, retryNum: 1, retryInterval: time.Millisecond, queue: q}
+
+	lbls := []pb.Label{{Name: "__name__", Value: "a"}}
+	// the first batch fails and is queued in memory, the second replays it, fails and is spilled
+	// with the third.
+	for i := 0; i < 3; i++ {
+		app.Add(lbls, int64(i), float64(i), 1)
+		if err := app.Flush(); err != nil {
+			t.Fatalf("expected batch %d queued, got %v", i+1, err)
+		}
+	}
+	if len(q.mem) != 1 || len(q.segments) != 1 {
+		t.Fatalf("expected 1 batch in memory and 1 segment, got %d and %d", len(q.mem), len(q.segments))
+	}
+
+	// the shard recovers.
+	app.Add(lbls, 3, 3, 1)
+	if err := app.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	sent := cli.seqs[cli.failNum:]
+	if len(sent) != 4 {
+		t.Fatalf("expected 4 batches sent, got seqs %v", sent)
+	}
+	for i, seq := range sent {
+		if seq != uint64(i+1) {
+			t.Fatalf("expected batches sent in order, got seqs %v", sent)
+		}
+	}
+	if !q.empty() {
+		t.Fatal("expected the queue empty")
+	}
+	if names, _ := ioutil.ReadDir(dir); len(names) != 0 {
+		t.Fatalf("expected the segments removed, got %d files", len(names))
+	}
+
+	// a restart replays the batches spilled before it.
+	for seq := uint64(5); seq <= 6; seq++ {
+		batch, _ := (&backendpb.AddRequest{WriterID: "w", Seq: seq}).Marshal()
+		if err := q.spill(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.w.Close()
+	if q, err = newWriteQueue(1, dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	cli = &fakeClient{}
+	app = &appender{client: cli, series: seriesHashMap{}, retryNum: 1, seq: 6, queue: q}
+	app.Add(lbls, 7, 7, 1)
+	if err := app.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if len(cli.seqs) != 3 || cli.seqs[0] != 5 || cli.seqs[1] != 6 || cli.seqs[2] != 7 {
+		t.Fatalf("expected the spilled batches replayed first, got seqs %v", cli.seqs)
+	}
+}
+
+func TestWriteQueue_SkipCorruptRecords(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	dir, err := ioutil.TempDir("", "writequeue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q, err := newWriteQueue(0, dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, batch := range []string{"first", "second", "third"} {
+		if err := q.push([]byte(batch)); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+
+	path := filepath.Join(dir, "00000000")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// flip a byte of the second batch and leave a partial record at the end, as if a spill crashed.
+	data[2*spillHeaderSize+len("first")]++
+	data = append(data, 0, 0, 0, 100, 1, 2, 3, 4, 's', 'h', 'o')
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if q, err = newWriteQueue(0, dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	var replayed []string
+	err = q.replay(func(batch []byte) error {
+		replayed = append(replayed, string(batch))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if len(replayed) != 2 || replayed[0] != "first" || replayed[1] != "third" {
+		t.Fatalf("expected the corrupt and the partial records skipped, got %v", replayed)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the segment removed, got %v", err)
+	}
+	if q.spilled != 0 {
+		t.Fatalf("expected nothing spilled, got %d bytes", q.spilled)
+	}
+}
+
+func TestWriteQueue_ReplayInBackground(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	dir, err := ioutil.TempDir("", "writequeue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q, err := newWriteQueue(1, dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, batch := range []string{"first", "second", "third"} {
+		if err := q.push([]byte(batch)); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+
+	var (
+		mtx  sync.Mutex
+		sent []string
+	)
+	failNum := 2
+	q.replayInBackground(10*time.Millisecond, func(batch []byte) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if failNum > 0 {
+			failNum--
+			return errors.New("shard down")
+		}
+		if string(batch) == "first" {
+			// batches are pushed while the queued ones are being sent
+			if err := q.push([]byte("fourth")); err != nil {
+				t.Errorf("push while replaying failed: %v", err)
+			}
+		}
+		sent = append(sent, string(batch))
+		return nil
+	})
+
+	for i := 0; i < 200 && !q.empty(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	if !q.empty() || len(sent) != 4 || sent[0] != "first" || sent[1] != "second" || sent[2] != "third" || sent[3] != "fourth" {
+		t.Fatalf("want all the batches replayed in order without a flush, got %v", sent)
+	}
+	if names, _ := ioutil.ReadDir(dir); len(names) != 0 {
+		t.Fatalf("want the segments removed, got %d files", len(names))
+	}
+}
@@ -16,6 +16,9 @@
 package main
 
 import (
+	"context"
+	"time"
+
 	"github.com/baudtime/baudtime/msg"
 	"github.com/baudtime/baudtime/tcp"
 )
@@ -23,8 +26,27 @@ import (
 type CodedConn struct {
 	codec tcp.MsgCodec
 	*tcp.Conn
-	rBuf []byte
-	wBuf []byte
+	rBuf    []byte
+	wBuf    []byte
+	timeout time.Duration // how long a read or write may take, 0 means no limit
+}
+
+// ioTimeoutError is a net.Error telling a read or write didn't finish in time, after which
+// the connection is shut down.
+type ioTimeoutError struct {
+	op string
+}
+
+func (e ioTimeoutError) Error() string {
+	return e.op + " timeout, connection shut down"
+}
+
+func (e ioTimeoutError) Timeout() bool {
+	return true
+}
+
+func (e ioTimeoutError) Temporary() bool {
+	return false
 }
 
 func NewCodedConn(address string) (*CodedConn, error) {
@@ -34,13 +56,19 @@ func NewCodedConn(address string) (*CodedConn, error) {
 	}
 
 	return &CodedConn{
-		Conn:  c,
-		rBuf:  make([]byte, tcp.MaxMsgSize),
-		wBuf:  make([]byte, tcp.MaxMsgSize),
+		Conn:    c,
+		rBuf:    make([]byte, tcp.MaxMsgSize),
+		wBuf:    make([]byte, tcp.MaxMsgSize),
+		timeout: *rwTimeout,
 	}, nil
 }
 
 func (c *CodedConn) WriteRaw(msg msg.Message) error {
+	return c.WriteRawContext(context.Background(), msg)
+}
+
+// WriteRawContext is WriteRaw, it gives up once ctx is done or after the timeout of c.
+func (c *CodedConn) WriteRawContext(ctx context.Context, msg msg.Message) error {
 	n, err := c.codec.Encode(tcp.Message{
 		Message: msg,
 	}, c.wBuf)
@@ -48,16 +76,29 @@ func (c *CodedConn) WriteRaw(msg msg.Message) error {
 	if err != nil {
 		return err
 	}
-	err = c.WriteMsg(c.wBuf[:n])
-	if err != nil {
-		return err
-	}
 
-	return c.Flush()
+	return c.guard(ctx, "write", func() error {
+		err := c.WriteMsg(c.wBuf[:n])
+		if err != nil {
+			return err
+		}
+
+		return c.Flush()
+	})
 }
 
 func (c *CodedConn) ReadRaw() (msg.Message, error) {
-	n, err := c.ReadMsg(c.rBuf)
+	return c.ReadRawContext(context.Background())
+}
+
+// ReadRawContext is ReadRaw, it gives up once ctx is done or after the timeout of c.
+func (c *CodedConn) ReadRawContext(ctx context.Context) (msg.Message, error) {
+	var n int
+
+	err := c.guard(ctx, "read", func() (err error) {
+		n, err = c.ReadMsg(c.rBuf)
+		return
+	})
 	if err != nil {
 		return tcp.EmptyMsg, err
 	}
@@ -65,3 +106,41 @@ func (c *CodedConn) ReadRaw() (msg.Message, error) {
 	m, err := c.codec.Decode(c.rBuf[:n])
 	return m.Message, err
 }
+
+// guard runs f, if it doesn't return once ctx is done or after the timeout of c, the connection
+// is shut down to unblock it, and an ioTimeoutError is returned, so that the caller reconnects.
+// The reads and writes are on the raw fd rather than the net poller, deadlines don't apply to them.
+func (c *CodedConn) guard(ctx context.Context, op string, f func() error) error {
+	deadline, hasDeadline := ctx.Deadline()
+	if c.timeout > 0 && (!hasDeadline || time.Until(deadline) > c.timeout) {
+		deadline, hasDeadline = time.Now().Add(c.timeout), true
+	}
+	if !hasDeadline && ctx.Done() == nil {
+		return f()
+	}
+
+	var expired <-chan time.Time
+	if hasDeadline {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-expired:
+	case <-ctx.Done():
+	}
+
+	c.TCPConn.CloseRead()
+	c.TCPConn.CloseWrite()
+	<-done // the buffers must not be touched once returned
+
+	return ioTimeoutError{op: op}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+)
+
+func TestCodedConn_Timeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// accepts but never replies
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	rwTimeoutBak := *rwTimeout
+	defer func() {
+		*rwTimeout = rwTimeoutBak
+	}()
+	*rwTimeout = 100 * time.Millisecond
+
+	request := &pb.AdminCmdRequest{Command: &pb.AdminCmdRequest_Info{Info: &pb.Info{}}}
+
+	c, err := NewCodedConn(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err = c.WriteRaw(request); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = c.ReadRaw()
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() || !checkConnBroken(err) {
+		t.Fatalf("want a timeout net.Error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("read gave up after %v", elapsed)
+	}
+
+	// the context of a query cuts it shorter
+	c, err = NewCodedConn(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.timeout = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err = c.WriteRawContext(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.ReadRawContext(ctx); !checkConnBroken(err) {
+		t.Fatalf("want a broken connection once the context is done, got %v", err)
+	}
+}
@@ -16,18 +16,22 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/user"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/baudtime/baudtime/promql"
 	"github.com/peterh/liner"
+	"github.com/pkg/errors"
 )
 
 var (
@@ -35,14 +39,63 @@ var (
 	historyFile    = filepath.Join(currentUser.HomeDir, ".baudtime")
 	ip             = flag.String("h", "127.0.0.1", "baudtime server ip (default 127.0.0.1)")
 	port           = flag.Int("p", 8088, "baudtime server port (default 8088)")
+	address        = flag.String("addr", "", "baudtime server address host:port, an alternative to -h and -p")
+	format         = flag.String("f", formatJSON, "format of query results, json, table or csv (default json)")
+	execute        = flag.String("e", "", "execute the command and exit, - reads commands from stdin line by line and stops at the first failed one")
+	retryNum       = flag.Int("retry", 5, "attempts to connect to the server before giving up (default 5)")
+	rwTimeout      = flag.Duration("timeout", 2*time.Minute, "how long a request may wait for the server, the connection is reset after it, 0 means no limit (default 2m)")
 	queryTimeout   = 120 * time.Second
+	minBackoff     = 100 * time.Millisecond
+	maxBackoff     = 5 * time.Second
 )
 
 var line *liner.State
 
+var commandRegexp = regexp.MustCompile(`'.*?'|".*?"|\S+`)
+
 func main() {
 	flag.Parse()
 
+	if !validFormat(*format) {
+		fmt.Printf("unknown format %s\n", *format)
+		os.Exit(1)
+	}
+
+	addr, err := serverAddr(*address, *ip, *port)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	exec := &executor{
+		addr:        addr,
+		queryEngine: promql.NewEngine(nil, 20, queryTimeout),
+		format:      *format,
+		retryNum:    *retryNum,
+		dial:        NewCodedConn,
+	}
+	err = exec.reconnect()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *execute != "" {
+		if *execute == "-" {
+			err = execLines(os.Stdin, exec.runCommand)
+		} else if cmd, args := splitCommand(*execute); cmd != "" {
+			err = exec.runCommand(cmd, args...)
+		}
+
+		if !exec.closed {
+			exec.codedConn.Close()
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
 	line = liner.NewLiner()
 	defer line.Close()
 
@@ -53,49 +106,86 @@ func main() {
 
 	defer saveHistory()
 
-	var addr = fmt.Sprintf("%s:%d", *ip, *port)
-
-	reg, _ := regexp.Compile(`'.*?'|".*?"|\S+`)
 	prompt := fmt.Sprintf("%s> ", addr)
 
-	exec := &executor{
-		addr:        addr,
-		queryEngine: promql.NewEngine(nil, 20, queryTimeout),
-	}
-	err := exec.reconnect()
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-
 	for !exec.closed {
-		cmd, err := line.Prompt(prompt)
+		cmdLine, err := line.Prompt(prompt)
 		if err != nil {
 			fmt.Printf("%s\n", err.Error())
 			return
 		}
 
-		cmds := reg.FindAllString(cmd, -1)
-		if len(cmds) == 0 {
+		cmd, args := splitCommand(cmdLine)
+		if cmd == "" {
 			continue
-		} else {
-			line.AppendHistory(cmd)
+		}
+		line.AppendHistory(cmdLine)
 
-			args := make([]string, len(cmds[1:]))
+		exec.runCommand(cmd, args...)
+	}
+}
 
-			for i := range args {
-				args[i] = strings.Trim(cmds[1+i], "\"'")
-			}
+var hostnameRegexp = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.?$`)
 
-			cmd := strings.ToLower(cmds[0])
-			err = exec.execCommand(cmd, args...)
-			if checkConnBroken(err) {
-				fmt.Print("\n\nTry to reconnect...\n\n")
-				exec.reconnect()
-				exec.execCommand(cmd, args...)
-			}
+// serverAddr validates and joins the host and port of the server, addr in the form of host:port
+// takes precedence over them if it's not empty. An IPv6 host may be enclosed in brackets or not.
+func serverAddr(addr, host string, port int) (string, error) {
+	if addr != "" {
+		h, p, err := net.SplitHostPort(addr)
+		if err != nil {
+			return "", errors.Errorf("invalid address %q: %v", addr, err)
+		}
+		if port, err = strconv.Atoi(p); err != nil {
+			return "", errors.Errorf("invalid port %q in address %q", p, addr)
+		}
+		host = h
+	}
+
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if host == "" {
+		return "", errors.New("empty host")
+	}
+	if net.ParseIP(host) == nil && (strings.Contains(host, ":") || len(host) > 253 || !hostnameRegexp.MatchString(host)) {
+		return "", errors.Errorf("invalid host %q, neither an ip nor a hostname", host)
+	}
+	if port <= 0 || port > 65535 {
+		return "", errors.Errorf("invalid port %d, must be in 1-65535", port)
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// splitCommand splits a command line to the lowercased command and its arguments, an argument
+// may be quoted to contain spaces.
+func splitCommand(cmdLine string) (string, []string) {
+	cmds := commandRegexp.FindAllString(cmdLine, -1)
+	if len(cmds) == 0 {
+		return "", nil
+	}
+
+	args := make([]string, len(cmds[1:]))
+	for i := range args {
+		args[i] = strings.Trim(cmds[1+i], "\"'")
+	}
+
+	return strings.ToLower(cmds[0]), args
+}
+
+// execLines runs the commands read from r line by line, it stops at the first failed one.
+func execLines(r io.Reader, run func(cmd string, args ...string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		cmd, args := splitCommand(scanner.Text())
+		if cmd == "" {
+			continue
+		}
+		if err := run(cmd, args...); err != nil {
+			return err
 		}
 	}
+	return scanner.Err()
 }
 
 func printGenericHelp() {
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+)
+
+func TestSplitCommand(t *testing.T) {
+	cmd, args := splitCommand(`INSTANTQRY 'up{job="node"}' 1560000000`)
+	if cmd != "instantqry" || len(args) != 2 || args[0] != `up{job="node"}` || args[1] != "1560000000" {
+		t.Fatalf("unexpected command %s %q", cmd, args)
+	}
+
+	if cmd, _ = splitCommand("  \t"); cmd != "" {
+		t.Fatalf("want no command of a blank line, got %s", cmd)
+	}
+}
+
+func TestExecLines(t *testing.T) {
+	var executed []string
+	run := func(cmd string, args ...string) error {
+		executed = append(executed, strings.Join(append([]string{cmd}, args...), " "))
+		if cmd == "bad" {
+			return errors.New("unknown command bad")
+		}
+		return nil
+	}
+
+	err := execLines(strings.NewReader("info\n\nlabelvals job\n"), run)
+	if err != nil || strings.Join(executed, ";") != "info;labelvals job" {
+		t.Fatalf("unexpected execution %q, err: %v", executed, err)
+	}
+
+	executed = nil
+	err = execLines(strings.NewReader("info\nbad\nlabelvals job\n"), run)
+	if err == nil || strings.Join(executed, ";") != "info;bad" {
+		t.Fatalf("want execution stopped at the failed command, got %q, err: %v", executed, err)
+	}
+}
+
+func TestServerAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		host    string
+		port    int
+		want    string
+		wantErr bool
+	}{
+		{host: "127.0.0.1", port: 8088, want: "127.0.0.1:8088"},
+		{host: "::1", port: 8088, want: "[::1]:8088"},
+		{host: "[fe80::1]", port: 8088, want: "[fe80::1]:8088"},
+		{host: "baudtime-01.example.com", port: 8088, want: "baudtime-01.example.com:8088"},
+		{host: "localhost", port: 80, want: "localhost:80"},
+		{addr: "10.0.0.1:8121", host: "127.0.0.1", port: 8088, want: "10.0.0.1:8121"},
+		{addr: "[2001:db8::1]:8121", want: "[2001:db8::1]:8121"},
+		{addr: "baudtime:8121", want: "baudtime:8121"},
+		{addr: "2001:db8::1:8121", wantErr: true},
+		{addr: "10.0.0.1", wantErr: true},
+		{addr: "10.0.0.1:http", wantErr: true},
+		{addr: ":8121", wantErr: true},
+		{host: "", port: 8088, wantErr: true},
+		{host: "10.0.0.1:8088", port: 8088, wantErr: true},
+		{host: "bad_host", port: 8088, wantErr: true},
+		{host: "-baudtime", port: 8088, wantErr: true},
+		{host: "127.0.0.1", port: 0, wantErr: true},
+		{host: "127.0.0.1", port: 65536, wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := serverAddr(test.addr, test.host, test.port)
+		if (err != nil) != test.wantErr {
+			t.Fatalf("addr %q host %q port %d: want error %v, got %v", test.addr, test.host, test.port, test.wantErr, err)
+		}
+		if got != test.want {
+			t.Fatalf("addr %q host %q port %d: want %q, got %q", test.addr, test.host, test.port, test.want, got)
+		}
+	}
+}
+
+func TestExecutor_WrongArgs(t *testing.T) {
+	e := &executor{format: "json"}
+	for _, cmd := range [][]string{{"info", "x"}, {"slaveof", "127.0.0.1"}, {"route"}, {"timing", "maybe"}, {"labelvals"}} {
+		if err := e.execCommand(cmd[0], cmd[1:]...); err == nil {
+			t.Fatalf("%v: want an error of the wrong arguments", cmd)
+		}
+	}
+
+	// showing a setting takes no argument
+	if err := e.execCommand("format"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecutor_Reconnect(t *testing.T) {
+	minBackoffBak, maxBackoffBak := minBackoff, maxBackoff
+	defer func() {
+		minBackoff, maxBackoff = minBackoffBak, maxBackoffBak
+	}()
+	minBackoff, maxBackoff = time.Millisecond, 4*time.Millisecond
+
+	var attempts int
+	up := false
+	dial := func(addr string) (*CodedConn, error) {
+		attempts++
+		if up {
+			return &CodedConn{}, nil
+		}
+		return nil, errors.New("connection refused")
+	}
+
+	e := &executor{addr: "127.0.0.1:8088", retryNum: 4, dial: dial}
+	err := e.reconnect()
+	if err == nil || !strings.Contains(err.Error(), "server unreachable") {
+		t.Fatalf("want server unreachable, got %v", err)
+	}
+	if attempts != 4 {
+		t.Fatalf("want 4 attempts, got %d", attempts)
+	}
+	if e.backoff != maxBackoff {
+		t.Fatalf("want backoff capped at %v, got %v", maxBackoff, e.backoff)
+	}
+
+	attempts, up = 0, true
+	if err = e.reconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 1 || e.codedConn == nil || e.backoff != 0 {
+		t.Fatalf("want connected at once with backoff reset, got %d attempts, backoff %v", attempts, e.backoff)
+	}
+}
+
+func TestParseRoute(t *testing.T) {
+	now := time.Unix(1560000000, 0)
+	day, _ := time.Parse("2006-01-02", "2019-06-01")
+
+	tests := []struct {
+		args     []string
+		wantLbls int
+		wantTime int64
+		wantErr  bool
+	}{
+		{args: []string{"up"}, wantLbls: 1, wantTime: 1560000000000},
+		{args: []string{"up", `job="node"`, "instance=10.0.0.1"}, wantLbls: 3, wantTime: 1560000000000},
+		{args: []string{"up", "job=node", "2019-06-01"}, wantLbls: 2, wantTime: day.UnixNano() / int64(time.Millisecond)},
+		{args: []string{"up", "1559000000"}, wantLbls: 1, wantTime: 1559000000000},
+		{args: []string{"up", "job", "2019-06-01"}, wantErr: true},
+		{args: []string{"up", "yesterday"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		route, err := parseRoute(test.args, now)
+		if test.wantErr {
+			if err == nil {
+				t.Fatalf("args %q: want error", test.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("args %q: unexpected error %v", test.args, err)
+		}
+		if len(route.Labels) != test.wantLbls || route.Labels[0].Value != "up" || route.Time != test.wantTime {
+			t.Fatalf("args %q: unexpected route %+v", test.args, route)
+		}
+	}
+
+	route, _ := parseRoute([]string{"up", `job="node"`}, now)
+	if route.Labels[1].Name != "job" || route.Labels[1].Value != "node" {
+		t.Fatalf("want quotes trimmed, got %+v", route.Labels[1])
+	}
+}
+
+func TestParseRebalance(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    pb.Rebalance
+		wantErr bool
+	}{
+		{args: nil, want: pb.Rebalance{}},
+		{args: []string{"status"}, want: pb.Rebalance{Status: true}},
+		{args: []string{"plan", "days=7"}, want: pb.Rebalance{DryRun: true, Days: 7}},
+		{args: []string{"up", "days=2", "node_load1"}, want: pb.Rebalance{Days: 2, Metrics: []string{"up", "node_load1"}}},
+		{args: []string{"status", "up"}, wantErr: true},
+		{args: []string{"days=0"}, wantErr: true},
+		{args: []string{"days=a"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		rebalance, err := parseRebalance(test.args)
+		if test.wantErr {
+			if err == nil {
+				t.Fatalf("args %q: want error", test.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("args %q: unexpected error %v", test.args, err)
+		}
+		if !reflect.DeepEqual(*rebalance, test.want) {
+			t.Fatalf("args %q: want %+v, got %+v", test.args, test.want, *rebalance)
+		}
+	}
+}
+
+func TestParseListMetrics(t *testing.T) {
+	if listMetrics, err := parseListMetrics(nil); err != nil || listMetrics.Full {
+		t.Fatalf("no args: want the routed metrics, got %+v, %v", listMetrics, err)
+	}
+	if listMetrics, err := parseListMetrics([]string{"full"}); err != nil || !listMetrics.Full {
+		t.Fatalf("full: want all the shards asked, got %+v, %v", listMetrics, err)
+	}
+	for _, args := range [][]string{{"up"}, {"full", "up"}} {
+		if _, err := parseListMetrics(args); err == nil {
+			t.Fatalf("args %q: want error", args)
+		}
+	}
+}
@@ -17,10 +17,18 @@ package main
 
 var helpCommands = [][]string{
 	{"SLAVEOF", "host port", "Replication"},
+	{"FORMAT", "[json|table|csv]", "Format of query results, csv and table print a row per sample"},
+	{"TIMING", "[on|off]", "Print how long a query takes and the number of series and samples it returns, on by default"},
 	{"INSTANTQRY", "expression [timestamp]", "10bit unix timestamp is the number of seconds that have elapsed since 00:00:00 Coordinated Universal Time (UTC), Thursday, 1 January 1970"},
 	{"WRITEPOINT", "metric{l=v, l=v} value timestamp", ""},
 	{"LABELVALS", "name constraint", "Server"},
+	{"ROUTE", "metric [label=value ...] [day]", "Shard a series is written to, day is 2006-01-02 or a timestamp, today by default"},
+	{"REBALANCE", "[status | [plan] [days=N] [metric ...]]", "Route the metrics to all the shards in balance from tomorrow on, plan only tells the moves, status the progress"},
+	{"METRICS", "[full]", "Names of the metrics routed in etcd, full asks all the shards for the ones stored"},
 	{"JOINCLUSTER", "-", "Server"},
+	{"LEAVECLUSTER", "-", "Server"},
+	{"DRAIN", "-", "Stop picking the shard of the node for new series, it still serves the ones it has"},
+	{"UNDRAIN", "-", "Pick the shard of the node for new series again"},
 	{"INFO", "-", "Server"},
 	{"PING", "-", "Server"},
 }
@@ -17,7 +17,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/baudtime/baudtime"
 	"github.com/baudtime/baudtime/msg"
@@ -27,6 +26,7 @@ import (
 	"github.com/baudtime/baudtime/util"
 	ts "github.com/baudtime/baudtime/util/time"
 	"github.com/pkg/errors"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -41,7 +41,18 @@ type executor struct {
 	addr        string
 	codedConn   *CodedConn
 	queryEngine *promql.Engine
+	format      string // how query results are printed, json, table or csv
+	noTiming    bool   // don't print how long a query takes and how much data it returns
 	closed      bool
+	retryNum    int           // max attempts of connecting to the server in one reconnect
+	backoff     time.Duration // how long to wait before the next attempt, it's reset once connected
+	dial        func(address string) (*CodedConn, error)
+}
+
+// usage prints the help of cmd run with wrong arguments and fails it, so that the console run by -e exits non-zero.
+func usage(cmd string) error {
+	printCommandHelp(cmd)
+	return errors.Errorf("wrong arguments of %s", cmd)
 }
 
 func (e *executor) execCommand(cmd string, args ...string) error {
@@ -54,8 +65,7 @@ func (e *executor) execCommand(cmd string, args ...string) error {
 		e.closed = true
 	case "joincluster":
 		if len(args) != 0 {
-			printCommandHelp(cmd)
-			return nil
+			return usage(cmd)
 		}
 
 		command := &pb.AdminCmdRequest{
@@ -64,41 +74,109 @@ func (e *executor) execCommand(cmd string, args ...string) error {
 			},
 		}
 
+		return e.execComand(command)
+	case "leavecluster":
+		if len(args) != 0 {
+			return usage(cmd)
+		}
+
+		command := &pb.AdminCmdRequest{
+			Command: &pb.AdminCmdRequest_LeaveCluster{
+				LeaveCluster: &pb.LeaveCluster{},
+			},
+		}
+
+		return e.execComand(command)
+	case "drain", "undrain":
+		if len(args) != 0 {
+			return usage(cmd)
+		}
+
+		command := &pb.AdminCmdRequest{
+			Command: &pb.AdminCmdRequest_Drain{
+				Drain: &pb.Drain{Undo: cmd == "undrain"},
+			},
+		}
+
 		return e.execComand(command)
 	case "info":
 		if len(args) != 0 {
-			printCommandHelp(cmd)
-			return nil
+			return usage(cmd)
 		}
 
 		command := &pb.AdminCmdRequest{
 			Command: &pb.AdminCmdRequest_Info{
-				Info: &pb.Info{},
+				Info: &pb.Info{Detailed: true},
 			},
 		}
 
 		return e.execComand(command)
 	case "slaveof":
 		if len(args) != 2 {
-			printCommandHelp(cmd)
-			return nil
+			return usage(cmd)
 		}
 
-		var command *backendpb.SlaveOfCommand
+		slaveOf := &pb.SlaveOf{}
+		if args[0] != "no" || args[1] != "one" {
+			slaveOf.MasterAddr = fmt.Sprintf("%s:%s", args[0], args[1])
+		}
 
-		if args[0] == "no" && args[1] == "one" {
-			command = &backendpb.SlaveOfCommand{}
-		} else {
-			command = &backendpb.SlaveOfCommand{
-				MasterAddr: fmt.Sprintf("%s:%s", args[0], args[1]),
-			}
+		command := &pb.AdminCmdRequest{
+			Command: &pb.AdminCmdRequest_SlaveOf{
+				SlaveOf: slaveOf,
+			},
+		}
+
+		return e.execComand(command)
+	case "route":
+		if len(args) == 0 {
+			return usage(cmd)
+		}
+
+		route, err := parseRoute(args, time.Now())
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+
+		command := &pb.AdminCmdRequest{
+			Command: &pb.AdminCmdRequest_Route{
+				Route: route,
+			},
+		}
+
+		return e.execComand(command)
+	case "rebalance":
+		rebalance, err := parseRebalance(args)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+
+		command := &pb.AdminCmdRequest{
+			Command: &pb.AdminCmdRequest_Rebalance{
+				Rebalance: rebalance,
+			},
+		}
+
+		return e.execComand(command)
+	case "metrics":
+		listMetrics, err := parseListMetrics(args)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+
+		command := &pb.AdminCmdRequest{
+			Command: &pb.AdminCmdRequest_ListMetrics{
+				ListMetrics: listMetrics,
+			},
 		}
 
 		return e.execComand(command)
 	case "instantqry":
 		if len(args) != 1 && len(args) != 2 {
-			printCommandHelp(cmd)
-			return nil
+			return usage(cmd)
 		}
 
 		expression := strings.Replace(args[0], " ", "", -1)
@@ -108,14 +186,15 @@ func (e *executor) execCommand(cmd string, args ...string) error {
 			var err error
 			ts, err = baudtime.ParseTime(args[1])
 			if err != nil {
-				fmt.Print(err)
+				fmt.Println(err)
 				return err
 			}
 		}
 
+		start := time.Now()
 		qry, err := e.queryEngine.NewInstantQuery(QueryableConn(e.codedConn), expression, ts)
 		if err != nil {
-			fmt.Print(err)
+			fmt.Println(err)
 			return err
 		}
 
@@ -123,24 +202,46 @@ func (e *executor) execCommand(cmd string, args ...string) error {
 		res := qry.Exec(ctx)
 		cancel()
 		if res.Err != nil {
-			fmt.Print(res.Err)
+			fmt.Println(res.Err)
 			return res.Err
 		}
+		took := time.Since(start)
 
-		queryRes, err := json.MarshalIndent(&queryResult{
-			ResultType: res.Value.Type(),
-			Result:     res.Value,
-		}, "", "\t")
-		if err != nil {
-			fmt.Print(err)
+		if err = writeResult(os.Stdout, e.format, res.Value); err != nil {
+			fmt.Println(err)
 			return err
 		}
+		if !e.noTiming {
+			// to stderr, so that the result piped to a file stays clean
+			writeTiming(os.Stderr, took, res.Value)
+		}
+	case "timing":
+		if len(args) == 0 {
+			if e.noTiming {
+				fmt.Println("off")
+			} else {
+				fmt.Println("on")
+			}
+			return nil
+		}
+		if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+			return usage(cmd)
+		}
+
+		e.noTiming = args[0] == "off"
+	case "format":
+		if len(args) == 0 {
+			fmt.Println(e.format)
+			return nil
+		}
+		if len(args) != 1 || !validFormat(args[0]) {
+			return usage(cmd)
+		}
 
-		fmt.Println(string(queryRes))
+		e.format = args[0]
 	case "writepoint":
 		if len(args) != 2 && len(args) != 3 {
-			printCommandHelp(cmd)
-			return nil
+			return usage(cmd)
 		}
 
 		var labels []pb.Label
@@ -204,8 +305,7 @@ func (e *executor) execCommand(cmd string, args ...string) error {
 		}
 	case "labelvals":
 		if len(args) == 0 {
-			printCommandHelp(cmd)
-			return nil
+			return usage(cmd)
 		}
 
 		command := &backendpb.LabelValuesRequest{
@@ -223,11 +323,90 @@ func (e *executor) execCommand(cmd string, args ...string) error {
 		return e.execComand(command)
 	default:
 		fmt.Println("Unkown Command")
+		return errors.Errorf("unknown command %s", cmd)
 	}
 
 	return nil
 }
 
+// parseRoute parses the arguments of route, a metric name followed by label=value pairs and optionally
+// a day, either 2006-01-02 or a timestamp, now if it's not given.
+func parseRoute(args []string, now time.Time) (*pb.Route, error) {
+	route := &pb.Route{
+		Labels: []pb.Label{{Name: "__name__", Value: args[0]}},
+		Time:   ts.FromTime(now),
+	}
+
+	for i, arg := range args[1:] {
+		idx := strings.Index(arg, "=")
+		if idx < 0 {
+			if i != len(args)-2 {
+				return nil, errors.Errorf("invalid label %q, want label=value", arg)
+			}
+
+			t, err := time.Parse("2006-01-02", arg)
+			if err != nil {
+				if t, err = baudtime.ParseTime(arg); err != nil {
+					return nil, errors.Errorf("invalid day %q", arg)
+				}
+			}
+			route.Time = ts.FromTime(t)
+			break
+		}
+
+		route.Labels = append(route.Labels, pb.Label{
+			Name:  arg[:idx],
+			Value: strings.Trim(arg[idx+1:], "\""),
+		})
+	}
+
+	return route, nil
+}
+
+// parseRebalance parses the arguments of rebalance, status alone, or optionally plan for a dry run,
+// days=N and the metrics to move.
+func parseRebalance(args []string) (*pb.Rebalance, error) {
+	rebalance := &pb.Rebalance{}
+
+	if len(args) > 0 && args[0] == "status" {
+		if len(args) != 1 {
+			return nil, errors.New("status takes no arguments")
+		}
+		rebalance.Status = true
+		return rebalance, nil
+	}
+	if len(args) > 0 && args[0] == "plan" {
+		rebalance.DryRun = true
+		args = args[1:]
+	}
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "days=") {
+			days, err := strconv.ParseUint(strings.TrimPrefix(arg, "days="), 10, 32)
+			if err != nil || days == 0 {
+				return nil, errors.Errorf("invalid days %q", arg)
+			}
+			rebalance.Days = uint32(days)
+			continue
+		}
+		rebalance.Metrics = append(rebalance.Metrics, arg)
+	}
+
+	return rebalance, nil
+}
+
+// parseListMetrics parses the arguments of metrics, nothing or full to ask all the shards.
+func parseListMetrics(args []string) (*pb.ListMetrics, error) {
+	switch {
+	case len(args) == 0:
+		return &pb.ListMetrics{}, nil
+	case len(args) == 1 && args[0] == "full":
+		return &pb.ListMetrics{Full: true}, nil
+	default:
+		return nil, errors.Errorf("invalid arguments %q, want nothing or full", args)
+	}
+}
+
 func (e *executor) execComand(cmd msg.Message) error {
 	if cmd != nil {
 		err := e.codedConn.WriteRaw(cmd)
@@ -253,12 +432,35 @@ func (e *executor) execComand(cmd msg.Message) error {
 				fmt.Println(r.Message)
 			} else {
 				fmt.Println("Err")
+				return r.Err()
+			}
+		case *pb.InfoResponse:
+			if r.Status == pb.StatusCode_Succeed {
+				writeInfo(os.Stdout, r, time.Now())
+			} else {
+				fmt.Println(r.ErrorMsg)
+				return errors.New(r.ErrorMsg)
+			}
+		case *pb.RouteResponse:
+			if r.Status == pb.StatusCode_Succeed {
+				writeRoute(os.Stdout, r)
+			} else {
+				fmt.Println(r.ErrorMsg)
+				return errors.New(r.ErrorMsg)
+			}
+		case *pb.RebalanceResponse:
+			if r.Status == pb.StatusCode_Succeed {
+				writeRebalance(os.Stdout, r)
+			} else {
+				fmt.Println(r.ErrorMsg)
+				return errors.New(r.ErrorMsg)
 			}
 		case *pb.LabelValuesResponse:
 			if r.Status == pb.StatusCode_Succeed {
 				fmt.Println(r.Values)
 			} else {
 				fmt.Println(r.ErrorMsg)
+				return errors.New(r.ErrorMsg)
 			}
 		default:
 			fmt.Print("invalid reply")
@@ -268,7 +470,43 @@ func (e *executor) execComand(cmd msg.Message) error {
 	return nil
 }
 
-func (e *executor) reconnect() (err error) {
-	e.codedConn, err = NewCodedConn(e.addr)
-	return
+// runCommand executes a command, it's executed again after reconnecting if the connection is broken.
+func (e *executor) runCommand(cmd string, args ...string) error {
+	err := e.execCommand(cmd, args...)
+	if checkConnBroken(err) {
+		fmt.Print("\n\nTry to reconnect...\n\n")
+		if err = e.reconnect(); err != nil {
+			fmt.Println(err)
+			return err
+		}
+		err = e.execCommand(cmd, args...)
+	}
+	return err
+}
+
+// reconnect connects to the server, it makes at most retryNum attempts with capped exponential backoff
+// between them. The backoff carries over to the next reconnect until an attempt succeeds, so that
+// repeating commands against a down server doesn't hammer it. The broken connection is kept if all
+// the attempts fail, the next command fails on it and reconnects again.
+func (e *executor) reconnect() error {
+	attempts := e.retryNum
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if e.backoff > 0 {
+			time.Sleep(e.backoff)
+		}
+
+		var c *CodedConn
+		if c, err = e.dial(e.addr); err == nil {
+			e.codedConn, e.backoff = c, 0
+			return nil
+		}
+		e.backoff = ts.Exponential(e.backoff, minBackoff, maxBackoff)
+	}
+
+	return errors.Errorf("server unreachable, %d attempts to connect to %s failed, last error: %v", attempts, e.addr, err)
 }
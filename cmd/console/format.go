@@ -0,0 +1,259 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/promql"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+const (
+	formatJSON  = "json"
+	formatTable = "table"
+	formatCSV   = "csv"
+)
+
+func validFormat(format string) bool {
+	return format == formatJSON || format == formatTable || format == formatCSV
+}
+
+// resultRow is a sample of a query result, metric and labels are empty for scalars and strings.
+type resultRow struct {
+	metric string
+	labels labels.Labels
+	t      int64
+	v      string
+}
+
+// writeResult renders a query result in format, csv and table have one row per sample with
+// columns for the metric, each label, the timestamp and the value.
+func writeResult(w io.Writer, format string, v promql.Value) error {
+	switch format {
+	case formatJSON:
+		queryRes, err := json.MarshalIndent(&queryResult{
+			ResultType: v.Type(),
+			Result:     v,
+		}, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(queryRes))
+		return err
+	case formatCSV, formatTable:
+	default:
+		return errors.Errorf("unknown format %s", format)
+	}
+
+	rows, labelNames := resultRows(v)
+
+	header := append([]string{"metric"}, labelNames...)
+	header = append(header, "timestamp", "value")
+
+	records := make([][]string, 0, len(rows)+1)
+	records = append(records, header)
+	for _, row := range rows {
+		record := make([]string, 0, len(header))
+		record = append(record, row.metric)
+		for _, name := range labelNames {
+			record = append(record, row.labels.Get(name))
+		}
+		if format == formatCSV {
+			record = append(record, strconv.FormatInt(row.t, 10), row.v)
+		} else {
+			record = append(record, time.Unix(0, row.t*int64(time.Millisecond)).Format("2006-01-02 15:04:05.000"), row.v)
+		}
+		records = append(records, record)
+	}
+
+	if format == formatCSV {
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(records); err != nil {
+			return err
+		}
+		return cw.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, record := range records {
+		for i, field := range record {
+			if i > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, field)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// resultRows flattens v to samples, it returns the names of all the labels but the metric name sorted.
+func resultRows(v promql.Value) ([]resultRow, []string) {
+	var rows []resultRow
+	names := make(map[string]struct{})
+
+	addSeries := func(metric labels.Labels, points []promql.Point) {
+		for _, l := range metric {
+			if l.Name != labels.MetricName {
+				names[l.Name] = struct{}{}
+			}
+		}
+		for _, p := range points {
+			rows = append(rows, resultRow{
+				metric: metric.Get(labels.MetricName),
+				labels: metric,
+				t:      p.T,
+				v:      strconv.FormatFloat(p.V, 'f', -1, 64),
+			})
+		}
+	}
+
+	switch val := v.(type) {
+	case promql.Matrix:
+		for _, s := range val {
+			addSeries(s.Metric, s.Points)
+		}
+	case promql.Vector:
+		for _, s := range val {
+			addSeries(s.Metric, []promql.Point{s.Point})
+		}
+	case promql.Scalar:
+		rows = append(rows, resultRow{t: val.T, v: strconv.FormatFloat(val.V, 'f', -1, 64)})
+	case promql.String:
+		rows = append(rows, resultRow{t: val.T, v: val.V})
+	}
+
+	labelNames := make([]string, 0, len(names))
+	for name := range names {
+		labelNames = append(labelNames, name)
+	}
+	sort.Strings(labelNames)
+
+	return rows, labelNames
+}
+
+// countResult returns the number of series and samples in v, a scalar or a string is a sample
+// of no series.
+func countResult(v promql.Value) (series, samples int) {
+	switch val := v.(type) {
+	case promql.Matrix:
+		for _, s := range val {
+			samples += len(s.Points)
+		}
+		return len(val), samples
+	case promql.Vector:
+		return len(val), len(val)
+	case promql.Scalar, promql.String:
+		return 0, 1
+	}
+	return 0, 0
+}
+
+// writeTiming writes a line telling how long a query took and how much data it returned.
+func writeTiming(w io.Writer, took time.Duration, v promql.Value) {
+	series, samples := countResult(v)
+	fmt.Fprintf(w, "%d series, %d samples (%v)\n", series, samples, took.Round(time.Microsecond))
+}
+
+// writeInfo renders the info of a node as one "Key: value" line per field.
+func writeInfo(w io.Writer, info *pb.InfoResponse, now time.Time) {
+	toTime := func(t int64) string {
+		if t <= 0 || t == math.MaxInt64 {
+			return "-"
+		}
+		return time.Unix(0, t*int64(time.Millisecond)).Format("2006-01-02 15:04:05.000")
+	}
+
+	fmt.Fprintf(w, "Version: %s\n", info.Version)
+	fmt.Fprintf(w, "Shard: %s\n", info.ShardID)
+	fmt.Fprintf(w, "Role: %s\n", info.Role)
+	fmt.Fprintf(w, "Addr: %s\n", info.Addr)
+	if info.MasterAddr != "" {
+		fmt.Fprintf(w, "MasterAddr: %s\n", info.MasterAddr)
+	}
+	fmt.Fprintf(w, "Uptime: %v\n", now.Sub(time.Unix(0, info.StartTime*int64(time.Millisecond))).Round(time.Second))
+	fmt.Fprintf(w, "MinT: %s\n", toTime(info.MinT))
+	fmt.Fprintf(w, "MaxT: %s\n", toTime(info.MaxT))
+	fmt.Fprintf(w, "DiskFree: %dGB\n", info.DiskFree)
+	fmt.Fprintf(w, "Series: %d\n", info.SeriesNum)
+	fmt.Fprintf(w, "SamplesReceived: %d\n", info.SamplesReceived)
+	fmt.Fprintf(w, "SamplesSucceed: %d\n", info.SamplesSucceed)
+	fmt.Fprintf(w, "SamplesFailed: %d\n", info.SamplesFailed)
+	fmt.Fprintf(w, "SamplesOutOfOrder: %d\n", info.SamplesOutOfOrder)
+	fmt.Fprintf(w, "SamplesOutOfBounds: %d\n", info.SamplesOutOfBounds)
+	fmt.Fprintf(w, "SamplesDuplicated: %d\n", info.SamplesDuplicated)
+
+	if health := info.Health; health != nil {
+		fmt.Fprintf(w, "Health: %s\n", health.Status)
+		if health.EtcdErr != "" {
+			fmt.Fprintf(w, "EtcdErr: %s\n", health.EtcdErr)
+		}
+		fmt.Fprintf(w, "WatchAlive: %s\n", toTime(health.WatchAlive))
+		fmt.Fprintf(w, "ShardsProbed: %d\n", health.ShardsProbed)
+		if len(health.Unreachable) > 0 {
+			fmt.Fprintf(w, "Unreachable: %s\n", strings.Join(health.Unreachable, ","))
+		}
+	}
+}
+
+// writeRebalance prints the progress of a rebalance and its moves, but the ones keeping the shard groups.
+func writeRebalance(w io.Writer, rebalance *pb.RebalanceResponse) {
+	running := "no"
+	if rebalance.Running {
+		running = "yes"
+	}
+
+	fmt.Fprintf(w, "Running: %s\n", running)
+	fmt.Fprintf(w, "Moves: %d, Applied: %d, Skipped: %d\n", len(rebalance.Moves), rebalance.Applied, rebalance.Skipped)
+	if rebalance.LastErr != "" {
+		fmt.Fprintf(w, "LastErr: %s\n", rebalance.LastErr)
+	}
+
+	for _, move := range rebalance.Moves {
+		from, to := strings.Join(move.From, ","), strings.Join(move.To, ",")
+		if from != to {
+			fmt.Fprintf(w, "%s day %d: %s -> %s\n", move.Metric, move.Day, from, to)
+		}
+	}
+}
+
+// writeRoute prints the route of a series, the route key is "-" if the shard is picked by the series hash.
+func writeRoute(w io.Writer, route *pb.RouteResponse) {
+	routeKey, source := route.RouteKey, "etcd"
+	if routeKey == "" {
+		routeKey = "-"
+	}
+	if route.FromCache {
+		source = "cache"
+	}
+
+	fmt.Fprintf(w, "ShardGroup: %s\n", strings.Join(route.ShardGroup, ","))
+	fmt.Fprintf(w, "Shard: %s\n", route.ShardID)
+	fmt.Fprintf(w, "RouteKey: %s\n", routeKey)
+	fmt.Fprintf(w, "Source: %s\n", source)
+}
@@ -0,0 +1,221 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/promql"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+func TestWriteResult(t *testing.T) {
+	matrix := promql.Matrix{
+		{
+			Metric: labels.FromStrings("__name__", "up", "instance", "a", "job", "node"),
+			Points: []promql.Point{{T: 1000, V: 1}, {T: 16000, V: 0}},
+		},
+		{
+			Metric: labels.FromStrings("__name__", "up", "idc", "bj", "job", "api"),
+			Points: []promql.Point{{T: 1000, V: 1.5}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeResult(&buf, formatCSV, matrix); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "metric,idc,instance,job,timestamp,value\n" +
+		"up,,a,node,1000,1\n" +
+		"up,,a,node,16000,0\n" +
+		"up,bj,,api,1000,1.5\n"
+	if buf.String() != wantCSV {
+		t.Fatalf("want csv:\n%s\ngot:\n%s", wantCSV, buf.String())
+	}
+
+	buf.Reset()
+	if err := writeResult(&buf, formatTable, matrix); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("want a header and 3 rows, got:\n%s", buf.String())
+	}
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "metric idc instance job timestamp value" {
+		t.Fatalf("unexpected header %q", lines[0])
+	}
+	ts := time.Unix(16, 0).Format("2006-01-02 15:04:05.000")
+	if !strings.Contains(lines[2], ts) || !strings.HasSuffix(lines[2], "0") || strings.Index(lines[2], "node") != strings.Index(lines[0], "job") {
+		t.Fatalf("unexpected row %q", lines[2])
+	}
+
+	buf.Reset()
+	if err := writeResult(&buf, formatJSON, matrix); err != nil {
+		t.Fatal(err)
+	}
+	var res struct {
+		ResultType string            `json:"resultType"`
+		Result     []json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil || res.ResultType != "matrix" || len(res.Result) != 2 {
+		t.Fatalf("unexpected json %s, err: %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := writeResult(&buf, formatCSV, promql.Scalar{T: 2000, V: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "metric,timestamp,value\n,2000,3\n" {
+		t.Fatalf("unexpected csv of a scalar:\n%s", buf.String())
+	}
+
+	if err := writeResult(&buf, "xml", matrix); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
+
+func TestWriteTiming(t *testing.T) {
+	tests := []struct {
+		v    promql.Value
+		want string
+	}{
+		{
+			v: promql.Matrix{
+				{Metric: labels.FromStrings("__name__", "up", "job", "node"), Points: []promql.Point{{T: 1000, V: 1}, {T: 2000, V: 1}}},
+				{Metric: labels.FromStrings("__name__", "up", "job", "api"), Points: []promql.Point{{T: 1000, V: 1}}},
+			},
+			want: "2 series, 3 samples (12.346ms)\n",
+		},
+		{
+			v:    promql.Vector{{Point: promql.Point{T: 1000, V: 1}, Metric: labels.FromStrings("__name__", "up")}},
+			want: "1 series, 1 samples (12.346ms)\n",
+		},
+		{
+			v:    promql.Vector{},
+			want: "0 series, 0 samples (12.346ms)\n",
+		},
+		{
+			v:    promql.Scalar{T: 1000, V: 1},
+			want: "0 series, 1 samples (12.346ms)\n",
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		writeTiming(&buf, 12345678*time.Nanosecond, test.v)
+		if buf.String() != test.want {
+			t.Fatalf("want %q, got %q", test.want, buf.String())
+		}
+	}
+}
+
+func TestWriteInfo(t *testing.T) {
+	info := &pb.InfoResponse{
+		Version:         "v1.0.0",
+		ShardID:         "shard-1",
+		Role:            "slave",
+		Addr:            "10.0.0.2:8088",
+		MasterAddr:      "10.0.0.1:8088",
+		StartTime:       1000,
+		MaxT:            16000,
+		DiskFree:        442,
+		SeriesNum:       10,
+		SamplesReceived: 100,
+		SamplesSucceed:  98,
+		SamplesFailed:   2,
+	}
+
+	var buf bytes.Buffer
+	writeInfo(&buf, info, time.Unix(3662, 0))
+
+	for _, want := range []string{
+		"Version: v1.0.0\n",
+		"Role: slave\n",
+		"MasterAddr: 10.0.0.1:8088\n",
+		"Uptime: 1h1m1s\n",
+		"MinT: -\n",
+		"MaxT: " + time.Unix(16, 0).Format("2006-01-02 15:04:05.000") + "\n",
+		"DiskFree: 442GB\n",
+		"SamplesFailed: 2\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("want %q in:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	info.Role, info.MasterAddr = "master", ""
+	writeInfo(&buf, info, time.Unix(3662, 0))
+	if strings.Contains(buf.String(), "MasterAddr") {
+		t.Fatalf("unexpected master address of a master:\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "Health") {
+		t.Fatalf("unexpected health of a node not a gateway:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	info.Health = &pb.Health{Status: "shards_unreachable", WatchRunning: true, WatchAlive: 16000, ShardsProbed: 2, Unreachable: []string{"shard-1", "shard-2"}}
+	writeInfo(&buf, info, time.Unix(3662, 0))
+	for _, want := range []string{
+		"Health: shards_unreachable\n",
+		"WatchAlive: " + time.Unix(16, 0).Format("2006-01-02 15:04:05.000") + "\n",
+		"ShardsProbed: 2\n",
+		"Unreachable: shard-1,shard-2\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("want %q in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestWriteRoute(t *testing.T) {
+	var buf bytes.Buffer
+	writeRoute(&buf, &pb.RouteResponse{ShardGroup: []string{"shard-1", "shard-2"}, ShardID: "shard-2", FromCache: true})
+
+	want := "ShardGroup: shard-1,shard-2\nShard: shard-2\nRouteKey: -\nSource: cache\n"
+	if buf.String() != want {
+		t.Fatalf("want %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	writeRoute(&buf, &pb.RouteResponse{ShardGroup: []string{"shard-1"}, ShardID: "shard-1", RouteKey: "instance"})
+	if !strings.Contains(buf.String(), "RouteKey: instance\n") || !strings.Contains(buf.String(), "Source: etcd\n") {
+		t.Fatalf("unexpected route:\n%s", buf.String())
+	}
+}
+
+func TestWriteRebalance(t *testing.T) {
+	var buf bytes.Buffer
+	writeRebalance(&buf, &pb.RebalanceResponse{
+		Moves: []pb.RouteMove{
+			{Metric: "cpu", Day: 18001, From: []string{"shard-1", "shard-2"}, To: []string{"shard-1", "shard-2"}},
+			{Metric: "up", Day: 18001, From: []string{"shard-1", "shard-2"}, To: []string{"shard-3", "shard-4"}},
+		},
+		Applied: 1,
+		Skipped: 1,
+		LastErr: "etcd is down",
+	})
+
+	want := "Running: no\nMoves: 2, Applied: 1, Skipped: 1\nLastErr: etcd is down\nup day 18001: shard-1,shard-2 -> shard-3,shard-4\n"
+	if buf.String() != want {
+		t.Fatalf("want %q, got %q", want, buf.String())
+	}
+}
@@ -17,10 +17,12 @@ package main
 
 import (
 	"context"
-	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
-	"github.com/baudtime/baudtime/util"
+	"io"
 
 	"github.com/baudtime/baudtime/backend"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/tcp"
+	"github.com/baudtime/baudtime/util"
 	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/pkg/labels"
 )
@@ -36,34 +38,53 @@ func QueryableConn(c *CodedConn) backend.Queryable {
 	})
 }
 
+// seriesPerFrame is the max number of series in one frame of a streamed select response.
+const seriesPerFrame = 100
+
 // querier is an adapter to make a Client usable as a Querier.
 type querier struct {
 	ctx        context.Context
 	mint, maxt int64
 	*CodedConn
+	sets []io.Closer
 }
 
 // Select implements Querier and uses the given matchers to read series
-// sets from the Client.
-func (q *querier) Select(selectParams *backend.SelectParams, matchers ...*labels.Matcher) (backend.SeriesSet, error) {
-	queryRequest := &backendpb.SelectRequest{
-		Mint:     q.mint,
-		Maxt:     q.maxt,
-		Interval: selectParams.Step,
-		Matchers: util.MatchersToProto(matchers),
+// sets from the Client, the series are streamed frame by frame.
+func (q *querier) Select(selectParams *backend.SelectParams, matchers ...*labels.Matcher) (backend.SeriesSet, backend.Warnings, error) {
+	matchers, satisfiable := util.NormalizeMatchers(matchers)
+	if !satisfiable {
+		return backend.EmptySeriesSet(), nil, nil
 	}
 
-	err := q.WriteRaw(queryRequest)
-	if err != nil {
-		return nil, err
+	queryRequest := &backendpb.SelectRequest{
+		Mint:           q.mint,
+		Maxt:           q.maxt,
+		Interval:       selectParams.Step,
+		Matchers:       util.MatchersToProto(matchers),
+		SeriesPerFrame: seriesPerFrame,
 	}
 
-	res, err := q.ReadRaw()
+	err := q.WriteRawContext(q.ctx, queryRequest)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return backend.FromQueryResult(res.(*backendpb.SelectResponse)), nil
+	set := backend.StreamSeriesSet(func() (*backendpb.SelectResponse, error) {
+		res, err := q.ReadRawContext(q.ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		selectResponse, ok := res.(*backendpb.SelectResponse)
+		if !ok {
+			return nil, tcp.BadMsgTypeError
+		}
+		return selectResponse, nil
+	})
+	q.sets = append(q.sets, set.(io.Closer))
+
+	return set, nil, nil
 }
 
 // LabelValues implements Querier and is a noop.
@@ -71,7 +92,16 @@ func (q *querier) LabelValues(name string, matchers ...*labels.Matcher) ([]strin
 	return nil, errors.New("not supported")
 }
 
-// Close implements Querier and is a noop.
+// LabelNames implements Querier and is a noop.
+func (q *querier) LabelNames() ([]string, error) {
+	return nil, errors.New("not supported")
+}
+
+// Close implements Querier, it drops the frames of the series sets not read to the end.
 func (q *querier) Close() error {
+	for _, set := range q.sets {
+		set.Close()
+	}
+	q.sets = nil
 	return nil
 }
@@ -29,7 +29,7 @@ import (
 func main() {
 	vars.Init("backend_client")
 
-	cli := client.NewBackendClient("name", "localhost:8088", 2)
+	cli := client.NewBackendClient("name", "localhost:8088", 2, 0)
 
 	var t int64
 
@@ -53,7 +53,7 @@ func main() {
 			}
 
 			t = ts.FromTime(time.Now())
-			points := []pb.Point{{t, float64(i + j*100)}}
+			points := []pb.Point{{T: t, V: float64(i + j*100)}}
 
 			r.Series[i] = &pb.Series{
 				Labels: lbs,
@@ -51,7 +51,7 @@ func send_gateway() {
 			}
 
 			t = ts.FromTime(time.Now())
-			points := []pb.Point{{t, float64(i + j*100)}}
+			points := []pb.Point{{T: t, V: float64(i + j*100)}}
 
 			r.Series[i] = &pb.Series{
 				Labels: lbs,
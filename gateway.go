@@ -17,6 +17,7 @@ package baudtime
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/baudtime/baudtime/util"
@@ -26,9 +27,11 @@ import (
 	"time"
 
 	"github.com/baudtime/baudtime/backend"
+	"github.com/baudtime/baudtime/meta"
 	"github.com/baudtime/baudtime/msg/pb"
 	gatewaypb "github.com/baudtime/baudtime/msg/pb/gateway"
 	"github.com/baudtime/baudtime/promql"
+	ts "github.com/baudtime/baudtime/util/time"
 	"github.com/hashicorp/go-multierror"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
@@ -40,6 +43,7 @@ import (
 type queryResult struct {
 	ResultType promql.ValueType `json:"resultType"`
 	Result     promql.Value     `json:"result"`
+	Warnings   []string         `json:"warnings,omitempty"`
 }
 
 type Gateway struct {
@@ -49,7 +53,7 @@ type Gateway struct {
 }
 
 func (gateway *Gateway) InstantQuery(request *gatewaypb.InstantQueryRequest) *gatewaypb.QueryResponse {
-	result, err := gateway.instantQuery(request.Time, request.Timeout, request.Query)
+	result, err := gateway.instantQuery(request.Time, request.Timeout, request.Query, false)
 	if err != nil {
 		return &gatewaypb.QueryResponse{Status: pb.StatusCode_Failed, ErrorMsg: err.Error()}
 	}
@@ -63,7 +67,7 @@ func (gateway *Gateway) InstantQuery(request *gatewaypb.InstantQueryRequest) *ga
 }
 
 func (gateway *Gateway) RangeQuery(request *gatewaypb.RangeQueryRequest) *gatewaypb.QueryResponse {
-	result, err := gateway.rangeQuery(request.Start, request.End, request.Step, request.Timeout, request.Query)
+	result, err := gateway.rangeQuery(request.Start, request.End, request.Step, request.Timeout, request.Query, false)
 	if err != nil {
 		return &gatewaypb.QueryResponse{Status: pb.StatusCode_Failed, ErrorMsg: err.Error()}
 	}
@@ -77,11 +81,125 @@ func (gateway *Gateway) RangeQuery(request *gatewaypb.RangeQueryRequest) *gatewa
 }
 
 func (gateway *Gateway) LabelValues(request *gatewaypb.LabelValuesRequest) *pb.LabelValuesResponse {
-	values, err := gateway.labelValues(request.Name, request.Constraint, request.Timeout)
+	values, continuation, err := gateway.labelValues(request.Name, request.Constraint, request.Start, request.End, request.Timeout,
+		int(request.Limit), request.Continuation, false)
 	if err != nil {
 		return &pb.LabelValuesResponse{Status: pb.StatusCode_Failed, ErrorMsg: err.Error()}
 	}
-	return &pb.LabelValuesResponse{Status: pb.StatusCode_Succeed, Values: values}
+	return &pb.LabelValuesResponse{Status: pb.StatusCode_Succeed, Values: values, Continuation: continuation}
+}
+
+// Route tells which shard a series would be written to at the given time, without writing it.
+// The labels are sorted and hashed as Ingest does, so that the answer is the same as the one of writing.
+func (gateway *Gateway) Route(cmd *pb.Route) *pb.RouteResponse {
+	if _, err := util.SortLabels(cmd.Labels); err != nil {
+		return &pb.RouteResponse{Status: pb.StatusCode_Failed, ErrorMsg: err.Error()}
+	}
+
+	// only looked up, a route not initialized yet is left to the first write of the metric
+	route, err := meta.Router().PeekRouteByLabels(ts.Time(cmd.Time), cmd.Labels, util.HashLabels(cmd.Labels))
+	if err != nil {
+		return &pb.RouteResponse{Status: pb.StatusCode_Failed, ErrorMsg: err.Error()}
+	}
+
+	return &pb.RouteResponse{
+		Status:     pb.StatusCode_Succeed,
+		ShardGroup: route.ShardGroup,
+		ShardID:    route.ShardID,
+		RouteKey:   route.RouteKey,
+		FromCache:  route.FromCache,
+	}
+}
+
+// Health returns the health of the gateway, see meta.HealthStatus.
+func (gateway *Gateway) Health() *pb.Health {
+	health := meta.HealthStatus()
+
+	var watchAlive int64
+	if !health.WatchAlive.IsZero() {
+		watchAlive = ts.FromTime(health.WatchAlive)
+	}
+
+	return &pb.Health{
+		Status:       health.Status,
+		EtcdErr:      health.EtcdErr,
+		WatchRunning: health.WatchRunning,
+		WatchAlive:   watchAlive,
+		ShardsProbed: uint32(health.ShardsProbed),
+		Unreachable:  health.Unreachable,
+	}
+}
+
+// HttpHealth answers the health of the gateway in json for the load balancers, with the status 503 if it's unhealthy.
+func (gateway *Gateway) HttpHealth(c *fasthttp.RequestCtx) {
+	health := meta.HealthStatus()
+
+	b, err := json.Marshal(health)
+	if err != nil {
+		c.Error(err.Error(), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	c.SetContentType("application/json; charset=utf-8")
+	if !health.Healthy() {
+		c.SetStatusCode(fasthttp.StatusServiceUnavailable)
+	}
+	c.SetBody(b)
+}
+
+// ListMetrics answers the names of the metrics known to the cluster, see backend.ListMetrics.
+func (gateway *Gateway) ListMetrics(cmd *pb.ListMetrics) *pb.LabelValuesResponse {
+	metricNames, err := backend.ListMetrics(context.Background(), meta.ListMetrics, gateway.Backend, cmd.Full)
+	if err != nil {
+		return &pb.LabelValuesResponse{Status: pb.StatusCode_Failed, ErrorMsg: err.Error()}
+	}
+	return &pb.LabelValuesResponse{Status: pb.StatusCode_Succeed, Values: metricNames}
+}
+
+// Rebalance plans the routes of the metrics on the days ahead and starts putting them, or only tells the
+// moves planned if it's a dry run, or tells the progress of the last rebalance if it's asked for the status.
+func (gateway *Gateway) Rebalance(cmd *pb.Rebalance) *pb.RebalanceResponse {
+	if cmd.Status {
+		progress := meta.RebalanceStatus()
+
+		response := &pb.RebalanceResponse{
+			Status:  pb.StatusCode_Succeed,
+			Applied: uint32(progress.Applied),
+			Skipped: uint32(progress.Skipped),
+			Running: progress.Running,
+		}
+		if progress.Plan != nil {
+			response.Moves = routeMovesToProto(progress.Plan.Moves)
+		}
+		if progress.Err != nil {
+			response.LastErr = progress.Err.Error()
+		}
+		return response
+	}
+
+	plan, err := meta.PlanRebalance(cmd.Metrics, int(cmd.Days))
+	if err != nil {
+		return &pb.RebalanceResponse{Status: pb.StatusCode_Failed, ErrorMsg: err.Error()}
+	}
+	if !cmd.DryRun {
+		if err = meta.StartRebalance(plan); err != nil {
+			return &pb.RebalanceResponse{Status: pb.StatusCode_Failed, ErrorMsg: err.Error()}
+		}
+	}
+
+	return &pb.RebalanceResponse{
+		Status:  pb.StatusCode_Succeed,
+		Moves:   routeMovesToProto(plan.Moves),
+		Running: !cmd.DryRun,
+	}
+}
+
+func routeMovesToProto(moves []meta.RouteMove) []pb.RouteMove {
+	protos := make([]pb.RouteMove, len(moves))
+	for i, move := range moves {
+		protos[i] = pb.RouteMove{Metric: move.MetricName, Day: move.Day, From: move.From, To: move.To}
+	}
+	return protos
 }
 
 func (gateway *Gateway) Ingest(request *gatewaypb.AddRequest) error {
@@ -97,12 +215,21 @@ func (gateway *Gateway) Ingest(request *gatewaypb.AddRequest) error {
 		}
 	}
 
-	var hasher = util.NewHasher()
 	for _, series := range request.Series {
-		hash := hasher.Hash(series.Labels)
+		if _, er := util.SortLabels(series.Labels); er != nil {
+			err = multierror.Append(err, er)
+			continue
+		}
+		hash := util.HashLabels(series.Labels)
 
 		for _, p := range series.Points {
-			if er := appender.Add(series.Labels, p.T, p.V, hash); er != nil {
+			var er error
+			if exemplarApp, ok := appender.(backend.ExemplarAppender); ok && p.E != nil {
+				er = exemplarApp.AddWithExemplar(series.Labels, p.T, p.V, hash, p.E)
+			} else {
+				er = appender.Add(series.Labels, p.T, p.V, hash)
+			}
+			if er != nil {
 				err = multierror.Append(err, er)
 			}
 		}
@@ -117,9 +244,10 @@ func (gateway *Gateway) Ingest(request *gatewaypb.AddRequest) error {
 }
 
 type httpResponse struct {
-	Status string      `json:"status"`
-	Data   interface{} `json:"data,omitempty"`
-	Error  string      `json:"error,omitempty"`
+	Status       string      `json:"status"`
+	Data         interface{} `json:"data,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	Continuation string      `json:"continuation,omitempty"` // to get the next page of a paged query with
 }
 
 func (gateway *Gateway) HttpInstantQuery(c *fasthttp.RequestCtx) {
@@ -138,7 +266,7 @@ func (gateway *Gateway) HttpInstantQuery(c *fasthttp.RequestCtx) {
 			query = string(q)
 		}
 
-		return gateway.instantQuery(ts, timeout, query)
+		return gateway.instantQuery(ts, timeout, query, c.QueryArgs().GetBool("slave_read"))
 	})
 }
 
@@ -166,31 +294,90 @@ func (gateway *Gateway) HttpRangeQuery(c *fasthttp.RequestCtx) {
 			query = string(arg)
 		}
 
-		return gateway.rangeQuery(start, end, step, timeout, query)
+		return gateway.rangeQuery(start, end, step, timeout, query, c.QueryArgs().GetBool("slave_read"))
 	})
 }
 
 func (gateway *Gateway) HttpLabelValues(c *fasthttp.RequestCtx) {
-	exeHttpQuery(c, func() (interface{}, error) {
+	exeHttpPagedQuery(c, func() (interface{}, string, error) {
 		name, ok := c.UserValue("name").(string)
 		if !ok {
-			return nil, errors.New("label name must be provided")
+			return nil, "", errors.New("label name must be provided")
 		}
 
-		var constraint, timeout string
+		var constraint, start, end, timeout, continuation string
+		var limit int
 		if arg := c.QueryArgs().Peek("constraint"); arg != nil {
 			constraint = string(arg)
 		}
 
+		if arg := c.QueryArgs().Peek("start"); arg != nil {
+			start = string(arg)
+		}
+
+		if arg := c.QueryArgs().Peek("end"); arg != nil {
+			end = string(arg)
+		}
+
+		if arg := c.QueryArgs().Peek("timeout"); arg != nil {
+			timeout = string(arg)
+		}
+
+		if arg := c.QueryArgs().Peek("limit"); arg != nil {
+			var err error
+			if limit, err = strconv.Atoi(string(arg)); err != nil || limit < 0 {
+				return nil, "", errors.Errorf("invalid limit: %q", arg)
+			}
+		}
+
+		if arg := c.QueryArgs().Peek("continuation"); arg != nil {
+			continuation = string(arg)
+		}
+
+		return gateway.labelValues(name, constraint, start, end, timeout, limit, continuation, c.QueryArgs().GetBool("slave_read"))
+	})
+}
+
+func (gateway *Gateway) HttpLabelNames(c *fasthttp.RequestCtx) {
+	exeHttpQuery(c, func() (interface{}, error) {
+		var timeout string
 		if arg := c.QueryArgs().Peek("timeout"); arg != nil {
 			timeout = string(arg)
 		}
 
-		return gateway.labelValues(name, constraint, timeout)
+		return gateway.labelNames(timeout)
 	})
 }
 
-func (gateway *Gateway) instantQuery(t, timeout, query string) (*queryResult, error) {
+func (gateway *Gateway) HttpSeriesByHash(c *fasthttp.RequestCtx) {
+	exeHttpQuery(c, func() (interface{}, error) {
+		metric, ok := c.UserValue("metric").(string)
+		if !ok {
+			return nil, errors.New("metric name must be provided")
+		}
+		hash, ok := c.UserValue("hash").(string)
+		if !ok {
+			return nil, errors.New("series hash must be provided")
+		}
+
+		var start, end, timeout string
+		if arg := c.QueryArgs().Peek("start"); arg != nil {
+			start = string(arg)
+		}
+
+		if arg := c.QueryArgs().Peek("end"); arg != nil {
+			end = string(arg)
+		}
+
+		if arg := c.QueryArgs().Peek("timeout"); arg != nil {
+			timeout = string(arg)
+		}
+
+		return gateway.seriesByHash(metric, hash, start, end, timeout, c.QueryArgs().GetBool("slave_read"))
+	})
+}
+
+func (gateway *Gateway) instantQuery(t, timeout, query string, slaveRead bool) (*queryResult, error) {
 	span := opentracing.StartSpan("instantQuery", opentracing.Tag{"query", query})
 	defer span.Finish()
 
@@ -206,6 +393,9 @@ func (gateway *Gateway) instantQuery(t, timeout, query string) (*queryResult, er
 	}
 
 	ctx := context.WithValue(context.Background(), "span", span)
+	if slaveRead {
+		ctx = backend.WithSlaveRead(ctx)
+	}
 	if timeout != "" {
 		var cancel context.CancelFunc
 		to, err := ParseDuration(timeout)
@@ -234,10 +424,11 @@ func (gateway *Gateway) instantQuery(t, timeout, query string) (*queryResult, er
 	return &queryResult{
 		ResultType: res.Value.Type(),
 		Result:     res.Value,
+		Warnings:   warningStrings(res.Warnings),
 	}, nil
 }
 
-func (gateway *Gateway) rangeQuery(startT, endT, step, timeout, query string) (*queryResult, error) {
+func (gateway *Gateway) rangeQuery(startT, endT, step, timeout, query string, slaveRead bool) (*queryResult, error) {
 	span := opentracing.StartSpan("rangeQuery", opentracing.Tag{"query", query})
 	defer span.Finish()
 
@@ -282,6 +473,9 @@ func (gateway *Gateway) rangeQuery(startT, endT, step, timeout, query string) (*
 	}
 
 	ctx := context.WithValue(context.Background(), "span", span)
+	if slaveRead {
+		ctx = backend.WithSlaveRead(ctx)
+	}
 	if timeout != "" {
 		var cancel context.CancelFunc
 		to, err := ParseDuration(timeout)
@@ -310,15 +504,19 @@ func (gateway *Gateway) rangeQuery(startT, endT, step, timeout, query string) (*
 	return &queryResult{
 		ResultType: res.Value.Type(),
 		Result:     res.Value,
+		Warnings:   warningStrings(res.Warnings),
 	}, nil
 }
 
-func (gateway *Gateway) labelValues(name, constraint, timeout string) ([]string, error) {
+// labelValues returns the values of the label, a page of them if limit is not 0, along with the continuation
+// to get the next page with. The continuation is the last value of the page, encoded in base64.
+// start and end only narrow the shards asked, the values of all the series of those shards are returned.
+func (gateway *Gateway) labelValues(name, constraint, start, end, timeout string, limit int, continuation string, slaveRead bool) ([]string, string, error) {
 	span := opentracing.StartSpan("labelValues", opentracing.Tag{"name", name}, opentracing.Tag{"constraint", constraint})
 	defer span.Finish()
 
 	if !model.LabelNameRE.MatchString(name) {
-		return nil, errors.Errorf("invalid label name: %q", name)
+		return nil, "", errors.Errorf("invalid label name: %q", name)
 	}
 
 	var matchers []*lb.Matcher
@@ -326,11 +524,112 @@ func (gateway *Gateway) labelValues(name, constraint, timeout string) ([]string,
 		var err error
 		matchers, err = promql.ParseMetricSelector(constraint)
 		if err != nil {
-			return nil, err
+			return nil, "", err
+		}
+	}
+
+	ctx := context.WithValue(context.Background(), "span", span)
+	if slaveRead {
+		ctx = backend.WithSlaveRead(ctx)
+	}
+	if timeout != "" {
+		var cancel context.CancelFunc
+		to, err := ParseDuration(timeout)
+		if err != nil {
+			return nil, "", err
+		}
+
+		ctx, cancel = context.WithTimeout(ctx, to)
+		defer cancel()
+	}
+
+	mint, maxt := int64(math.MinInt64), int64(math.MaxInt64)
+	if start != "" {
+		t, err := ParseTime(start)
+		if err != nil {
+			return nil, "", err
+		}
+		mint = ts.FromTime(t)
+	}
+	if end != "" {
+		t, err := ParseTime(end)
+		if err != nil {
+			return nil, "", err
+		}
+		maxt = ts.FromTime(t)
+	}
+	if mint > maxt {
+		return nil, "", errors.New("end time must not be before start time")
+	}
+
+	if limit > 0 || continuation != "" {
+		after, err := base64.RawURLEncoding.DecodeString(continuation)
+		if err != nil {
+			return nil, "", errors.Errorf("invalid continuation: %q", continuation)
+		}
+		page := backend.LabelValuesPage{After: string(after)}
+		if limit > 0 {
+			page.Limit = limit + 1 // one more tells whether there's a next page
 		}
+		ctx = backend.WithLabelValuesPage(ctx, page)
+	}
+
+	q, err := gateway.Backend.Querier(ctx, mint, maxt)
+	if err != nil {
+		return nil, "", err
+	}
+	defer q.Close()
+
+	vals, err := q.LabelValues(name, matchers...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if limit > 0 && len(vals) > limit {
+		vals = vals[:limit]
+		return vals, base64.RawURLEncoding.EncodeToString([]byte(vals[limit-1])), nil
+	}
+	return vals, "", nil
+}
+
+// seriesByHash returns the samples in [start, end] of the series of metric whose hash, in decimal or in hex
+// prefixed with 0x, is util.HashLabels of its labels sorted by name, as a matrix of one series at most.
+func (gateway *Gateway) seriesByHash(metric, hash, start, end, timeout string, slaveRead bool) (promql.Matrix, error) {
+	span := opentracing.StartSpan("seriesByHash", opentracing.Tag{"metric", metric}, opentracing.Tag{"hash", hash})
+	defer span.Finish()
+
+	if !model.IsValidMetricName(model.LabelValue(metric)) {
+		return nil, errors.Errorf("invalid metric name: %q", metric)
+	}
+	h, err := strconv.ParseUint(hash, 0, 64)
+	if err != nil || h == 0 {
+		return nil, errors.Errorf("invalid series hash: %q", hash)
+	}
+
+	if start == "" {
+		return nil, errors.New("start time must be provided")
+	}
+	startT, err := ParseTime(start)
+	if err != nil {
+		return nil, err
+	}
+
+	if end == "" {
+		return nil, errors.New("end time must be provided")
+	}
+	endT, err := ParseTime(end)
+	if err != nil {
+		return nil, err
+	}
+
+	if endT.Before(startT) {
+		return nil, errors.New("end time must not be before start time")
 	}
 
 	ctx := context.WithValue(context.Background(), "span", span)
+	if slaveRead {
+		ctx = backend.WithSlaveRead(ctx)
+	}
 	if timeout != "" {
 		var cancel context.CancelFunc
 		to, err := ParseDuration(timeout)
@@ -342,32 +641,97 @@ func (gateway *Gateway) labelValues(name, constraint, timeout string) ([]string,
 		defer cancel()
 	}
 
-	q, err := gateway.Backend.Querier(ctx, math.MinInt64, math.MaxInt64)
+	q, err := gateway.Backend.Querier(ctx, ts.FromTime(startT), ts.FromTime(endT))
 	if err != nil {
 		return nil, err
 	}
 	defer q.Close()
 
-	vals, err := q.LabelValues(name, matchers...)
+	hq, ok := q.(backend.HashQuerier)
+	if !ok {
+		return nil, errors.New("select by hash is not supported by the backend")
+	}
+	set, err := hq.SelectByHash(metric, h)
 	if err != nil {
 		return nil, err
 	}
 
-	return vals, nil
+	matrix := promql.Matrix{}
+	for set.Next() {
+		series := set.At()
+
+		var points []promql.Point
+		it := series.Iterator()
+		for it.Next() {
+			t, v := it.At()
+			points = append(points, promql.Point{T: t, V: v})
+		}
+		if err = it.Err(); err != nil {
+			return nil, err
+		}
+		matrix = append(matrix, promql.Series{Metric: series.Labels(), Points: points})
+	}
+	return matrix, set.Err()
+}
+
+func (gateway *Gateway) labelNames(timeout string) ([]string, error) {
+	span := opentracing.StartSpan("labelNames")
+	defer span.Finish()
+
+	ctx := context.WithValue(context.Background(), "span", span)
+	if timeout != "" {
+		var cancel context.CancelFunc
+		to, err := ParseDuration(timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel = context.WithTimeout(ctx, to)
+		defer cancel()
+	}
+
+	q, err := gateway.Backend.Querier(ctx, math.MinInt64, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+
+	return q.LabelNames()
+}
+
+func warningStrings(warnings backend.Warnings) []string {
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	strs := make([]string, 0, len(warnings))
+	for _, w := range warnings {
+		strs = append(strs, w.Error())
+	}
+	return strs
 }
 
 func exeHttpQuery(c *fasthttp.RequestCtx, f func() (interface{}, error)) {
+	exeHttpPagedQuery(c, func() (interface{}, string, error) {
+		result, err := f()
+		return result, "", err
+	})
+}
+
+// exeHttpPagedQuery is exeHttpQuery, besides f returns the continuation to get the next page with.
+func exeHttpPagedQuery(c *fasthttp.RequestCtx, f func() (interface{}, string, error)) {
 	c.SetContentType("application/json; charset=utf-8")
 
-	result, err := f()
+	result, continuation, err := f()
 	if err != nil {
 		c.Error(err.Error(), fasthttp.StatusInternalServerError)
 		return
 	}
 
 	queryRes, err := json.Marshal(&httpResponse{
-		Status: "success",
-		Data:   result,
+		Status:       "success",
+		Data:         result,
+		Continuation: continuation,
 	})
 	if err != nil {
 		c.Error(err.Error(), fasthttp.StatusInternalServerError)
@@ -14,14 +14,17 @@ require (
 	github.com/fsnotify/fsnotify v1.4.7
 	github.com/go-kit/kit v0.9.0
 	github.com/gogo/protobuf v1.2.1
+	github.com/golang/snappy v0.0.1
 	github.com/gorilla/websocket v1.4.0 // indirect
 	github.com/grpc-ecosystem/go-grpc-middleware v1.0.0 // indirect
 	github.com/grpc-ecosystem/go-grpc-prometheus v1.2.0 // indirect
 	github.com/hashicorp/go-multierror v1.0.0
 	github.com/jonboulle/clockwork v0.1.0 // indirect
+	github.com/klauspost/compress v1.10.3
 	github.com/opentracing/opentracing-go v1.1.0
 	github.com/peterh/liner v1.1.0
 	github.com/pkg/errors v0.8.1
+	github.com/prometheus/client_golang v1.0.0
 	github.com/prometheus/common v0.6.0
 	github.com/prometheus/prometheus v2.10.0+incompatible
 	github.com/prometheus/tsdb v0.10.0
@@ -65,50 +65,119 @@ func (r *ClientRefer) UnRef() {
 	}
 }
 
-type lease struct {
-	day     uint64
-	leaseID clientv3.LeaseID
-	sync.Mutex
-}
-
 var (
 	ErrKeyNotFound = errors.New("key not found in etcd")
 	clientRef      ClientRefer
-	leases         [366]lease
+	routeLeases    = &leaseManager{
+		leases: make(map[uint64]*dayLease),
+		lessor: func() (clientv3.Lease, func(), error) {
+			cli, err := clientRef.Ref()
+			if err != nil {
+				return nil, nil, err
+			}
+			return cli, clientRef.UnRef, nil
+		},
+	}
 )
 
-func getEtcdLease(day uint64) (clientv3.LeaseID, error) {
-	idx := day % 366
-	l := leases[idx]
+// leaseActiveDays is how many days back from the latest one asked for a lease is kept alive, the days
+// ahead of today, e.g. routed by a rebalance, don't count as the latest one till they come.
+const leaseActiveDays = 2
+
+// leaseManager hands the same lease to all the routes put of a day, and keeps the leases of the
+// active days alive, so that the routes expire RouteInfoTTL after their day goes by rather than
+// after they are put.
+type leaseManager struct {
+	sync.Mutex
+	leases map[uint64]*dayLease
+	latest uint64
+	lessor func() (clientv3.Lease, func(), error) // returns the lease api and the func releasing it
+}
 
-	l.Lock()
-	defer l.Unlock()
+type dayLease struct {
+	id     clientv3.LeaseID
+	cancel context.CancelFunc
+}
 
-	if l.leaseID != clientv3.NoLease && l.day == day {
-		return l.leaseID, nil
+// get returns the lease of the day, it's granted with ttl in seconds and kept alive if the day has none.
+func (lm *leaseManager) get(day, today uint64, ttl int64) (clientv3.LeaseID, error) {
+	lm.Lock()
+	defer lm.Unlock()
+
+	if latest := minDay(day, today); latest > lm.latest {
+		lm.latest = latest
+		for d, l := range lm.leases {
+			if d+leaseActiveDays <= latest {
+				l.cancel() // not kept alive, so it expires after ttl
+				delete(lm.leases, d)
+			}
+		}
 	}
 
-	ttl := int64(vars.Cfg.Gateway.Route.RouteInfoTTL) / 1e9
+	if l, found := lm.leases[day]; found {
+		return l.id, nil
+	}
 
-	cli, err := clientRef.Ref()
+	lessor, release, err := lm.lessor()
 	if err != nil {
-		return -1, err
+		return clientv3.NoLease, err
 	}
-	defer clientRef.UnRef()
 
-	if l.leaseID != clientv3.NoLease {
-		cli.Revoke(context.Background(), l.leaseID)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(vars.Cfg.EtcdCommon.RWTimeout))
+	leaseResp, err := lessor.Grant(ctx, ttl)
+	cancel()
+	if err != nil {
+		release()
+		return clientv3.NoLease, err
 	}
 
-	leaseResp, err := cli.Grant(context.Background(), ttl)
+	ctx, cancel = context.WithCancel(context.Background())
+	keepAlive, err := lessor.KeepAlive(ctx, leaseResp.ID)
 	if err != nil {
-		return -1, err
+		cancel()
+		release()
+		return clientv3.NoLease, err
 	}
 
-	l.day = day
-	l.leaseID = leaseResp.ID
+	l := &dayLease{id: leaseResp.ID, cancel: cancel}
+	lm.leases[day] = l
 
-	return l.leaseID, nil
+	go lm.keepAlive(day, l, keepAlive, release)
+
+	return l.id, nil
+}
+
+// keepAlive eats the keepalive responses of the lease of the day, once they stop, the lease is
+// dropped and the next put of the day gets a new one, unless it's retired as the day goes by.
+func (lm *leaseManager) keepAlive(day uint64, l *dayLease, keepAlive <-chan *clientv3.LeaseKeepAliveResponse, release func()) {
+	defer release()
+
+	for range keepAlive {
+		// just eat messages
+	}
+
+	lm.Lock()
+	lost := lm.leases[day] == l
+	if lost {
+		delete(lm.leases, day)
+	}
+	lm.Unlock()
+
+	if lost {
+		l.cancel()
+		level.Warn(vars.Logger).Log("msg", "keepalive of route lease lost, another one is granted on the next put", "day", day, "lease", l.id)
+	}
+}
+
+func minDay(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func getEtcdLease(d uint64) (clientv3.LeaseID, error) {
+	return routeLeases.get(d, day(time.Now()), int64(vars.Cfg.Gateway.Route.RouteInfoTTL)/1e9)
 }
 
 func exist(k string) (bool, error) {
@@ -200,6 +269,29 @@ func etcdGetWithPrefix(prefix string) (*clientv3.GetResponse, error) {
 	return resp, er
 }
 
+// etcdGetPage gets the keys in [from, end) without their values, at most limit of them in the order of the keys.
+// resp.More tells whether there are more keys in the range after the page.
+func etcdGetPage(from, end string, limit int64) (*clientv3.GetResponse, error) {
+	var resp *clientv3.GetResponse
+	er := redo.Retry(time.Duration(vars.Cfg.EtcdCommon.RetryInterval), vars.Cfg.EtcdCommon.RetryNum, func() (bool, error) {
+		cli, err := clientRef.Ref()
+		if err != nil {
+			return true, err
+		}
+		defer clientRef.UnRef()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(vars.Cfg.EtcdCommon.RWTimeout))
+
+		resp, err = cli.Get(ctx, from, clientv3.WithRange(end), clientv3.WithLimit(limit), clientv3.WithKeysOnly())
+		cancel()
+		if err != nil {
+			return true, err
+		}
+		return false, nil
+	})
+	return resp, er
+}
+
 func etcdPut(k string, v interface{}, leaseID clientv3.LeaseID) error {
 	return redo.Retry(time.Duration(vars.Cfg.EtcdCommon.RetryInterval), vars.Cfg.EtcdCommon.RetryNum, func() (bool, error) {
 		var (
@@ -238,6 +330,39 @@ func etcdPut(k string, v interface{}, leaseID clientv3.LeaseID) error {
 	})
 }
 
+// etcdPutIfAbsent puts v to k unless k exists, it tells whether it's put.
+func etcdPutIfAbsent(k string, v interface{}, leaseID clientv3.LeaseID) (bool, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	var put bool
+	err = redo.Retry(time.Duration(vars.Cfg.EtcdCommon.RetryInterval), vars.Cfg.EtcdCommon.RetryNum, func() (bool, error) {
+		cli, err := clientRef.Ref()
+		if err != nil {
+			return true, err
+		}
+		defer clientRef.UnRef()
+
+		var opts []clientv3.OpOption
+		if leaseID != clientv3.NoLease {
+			opts = append(opts, clientv3.WithLease(leaseID))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(vars.Cfg.EtcdCommon.RWTimeout))
+		resp, err := cli.Txn(ctx).If(clientv3.Compare(clientv3.CreateRevision(k), "=", 0)).Then(clientv3.OpPut(k, string(b), opts...)).Commit()
+		cancel()
+		if err != nil {
+			return true, err
+		}
+
+		put = resp.Succeeded
+		return false, nil
+	})
+	return put, err
+}
+
 func mutexRun(lock string, f func(session *concurrency.Session) error) error {
 	cli, err := clientRef.Ref()
 	if err != nil {
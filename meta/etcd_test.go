@@ -0,0 +1,335 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/vars"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/go-kit/kit/log"
+)
+
+// fakeLessor grants leases with increasing ids, the keepalive of a lease goes on until it's
+// cancelled or lost.
+type fakeLessor struct {
+	clientv3.Lease
+	mtx        sync.Mutex
+	lastID     clientv3.LeaseID
+	keepAlives map[clientv3.LeaseID]chan *clientv3.LeaseKeepAliveResponse
+	closed     map[clientv3.LeaseID]*sync.Once
+}
+
+func (f *fakeLessor) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.lastID++
+	return &clientv3.LeaseGrantResponse{ID: f.lastID, TTL: ttl}, nil
+}
+
+func (f *fakeLessor) KeepAlive(ctx context.Context, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	ch := make(chan *clientv3.LeaseKeepAliveResponse)
+	f.keepAlives[id] = ch
+	f.closed[id] = new(sync.Once)
+
+	go func() {
+		<-ctx.Done()
+		f.lose(id)
+	}()
+	return ch, nil
+}
+
+func (f *fakeLessor) lose(id clientv3.LeaseID) {
+	f.mtx.Lock()
+	ch, once := f.keepAlives[id], f.closed[id]
+	f.mtx.Unlock()
+
+	once.Do(func() {
+		close(ch)
+	})
+}
+
+func TestLeaseManager(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	lessor := &fakeLessor{
+		keepAlives: make(map[clientv3.LeaseID]chan *clientv3.LeaseKeepAliveResponse),
+		closed:     make(map[clientv3.LeaseID]*sync.Once),
+	}
+
+	var mtx sync.Mutex
+	refs := 0
+	lm := &leaseManager{
+		leases: make(map[uint64]*dayLease),
+		lessor: func() (clientv3.Lease, func(), error) {
+			mtx.Lock()
+			refs++
+			mtx.Unlock()
+			return lessor, func() {
+				mtx.Lock()
+				refs--
+				mtx.Unlock()
+			}, nil
+		},
+	}
+
+	waitDropped := func(day uint64) {
+		for i := 0; i < 100; i++ {
+			lm.Lock()
+			_, found := lm.leases[day]
+			lm.Unlock()
+			if !found {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("lease of day %d not dropped", day)
+	}
+
+	const day = uint64(18000)
+
+	id, err := lm.get(day, day, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := lm.get(day, day, 60); again != id {
+		t.Fatalf("want the same lease %d of the day, got %d", id, again)
+	}
+
+	// keepalive lost, another lease is granted and kept alive
+	lessor.lose(id)
+	waitDropped(day)
+
+	reacquired, err := lm.get(day, day, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reacquired == id {
+		t.Fatalf("want another lease after the keepalive is lost, got %d again", id)
+	}
+	if again, _ := lm.get(day, day, 60); again != reacquired {
+		t.Fatalf("want the reacquired lease %d, got %d", reacquired, again)
+	}
+
+	// the lease of a day not active is retired
+	if _, err = lm.get(day+leaseActiveDays, day+leaseActiveDays, 60); err != nil {
+		t.Fatal(err)
+	}
+	waitDropped(day)
+
+	// the leases of the days ahead, e.g. routed by a rebalance, don't retire the lease of today
+	today := day + leaseActiveDays
+	for ahead := today + 1; ahead <= today+leaseActiveDays+3; ahead++ {
+		if _, err = lm.get(ahead, today, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lm.Lock()
+	_, found := lm.leases[today]
+	lm.Unlock()
+	if !found {
+		t.Fatalf("want the lease of today kept alive after leases of the days ahead are granted")
+	}
+
+	lm.Lock()
+	for _, l := range lm.leases {
+		l.cancel()
+	}
+	lm.Unlock()
+
+	for i := 0; i < 100; i++ {
+		mtx.Lock()
+		n := refs
+		mtx.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the lease api is not released after the keepalives stop")
+}
+
+// fakeKV keeps the keys in memory, it serves gets of a key or a range of keys, limited as etcd does.
+type fakeKV struct {
+	clientv3.KV
+	mtx  sync.Mutex
+	kvs  map[string]string
+	gets int
+}
+
+func (kv *fakeKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	kv.mtx.Lock()
+	defer kv.mtx.Unlock()
+
+	kv.kvs[key] = val
+	return &clientv3.PutResponse{}, nil
+}
+
+func (kv *fakeKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	kv.mtx.Lock()
+	defer kv.mtx.Unlock()
+
+	kv.gets++
+
+	op := clientv3.OpGet(key, opts...)
+	end := string(op.RangeBytes())
+	limit := reflect.ValueOf(op).FieldByName("limit").Int() // not exported by clientv3.Op
+
+	resp := &clientv3.GetResponse{}
+	for k, v := range kv.kvs {
+		if k == key || (end != "" && k >= key && k < end) {
+			resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(v)})
+		}
+	}
+	sort.Slice(resp.Kvs, func(i, j int) bool {
+		return string(resp.Kvs[i].Key) < string(resp.Kvs[j].Key)
+	})
+	resp.Count = int64(len(resp.Kvs))
+	if limit > 0 && resp.Count > limit {
+		resp.Kvs, resp.More = resp.Kvs[:limit], true
+	}
+	return resp, nil
+}
+
+func TestNamespace_Isolation(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	kv := &fakeKV{kvs: make(map[string]string)}
+	clientRef.cli, clientRef.ref = &clientv3.Client{KV: kv}, 1
+	nameSpace := vars.Cfg.NameSpace
+	defer func() {
+		clientRef.cli, clientRef.ref = nil, 0
+		vars.Cfg.NameSpace = nameSpace
+	}()
+
+	clusters := map[string][]Node{
+		"east": {{ShardID: "shard-1", IP: "10.0.0.1", Port: "8088"}, {ShardID: "shard-2", IP: "10.0.0.2", Port: "8088"}},
+		"eas":  {{ShardID: "shard-1", IP: "10.0.1.1", Port: "8088"}},
+		"west": {{ShardID: "shard-1", IP: "10.0.2.1", Port: "8088"}},
+	}
+
+	// registered as the heartbeats do
+	for ns, nodes := range clusters {
+		vars.Cfg.NameSpace = ns
+		for _, node := range nodes {
+			b, _ := json.Marshal(node)
+			kv.Put(context.Background(), nodePrefix()+node.Addr(), string(b))
+		}
+	}
+
+	for ns, nodes := range clusters {
+		vars.Cfg.NameSpace = ns
+
+		got, err := etcdStore{}.GetNodes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(nodes) {
+			t.Fatalf("namespace %s: want %d nodes, got %v", ns, len(nodes), got)
+		}
+		for _, node := range got {
+			if exists, _ := (etcdStore{}).NodeExists(node.Addr()); !exists {
+				t.Fatalf("namespace %s: node %s not found", ns, node.Addr())
+			}
+		}
+		for other, nodes := range clusters {
+			if other == ns {
+				continue
+			}
+			for _, node := range nodes {
+				if exists, _ := (etcdStore{}).NodeExists(node.Addr()); exists {
+					t.Fatalf("namespace %s sees node %s of namespace %s", ns, node.Addr(), other)
+				}
+			}
+		}
+	}
+}
+
+func TestEtcdStore_GetRoutedMetricsPaged(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	kv := &fakeKV{kvs: make(map[string]string)}
+	clientRef.cli, clientRef.ref = &clientv3.Client{KV: kv}, 1
+	pageSize := routedMetricsPageSize
+	defer func() {
+		clientRef.cli, clientRef.ref = nil, 0
+		routedMetricsPageSize = pageSize
+	}()
+
+	// 500 metrics routed on 10 days, along with a partition, and names sorting around the '/' of the keys
+	var want []string
+	for i := 0; i < 500; i++ {
+		want = append(want, fmt.Sprintf("metric_%03d", i))
+	}
+	want = append(want, "up", "up.1", "up0", "up_time")
+	for _, metricName := range append(want, partitionRouteName("tenant", "a")) {
+		for day := 0; day < 10; day++ {
+			kv.Put(context.Background(), fmt.Sprintf("%s%s/%d", routeInfoPrefix(), metricName, day), `["shard-1"]`)
+		}
+	}
+	kv.Put(context.Background(), sGrpRoutePrefix()+"up", "job")
+	sort.Strings(want)
+
+	routedMetricsPageSize = 7
+	defer SetStore(SetStore(etcdStore{}))
+
+	got, err := ListMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %d metrics %v, got %d %v", len(want), want, len(got), got)
+	}
+	// a page per route as the rest of its days are skipped, and the last one empty, rather than 5050/7 pages
+	if kv.gets != 506 {
+		t.Fatalf("want 506 pages, got %d", kv.gets)
+	}
+}
+
+func TestEtcdStore_GetDraining(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	kv := &fakeKV{kvs: make(map[string]string)}
+	clientRef.cli, clientRef.ref = &clientv3.Client{KV: kv}, 1
+	defer func() {
+		clientRef.cli, clientRef.ref = nil, 0
+	}()
+
+	kv.Put(context.Background(), drainPrefix()+"10.0.0.1:8088", "true")
+	kv.Put(context.Background(), drainPrefix()+"10.0.0.2:8088", "false")
+
+	for addr, want := range map[string]bool{"10.0.0.1:8088": true, "10.0.0.2:8088": false, "10.0.0.3:8088": false} {
+		draining, err := etcdStore{}.GetDraining(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if draining != want {
+			t.Fatalf("%s: want draining %v, got %v", addr, want, draining)
+		}
+	}
+}
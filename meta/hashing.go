@@ -0,0 +1,170 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/baudtime/baudtime/vars"
+	"github.com/cespare/xxhash"
+)
+
+// Route modes, how metrics are assigned to shard groups and series to the shards of a group.
+const (
+	// RouteModeModulo takes the first masters as the shard group and picks a shard by hash modulo
+	// the group size, adding a shard moves most of the series.
+	RouteModeModulo = "modulo"
+	// RouteModeRendezvous ranks shards by rendezvous (highest random weight) hashing, adding
+	// a shard only moves the series it wins.
+	RouteModeRendezvous = "rendezvous"
+)
+
+func routeMode() string {
+	if vars.Cfg.Gateway != nil && vars.Cfg.Gateway.Route.Mode == RouteModeRendezvous {
+		return RouteModeRendezvous
+	}
+	return RouteModeModulo
+}
+
+// pickShard returns the shard in the group which hash h is routed to.
+func pickShard(shardGroup []string, h uint64) string {
+	if routeMode() == RouteModeRendezvous {
+		return rendezvousPick(shardGroup, h)
+	}
+	return shardGroup[h%uint64(len(shardGroup))]
+}
+
+// pickShards returns the shards of a group the values of its route key are picked to.
+func pickShards(shardGroup, values []string) []string {
+	ids := make([]string, 0, len(values))
+	for _, value := range values {
+		id := pickShard(shardGroup, xxhash.Sum64String(value))
+		if !contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// pickShardGroup returns n shards of shardIDs as the shard group of the metric.
+func pickShardGroup(shardIDs []string, metricName string, n int) []string {
+	if n > len(shardIDs) {
+		n = len(shardIDs)
+	}
+
+	if routeMode() != RouteModeRendezvous {
+		return append([]string(nil), shardIDs[:n]...)
+	}
+
+	h := xxhash.Sum64String(metricName)
+	ranked := append([]string(nil), shardIDs...)
+	sort.Slice(ranked, func(i, j int) bool {
+		wi, wj := rendezvousWeight(h, ranked[i]), rendezvousWeight(h, ranked[j])
+		if wi != wj {
+			return wi > wj
+		}
+		return ranked[i] < ranked[j]
+	})
+	return ranked[:n]
+}
+
+// pickWeightedShardGroup returns n shards of the masters as the shard group of the metric, ranked
+// by weighted rendezvous hashing, so that the metrics are spread over the shards in proportion
+// to their weights and free disks. Shards whose masters are on a host already in the group are
+// only picked if the group can't be filled up otherwise. As the free disks change, another gateway may pick
+// another group for the metric at the same time, the one initialized first is taken by all of them.
+func pickWeightedShardGroup(masters []Node, metricName string, n int) []string {
+	if n > len(masters) {
+		n = len(masters)
+	}
+
+	h := xxhash.Sum64String(metricName)
+
+	type ranked struct {
+		master *Node
+		score  float64
+	}
+	ranks := make([]ranked, len(masters))
+	for i := range masters {
+		ranks[i] = ranked{master: &masters[i], score: weightedScore(h, &masters[i])}
+	}
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i].score != ranks[j].score {
+			return ranks[i].score > ranks[j].score
+		}
+		return ranks[i].master.ShardID < ranks[j].master.ShardID
+	})
+
+	shardGroup := make([]string, 0, n)
+	hosts := make(map[string]bool, n)
+	var sameHost []string
+
+	for _, r := range ranks {
+		if len(shardGroup) == n {
+			break
+		}
+		if hosts[r.master.IP] {
+			sameHost = append(sameHost, r.master.ShardID)
+			continue
+		}
+		hosts[r.master.IP] = true
+		shardGroup = append(shardGroup, r.master.ShardID)
+	}
+
+	return append(shardGroup, sameHost[:n-len(shardGroup)]...)
+}
+
+// weightedScore is the weighted rendezvous score of the master for the hash h of a metric,
+// the weight is the one of the node scaled by the free fraction of its disk.
+func weightedScore(h uint64, master *Node) float64 {
+	weight := float64(master.Weight)
+	if weight <= 0 {
+		weight = 1
+	}
+	if master.DiskTotal > 0 {
+		// a full disk still gets a few, rather than none, when all are full
+		weight *= math.Max(float64(master.DiskFree)/float64(master.DiskTotal), 0.01)
+	}
+
+	// uniform in (0, 1)
+	u := (float64(rendezvousWeight(h, master.ShardID)>>11) + 0.5) / (1 << 53)
+	return -weight / math.Log(u)
+}
+
+func rendezvousPick(shardIDs []string, h uint64) string {
+	var (
+		chosen string
+		max    uint64
+	)
+	for i, shardID := range shardIDs {
+		if w := rendezvousWeight(h, shardID); i == 0 || w > max || (w == max && shardID < chosen) {
+			chosen, max = shardID, w
+		}
+	}
+	return chosen
+}
+
+func rendezvousWeight(h uint64, shardID string) uint64 {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], h)
+
+	d := xxhash.New()
+	d.Write(b[:])
+	d.Write([]byte(shardID))
+	return d.Sum64()
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/cespare/xxhash"
+)
+
+func shardIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = "shard-" + strconv.Itoa(i)
+	}
+	return ids
+}
+
+// TestRouteMode_Churn measures the fraction of series and metrics reassigned when one shard is added.
+func TestRouteMode_Churn(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	const (
+		shardNum  = 10
+		seriesNum = 10000
+		groupCap  = 3
+	)
+
+	churn := make(map[string][2]float64)
+	for _, mode := range []string{RouteModeModulo, RouteModeRendezvous} {
+		vars.Cfg.Gateway = &vars.GatewayConfig{Route: vars.RouteConfig{ShardGroupCap: groupCap, Mode: mode}}
+		r := &router{meta: &meta{routeInfos: new(sync.Map)}}
+		now := time.Now()
+
+		// series of a metric whose shard group is all the shards
+		seriesMoved := 0
+		for i := 0; i < seriesNum; i++ {
+			lbls := []pb.Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: strconv.Itoa(i)}}
+			h := xxhash.Sum64String(lbls[1].Value)
+
+			r.meta.getRouteInfoFromCache("up").Put(day(now), shardIDs(shardNum))
+			before, err := r.GetShardIDByLabels(now, lbls, h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.meta.getRouteInfoFromCache("up").Put(day(now), shardIDs(shardNum+1))
+			after, err := r.GetShardIDByLabels(now, lbls, h)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if before != after {
+				seriesMoved++
+			}
+		}
+
+		// shard groups of metrics picked out of the masters, with modulo it depends on where
+		// the new master lands in the list, here it's the last one.
+		groupsMoved := 0
+		for i := 0; i < seriesNum; i++ {
+			metricName := "metric_" + strconv.Itoa(i)
+			before := make(map[string]bool)
+			for _, id := range pickShardGroup(shardIDs(shardNum), metricName, groupCap) {
+				before[id] = true
+			}
+			for _, id := range pickShardGroup(shardIDs(shardNum+1), metricName, groupCap) {
+				if !before[id] {
+					groupsMoved++
+					break
+				}
+			}
+		}
+
+		churn[mode] = [2]float64{float64(seriesMoved) / seriesNum, float64(groupsMoved) / seriesNum}
+		t.Logf("%s: %.1f%% series moved, %.1f%% shard groups changed", mode, churn[mode][0]*100, churn[mode][1]*100)
+	}
+
+	// ideally 1/(shardNum+1) of the series move to the new shard
+	if got := churn[RouteModeRendezvous][0]; got > 2.0/(shardNum+1) {
+		t.Fatalf("rendezvous moved %.1f%% series, want about %.1f%%", got*100, 100.0/(shardNum+1))
+	}
+	if churn[RouteModeRendezvous][0] >= churn[RouteModeModulo][0] {
+		t.Fatalf("rendezvous should move fewer series than modulo")
+	}
+	// a group changes only if the new shard ranks in its top groupCap
+	if got := churn[RouteModeRendezvous][1]; got > 2.0*groupCap/(shardNum+1) {
+		t.Fatalf("rendezvous changed %.1f%% shard groups", got*100)
+	}
+}
+
+func TestPickWeightedShardGroup(t *testing.T) {
+	const metricNum = 20000
+
+	distribution := func(masters []Node) map[string]float64 {
+		counts := make(map[string]float64)
+		for i := 0; i < metricNum; i++ {
+			for _, id := range pickWeightedShardGroup(masters, "metric_"+strconv.Itoa(i), 1) {
+				counts[id]++
+			}
+		}
+		for id := range counts {
+			counts[id] /= metricNum
+		}
+		return counts
+	}
+
+	tests := []struct {
+		masters []Node
+		want    map[string]float64
+	}{
+		{
+			masters: []Node{
+				{ShardID: "shard-1", IP: "10.0.0.1"},
+				{ShardID: "shard-2", IP: "10.0.0.2", Weight: 1},
+				{ShardID: "shard-3", IP: "10.0.0.3", Weight: 2},
+				{ShardID: "shard-4", IP: "10.0.0.4", Weight: 4},
+			},
+			want: map[string]float64{"shard-1": 0.125, "shard-2": 0.125, "shard-3": 0.25, "shard-4": 0.5},
+		},
+		{
+			masters: []Node{
+				{ShardID: "shard-1", IP: "10.0.0.1", DiskFree: 90, DiskTotal: 100},
+				{ShardID: "shard-2", IP: "10.0.0.2", DiskFree: 30, DiskTotal: 100},
+			},
+			want: map[string]float64{"shard-1": 0.75, "shard-2": 0.25},
+		},
+	}
+
+	for i, test := range tests {
+		got := distribution(test.masters)
+		for id, want := range test.want {
+			if got[id] < want-0.02 || got[id] > want+0.02 {
+				t.Fatalf("case %d: want %.3f of the metrics on %s, got %.3f", i, want, id, got[id])
+			}
+		}
+	}
+
+	// two of the shards are on one host
+	masters := []Node{
+		{ShardID: "shard-1", IP: "10.0.0.1", Weight: 4},
+		{ShardID: "shard-2", IP: "10.0.0.1", Weight: 4},
+		{ShardID: "shard-3", IP: "10.0.0.2"},
+	}
+	for i := 0; i < 1000; i++ {
+		metricName := "metric_" + strconv.Itoa(i)
+
+		shardGroup := pickWeightedShardGroup(masters, metricName, 2)
+		if len(shardGroup) != 2 || (shardGroup[0] != "shard-3" && shardGroup[1] != "shard-3") {
+			t.Fatalf("%s: want the shard group over both hosts, got %v", metricName, shardGroup)
+		}
+
+		if shardGroup = pickWeightedShardGroup(masters, metricName, 5); len(shardGroup) != 3 {
+			t.Fatalf("%s: want all the shards when the cap exceeds them, got %v", metricName, shardGroup)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/baudtime/baudtime/util/toml"
+	"github.com/baudtime/baudtime/vars"
+)
+
+// The statuses of a health check, it's the first one of the failed checks in this order.
+const (
+	HealthOK                = "ok"
+	HealthEtcdDown          = "etcd_down"          // the store isn't reachable
+	HealthWatchStalled      = "watch_stalled"      // the meta isn't watched, or its watch is stuck
+	HealthShardsUnreachable = "shards_unreachable" // none of the masters probed is reachable
+)
+
+// Health is the status of the meta data a gateway routes by and of its connectivity to the shards.
+type Health struct {
+	Status       string    `json:"status"`
+	EtcdErr      string    `json:"etcdErr,omitempty"`
+	WatchRunning bool      `json:"watchRunning"`
+	WatchAlive   time.Time `json:"watchAlive"` // the last time the etcd watch handled an event or a tick, zero if it's not watching
+	ShardsProbed int       `json:"shardsProbed"`
+	Unreachable  []string  `json:"unreachable,omitempty"` // the shards probed whose masters are unreachable or unknown
+}
+
+func (h Health) Healthy() bool {
+	return h.Status == HealthOK
+}
+
+// HealthStatus checks that the store is reachable, that the meta is watched and that some of the masters
+// picked at random are reachable. A gateway reaching only some of them is healthy, since the others are
+// most likely unreachable from any gateway, but they're told in Unreachable.
+func HealthStatus() Health {
+	return checkHealth(globalMeta, time.Now())
+}
+
+func checkHealth(m *meta, now time.Time) Health {
+	cfg := healthConfig()
+
+	var h Health
+	if err := store().Ping(); err != nil {
+		h.EtcdErr = err.Error()
+	}
+
+	if m != nil {
+		if alive := atomic.LoadInt64(&m.watchAlive); alive != 0 {
+			h.WatchAlive = time.Unix(0, alive)
+			h.WatchRunning = now.Sub(h.WatchAlive) <= time.Duration(cfg.WatchStallTimeout)
+		} else {
+			h.WatchRunning = !m.watchEtcd // a MemStore notifies the changes itself
+		}
+		h.ShardsProbed, h.Unreachable = m.probeShards(cfg.ProbeShards)
+	}
+
+	switch {
+	case h.EtcdErr != "":
+		h.Status = HealthEtcdDown
+	case !h.WatchRunning:
+		h.Status = HealthWatchStalled
+	case h.ShardsProbed > 0 && len(h.Unreachable) == h.ShardsProbed:
+		h.Status = HealthShardsUnreachable
+	default:
+		h.Status = HealthOK
+	}
+	return h
+}
+
+// probeShards probes the masters of n shards picked at random at the same time, it returns the number of
+// the shards probed and the ones whose masters are unreachable or unknown.
+func (m *meta) probeShards(n int) (int, []string) {
+	shards := (*map[string]*Shard)(atomic.LoadPointer(&m.shards))
+	if shards == nil {
+		return 0, nil
+	}
+
+	shardIDs := make([]string, 0, len(*shards))
+	for shardID := range *shards {
+		shardIDs = append(shardIDs, shardID)
+	}
+	rand.Shuffle(len(shardIDs), func(i, j int) {
+		shardIDs[i], shardIDs[j] = shardIDs[j], shardIDs[i]
+	})
+	if n < len(shardIDs) {
+		shardIDs = shardIDs[:n]
+	}
+
+	var (
+		wg          sync.WaitGroup
+		mtx         sync.Mutex
+		unreachable []string
+	)
+	for _, shardID := range shardIDs {
+		wg.Add(1)
+		go func(shardID string, master *Node) {
+			defer wg.Done()
+			if master == nil || !m.nodes.probe(master) {
+				mtx.Lock()
+				unreachable = append(unreachable, shardID)
+				mtx.Unlock()
+			}
+		}(shardID, (*shards)[shardID].Master)
+	}
+	wg.Wait()
+
+	sort.Strings(unreachable)
+	return len(shardIDs), unreachable
+}
+
+func healthConfig() vars.HealthConfig {
+	var cfg vars.HealthConfig
+	if vars.Cfg.Gateway != nil {
+		cfg = vars.Cfg.Gateway.Health
+	}
+	if cfg.WatchStallTimeout <= 0 {
+		cfg.WatchStallTimeout = toml.Duration(time.Minute)
+	}
+	if cfg.ProbeShards <= 0 {
+		cfg.ProbeShards = 3
+	}
+	return cfg
+}
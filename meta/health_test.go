@@ -0,0 +1,178 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/baudtime/baudtime/util/toml"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/go-kit/kit/log"
+)
+
+// pingStore is a store whose ping fails with err.
+type pingStore struct {
+	Store
+	err error
+}
+
+func (s pingStore) Ping() error {
+	return s.err
+}
+
+func TestCheckHealth(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+	vars.Cfg.Gateway = &vars.GatewayConfig{Health: vars.HealthConfig{WatchStallTimeout: toml.Duration(time.Minute)}}
+
+	mem := NewMemStore()
+	defer SetStore(SetStore(mem))
+
+	now := time.Now()
+	shards := map[string]*Shard{
+		"shard-1": {Master: &Node{ShardID: "shard-1", IP: "10.0.0.1", Port: "8088"}},
+		"shard-2": {Master: &Node{ShardID: "shard-2", IP: "10.0.0.2", Port: "8088"}},
+		"shard-3": {Slaves: []*Node{{ShardID: "shard-3", IP: "10.0.0.3", Port: "8088", MasterIP: "10.0.0.4", MasterPort: "8088"}}},
+	}
+
+	tests := []struct {
+		pingErr     error
+		watchEtcd   bool
+		watchAlive  time.Time
+		alive       map[string]bool
+		status      string
+		unreachable []string
+	}{
+		// a shard without a master is unreachable, but the others are reached
+		{alive: map[string]bool{"10.0.0.1:8088": true}, status: HealthOK, unreachable: []string{"shard-2", "shard-3"}},
+		{alive: map[string]bool{}, status: HealthShardsUnreachable, unreachable: []string{"shard-1", "shard-2", "shard-3"}},
+		{watchEtcd: true, watchAlive: now.Add(-time.Second), alive: map[string]bool{"10.0.0.1:8088": true, "10.0.0.2:8088": true}, status: HealthOK, unreachable: []string{"shard-3"}},
+		// the watch is stuck, or it's not watching, e.g. reconnecting to etcd
+		{watchEtcd: true, watchAlive: now.Add(-2 * time.Minute), alive: map[string]bool{"10.0.0.1:8088": true}, status: HealthWatchStalled, unreachable: []string{"shard-2", "shard-3"}},
+		{watchEtcd: true, alive: map[string]bool{"10.0.0.1:8088": true}, status: HealthWatchStalled, unreachable: []string{"shard-2", "shard-3"}},
+		// etcd down is told rather than the rest
+		{pingErr: errors.New("etcd is down"), watchEtcd: true, alive: map[string]bool{}, status: HealthEtcdDown, unreachable: []string{"shard-1", "shard-2", "shard-3"}},
+	}
+
+	for i, test := range tests {
+		SetStore(pingStore{Store: mem, err: test.pingErr})
+		nodes := fakeNodes{alive: func(node *Node) bool {
+			return test.alive[node.Addr()]
+		}}
+
+		m := &meta{routeInfos: new(sync.Map), watchEtcd: test.watchEtcd, nodes: nodes}
+		atomic.StorePointer(&m.shards, unsafe.Pointer(&shards))
+		if !test.watchAlive.IsZero() {
+			m.watchAlive = test.watchAlive.UnixNano()
+		}
+
+		h := checkHealth(m, now)
+		if h.Status != test.status || h.ShardsProbed != 3 || !reflect.DeepEqual(h.Unreachable, test.unreachable) {
+			t.Fatalf("case %d: want %s with %v unreachable of 3, got %+v", i, test.status, test.unreachable, h)
+		}
+		if h.Healthy() != (test.status == HealthOK) || (test.pingErr != nil) != (h.EtcdErr != "") {
+			t.Fatalf("case %d: unexpected health %+v", i, h)
+		}
+	}
+
+	// only a sample of the masters is probed
+	SetStore(mem)
+	vars.Cfg.Gateway.Health.ProbeShards = 2
+	var probed int32
+	nodes := fakeNodes{alive: func(*Node) bool {
+		atomic.AddInt32(&probed, 1)
+		return true
+	}}
+	m := &meta{routeInfos: new(sync.Map), nodes: nodes}
+	atomic.StorePointer(&m.shards, unsafe.Pointer(&shards))
+	if h := checkHealth(m, now); h.ShardsProbed != 2 || len(h.Unreachable) > 1 {
+		t.Fatalf("want 2 shards probed, got %+v", h)
+	}
+	if got := atomic.LoadInt32(&probed); got > 2 {
+		t.Fatalf("want no more than 2 masters probed, got %d", got)
+	}
+}
+
+func TestMeta_WatchHealth(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	gateway, intervalBak, tickBak := vars.Cfg.Gateway, watchRetryInterval, watchTickInterval
+	defer func() {
+		vars.Cfg.Gateway, watchRetryInterval, watchTickInterval = gateway, intervalBak, tickBak
+	}()
+	vars.Cfg.Gateway = &vars.GatewayConfig{Health: vars.HealthConfig{WatchStallTimeout: toml.Duration(time.Second)}}
+
+	mem := NewMemStore()
+	defer SetStore(SetStore(mem))
+
+	watchers := make(chan *fakeWatcher, 1)
+	watchRetryInterval, watchTickInterval = time.Hour, 10*time.Millisecond
+	newWatcher := func() (clientv3.Watcher, error) {
+		select {
+		case <-watchers: // etcd is down since the first watch is broken
+			return nil, errors.New("etcd is down")
+		default:
+		}
+		w := &fakeWatcher{watched: make(chan struct{}), closed: make(chan struct{})}
+		watchers <- w
+		return w, nil
+	}
+
+	m := &meta{routeInfos: new(sync.Map), watchEtcd: true, newWatcher: newWatcher}
+	if err := m.RefreshCluster(); err != nil {
+		t.Fatal(err)
+	}
+	stopc, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		m.keepWatching(stopc)
+		close(done)
+	}()
+	defer func() {
+		close(stopc)
+		<-done
+	}()
+
+	w := <-watchers
+	watchers <- w
+	<-w.watched
+
+	// nothing happens, but the loop ticks
+	time.Sleep(50 * time.Millisecond)
+	first := atomic.LoadInt64(&m.watchAlive)
+	time.Sleep(50 * time.Millisecond)
+	if h := checkHealth(m, time.Now()); !h.WatchRunning || h.Status != HealthOK || atomic.LoadInt64(&m.watchAlive) == first {
+		t.Fatalf("want the idle watch running, got %+v", h)
+	}
+
+	w.broken()
+	select {
+	case <-w.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for the broken watcher closed")
+	}
+	if h := checkHealth(m, time.Now()); h.WatchRunning || h.Status != HealthWatchStalled {
+		t.Fatalf("want the watch reconnecting to etcd not running, got %+v", h)
+	}
+}
@@ -20,7 +20,7 @@ import (
 	"encoding/json"
 	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
 	"github.com/baudtime/baudtime/tcp"
-	"os"
+	"math"
 	"strconv"
 	"strings"
 	"sync"
@@ -29,6 +29,8 @@ import (
 	"unsafe"
 
 	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/util/redo"
+	"github.com/baudtime/baudtime/util/toml"
 	"github.com/baudtime/baudtime/vars"
 	"github.com/coreos/etcd/clientv3"
 	"github.com/coreos/etcd/clientv3/concurrency"
@@ -37,6 +39,11 @@ import (
 	"github.com/pkg/errors"
 )
 
+var ErrNotEnoughShards = errors.New("not enough shards")
+
+// ErrNotRouted is returned by a lookup of a route not initialized yet which doesn't initialize it, see peekShardIDs.
+var ErrNotRouted = errors.New("not routed yet")
+
 type Shard struct {
 	Master      *Node
 	Slaves      []*Node
@@ -48,38 +55,114 @@ type meta struct {
 	sync.Once
 	shards     unsafe.Pointer //point to a map[string]*Shard
 	routeInfos *sync.Map
+	flights    sync.Map //routeKey -> *routeFlight, the routes being loaded from the store
 	refreshing uint32
+	watchEtcd  bool  //the meta is watched in etcd rather than kept by a MemStore
+	watchAlive int64 //unix nanoseconds the etcd watch last handled an event or a tick, 0 if it's not watching
+	nodes      nodeClient
+	newWatcher func() (clientv3.Watcher, error) //connects to etcd for watching
+}
+
+type routeKey struct {
+	metricName string
+	day        uint64
+}
+
+// routeFlight is loading a route from the store, whose result is shared by the concurrent lookups of the route.
+type routeFlight struct {
+	wg             sync.WaitGroup
+	shardGroup     []string
+	shardGrpRouteK string
+	err            error
 }
 
 func (m *meta) getShardIDs(metricName string, day uint64) ([]string, string, error) {
+	shardGroup, shardGrpRouteK, _, err := m.lookupShardIDs(metricName, day)
+	return shardGroup, shardGrpRouteK, err
+}
+
+// lookupShardIDs is getShardIDs, besides it tells whether the shard group is found in the cache
+// rather than loaded from or initialized in etcd. Only one lookup of a route goes to etcd at a time,
+// the concurrent ones wait for and share its result.
+func (m *meta) lookupShardIDs(metricName string, day uint64) ([]string, string, bool, error) {
 	shardGroup, shardGrpRouteK, found := m.getShardIDsFromCache(metricName, day)
 	if found {
-		return shardGroup, shardGrpRouteK, nil
+		routeCacheHits.Inc()
+		return shardGroup, shardGrpRouteK, true, nil
 	}
 
-	var err error
-
 	routeInfo := m.getRouteInfoFromCache(metricName)
-	routeInfo.Lock()
+	if err := routeInfo.GetMissing(day); err != nil {
+		routeCacheNegativeHits.Inc()
+		return nil, "", false, err
+	}
+	routeCacheMisses.Inc()
 
-	shardGroup, shardGrpRouteK, found = m.getShardIDsFromCache(metricName, day)
-	if !found {
-		shardGroup, shardGrpRouteK, err = m.getShardIDsFromEtcd(metricName, day)
-		if err == nil {
-			routeInfo.ShardGrpRouteK = shardGrpRouteK
-			routeInfo.Put(day, shardGroup)
+	key := routeKey{metricName: metricName, day: day}
+
+	f := new(routeFlight)
+	f.wg.Add(1)
+	if inFlight, loaded := m.flights.LoadOrStore(key, f); loaded {
+		f = inFlight.(*routeFlight)
+		f.wg.Wait()
+		return f.shardGroup, f.shardGrpRouteK, false, f.err
+	}
+
+	// the route may be cached by the last flight just before it landed
+	if shardGroup, shardGrpRouteK, found = m.getShardIDsFromCache(metricName, day); found {
+		f.shardGroup, f.shardGrpRouteK = shardGroup, shardGrpRouteK
+	} else if f.err = routeInfo.GetMissing(day); f.err == nil {
+		f.shardGroup, f.shardGrpRouteK, f.err = m.getShardIDsFromStore(metricName, day)
+		if f.err != nil {
+			routeStoreErrors.Inc()
 		}
+
+		routeInfo.Lock()
+		if f.err == nil {
+			if routeInfo.ShardGrpRouteK != f.shardGrpRouteK { // it's read without the lock
+				routeInfo.ShardGrpRouteK = f.shardGrpRouteK
+			}
+			routeInfo.Put(day, f.shardGroup)
+		} else if ttl := time.Duration(vars.Cfg.Gateway.Route.NegativeTTL); ttl > 0 && errors.Cause(f.err) == ErrNotEnoughShards {
+			routeInfo.PutMissing(day, f.err, ttl)
+		}
+		routeInfo.Unlock()
 	}
 
-	routeInfo.Unlock()
+	m.flights.Delete(key)
+	f.wg.Done()
 
-	return shardGroup, shardGrpRouteK, err
+	return f.shardGroup, f.shardGrpRouteK, found, f.err
+}
+
+// peekShardIDs is lookupShardIDs without initializing the route, ErrNotRouted is returned if it's not initialized.
+// A route loaded from the store isn't cached either, it's read only.
+func (m *meta) peekShardIDs(metricName string, day uint64) ([]string, string, bool, error) {
+	if shardGroup, shardGrpRouteK, found := m.getShardIDsFromCache(metricName, day); found {
+		return shardGroup, shardGrpRouteK, true, nil
+	}
+
+	sGrpRouteKey, err := store().GetRouteKey(metricName)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	shardGroup, err := store().GetRoute(metricName, day)
+	if err == ErrKeyNotFound {
+		return nil, "", false, errors.Wrapf(ErrNotRouted, "%s on day %d", metricName, day)
+	}
+	if err != nil {
+		return nil, "", false, err
+	}
+	return shardGroup, sGrpRouteKey, false, nil
 }
 
 func (m *meta) getRouteInfoFromCache(metricName string) *RouteInfo {
 	routeInfo, ok := m.routeInfos.Load(metricName)
 	if !ok {
-		routeInfo, _ = m.routeInfos.LoadOrStore(metricName, NewRouteInfo(metricName))
+		if routeInfo, ok = m.routeInfos.LoadOrStore(metricName, NewRouteInfo(metricName)); !ok {
+			routeCachedMetrics.Inc()
+		}
 	}
 	return routeInfo.(*RouteInfo)
 }
@@ -91,19 +174,15 @@ func (m *meta) getShardIDsFromCache(metricName string, day uint64) ([]string, st
 	return shardGroup, routeInfo.ShardGrpRouteK, found
 }
 
-func (m *meta) getShardIDsFromEtcd(metricName string, day uint64) ([]string, string, error) {
-	level.Info(vars.Logger).Log("msg", "get shards from etcd", "metric", metricName, "day", day)
+func (m *meta) getShardIDsFromStore(metricName string, day uint64) ([]string, string, error) {
+	level.Info(vars.Logger).Log("msg", "get shards from store", "metric", metricName, "day", day)
 
-	sGrpRouteKey := ""
-	err := etcdGet(sGrpRoutePrefix()+metricName, &sGrpRouteKey)
-	if err != nil && err != ErrKeyNotFound {
+	sGrpRouteKey, err := store().GetRouteKey(metricName)
+	if err != nil {
 		return nil, "", err
 	}
 
-	shardGroup := make([]string, 0, vars.Cfg.Gateway.Route.ShardGroupCap)
-
-	key := routeInfoPrefix() + metricName + "/" + strconv.FormatUint(day, 10)
-	err = etcdGet(key, &shardGroup)
+	shardGroup, err := store().GetRoute(metricName, day)
 	if err == nil {
 		return shardGroup, sGrpRouteKey, nil
 	}
@@ -112,30 +191,68 @@ func (m *meta) getShardIDsFromEtcd(metricName string, day uint64) ([]string, str
 		return nil, "", err
 	}
 
+	key := routeInfoPrefix() + metricName + "/" + strconv.FormatUint(day, 10)
+
 	masters, err := GetMasters()
 	if err != nil {
 		return nil, "", err
 	}
+	masters = undrained(masters)
 
 	if len(masters) < vars.Cfg.Gateway.Route.ShardGroupCap {
-		return nil, "", errors.Errorf("not enough shards to init %v", key)
+		return nil, "", errors.Wrapf(ErrNotEnoughShards, "init %v", key)
 	}
 
-	for i := 0; i < vars.Cfg.Gateway.Route.ShardGroupCap && i < len(masters); i++ {
-		shardGroup = append(shardGroup, masters[i].ShardID)
+	if vars.Cfg.Gateway.Route.Weighted {
+		shardGroup = pickWeightedShardGroup(masters, metricName, vars.Cfg.Gateway.Route.ShardGroupCap)
+	} else {
+		shardIDs := make([]string, len(masters))
+		for i, master := range masters {
+			shardIDs[i] = master.ShardID
+		}
+		shardGroup = pickShardGroup(shardIDs, metricName, vars.Cfg.Gateway.Route.ShardGroupCap)
 	}
 
-	leaseID, err := getEtcdLease(day)
+	// another gateway may have initialized the route meanwhile, take its group then
+	put, err := store().PutRouteIfAbsent(metricName, day, shardGroup)
 	if err != nil {
 		return nil, "", err
 	}
+	if !put {
+		if shardGroup, err = store().GetRoute(metricName, day); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return shardGroup, sGrpRouteKey, nil
+}
 
-	err = etcdPut(key, shardGroup, leaseID)
+// undrained filters out the draining masters in place, their shards take no new series.
+func undrained(masters []Node) []Node {
+	n := 0
+	for _, master := range masters {
+		if !master.Draining {
+			masters[n] = master
+			n++
+		}
+	}
+	return masters[:n]
+}
+
+// getAllShardIDs returns the shard groups of every day on which the metric has been routed,
+// unlike getShardIDs it never initializes a shard group.
+func (m *meta) getAllShardIDs(metricName string) ([][]string, string, error) {
+	sGrpRouteKey, err := store().GetRouteKey(metricName)
 	if err != nil {
 		return nil, "", err
 	}
 
-	return shardGroup, sGrpRouteKey, nil
+	shardGroups, err := store().GetRoutes(metricName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return shardGroups, sGrpRouteKey, nil
 }
 
 func (m *meta) RefreshCluster() error {
@@ -167,108 +284,234 @@ func (m *meta) RefreshCluster() error {
 		}
 	}
 
-	atomic.StorePointer(&m.shards, (unsafe.Pointer)(&shards))
+	old := (*map[string]*Shard)(atomic.SwapPointer(&m.shards, (unsafe.Pointer)(&shards)))
+	if old != nil {
+		for shardID, shard := range *old {
+			if shard.Master == nil {
+				continue
+			}
+			if newShard, found := shards[shardID]; !found || newShard.Master == nil || newShard.Master.Addr() != shard.Master.Addr() {
+				masterChanged(shardID, shard.Master)
+			}
+		}
+	}
 	return nil
 }
 
+var (
+	masterChangedMtx   sync.RWMutex
+	masterChangedHooks []func(shardID string, oldMaster *Node)
+)
+
+// OnMasterChanged registers f to be called with the old master of a shard once the cluster is
+// refreshed with another master of it or none, e.g. to drop the connections to the old master.
+func OnMasterChanged(f func(shardID string, oldMaster *Node)) {
+	masterChangedMtx.Lock()
+	masterChangedHooks = append(masterChangedHooks, f)
+	masterChangedMtx.Unlock()
+}
+
+func masterChanged(shardID string, oldMaster *Node) {
+	masterChangedMtx.RLock()
+	defer masterChangedMtx.RUnlock()
+
+	for _, f := range masterChangedHooks {
+		f(shardID, oldMaster)
+	}
+}
+
 func (m *meta) GetShard(shardID string) (shard *Shard, found bool) {
 	shards := (*map[string]*Shard)(atomic.LoadPointer(&m.shards))
 	shard, found = (*shards)[shardID]
 	return
 }
 
+// onRouteInfoEvent applies a watched change of the route info to the cache.
+func (m *meta) onRouteInfoEvent(ev *clientv3.Event) {
+	strArray := strings.Split(string(ev.Kv.Key), "/")
+	metricName := strings.TrimPrefix(strArray[0], routeInfoPrefix())
+	day, err := strconv.ParseUint(strArray[1], 10, 0)
+	if err != nil {
+		return
+	}
+
+	if ev.Type == mvccpb.DELETE {
+		routeInfo := m.getRouteInfoFromCache(metricName)
+		routeInfo.Delete(day)
+		if day == routeInfo.Timeline {
+			m.routeInfos.Delete(metricName)
+			routeCachedMetrics.Dec()
+		}
+	} else {
+		shardGroup := make([]string, 0, vars.Cfg.Gateway.Route.ShardGroupCap)
+		if err = json.Unmarshal(ev.Kv.Value, &shardGroup); err == nil {
+			routeInfo := m.getRouteInfoFromCache(metricName)
+			routeInfo.ClearMissing()
+			routeInfo.Put(day, shardGroup)
+		}
+	}
+}
+
+// dialWatcher connects to etcd for watching.
+func dialWatcher() (clientv3.Watcher, error) {
+	return clientv3.New(clientv3.Config{
+		Endpoints:   vars.Cfg.EtcdCommon.Endpoints,
+		DialTimeout: time.Duration(vars.Cfg.EtcdCommon.DialTimeout),
+	})
+}
+
+var (
+	watchRetryInterval    = time.Second      // initial interval between connecting to etcd for watching
+	watchMaxRetryInterval = 30 * time.Second // the doubled interval is capped to it
+)
+
 func (m *meta) watch() {
 	m.Do(func() {
-		go func() {
-			cli, err := clientv3.New(clientv3.Config{
-				Endpoints:   vars.Cfg.EtcdCommon.Endpoints,
-				DialTimeout: time.Duration(vars.Cfg.EtcdCommon.DialTimeout),
-			})
-			if err != nil {
-				level.Error(vars.Logger).Log("msg", "failed to connect to etcd", "err", err)
-				os.Exit(1)
+		go m.keepWatching(nil)
+	})
+}
+
+// keepWatching watches etcd till stopc is closed, it reconnects with backoff once a watch is broken,
+// e.g. etcd restarts, and refreshes the cluster after reconnected to catch the events missed.
+func (m *meta) keepWatching(stopc <-chan struct{}) {
+	for reconnected := false; ; reconnected = true {
+		var (
+			w        clientv3.Watcher
+			err      error
+			interval = watchRetryInterval
+		)
+
+		for {
+			if w, err = m.newWatcher(); err == nil {
+				break
 			}
-			defer cli.Close()
-
-			rch := cli.Watch(context.Background(), routeInfoPrefix(), clientv3.WithPrefix())
-			gch := cli.Watch(context.Background(), sGrpRoutePrefix(), clientv3.WithPrefix())
-			nch := cli.Watch(context.Background(), nodePrefix(), clientv3.WithPrefix(), clientv3.WithPrevKV())
-
-			var wresp clientv3.WatchResponse
-
-			level.Info(vars.Logger).Log("msg", "i am watching etcd events now")
-			for {
-				select {
-				case wresp = <-rch:
-					for _, ev := range wresp.Events {
-						level.Warn(vars.Logger).Log(
-							"msg", "get etcd event",
-							"type", ev.Type,
-							"key", ev.Kv.Key,
-							"value", ev.Kv.Value,
-						)
-
-						strArray := strings.Split(string(ev.Kv.Key), "/")
-						metricName := strings.TrimPrefix(strArray[0], routeInfoPrefix())
-						day, err := strconv.ParseUint(strArray[1], 10, 0)
-						if err != nil {
-							continue
-						}
-
-						if ev.Type == mvccpb.DELETE {
-							routeInfo := m.getRouteInfoFromCache(metricName)
-							routeInfo.Delete(day)
-							if day == routeInfo.Timeline {
-								m.routeInfos.Delete(metricName)
-							}
-						} else {
-							shardGroup := make([]string, 0, vars.Cfg.Gateway.Route.ShardGroupCap)
-							if err = json.Unmarshal(ev.Kv.Value, &shardGroup); err == nil {
-								routeInfo := m.getRouteInfoFromCache(metricName)
-								routeInfo.Put(day, shardGroup)
-							}
-						}
-					}
-				case wresp = <-gch:
-					for _, ev := range wresp.Events {
-						level.Warn(vars.Logger).Log(
-							"msg", "get etcd event",
-							"type", ev.Type,
-							"key", ev.Kv.Key,
-							"value", ev.Kv.Value,
-						)
-
-						metricName := strings.TrimPrefix(string(ev.Kv.Key), sGrpRoutePrefix())
-						routeInfo := m.getRouteInfoFromCache(metricName)
-						if ev.Type == mvccpb.DELETE {
-							routeInfo.ShardGrpRouteK = ""
-						} else {
-							routeInfo.ShardGrpRouteK = string(ev.Kv.Value)
-						}
-					}
-				case wresp = <-nch:
-					for _, ev := range wresp.Events {
-						if ev.Type == mvccpb.DELETE && ev.PrevKv != nil {
-							level.Warn(vars.Logger).Log(
-								"msg", "get etcd event",
-								"type", ev.Type,
-								"key", ev.Kv.Key,
-								"value", ev.Kv.Value,
-								"preKey", ev.PrevKv.Key,
-								"preValue", ev.PrevKv.Value,
-							)
-
-							var node Node
-							if err = json.Unmarshal(ev.PrevKv.Value, &node); err == nil {
-								FailoverIfNeeded(&node)
-							}
-						}
+			level.Error(vars.Logger).Log("msg", "failed to connect to etcd for watching, retry later", "interval", interval, "err", err)
+
+			select {
+			case <-stopc:
+				return
+			case <-time.After(interval):
+			}
+			if interval *= 2; interval > watchMaxRetryInterval {
+				interval = watchMaxRetryInterval
+			}
+		}
+
+		stopped := m.watchEvents(w, reconnected, stopc)
+		w.Close()
+		if stopped {
+			return
+		}
+	}
+}
+
+// watchEvents applies the watched events till a watch is broken or stopc is closed, which is told by
+// the returned bool.
+func (m *meta) watchEvents(w clientv3.Watcher, refresh bool, stopc <-chan struct{}) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rch := w.Watch(ctx, routeInfoPrefix(), clientv3.WithPrefix())
+	gch := w.Watch(ctx, sGrpRoutePrefix(), clientv3.WithPrefix())
+	nch := w.Watch(ctx, nodePrefix(), clientv3.WithPrefix(), clientv3.WithPrevKV())
+
+	if refresh {
+		m.RefreshCluster()
+	}
+
+	// the loop ticks even if nothing changes, so that it's found stalled only if it's stuck
+	tick := time.NewTicker(watchTickInterval)
+	defer tick.Stop()
+
+	m.touchWatch()
+	defer atomic.StoreInt64(&m.watchAlive, 0)
+
+	var (
+		wresp clientv3.WatchResponse
+		ok    bool
+	)
+
+	broken := func() bool {
+		if !ok || wresp.Err() != nil {
+			level.Warn(vars.Logger).Log("msg", "etcd watch is broken, rewatch", "err", wresp.Err())
+			return true
+		}
+		return false
+	}
+
+	level.Info(vars.Logger).Log("msg", "i am watching etcd events now")
+	for {
+		m.touchWatch()
+
+		select {
+		case wresp, ok = <-rch:
+			if broken() {
+				return false
+			}
+			for _, ev := range wresp.Events {
+				level.Warn(vars.Logger).Log(
+					"msg", "get etcd event",
+					"type", ev.Type,
+					"key", ev.Kv.Key,
+					"value", ev.Kv.Value,
+				)
+
+				m.onRouteInfoEvent(ev)
+			}
+		case wresp, ok = <-gch:
+			if broken() {
+				return false
+			}
+			for _, ev := range wresp.Events {
+				level.Warn(vars.Logger).Log(
+					"msg", "get etcd event",
+					"type", ev.Type,
+					"key", ev.Kv.Key,
+					"value", ev.Kv.Value,
+				)
+
+				metricName := strings.TrimPrefix(string(ev.Kv.Key), sGrpRoutePrefix())
+				routeInfo := m.getRouteInfoFromCache(metricName)
+				if ev.Type == mvccpb.DELETE {
+					routeInfo.ShardGrpRouteK = ""
+				} else {
+					routeInfo.ShardGrpRouteK = string(ev.Kv.Value)
+				}
+			}
+		case wresp, ok = <-nch:
+			if broken() {
+				return false
+			}
+			for _, ev := range wresp.Events {
+				if ev.Type == mvccpb.DELETE && ev.PrevKv != nil {
+					level.Warn(vars.Logger).Log(
+						"msg", "get etcd event",
+						"type", ev.Type,
+						"key", ev.Kv.Key,
+						"value", ev.Kv.Value,
+						"preKey", ev.PrevKv.Key,
+						"preValue", ev.PrevKv.Value,
+					)
+
+					var node Node
+					if err := json.Unmarshal(ev.PrevKv.Value, &node); err == nil {
+						FailoverIfNeeded(&node)
 					}
-					m.RefreshCluster()
 				}
 			}
-		}()
-	})
+			m.RefreshCluster()
+		case <-tick.C:
+		case <-stopc:
+			return true
+		}
+	}
+}
+
+// watchTickInterval is how often the watch loop ticks without any event.
+var watchTickInterval = 10 * time.Second
+
+func (m *meta) touchWatch() {
+	atomic.StoreInt64(&m.watchAlive, time.Now().UnixNano())
 }
 
 var globalMeta *meta
@@ -276,6 +519,8 @@ var globalMeta *meta
 func Watch() error {
 	m := &meta{
 		routeInfos: new(sync.Map),
+		nodes:      tcpNodes{},
+		newWatcher: dialWatcher,
 	}
 
 	err := m.RefreshCluster()
@@ -283,7 +528,12 @@ func Watch() error {
 		return err
 	}
 
-	m.watch()
+	if mem, ok := store().(*MemStore); ok {
+		mem.watch(m.RefreshCluster)
+	} else {
+		m.watchEtcd = true
+		m.watch()
+	}
 	globalMeta = m
 
 	level.Info(vars.Logger).Log("msg", "watching nodes")
@@ -315,6 +565,295 @@ func GetSlaves(shardID string) []*Node {
 	return shard.Slaves
 }
 
+// nodeClient talks to the nodes directly rather than through the store, see tcpNodes.
+type nodeClient interface {
+	// probe reports whether node is alive.
+	probe(node *Node) bool
+	// info asks slave for its detailed info.
+	info(slave *Node) (*pb.InfoResponse, error)
+	// promote makes slave the master of its shard by sending it a slaveof no one command.
+	promote(slave *Node) error
+}
+
+// tcpNodes talks to the nodes over direct connections.
+type tcpNodes struct{}
+
+func (tcpNodes) probe(node *Node) bool {
+	return nodeAlive(node, time.Duration(failoverConfig().ProbeTimeout))
+}
+
+// nodeAlive reports whether node answers an info command in timeout over a direct connection.
+func nodeAlive(node *Node, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn, err := tcp.ConnectContext(ctx, node.Addr())
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	var msgCodec tcp.MsgCodec
+	buf := make([]byte, tcp.MaxMsgSize)
+
+	n, err := msgCodec.Encode(tcp.Message{Message: &pb.AdminCmdRequest{Command: &pb.AdminCmdRequest_Info{Info: &pb.Info{}}}}, buf)
+	if err != nil {
+		return false
+	}
+	if err = conn.WriteMsg(buf[:n]); err != nil {
+		return false
+	}
+	if err = conn.Flush(); err != nil {
+		return false
+	}
+
+	alive := make(chan bool, 1)
+	go func() {
+		nn, er := conn.ReadMsg(buf)
+		if er != nil {
+			alive <- false
+			return
+		}
+
+		reply, er := msgCodec.Decode(buf[:nn])
+		if er != nil {
+			alive <- false
+			return
+		}
+
+		resp, ok := reply.GetRaw().(*pb.GeneralResponse)
+		alive <- ok && resp.Status == pb.StatusCode_Succeed
+	}()
+
+	select {
+	case ok := <-alive:
+		return ok
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stayedDown probes node several times in the grace period, it returns false as soon as the node
+// is found alive, so a flapping node doesn't fail over.
+func stayedDown(node *Node, grace time.Duration, probe func(*Node) bool) bool {
+	if grace <= 0 {
+		return true
+	}
+
+	const probeTimes = 5
+	for i := 0; i < probeTimes; i++ {
+		time.Sleep(grace / probeTimes)
+		if probe(node) {
+			return false
+		}
+	}
+	return true
+}
+
+func failoverConfig() vars.FailoverConfig {
+	cfg := vars.FailoverConfig{ProbeTimeout: toml.Duration(2 * time.Second)}
+	if vars.Cfg.Gateway != nil {
+		cfg = vars.Cfg.Gateway.Failover
+		if cfg.ProbeTimeout <= 0 {
+			cfg.ProbeTimeout = toml.Duration(2 * time.Second)
+		}
+	}
+	return cfg
+}
+
+// chooseFailoverSlave returns the least lagged slave in the idc, or the least lagged one of all
+// if no slave is in the idc. The earlier one wins among slaves lagging equally.
+func chooseFailoverSlave(slaves []*Node, idc string) *Node {
+	var local, global *Node
+
+	for _, slave := range slaves {
+		if global == nil || slave.MaxT > global.MaxT {
+			global = slave
+		}
+		if slave.IDC == idc && (local == nil || slave.MaxT > local.MaxT) {
+			local = slave
+		}
+	}
+
+	if local != nil {
+		return local
+	}
+	return global
+}
+
+func (tcpNodes) info(slave *Node) (*pb.InfoResponse, error) {
+	return detailedInfo(slave, time.Duration(failoverConfig().ProbeTimeout))
+}
+
+// detailedInfo sends node a detailed info command over a direct connection, the nodes not knowing it reply
+// a GeneralResponse, which is returned as an error.
+func detailedInfo(node *Node, timeout time.Duration) (*pb.InfoResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn, err := tcp.ConnectContext(ctx, node.Addr())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var msgCodec tcp.MsgCodec
+	buf := make([]byte, tcp.MaxMsgSize)
+
+	n, err := msgCodec.Encode(tcp.Message{Message: &pb.AdminCmdRequest{Command: &pb.AdminCmdRequest_Info{Info: &pb.Info{Detailed: true}}}}, buf)
+	if err != nil {
+		return nil, err
+	}
+	if err = conn.WriteMsg(buf[:n]); err == nil {
+		err = conn.Flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		info *pb.InfoResponse
+		err  error
+	}
+	c := make(chan result, 1)
+	go func() {
+		nn, er := conn.ReadMsg(buf)
+		if er != nil {
+			c <- result{err: er}
+			return
+		}
+
+		reply, er := msgCodec.Decode(buf[:nn])
+		if er != nil {
+			c <- result{err: er}
+			return
+		}
+
+		switch resp := reply.GetRaw().(type) {
+		case *pb.InfoResponse:
+			if resp.Status != pb.StatusCode_Succeed {
+				c <- result{err: errors.New(resp.ErrorMsg)}
+				return
+			}
+			c <- result{info: resp}
+		default:
+			c <- result{err: errors.Errorf("no detailed info from %s", node.Addr())}
+		}
+	}()
+
+	select {
+	case r := <-c:
+		return r.info, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// choosePromotedSlave picks the slave to promote by chooseFailoverSlave among the ones not compacting, as a slave
+// busy compacting may stall serving once promoted. A slave failing to answer info is taken as not compacting.
+// If all the slaves are compacting, the least loaded one is picked with a warning.
+func choosePromotedSlave(slaves []*Node, idc string, info func(*Node) (*pb.InfoResponse, error)) *Node {
+	infos := make([]*pb.InfoResponse, len(slaves))
+
+	var wg sync.WaitGroup
+	for i, slave := range slaves {
+		wg.Add(1)
+		go func(i int, slave *Node) {
+			defer wg.Done()
+			if resp, err := info(slave); err == nil {
+				infos[i] = resp
+			}
+		}(i, slave)
+	}
+	wg.Wait()
+
+	quiet := make([]*Node, 0, len(slaves))
+	for i, slave := range slaves {
+		if infos[i] == nil || !infos[i].Compacting {
+			quiet = append(quiet, slave)
+		}
+	}
+	if len(quiet) > 0 {
+		return chooseFailoverSlave(quiet, idc)
+	}
+
+	var least []*Node
+	leastLoad := int64(math.MaxInt64)
+	for i, slave := range slaves {
+		if load := infos[i].Load; load < leastLoad {
+			leastLoad, least = load, append(least[:0], slave)
+		} else if load == leastLoad {
+			least = append(least, slave)
+		}
+	}
+
+	chosen := chooseFailoverSlave(least, idc)
+	level.Warn(vars.Logger).Log("msg", "all slaves are compacting, promote the least loaded one", "shard", chosen.ShardID, "chosen", chosen.Addr(), "load", leastLoad)
+	return chosen
+}
+
+func (tcpNodes) promote(slave *Node) error {
+	buf := make([]byte, tcp.MaxMsgSize)
+	var msgCodec tcp.MsgCodec
+
+	n, err := msgCodec.Encode(tcp.Message{Message: &backendpb.SlaveOfCommand{}}, buf) //slaveof no one
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	slaveConn, err := tcp.ConnectContext(ctx, slave.Addr())
+	if err != nil {
+		return err
+	}
+	defer slaveConn.Close()
+
+	err = slaveConn.WriteMsg(buf[:n])
+	if err == nil {
+		err = slaveConn.Flush()
+	}
+	if err != nil {
+		return err
+	}
+
+	c := make(chan error, 1)
+	go func() {
+		nn, er := slaveConn.ReadMsg(buf)
+		if er != nil {
+			c <- nil
+			return
+		}
+
+		reply, er := msgCodec.Decode(buf[:nn])
+		if raw := reply.GetRaw(); er == nil && raw != nil {
+			if reply, ok := raw.(*pb.GeneralResponse); ok && reply.Status != pb.StatusCode_Succeed {
+				c <- reply.Err()
+				return
+			}
+		}
+		c <- nil
+	}()
+
+	select {
+	case err = <-c:
+	case <-ctx.Done():
+	}
+
+	return err
+}
+
+// handOver promotes slave through nodes, retrying unless the slave refuses it as a bad request. It's safe
+// to retry as slaveof no one is idempotent, e.g. a slave stops following a dead master even
+// if it fails to tell the master.
+func handOver(nodes nodeClient, slave *Node) error {
+	return redo.RetryWithBackoff(time.Second, 3, func() (bool, error) {
+		err := nodes.promote(slave)
+		return err != nil && pb.Retryable(err), err
+	})
+}
+
 func FailoverIfNeeded(node *Node) {
 	if node == nil {
 		return
@@ -330,89 +869,53 @@ func FailoverIfNeeded(node *Node) {
 		return
 	}
 
-	buf := make([]byte, tcp.MaxMsgSize)
-	var msgCodec tcp.MsgCodec
-
-	n, err := msgCodec.Encode(tcp.Message{Message: &backendpb.SlaveOfCommand{}}, buf) //slaveof no one
-	if err != nil {
-		level.Error(vars.Logger).Log("err", err)
+	if !atomic.CompareAndSwapUint32(&shard.failovering, 0, 1) {
 		return
 	}
+	defer atomic.StoreUint32(&shard.failovering, 0)
 
-	if !atomic.CompareAndSwapUint32(&shard.failovering, 0, 1) {
+	nodes := globalMeta.nodes
+	cfg := failoverConfig()
+	if !stayedDown(node, time.Duration(cfg.GracePeriod), nodes.probe) {
+		level.Warn(vars.Logger).Log("msg", "node is back in grace period, failover cancelled", "shard", node.ShardID, "node", node.Addr())
 		return
 	}
-	defer atomic.StoreUint32(&shard.failovering, 0)
 
-	failoverErr := mutexRun("failover", func(session *concurrency.Session) error {
+	failover := func(session *concurrency.Session) error {
 		master := GetMaster(node.ShardID)
 		if master != nil && master.Addr() != node.Addr() { //already failover by other gateway
 			return nil
 		}
 
+		if nodes.probe(node) { //a transient blip of etcd
+			level.Warn(vars.Logger).Log("msg", "node is alive, failover cancelled", "shard", node.ShardID, "node", node.Addr())
+			return nil
+		}
+
 		slaves := GetSlaves(node.ShardID)
 		if len(slaves) == 0 {
 			return errors.New("no available slave to failover")
 		}
 
-		var chosen *Node
-		for _, slave := range slaves {
-			if slave.IDC == node.IDC {
-				chosen = slave
-			}
-		}
-		if chosen == nil {
-			chosen = slaves[0]
-		}
-
-		slaveConn, err := tcp.Connect(chosen.Addr())
-		if err != nil {
-			return err
-		}
-		defer slaveConn.Close()
-
-		err = slaveConn.WriteMsg(buf[:n])
-		if err != nil {
-			return err
-		}
-
+		chosen := choosePromotedSlave(slaves, node.IDC, nodes.info)
 		level.Warn(vars.Logger).Log("msg", "failover triggered", "shard", node.ShardID, "chosen", chosen.Addr())
 
-		c := make(chan struct{})
-		go func() {
-			defer close(c)
-
-			nn, er := slaveConn.ReadMsg(buf)
-			if er != nil {
-				return
-			}
-
-			reply, er := msgCodec.Decode(buf[:nn])
-			if raw := reply.GetRaw(); er == nil && raw != nil {
-				reply, ok := raw.(*pb.GeneralResponse)
-				if !ok {
-					return
-				}
-
-				if reply.Status != pb.StatusCode_Succeed {
-					err = errors.New(reply.Message)
-				} else {
-					level.Warn(vars.Logger).Log("msg", "failover succeed", "shard", node.ShardID, "chosen", chosen.Addr())
-				}
-			}
-		}()
-
-		select {
-		case <-c:
-		case <-time.After(15 * time.Second):
+		err := handOver(nodes, chosen)
+		if err == nil {
+			level.Warn(vars.Logger).Log("msg", "failover succeed", "shard", node.ShardID, "chosen", chosen.Addr())
 		}
 
 		globalMeta.RefreshCluster()
 
 		return err
+	}
+
+	// the legacy lock first, as the gateways not upgraded yet take it only
+	failoverErr := mutexRun(legacyFailoverLock, func(*concurrency.Session) error {
+		return mutexRun(failoverLock(), failover)
 	})
 
 	if failoverErr != nil {
-		level.Error(vars.Logger).Log("msg", "error occurred when failover ", "shard", node.ShardID, "err", failoverErr)
+		level.Error(vars.Logger).Log("msg", "error occurred when failover ", "shard", node.ShardID, "code", pb.CodeOf(failoverErr), "err", failoverErr)
 	}
 }
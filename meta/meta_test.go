@@ -0,0 +1,534 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/tcp"
+	"github.com/baudtime/baudtime/util/toml"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/go-kit/kit/log"
+)
+
+func TestChooseFailoverSlave(t *testing.T) {
+	slave := func(ip, idc string, maxT int64) *Node {
+		return &Node{IP: ip, Port: "8088", IDC: idc, MaxT: maxT}
+	}
+
+	tests := []struct {
+		slaves []*Node
+		idc    string
+		want   string
+	}{
+		{
+			slaves: []*Node{slave("10.0.0.1", "bj", 900), slave("10.0.0.2", "bj", 1000), slave("10.0.0.3", "sh", 1100)},
+			idc:    "bj",
+			want:   "10.0.0.2",
+		},
+		{
+			slaves: []*Node{slave("10.0.0.1", "sh", 900), slave("10.0.0.2", "gz", 1000), slave("10.0.0.3", "sh", 800)},
+			idc:    "bj",
+			want:   "10.0.0.2",
+		},
+		{
+			slaves: []*Node{slave("10.0.0.1", "sh", 1000), slave("10.0.0.2", "bj", 1000)},
+			idc:    "bj",
+			want:   "10.0.0.2",
+		},
+		{
+			slaves: []*Node{slave("10.0.0.1", "sh", 1000), slave("10.0.0.2", "sh", 1000)},
+			idc:    "bj",
+			want:   "10.0.0.1",
+		},
+		{
+			slaves: []*Node{slave("10.0.0.1", "bj", 0), slave("10.0.0.2", "bj", 500)},
+			idc:    "bj",
+			want:   "10.0.0.2",
+		},
+	}
+
+	for i, test := range tests {
+		if got := chooseFailoverSlave(test.slaves, test.idc); got.IP != test.want {
+			t.Fatalf("case %d: want %s, got %s", i, test.want, got.IP)
+		}
+	}
+}
+
+func TestChoosePromotedSlave(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	slave := func(ip, idc string, maxT int64) *Node {
+		return &Node{IP: ip, Port: "8088", IDC: idc, MaxT: maxT}
+	}
+
+	tests := []struct {
+		slaves []*Node
+		infos  map[string]*pb.InfoResponse // by ip, a missing one fails to answer
+		want   string
+	}{
+		{ // the slave in the idc is compacting, the quiet one in another idc is chosen instead
+			slaves: []*Node{slave("10.0.0.1", "bj", 1000), slave("10.0.0.2", "sh", 900)},
+			infos: map[string]*pb.InfoResponse{
+				"10.0.0.1": {Compacting: true},
+				"10.0.0.2": {},
+			},
+			want: "10.0.0.2",
+		},
+		{ // a slave failing to answer is taken as quiet
+			slaves: []*Node{slave("10.0.0.1", "bj", 1000), slave("10.0.0.2", "sh", 900)},
+			infos:  map[string]*pb.InfoResponse{},
+			want:   "10.0.0.1",
+		},
+		{ // all compacting, the least loaded one is chosen
+			slaves: []*Node{slave("10.0.0.1", "bj", 1000), slave("10.0.0.2", "sh", 900), slave("10.0.0.3", "sh", 1000)},
+			infos: map[string]*pb.InfoResponse{
+				"10.0.0.1": {Compacting: true, Load: 8},
+				"10.0.0.2": {Compacting: true, Load: 2},
+				"10.0.0.3": {Compacting: true, Load: 2},
+			},
+			want: "10.0.0.3",
+		},
+	}
+
+	for i, test := range tests {
+		info := func(node *Node) (*pb.InfoResponse, error) {
+			if resp, found := test.infos[node.IP]; found {
+				return resp, nil
+			}
+			return nil, errors.New("connection refused")
+		}
+		if got := choosePromotedSlave(test.slaves, "bj", info); got.IP != test.want {
+			t.Fatalf("case %d: want %s, got %s", i, test.want, got.IP)
+		}
+	}
+}
+
+func TestStayedDown(t *testing.T) {
+	node := &Node{IP: "10.0.0.1", Port: "8088"}
+
+	// the node flaps back on the third probe
+	probes := 0
+	flapping := func(*Node) bool {
+		probes++
+		return probes == 3
+	}
+	if stayedDown(node, 50*time.Millisecond, flapping) {
+		t.Fatalf("expected a flapping node not to be considered down")
+	}
+	if probes != 3 {
+		t.Fatalf("want probing stopped at the third probe, got %d probes", probes)
+	}
+
+	probes = 0
+	down := func(*Node) bool {
+		probes++
+		return false
+	}
+	if !stayedDown(node, 50*time.Millisecond, down) {
+		t.Fatalf("expected a dead node to be considered down")
+	}
+	if probes == 0 {
+		t.Fatalf("expected the node to be probed in the grace period")
+	}
+
+	probes = 0
+	if !stayedDown(node, 0, down) || probes != 0 {
+		t.Fatalf("expected no probing without grace period")
+	}
+}
+
+func TestNodeAlive(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	ln, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			c, err := ln.AcceptTCP()
+			if err != nil {
+				return
+			}
+			loop := tcp.NewReadWriteLoop(tcp.NewConn(c), tcp.RoleServer, func(ctx context.Context, in tcp.Message, inBytes []byte) tcp.Message {
+				return tcp.Message{Opaque: in.GetOpaque(), Message: &pb.GeneralResponse{Status: pb.StatusCode_Succeed}}
+			})
+			go loop.LoopRead()
+			go loop.LoopWrite()
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	node := &Node{IP: "127.0.0.1", Port: strconv.Itoa(addr.Port)}
+	if !nodeAlive(node, 2*time.Second) {
+		t.Fatalf("expected node answering info to be alive")
+	}
+
+	ln.Close()
+	if nodeAlive(node, 2*time.Second) {
+		t.Fatalf("expected node not listening to be down")
+	}
+}
+
+func TestRouteInfo_NegativeCache(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	vars.Cfg.Gateway = &vars.GatewayConfig{Route: vars.RouteConfig{ShardGroupCap: 2, NegativeTTL: toml.Duration(time.Minute)}}
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	m := &meta{routeInfos: new(sync.Map)}
+	const metricName, day = "up", uint64(18000)
+
+	m.getRouteInfoFromCache(metricName).PutMissing(day, ErrNotEnoughShards, time.Duration(vars.Cfg.Gateway.Route.NegativeTTL))
+	if _, _, err := m.getShardIDs(metricName, day); err != ErrNotEnoughShards {
+		t.Fatalf("want the cached error, got %v", err)
+	}
+
+	m.onRouteInfoEvent(&clientv3.Event{
+		Type: mvccpb.PUT,
+		Kv: &mvccpb.KeyValue{
+			Key:   []byte(routeInfoPrefix() + metricName + "/" + strconv.FormatUint(day, 10)),
+			Value: []byte(`["shard-1","shard-2"]`),
+		},
+	})
+
+	shardGroup, _, err := m.getShardIDs(metricName, day)
+	if err != nil {
+		t.Fatalf("unexpected error after the route is put: %v", err)
+	}
+	if len(shardGroup) != 2 || shardGroup[0] != "shard-1" || shardGroup[1] != "shard-2" {
+		t.Fatalf("unexpected shard group %v", shardGroup)
+	}
+
+	routeInfo := NewRouteInfo(metricName)
+	routeInfo.PutMissing(day, ErrNotEnoughShards, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if err = routeInfo.GetMissing(day); err != nil {
+		t.Fatalf("want expired negative entry, got %v", err)
+	}
+}
+
+// fakeNodes is a nodeClient answering by its funcs, a node is alive if alive isn't set.
+type fakeNodes struct {
+	alive     func(node *Node) bool
+	promoteTo func(slave *Node) error
+}
+
+func (n fakeNodes) probe(node *Node) bool {
+	return n.alive == nil || n.alive(node)
+}
+
+func (n fakeNodes) info(*Node) (*pb.InfoResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (n fakeNodes) promote(slave *Node) error {
+	return n.promoteTo(slave)
+}
+
+// nodesStore is a MemStore whose nodes are got by its func.
+type nodesStore struct {
+	*MemStore
+	nodes func() ([]Node, error)
+}
+
+func (s nodesStore) GetNodes() ([]Node, error) {
+	return s.nodes()
+}
+
+func TestHeartbeat_Leave(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	master := Node{ShardID: "shard-1", IP: "10.0.0.1", Port: "8088", IDC: "bj"}
+	slave := func(ip string, maxT int64) Node {
+		return Node{ShardID: "shard-1", IP: ip, Port: "8088", IDC: "bj", MasterIP: master.IP, MasterPort: master.Port, MaxT: maxT}
+	}
+
+	var mtx sync.Mutex
+	registry := map[string]Node{
+		master.Addr():   master,
+		"10.0.0.2:8088": slave("10.0.0.2", 900),
+		"10.0.0.3:8088": slave("10.0.0.3", 1000),
+		"10.0.0.4:8088": {ShardID: "shard-2", IP: "10.0.0.4", Port: "8088"},
+		"10.0.0.5:8088": {ShardID: "shard-2", IP: "10.0.0.5", Port: "8088", MasterIP: "10.0.0.4", MasterPort: "8088"},
+	}
+
+	h := NewHeartbeat(time.Minute, time.Minute, func() (Node, error) {
+		return master, nil
+	})
+
+	defer SetStore(SetStore(nodesStore{MemStore: NewMemStore(), nodes: func() ([]Node, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		select {
+		case <-h.exitCh: // the key is deleted from etcd once the heartbeat stops
+			delete(registry, master.Addr())
+		default:
+		}
+
+		var nodes []Node
+		for _, node := range registry {
+			nodes = append(nodes, node)
+		}
+		return nodes, nil
+	}}))
+
+	var promoted []string
+	h.nodes = fakeNodes{promoteTo: func(slave *Node) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		promoted = append(promoted, slave.Addr())
+		node := registry[slave.Addr()]
+		node.MasterIP, node.MasterPort = "", ""
+		registry[slave.Addr()] = node
+		return nil
+	}}
+
+	if err := h.Leave(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(promoted) != 1 || promoted[0] != "10.0.0.3:8088" {
+		t.Fatalf("want the most up to date slave promoted, got %v", promoted)
+	}
+
+	m := &meta{routeInfos: new(sync.Map)}
+	if err := m.RefreshCluster(); err != nil {
+		t.Fatal(err)
+	}
+
+	for shardID, shard := range *(*map[string]*Shard)(m.shards) {
+		if shard.Master != nil && shard.Master.Addr() == master.Addr() {
+			t.Fatalf("left node is still the master of %s", shardID)
+		}
+		for _, s := range shard.Slaves {
+			if s.Addr() == master.Addr() {
+				t.Fatalf("left node is still a slave of %s", shardID)
+			}
+		}
+	}
+
+	shard, found := m.GetShard("shard-1")
+	if !found || shard.Master == nil || shard.Master.Addr() != "10.0.0.3:8088" {
+		t.Fatalf("want 10.0.0.3:8088 to be the master of shard-1, got %v", shard)
+	}
+
+	h.Stop() // stopping again on shutdown is fine
+}
+
+func TestHandOver(t *testing.T) {
+	tests := []struct {
+		errs         []error
+		wantErr      bool
+		wantAttempts int
+	}{
+		{errs: nil, wantErr: false, wantAttempts: 1},
+		{errs: []error{&pb.ResponseError{Message: "can't reach the master"}}, wantErr: false, wantAttempts: 2},
+		{errs: []error{&pb.ResponseError{Code: pb.ErrorCode_BadRequest, Message: "already an slave of some other master"}}, wantErr: true, wantAttempts: 1},
+	}
+
+	for i, test := range tests {
+		attempts := 0
+		nodes := fakeNodes{promoteTo: func(*Node) error {
+			attempts++
+			if attempts <= len(test.errs) {
+				return test.errs[attempts-1]
+			}
+			return nil
+		}}
+
+		err := handOver(nodes, &Node{IP: "10.0.0.2", Port: "8088"})
+		if (err != nil) != test.wantErr {
+			t.Fatalf("case %d: want error %v, got %v", i, test.wantErr, err)
+		}
+		if attempts != test.wantAttempts {
+			t.Fatalf("case %d: want %d attempts, got %d", i, test.wantAttempts, attempts)
+		}
+	}
+}
+
+func TestRefreshCluster_MasterChanged(t *testing.T) {
+	hooksBak := masterChangedHooks
+	defer func() {
+		masterChangedHooks = hooksBak
+	}()
+
+	nodes := []Node{
+		{ShardID: "shard-1", IP: "10.0.0.1", Port: "8088"},
+		{ShardID: "shard-1", IP: "10.0.0.2", Port: "8088", MasterIP: "10.0.0.1", MasterPort: "8088"},
+		{ShardID: "shard-2", IP: "10.0.0.3", Port: "8088"},
+	}
+	defer SetStore(SetStore(nodesStore{MemStore: NewMemStore(), nodes: func() ([]Node, error) {
+		return nodes, nil
+	}}))
+
+	var changed []string
+	masterChangedHooks = nil
+	OnMasterChanged(func(shardID string, oldMaster *Node) {
+		changed = append(changed, shardID+"@"+oldMaster.Addr())
+	})
+
+	m := &meta{routeInfos: new(sync.Map)}
+	if err := m.RefreshCluster(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RefreshCluster(); err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Fatalf("want no master changed, got %v", changed)
+	}
+
+	nodes = []Node{
+		{ShardID: "shard-1", IP: "10.0.0.2", Port: "8088"},
+		{ShardID: "shard-2", IP: "10.0.0.3", Port: "8088"},
+	}
+	if err := m.RefreshCluster(); err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 || changed[0] != "shard-1@10.0.0.1:8088" {
+		t.Fatalf("want the old master of shard-1 told, got %v", changed)
+	}
+}
+
+// fakeWatcher hands out a channel for every watch, which is closed once the watcher is broken.
+type fakeWatcher struct {
+	mtx     sync.Mutex
+	chs     []chan clientv3.WatchResponse
+	watched chan struct{}
+	closed  chan struct{}
+}
+
+func (w *fakeWatcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	ch := make(chan clientv3.WatchResponse)
+	w.chs = append(w.chs, ch)
+	if len(w.chs) == 3 {
+		close(w.watched)
+	}
+	return ch
+}
+
+func (w *fakeWatcher) Close() error {
+	close(w.closed)
+	return nil
+}
+
+func (w *fakeWatcher) broken() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	for _, ch := range w.chs {
+		close(ch)
+	}
+}
+
+func TestMeta_Rewatch(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	intervalBak := watchRetryInterval
+	defer func() {
+		watchRetryInterval = intervalBak
+	}()
+
+	var (
+		mtx      sync.Mutex
+		dials    int
+		refresh  int
+		watchers = make(chan *fakeWatcher, 2)
+	)
+
+	watchRetryInterval = time.Millisecond
+	newWatcher := func() (clientv3.Watcher, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if dials++; dials == 1 {
+			return nil, errors.New("etcd is down")
+		}
+		w := &fakeWatcher{watched: make(chan struct{}), closed: make(chan struct{})}
+		watchers <- w
+		return w, nil
+	}
+	defer SetStore(SetStore(nodesStore{MemStore: NewMemStore(), nodes: func() ([]Node, error) {
+		mtx.Lock()
+		refresh++
+		mtx.Unlock()
+		return nil, nil
+	}}))
+
+	m := &meta{routeInfos: new(sync.Map), newWatcher: newWatcher}
+	stopc := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		m.keepWatching(stopc)
+		close(done)
+	}()
+
+	wait := func(c <-chan struct{}, what string) {
+		select {
+		case <-c:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for %s", what)
+		}
+	}
+
+	first := <-watchers
+	wait(first.watched, "the first watch")
+	mtx.Lock()
+	if dials != 2 || refresh != 0 {
+		t.Fatalf("want the first watch after a failed dial without refresh, got %d dials, %d refreshes", dials, refresh)
+	}
+	mtx.Unlock()
+
+	first.broken()
+	wait(first.closed, "the broken watcher closed")
+
+	second := <-watchers
+	wait(second.watched, "the rewatch")
+	for i := 0; ; i++ {
+		mtx.Lock()
+		n := refresh
+		mtx.Unlock()
+		if n == 1 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("want the cluster refreshed once after reconnected, got %d", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(stopc)
+	wait(done, "the watch stopped")
+	wait(second.closed, "the watcher closed on stop")
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	routeLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "baudtime",
+		Subsystem: "route_cache",
+		Name:      "lookups_total",
+		Help:      "Total number of route lookups, by whether they are served by the cache (hit), the cached failure (negative_hit) or the store (miss).",
+	}, []string{"result"})
+	routeStoreErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "baudtime",
+		Subsystem: "route_cache",
+		Name:      "store_errors_total",
+		Help:      "Total number of routes failed to be loaded from or initialized in the store.",
+	})
+	routeCachedMetrics = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "baudtime",
+		Subsystem: "route_cache",
+		Name:      "metrics",
+		Help:      "Number of metrics whose routes are cached.",
+	})
+
+	routeCacheHits         = routeLookups.WithLabelValues("hit")
+	routeCacheMisses       = routeLookups.WithLabelValues("miss")
+	routeCacheNegativeHits = routeLookups.WithLabelValues("negative_hit")
+)
+
+func init() {
+	prometheus.MustRegister(routeLookups, routeStoreErrors, routeCachedMetrics)
+}
@@ -41,9 +41,15 @@ type Node struct {
 	IP         string
 	Port       string
 	DiskFree   uint64
+	DiskTotal  uint64 `json:",omitempty"` // GB, 0 if not reported.
+	Weight     int    `json:",omitempty"` // Capacity of the node relative to the others, 0 is taken as 1.
 	IDC        string
 	MasterIP   string
 	MasterPort string
+	MinT       int64  `json:",omitempty"` // The earliest timestamp stored on the node, 0 if not reported.
+	MaxT       int64  `json:",omitempty"` // The latest timestamp applied on the node, 0 if not reported. The smaller it's, the more a slave lags behind.
+	Draining   bool   `json:",omitempty"` // The node serves as usual but its shard isn't picked for the shard groups of new routes.
+	SeriesNum  uint64 `json:",omitempty"` // Series in the head of the node, a cardinality hint for estimating query costs. 0 if not reported.
 }
 
 var EmptyNode = Node{}
@@ -73,6 +79,10 @@ func (node Node) String() string {
 		s += fmt.Sprintf("\nMasterPort: %v", node.MasterPort)
 	}
 
+	if node.Draining {
+		s += "\nDraining: true"
+	}
+
 	return s
 }
 
@@ -82,7 +92,7 @@ func (node Node) MayOnline() bool {
 		return true
 	}
 
-	nodeExist, err := exist(nodePrefix() + addr)
+	nodeExist, err := store().NodeExists(addr)
 	if err != nil {
 		return true
 	}
@@ -105,32 +115,28 @@ func (nodes Nodes) Less(i, j int) bool {
 }
 
 func GetNodes(withSort bool) ([]Node, error) {
-	resp, err := etcdGetWithPrefix(nodePrefix())
-	if err == ErrKeyNotFound {
-		return nil, nil
-	}
-
+	nodes, err := store().GetNodes()
 	if err != nil {
 		return nil, err
 	}
 
-	nodes := make(Nodes, len(resp.Kvs))
-	for i, kv := range resp.Kvs {
-		var node Node
-		err = json.Unmarshal(kv.Value, &node)
-		if err != nil {
-			return nil, err
-		}
-		nodes[i] = node
-	}
-
 	if withSort {
-		sort.Sort(nodes)
+		sort.Sort(Nodes(nodes))
 	}
 
 	return nodes, nil
 }
 
+// Draining tells whether the node of addr has been drained, e.g. to restore it once the node restarts.
+func Draining(addr string) (bool, error) {
+	return store().GetDraining(addr)
+}
+
+// Drain keeps the node of addr drained across its restarts, or takes it back if draining is false.
+func Drain(addr string, draining bool) error {
+	return store().PutDraining(addr, draining)
+}
+
 func GetMasters() ([]Node, error) {
 	nodes, err := GetNodes(false)
 	if err != nil {
@@ -158,10 +164,13 @@ type Heartbeat struct {
 	interval     time.Duration
 	f            func() (Node, error)
 	lastNodeInfo Node
+	nodes        nodeClient
+	mem          *MemStore //the node is put into it rather than etcd if it's the store in use
 	registerC    chan struct{}
 	exitCh       chan struct{}
 	wg           sync.WaitGroup
 	started      uint32
+	stopOnce     sync.Once
 }
 
 func NewHeartbeat(leaseTTL time.Duration, reportInterval time.Duration, f func() (Node, error)) *Heartbeat {
@@ -169,6 +178,7 @@ func NewHeartbeat(leaseTTL time.Duration, reportInterval time.Duration, f func()
 		leaseTTL:  int64(leaseTTL.Seconds()),
 		interval:  reportInterval,
 		f:         f,
+		nodes:     tcpNodes{},
 		registerC: make(chan struct{}),
 		exitCh:    make(chan struct{}),
 	}
@@ -179,6 +189,11 @@ func (h *Heartbeat) Start() error {
 		return nil
 	}
 
+	if mem, ok := store().(*MemStore); ok {
+		h.mem = mem
+		return h.startInMem()
+	}
+
 	cli, err := clientv3.New(clientv3.Config{
 		Endpoints:   vars.Cfg.EtcdCommon.Endpoints,
 		DialTimeout: time.Duration(vars.Cfg.EtcdCommon.DialTimeout),
@@ -219,10 +234,36 @@ func (h *Heartbeat) Start() error {
 	return nil
 }
 
+// startInMem reports the node to the MemStore, where no lease is needed to keep it.
+func (h *Heartbeat) startInMem() error {
+	node, err := h.f()
+	if err != nil {
+		return errors.Wrap(err, "can't get node info")
+	}
+
+	h.reportInfo(node)
+
+	h.wg.Add(1)
+	go func() {
+		h.cronReportInfo()
+		h.wg.Done()
+	}()
+
+	return nil
+}
+
 func (h *Heartbeat) Stop() {
+	h.stopOnce.Do(h.stop)
+}
+
+func (h *Heartbeat) stop() {
 	close(h.exitCh)
 	h.wg.Wait()
 
+	if h.mem != nil {
+		h.mem.DeleteNode(h.lastNodeInfo.Addr())
+	}
+
 	if h.client != nil {
 		redo.Retry(time.Duration(vars.Cfg.EtcdCommon.RetryInterval), vars.Cfg.EtcdCommon.RetryNum, func() (bool, error) {
 			ctx, cancel := context.WithTimeout(h.client.Ctx(), time.Duration(vars.Cfg.EtcdCommon.RWTimeout))
@@ -239,6 +280,40 @@ func (h *Heartbeat) Stop() {
 	}
 }
 
+// Leave deregisters the node, if it's a master, its mastership is handed over to the most
+// up to date slave first, so that the shard doesn't wait for the lease to expire to failover.
+func (h *Heartbeat) Leave() error {
+	node, err := h.f()
+	if err != nil {
+		return errors.Wrap(err, "can't get node info")
+	}
+
+	if node.MasterIP == "" && node.MasterPort == "" && node.ShardID != "" {
+		nodes, err := GetNodes(false)
+		if err != nil {
+			return err
+		}
+
+		var slaves []*Node
+		for i := range nodes {
+			if nodes[i].ShardID == node.ShardID && nodes[i].MasterIP == node.IP && nodes[i].MasterPort == node.Port {
+				slaves = append(slaves, &nodes[i])
+			}
+		}
+
+		if len(slaves) > 0 {
+			chosen := chooseFailoverSlave(slaves, node.IDC)
+			if err = handOver(h.nodes, chosen); err != nil {
+				return errors.Wrapf(err, "can't hand mastership over to %s", chosen.Addr())
+			}
+			level.Warn(vars.Logger).Log("msg", "mastership handed over", "shard", node.ShardID, "chosen", chosen.Addr())
+		}
+	}
+
+	h.Stop()
+	return nil
+}
+
 func (h *Heartbeat) keepLease() {
 	reConnect, reGrant := false, false
 
@@ -323,6 +398,12 @@ func (h *Heartbeat) keepLease() {
 }
 
 func (h *Heartbeat) reportInfo(node Node) error {
+	if h.mem != nil {
+		h.mem.PutNode(node)
+		h.lastNodeInfo = node
+		return nil
+	}
+
 	b, err := json.Marshal(&node)
 	if err != nil {
 		return err
@@ -351,6 +432,14 @@ func (h *Heartbeat) reportInfo(node Node) error {
 	return err
 }
 
+// Report reports the node info at once rather than on the next tick, e.g. once the node is drained.
+func (h *Heartbeat) Report() {
+	select {
+	case h.registerC <- struct{}{}:
+	case <-h.exitCh:
+	}
+}
+
 func (h *Heartbeat) cronReportInfo() {
 	var (
 		node Node
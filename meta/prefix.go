@@ -17,25 +17,32 @@ package meta
 
 import "github.com/baudtime/baudtime/vars"
 
-var nodePfx, routeInfoPfx, sGrpRoutePfx string
+// The meta keys in etcd, as well as the watches over them, start with the namespace of the cluster, so that
+// clusters sharing an etcd don't see each other. The namespaces of such clusters must not be prefixed by one
+// another and '_', or the keys of one namespace fall in the range of another, e.g. "a_node_" is a prefix of the
+// node keys of namespace "a_node".
 
 func nodePrefix() string {
-	if nodePfx == "" {
-		nodePfx = vars.Cfg.NameSpace + "_node_"
-	}
-	return nodePfx
+	return vars.Cfg.NameSpace + "_node_"
 }
 
 func routeInfoPrefix() string {
-	if routeInfoPfx == "" {
-		routeInfoPfx = vars.Cfg.NameSpace + "_routeInfo_"
-	}
-	return routeInfoPfx
+	return vars.Cfg.NameSpace + "_routeInfo_"
 }
 
 func sGrpRoutePrefix() string {
-	if sGrpRoutePfx == "" {
-		sGrpRoutePfx = vars.Cfg.NameSpace + "_sGrpRouteKey_"
-	}
-	return sGrpRoutePfx
+	return vars.Cfg.NameSpace + "_sGrpRouteKey_"
 }
+
+func drainPrefix() string {
+	return vars.Cfg.NameSpace + "_drain_"
+}
+
+// failoverLock names the lock the gateways of the cluster take to fail over a shard.
+func failoverLock() string {
+	return vars.Cfg.NameSpace + "_failover"
+}
+
+// legacyFailoverLock is the lock the gateways took before the namespaced one, it's taken too so that
+// the gateways not upgraded yet and the upgraded ones don't fail over a shard at the same time.
+const legacyFailoverLock = "failover"
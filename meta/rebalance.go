@@ -0,0 +1,268 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/baudtime/baudtime/vars"
+	"github.com/cespare/xxhash"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// ErrRebalancing is returned starting a rebalance while the last one is running.
+var ErrRebalancing = errors.New("a rebalance is running")
+
+// RouteMove routes a metric to a shard group on a day, which is the same one as before if the metric
+// keeps its shards, rather than leaving the day to be routed as usual and out of balance.
+type RouteMove struct {
+	MetricName string
+	Day        uint64
+	From       []string // the shard group of the metric on the day the move is planned
+	To         []string
+}
+
+// RebalancePlan routes the metrics to balanced shard groups on the days ahead, so that the shards joined
+// after the metrics were routed take their share of them. The days begun are never moved, the series
+// being written to them stay where they are.
+type RebalancePlan struct {
+	Moves []RouteMove
+}
+
+// PlanRebalance plans the routes of the metrics on the days days from tomorrow on, of all the metrics
+// routed today if metricNames is empty. The metrics not routed today are left to be routed as usual.
+func PlanRebalance(metricNames []string, days int) (*RebalancePlan, error) {
+	return planRebalance(metricNames, day(time.Now()), days)
+}
+
+// planRebalance balances the shard groups over the undrained masters by the metrics routed today to
+// each of them, which are counted for the metrics not moved too. A metric keeps the shards of its group
+// today which aren't over their share and takes the least loaded ones for the rest, so that the same
+// routes and masters always make the same plan.
+func planRebalance(metricNames []string, today uint64, days int) (*RebalancePlan, error) {
+	if days <= 0 {
+		days = 1
+	}
+
+	masters, err := GetMasters()
+	if err != nil {
+		return nil, err
+	}
+	masters = undrained(masters)
+
+	n := vars.Cfg.Gateway.Route.ShardGroupCap
+	if len(masters) == 0 || len(masters) < n {
+		return nil, errors.Wrap(ErrNotEnoughShards, "rebalance")
+	}
+
+	shardIDs := make([]string, len(masters))
+	candidate := make(map[string]bool, len(masters))
+	for i, master := range masters {
+		shardIDs[i] = master.ShardID
+		candidate[master.ShardID] = true
+	}
+
+	routed, err := store().GetRoutedMetrics()
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string][]string, len(routed))
+	for _, metricName := range routed {
+		shardGroup, err := store().GetRoute(metricName, today)
+		if err == ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		current[metricName] = shardGroup
+	}
+
+	moving := make(map[string]bool)
+	for _, metricName := range metricNames {
+		if _, found := current[metricName]; found {
+			moving[metricName] = true
+		}
+	}
+	if len(metricNames) == 0 {
+		for metricName := range current {
+			moving[metricName] = true
+		}
+	}
+
+	share := (n*len(current) + len(shardIDs) - 1) / len(shardIDs)
+	load := make(map[string]int, len(shardIDs))
+	for metricName, shardGroup := range current {
+		if !moving[metricName] {
+			for _, shardID := range shardGroup {
+				load[shardID]++
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(moving))
+	for metricName := range moving {
+		sorted = append(sorted, metricName)
+	}
+	sort.Strings(sorted)
+
+	targets := make(map[string][]string, len(sorted))
+	for _, metricName := range sorted {
+		var kept []string
+		for _, shardID := range current[metricName] {
+			if len(kept) < n && candidate[shardID] && load[shardID] < share {
+				kept = append(kept, shardID)
+				load[shardID]++
+			}
+		}
+		targets[metricName] = kept
+	}
+
+	plan := new(RebalancePlan)
+	for _, metricName := range sorted {
+		kept := targets[metricName]
+
+		h := xxhash.Sum64String(metricName)
+		ranked := make([]string, 0, len(shardIDs))
+		for _, shardID := range shardIDs {
+			if !contains(kept, shardID) {
+				ranked = append(ranked, shardID)
+			}
+		}
+		sort.Slice(ranked, func(i, j int) bool {
+			if load[ranked[i]] != load[ranked[j]] {
+				return load[ranked[i]] < load[ranked[j]]
+			}
+			if wi, wj := rendezvousWeight(h, ranked[i]), rendezvousWeight(h, ranked[j]); wi != wj {
+				return wi > wj
+			}
+			return ranked[i] < ranked[j]
+		})
+
+		to := append(kept, ranked[:n-len(kept)]...)
+		for _, shardID := range to[len(kept):] {
+			load[shardID]++
+		}
+
+		for d := today + 1; d <= today+uint64(days); d++ {
+			plan.Moves = append(plan.Moves, RouteMove{MetricName: metricName, Day: d, From: current[metricName], To: to})
+		}
+	}
+
+	return plan, nil
+}
+
+// Apply puts the routes of the moves, calling onMove after each one. A move is applied if its day is
+// routed to its shard group, but the days routed otherwise in the meantime, e.g. by the series written
+// ahead of time, are skipped rather than moved from under the series. So a plan may be applied again.
+func (p *RebalancePlan) Apply(onMove func(move RouteMove, applied bool)) error {
+	for _, move := range p.Moves {
+		applied, err := store().PutRouteIfAbsent(move.MetricName, move.Day, move.To)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			routed, err := store().GetRoute(move.MetricName, move.Day)
+			if err != nil {
+				return err
+			}
+			applied = sameShards(routed, move.To)
+		}
+		onMove(move, applied)
+	}
+	return nil
+}
+
+// RebalanceProgress is how far the last rebalance started went.
+type RebalanceProgress struct {
+	Plan    *RebalancePlan
+	Applied int
+	Skipped int
+	Running bool
+	Err     error
+}
+
+var rebalance struct {
+	sync.Mutex
+	progress RebalanceProgress
+}
+
+// StartRebalance applies the plan in the background, whose progress is told by RebalanceStatus.
+// It fails with ErrRebalancing if the last rebalance is still running.
+func StartRebalance(plan *RebalancePlan) error {
+	rebalance.Lock()
+	defer rebalance.Unlock()
+
+	if rebalance.progress.Running {
+		return ErrRebalancing
+	}
+	rebalance.progress = RebalanceProgress{Plan: plan, Running: true}
+
+	go func() {
+		err := plan.Apply(func(move RouteMove, applied bool) {
+			rebalance.Lock()
+			if applied {
+				rebalance.progress.Applied++
+			} else {
+				rebalance.progress.Skipped++
+				level.Warn(vars.Logger).Log("msg", "the day to rebalance is routed already", "metric", move.MetricName, "day", move.Day)
+			}
+			rebalance.Unlock()
+		})
+		if err != nil {
+			level.Error(vars.Logger).Log("msg", "failed to rebalance", "err", err)
+		}
+
+		rebalance.Lock()
+		rebalance.progress.Running = false
+		rebalance.progress.Err = err
+		rebalance.Unlock()
+	}()
+
+	return nil
+}
+
+// RebalanceStatus returns the progress of the last rebalance started.
+func RebalanceStatus() RebalanceProgress {
+	rebalance.Lock()
+	defer rebalance.Unlock()
+	return rebalance.progress
+}
+
+func contains(shardIDs []string, shardID string) bool {
+	for _, id := range shardIDs {
+		if id == shardID {
+			return true
+		}
+	}
+	return false
+}
+
+// sameShards tells whether a and b are the same shards, in whatever order.
+func sameShards(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, shardID := range a {
+		if !contains(b, shardID) {
+			return false
+		}
+	}
+	return true
+}
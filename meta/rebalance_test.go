@@ -0,0 +1,181 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log"
+)
+
+func TestRebalance(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	vars.Cfg.Gateway = &vars.GatewayConfig{Route: vars.RouteConfig{ShardGroupCap: 2}}
+	vars.Logger = log.NewNopLogger()
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	mem := NewMemStore()
+	defer SetStore(SetStore(mem))
+
+	const today = uint64(18000)
+
+	// the metrics are routed to the first shards, the later ones are idle
+	metricNames := []string{"cpu", "disk", "load", "mem", "net", "up"}
+	for _, node := range []Node{
+		{ShardID: "shard-1", IP: "10.0.0.1", Port: "8088"},
+		{ShardID: "shard-2", IP: "10.0.0.2", Port: "8088"},
+	} {
+		mem.PutNode(node)
+	}
+	for _, metricName := range metricNames {
+		if err := mem.PutRoute(metricName, today, []string{"shard-1", "shard-2"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a series of up written ahead of time routes tomorrow already
+	if err := mem.PutRoute("up", today+1, []string{"shard-1", "shard-2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mem.PutNode(Node{ShardID: "shard-3", IP: "10.0.0.3", Port: "8088"})
+	mem.PutNode(Node{ShardID: "shard-4", IP: "10.0.0.4", Port: "8088"})
+
+	plan, err := planRebalance(nil, today, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Moves) != 2*len(metricNames) {
+		t.Fatalf("want every metric routed on both days, got %v", plan.Moves)
+	}
+
+	apply := func(plan *RebalancePlan) (applied, skipped int) {
+		err := plan.Apply(func(move RouteMove, ok bool) {
+			if ok {
+				applied++
+			} else {
+				skipped++
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if applied, skipped := apply(plan); skipped != 1 || applied != len(plan.Moves)-1 {
+		t.Fatalf("want the move of up tomorrow skipped only, got %d applied and %d skipped of %d", applied, skipped, len(plan.Moves))
+	}
+
+	for _, metricName := range metricNames {
+		// the series being written today stay where they are
+		if shardGroup, _ := mem.GetRoute(metricName, today); !sameShards(shardGroup, []string{"shard-1", "shard-2"}) {
+			t.Fatalf("want %s routed today as before, got %v", metricName, shardGroup)
+		}
+	}
+	if shardGroup, _ := mem.GetRoute("up", today+1); !sameShards(shardGroup, []string{"shard-1", "shard-2"}) {
+		t.Fatalf("want up routed tomorrow as before, got %v", shardGroup)
+	}
+
+	// every shard takes its share of the metrics on the days rebalanced
+	for d := today + 1; d <= today+2; d++ {
+		load := make(map[string]int)
+		for _, metricName := range metricNames {
+			shardGroup, err := mem.GetRoute(metricName, d)
+			if err != nil {
+				t.Fatalf("want %s routed on day %d, got %v", metricName, d, err)
+			}
+			if len(shardGroup) != 2 || shardGroup[0] == shardGroup[1] {
+				t.Fatalf("want 2 shards routed for %s on day %d, got %v", metricName, d, shardGroup)
+			}
+			for _, shardID := range shardGroup {
+				load[shardID]++
+			}
+		}
+		if d == today+2 {
+			for _, shardID := range []string{"shard-1", "shard-2", "shard-3", "shard-4"} {
+				if load[shardID] != 3 {
+					t.Fatalf("want 3 metrics routed to every shard on day %d, got %v", d, load)
+				}
+			}
+		}
+	}
+
+	// the plan again is the same and applied already
+	again, err := planRebalance(nil, today, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Moves) != len(plan.Moves) {
+		t.Fatalf("want the same plan again, got %v rather than %v", again.Moves, plan.Moves)
+	}
+	for i := range again.Moves {
+		if again.Moves[i].MetricName != plan.Moves[i].MetricName || again.Moves[i].Day != plan.Moves[i].Day ||
+			!sameShards(again.Moves[i].To, plan.Moves[i].To) {
+			t.Fatalf("want the same plan again, got %v rather than %v", again.Moves[i], plan.Moves[i])
+		}
+	}
+	if applied, skipped := apply(again); skipped != 1 || applied != len(again.Moves)-1 {
+		t.Fatalf("want the plan applied again as before, got %d applied and %d skipped", applied, skipped)
+	}
+
+	// the metrics given are moved only
+	plan, err = planRebalance([]string{"up", "unknown"}, today+1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, move := range plan.Moves {
+		if move.MetricName != "up" || move.Day != today+2 {
+			t.Fatalf("want up moved on day %d only, got %v", today+2, move)
+		}
+	}
+}
+
+func TestStartRebalance(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	vars.Cfg.Gateway = &vars.GatewayConfig{Route: vars.RouteConfig{ShardGroupCap: 1}}
+	vars.Logger = log.NewNopLogger()
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	mem := NewMemStore()
+	defer SetStore(SetStore(mem))
+
+	plan := &RebalancePlan{Moves: []RouteMove{
+		{MetricName: "up", Day: 18001, To: []string{"shard-2"}},
+		{MetricName: "cpu", Day: 18001, To: []string{"shard-2"}},
+	}}
+	if err := mem.PutRoute("cpu", 18001, []string{"shard-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := StartRebalance(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	progress := RebalanceStatus()
+	for progress.Running && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		progress = RebalanceStatus()
+	}
+	if progress.Running || progress.Err != nil || progress.Applied != 1 || progress.Skipped != 1 || progress.Plan != plan {
+		t.Fatalf("want the rebalance done with 1 applied and 1 skipped, got %+v", progress)
+	}
+}
@@ -16,15 +16,42 @@
 package meta
 
 import (
+	"strings"
 	"sync"
+	"time"
 )
 
+// ListMetrics returns the names of the metrics routed on any day, sorted. The names come from the route keys in
+// the store, so the metrics whose routes expired and the ones only written with a partition label, which are routed
+// by the partition rather than their names, aren't known here but to the shards.
+func ListMetrics() ([]string, error) {
+	routed, err := store().GetRoutedMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	metricNames := routed[:0]
+	for _, routeName := range routed {
+		if !strings.Contains(routeName, "=") {
+			metricNames = append(metricNames, routeName)
+		}
+	}
+	return metricNames, nil
+}
+
 type RouteInfo struct {
 	metricName string
 	Timeline   uint64
 	*sync.Map
 	ShardGrpRouteK string
 	sync.Mutex
+	missing *sync.Map //day -> missingRoute, the days whose route failed to init
+}
+
+// missingRoute is a negative cache entry of a route.
+type missingRoute struct {
+	err      error
+	expireAt time.Time
 }
 
 func NewRouteInfo(metricName string) *RouteInfo {
@@ -33,6 +60,7 @@ func NewRouteInfo(metricName string) *RouteInfo {
 		Timeline:       0,
 		Map:            new(sync.Map),
 		ShardGrpRouteK: "",
+		missing:        new(sync.Map),
 	}
 }
 
@@ -42,6 +70,7 @@ func (r *RouteInfo) Put(day uint64, v []string) {
 	}
 
 	r.Map.Store(day, v)
+	r.missing.Delete(day)
 
 	var toDelete []interface{}
 	r.Map.Range(func(day, value interface{}) bool {
@@ -65,3 +94,31 @@ func (r *RouteInfo) Get(day uint64) ([]string, bool) {
 	}
 	return nil, false
 }
+
+// PutMissing caches err as the result of looking up the route of day until ttl elapses.
+func (r *RouteInfo) PutMissing(day uint64, err error, ttl time.Duration) {
+	r.missing.Store(day, missingRoute{err: err, expireAt: time.Now().Add(ttl)})
+}
+
+// GetMissing returns the cached error of looking up the route of day, nil if there's none or it expired.
+func (r *RouteInfo) GetMissing(day uint64) error {
+	v, found := r.missing.Load(day)
+	if !found {
+		return nil
+	}
+
+	missing := v.(missingRoute)
+	if time.Now().After(missing.expireAt) {
+		r.missing.Delete(day)
+		return nil
+	}
+	return missing.err
+}
+
+// ClearMissing drops all the cached errors.
+func (r *RouteInfo) ClearMissing() {
+	r.missing.Range(func(day, _ interface{}) bool {
+		r.missing.Delete(day)
+		return true
+	})
+}
@@ -16,105 +16,208 @@
 package meta
 
 import (
+	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/util"
 	tm "github.com/baudtime/baudtime/util/time"
+	"github.com/baudtime/baudtime/vars"
 	"github.com/cespare/xxhash"
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/pkg/labels"
 )
 
+var ErrNoExactMetricName = errors.New("no exact metric name in matchers")
+
+var baseTime, _ = time.Parse("2006-01-02 15:04:05", "2019-01-01 00:00:00")
+
+// ShardRouter resolves the shards which a series is written to and the ones which a query reads from.
+// The shards of a query are nil on an error, even if only some of its routes fail to be resolved, as
+// reading from the others would silently miss the series of the failed ones.
+type ShardRouter interface {
+	GetShardIDByLabels(t time.Time, lbls []pb.Label, hash uint64) (string, error)
+	GetRouteByLabels(t time.Time, lbls []pb.Label, hash uint64) (Route, error)
+	PeekRouteByLabels(t time.Time, lbls []pb.Label, hash uint64) (Route, error)
+	GetShardIDsByTime(t time.Time, matchers ...*labels.Matcher) ([]string, error)
+	GetShardIDsByTimeSpan(from, to time.Time, matchers ...*labels.Matcher) ([]string, error)
+	GetShardIDsByMetric(matchers ...*labels.Matcher) ([]string, error)
+	GetShardIDsByHash(from, to time.Time, metricName string, hash uint64) ([]string, error)
+}
+
 var (
-	baseTime, _      = time.Parse("2006-01-02 15:04:05", "2019-01-01 00:00:00")
-	globalRouter     *router
-	globalRouterOnce sync.Once
+	globalRouter ShardRouter
+	routerMtx    sync.RWMutex
 )
 
+// Router returns the router in use, which is the one backed by the route infos in etcd unless replaced by SetRouter.
+func Router() ShardRouter {
+	routerMtx.RLock()
+	r := globalRouter
+	routerMtx.RUnlock()
+
+	if r == nil {
+		routerMtx.Lock()
+		if globalRouter == nil {
+			globalRouter = &router{meta: globalMeta}
+		}
+		r = globalRouter
+		routerMtx.Unlock()
+	}
+	return r
+}
+
+// SetRouter replaces the router in use and returns the previous one, e.g. an in memory router in tests.
+// A nil r restores the default router.
+func SetRouter(r ShardRouter) ShardRouter {
+	routerMtx.Lock()
+	prev := globalRouter
+	globalRouter = r
+	routerMtx.Unlock()
+	return prev
+}
+
 //router's responsibility is computing
 type router struct {
 	meta *meta
 }
 
-func Router() *router {
-	if globalRouter == nil {
-		globalRouterOnce.Do(func() {
-			globalRouter = &router{meta: globalMeta}
-		})
-	}
-	return globalRouter
+// Route is how a series is routed, see GetRouteByLabels.
+type Route struct {
+	ShardGroup []string
+	ShardID    string
+	RouteKey   string // the label whose value picks the shard out of the group, empty if the series hash does
+	FromCache  bool   // whether the shard group is found in the cache rather than loaded from etcd
 }
 
-//used by write
+//used by write, hash is util.HashLabels of lbls sorted by name
 func (r *router) GetShardIDByLabels(t time.Time, lbls []pb.Label, hash uint64) (string, error) {
-	var (
-		err        error
-		metricName string
-	)
+	route, err := r.GetRouteByLabels(t, lbls, hash)
+	if err != nil {
+		return "", err
+	}
+	return route.ShardID, nil
+}
+
+// GetRouteByLabels resolves the route of a series the same way as writing it does, through the same cache.
+// If no route is cached for the metric at t, a shard group is loaded or initialized in etcd.
+func (r *router) GetRouteByLabels(t time.Time, lbls []pb.Label, hash uint64) (Route, error) {
+	return r.routeByLabels(t, lbls, hash, r.meta.lookupShardIDs)
+}
 
+// PeekRouteByLabels is GetRouteByLabels without initializing the route nor caching it, e.g. to tell where
+// a series would be written to. ErrNotRouted is returned if the route of the metric at t isn't initialized.
+func (r *router) PeekRouteByLabels(t time.Time, lbls []pb.Label, hash uint64) (Route, error) {
+	return r.routeByLabels(t, lbls, hash, r.meta.peekShardIDs)
+}
+
+func (r *router) routeByLabels(t time.Time, lbls []pb.Label, hash uint64, lookup func(routeName string, day uint64) ([]string, string, bool, error)) (Route, error) {
+	var metricName, partition string
+
+	partitionLabel := partitionLabel()
 	for _, l := range lbls {
-		if l.Name == labels.MetricName {
+		switch l.Name {
+		case labels.MetricName:
 			metricName = l.Value
+		case partitionLabel:
+			partition = l.Value
 		}
 	}
 	if metricName == "" {
-		return "", errors.New("metric name not found in labels")
+		return Route{}, errors.New("metric name not found in labels")
 	}
 
-	shardGroup, shardGrpRouteK, err := r.meta.getShardIDs(metricName, day(t))
+	routeName := metricName
+	if partition != "" {
+		routeName = partitionRouteName(partitionLabel, partition)
+	}
+
+	shardGroup, shardGrpRouteK, fromCache, err := lookup(routeName, day(t))
 	if err != nil {
-		return "", err
+		return Route{}, err
 	}
 
+	route := Route{ShardGroup: shardGroup, FromCache: fromCache}
+
 	if shardGrpRouteK != "" && len(shardGroup) > 0 {
 		for _, l := range lbls {
 			if l.Name == shardGrpRouteK {
-				idx := xxhash.Sum64String(l.Value) % uint64(len(shardGroup))
-				return shardGroup[idx], nil
+				route.ShardID = pickShard(shardGroup, xxhash.Sum64String(l.Value))
+				route.RouteKey = shardGrpRouteK
+				return route, nil
 			}
 		}
 	}
 
-	idx := hash % uint64(len(shardGroup))
-	return shardGroup[idx], nil
+	route.ShardID = pickShard(shardGroup, hash)
+	return route, nil
 }
 
+//used by query, returns the shard group of the metric on the day of t. A query is only scoped by exact metric names,
+//i.e. an equal matcher or a regex of literals like a|b|c, whose shard groups are united. However negative the other
+//matchers are, all the shards are returned if the name is unconstrained, negated or a true regex.
+//If a partition label is configured, the query is scoped by exact values of it instead, see queryRouteNames.
 func (r *router) GetShardIDsByTime(t time.Time, matchers ...*labels.Matcher) ([]string, error) {
-	var (
-		err        error
-		metricName string
-	)
+	routeNames := queryRouteNames(matchers)
+	if len(routeNames) == 0 {
+		return r.allShardIDs(), nil
+	}
+	if len(routeNames) == 1 {
+		return r.getShardIDsByTime(t, routeNames[0], matchers)
+	}
 
-	for _, m := range matchers {
-		if m.Name == labels.MetricName {
-			metricName = m.Value
+	var multiErr error
+	idSet := make(map[string]struct{})
+
+	for _, routeName := range routeNames {
+		if ids, err := r.getShardIDsByTime(t, routeName, matchers); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		} else {
+			for _, id := range ids {
+				idSet[id] = struct{}{}
+			}
 		}
 	}
-	if metricName == "" {
-		return nil, errors.New("metric name not found in matchers")
+
+	if multiErr != nil {
+		return nil, multiErr
+	}
+
+	ids := make([]string, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
 	}
 
-	shardGroup, shardGrpRouteK, err := r.meta.getShardIDs(metricName, day(t))
+	return ids, nil
+}
+
+// getShardIDsByTime returns the shard group of a route on the day of t, or only the shards of it the values
+// of its route key the matchers are scoped by are picked to.
+func (r *router) getShardIDsByTime(t time.Time, routeName string, matchers []*labels.Matcher) ([]string, error) {
+	shardGroup, shardGrpRouteK, err := r.meta.getShardIDs(routeName, day(t))
 	if err != nil {
 		return nil, err
 	}
 
 	if shardGrpRouteK != "" && len(shardGroup) > 0 {
-		for _, m := range matchers {
-			if m.Name == shardGrpRouteK && m.Type == labels.MatchEqual {
-				idx := xxhash.Sum64String(m.Value) % uint64(len(shardGroup))
-				return []string{shardGroup[idx]}, nil
-			}
+		if values, found := exactValues(matchers, shardGrpRouteK); found {
+			return pickShards(shardGroup, values), nil
 		}
 	}
 
 	return shardGroup, nil
 }
 
-//used by query
+//used by query, returns the union of the shard groups of every day in [from, to],
+//as the metric may be routed to other shards on another day.
 func (r *router) GetShardIDsByTimeSpan(from, to time.Time, matchers ...*labels.Matcher) ([]string, error) {
+	if len(queryRouteNames(matchers)) == 0 {
+		return r.allShardIDs(), nil
+	}
+
 	var multiErr error
 	idSet := make(map[string]struct{})
 
@@ -136,12 +239,169 @@ func (r *router) GetShardIDsByTimeSpan(from, to time.Time, matchers ...*labels.M
 		}
 	}
 
+	if multiErr != nil {
+		return nil, multiErr
+	}
+
 	ids := make([]string, 0, len(idSet))
 	for id := range idSet {
 		ids = append(ids, id)
 	}
 
-	return ids, multiErr
+	return ids, nil
+}
+
+//used by select by hash, returns the shard which the series of hash is written to on every day in [from, to],
+//hash is util.HashLabels of its labels sorted by name. The whole shard group of a day is returned if the metric
+//is routed by a label, whose value isn't known from the hash. So are all the shards if a partition label is configured.
+func (r *router) GetShardIDsByHash(from, to time.Time, metricName string, hash uint64) ([]string, error) {
+	if partitionLabel() != "" {
+		return r.allShardIDs(), nil
+	}
+
+	var multiErr error
+	idSet := make(map[string]struct{})
+
+	add := func(t time.Time) {
+		shardGroup, shardGrpRouteK, err := r.meta.getShardIDs(metricName, day(t))
+		if err != nil {
+			multiErr = multierror.Append(multiErr, err)
+			return
+		}
+		if shardGrpRouteK == "" && len(shardGroup) > 0 {
+			idSet[pickShard(shardGroup, hash)] = struct{}{}
+			return
+		}
+		for _, id := range shardGroup {
+			idSet[id] = struct{}{}
+		}
+	}
+
+	for t := from; t.Before(to); t = t.Add(24 * time.Hour) {
+		add(t)
+	}
+	add(to)
+
+	if multiErr != nil {
+		return nil, multiErr
+	}
+
+	ids := make([]string, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+//used by label values, returns the shards which the metric has ever been routed to.
+//it only resolves exact metric names, ErrNoExactMetricName is returned for an unconstrained or true regex one.
+func (r *router) GetShardIDsByMetric(matchers ...*labels.Matcher) ([]string, error) {
+	routeNames := queryRouteNames(matchers)
+	if len(routeNames) == 0 {
+		return nil, ErrNoExactMetricName
+	}
+
+	idSet := make(map[string]struct{})
+	for _, routeName := range routeNames {
+		shardGroups, shardGrpRouteK, err := r.meta.getAllShardIDs(routeName)
+		if err != nil {
+			return nil, err
+		}
+
+		var routeValues []string
+		if shardGrpRouteK != "" {
+			routeValues, _ = exactValues(matchers, shardGrpRouteK)
+		}
+
+		for _, shardGroup := range shardGroups {
+			ids := shardGroup
+			if len(routeValues) > 0 && len(shardGroup) > 0 {
+				ids = pickShards(shardGroup, routeValues)
+			}
+			for _, id := range ids {
+				idSet[id] = struct{}{}
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// queryRouteNames returns the names of the routes a query is scoped by, none if it may read from any shard.
+// If a partition label is configured, the series having it are routed by its value whatever their metric is,
+// so a query is scoped by exact values of it, or by exact metric names only if it asks for the series
+// without the label, e.g. {__name__="up", tenant=""}.
+// The routes of the metric names aren't looked up along with the ones of a partition, so the label is only to be
+// configured for a new cluster, setting it on an existing one hides the series written before from the queries.
+func queryRouteNames(matchers []*labels.Matcher) []string {
+	if partitionLabel := partitionLabel(); partitionLabel != "" {
+		switch partitions, found := exactValues(matchers, partitionLabel); {
+		case !found:
+			return nil
+		case len(partitions) > 1 || partitions[0] != "":
+			routeNames := make([]string, len(partitions))
+			for i, partition := range partitions {
+				routeNames[i] = partitionRouteName(partitionLabel, partition)
+			}
+			return routeNames
+		}
+	}
+
+	metricNames, _ := exactValues(matchers, labels.MetricName)
+	return metricNames
+}
+
+// exactValues returns the values a label is positively constrained to by an equal matcher, or a regex matcher
+// of an alternation of literals like a|b|c, see util.RegexLiterals. found is false if there isn't such a matcher.
+// Only a regex never matching the empty value is taken, so that the values are never empty but an equal one.
+func exactValues(matchers []*labels.Matcher, name string) (values []string, found bool) {
+	for _, m := range matchers {
+		// the metric name is never empty, __name__="" constrains nothing
+		if m.Name == name && m.Type == labels.MatchEqual && (m.Value != "" || name != labels.MetricName) {
+			return []string{m.Value}, true
+		}
+	}
+	for _, m := range matchers {
+		if m.Name == name && m.Type == labels.MatchRegexp {
+			if values, found = util.RegexLiterals(m.Value); found {
+				return values, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func partitionLabel() string {
+	if vars.Cfg.Gateway == nil {
+		return ""
+	}
+	return vars.Cfg.Gateway.Route.PartitionLabel
+}
+
+// partitionRouteName is the name the series of a partition are routed by in place of their metric names.
+// It never collides with a metric name, which can't contain a '='. The value is escaped to fit in an etcd key.
+func partitionRouteName(partitionLabel, partition string) string {
+	return partitionLabel + "=" + url.QueryEscape(partition)
+}
+
+func (r *router) allShardIDs() []string {
+	p := atomic.LoadPointer(&r.meta.shards)
+	if p == nil {
+		return nil
+	}
+
+	shards := *(*map[string]*Shard)(p)
+	ids := make([]string, 0, len(shards))
+	for id := range shards {
+		ids = append(ids, id)
+	}
+	return ids
 }
 
 func day(t time.Time) uint64 {
@@ -0,0 +1,546 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/util"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/cespare/xxhash"
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+func TestRouter_GetShardIDsByMetricNotExact(t *testing.T) {
+	tests := [][]*labels.Matcher{
+		nil,
+		{mustNewMatcher(labels.MatchEqual, "job", "node")},
+		{mustNewMatcher(labels.MatchRegexp, labels.MetricName, "node_.*")},
+		{mustNewMatcher(labels.MatchNotEqual, labels.MetricName, "up")},
+	}
+
+	for _, matchers := range tests {
+		if _, err := Router().GetShardIDsByMetric(matchers...); err != ErrNoExactMetricName {
+			t.Fatalf("matchers %v: want %v, got %v", matchers, ErrNoExactMetricName, err)
+		}
+	}
+}
+
+func TestRouter_GetRouteByLabels(t *testing.T) {
+	r := &router{meta: &meta{routeInfos: new(sync.Map)}}
+	now := time.Now()
+	shardGroup := []string{"shard-1", "shard-2", "shard-3"}
+
+	r.meta.getRouteInfoFromCache("up").Put(day(now), shardGroup)
+	routeInfo := r.meta.getRouteInfoFromCache("node_load1")
+	routeInfo.ShardGrpRouteK = "instance"
+	routeInfo.Put(day(now), shardGroup)
+
+	tests := []struct {
+		lbls      []pb.Label
+		hash      uint64
+		wantShard string
+		wantKey   string
+	}{
+		{
+			lbls:      []pb.Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: "10.0.0.1"}},
+			hash:      4,
+			wantShard: "shard-2",
+		},
+		{
+			lbls:      []pb.Label{{Name: "__name__", Value: "node_load1"}, {Name: "instance", Value: "10.0.0.1"}},
+			hash:      4,
+			wantShard: pickShard(shardGroup, xxhash.Sum64String("10.0.0.1")),
+			wantKey:   "instance",
+		},
+		{
+			lbls:      []pb.Label{{Name: "__name__", Value: "node_load1"}, {Name: "job", Value: "node"}},
+			hash:      5,
+			wantShard: "shard-3",
+		},
+	}
+
+	for _, test := range tests {
+		route, err := r.GetRouteByLabels(now, test.lbls, test.hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if route.ShardID != test.wantShard || route.RouteKey != test.wantKey || !route.FromCache || len(route.ShardGroup) != len(shardGroup) {
+			t.Fatalf("labels %v: unexpected route %+v", test.lbls, route)
+		}
+
+		shardID, err := r.GetShardIDByLabels(now, test.lbls, test.hash)
+		if err != nil || shardID != route.ShardID {
+			t.Fatalf("labels %v: written to %s, but routed to %s", test.lbls, shardID, route.ShardID)
+		}
+	}
+
+	if _, err := r.GetRouteByLabels(now, []pb.Label{{Name: "job", Value: "node"}}, 0); err == nil {
+		t.Fatalf("want error of labels without metric name")
+	}
+}
+
+func TestRouter_PeekRouteByLabels(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	vars.Cfg.Gateway = &vars.GatewayConfig{Route: vars.RouteConfig{ShardGroupCap: 1}}
+	vars.Logger = log.NewNopLogger()
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+	mem := NewMemStore()
+	mem.PutNode(Node{ShardID: "shard-1", IP: "10.0.0.1", Port: "8088"})
+	defer SetStore(SetStore(mem))
+
+	r := &router{meta: &meta{routeInfos: new(sync.Map)}}
+	now := baseTime.Add(100 * 24 * time.Hour)
+	lbls := []pb.Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: "10.0.0.1"}}
+
+	// not routed yet, it's left so
+	if _, err := r.PeekRouteByLabels(now, lbls, 0); errors.Cause(err) != ErrNotRouted {
+		t.Fatalf("want %v, got %v", ErrNotRouted, err)
+	}
+	if _, err := mem.GetRoute("up", day(now)); err != ErrKeyNotFound {
+		t.Fatalf("want the route not initialized, got %v", err)
+	}
+
+	// routed, it's loaded from the store but not cached
+	if err := mem.PutRoute("up", day(now), []string{"shard-1"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		route, err := r.PeekRouteByLabels(now, lbls, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if route.ShardID != "shard-1" || route.FromCache {
+			t.Fatalf("unexpected route %+v", route)
+		}
+	}
+}
+
+func TestRouter_GetShardIDsByTimeSpan(t *testing.T) {
+	r := &router{meta: &meta{routeInfos: new(sync.Map)}}
+
+	day1 := baseTime.Add(100 * 24 * time.Hour)
+	day2, day3 := day1.Add(24*time.Hour), day1.Add(48*time.Hour)
+
+	// the metric is reassigned every day
+	routeInfo := r.meta.getRouteInfoFromCache("up")
+	routeInfo.Put(day(day1), []string{"shard-1", "shard-2"})
+	routeInfo.Put(day(day2), []string{"shard-3", "shard-4"})
+	routeInfo.Put(day(day3), []string{"shard-2", "shard-5"})
+
+	tests := []struct {
+		from, to time.Time
+		want     []string
+	}{
+		{from: day1.Add(time.Hour), to: day1.Add(2 * time.Hour), want: []string{"shard-1", "shard-2"}},
+		{from: day1.Add(23 * time.Hour), to: day2.Add(time.Hour), want: []string{"shard-1", "shard-2", "shard-3", "shard-4"}},
+		{from: day1.Add(23*time.Hour + 59*time.Minute), to: day3, want: []string{"shard-1", "shard-2", "shard-3", "shard-4", "shard-5"}},
+		{from: day2, to: day3.Add(-time.Millisecond), want: []string{"shard-3", "shard-4"}},
+	}
+
+	for i, test := range tests {
+		ids, err := r.GetShardIDsByTimeSpan(test.from, test.to, mustNewMatcher(labels.MatchEqual, labels.MetricName, "up"))
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		sort.Strings(ids)
+		if strings.Join(ids, ",") != strings.Join(test.want, ",") {
+			t.Fatalf("case %d: want the shards %v of every day in the span, got %v", i, test.want, ids)
+		}
+	}
+}
+
+func TestRouter_GetShardIDsByTimeSpanNegativeMatchers(t *testing.T) {
+	r := &router{meta: &meta{routeInfos: new(sync.Map)}}
+	shards := map[string]*Shard{"shard-1": {}, "shard-2": {}, "shard-3": {}, "shard-4": {}}
+	atomic.StorePointer(&r.meta.shards, unsafe.Pointer(&shards))
+
+	now := baseTime.Add(100 * 24 * time.Hour)
+	r.meta.getRouteInfoFromCache("m").Put(day(now), []string{"shard-1", "shard-2"})
+
+	tests := []struct {
+		matchers []*labels.Matcher
+		want     []string
+	}{
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchEqual, labels.MetricName, "m"), mustNewMatcher(labels.MatchNotEqual, "job", "x")},
+			want:     []string{"shard-1", "shard-2"},
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchNotRegexp, "job", "x|y"), mustNewMatcher(labels.MatchEqual, labels.MetricName, "m")},
+			want:     []string{"shard-1", "shard-2"},
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchNotEqual, "job", "x")},
+			want:     []string{"shard-1", "shard-2", "shard-3", "shard-4"},
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchNotEqual, labels.MetricName, "m"), mustNewMatcher(labels.MatchEqual, "job", "x")},
+			want:     []string{"shard-1", "shard-2", "shard-3", "shard-4"},
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchRegexp, labels.MetricName, "m|n.*")},
+			want:     []string{"shard-1", "shard-2", "shard-3", "shard-4"},
+		},
+		// an empty name constrains nothing, it doesn't hide the regex of the name either
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchEqual, labels.MetricName, ""), mustNewMatcher(labels.MatchEqual, "job", "x")},
+			want:     []string{"shard-1", "shard-2", "shard-3", "shard-4"},
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchEqual, labels.MetricName, ""), mustNewMatcher(labels.MatchRegexp, labels.MetricName, "m")},
+			want:     []string{"shard-1", "shard-2"},
+		},
+	}
+
+	for i, test := range tests {
+		ids, err := r.GetShardIDsByTimeSpan(now, now.Add(time.Hour), test.matchers...)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		sort.Strings(ids)
+		if strings.Join(ids, ",") != strings.Join(test.want, ",") {
+			t.Fatalf("case %d: matchers %v want the shards %v, got %v", i, test.matchers, test.want, ids)
+		}
+	}
+}
+
+func TestRouter_RegexAlternation(t *testing.T) {
+	r := &router{meta: &meta{routeInfos: new(sync.Map)}}
+	shards := map[string]*Shard{"shard-1": {}, "shard-2": {}, "shard-3": {}, "shard-4": {}, "shard-5": {}, "shard-6": {}, "shard-7": {}}
+	atomic.StorePointer(&r.meta.shards, unsafe.Pointer(&shards))
+
+	now := baseTime.Add(100 * 24 * time.Hour)
+	r.meta.getRouteInfoFromCache("m").Put(day(now), []string{"shard-1", "shard-2"})
+	r.meta.getRouteInfoFromCache("n").Put(day(now), []string{"shard-3", "shard-4"})
+	k := r.meta.getRouteInfoFromCache("k")
+	k.ShardGrpRouteK = "job"
+	k.Put(day(now), []string{"shard-5", "shard-6", "shard-7"})
+
+	// the shards the jobs of k are picked to, one by one
+	jobShards := func(jobs ...string) []string {
+		var ids []string
+		for _, job := range jobs {
+			picked, err := r.GetShardIDsByTime(now, mustNewMatcher(labels.MatchEqual, labels.MetricName, "k"), mustNewMatcher(labels.MatchEqual, "job", job))
+			if err != nil || len(picked) != 1 {
+				t.Fatalf("job %s: want a shard, got %v, %v", job, picked, err)
+			}
+			if !contains(ids, picked[0]) {
+				ids = append(ids, picked[0])
+			}
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	all := []string{"shard-1", "shard-2", "shard-3", "shard-4", "shard-5", "shard-6", "shard-7"}
+	tests := []struct {
+		matchers []*labels.Matcher
+		want     []string
+	}{
+		// alternations of literals are routed to the union of the shard groups of them
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchRegexp, labels.MetricName, "m|n")},
+			want:     []string{"shard-1", "shard-2", "shard-3", "shard-4"},
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchRegexp, labels.MetricName, "(m|n|m)"), mustNewMatcher(labels.MatchNotEqual, "job", "x")},
+			want:     []string{"shard-1", "shard-2", "shard-3", "shard-4"},
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchRegexp, labels.MetricName, "(?:m)")},
+			want:     []string{"shard-1", "shard-2"},
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchEqual, labels.MetricName, "k"), mustNewMatcher(labels.MatchRegexp, "job", "a|b|c")},
+			want:     jobShards("a", "b", "c"),
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchRegexp, labels.MetricName, "k|m"), mustNewMatcher(labels.MatchRegexp, "job", "a")},
+			want:     append([]string{"shard-1", "shard-2"}, jobShards("a")...),
+		},
+		// an equal matcher scopes a regex one of the same label
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchRegexp, labels.MetricName, "m.*"), mustNewMatcher(labels.MatchEqual, labels.MetricName, "n")},
+			want:     []string{"shard-3", "shard-4"},
+		},
+		// true regexes may match any name or route key value
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchRegexp, labels.MetricName, "m.*")},
+			want:     all,
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchRegexp, labels.MetricName, "m|n|")},
+			want:     all,
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchRegexp, labels.MetricName, "(?i)m")},
+			want:     all,
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchEqual, labels.MetricName, "k"), mustNewMatcher(labels.MatchRegexp, "job", "a|b.+")},
+			want:     []string{"shard-5", "shard-6", "shard-7"},
+		},
+	}
+
+	for i, test := range tests {
+		for _, ids := range [][]string{mustShardIDs(r.GetShardIDsByTime(now, test.matchers...)), mustShardIDs(r.GetShardIDsByTimeSpan(now, now.Add(time.Hour), test.matchers...))} {
+			sort.Strings(ids)
+			sort.Strings(test.want)
+			if strings.Join(ids, ",") != strings.Join(test.want, ",") {
+				t.Fatalf("case %d: matchers %v want the shards %v, got %v", i, test.matchers, test.want, ids)
+			}
+		}
+	}
+}
+
+func TestRouter_GetShardIDsByTimePartialError(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	vars.Cfg.Gateway = &vars.GatewayConfig{Route: vars.RouteConfig{ShardGroupCap: 2}}
+	vars.Logger = log.NewNopLogger()
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+	defer SetStore(SetStore(NewMemStore()))
+
+	r := &router{meta: &meta{routeInfos: new(sync.Map)}}
+	now := baseTime.Add(100 * 24 * time.Hour)
+	r.meta.getRouteInfoFromCache("m").Put(day(now), []string{"shard-1", "shard-2"})
+
+	// n can't be routed without any shard, m alone would miss its series
+	matchers := []*labels.Matcher{mustNewMatcher(labels.MatchRegexp, labels.MetricName, "m|n")}
+	if ids, err := r.GetShardIDsByTime(now, matchers...); err == nil || ids != nil {
+		t.Fatalf("want no shards but an error, got %v, %v", ids, err)
+	}
+	if ids, err := r.GetShardIDsByTimeSpan(now, now.Add(time.Hour), matchers...); err == nil || ids != nil {
+		t.Fatalf("want no shards but an error, got %v, %v", ids, err)
+	}
+}
+
+func mustShardIDs(ids []string, err error) []string {
+	if err != nil {
+		panic(err)
+	}
+	return ids
+}
+
+func TestRouter_PartitionLabel(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	vars.Cfg.Gateway = &vars.GatewayConfig{Route: vars.RouteConfig{ShardGroupCap: 2, PartitionLabel: "tenant"}}
+	vars.Logger = log.NewNopLogger()
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	mem := NewMemStore()
+	defer SetStore(SetStore(mem))
+
+	shards := make(map[string]*Shard)
+	for i := 1; i <= 8; i++ {
+		node := Node{ShardID: fmt.Sprintf("shard-%d", i), IP: fmt.Sprintf("10.0.0.%d", i), Port: "8088"}
+		mem.PutNode(node)
+		shards[node.ShardID] = &Shard{Master: &node}
+	}
+	r := &router{meta: &meta{routeInfos: new(sync.Map)}}
+	atomic.StorePointer(&r.meta.shards, unsafe.Pointer(&shards))
+
+	now := baseTime.Add(100 * 24 * time.Hour)
+	groupOf := func(lbls ...string) []string {
+		route, err := r.GetRouteByLabels(now, util.LabelsToProto(labels.FromStrings(lbls...)), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return route.ShardGroup
+	}
+
+	// the series of a tenant stay together whatever their metrics are
+	group := groupOf("__name__", "cpu", "tenant", "a/b")
+	if other := groupOf("__name__", "mem", "tenant", "a/b", "instance", "10.0.0.1"); !sameShards(group, other) {
+		t.Fatalf("want the metrics of a tenant on the same shard group, got %v and %v", group, other)
+	}
+	if shardGroup, err := mem.GetRoute(partitionRouteName("tenant", "a/b"), day(now)); err != nil || !sameShards(shardGroup, group) {
+		t.Fatalf("want the shard group of the tenant in the store, got %v, %v", shardGroup, err)
+	}
+	// the ones without the label are routed by their metric names
+	if _, err := mem.GetRoute("mem", day(now)); err != ErrKeyNotFound {
+		t.Fatalf("want no route of the metric of a tenant, got %v", err)
+	}
+	untenanted := groupOf("__name__", "mem", "instance", "10.0.0.1")
+	if shardGroup, err := mem.GetRoute("mem", day(now)); err != nil || !sameShards(shardGroup, untenanted) {
+		t.Fatalf("want the series without a tenant routed by the metric, got %v, %v", shardGroup, err)
+	}
+
+	union := func(groups ...[]string) []string {
+		var ids []string
+		for _, group := range groups {
+			for _, id := range group {
+				if !contains(ids, id) {
+					ids = append(ids, id)
+				}
+			}
+		}
+		return ids
+	}
+
+	tests := []struct {
+		matchers []*labels.Matcher
+		want     []string
+	}{
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchEqual, labels.MetricName, "mem"), mustNewMatcher(labels.MatchEqual, "tenant", "a/b")},
+			want:     group,
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchEqual, "tenant", "a/b")},
+			want:     group,
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchEqual, labels.MetricName, "mem"), mustNewMatcher(labels.MatchEqual, "tenant", "")},
+			want:     untenanted,
+		},
+		// the series of the metric may be of any tenant
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchEqual, labels.MetricName, "mem")},
+			want:     r.allShardIDs(),
+		},
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchEqual, labels.MetricName, "mem"), mustNewMatcher(labels.MatchRegexp, "tenant", "a/.*")},
+			want:     r.allShardIDs(),
+		},
+		// a set of tenants
+		{
+			matchers: []*labels.Matcher{mustNewMatcher(labels.MatchEqual, labels.MetricName, "mem"), mustNewMatcher(labels.MatchRegexp, "tenant", "a/b|c")},
+			want:     union(group, groupOf("__name__", "cpu", "tenant", "c")),
+		},
+	}
+
+	for i, test := range tests {
+		ids, err := r.GetShardIDsByTimeSpan(now, now.Add(time.Hour), test.matchers...)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if !sameShards(ids, test.want) {
+			t.Fatalf("case %d: matchers %v want the shards %v, got %v", i, test.matchers, test.want, ids)
+		}
+	}
+}
+
+func TestRouter_GetShardIDsByHash(t *testing.T) {
+	r := &router{meta: &meta{routeInfos: new(sync.Map)}}
+
+	day1 := baseTime.Add(100 * 24 * time.Hour)
+	day2 := day1.Add(24 * time.Hour)
+	group1, group2 := []string{"shard-1", "shard-2", "shard-3"}, []string{"shard-4", "shard-5"}
+
+	routeInfo := r.meta.getRouteInfoFromCache("up")
+	routeInfo.Put(day(day1), group1)
+	routeInfo.Put(day(day2), group2)
+	routeInfo = r.meta.getRouteInfoFromCache("node_load1")
+	routeInfo.ShardGrpRouteK = "instance"
+	routeInfo.Put(day(day1), group1)
+
+	lbls := []pb.Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: "10.0.0.1"}}
+	hash := uint64(7)
+	written1, err := r.GetShardIDByLabels(day1.Add(time.Hour), lbls, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written2, err := r.GetShardIDByLabels(day2.Add(time.Hour), lbls, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		metric   string
+		from, to time.Time
+		want     []string
+	}{
+		{metric: "up", from: day1.Add(time.Hour), to: day1.Add(2 * time.Hour), want: []string{written1}},
+		{metric: "up", from: day1.Add(time.Hour), to: day2.Add(time.Hour), want: []string{written1, written2}},
+		// the value of the route key isn't known from the hash
+		{metric: "node_load1", from: day1.Add(time.Hour), to: day1.Add(2 * time.Hour), want: group1},
+	}
+
+	for i, test := range tests {
+		ids, err := r.GetShardIDsByHash(test.from, test.to, test.metric, hash)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		sort.Strings(ids)
+		if strings.Join(ids, ",") != strings.Join(test.want, ",") {
+			t.Fatalf("case %d: want the shards %v written to, got %v", i, test.want, ids)
+		}
+	}
+}
+
+// staticRouter routes everything to one shard.
+type staticRouter struct {
+	ShardRouter
+	shardID string
+}
+
+func (r staticRouter) GetShardIDByLabels(time.Time, []pb.Label, uint64) (string, error) {
+	return r.shardID, nil
+}
+
+func TestSetRouter(t *testing.T) {
+	defer SetRouter(SetRouter(staticRouter{shardID: "shard-1"}))
+
+	lbls := []pb.Label{{Name: "__name__", Value: "up"}}
+	if id, err := Router().GetShardIDByLabels(time.Now(), lbls, 0); err != nil || id != "shard-1" {
+		t.Fatalf("want shard-1 of the router set, got %s, %v", id, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if j%10 == i {
+					SetRouter(staticRouter{shardID: "shard-1"})
+				}
+				Router().GetShardIDByLabels(time.Now(), lbls, 0)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	SetRouter(nil)
+	if _, ok := Router().(*router); !ok {
+		t.Fatalf("want the default router restored, got %T", Router())
+	}
+}
+
+func mustNewMatcher(t labels.MatchType, n, v string) *labels.Matcher {
+	m, err := labels.NewMatcher(t, n, v)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
@@ -0,0 +1,409 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baudtime/baudtime/vars"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/pkg/errors"
+)
+
+// Store keeps the meta data shared by the nodes, the nodes registered and the routes of the metrics.
+// It's etcd by default, see vars.Config.MetaStore.
+type Store interface {
+	// GetNodes returns the nodes registered.
+	GetNodes() ([]Node, error)
+	// NodeExists reports whether the node of addr is registered.
+	NodeExists(addr string) (bool, error)
+	// GetRoute returns the shard group of the metric on the day, ErrKeyNotFound if it's not initialized.
+	GetRoute(metricName string, day uint64) ([]string, error)
+	// PutRoute initializes the shard group of the metric on the day.
+	PutRoute(metricName string, day uint64, shardGroup []string) error
+	// PutRouteIfAbsent is PutRoute unless the shard group of the metric on the day is initialized, it tells whether it's put.
+	PutRouteIfAbsent(metricName string, day uint64, shardGroup []string) (bool, error)
+	// GetRoutes returns the shard groups of every day on which the metric has been routed.
+	GetRoutes(metricName string) ([][]string, error)
+	// GetRoutedMetrics returns the metrics routed on any day.
+	GetRoutedMetrics() ([]string, error)
+	// GetRouteKey returns the label whose value picks the shard out of the group of the metric, empty if none.
+	GetRouteKey(metricName string) (string, error)
+	// GetDraining tells whether the node of addr is drained, which outlives the registration of the node.
+	GetDraining(addr string) (bool, error)
+	// PutDraining drains the node of addr, or takes it back if draining is false.
+	PutDraining(addr string, draining bool) error
+	// Ping tells whether the store is reachable, without retrying.
+	Ping() error
+}
+
+var (
+	globalStore Store
+	storeMtx    sync.Mutex
+)
+
+// store returns the store in use, which is chosen by vars.Config.MetaStore unless replaced by SetStore.
+func store() Store {
+	storeMtx.Lock()
+	defer storeMtx.Unlock()
+
+	if globalStore == nil {
+		if vars.Cfg.MetaStore == "memory" {
+			globalStore = NewMemStore()
+		} else {
+			globalStore = etcdStore{}
+		}
+	}
+	return globalStore
+}
+
+// SetStore replaces the store in use and returns the previous one, e.g. a MemStore in tests.
+// A nil s restores the one chosen by the config.
+func SetStore(s Store) Store {
+	storeMtx.Lock()
+	prev := globalStore
+	globalStore = s
+	storeMtx.Unlock()
+	return prev
+}
+
+// etcdStore implements Store, the routes initialized are expired after vars.RouteConfig.RouteInfoTTL.
+type etcdStore struct{}
+
+func (etcdStore) GetNodes() ([]Node, error) {
+	resp, err := etcdGetWithPrefix(nodePrefix())
+	if err == ErrKeyNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]Node, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		var node Node
+		err = json.Unmarshal(kv.Value, &node)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = node
+	}
+
+	return nodes, nil
+}
+
+func (etcdStore) NodeExists(addr string) (bool, error) {
+	return exist(nodePrefix() + addr)
+}
+
+func (etcdStore) GetRoute(metricName string, day uint64) ([]string, error) {
+	shardGroup := make([]string, 0, vars.Cfg.Gateway.Route.ShardGroupCap)
+
+	err := etcdGet(routeInfoPrefix()+metricName+"/"+strconv.FormatUint(day, 10), &shardGroup)
+	if err != nil {
+		return nil, err
+	}
+	return shardGroup, nil
+}
+
+func (etcdStore) PutRoute(metricName string, day uint64, shardGroup []string) error {
+	leaseID, err := getEtcdLease(day)
+	if err != nil {
+		return err
+	}
+
+	return etcdPut(routeInfoPrefix()+metricName+"/"+strconv.FormatUint(day, 10), shardGroup, leaseID)
+}
+
+func (etcdStore) PutRouteIfAbsent(metricName string, day uint64, shardGroup []string) (bool, error) {
+	leaseID, err := getEtcdLease(day)
+	if err != nil {
+		return false, err
+	}
+
+	return etcdPutIfAbsent(routeInfoPrefix()+metricName+"/"+strconv.FormatUint(day, 10), shardGroup, leaseID)
+}
+
+func (etcdStore) GetRoutes(metricName string) ([][]string, error) {
+	resp, err := etcdGetWithPrefix(routeInfoPrefix() + metricName + "/")
+	if err == ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	shardGroups := make([][]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var shardGroup []string
+		if err = json.Unmarshal(kv.Value, &shardGroup); err != nil {
+			return nil, err
+		}
+		shardGroups = append(shardGroups, shardGroup)
+	}
+
+	return shardGroups, nil
+}
+
+// routedMetricsPageSize is how many route keys GetRoutedMetrics gets from etcd at a time.
+var routedMetricsPageSize int64 = 1000
+
+// GetRoutedMetrics pages through the route keys, which are one per metric and day, so as not to get
+// all of them in a response. Once a metric is seen, the page after skips the rest of its days.
+func (etcdStore) GetRoutedMetrics() ([]string, error) {
+	prefix := routeInfoPrefix()
+	end := clientv3.GetPrefixRangeEnd(prefix)
+
+	// the keys of a metric are next to each other
+	var metricNames []string
+	for from := prefix; ; {
+		resp, err := etcdGetPage(from, end, routedMetricsPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, kv := range resp.Kvs {
+			metricName := strings.Split(strings.TrimPrefix(string(kv.Key), prefix), "/")[0]
+			if n := len(metricNames); n == 0 || metricNames[n-1] != metricName {
+				metricNames = append(metricNames, metricName)
+			}
+		}
+
+		if !resp.More || len(resp.Kvs) == 0 {
+			// sorted by the keys, a metric may come before a prefix of it, e.g. up.1/ before up/
+			sort.Strings(metricNames)
+			return metricNames, nil
+		}
+		// '0' comes right after '/', so it's past every prefix+metric+"/"+day key of the last metric
+		from = prefix + metricNames[len(metricNames)-1] + "0"
+	}
+}
+
+func (etcdStore) GetRouteKey(metricName string) (string, error) {
+	sGrpRouteKey := ""
+	err := etcdGet(sGrpRoutePrefix()+metricName, &sGrpRouteKey)
+	if err != nil && err != ErrKeyNotFound {
+		return "", err
+	}
+	return sGrpRouteKey, nil
+}
+
+func (etcdStore) GetDraining(addr string) (bool, error) {
+	var draining bool
+	err := etcdGet(drainPrefix()+addr, &draining)
+	if err != nil && err != ErrKeyNotFound {
+		return false, err
+	}
+	return draining, nil
+}
+
+func (etcdStore) PutDraining(addr string, draining bool) error {
+	// not leased, so that it survives the restart of the node
+	return etcdPut(drainPrefix()+addr, draining, clientv3.NoLease)
+}
+
+func (etcdStore) Ping() error {
+	cli, err := clientRef.Ref()
+	if err != nil {
+		return err
+	}
+	defer clientRef.UnRef()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(vars.Cfg.EtcdCommon.RWTimeout))
+	defer cancel()
+
+	_, err = cli.Get(ctx, nodePrefix(), clientv3.WithPrefix(), clientv3.WithCountOnly())
+	return err
+}
+
+// MemStore implements Store in memory, it's for a single node, where the gateway and the storage run
+// in one process, or for tests. The routes put are never expired.
+type MemStore struct {
+	mtx          sync.RWMutex
+	nodes        map[string]Node
+	routes       map[string]map[uint64][]string
+	routeKeys    map[string]string
+	draining     map[string]bool
+	nodesChanged func() error
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		nodes:     make(map[string]Node),
+		routes:    make(map[string]map[uint64][]string),
+		routeKeys: make(map[string]string),
+		draining:  make(map[string]bool),
+	}
+}
+
+func (s *MemStore) GetNodes() ([]Node, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	nodes := make([]Node, 0, len(s.nodes))
+	for _, node := range s.nodes {
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func (s *MemStore) NodeExists(addr string) (bool, error) {
+	s.mtx.RLock()
+	_, found := s.nodes[addr]
+	s.mtx.RUnlock()
+	return found, nil
+}
+
+// PutNode registers node or updates it, as the heartbeat does with etcd.
+func (s *MemStore) PutNode(node Node) {
+	s.mtx.Lock()
+	s.nodes[node.Addr()] = node
+	changed := s.nodesChanged
+	s.mtx.Unlock()
+
+	if changed != nil {
+		changed()
+	}
+}
+
+// DeleteNode deregisters the node of addr.
+func (s *MemStore) DeleteNode(addr string) {
+	s.mtx.Lock()
+	delete(s.nodes, addr)
+	changed := s.nodesChanged
+	s.mtx.Unlock()
+
+	if changed != nil {
+		changed()
+	}
+}
+
+func (s *MemStore) GetRoute(metricName string, day uint64) ([]string, error) {
+	s.mtx.RLock()
+	shardGroup, found := s.routes[metricName][day]
+	s.mtx.RUnlock()
+
+	if !found {
+		return nil, ErrKeyNotFound
+	}
+	return append([]string(nil), shardGroup...), nil
+}
+
+func (s *MemStore) PutRoute(metricName string, day uint64, shardGroup []string) error {
+	if len(shardGroup) == 0 {
+		return errors.Errorf("empty shard group of %s on day %d", metricName, day)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	days, found := s.routes[metricName]
+	if !found {
+		days = make(map[uint64][]string)
+		s.routes[metricName] = days
+	}
+	days[day] = append([]string(nil), shardGroup...)
+	return nil
+}
+
+func (s *MemStore) PutRouteIfAbsent(metricName string, day uint64, shardGroup []string) (bool, error) {
+	if len(shardGroup) == 0 {
+		return false, errors.Errorf("empty shard group of %s on day %d", metricName, day)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	days, found := s.routes[metricName]
+	if !found {
+		days = make(map[uint64][]string)
+		s.routes[metricName] = days
+	}
+	if _, found = days[day]; found {
+		return false, nil
+	}
+	days[day] = append([]string(nil), shardGroup...)
+	return true, nil
+}
+
+func (s *MemStore) GetRoutes(metricName string) ([][]string, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	shardGroups := make([][]string, 0, len(s.routes[metricName]))
+	for _, shardGroup := range s.routes[metricName] {
+		shardGroups = append(shardGroups, append([]string(nil), shardGroup...))
+	}
+	return shardGroups, nil
+}
+
+func (s *MemStore) GetRoutedMetrics() ([]string, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	metricNames := make([]string, 0, len(s.routes))
+	for metricName := range s.routes {
+		metricNames = append(metricNames, metricName)
+	}
+	sort.Strings(metricNames)
+	return metricNames, nil
+}
+
+func (s *MemStore) GetRouteKey(metricName string) (string, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.routeKeys[metricName], nil
+}
+
+func (s *MemStore) GetDraining(addr string) (bool, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.draining[addr], nil
+}
+
+func (s *MemStore) PutDraining(addr string, draining bool) error {
+	s.mtx.Lock()
+	s.draining[addr] = draining
+	s.mtx.Unlock()
+	return nil
+}
+
+func (s *MemStore) Ping() error {
+	return nil
+}
+
+// PutRouteKey sets the label whose value picks the shard out of the group of the metric.
+func (s *MemStore) PutRouteKey(metricName string, routeKey string) {
+	s.mtx.Lock()
+	s.routeKeys[metricName] = routeKey
+	s.mtx.Unlock()
+}
+
+// watch lets f be called once a node is put or deleted, like watching the nodes in etcd.
+func (s *MemStore) watch(f func() error) {
+	s.mtx.Lock()
+	s.nodesChanged = f
+	s.mtx.Unlock()
+}
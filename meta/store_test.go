@@ -0,0 +1,401 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package meta
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/util/toml"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMemStore_RefreshCluster(t *testing.T) {
+	mem := NewMemStore()
+	defer SetStore(SetStore(mem))
+
+	m := &meta{routeInfos: new(sync.Map)}
+	if err := m.RefreshCluster(); err != nil {
+		t.Fatal(err)
+	}
+	mem.watch(m.RefreshCluster)
+
+	mem.PutNode(Node{ShardID: "shard-1", IP: "10.0.0.1", Port: "8088"})
+	mem.PutNode(Node{ShardID: "shard-1", IP: "10.0.0.2", Port: "8088", MasterIP: "10.0.0.1", MasterPort: "8088"})
+	mem.PutNode(Node{ShardID: "shard-2", IP: "10.0.0.3", Port: "8088"})
+
+	shard, found := m.GetShard("shard-1")
+	if !found || shard.Master == nil || shard.Master.Addr() != "10.0.0.1:8088" || len(shard.Slaves) != 1 {
+		t.Fatalf("unexpected shard-1 %v", shard)
+	}
+
+	masters, err := GetMasters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(masters) != 2 {
+		t.Fatalf("want 2 masters, got %v", masters)
+	}
+
+	if exist, _ := mem.NodeExists("10.0.0.1:8088"); !exist {
+		t.Fatalf("want the node put to exist")
+	}
+
+	mem.DeleteNode("10.0.0.1:8088")
+	if exist, _ := mem.NodeExists("10.0.0.1:8088"); exist {
+		t.Fatalf("want the node deleted not to exist")
+	}
+	if shard, _ = m.GetShard("shard-1"); shard.Master != nil {
+		t.Fatalf("want no master of shard-1 after it's deleted, got %v", shard.Master)
+	}
+}
+
+func TestMemStore_Route(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	vars.Cfg.Gateway = &vars.GatewayConfig{Route: vars.RouteConfig{ShardGroupCap: 2}}
+	vars.Logger = log.NewNopLogger()
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	mem := NewMemStore()
+	defer SetStore(SetStore(mem))
+
+	m := &meta{routeInfos: new(sync.Map)}
+	const day = uint64(18000)
+
+	if _, _, err := m.getShardIDs("up", day); err == nil {
+		t.Fatalf("want an error initializing a route without enough shards")
+	}
+
+	for _, node := range []Node{
+		{ShardID: "shard-1", IP: "10.0.0.1", Port: "8088"},
+		{ShardID: "shard-2", IP: "10.0.0.2", Port: "8088"},
+		{ShardID: "shard-3", IP: "10.0.0.3", Port: "8088"},
+	} {
+		mem.PutNode(node)
+	}
+	mem.PutRouteKey("node_load1", "instance")
+
+	shardGroup, routeKey, err := m.getShardIDs("node_load1", day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shardGroup) != 2 || routeKey != "instance" {
+		t.Fatalf("unexpected route %v, %s", shardGroup, routeKey)
+	}
+
+	stored, err := mem.GetRoute("node_load1", day)
+	if err != nil || len(stored) != 2 || stored[0] != shardGroup[0] || stored[1] != shardGroup[1] {
+		t.Fatalf("want the route initialized put into the store, got %v, %v", stored, err)
+	}
+
+	// another gateway loads the route put rather than initializing one
+	another := &meta{routeInfos: new(sync.Map)}
+	if loaded, _, _ := another.getShardIDs("node_load1", day); len(loaded) != 2 || loaded[0] != shardGroup[0] || loaded[1] != shardGroup[1] {
+		t.Fatalf("want the same route loaded, got %v", loaded)
+	}
+
+	if err = mem.PutRoute("node_load1", day+1, []string{"shard-3"}); err != nil {
+		t.Fatal(err)
+	}
+	shardGroups, routeKey, err := m.getAllShardIDs("node_load1")
+	if err != nil || len(shardGroups) != 2 || routeKey != "instance" {
+		t.Fatalf("unexpected routes %v, %s, %v", shardGroups, routeKey, err)
+	}
+}
+
+func TestMemStore_Drain(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	vars.Cfg.Gateway = &vars.GatewayConfig{Route: vars.RouteConfig{ShardGroupCap: 2}}
+	vars.Logger = log.NewNopLogger()
+	metaBak := globalMeta
+	defer func() {
+		vars.Cfg.Gateway = gateway
+		globalMeta = metaBak
+	}()
+
+	mem := NewMemStore()
+	defer SetStore(SetStore(mem))
+
+	m := &meta{routeInfos: new(sync.Map)}
+	globalMeta = m
+	const day = uint64(18000)
+
+	for _, node := range []Node{
+		{ShardID: "shard-1", IP: "10.0.0.1", Port: "8088"},
+		{ShardID: "shard-2", IP: "10.0.0.2", Port: "8088", Draining: true},
+		{ShardID: "shard-3", IP: "10.0.0.3", Port: "8088"},
+	} {
+		mem.PutNode(node)
+	}
+	if err := m.RefreshCluster(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, metric := range []string{"up", "node_load1", "node_load5", "http_requests_total"} {
+		shardGroup, _, err := m.getShardIDs(metric, day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(shardGroup) != 2 || shardGroup[0] == "shard-2" || shardGroup[1] == "shard-2" {
+			t.Fatalf("want the draining shard-2 not picked for %s, got %v", metric, shardGroup)
+		}
+	}
+
+	if shard, found := AllShards()["shard-2"]; !found || shard.Master == nil || !shard.Master.Draining {
+		t.Fatalf("want the draining shard-2 still in the cluster, got %v", shard)
+	}
+
+	// not enough shards left once another one drains
+	mem.PutNode(Node{ShardID: "shard-3", IP: "10.0.0.3", Port: "8088", Draining: true})
+	if _, _, err := m.getShardIDs("node_load15", day); err == nil {
+		t.Fatalf("want an error initializing a route with a single shard not draining")
+	}
+
+	// undrained
+	mem.PutNode(Node{ShardID: "shard-2", IP: "10.0.0.2", Port: "8088"})
+	if shardGroup, _, err := m.getShardIDs("node_load15", day); err != nil || len(shardGroup) != 2 {
+		t.Fatalf("want shard-2 picked again once undrained, got %v, %v", shardGroup, err)
+	}
+}
+
+func TestHeartbeat_MemStore(t *testing.T) {
+	mem := NewMemStore()
+	defer SetStore(SetStore(mem))
+
+	node := Node{ShardID: "shard-1", IP: "10.0.0.1", Port: "8088"}
+	h := NewHeartbeat(time.Minute, time.Minute, func() (Node, error) {
+		return node, nil
+	})
+	if err := h.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if nodes, _ := GetNodes(false); len(nodes) != 1 || nodes[0] != node {
+		t.Fatalf("want the node registered, got %v", nodes)
+	}
+
+	h.Stop()
+	if nodes, _ := GetNodes(false); len(nodes) != 0 {
+		t.Fatalf("want the node deregistered, got %v", nodes)
+	}
+}
+
+// countingStore counts the routes got, each of which is held till release is closed.
+type countingStore struct {
+	Store
+	calls   int32
+	release chan struct{}
+	err     error
+}
+
+func (s *countingStore) GetRouteKey(string) (string, error) {
+	return "", nil
+}
+
+func (s *countingStore) GetRoute(string, uint64) ([]string, error) {
+	atomic.AddInt32(&s.calls, 1)
+	<-s.release
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []string{"shard-1", "shard-2"}, nil
+}
+
+func TestMeta_LookupShardIDsCoalesced(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	vars.Cfg.Gateway = &vars.GatewayConfig{Route: vars.RouteConfig{ShardGroupCap: 2}}
+	vars.Logger = log.NewNopLogger()
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	const n = 50
+
+	for _, storeErr := range []error{nil, errors.New("etcd is down")} {
+		s := &countingStore{release: make(chan struct{}), err: storeErr}
+		prev := SetStore(s)
+
+		m := &meta{routeInfos: new(sync.Map)}
+
+		var wg sync.WaitGroup
+		errs := make([]error, n)
+		groups := make([][]string, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				groups[i], _, errs[i] = m.getShardIDs("up", 18000)
+			}(i)
+		}
+
+		// let the lookups pile up on the one in flight
+		for atomic.LoadInt32(&s.calls) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(s.release)
+		wg.Wait()
+
+		SetStore(prev)
+
+		if calls := atomic.LoadInt32(&s.calls); calls != 1 {
+			t.Fatalf("store error %v: want 1 route got from the store, got %d", storeErr, calls)
+		}
+		for i := 0; i < n; i++ {
+			if errs[i] != storeErr {
+				t.Fatalf("store error %v: lookup %d got error %v", storeErr, i, errs[i])
+			}
+			if storeErr == nil && len(groups[i]) != 2 {
+				t.Fatalf("lookup %d got shard group %v", i, groups[i])
+			}
+		}
+	}
+}
+
+// racingStore puts the route of another gateway right after the route is found missing.
+type racingStore struct {
+	*MemStore
+	raced []string
+}
+
+func (s *racingStore) GetRoute(metricName string, day uint64) ([]string, error) {
+	shardGroup, err := s.MemStore.GetRoute(metricName, day)
+	if err == ErrKeyNotFound && s.raced != nil {
+		s.MemStore.PutRoute(metricName, day, s.raced)
+		s.raced = nil
+	}
+	return shardGroup, err
+}
+
+func TestMeta_RouteInitializedByAnother(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	vars.Cfg.Gateway = &vars.GatewayConfig{Route: vars.RouteConfig{ShardGroupCap: 2}}
+	vars.Logger = log.NewNopLogger()
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	mem := NewMemStore()
+	for _, node := range []Node{
+		{ShardID: "shard-1", IP: "10.0.0.1", Port: "8088"},
+		{ShardID: "shard-2", IP: "10.0.0.2", Port: "8088"},
+		{ShardID: "shard-3", IP: "10.0.0.3", Port: "8088"},
+	} {
+		mem.PutNode(node)
+	}
+	defer SetStore(SetStore(&racingStore{MemStore: mem, raced: []string{"shard-9", "shard-8"}}))
+
+	m := &meta{routeInfos: new(sync.Map)}
+	const day = uint64(18000)
+
+	shardGroup, _, err := m.getShardIDs("up", day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shardGroup) != 2 || shardGroup[0] != "shard-9" || shardGroup[1] != "shard-8" {
+		t.Fatalf("want the route of the other gateway taken, got %v", shardGroup)
+	}
+	if stored, _ := mem.GetRoute("up", day); len(stored) != 2 || stored[0] != "shard-9" {
+		t.Fatalf("want the route of the other gateway kept, got %v", stored)
+	}
+}
+
+func TestRouteCacheMetrics(t *testing.T) {
+	gateway := vars.Cfg.Gateway
+	vars.Cfg.Gateway = &vars.GatewayConfig{Route: vars.RouteConfig{ShardGroupCap: 2, NegativeTTL: toml.Duration(time.Minute)}}
+	vars.Logger = log.NewNopLogger()
+	defer func() {
+		vars.Cfg.Gateway = gateway
+	}()
+
+	mem := NewMemStore()
+	defer SetStore(SetStore(mem))
+	mem.PutNode(Node{ShardID: "shard-1", IP: "10.0.0.1", Port: "8088"})
+
+	value := func(c interface{ Write(*dto.Metric) error }) float64 {
+		m := new(dto.Metric)
+		if err := c.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		if m.Gauge != nil {
+			return m.Gauge.GetValue()
+		}
+		return m.Counter.GetValue()
+	}
+	snapshot := func() [5]float64 {
+		return [5]float64{
+			value(routeCacheHits), value(routeCacheMisses), value(routeCacheNegativeHits),
+			value(routeStoreErrors), value(routeCachedMetrics),
+		}
+	}
+
+	m := &meta{routeInfos: new(sync.Map)}
+	const day = uint64(18000)
+
+	before := snapshot()
+	m.getShardIDs("up", day) // not enough shards
+	m.getShardIDs("up", day) // the failure cached
+
+	mem.PutNode(Node{ShardID: "shard-2", IP: "10.0.0.2", Port: "8088"})
+	m.getShardIDs("node_load1", day)
+	m.getShardIDs("node_load1", day)
+	m.getShardIDs("node_load1", day)
+
+	after := snapshot()
+	want := [5]float64{2, 2, 1, 1, 2} // hits, misses, negative hits, store errors, cached metrics
+	for i := range want {
+		if got := after[i] - before[i]; got != want[i] {
+			t.Fatalf("want %v more of the metrics, got %v", want, [5]float64{
+				after[0] - before[0], after[1] - before[1], after[2] - before[2], after[3] - before[3], after[4] - before[4],
+			})
+		}
+	}
+}
+
+func TestMeta_Drain(t *testing.T) {
+	mem := NewMemStore()
+	defer SetStore(SetStore(mem))
+
+	if draining, err := Draining("10.0.0.1:8088"); err != nil || draining {
+		t.Fatalf("want not draining, got %v, %v", draining, err)
+	}
+	if err := Drain("10.0.0.1:8088", true); err != nil {
+		t.Fatal(err)
+	}
+
+	// the node deregistered, as it's restarting, is still drained
+	mem.DeleteNode("10.0.0.1:8088")
+	if draining, _ := Draining("10.0.0.1:8088"); !draining {
+		t.Fatal("want draining")
+	}
+	if draining, _ := Draining("10.0.0.2:8088"); draining {
+		t.Fatal("want the other node not draining")
+	}
+
+	if err := Drain("10.0.0.1:8088", false); err != nil {
+		t.Fatal(err)
+	}
+	if draining, _ := Draining("10.0.0.1:8088"); draining {
+		t.Fatal("want not draining once taken back")
+	}
+}
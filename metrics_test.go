@@ -0,0 +1,82 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package baudtime
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// the gzip and deflate of fasthttp are the ones of klauspost/compress, the metrics served
+// compressed through them at every level must read back as they are served plain.
+func TestHandleHttpMetrics_Compressed(t *testing.T) {
+	var plain fasthttp.RequestCtx
+	handleHttpMetrics(&plain)
+	if plain.Response.StatusCode() != fasthttp.StatusOK || len(plain.Response.Body()) == 0 {
+		t.Fatalf("unexpected response %d: %s", plain.Response.StatusCode(), plain.Response.Body())
+	}
+	want := append([]byte(nil), plain.Response.Body()...)
+	serve := func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType(string(plain.Response.Header.ContentType()))
+		ctx.SetBody(want)
+	}
+
+	for _, level := range []int{
+		fasthttp.CompressNoCompression,
+		fasthttp.CompressBestSpeed,
+		fasthttp.CompressDefaultCompression,
+		fasthttp.CompressBestCompression,
+		fasthttp.CompressHuffmanOnly,
+	} {
+		for _, encoding := range []string{"gzip", "deflate"} {
+			var c fasthttp.RequestCtx
+			c.Request.Header.Set("Accept-Encoding", encoding)
+			fasthttp.CompressHandlerLevel(serve, level)(&c)
+
+			if got := string(c.Response.Header.Peek("Content-Encoding")); got != encoding {
+				t.Fatalf("level %d: want a %s encoded response, got %q", level, encoding, got)
+			}
+
+			var (
+				body []byte
+				err  error
+			)
+			if encoding == "gzip" {
+				body, err = c.Response.BodyGunzip()
+			} else {
+				body, err = c.Response.BodyInflate()
+			}
+			if err != nil {
+				t.Fatalf("level %d: failed to decode %s: %v", level, encoding, err)
+			}
+			if !bytes.Equal(body, want) {
+				t.Fatalf("level %d: %s decoded differs from the plain response", level, encoding)
+			}
+		}
+	}
+
+	src := bytes.Repeat(want, 4)
+	for _, level := range []int{fasthttp.CompressBestSpeed, fasthttp.CompressDefaultCompression, fasthttp.CompressBestCompression} {
+		if got, err := fasthttp.AppendGunzipBytes(nil, fasthttp.AppendGzipBytesLevel(nil, src, level)); err != nil || !bytes.Equal(got, src) {
+			t.Fatalf("level %d: gzip round trip failed: %v", level, err)
+		}
+		if got, err := fasthttp.AppendInflateBytes(nil, fasthttp.AppendDeflateBytesLevel(nil, src, level)); err != nil || !bytes.Equal(got, src) {
+			t.Fatalf("level %d: deflate round trip failed: %v", level, err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ package pb
 import proto "github.com/gogo/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import _ "github.com/gogo/protobuf/gogoproto"
 
 import io "io"
 
@@ -24,6 +25,12 @@ type AdminCmdRequest struct {
 	// Types that are valid to be assigned to Command:
 	//	*AdminCmdRequest_Info
 	//	*AdminCmdRequest_JoinCluster
+	//	*AdminCmdRequest_SlaveOf
+	//	*AdminCmdRequest_LeaveCluster
+	//	*AdminCmdRequest_Route
+	//	*AdminCmdRequest_Drain
+	//	*AdminCmdRequest_Rebalance
+	//	*AdminCmdRequest_ListMetrics
 	Command isAdminCmdRequest_Command `protobuf_oneof:"command"`
 }
 
@@ -31,7 +38,7 @@ func (m *AdminCmdRequest) Reset()         { *m = AdminCmdRequest{} }
 func (m *AdminCmdRequest) String() string { return proto.CompactTextString(m) }
 func (*AdminCmdRequest) ProtoMessage()    {}
 func (*AdminCmdRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_admin_b9c1af4f7059a473, []int{0}
+	return fileDescriptor_admin_a41a34c64919512b, []int{0}
 }
 func (m *AdminCmdRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -72,9 +79,33 @@ type AdminCmdRequest_Info struct {
 type AdminCmdRequest_JoinCluster struct {
 	JoinCluster *JoinCluster `protobuf:"bytes,2,opt,name=joinCluster,oneof"`
 }
+type AdminCmdRequest_SlaveOf struct {
+	SlaveOf *SlaveOf `protobuf:"bytes,3,opt,name=slaveOf,oneof"`
+}
+type AdminCmdRequest_LeaveCluster struct {
+	LeaveCluster *LeaveCluster `protobuf:"bytes,4,opt,name=leaveCluster,oneof"`
+}
+type AdminCmdRequest_Route struct {
+	Route *Route `protobuf:"bytes,5,opt,name=route,oneof"`
+}
+type AdminCmdRequest_Drain struct {
+	Drain *Drain `protobuf:"bytes,6,opt,name=drain,oneof"`
+}
+type AdminCmdRequest_Rebalance struct {
+	Rebalance *Rebalance `protobuf:"bytes,7,opt,name=rebalance,oneof"`
+}
+type AdminCmdRequest_ListMetrics struct {
+	ListMetrics *ListMetrics `protobuf:"bytes,8,opt,name=listMetrics,oneof"`
+}
 
-func (*AdminCmdRequest_Info) isAdminCmdRequest_Command()        {}
-func (*AdminCmdRequest_JoinCluster) isAdminCmdRequest_Command() {}
+func (*AdminCmdRequest_Info) isAdminCmdRequest_Command()         {}
+func (*AdminCmdRequest_JoinCluster) isAdminCmdRequest_Command()  {}
+func (*AdminCmdRequest_SlaveOf) isAdminCmdRequest_Command()      {}
+func (*AdminCmdRequest_LeaveCluster) isAdminCmdRequest_Command() {}
+func (*AdminCmdRequest_Route) isAdminCmdRequest_Command()        {}
+func (*AdminCmdRequest_Drain) isAdminCmdRequest_Command()        {}
+func (*AdminCmdRequest_Rebalance) isAdminCmdRequest_Command()    {}
+func (*AdminCmdRequest_ListMetrics) isAdminCmdRequest_Command()  {}
 
 func (m *AdminCmdRequest) GetCommand() isAdminCmdRequest_Command {
 	if m != nil {
@@ -97,11 +128,59 @@ func (m *AdminCmdRequest) GetJoinCluster() *JoinCluster {
 	return nil
 }
 
+func (m *AdminCmdRequest) GetSlaveOf() *SlaveOf {
+	if x, ok := m.GetCommand().(*AdminCmdRequest_SlaveOf); ok {
+		return x.SlaveOf
+	}
+	return nil
+}
+
+func (m *AdminCmdRequest) GetLeaveCluster() *LeaveCluster {
+	if x, ok := m.GetCommand().(*AdminCmdRequest_LeaveCluster); ok {
+		return x.LeaveCluster
+	}
+	return nil
+}
+
+func (m *AdminCmdRequest) GetRoute() *Route {
+	if x, ok := m.GetCommand().(*AdminCmdRequest_Route); ok {
+		return x.Route
+	}
+	return nil
+}
+
+func (m *AdminCmdRequest) GetDrain() *Drain {
+	if x, ok := m.GetCommand().(*AdminCmdRequest_Drain); ok {
+		return x.Drain
+	}
+	return nil
+}
+
+func (m *AdminCmdRequest) GetRebalance() *Rebalance {
+	if x, ok := m.GetCommand().(*AdminCmdRequest_Rebalance); ok {
+		return x.Rebalance
+	}
+	return nil
+}
+
+func (m *AdminCmdRequest) GetListMetrics() *ListMetrics {
+	if x, ok := m.GetCommand().(*AdminCmdRequest_ListMetrics); ok {
+		return x.ListMetrics
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*AdminCmdRequest) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _AdminCmdRequest_OneofMarshaler, _AdminCmdRequest_OneofUnmarshaler, _AdminCmdRequest_OneofSizer, []interface{}{
 		(*AdminCmdRequest_Info)(nil),
 		(*AdminCmdRequest_JoinCluster)(nil),
+		(*AdminCmdRequest_SlaveOf)(nil),
+		(*AdminCmdRequest_LeaveCluster)(nil),
+		(*AdminCmdRequest_Route)(nil),
+		(*AdminCmdRequest_Drain)(nil),
+		(*AdminCmdRequest_Rebalance)(nil),
+		(*AdminCmdRequest_ListMetrics)(nil),
 	}
 }
 
@@ -119,6 +198,36 @@ func _AdminCmdRequest_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.JoinCluster); err != nil {
 			return err
 		}
+	case *AdminCmdRequest_SlaveOf:
+		_ = b.EncodeVarint(3<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.SlaveOf); err != nil {
+			return err
+		}
+	case *AdminCmdRequest_LeaveCluster:
+		_ = b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.LeaveCluster); err != nil {
+			return err
+		}
+	case *AdminCmdRequest_Route:
+		_ = b.EncodeVarint(5<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Route); err != nil {
+			return err
+		}
+	case *AdminCmdRequest_Drain:
+		_ = b.EncodeVarint(6<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Drain); err != nil {
+			return err
+		}
+	case *AdminCmdRequest_Rebalance:
+		_ = b.EncodeVarint(7<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Rebalance); err != nil {
+			return err
+		}
+	case *AdminCmdRequest_ListMetrics:
+		_ = b.EncodeVarint(8<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.ListMetrics); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("AdminCmdRequest.Command has unexpected type %T", x)
@@ -145,6 +254,54 @@ func _AdminCmdRequest_OneofUnmarshaler(msg proto.Message, tag, wire int, b *prot
 		err := b.DecodeMessage(msg)
 		m.Command = &AdminCmdRequest_JoinCluster{msg}
 		return true, err
+	case 3: // command.slaveOf
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(SlaveOf)
+		err := b.DecodeMessage(msg)
+		m.Command = &AdminCmdRequest_SlaveOf{msg}
+		return true, err
+	case 4: // command.leaveCluster
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(LeaveCluster)
+		err := b.DecodeMessage(msg)
+		m.Command = &AdminCmdRequest_LeaveCluster{msg}
+		return true, err
+	case 5: // command.route
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Route)
+		err := b.DecodeMessage(msg)
+		m.Command = &AdminCmdRequest_Route{msg}
+		return true, err
+	case 6: // command.drain
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Drain)
+		err := b.DecodeMessage(msg)
+		m.Command = &AdminCmdRequest_Drain{msg}
+		return true, err
+	case 7: // command.rebalance
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Rebalance)
+		err := b.DecodeMessage(msg)
+		m.Command = &AdminCmdRequest_Rebalance{msg}
+		return true, err
+	case 8: // command.listMetrics
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ListMetrics)
+		err := b.DecodeMessage(msg)
+		m.Command = &AdminCmdRequest_ListMetrics{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -164,6 +321,36 @@ func _AdminCmdRequest_OneofSizer(msg proto.Message) (n int) {
 		n += 1 // tag and wire
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *AdminCmdRequest_SlaveOf:
+		s := proto.Size(x.SlaveOf)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *AdminCmdRequest_LeaveCluster:
+		s := proto.Size(x.LeaveCluster)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *AdminCmdRequest_Route:
+		s := proto.Size(x.Route)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *AdminCmdRequest_Drain:
+		s := proto.Size(x.Drain)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *AdminCmdRequest_Rebalance:
+		s := proto.Size(x.Rebalance)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *AdminCmdRequest_ListMetrics:
+		s := proto.Size(x.ListMetrics)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -172,13 +359,14 @@ func _AdminCmdRequest_OneofSizer(msg proto.Message) (n int) {
 }
 
 type Info struct {
+	Detailed bool `protobuf:"varint,1,opt,name=detailed,proto3" json:"detailed,omitempty"`
 }
 
 func (m *Info) Reset()         { *m = Info{} }
 func (m *Info) String() string { return proto.CompactTextString(m) }
 func (*Info) ProtoMessage()    {}
 func (*Info) Descriptor() ([]byte, []int) {
-	return fileDescriptor_admin_b9c1af4f7059a473, []int{1}
+	return fileDescriptor_admin_a41a34c64919512b, []int{1}
 }
 func (m *Info) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -207,21 +395,49 @@ func (m *Info) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_Info proto.InternalMessageInfo
 
-type JoinCluster struct {
+func (m *Info) GetDetailed() bool {
+	if m != nil {
+		return m.Detailed
+	}
+	return false
 }
 
-func (m *JoinCluster) Reset()         { *m = JoinCluster{} }
-func (m *JoinCluster) String() string { return proto.CompactTextString(m) }
-func (*JoinCluster) ProtoMessage()    {}
-func (*JoinCluster) Descriptor() ([]byte, []int) {
-	return fileDescriptor_admin_b9c1af4f7059a473, []int{2}
+type InfoResponse struct {
+	Status             StatusCode `protobuf:"varint,1,opt,name=status,proto3,enum=pb.StatusCode" json:"status,omitempty"`
+	ErrorMsg           string     `protobuf:"bytes,2,opt,name=errorMsg,proto3" json:"errorMsg,omitempty"`
+	Version            string     `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	ShardID            string     `protobuf:"bytes,4,opt,name=shardID,proto3" json:"shardID,omitempty"`
+	Role               string     `protobuf:"bytes,5,opt,name=role,proto3" json:"role,omitempty"`
+	Addr               string     `protobuf:"bytes,6,opt,name=addr,proto3" json:"addr,omitempty"`
+	MasterAddr         string     `protobuf:"bytes,7,opt,name=masterAddr,proto3" json:"masterAddr,omitempty"`
+	StartTime          int64      `protobuf:"varint,8,opt,name=startTime,proto3" json:"startTime,omitempty"`
+	MinT               int64      `protobuf:"varint,9,opt,name=minT,proto3" json:"minT,omitempty"`
+	MaxT               int64      `protobuf:"varint,10,opt,name=maxT,proto3" json:"maxT,omitempty"`
+	DiskFree           uint64     `protobuf:"varint,11,opt,name=diskFree,proto3" json:"diskFree,omitempty"`
+	SeriesNum          uint64     `protobuf:"varint,12,opt,name=seriesNum,proto3" json:"seriesNum,omitempty"`
+	SamplesReceived    uint64     `protobuf:"varint,13,opt,name=samplesReceived,proto3" json:"samplesReceived,omitempty"`
+	SamplesSucceed     uint64     `protobuf:"varint,14,opt,name=samplesSucceed,proto3" json:"samplesSucceed,omitempty"`
+	SamplesFailed      uint64     `protobuf:"varint,15,opt,name=samplesFailed,proto3" json:"samplesFailed,omitempty"`
+	SamplesOutOfOrder  uint64     `protobuf:"varint,16,opt,name=samplesOutOfOrder,proto3" json:"samplesOutOfOrder,omitempty"`
+	SamplesOutOfBounds uint64     `protobuf:"varint,17,opt,name=samplesOutOfBounds,proto3" json:"samplesOutOfBounds,omitempty"`
+	SamplesDuplicated  uint64     `protobuf:"varint,18,opt,name=samplesDuplicated,proto3" json:"samplesDuplicated,omitempty"`
+	Compacting         bool       `protobuf:"varint,19,opt,name=compacting,proto3" json:"compacting,omitempty"`
+	Load               int64      `protobuf:"varint,20,opt,name=load,proto3" json:"load,omitempty"`
+	Health             *Health    `protobuf:"bytes,21,opt,name=health" json:"health,omitempty"`
 }
-func (m *JoinCluster) XXX_Unmarshal(b []byte) error {
+
+func (m *InfoResponse) Reset()         { *m = InfoResponse{} }
+func (m *InfoResponse) String() string { return proto.CompactTextString(m) }
+func (*InfoResponse) ProtoMessage()    {}
+func (*InfoResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_a41a34c64919512b, []int{2}
+}
+func (m *InfoResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
 }
-func (m *JoinCluster) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+func (m *InfoResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 	if deterministic {
-		return xxx_messageInfo_JoinCluster.Marshal(b, m, deterministic)
+		return xxx_messageInfo_InfoResponse.Marshal(b, m, deterministic)
 	} else {
 		b = b[:cap(b)]
 		n, err := m.MarshalTo(b)
@@ -231,189 +447,3849 @@ func (m *JoinCluster) XXX_Marshal(b []byte, deterministic bool) ([]byte, error)
 		return b[:n], nil
 	}
 }
-func (dst *JoinCluster) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_JoinCluster.Merge(dst, src)
+func (dst *InfoResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InfoResponse.Merge(dst, src)
 }
-func (m *JoinCluster) XXX_Size() int {
+func (m *InfoResponse) XXX_Size() int {
 	return m.Size()
 }
-func (m *JoinCluster) XXX_DiscardUnknown() {
-	xxx_messageInfo_JoinCluster.DiscardUnknown(m)
+func (m *InfoResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_InfoResponse.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_JoinCluster proto.InternalMessageInfo
+var xxx_messageInfo_InfoResponse proto.InternalMessageInfo
 
-func init() {
-	proto.RegisterType((*AdminCmdRequest)(nil), "pb.AdminCmdRequest")
-	proto.RegisterType((*Info)(nil), "pb.Info")
-	proto.RegisterType((*JoinCluster)(nil), "pb.JoinCluster")
+func (m *InfoResponse) GetStatus() StatusCode {
+	if m != nil {
+		return m.Status
+	}
+	return StatusCode_Succeed
 }
-func (m *AdminCmdRequest) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+
+func (m *InfoResponse) GetErrorMsg() string {
+	if m != nil {
+		return m.ErrorMsg
 	}
-	return dAtA[:n], nil
+	return ""
 }
 
-func (m *AdminCmdRequest) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Command != nil {
-		nn1, err := m.Command.MarshalTo(dAtA[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += nn1
+func (m *InfoResponse) GetVersion() string {
+	if m != nil {
+		return m.Version
 	}
-	return i, nil
+	return ""
 }
 
-func (m *AdminCmdRequest_Info) MarshalTo(dAtA []byte) (int, error) {
-	i := 0
-	if m.Info != nil {
-		dAtA[i] = 0xa
-		i++
-		i = encodeVarintAdmin(dAtA, i, uint64(m.Info.Size()))
-		n2, err := m.Info.MarshalTo(dAtA[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n2
+func (m *InfoResponse) GetShardID() string {
+	if m != nil {
+		return m.ShardID
 	}
-	return i, nil
+	return ""
 }
-func (m *AdminCmdRequest_JoinCluster) MarshalTo(dAtA []byte) (int, error) {
-	i := 0
-	if m.JoinCluster != nil {
-		dAtA[i] = 0x12
-		i++
-		i = encodeVarintAdmin(dAtA, i, uint64(m.JoinCluster.Size()))
-		n3, err := m.JoinCluster.MarshalTo(dAtA[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n3
+
+func (m *InfoResponse) GetRole() string {
+	if m != nil {
+		return m.Role
 	}
-	return i, nil
+	return ""
 }
-func (m *Info) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+
+func (m *InfoResponse) GetAddr() string {
+	if m != nil {
+		return m.Addr
 	}
-	return dAtA[:n], nil
+	return ""
 }
 
-func (m *Info) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	return i, nil
+func (m *InfoResponse) GetMasterAddr() string {
+	if m != nil {
+		return m.MasterAddr
+	}
+	return ""
 }
 
-func (m *JoinCluster) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (m *InfoResponse) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
 	}
-	return dAtA[:n], nil
+	return 0
 }
 
-func (m *JoinCluster) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	return i, nil
+func (m *InfoResponse) GetMinT() int64 {
+	if m != nil {
+		return m.MinT
+	}
+	return 0
 }
 
-func encodeVarintAdmin(dAtA []byte, offset int, v uint64) int {
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *InfoResponse) GetMaxT() int64 {
+	if m != nil {
+		return m.MaxT
 	}
-	dAtA[offset] = uint8(v)
-	return offset + 1
+	return 0
 }
-func (m *AdminCmdRequest) Size() (n int) {
-	if m == nil {
-		return 0
+
+func (m *InfoResponse) GetDiskFree() uint64 {
+	if m != nil {
+		return m.DiskFree
 	}
-	var l int
-	_ = l
-	if m.Command != nil {
-		n += m.Command.Size()
+	return 0
+}
+
+func (m *InfoResponse) GetSeriesNum() uint64 {
+	if m != nil {
+		return m.SeriesNum
 	}
-	return n
+	return 0
 }
 
-func (m *AdminCmdRequest_Info) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *InfoResponse) GetSamplesReceived() uint64 {
+	if m != nil {
+		return m.SamplesReceived
 	}
-	var l int
-	_ = l
-	if m.Info != nil {
-		l = m.Info.Size()
-		n += 1 + l + sovAdmin(uint64(l))
+	return 0
+}
+
+func (m *InfoResponse) GetSamplesSucceed() uint64 {
+	if m != nil {
+		return m.SamplesSucceed
 	}
-	return n
+	return 0
 }
-func (m *AdminCmdRequest_JoinCluster) Size() (n int) {
-	if m == nil {
-		return 0
+
+func (m *InfoResponse) GetSamplesFailed() uint64 {
+	if m != nil {
+		return m.SamplesFailed
 	}
-	var l int
-	_ = l
-	if m.JoinCluster != nil {
-		l = m.JoinCluster.Size()
-		n += 1 + l + sovAdmin(uint64(l))
+	return 0
+}
+
+func (m *InfoResponse) GetSamplesOutOfOrder() uint64 {
+	if m != nil {
+		return m.SamplesOutOfOrder
 	}
-	return n
+	return 0
 }
-func (m *Info) Size() (n int) {
-	if m == nil {
-		return 0
+
+func (m *InfoResponse) GetSamplesOutOfBounds() uint64 {
+	if m != nil {
+		return m.SamplesOutOfBounds
 	}
-	var l int
-	_ = l
-	return n
+	return 0
 }
 
-func (m *JoinCluster) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *InfoResponse) GetSamplesDuplicated() uint64 {
+	if m != nil {
+		return m.SamplesDuplicated
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetCompacting() bool {
+	if m != nil {
+		return m.Compacting
+	}
+	return false
+}
+
+func (m *InfoResponse) GetLoad() int64 {
+	if m != nil {
+		return m.Load
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetHealth() *Health {
+	if m != nil {
+		return m.Health
+	}
+	return nil
+}
+
+type Health struct {
+	Status       string   `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	EtcdErr      string   `protobuf:"bytes,2,opt,name=etcdErr,proto3" json:"etcdErr,omitempty"`
+	WatchRunning bool     `protobuf:"varint,3,opt,name=watchRunning,proto3" json:"watchRunning,omitempty"`
+	WatchAlive   int64    `protobuf:"varint,4,opt,name=watchAlive,proto3" json:"watchAlive,omitempty"`
+	ShardsProbed uint32   `protobuf:"varint,5,opt,name=shardsProbed,proto3" json:"shardsProbed,omitempty"`
+	Unreachable  []string `protobuf:"bytes,6,rep,name=unreachable" json:"unreachable,omitempty"`
+}
+
+func (m *Health) Reset()         { *m = Health{} }
+func (m *Health) String() string { return proto.CompactTextString(m) }
+func (*Health) ProtoMessage()    {}
+func (*Health) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_a41a34c64919512b, []int{3}
+}
+func (m *Health) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Health) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Health.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *Health) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Health.Merge(dst, src)
+}
+func (m *Health) XXX_Size() int {
+	return m.Size()
+}
+func (m *Health) XXX_DiscardUnknown() {
+	xxx_messageInfo_Health.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Health proto.InternalMessageInfo
+
+func (m *Health) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *Health) GetEtcdErr() string {
+	if m != nil {
+		return m.EtcdErr
+	}
+	return ""
+}
+
+func (m *Health) GetWatchRunning() bool {
+	if m != nil {
+		return m.WatchRunning
+	}
+	return false
+}
+
+func (m *Health) GetWatchAlive() int64 {
+	if m != nil {
+		return m.WatchAlive
+	}
+	return 0
+}
+
+func (m *Health) GetShardsProbed() uint32 {
+	if m != nil {
+		return m.ShardsProbed
+	}
+	return 0
+}
+
+func (m *Health) GetUnreachable() []string {
+	if m != nil {
+		return m.Unreachable
+	}
+	return nil
+}
+
+type JoinCluster struct {
+}
+
+func (m *JoinCluster) Reset()         { *m = JoinCluster{} }
+func (m *JoinCluster) String() string { return proto.CompactTextString(m) }
+func (*JoinCluster) ProtoMessage()    {}
+func (*JoinCluster) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_a41a34c64919512b, []int{4}
+}
+func (m *JoinCluster) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *JoinCluster) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_JoinCluster.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *JoinCluster) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_JoinCluster.Merge(dst, src)
+}
+func (m *JoinCluster) XXX_Size() int {
+	return m.Size()
+}
+func (m *JoinCluster) XXX_DiscardUnknown() {
+	xxx_messageInfo_JoinCluster.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_JoinCluster proto.InternalMessageInfo
+
+type SlaveOf struct {
+	MasterAddr string `protobuf:"bytes,1,opt,name=masterAddr,proto3" json:"masterAddr,omitempty"`
+}
+
+func (m *SlaveOf) Reset()         { *m = SlaveOf{} }
+func (m *SlaveOf) String() string { return proto.CompactTextString(m) }
+func (*SlaveOf) ProtoMessage()    {}
+func (*SlaveOf) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_a41a34c64919512b, []int{5}
+}
+func (m *SlaveOf) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SlaveOf) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SlaveOf.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *SlaveOf) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SlaveOf.Merge(dst, src)
+}
+func (m *SlaveOf) XXX_Size() int {
+	return m.Size()
+}
+func (m *SlaveOf) XXX_DiscardUnknown() {
+	xxx_messageInfo_SlaveOf.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SlaveOf proto.InternalMessageInfo
+
+func (m *SlaveOf) GetMasterAddr() string {
+	if m != nil {
+		return m.MasterAddr
+	}
+	return ""
+}
+
+type LeaveCluster struct {
+}
+
+func (m *LeaveCluster) Reset()         { *m = LeaveCluster{} }
+func (m *LeaveCluster) String() string { return proto.CompactTextString(m) }
+func (*LeaveCluster) ProtoMessage()    {}
+func (*LeaveCluster) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_a41a34c64919512b, []int{6}
+}
+func (m *LeaveCluster) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *LeaveCluster) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_LeaveCluster.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *LeaveCluster) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LeaveCluster.Merge(dst, src)
+}
+func (m *LeaveCluster) XXX_Size() int {
+	return m.Size()
+}
+func (m *LeaveCluster) XXX_DiscardUnknown() {
+	xxx_messageInfo_LeaveCluster.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LeaveCluster proto.InternalMessageInfo
+
+type Drain struct {
+	Undo bool `protobuf:"varint,1,opt,name=undo,proto3" json:"undo,omitempty"`
+}
+
+func (m *Drain) Reset()         { *m = Drain{} }
+func (m *Drain) String() string { return proto.CompactTextString(m) }
+func (*Drain) ProtoMessage()    {}
+func (*Drain) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_a41a34c64919512b, []int{7}
+}
+func (m *Drain) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Drain) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Drain.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *Drain) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Drain.Merge(dst, src)
+}
+func (m *Drain) XXX_Size() int {
+	return m.Size()
+}
+func (m *Drain) XXX_DiscardUnknown() {
+	xxx_messageInfo_Drain.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Drain proto.InternalMessageInfo
+
+func (m *Drain) GetUndo() bool {
+	if m != nil {
+		return m.Undo
+	}
+	return false
+}
+
+type Route struct {
+	Labels []Label `protobuf:"bytes,1,rep,name=labels" json:"labels"`
+	Time   int64   `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+}
+
+func (m *Route) Reset()         { *m = Route{} }
+func (m *Route) String() string { return proto.CompactTextString(m) }
+func (*Route) ProtoMessage()    {}
+func (*Route) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_a41a34c64919512b, []int{8}
+}
+func (m *Route) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Route) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Route.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *Route) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Route.Merge(dst, src)
+}
+func (m *Route) XXX_Size() int {
+	return m.Size()
+}
+func (m *Route) XXX_DiscardUnknown() {
+	xxx_messageInfo_Route.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Route proto.InternalMessageInfo
+
+func (m *Route) GetLabels() []Label {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *Route) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+type RouteResponse struct {
+	Status     StatusCode `protobuf:"varint,1,opt,name=status,proto3,enum=pb.StatusCode" json:"status,omitempty"`
+	ErrorMsg   string     `protobuf:"bytes,2,opt,name=errorMsg,proto3" json:"errorMsg,omitempty"`
+	ShardGroup []string   `protobuf:"bytes,3,rep,name=shardGroup" json:"shardGroup,omitempty"`
+	ShardID    string     `protobuf:"bytes,4,opt,name=shardID,proto3" json:"shardID,omitempty"`
+	RouteKey   string     `protobuf:"bytes,5,opt,name=routeKey,proto3" json:"routeKey,omitempty"`
+	FromCache  bool       `protobuf:"varint,6,opt,name=fromCache,proto3" json:"fromCache,omitempty"`
+}
+
+func (m *RouteResponse) Reset()         { *m = RouteResponse{} }
+func (m *RouteResponse) String() string { return proto.CompactTextString(m) }
+func (*RouteResponse) ProtoMessage()    {}
+func (*RouteResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_a41a34c64919512b, []int{9}
+}
+func (m *RouteResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RouteResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RouteResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *RouteResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RouteResponse.Merge(dst, src)
+}
+func (m *RouteResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *RouteResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RouteResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RouteResponse proto.InternalMessageInfo
+
+func (m *RouteResponse) GetStatus() StatusCode {
+	if m != nil {
+		return m.Status
+	}
+	return StatusCode_Succeed
+}
+
+func (m *RouteResponse) GetErrorMsg() string {
+	if m != nil {
+		return m.ErrorMsg
+	}
+	return ""
+}
+
+func (m *RouteResponse) GetShardGroup() []string {
+	if m != nil {
+		return m.ShardGroup
+	}
+	return nil
+}
+
+func (m *RouteResponse) GetShardID() string {
+	if m != nil {
+		return m.ShardID
+	}
+	return ""
+}
+
+func (m *RouteResponse) GetRouteKey() string {
+	if m != nil {
+		return m.RouteKey
+	}
+	return ""
+}
+
+func (m *RouteResponse) GetFromCache() bool {
+	if m != nil {
+		return m.FromCache
+	}
+	return false
+}
+
+type Rebalance struct {
+	Metrics []string `protobuf:"bytes,1,rep,name=metrics" json:"metrics,omitempty"`
+	Days    uint32   `protobuf:"varint,2,opt,name=days,proto3" json:"days,omitempty"`
+	DryRun  bool     `protobuf:"varint,3,opt,name=dryRun,proto3" json:"dryRun,omitempty"`
+	Status  bool     `protobuf:"varint,4,opt,name=status,proto3" json:"status,omitempty"`
+}
+
+func (m *Rebalance) Reset()         { *m = Rebalance{} }
+func (m *Rebalance) String() string { return proto.CompactTextString(m) }
+func (*Rebalance) ProtoMessage()    {}
+func (*Rebalance) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_a41a34c64919512b, []int{10}
+}
+func (m *Rebalance) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Rebalance) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Rebalance.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *Rebalance) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Rebalance.Merge(dst, src)
+}
+func (m *Rebalance) XXX_Size() int {
+	return m.Size()
+}
+func (m *Rebalance) XXX_DiscardUnknown() {
+	xxx_messageInfo_Rebalance.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Rebalance proto.InternalMessageInfo
+
+func (m *Rebalance) GetMetrics() []string {
+	if m != nil {
+		return m.Metrics
+	}
+	return nil
+}
+
+func (m *Rebalance) GetDays() uint32 {
+	if m != nil {
+		return m.Days
+	}
+	return 0
+}
+
+func (m *Rebalance) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+func (m *Rebalance) GetStatus() bool {
+	if m != nil {
+		return m.Status
+	}
+	return false
+}
+
+type RouteMove struct {
+	Metric string   `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"`
+	Day    uint64   `protobuf:"varint,2,opt,name=day,proto3" json:"day,omitempty"`
+	From   []string `protobuf:"bytes,3,rep,name=from" json:"from,omitempty"`
+	To     []string `protobuf:"bytes,4,rep,name=to" json:"to,omitempty"`
+}
+
+func (m *RouteMove) Reset()         { *m = RouteMove{} }
+func (m *RouteMove) String() string { return proto.CompactTextString(m) }
+func (*RouteMove) ProtoMessage()    {}
+func (*RouteMove) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_a41a34c64919512b, []int{11}
+}
+func (m *RouteMove) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RouteMove) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RouteMove.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *RouteMove) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RouteMove.Merge(dst, src)
+}
+func (m *RouteMove) XXX_Size() int {
+	return m.Size()
+}
+func (m *RouteMove) XXX_DiscardUnknown() {
+	xxx_messageInfo_RouteMove.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RouteMove proto.InternalMessageInfo
+
+func (m *RouteMove) GetMetric() string {
+	if m != nil {
+		return m.Metric
+	}
+	return ""
+}
+
+func (m *RouteMove) GetDay() uint64 {
+	if m != nil {
+		return m.Day
+	}
+	return 0
+}
+
+func (m *RouteMove) GetFrom() []string {
+	if m != nil {
+		return m.From
+	}
+	return nil
+}
+
+func (m *RouteMove) GetTo() []string {
+	if m != nil {
+		return m.To
+	}
+	return nil
+}
+
+type RebalanceResponse struct {
+	Status   StatusCode  `protobuf:"varint,1,opt,name=status,proto3,enum=pb.StatusCode" json:"status,omitempty"`
+	ErrorMsg string      `protobuf:"bytes,2,opt,name=errorMsg,proto3" json:"errorMsg,omitempty"`
+	Moves    []RouteMove `protobuf:"bytes,3,rep,name=moves" json:"moves"`
+	Applied  uint32      `protobuf:"varint,4,opt,name=applied,proto3" json:"applied,omitempty"`
+	Skipped  uint32      `protobuf:"varint,5,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	Running  bool        `protobuf:"varint,6,opt,name=running,proto3" json:"running,omitempty"`
+	LastErr  string      `protobuf:"bytes,7,opt,name=lastErr,proto3" json:"lastErr,omitempty"`
+}
+
+func (m *RebalanceResponse) Reset()         { *m = RebalanceResponse{} }
+func (m *RebalanceResponse) String() string { return proto.CompactTextString(m) }
+func (*RebalanceResponse) ProtoMessage()    {}
+func (*RebalanceResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_a41a34c64919512b, []int{12}
+}
+func (m *RebalanceResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RebalanceResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RebalanceResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *RebalanceResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RebalanceResponse.Merge(dst, src)
+}
+func (m *RebalanceResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *RebalanceResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RebalanceResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RebalanceResponse proto.InternalMessageInfo
+
+func (m *RebalanceResponse) GetStatus() StatusCode {
+	if m != nil {
+		return m.Status
+	}
+	return StatusCode_Succeed
+}
+
+func (m *RebalanceResponse) GetErrorMsg() string {
+	if m != nil {
+		return m.ErrorMsg
+	}
+	return ""
+}
+
+func (m *RebalanceResponse) GetMoves() []RouteMove {
+	if m != nil {
+		return m.Moves
+	}
+	return nil
+}
+
+func (m *RebalanceResponse) GetApplied() uint32 {
+	if m != nil {
+		return m.Applied
+	}
+	return 0
+}
+
+func (m *RebalanceResponse) GetSkipped() uint32 {
+	if m != nil {
+		return m.Skipped
+	}
+	return 0
+}
+
+func (m *RebalanceResponse) GetRunning() bool {
+	if m != nil {
+		return m.Running
+	}
+	return false
+}
+
+func (m *RebalanceResponse) GetLastErr() string {
+	if m != nil {
+		return m.LastErr
+	}
+	return ""
+}
+
+type ListMetrics struct {
+	Full bool `protobuf:"varint,1,opt,name=full,proto3" json:"full,omitempty"`
+}
+
+func (m *ListMetrics) Reset()         { *m = ListMetrics{} }
+func (m *ListMetrics) String() string { return proto.CompactTextString(m) }
+func (*ListMetrics) ProtoMessage()    {}
+func (*ListMetrics) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_a41a34c64919512b, []int{13}
+}
+func (m *ListMetrics) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ListMetrics) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ListMetrics.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *ListMetrics) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListMetrics.Merge(dst, src)
+}
+func (m *ListMetrics) XXX_Size() int {
+	return m.Size()
+}
+func (m *ListMetrics) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListMetrics.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListMetrics proto.InternalMessageInfo
+
+func (m *ListMetrics) GetFull() bool {
+	if m != nil {
+		return m.Full
+	}
+	return false
+}
+
+func init() {
+	proto.RegisterType((*AdminCmdRequest)(nil), "pb.AdminCmdRequest")
+	proto.RegisterType((*Info)(nil), "pb.Info")
+	proto.RegisterType((*InfoResponse)(nil), "pb.InfoResponse")
+	proto.RegisterType((*Health)(nil), "pb.Health")
+	proto.RegisterType((*JoinCluster)(nil), "pb.JoinCluster")
+	proto.RegisterType((*SlaveOf)(nil), "pb.SlaveOf")
+	proto.RegisterType((*LeaveCluster)(nil), "pb.LeaveCluster")
+	proto.RegisterType((*Drain)(nil), "pb.Drain")
+	proto.RegisterType((*Route)(nil), "pb.Route")
+	proto.RegisterType((*RouteResponse)(nil), "pb.RouteResponse")
+	proto.RegisterType((*Rebalance)(nil), "pb.Rebalance")
+	proto.RegisterType((*RouteMove)(nil), "pb.RouteMove")
+	proto.RegisterType((*RebalanceResponse)(nil), "pb.RebalanceResponse")
+	proto.RegisterType((*ListMetrics)(nil), "pb.ListMetrics")
+}
+func (m *AdminCmdRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *AdminCmdRequest) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Command != nil {
+		nn1, err := m.Command.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += nn1
+	}
+	return i, nil
+}
+
+func (m *AdminCmdRequest_Info) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.Info != nil {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Info.Size()))
+		n2, err := m.Info.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n2
+	}
+	return i, nil
+}
+func (m *AdminCmdRequest_JoinCluster) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.JoinCluster != nil {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.JoinCluster.Size()))
+		n3, err := m.JoinCluster.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n3
+	}
+	return i, nil
+}
+func (m *AdminCmdRequest_SlaveOf) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.SlaveOf != nil {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.SlaveOf.Size()))
+		n4, err := m.SlaveOf.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n4
+	}
+	return i, nil
+}
+func (m *AdminCmdRequest_LeaveCluster) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.LeaveCluster != nil {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.LeaveCluster.Size()))
+		n5, err := m.LeaveCluster.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n5
+	}
+	return i, nil
+}
+func (m *AdminCmdRequest_Route) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.Route != nil {
+		dAtA[i] = 0x2a
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Route.Size()))
+		n6, err := m.Route.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n6
+	}
+	return i, nil
+}
+func (m *AdminCmdRequest_Drain) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.Drain != nil {
+		dAtA[i] = 0x32
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Drain.Size()))
+		n7, err := m.Drain.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n7
+	}
+	return i, nil
+}
+func (m *AdminCmdRequest_Rebalance) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.Rebalance != nil {
+		dAtA[i] = 0x3a
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Rebalance.Size()))
+		n8, err := m.Rebalance.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n8
+	}
+	return i, nil
+}
+func (m *AdminCmdRequest_ListMetrics) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.ListMetrics != nil {
+		dAtA[i] = 0x42
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.ListMetrics.Size()))
+		n9, err := m.ListMetrics.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n9
+	}
+	return i, nil
+}
+func (m *Info) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Info) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Detailed {
+		dAtA[i] = 0x8
+		i++
+		if m.Detailed {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	return i, nil
+}
+
+func (m *InfoResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *InfoResponse) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Status != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Status))
+	}
+	if len(m.ErrorMsg) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.ErrorMsg)))
+		i += copy(dAtA[i:], m.ErrorMsg)
+	}
+	if len(m.Version) > 0 {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.Version)))
+		i += copy(dAtA[i:], m.Version)
+	}
+	if len(m.ShardID) > 0 {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.ShardID)))
+		i += copy(dAtA[i:], m.ShardID)
+	}
+	if len(m.Role) > 0 {
+		dAtA[i] = 0x2a
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.Role)))
+		i += copy(dAtA[i:], m.Role)
+	}
+	if len(m.Addr) > 0 {
+		dAtA[i] = 0x32
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.Addr)))
+		i += copy(dAtA[i:], m.Addr)
+	}
+	if len(m.MasterAddr) > 0 {
+		dAtA[i] = 0x3a
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.MasterAddr)))
+		i += copy(dAtA[i:], m.MasterAddr)
+	}
+	if m.StartTime != 0 {
+		dAtA[i] = 0x40
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.StartTime))
+	}
+	if m.MinT != 0 {
+		dAtA[i] = 0x48
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.MinT))
+	}
+	if m.MaxT != 0 {
+		dAtA[i] = 0x50
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.MaxT))
+	}
+	if m.DiskFree != 0 {
+		dAtA[i] = 0x58
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.DiskFree))
+	}
+	if m.SeriesNum != 0 {
+		dAtA[i] = 0x60
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.SeriesNum))
+	}
+	if m.SamplesReceived != 0 {
+		dAtA[i] = 0x68
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.SamplesReceived))
+	}
+	if m.SamplesSucceed != 0 {
+		dAtA[i] = 0x70
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.SamplesSucceed))
+	}
+	if m.SamplesFailed != 0 {
+		dAtA[i] = 0x78
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.SamplesFailed))
+	}
+	if m.SamplesOutOfOrder != 0 {
+		dAtA[i] = 0x80
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.SamplesOutOfOrder))
+	}
+	if m.SamplesOutOfBounds != 0 {
+		dAtA[i] = 0x88
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.SamplesOutOfBounds))
+	}
+	if m.SamplesDuplicated != 0 {
+		dAtA[i] = 0x90
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.SamplesDuplicated))
+	}
+	if m.Compacting {
+		dAtA[i] = 0x98
+		i++
+		dAtA[i] = 0x1
+		i++
+		if m.Compacting {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.Load != 0 {
+		dAtA[i] = 0xa0
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Load))
+	}
+	if m.Health != nil {
+		dAtA[i] = 0xaa
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Health.Size()))
+		n10, err := m.Health.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n10
+	}
+	return i, nil
+}
+
+func (m *Health) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Health) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Status) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.Status)))
+		i += copy(dAtA[i:], m.Status)
+	}
+	if len(m.EtcdErr) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.EtcdErr)))
+		i += copy(dAtA[i:], m.EtcdErr)
+	}
+	if m.WatchRunning {
+		dAtA[i] = 0x18
+		i++
+		if m.WatchRunning {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.WatchAlive != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.WatchAlive))
+	}
+	if m.ShardsProbed != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.ShardsProbed))
+	}
+	if len(m.Unreachable) > 0 {
+		for _, s := range m.Unreachable {
+			dAtA[i] = 0x32
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
+	return i, nil
+}
+
+func (m *JoinCluster) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *JoinCluster) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	return i, nil
+}
+
+func (m *SlaveOf) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SlaveOf) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.MasterAddr) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.MasterAddr)))
+		i += copy(dAtA[i:], m.MasterAddr)
+	}
+	return i, nil
+}
+
+func (m *LeaveCluster) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *LeaveCluster) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	return i, nil
+}
+
+func (m *Drain) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Drain) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Undo {
+		dAtA[i] = 0x8
+		i++
+		if m.Undo {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	return i, nil
+}
+
+func (m *Route) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Route) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Labels) > 0 {
+		for _, msg := range m.Labels {
+			dAtA[i] = 0xa
+			i++
+			i = encodeVarintAdmin(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.Time != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Time))
+	}
+	return i, nil
+}
+
+func (m *RouteResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *RouteResponse) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Status != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Status))
+	}
+	if len(m.ErrorMsg) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.ErrorMsg)))
+		i += copy(dAtA[i:], m.ErrorMsg)
+	}
+	if len(m.ShardGroup) > 0 {
+		for _, s := range m.ShardGroup {
+			dAtA[i] = 0x1a
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
+	if len(m.ShardID) > 0 {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.ShardID)))
+		i += copy(dAtA[i:], m.ShardID)
+	}
+	if len(m.RouteKey) > 0 {
+		dAtA[i] = 0x2a
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.RouteKey)))
+		i += copy(dAtA[i:], m.RouteKey)
+	}
+	if m.FromCache {
+		dAtA[i] = 0x30
+		i++
+		if m.FromCache {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	return i, nil
+}
+
+func (m *Rebalance) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Rebalance) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Metrics) > 0 {
+		for _, s := range m.Metrics {
+			dAtA[i] = 0xa
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
+	if m.Days != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Days))
+	}
+	if m.DryRun {
+		dAtA[i] = 0x18
+		i++
+		if m.DryRun {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.Status {
+		dAtA[i] = 0x20
+		i++
+		if m.Status {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	return i, nil
+}
+
+func (m *RouteMove) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *RouteMove) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Metric) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.Metric)))
+		i += copy(dAtA[i:], m.Metric)
+	}
+	if m.Day != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Day))
+	}
+	if len(m.From) > 0 {
+		for _, s := range m.From {
+			dAtA[i] = 0x1a
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
+	if len(m.To) > 0 {
+		for _, s := range m.To {
+			dAtA[i] = 0x22
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
+	return i, nil
+}
+
+func (m *RebalanceResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *RebalanceResponse) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Status != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Status))
+	}
+	if len(m.ErrorMsg) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.ErrorMsg)))
+		i += copy(dAtA[i:], m.ErrorMsg)
+	}
+	if len(m.Moves) > 0 {
+		for _, msg := range m.Moves {
+			dAtA[i] = 0x1a
+			i++
+			i = encodeVarintAdmin(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.Applied != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Applied))
+	}
+	if m.Skipped != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(m.Skipped))
+	}
+	if m.Running {
+		dAtA[i] = 0x30
+		i++
+		if m.Running {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if len(m.LastErr) > 0 {
+		dAtA[i] = 0x3a
+		i++
+		i = encodeVarintAdmin(dAtA, i, uint64(len(m.LastErr)))
+		i += copy(dAtA[i:], m.LastErr)
+	}
+	return i, nil
+}
+
+func (m *ListMetrics) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ListMetrics) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Full {
+		dAtA[i] = 0x8
+		i++
+		if m.Full {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	return i, nil
+}
+
+func encodeVarintAdmin(dAtA []byte, offset int, v uint64) int {
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return offset + 1
+}
+func (m *AdminCmdRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Command != nil {
+		n += m.Command.Size()
+	}
+	return n
+}
+
+func (m *AdminCmdRequest_Info) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Info != nil {
+		l = m.Info.Size()
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	return n
+}
+func (m *AdminCmdRequest_JoinCluster) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.JoinCluster != nil {
+		l = m.JoinCluster.Size()
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	return n
+}
+func (m *AdminCmdRequest_SlaveOf) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.SlaveOf != nil {
+		l = m.SlaveOf.Size()
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	return n
+}
+func (m *AdminCmdRequest_LeaveCluster) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.LeaveCluster != nil {
+		l = m.LeaveCluster.Size()
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	return n
+}
+func (m *AdminCmdRequest_Route) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Route != nil {
+		l = m.Route.Size()
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	return n
+}
+func (m *AdminCmdRequest_Drain) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Drain != nil {
+		l = m.Drain.Size()
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	return n
+}
+func (m *AdminCmdRequest_Rebalance) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Rebalance != nil {
+		l = m.Rebalance.Size()
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	return n
+}
+func (m *AdminCmdRequest_ListMetrics) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.ListMetrics != nil {
+		l = m.ListMetrics.Size()
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	return n
+}
+func (m *Info) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Detailed {
+		n += 2
+	}
+	return n
+}
+
+func (m *InfoResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Status != 0 {
+		n += 1 + sovAdmin(uint64(m.Status))
+	}
+	l = len(m.ErrorMsg)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	l = len(m.Version)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	l = len(m.ShardID)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	l = len(m.Role)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	l = len(m.Addr)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	l = len(m.MasterAddr)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	if m.StartTime != 0 {
+		n += 1 + sovAdmin(uint64(m.StartTime))
+	}
+	if m.MinT != 0 {
+		n += 1 + sovAdmin(uint64(m.MinT))
+	}
+	if m.MaxT != 0 {
+		n += 1 + sovAdmin(uint64(m.MaxT))
+	}
+	if m.DiskFree != 0 {
+		n += 1 + sovAdmin(uint64(m.DiskFree))
+	}
+	if m.SeriesNum != 0 {
+		n += 1 + sovAdmin(uint64(m.SeriesNum))
+	}
+	if m.SamplesReceived != 0 {
+		n += 1 + sovAdmin(uint64(m.SamplesReceived))
+	}
+	if m.SamplesSucceed != 0 {
+		n += 1 + sovAdmin(uint64(m.SamplesSucceed))
+	}
+	if m.SamplesFailed != 0 {
+		n += 1 + sovAdmin(uint64(m.SamplesFailed))
+	}
+	if m.SamplesOutOfOrder != 0 {
+		n += 2 + sovAdmin(uint64(m.SamplesOutOfOrder))
+	}
+	if m.SamplesOutOfBounds != 0 {
+		n += 2 + sovAdmin(uint64(m.SamplesOutOfBounds))
+	}
+	if m.SamplesDuplicated != 0 {
+		n += 2 + sovAdmin(uint64(m.SamplesDuplicated))
+	}
+	if m.Compacting {
+		n += 3
+	}
+	if m.Load != 0 {
+		n += 2 + sovAdmin(uint64(m.Load))
+	}
+	if m.Health != nil {
+		l = m.Health.Size()
+		n += 2 + l + sovAdmin(uint64(l))
+	}
+	return n
+}
+
+func (m *Health) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Status)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	l = len(m.EtcdErr)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	if m.WatchRunning {
+		n += 2
+	}
+	if m.WatchAlive != 0 {
+		n += 1 + sovAdmin(uint64(m.WatchAlive))
+	}
+	if m.ShardsProbed != 0 {
+		n += 1 + sovAdmin(uint64(m.ShardsProbed))
+	}
+	if len(m.Unreachable) > 0 {
+		for _, s := range m.Unreachable {
+			l = len(s)
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *JoinCluster) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
+}
+
+func (m *SlaveOf) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.MasterAddr)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	return n
+}
+
+func (m *LeaveCluster) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
+}
+
+func (m *Drain) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Undo {
+		n += 2
+	}
+	return n
+}
+
+func (m *Route) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Labels) > 0 {
+		for _, e := range m.Labels {
+			l = e.Size()
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	if m.Time != 0 {
+		n += 1 + sovAdmin(uint64(m.Time))
+	}
+	return n
+}
+
+func (m *RouteResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Status != 0 {
+		n += 1 + sovAdmin(uint64(m.Status))
+	}
+	l = len(m.ErrorMsg)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	if len(m.ShardGroup) > 0 {
+		for _, s := range m.ShardGroup {
+			l = len(s)
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	l = len(m.ShardID)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	l = len(m.RouteKey)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	if m.FromCache {
+		n += 2
+	}
+	return n
+}
+
+func (m *Rebalance) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Metrics) > 0 {
+		for _, s := range m.Metrics {
+			l = len(s)
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	if m.Days != 0 {
+		n += 1 + sovAdmin(uint64(m.Days))
+	}
+	if m.DryRun {
+		n += 2
+	}
+	if m.Status {
+		n += 2
+	}
+	return n
+}
+
+func (m *RouteMove) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Metric)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	if m.Day != 0 {
+		n += 1 + sovAdmin(uint64(m.Day))
+	}
+	if len(m.From) > 0 {
+		for _, s := range m.From {
+			l = len(s)
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	if len(m.To) > 0 {
+		for _, s := range m.To {
+			l = len(s)
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *RebalanceResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Status != 0 {
+		n += 1 + sovAdmin(uint64(m.Status))
+	}
+	l = len(m.ErrorMsg)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	if len(m.Moves) > 0 {
+		for _, e := range m.Moves {
+			l = e.Size()
+			n += 1 + l + sovAdmin(uint64(l))
+		}
+	}
+	if m.Applied != 0 {
+		n += 1 + sovAdmin(uint64(m.Applied))
+	}
+	if m.Skipped != 0 {
+		n += 1 + sovAdmin(uint64(m.Skipped))
+	}
+	if m.Running {
+		n += 2
+	}
+	l = len(m.LastErr)
+	if l > 0 {
+		n += 1 + l + sovAdmin(uint64(l))
+	}
+	return n
+}
+
+func (m *ListMetrics) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Full {
+		n += 2
+	}
+	return n
+}
+
+func sovAdmin(x uint64) (n int) {
+	for {
+		n++
+		x >>= 7
+		if x == 0 {
+			break
+		}
+	}
+	return n
+}
+func sozAdmin(x uint64) (n int) {
+	return sovAdmin(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *AdminCmdRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AdminCmdRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AdminCmdRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Info", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Info{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Command = &AdminCmdRequest_Info{v}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field JoinCluster", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &JoinCluster{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Command = &AdminCmdRequest_JoinCluster{v}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SlaveOf", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &SlaveOf{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Command = &AdminCmdRequest_SlaveOf{v}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LeaveCluster", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &LeaveCluster{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Command = &AdminCmdRequest_LeaveCluster{v}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Route", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Route{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Command = &AdminCmdRequest_Route{v}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Drain", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Drain{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Command = &AdminCmdRequest_Drain{v}
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Rebalance", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Rebalance{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Command = &AdminCmdRequest_Rebalance{v}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ListMetrics", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &ListMetrics{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Command = &AdminCmdRequest_ListMetrics{v}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Info) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Info: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Info: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Detailed", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Detailed = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *InfoResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: InfoResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: InfoResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+			}
+			m.Status = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Status |= (StatusCode(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ErrorMsg", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ErrorMsg = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Version = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ShardID", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ShardID = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Role", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Role = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Addr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MasterAddr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.MasterAddr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartTime", wireType)
+			}
+			m.StartTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StartTime |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MinT", wireType)
+			}
+			m.MinT = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MinT |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxT", wireType)
+			}
+			m.MaxT = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxT |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DiskFree", wireType)
+			}
+			m.DiskFree = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.DiskFree |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SeriesNum", wireType)
+			}
+			m.SeriesNum = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SeriesNum |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SamplesReceived", wireType)
+			}
+			m.SamplesReceived = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SamplesReceived |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SamplesSucceed", wireType)
+			}
+			m.SamplesSucceed = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SamplesSucceed |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 15:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SamplesFailed", wireType)
+			}
+			m.SamplesFailed = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SamplesFailed |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SamplesOutOfOrder", wireType)
+			}
+			m.SamplesOutOfOrder = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SamplesOutOfOrder |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 17:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SamplesOutOfBounds", wireType)
+			}
+			m.SamplesOutOfBounds = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SamplesOutOfBounds |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 18:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SamplesDuplicated", wireType)
+			}
+			m.SamplesDuplicated = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SamplesDuplicated |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 19:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compacting", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Compacting = bool(v != 0)
+		case 20:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Load", wireType)
+			}
+			m.Load = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Load |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 21:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Health", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Health == nil {
+				m.Health = &Health{}
+			}
+			if err := m.Health.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Health) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Health: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Health: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Status = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EtcdErr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.EtcdErr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field WatchRunning", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.WatchRunning = bool(v != 0)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field WatchAlive", wireType)
+			}
+			m.WatchAlive = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.WatchAlive |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ShardsProbed", wireType)
+			}
+			m.ShardsProbed = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ShardsProbed |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Unreachable", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Unreachable = append(m.Unreachable, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *JoinCluster) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: JoinCluster: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: JoinCluster: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SlaveOf) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SlaveOf: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SlaveOf: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MasterAddr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.MasterAddr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *LeaveCluster) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: LeaveCluster: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: LeaveCluster: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Drain) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Drain: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Drain: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Undo", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Undo = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Route) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Route: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Route: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Labels = append(m.Labels, Label{})
+			if err := m.Labels[len(m.Labels)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Time", wireType)
+			}
+			m.Time = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Time |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *RouteResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: RouteResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: RouteResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+			}
+			m.Status = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Status |= (StatusCode(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ErrorMsg", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ErrorMsg = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ShardGroup", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ShardGroup = append(m.ShardGroup, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ShardID", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ShardID = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RouteKey", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.RouteKey = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FromCache", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.FromCache = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Rebalance) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAdmin
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Rebalance: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Rebalance: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metrics", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Metrics = append(m.Metrics, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Days", wireType)
+			}
+			m.Days = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Days |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DryRun", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.DryRun = bool(v != 0)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Status = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAdmin(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
 	}
-	var l int
-	_ = l
-	return n
-}
 
-func sovAdmin(x uint64) (n int) {
-	for {
-		n++
-		x >>= 7
-		if x == 0 {
-			break
-		}
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
 	}
-	return n
-}
-func sozAdmin(x uint64) (n int) {
-	return sovAdmin(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+	return nil
 }
-func (m *AdminCmdRequest) Unmarshal(dAtA []byte) error {
+func (m *RouteMove) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -436,17 +4312,17 @@ func (m *AdminCmdRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: AdminCmdRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: RouteMove: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: AdminCmdRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: RouteMove: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Info", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Metric", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowAdmin
@@ -456,29 +4332,45 @@ func (m *AdminCmdRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				stringLen |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthAdmin
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &Info{}
-			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			m.Command = &AdminCmdRequest_Info{v}
+			m.Metric = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Day", wireType)
+			}
+			m.Day = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Day |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field JoinCluster", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field From", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowAdmin
@@ -488,23 +4380,49 @@ func (m *AdminCmdRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				stringLen |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthAdmin
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &JoinCluster{}
-			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.From = append(m.From, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field To", wireType)
 			}
-			m.Command = &AdminCmdRequest_JoinCluster{v}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.To = append(m.To, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -527,7 +4445,7 @@ func (m *AdminCmdRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Info) Unmarshal(dAtA []byte) error {
+func (m *RebalanceResponse) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -550,12 +4468,178 @@ func (m *Info) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Info: wiretype end group for non-group")
+			return fmt.Errorf("proto: RebalanceResponse: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Info: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: RebalanceResponse: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+			}
+			m.Status = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Status |= (StatusCode(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ErrorMsg", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ErrorMsg = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Moves", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Moves = append(m.Moves, RouteMove{})
+			if err := m.Moves[len(m.Moves)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Applied", wireType)
+			}
+			m.Applied = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Applied |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Skipped", wireType)
+			}
+			m.Skipped = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Skipped |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Running", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Running = bool(v != 0)
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastErr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAdmin
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.LastErr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipAdmin(dAtA[iNdEx:])
@@ -577,7 +4661,7 @@ func (m *Info) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *JoinCluster) Unmarshal(dAtA []byte) error {
+func (m *ListMetrics) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -600,12 +4684,32 @@ func (m *JoinCluster) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: JoinCluster: wiretype end group for non-group")
+			return fmt.Errorf("proto: ListMetrics: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: JoinCluster: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: ListMetrics: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Full", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAdmin
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Full = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipAdmin(dAtA[iNdEx:])
@@ -732,20 +4836,73 @@ var (
 	ErrIntOverflowAdmin   = fmt.Errorf("proto: integer overflow")
 )
 
-func init() { proto.RegisterFile("admin.proto", fileDescriptor_admin_b9c1af4f7059a473) }
-
-var fileDescriptor_admin_b9c1af4f7059a473 = []byte{
-	// 177 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x4e, 0x4c, 0xc9, 0xcd,
-	0xcc, 0xd3, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x2a, 0x48, 0x52, 0x2a, 0xe4, 0xe2, 0x77,
-	0x04, 0x09, 0x39, 0xe7, 0xa6, 0x04, 0xa5, 0x16, 0x96, 0xa6, 0x16, 0x97, 0x08, 0xc9, 0x71, 0xb1,
-	0x64, 0xe6, 0xa5, 0xe5, 0x4b, 0x30, 0x2a, 0x30, 0x6a, 0x70, 0x1b, 0x71, 0xe8, 0x15, 0x24, 0xe9,
-	0x79, 0xe6, 0xa5, 0xe5, 0x7b, 0x30, 0x04, 0x81, 0xc5, 0x85, 0x8c, 0xb9, 0xb8, 0xb3, 0xf2, 0x33,
-	0xf3, 0x9c, 0x73, 0x4a, 0x8b, 0x4b, 0x52, 0x8b, 0x24, 0x98, 0xc0, 0xca, 0xf8, 0x41, 0xca, 0xbc,
-	0x10, 0xc2, 0x1e, 0x0c, 0x41, 0xc8, 0xaa, 0x9c, 0x38, 0xb9, 0xd8, 0x93, 0xf3, 0x73, 0x73, 0x13,
-	0xf3, 0x52, 0x94, 0xd8, 0xb8, 0x58, 0x40, 0xe6, 0x29, 0xf1, 0x72, 0x71, 0x23, 0x69, 0x70, 0x92,
-	0x39, 0xf1, 0x48, 0x8e, 0xf1, 0xc2, 0x23, 0x39, 0xc6, 0x07, 0x8f, 0xe4, 0x18, 0x27, 0x3c, 0x96,
-	0x63, 0xb8, 0xf0, 0x58, 0x8e, 0xe1, 0xc6, 0x63, 0x39, 0x86, 0x28, 0xa6, 0x82, 0xa4, 0x24, 0x36,
-	0xb0, 0x93, 0x8d, 0x01, 0x01, 0x00, 0x00, 0xff, 0xff, 0x75, 0x22, 0xb8, 0x8f, 0xc1, 0x00, 0x00,
-	0x00,
+func init() { proto.RegisterFile("admin.proto", fileDescriptor_admin_a41a34c64919512b) }
+
+var fileDescriptor_admin_a41a34c64919512b = []byte{
+	// 1037 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0xcd, 0x6e, 0xdb, 0x46,
+	0x10, 0xd6, 0xbf, 0xc4, 0x91, 0x25, 0xdb, 0xdb, 0x1f, 0x2c, 0xdc, 0x40, 0x75, 0x88, 0x22, 0x71,
+	0x80, 0xc6, 0x01, 0x12, 0xa0, 0x77, 0xff, 0x24, 0x55, 0x5a, 0xbb, 0x2e, 0x36, 0xbe, 0xb4, 0xb7,
+	0x15, 0x77, 0x65, 0xb1, 0x21, 0xb9, 0xec, 0x2e, 0xa9, 0xc6, 0x6f, 0xd1, 0xe7, 0xe8, 0x73, 0x14,
+	0x68, 0x8e, 0x39, 0xf6, 0x54, 0x14, 0xf6, 0xad, 0xcf, 0xd0, 0x43, 0x31, 0x43, 0x52, 0xa2, 0xdc,
+	0xa2, 0xa7, 0xdc, 0x66, 0xbe, 0xef, 0xdb, 0xd9, 0xe5, 0xb7, 0xb3, 0x23, 0xc1, 0x50, 0xaa, 0x38,
+	0x4c, 0x0e, 0x53, 0x6b, 0x32, 0xc3, 0x5a, 0xe9, 0x6c, 0xef, 0xf1, 0x55, 0x98, 0x2d, 0xf2, 0xd9,
+	0x61, 0x60, 0xe2, 0x27, 0x57, 0xe6, 0xca, 0x3c, 0x21, 0x6a, 0x96, 0xcf, 0x29, 0xa3, 0x84, 0xa2,
+	0x62, 0xc9, 0xde, 0x20, 0x9d, 0x15, 0x91, 0xff, 0x77, 0x0b, 0xb6, 0x8f, 0xb0, 0xd8, 0x49, 0xac,
+	0x84, 0xfe, 0x31, 0xd7, 0x2e, 0x63, 0x13, 0xe8, 0x84, 0xc9, 0xdc, 0xf0, 0xe6, 0x7e, 0xf3, 0x60,
+	0xf8, 0x74, 0x70, 0x98, 0xce, 0x0e, 0x5f, 0x26, 0x73, 0x33, 0x6d, 0x08, 0xc2, 0xd9, 0x33, 0x18,
+	0xfe, 0x60, 0xc2, 0xe4, 0x24, 0xca, 0x5d, 0xa6, 0x2d, 0x6f, 0x91, 0x6c, 0x1b, 0x65, 0x5f, 0xad,
+	0xe1, 0x69, 0x43, 0xd4, 0x55, 0xec, 0x21, 0xf4, 0x5d, 0x24, 0x97, 0xfa, 0x62, 0xce, 0xdb, 0xb4,
+	0x60, 0x88, 0x0b, 0x5e, 0x15, 0xd0, 0xb4, 0x21, 0x2a, 0x96, 0x7d, 0x01, 0x5b, 0x91, 0x96, 0x4b,
+	0x5d, 0x95, 0xef, 0x90, 0x7a, 0x07, 0xd5, 0x67, 0x35, 0x7c, 0xda, 0x10, 0x1b, 0x3a, 0x76, 0x1f,
+	0xba, 0xd6, 0xe4, 0x99, 0xe6, 0x5d, 0x5a, 0xe0, 0xe1, 0x02, 0x81, 0xc0, 0xb4, 0x21, 0x0a, 0x06,
+	0x25, 0xca, 0xca, 0x30, 0xe1, 0xbd, 0xb5, 0xe4, 0x14, 0x01, 0x94, 0x10, 0xc3, 0x1e, 0x83, 0x67,
+	0xf5, 0x4c, 0x46, 0x32, 0x09, 0x34, 0xef, 0x93, 0x6c, 0x44, 0x95, 0x2a, 0x70, 0xda, 0x10, 0x6b,
+	0x05, 0x5a, 0x11, 0x85, 0x2e, 0x3b, 0xd7, 0x99, 0x0d, 0x03, 0xc7, 0x07, 0x6b, 0x2b, 0xce, 0xd6,
+	0x30, 0x5a, 0x51, 0x53, 0x1d, 0x7b, 0xd0, 0x0f, 0x4c, 0x1c, 0xcb, 0x44, 0xf9, 0x3e, 0x74, 0xd0,
+	0x5a, 0xb6, 0x07, 0x03, 0xa5, 0x33, 0x19, 0x46, 0x5a, 0x91, 0xed, 0x03, 0xb1, 0xca, 0xfd, 0x5f,
+	0xba, 0xb0, 0x85, 0x22, 0xa1, 0x5d, 0x6a, 0x12, 0xa7, 0xd9, 0x03, 0xe8, 0xb9, 0x4c, 0x66, 0xb9,
+	0x23, 0xe9, 0xf8, 0xe9, 0x98, 0x9c, 0x24, 0xe4, 0xc4, 0x28, 0x2d, 0x4a, 0x16, 0x8b, 0x6a, 0x6b,
+	0x8d, 0x3d, 0x77, 0x57, 0x74, 0x49, 0x9e, 0x58, 0xe5, 0x8c, 0x43, 0x7f, 0xa9, 0xad, 0x0b, 0x4d,
+	0x42, 0xd7, 0xe1, 0x89, 0x2a, 0x45, 0xc6, 0x2d, 0xa4, 0x55, 0x2f, 0x4f, 0xc9, 0x7a, 0x4f, 0x54,
+	0x29, 0x63, 0xd0, 0xb1, 0x26, 0x2a, 0x0c, 0xf6, 0x04, 0xc5, 0x88, 0x49, 0xa5, 0x2c, 0x39, 0xea,
+	0x09, 0x8a, 0xd9, 0x04, 0x20, 0x96, 0x78, 0x27, 0x47, 0xc8, 0xf4, 0x89, 0xa9, 0x21, 0xec, 0x1e,
+	0x78, 0x2e, 0x93, 0x36, 0xbb, 0x0c, 0x63, 0x4d, 0x96, 0xb5, 0xc5, 0x1a, 0xc0, 0x8a, 0x71, 0x98,
+	0x5c, 0x72, 0x8f, 0x08, 0x8a, 0x09, 0x93, 0x6f, 0x2e, 0x39, 0x94, 0x98, 0x7c, 0x73, 0x49, 0x96,
+	0x85, 0xee, 0xf5, 0x0b, 0xab, 0x35, 0x1f, 0xee, 0x37, 0x0f, 0x3a, 0x62, 0x95, 0xd3, 0x0e, 0xda,
+	0x86, 0xda, 0x7d, 0x93, 0xc7, 0x7c, 0x8b, 0xc8, 0x35, 0xc0, 0x0e, 0x60, 0xdb, 0xc9, 0x38, 0x8d,
+	0xb4, 0x13, 0x3a, 0xd0, 0xe1, 0x52, 0x2b, 0x3e, 0x22, 0xcd, 0x5d, 0x98, 0x3d, 0x80, 0x71, 0x09,
+	0xbd, 0xca, 0x83, 0x40, 0x6b, 0xc5, 0xc7, 0x24, 0xbc, 0x83, 0xb2, 0xcf, 0x60, 0x54, 0x22, 0x2f,
+	0x8a, 0x3b, 0xdc, 0x26, 0xd9, 0x26, 0xc8, 0x3e, 0x87, 0xdd, 0x12, 0xb8, 0xc8, 0xb3, 0x8b, 0xf9,
+	0x85, 0x55, 0xda, 0xf2, 0x1d, 0x52, 0xfe, 0x9b, 0x60, 0x87, 0xc0, 0xea, 0xe0, 0xb1, 0xc9, 0x13,
+	0xe5, 0xf8, 0x2e, 0xc9, 0xff, 0x83, 0xa9, 0x55, 0x3f, 0xcd, 0xd3, 0x28, 0x0c, 0x64, 0xa6, 0x15,
+	0x67, 0x1b, 0xd5, 0xd7, 0x04, 0xde, 0x51, 0x60, 0xe2, 0x54, 0x06, 0x59, 0x98, 0x5c, 0xf1, 0x0f,
+	0xa8, 0xe5, 0x6a, 0x08, 0x3a, 0x1e, 0x19, 0xa9, 0xf8, 0x87, 0x85, 0xe3, 0x18, 0x33, 0x1f, 0x7a,
+	0x0b, 0x2d, 0xa3, 0x6c, 0xc1, 0x3f, 0xa2, 0x3e, 0x07, 0xec, 0xbb, 0x29, 0x21, 0xa2, 0x64, 0xfc,
+	0x5f, 0x9b, 0xd0, 0x2b, 0x20, 0xf6, 0xf1, 0x46, 0x9b, 0x7a, 0xab, 0xb6, 0xe4, 0xd0, 0xd7, 0x59,
+	0xa0, 0x9e, 0x5b, 0x5b, 0x76, 0x65, 0x95, 0x32, 0x1f, 0xb6, 0x7e, 0x92, 0x59, 0xb0, 0x10, 0x79,
+	0x92, 0xe0, 0xb1, 0xda, 0x74, 0xac, 0x0d, 0x0c, 0x0f, 0x4e, 0xf9, 0x51, 0x14, 0x2e, 0x35, 0x75,
+	0x68, 0x5b, 0xd4, 0x10, 0xac, 0x41, 0xfd, 0xea, 0xbe, 0xb5, 0x66, 0xa6, 0x15, 0x35, 0xeb, 0x48,
+	0x6c, 0x60, 0x6c, 0x1f, 0x86, 0x79, 0x62, 0xb5, 0x0c, 0x16, 0x72, 0x16, 0x69, 0xde, 0xdb, 0x6f,
+	0x1f, 0x78, 0xa2, 0x0e, 0xf9, 0x23, 0x18, 0xd6, 0x66, 0x99, 0xff, 0x08, 0xfa, 0xe5, 0xa4, 0xba,
+	0xd3, 0xdc, 0xcd, 0xbb, 0xcd, 0xed, 0x8f, 0x61, 0xab, 0x3e, 0xa6, 0xfc, 0x4f, 0xa0, 0x4b, 0x23,
+	0x06, 0x1d, 0xcd, 0x13, 0x65, 0xca, 0xe7, 0x4d, 0xb1, 0x7f, 0x0a, 0x5d, 0x1a, 0x51, 0xec, 0x21,
+	0xf4, 0x22, 0x39, 0xd3, 0x11, 0x7a, 0xd5, 0xae, 0x46, 0xd3, 0x19, 0x22, 0xc7, 0x9d, 0xb7, 0x7f,
+	0x7c, 0xda, 0x10, 0x25, 0x8d, 0x55, 0x32, 0x7c, 0x36, 0xad, 0xe2, 0x5e, 0x30, 0xf6, 0x7f, 0x6b,
+	0xc2, 0x88, 0xca, 0xbc, 0xd7, 0x09, 0x31, 0x01, 0x20, 0xd3, 0xbe, 0xb4, 0x26, 0x4f, 0x79, 0x9b,
+	0x3c, 0xaa, 0x21, 0xff, 0x33, 0x27, 0xf6, 0x60, 0x40, 0xf3, 0xf6, 0x6b, 0x7d, 0x5d, 0xce, 0x8a,
+	0x55, 0x8e, 0x2f, 0x73, 0x6e, 0x4d, 0x7c, 0x22, 0x83, 0x85, 0xa6, 0xa1, 0x31, 0x10, 0x6b, 0xc0,
+	0x0f, 0xc1, 0x5b, 0x0d, 0x5a, 0xdc, 0x20, 0x2e, 0xe7, 0x6a, 0x93, 0x76, 0xaf, 0x52, 0x34, 0x41,
+	0xc9, 0x6b, 0x47, 0x47, 0x1e, 0x09, 0x8a, 0xb1, 0xdb, 0x94, 0xbd, 0x16, 0x79, 0x52, 0x76, 0x4d,
+	0x99, 0xd5, 0xba, 0xb0, 0x53, 0xe0, 0x45, 0xe6, 0x7f, 0x07, 0x1e, 0x79, 0x76, 0x6e, 0x96, 0x1a,
+	0x45, 0x45, 0xed, 0xaa, 0x55, 0x8b, 0x8c, 0xed, 0x40, 0x5b, 0xc9, 0x6b, 0xda, 0xa7, 0x23, 0x30,
+	0xc4, 0xad, 0xf1, 0xb8, 0xa5, 0x1f, 0x14, 0xb3, 0x31, 0xb4, 0x32, 0xc3, 0x3b, 0x84, 0xb4, 0x32,
+	0xe3, 0xff, 0xd5, 0x84, 0xdd, 0xd5, 0x67, 0xbc, 0xd7, 0x3b, 0x79, 0x04, 0xdd, 0xd8, 0x2c, 0xb5,
+	0xa3, 0xed, 0xab, 0x5f, 0xa6, 0xea, 0x2b, 0xca, 0x4e, 0x29, 0x14, 0xe8, 0x9e, 0x4c, 0xd3, 0x28,
+	0xd4, 0x8a, 0x3e, 0x7c, 0x24, 0xaa, 0x14, 0x19, 0xf7, 0x3a, 0x4c, 0xd3, 0xd5, 0xe3, 0xa8, 0x52,
+	0x64, 0x6c, 0xf9, 0xf4, 0x8a, 0xab, 0xa9, 0x52, 0x64, 0x22, 0xe9, 0xb2, 0xe7, 0xb6, 0x9a, 0xe7,
+	0x55, 0xea, 0xdf, 0x87, 0x61, 0xed, 0xa7, 0x8e, 0xfc, 0xc9, 0xa3, 0xa8, 0xea, 0x72, 0x8c, 0x8f,
+	0xef, 0xbd, 0xbd, 0x99, 0x34, 0xdf, 0xdd, 0x4c, 0x9a, 0x7f, 0xde, 0x4c, 0x9a, 0x3f, 0xdf, 0x4e,
+	0x1a, 0xef, 0x6e, 0x27, 0x8d, 0xdf, 0x6f, 0x27, 0x8d, 0xef, 0x5b, 0xe9, 0x6c, 0xd6, 0xa3, 0x3f,
+	0x22, 0xcf, 0xfe, 0x19, 0x00, 0x8c, 0x09, 0xf7, 0xd1, 0xd4, 0x08, 0x00, 0x00,
 }
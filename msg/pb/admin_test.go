@@ -0,0 +1,107 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAdminCmdRequest_RoundTrip(t *testing.T) {
+	tests := []struct {
+		cmd *AdminCmdRequest
+		tag byte // the first byte on the wire, field number << 3 | wire type
+	}{
+		{&AdminCmdRequest{Command: &AdminCmdRequest_Info{Info: &Info{}}}, 1<<3 | 2},
+		{&AdminCmdRequest{Command: &AdminCmdRequest_JoinCluster{JoinCluster: &JoinCluster{}}}, 2<<3 | 2},
+		{&AdminCmdRequest{Command: &AdminCmdRequest_SlaveOf{SlaveOf: &SlaveOf{MasterAddr: "10.0.0.1:8088"}}}, 3<<3 | 2},
+		{&AdminCmdRequest{Command: &AdminCmdRequest_SlaveOf{SlaveOf: &SlaveOf{}}}, 3<<3 | 2},
+		{&AdminCmdRequest{Command: &AdminCmdRequest_LeaveCluster{LeaveCluster: &LeaveCluster{}}}, 4<<3 | 2},
+	}
+
+	for _, test := range tests {
+		b, err := test.cmd.Marshal()
+		if err != nil {
+			t.Fatalf("marshal %v: %v", test.cmd, err)
+		}
+		if len(b) == 0 || b[0] != test.tag {
+			t.Fatalf("%v: want tag byte %#x, got %x", test.cmd, test.tag, b)
+		}
+
+		got := new(AdminCmdRequest)
+		if err = got.Unmarshal(b); err != nil {
+			t.Fatalf("unmarshal %v: %v", test.cmd, err)
+		}
+		if !reflect.DeepEqual(got, test.cmd) {
+			t.Fatalf("want %v, got %v", test.cmd, got)
+		}
+	}
+
+	got := new(AdminCmdRequest)
+	if err := got.Unmarshal([]byte{3<<3 | 2, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if slaveOf := got.GetSlaveOf(); slaveOf == nil || slaveOf.MasterAddr != "" {
+		t.Fatalf("want slave of no one, got %v", got)
+	}
+}
+
+func TestInfo_Compatible(t *testing.T) {
+	// older nodes send and expect an empty info
+	b, err := (&Info{}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 0 {
+		t.Fatalf("want an empty info marshalled to nothing, got %x", b)
+	}
+
+	b, err = (&Info{Detailed: true}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := new(Info)
+	if err = info.Unmarshal(b); err != nil || !info.Detailed {
+		t.Fatalf("want a detailed info, got %v, err: %v", info, err)
+	}
+
+	resp := &InfoResponse{
+		Status:          StatusCode_Succeed,
+		Version:         "v1.0.0",
+		ShardID:         "607eb5e0-7a08-11e8-9007-00ffd6453d6d",
+		Role:            "slave",
+		Addr:            "10.0.0.2:8088",
+		MasterAddr:      "10.0.0.1:8088",
+		StartTime:       1530109426124,
+		MinT:            1530000000000,
+		MaxT:            1530109426000,
+		DiskFree:        442,
+		SeriesNum:       1000,
+		SamplesReceived: 100,
+		SamplesSucceed:  98,
+		SamplesFailed:   2,
+	}
+	if b, err = resp.Marshal(); err != nil {
+		t.Fatal(err)
+	}
+	got := new(InfoResponse)
+	if err = got.Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, resp) {
+		t.Fatalf("want %v, got %v", resp, got)
+	}
+}
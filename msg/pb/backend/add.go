@@ -0,0 +1,28 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"github.com/baudtime/baudtime/msg/pb"
+)
+
+// Err returns nil if r succeeded, otherwise a *pb.ResponseError.
+func (r *AddResponse) Err() error {
+	if r.Status == pb.StatusCode_Succeed {
+		return nil
+	}
+	return &pb.ResponseError{Code: r.Code, Message: r.Message}
+}
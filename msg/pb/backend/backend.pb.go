@@ -9,6 +9,8 @@ import math "math"
 import pb "github.com/baudtime/baudtime/msg/pb"
 import _ "github.com/gogo/protobuf/gogoproto"
 
+import encoding_binary "encoding/binary"
+
 import io "io"
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -48,7 +50,57 @@ func (x MatchType) String() string {
 	return proto.EnumName(MatchType_name, int32(x))
 }
 func (MatchType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_backend_46c6160745bca556, []int{0}
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{0}
+}
+
+type AggrOp int32
+
+const (
+	AggrOp_AggrNone AggrOp = 0
+	AggrOp_AggrSum  AggrOp = 1
+)
+
+var AggrOp_name = map[int32]string{
+	0: "AggrNone",
+	1: "AggrSum",
+}
+var AggrOp_value = map[string]int32{
+	"AggrNone": 0,
+	"AggrSum":  1,
+}
+
+func (x AggrOp) String() string {
+	return proto.EnumName(AggrOp_name, int32(x))
+}
+func (AggrOp) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{1}
+}
+
+// AckLevel is how many replicas of a shard must have applied a batch before it's acknowledged.
+type AckLevel int32
+
+const (
+	AckLevel_AckAsync  AckLevel = 0
+	AckLevel_AckOne    AckLevel = 1
+	AckLevel_AckQuorum AckLevel = 2
+)
+
+var AckLevel_name = map[int32]string{
+	0: "AckAsync",
+	1: "AckOne",
+	2: "AckQuorum",
+}
+var AckLevel_value = map[string]int32{
+	"AckAsync":  0,
+	"AckOne":    1,
+	"AckQuorum": 2,
+}
+
+func (x AckLevel) String() string {
+	return proto.EnumName(AckLevel_name, int32(x))
+}
+func (AckLevel) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{2}
 }
 
 type Matcher struct {
@@ -61,7 +113,7 @@ func (m *Matcher) Reset()         { *m = Matcher{} }
 func (m *Matcher) String() string { return proto.CompactTextString(m) }
 func (*Matcher) ProtoMessage()    {}
 func (*Matcher) Descriptor() ([]byte, []int) {
-	return fileDescriptor_backend_46c6160745bca556, []int{0}
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{0}
 }
 func (m *Matcher) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -111,19 +163,133 @@ func (m *Matcher) GetValue() string {
 	return ""
 }
 
+// Aggregation is applied by the shards to the series selected, so that only the partial
+// aggregates are sent back to be merged.
+type Aggregation struct {
+	Op       AggrOp   `protobuf:"varint,1,opt,name=op,proto3,enum=backend.AggrOp" json:"op,omitempty"`
+	Grouping []string `protobuf:"bytes,2,rep,name=grouping" json:"grouping,omitempty"`
+}
+
+func (m *Aggregation) Reset()         { *m = Aggregation{} }
+func (m *Aggregation) String() string { return proto.CompactTextString(m) }
+func (*Aggregation) ProtoMessage()    {}
+func (*Aggregation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{1}
+}
+func (m *Aggregation) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Aggregation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Aggregation.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *Aggregation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Aggregation.Merge(dst, src)
+}
+func (m *Aggregation) XXX_Size() int {
+	return m.Size()
+}
+func (m *Aggregation) XXX_DiscardUnknown() {
+	xxx_messageInfo_Aggregation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Aggregation proto.InternalMessageInfo
+
+func (m *Aggregation) GetOp() AggrOp {
+	if m != nil {
+		return m.Op
+	}
+	return AggrOp_AggrNone
+}
+
+func (m *Aggregation) GetGrouping() []string {
+	if m != nil {
+		return m.Grouping
+	}
+	return nil
+}
+
+// ValueBounds keeps the samples whose value is in [min, max] only. NaN is in no bounds, but stale
+// markers are always kept to tell where a series ends, so are native histogram samples.
+type ValueBounds struct {
+	Min float64 `protobuf:"fixed64,1,opt,name=min,proto3" json:"min,omitempty"`
+	Max float64 `protobuf:"fixed64,2,opt,name=max,proto3" json:"max,omitempty"`
+}
+
+func (m *ValueBounds) Reset()         { *m = ValueBounds{} }
+func (m *ValueBounds) String() string { return proto.CompactTextString(m) }
+func (*ValueBounds) ProtoMessage()    {}
+func (*ValueBounds) Descriptor() ([]byte, []int) {
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{2}
+}
+func (m *ValueBounds) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ValueBounds) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ValueBounds.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *ValueBounds) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValueBounds.Merge(dst, src)
+}
+func (m *ValueBounds) XXX_Size() int {
+	return m.Size()
+}
+func (m *ValueBounds) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValueBounds.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValueBounds proto.InternalMessageInfo
+
+func (m *ValueBounds) GetMin() float64 {
+	if m != nil {
+		return m.Min
+	}
+	return 0
+}
+
+func (m *ValueBounds) GetMax() float64 {
+	if m != nil {
+		return m.Max
+	}
+	return 0
+}
+
 type SelectRequest struct {
-	Mint     int64      `protobuf:"zigzag64,1,opt,name=mint,proto3" json:"mint,omitempty"`
-	Maxt     int64      `protobuf:"zigzag64,2,opt,name=maxt,proto3" json:"maxt,omitempty"`
-	Interval int64      `protobuf:"zigzag64,3,opt,name=interval,proto3" json:"interval,omitempty"`
-	Matchers []*Matcher `protobuf:"bytes,4,rep,name=matchers" json:"matchers,omitempty"`
-	SpanCtx  []byte     `protobuf:"bytes,5,opt,name=spanCtx,proto3" json:"spanCtx,omitempty"`
+	Mint           int64        `protobuf:"zigzag64,1,opt,name=mint,proto3" json:"mint,omitempty"`
+	Maxt           int64        `protobuf:"zigzag64,2,opt,name=maxt,proto3" json:"maxt,omitempty"`
+	Interval       int64        `protobuf:"zigzag64,3,opt,name=interval,proto3" json:"interval,omitempty"`
+	Matchers       []*Matcher   `protobuf:"bytes,4,rep,name=matchers" json:"matchers,omitempty"`
+	SpanCtx        []byte       `protobuf:"bytes,5,opt,name=spanCtx,proto3" json:"spanCtx,omitempty"`
+	SeriesPerFrame uint32       `protobuf:"varint,6,opt,name=seriesPerFrame,proto3" json:"seriesPerFrame,omitempty"`
+	CompactPoints  bool         `protobuf:"varint,7,opt,name=compactPoints,proto3" json:"compactPoints,omitempty"`
+	Aggregation    *Aggregation `protobuf:"bytes,8,opt,name=aggregation" json:"aggregation,omitempty"`
+	ValueBounds    *ValueBounds `protobuf:"bytes,9,opt,name=valueBounds" json:"valueBounds,omitempty"`
+	Hash           uint64       `protobuf:"varint,10,opt,name=hash,proto3" json:"hash,omitempty"`
+	RawChunks      bool         `protobuf:"varint,11,opt,name=rawChunks,proto3" json:"rawChunks,omitempty"`
 }
 
 func (m *SelectRequest) Reset()         { *m = SelectRequest{} }
 func (m *SelectRequest) String() string { return proto.CompactTextString(m) }
 func (*SelectRequest) ProtoMessage()    {}
 func (*SelectRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_backend_46c6160745bca556, []int{1}
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{3}
 }
 func (m *SelectRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -187,17 +353,60 @@ func (m *SelectRequest) GetSpanCtx() []byte {
 	return nil
 }
 
+func (m *SelectRequest) GetSeriesPerFrame() uint32 {
+	if m != nil {
+		return m.SeriesPerFrame
+	}
+	return 0
+}
+
+func (m *SelectRequest) GetCompactPoints() bool {
+	if m != nil {
+		return m.CompactPoints
+	}
+	return false
+}
+
+func (m *SelectRequest) GetAggregation() *Aggregation {
+	if m != nil {
+		return m.Aggregation
+	}
+	return nil
+}
+
+func (m *SelectRequest) GetValueBounds() *ValueBounds {
+	if m != nil {
+		return m.ValueBounds
+	}
+	return nil
+}
+
+func (m *SelectRequest) GetHash() uint64 {
+	if m != nil {
+		return m.Hash
+	}
+	return 0
+}
+
+func (m *SelectRequest) GetRawChunks() bool {
+	if m != nil {
+		return m.RawChunks
+	}
+	return false
+}
+
 type SelectResponse struct {
 	Status   pb.StatusCode `protobuf:"varint,1,opt,name=status,proto3,enum=pb.StatusCode" json:"status,omitempty"`
 	Series   []*pb.Series  `protobuf:"bytes,2,rep,name=series" json:"series,omitempty"`
 	ErrorMsg string        `protobuf:"bytes,3,opt,name=errorMsg,proto3" json:"errorMsg,omitempty"`
+	HasMore  bool          `protobuf:"varint,4,opt,name=hasMore,proto3" json:"hasMore,omitempty"`
 }
 
 func (m *SelectResponse) Reset()         { *m = SelectResponse{} }
 func (m *SelectResponse) String() string { return proto.CompactTextString(m) }
 func (*SelectResponse) ProtoMessage()    {}
 func (*SelectResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_backend_46c6160745bca556, []int{2}
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{4}
 }
 func (m *SelectResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -247,15 +456,25 @@ func (m *SelectResponse) GetErrorMsg() string {
 	return ""
 }
 
+func (m *SelectResponse) GetHasMore() bool {
+	if m != nil {
+		return m.HasMore
+	}
+	return false
+}
+
 type AddRequest struct {
-	Series []*pb.Series `protobuf:"bytes,1,rep,name=series" json:"series,omitempty"`
+	Series   []*pb.Series `protobuf:"bytes,1,rep,name=series" json:"series,omitempty"`
+	WriterID string       `protobuf:"bytes,2,opt,name=writerID,proto3" json:"writerID,omitempty"`
+	Seq      uint64       `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`
+	Ack      AckLevel     `protobuf:"varint,4,opt,name=ack,proto3,enum=backend.AckLevel" json:"ack,omitempty"`
 }
 
 func (m *AddRequest) Reset()         { *m = AddRequest{} }
 func (m *AddRequest) String() string { return proto.CompactTextString(m) }
 func (*AddRequest) ProtoMessage()    {}
 func (*AddRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_backend_46c6160745bca556, []int{3}
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{5}
 }
 func (m *AddRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -291,17 +510,117 @@ func (m *AddRequest) GetSeries() []*pb.Series {
 	return nil
 }
 
+func (m *AddRequest) GetWriterID() string {
+	if m != nil {
+		return m.WriterID
+	}
+	return ""
+}
+
+func (m *AddRequest) GetSeq() uint64 {
+	if m != nil {
+		return m.Seq
+	}
+	return 0
+}
+
+func (m *AddRequest) GetAck() AckLevel {
+	if m != nil {
+		return m.Ack
+	}
+	return AckLevel_AckAsync
+}
+
+// AddResponse is the reply to an AddRequest acknowledged by one or a quorum.
+type AddResponse struct {
+	Status   pb.StatusCode `protobuf:"varint,1,opt,name=status,proto3,enum=pb.StatusCode" json:"status,omitempty"`
+	Code     pb.ErrorCode  `protobuf:"varint,2,opt,name=code,proto3,enum=pb.ErrorCode" json:"code,omitempty"`
+	Message  string        `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Acked    uint32        `protobuf:"varint,4,opt,name=acked,proto3" json:"acked,omitempty"`
+	Replicas uint32        `protobuf:"varint,5,opt,name=replicas,proto3" json:"replicas,omitempty"`
+}
+
+func (m *AddResponse) Reset()         { *m = AddResponse{} }
+func (m *AddResponse) String() string { return proto.CompactTextString(m) }
+func (*AddResponse) ProtoMessage()    {}
+func (*AddResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{6}
+}
+func (m *AddResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *AddResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_AddResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *AddResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AddResponse.Merge(dst, src)
+}
+func (m *AddResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *AddResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AddResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AddResponse proto.InternalMessageInfo
+
+func (m *AddResponse) GetStatus() pb.StatusCode {
+	if m != nil {
+		return m.Status
+	}
+	return pb.StatusCode_Succeed
+}
+
+func (m *AddResponse) GetCode() pb.ErrorCode {
+	if m != nil {
+		return m.Code
+	}
+	return pb.ErrorCode_Unspecified
+}
+
+func (m *AddResponse) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *AddResponse) GetAcked() uint32 {
+	if m != nil {
+		return m.Acked
+	}
+	return 0
+}
+
+func (m *AddResponse) GetReplicas() uint32 {
+	if m != nil {
+		return m.Replicas
+	}
+	return 0
+}
+
 type LabelValuesRequest struct {
 	Name     string     `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Matchers []*Matcher `protobuf:"bytes,2,rep,name=matchers" json:"matchers,omitempty"`
 	SpanCtx  []byte     `protobuf:"bytes,3,opt,name=spanCtx,proto3" json:"spanCtx,omitempty"`
+	Limit    uint32     `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	After    string     `protobuf:"bytes,5,opt,name=after,proto3" json:"after,omitempty"`
 }
 
 func (m *LabelValuesRequest) Reset()         { *m = LabelValuesRequest{} }
 func (m *LabelValuesRequest) String() string { return proto.CompactTextString(m) }
 func (*LabelValuesRequest) ProtoMessage()    {}
 func (*LabelValuesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_backend_46c6160745bca556, []int{4}
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{7}
 }
 func (m *LabelValuesRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -351,13 +670,138 @@ func (m *LabelValuesRequest) GetSpanCtx() []byte {
 	return nil
 }
 
+func (m *LabelValuesRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *LabelValuesRequest) GetAfter() string {
+	if m != nil {
+		return m.After
+	}
+	return ""
+}
+
+type LabelNamesRequest struct {
+	SpanCtx []byte `protobuf:"bytes,1,opt,name=spanCtx,proto3" json:"spanCtx,omitempty"`
+}
+
+func (m *LabelNamesRequest) Reset()         { *m = LabelNamesRequest{} }
+func (m *LabelNamesRequest) String() string { return proto.CompactTextString(m) }
+func (*LabelNamesRequest) ProtoMessage()    {}
+func (*LabelNamesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{8}
+}
+func (m *LabelNamesRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *LabelNamesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_LabelNamesRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *LabelNamesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LabelNamesRequest.Merge(dst, src)
+}
+func (m *LabelNamesRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *LabelNamesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LabelNamesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LabelNamesRequest proto.InternalMessageInfo
+
+func (m *LabelNamesRequest) GetSpanCtx() []byte {
+	if m != nil {
+		return m.SpanCtx
+	}
+	return nil
+}
+
+type LabelNamesResponse struct {
+	Names    []string      `protobuf:"bytes,1,rep,name=names" json:"names,omitempty"`
+	Status   pb.StatusCode `protobuf:"varint,2,opt,name=status,proto3,enum=pb.StatusCode" json:"status,omitempty"`
+	ErrorMsg string        `protobuf:"bytes,3,opt,name=errorMsg,proto3" json:"errorMsg,omitempty"`
+}
+
+func (m *LabelNamesResponse) Reset()         { *m = LabelNamesResponse{} }
+func (m *LabelNamesResponse) String() string { return proto.CompactTextString(m) }
+func (*LabelNamesResponse) ProtoMessage()    {}
+func (*LabelNamesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_backend_fbfeee24ae8ba7da, []int{9}
+}
+func (m *LabelNamesResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *LabelNamesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_LabelNamesResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *LabelNamesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LabelNamesResponse.Merge(dst, src)
+}
+func (m *LabelNamesResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *LabelNamesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LabelNamesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LabelNamesResponse proto.InternalMessageInfo
+
+func (m *LabelNamesResponse) GetNames() []string {
+	if m != nil {
+		return m.Names
+	}
+	return nil
+}
+
+func (m *LabelNamesResponse) GetStatus() pb.StatusCode {
+	if m != nil {
+		return m.Status
+	}
+	return pb.StatusCode_Succeed
+}
+
+func (m *LabelNamesResponse) GetErrorMsg() string {
+	if m != nil {
+		return m.ErrorMsg
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Matcher)(nil), "backend.Matcher")
+	proto.RegisterType((*Aggregation)(nil), "backend.Aggregation")
+	proto.RegisterType((*ValueBounds)(nil), "backend.ValueBounds")
 	proto.RegisterType((*SelectRequest)(nil), "backend.SelectRequest")
 	proto.RegisterType((*SelectResponse)(nil), "backend.SelectResponse")
 	proto.RegisterType((*AddRequest)(nil), "backend.AddRequest")
+	proto.RegisterType((*AddResponse)(nil), "backend.AddResponse")
 	proto.RegisterType((*LabelValuesRequest)(nil), "backend.LabelValuesRequest")
+	proto.RegisterType((*LabelNamesRequest)(nil), "backend.LabelNamesRequest")
+	proto.RegisterType((*LabelNamesResponse)(nil), "backend.LabelNamesResponse")
 	proto.RegisterEnum("backend.MatchType", MatchType_name, MatchType_value)
+	proto.RegisterEnum("backend.AggrOp", AggrOp_name, AggrOp_value)
+	proto.RegisterEnum("backend.AckLevel", AckLevel_name, AckLevel_value)
 }
 func (m *Matcher) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
@@ -394,7 +838,7 @@ func (m *Matcher) MarshalTo(dAtA []byte) (int, error) {
 	return i, nil
 }
 
-func (m *SelectRequest) Marshal() (dAtA []byte, err error) {
+func (m *Aggregation) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalTo(dAtA)
@@ -404,17 +848,85 @@ func (m *SelectRequest) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *SelectRequest) MarshalTo(dAtA []byte) (int, error) {
+func (m *Aggregation) MarshalTo(dAtA []byte) (int, error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	if m.Mint != 0 {
+	if m.Op != 0 {
 		dAtA[i] = 0x8
 		i++
-		i = encodeVarintBackend(dAtA, i, uint64((uint64(m.Mint)<<1)^uint64((m.Mint>>63))))
+		i = encodeVarintBackend(dAtA, i, uint64(m.Op))
 	}
-	if m.Maxt != 0 {
+	if len(m.Grouping) > 0 {
+		for _, s := range m.Grouping {
+			dAtA[i] = 0x12
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
+	return i, nil
+}
+
+func (m *ValueBounds) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ValueBounds) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Min != 0 {
+		dAtA[i] = 0x9
+		i++
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Min))))
+		i += 8
+	}
+	if m.Max != 0 {
+		dAtA[i] = 0x11
+		i++
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Max))))
+		i += 8
+	}
+	return i, nil
+}
+
+func (m *SelectRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SelectRequest) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Mint != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64((uint64(m.Mint)<<1)^uint64((m.Mint>>63))))
+	}
+	if m.Maxt != 0 {
 		dAtA[i] = 0x10
 		i++
 		i = encodeVarintBackend(dAtA, i, uint64((uint64(m.Maxt)<<1)^uint64((m.Maxt>>63))))
@@ -442,6 +954,56 @@ func (m *SelectRequest) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintBackend(dAtA, i, uint64(len(m.SpanCtx)))
 		i += copy(dAtA[i:], m.SpanCtx)
 	}
+	if m.SeriesPerFrame != 0 {
+		dAtA[i] = 0x30
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(m.SeriesPerFrame))
+	}
+	if m.CompactPoints {
+		dAtA[i] = 0x38
+		i++
+		if m.CompactPoints {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.Aggregation != nil {
+		dAtA[i] = 0x42
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(m.Aggregation.Size()))
+		n1, err := m.Aggregation.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n1
+	}
+	if m.ValueBounds != nil {
+		dAtA[i] = 0x4a
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(m.ValueBounds.Size()))
+		n2, err := m.ValueBounds.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n2
+	}
+	if m.Hash != 0 {
+		dAtA[i] = 0x50
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(m.Hash))
+	}
+	if m.RawChunks {
+		dAtA[i] = 0x58
+		i++
+		if m.RawChunks {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
 	return i, nil
 }
 
@@ -483,6 +1045,16 @@ func (m *SelectResponse) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintBackend(dAtA, i, uint64(len(m.ErrorMsg)))
 		i += copy(dAtA[i:], m.ErrorMsg)
 	}
+	if m.HasMore {
+		dAtA[i] = 0x20
+		i++
+		if m.HasMore {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
 	return i, nil
 }
 
@@ -513,6 +1085,66 @@ func (m *AddRequest) MarshalTo(dAtA []byte) (int, error) {
 			i += n
 		}
 	}
+	if len(m.WriterID) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(len(m.WriterID)))
+		i += copy(dAtA[i:], m.WriterID)
+	}
+	if m.Seq != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(m.Seq))
+	}
+	if m.Ack != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(m.Ack))
+	}
+	return i, nil
+}
+
+func (m *AddResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *AddResponse) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Status != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(m.Status))
+	}
+	if m.Code != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(m.Code))
+	}
+	if len(m.Message) > 0 {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(len(m.Message)))
+		i += copy(dAtA[i:], m.Message)
+	}
+	if m.Acked != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(m.Acked))
+	}
+	if m.Replicas != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(m.Replicas))
+	}
 	return i, nil
 }
 
@@ -555,6 +1187,85 @@ func (m *LabelValuesRequest) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintBackend(dAtA, i, uint64(len(m.SpanCtx)))
 		i += copy(dAtA[i:], m.SpanCtx)
 	}
+	if m.Limit != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(m.Limit))
+	}
+	if len(m.After) > 0 {
+		dAtA[i] = 0x2a
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(len(m.After)))
+		i += copy(dAtA[i:], m.After)
+	}
+	return i, nil
+}
+
+func (m *LabelNamesRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *LabelNamesRequest) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.SpanCtx) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(len(m.SpanCtx)))
+		i += copy(dAtA[i:], m.SpanCtx)
+	}
+	return i, nil
+}
+
+func (m *LabelNamesResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *LabelNamesResponse) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Names) > 0 {
+		for _, s := range m.Names {
+			dAtA[i] = 0xa
+			i++
+			l = len(s)
+			for l >= 1<<7 {
+				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
+				l >>= 7
+				i++
+			}
+			dAtA[i] = uint8(l)
+			i++
+			i += copy(dAtA[i:], s)
+		}
+	}
+	if m.Status != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(m.Status))
+	}
+	if len(m.ErrorMsg) > 0 {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintBackend(dAtA, i, uint64(len(m.ErrorMsg)))
+		i += copy(dAtA[i:], m.ErrorMsg)
+	}
 	return i, nil
 }
 
@@ -587,6 +1298,39 @@ func (m *Matcher) Size() (n int) {
 	return n
 }
 
+func (m *Aggregation) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Op != 0 {
+		n += 1 + sovBackend(uint64(m.Op))
+	}
+	if len(m.Grouping) > 0 {
+		for _, s := range m.Grouping {
+			l = len(s)
+			n += 1 + l + sovBackend(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *ValueBounds) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Min != 0 {
+		n += 9
+	}
+	if m.Max != 0 {
+		n += 9
+	}
+	return n
+}
+
 func (m *SelectRequest) Size() (n int) {
 	if m == nil {
 		return 0
@@ -612,6 +1356,26 @@ func (m *SelectRequest) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovBackend(uint64(l))
 	}
+	if m.SeriesPerFrame != 0 {
+		n += 1 + sovBackend(uint64(m.SeriesPerFrame))
+	}
+	if m.CompactPoints {
+		n += 2
+	}
+	if m.Aggregation != nil {
+		l = m.Aggregation.Size()
+		n += 1 + l + sovBackend(uint64(l))
+	}
+	if m.ValueBounds != nil {
+		l = m.ValueBounds.Size()
+		n += 1 + l + sovBackend(uint64(l))
+	}
+	if m.Hash != 0 {
+		n += 1 + sovBackend(uint64(m.Hash))
+	}
+	if m.RawChunks {
+		n += 2
+	}
 	return n
 }
 
@@ -634,6 +1398,9 @@ func (m *SelectResponse) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovBackend(uint64(l))
 	}
+	if m.HasMore {
+		n += 2
+	}
 	return n
 }
 
@@ -649,6 +1416,41 @@ func (m *AddRequest) Size() (n int) {
 			n += 1 + l + sovBackend(uint64(l))
 		}
 	}
+	l = len(m.WriterID)
+	if l > 0 {
+		n += 1 + l + sovBackend(uint64(l))
+	}
+	if m.Seq != 0 {
+		n += 1 + sovBackend(uint64(m.Seq))
+	}
+	if m.Ack != 0 {
+		n += 1 + sovBackend(uint64(m.Ack))
+	}
+	return n
+}
+
+func (m *AddResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Status != 0 {
+		n += 1 + sovBackend(uint64(m.Status))
+	}
+	if m.Code != 0 {
+		n += 1 + sovBackend(uint64(m.Code))
+	}
+	l = len(m.Message)
+	if l > 0 {
+		n += 1 + l + sovBackend(uint64(l))
+	}
+	if m.Acked != 0 {
+		n += 1 + sovBackend(uint64(m.Acked))
+	}
+	if m.Replicas != 0 {
+		n += 1 + sovBackend(uint64(m.Replicas))
+	}
 	return n
 }
 
@@ -672,30 +1474,72 @@ func (m *LabelValuesRequest) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovBackend(uint64(l))
 	}
+	if m.Limit != 0 {
+		n += 1 + sovBackend(uint64(m.Limit))
+	}
+	l = len(m.After)
+	if l > 0 {
+		n += 1 + l + sovBackend(uint64(l))
+	}
 	return n
 }
 
-func sovBackend(x uint64) (n int) {
-	for {
-		n++
-		x >>= 7
-		if x == 0 {
-			break
-		}
+func (m *LabelNamesRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.SpanCtx)
+	if l > 0 {
+		n += 1 + l + sovBackend(uint64(l))
 	}
 	return n
 }
-func sozBackend(x uint64) (n int) {
-	return sovBackend(uint64((x << 1) ^ uint64((int64(x) >> 63))))
-}
-func (m *Matcher) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
+
+func (m *LabelNamesResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Names) > 0 {
+		for _, s := range m.Names {
+			l = len(s)
+			n += 1 + l + sovBackend(uint64(l))
+		}
+	}
+	if m.Status != 0 {
+		n += 1 + sovBackend(uint64(m.Status))
+	}
+	l = len(m.ErrorMsg)
+	if l > 0 {
+		n += 1 + l + sovBackend(uint64(l))
+	}
+	return n
+}
+
+func sovBackend(x uint64) (n int) {
+	for {
+		n++
+		x >>= 7
+		if x == 0 {
+			break
+		}
+	}
+	return n
+}
+func sozBackend(x uint64) (n int) {
+	return sovBackend(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *Matcher) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
 				return ErrIntOverflowBackend
 			}
 			if iNdEx >= l {
@@ -721,7 +1565,772 @@ func (m *Matcher) Unmarshal(dAtA []byte) error {
 			if wireType != 0 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
 			}
-			m.Type = 0
+			m.Type = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Type |= (MatchType(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBackend(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBackend
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Aggregation) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBackend
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Aggregation: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Aggregation: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Op", wireType)
+			}
+			m.Op = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Op |= (AggrOp(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Grouping", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Grouping = append(m.Grouping, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBackend(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBackend
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ValueBounds) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBackend
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ValueBounds: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ValueBounds: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Min", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Min = float64(math.Float64frombits(v))
+		case 2:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Max", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Max = float64(math.Float64frombits(v))
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBackend(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBackend
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SelectRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBackend
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SelectRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SelectRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mint", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
+			m.Mint = int64(v)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Maxt", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
+			m.Maxt = int64(v)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Interval", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
+			m.Interval = int64(v)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Matchers", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Matchers = append(m.Matchers, &Matcher{})
+			if err := m.Matchers[len(m.Matchers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SpanCtx", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SpanCtx = append(m.SpanCtx[:0], dAtA[iNdEx:postIndex]...)
+			if m.SpanCtx == nil {
+				m.SpanCtx = []byte{}
+			}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SeriesPerFrame", wireType)
+			}
+			m.SeriesPerFrame = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SeriesPerFrame |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CompactPoints", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.CompactPoints = bool(v != 0)
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Aggregation", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Aggregation == nil {
+				m.Aggregation = &Aggregation{}
+			}
+			if err := m.Aggregation.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValueBounds", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.ValueBounds == nil {
+				m.ValueBounds = &ValueBounds{}
+			}
+			if err := m.ValueBounds.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Hash", wireType)
+			}
+			m.Hash = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Hash |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RawChunks", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.RawChunks = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBackend(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBackend
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SelectResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBackend
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SelectResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SelectResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+			}
+			m.Status = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Status |= (pb.StatusCode(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Series", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Series = append(m.Series, &pb.Series{})
+			if err := m.Series[len(m.Series)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ErrorMsg", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ErrorMsg = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field HasMore", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.HasMore = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBackend(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBackend
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *AddRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBackend
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AddRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AddRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Series", wireType)
+			}
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBackend
@@ -731,14 +2340,26 @@ func (m *Matcher) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Type |= (MatchType(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			if msglen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Series = append(m.Series, &pb.Series{})
+			if err := m.Series[len(m.Series)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field WriterID", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -763,13 +2384,13 @@ func (m *Matcher) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Name = string(dAtA[iNdEx:postIndex])
+			m.WriterID = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 3:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
 			}
-			var stringLen uint64
+			m.Seq = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBackend
@@ -779,21 +2400,30 @@ func (m *Matcher) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
+				m.Seq |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthBackend
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ack", wireType)
 			}
-			postIndex := iNdEx + intStringLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
+			m.Ack = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Ack |= (AckLevel(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			m.Value = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBackend(dAtA[iNdEx:])
@@ -815,7 +2445,7 @@ func (m *Matcher) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SelectRequest) Unmarshal(dAtA []byte) error {
+func (m *AddResponse) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -838,17 +2468,17 @@ func (m *SelectRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SelectRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: AddResponse: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SelectRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: AddResponse: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Mint", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
 			}
-			var v uint64
+			m.Status = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBackend
@@ -858,18 +2488,16 @@ func (m *SelectRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
+				m.Status |= (pb.StatusCode(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
-			m.Mint = int64(v)
 		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Maxt", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
 			}
-			var v uint64
+			m.Code = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBackend
@@ -879,18 +2507,16 @@ func (m *SelectRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
+				m.Code |= (pb.ErrorCode(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
-			m.Maxt = int64(v)
 		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Interval", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Message", wireType)
 			}
-			var v uint64
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBackend
@@ -900,18 +2526,26 @@ func (m *SelectRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
+				stringLen |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
-			m.Interval = int64(v)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Message = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 4:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Matchers", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Acked", wireType)
 			}
-			var msglen int
+			m.Acked = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBackend
@@ -921,28 +2555,16 @@ func (m *SelectRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				m.Acked |= (uint32(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthBackend
-			}
-			postIndex := iNdEx + msglen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Matchers = append(m.Matchers, &Matcher{})
-			if err := m.Matchers[len(m.Matchers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
 		case 5:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SpanCtx", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Replicas", wireType)
 			}
-			var byteLen int
+			m.Replicas = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBackend
@@ -952,23 +2574,11 @@ func (m *SelectRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				m.Replicas |= (uint32(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
-				return ErrInvalidLengthBackend
-			}
-			postIndex := iNdEx + byteLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.SpanCtx = append(m.SpanCtx[:0], dAtA[iNdEx:postIndex]...)
-			if m.SpanCtx == nil {
-				m.SpanCtx = []byte{}
-			}
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBackend(dAtA[iNdEx:])
@@ -990,7 +2600,7 @@ func (m *SelectRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SelectResponse) Unmarshal(dAtA []byte) error {
+func (m *LabelValuesRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1013,17 +2623,17 @@ func (m *SelectResponse) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SelectResponse: wiretype end group for non-group")
+			return fmt.Errorf("proto: LabelValuesRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SelectResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: LabelValuesRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
 			}
-			m.Status = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBackend
@@ -1033,14 +2643,24 @@ func (m *SelectResponse) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Status |= (pb.StatusCode(b) & 0x7F) << shift
+				stringLen |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Series", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Matchers", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -1064,14 +2684,64 @@ func (m *SelectResponse) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Series = append(m.Series, &pb.Series{})
-			if err := m.Series[len(m.Series)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Matchers = append(m.Matchers, &Matcher{})
+			if err := m.Matchers[len(m.Matchers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
 		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ErrorMsg", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field SpanCtx", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBackend
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SpanCtx = append(m.SpanCtx[:0], dAtA[iNdEx:postIndex]...)
+			if m.SpanCtx == nil {
+				m.SpanCtx = []byte{}
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
+			}
+			m.Limit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBackend
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Limit |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field After", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -1096,7 +2766,7 @@ func (m *SelectResponse) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.ErrorMsg = string(dAtA[iNdEx:postIndex])
+			m.After = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -1119,7 +2789,7 @@ func (m *SelectResponse) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *AddRequest) Unmarshal(dAtA []byte) error {
+func (m *LabelNamesRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1142,17 +2812,17 @@ func (m *AddRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: AddRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: LabelNamesRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: AddRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: LabelNamesRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Series", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field SpanCtx", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBackend
@@ -1162,21 +2832,21 @@ func (m *AddRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				byteLen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthBackend
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Series = append(m.Series, &pb.Series{})
-			if err := m.Series[len(m.Series)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.SpanCtx = append(m.SpanCtx[:0], dAtA[iNdEx:postIndex]...)
+			if m.SpanCtx == nil {
+				m.SpanCtx = []byte{}
 			}
 			iNdEx = postIndex
 		default:
@@ -1200,7 +2870,7 @@ func (m *AddRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *LabelValuesRequest) Unmarshal(dAtA []byte) error {
+func (m *LabelNamesResponse) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1223,15 +2893,15 @@ func (m *LabelValuesRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: LabelValuesRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: LabelNamesResponse: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: LabelValuesRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: LabelNamesResponse: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Names", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -1256,13 +2926,13 @@ func (m *LabelValuesRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Name = string(dAtA[iNdEx:postIndex])
+			m.Names = append(m.Names, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Matchers", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
 			}
-			var msglen int
+			m.Status = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBackend
@@ -1272,28 +2942,16 @@ func (m *LabelValuesRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				m.Status |= (pb.StatusCode(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthBackend
-			}
-			postIndex := iNdEx + msglen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Matchers = append(m.Matchers, &Matcher{})
-			if err := m.Matchers[len(m.Matchers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
 		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SpanCtx", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ErrorMsg", wireType)
 			}
-			var byteLen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowBackend
@@ -1303,22 +2961,20 @@ func (m *LabelValuesRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				stringLen |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthBackend
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + intStringLen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.SpanCtx = append(m.SpanCtx[:0], dAtA[iNdEx:postIndex]...)
-			if m.SpanCtx == nil {
-				m.SpanCtx = []byte{}
-			}
+			m.ErrorMsg = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -1446,37 +3102,62 @@ var (
 	ErrIntOverflowBackend   = fmt.Errorf("proto: integer overflow")
 )
 
-func init() { proto.RegisterFile("backend.proto", fileDescriptor_backend_46c6160745bca556) }
-
-var fileDescriptor_backend_46c6160745bca556 = []byte{
-	// 449 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x52, 0xbd, 0x72, 0xd3, 0x40,
-	0x10, 0xf6, 0xd9, 0x8e, 0x9d, 0x6c, 0xb0, 0x31, 0x3b, 0x14, 0x1a, 0x17, 0x1a, 0xa3, 0x22, 0xe3,
-	0x61, 0x82, 0xcc, 0x84, 0x27, 0x80, 0x0c, 0x1d, 0x49, 0x71, 0x66, 0x28, 0xa0, 0x3a, 0x59, 0x8b,
-	0x22, 0xd0, 0xcf, 0x45, 0x77, 0x62, 0xc4, 0x5b, 0xd0, 0xf3, 0x42, 0x94, 0x29, 0x29, 0x19, 0xfb,
-	0x45, 0x18, 0xad, 0x24, 0x07, 0x17, 0x14, 0xe9, 0xf6, 0xfb, 0xb9, 0xbd, 0xbd, 0x6f, 0x0f, 0x26,
-	0x81, 0xda, 0x7c, 0xa5, 0x2c, 0xf4, 0x75, 0x91, 0xdb, 0x1c, 0xc7, 0x2d, 0x9c, 0x9f, 0x47, 0xb1,
-	0xf5, 0xbf, 0x84, 0xfe, 0x26, 0x4f, 0x57, 0x81, 0x2a, 0x43, 0x1b, 0xa7, 0x74, 0x5f, 0xa4, 0x26,
-	0x5a, 0xe9, 0x60, 0xa5, 0x83, 0xe6, 0xd8, 0xfc, 0x45, 0x14, 0xdb, 0x9b, 0x32, 0x60, 0x77, 0x94,
-	0x47, 0xf9, 0x8a, 0xe9, 0xa0, 0xfc, 0xcc, 0x88, 0x01, 0x57, 0x8d, 0xdd, 0xfb, 0x04, 0xe3, 0x2b,
-	0x65, 0x37, 0x37, 0x54, 0xe0, 0x19, 0x0c, 0xdf, 0x7f, 0xd7, 0xe4, 0x88, 0x85, 0x58, 0x4e, 0x2f,
-	0xd0, 0xef, 0xc6, 0x61, 0xbd, 0x56, 0x24, 0xeb, 0x88, 0x30, 0xbc, 0x56, 0x29, 0x39, 0xfd, 0x85,
-	0x58, 0x9e, 0x48, 0xae, 0xf1, 0x29, 0x1c, 0x7d, 0x50, 0x49, 0x49, 0xce, 0x80, 0xc9, 0x06, 0x78,
-	0x3f, 0x05, 0x4c, 0xd6, 0x94, 0xd0, 0xc6, 0x4a, 0xba, 0x2d, 0xc9, 0xd8, 0xfa, 0x6c, 0x1a, 0x67,
-	0x96, 0xef, 0x40, 0xc9, 0x35, 0x73, 0xaa, 0xb2, 0xdc, 0xaf, 0xe6, 0x54, 0x65, 0x71, 0x0e, 0xc7,
-	0x71, 0x66, 0xa9, 0xf8, 0xa6, 0x12, 0x6e, 0x89, 0x72, 0x8f, 0xf1, 0x1c, 0x8e, 0xd3, 0x66, 0x64,
-	0xe3, 0x0c, 0x17, 0x83, 0xe5, 0xe9, 0xc5, 0xec, 0x70, 0x56, 0x2a, 0xe4, 0xde, 0x81, 0x0e, 0x8c,
-	0x8d, 0x56, 0xd9, 0xa5, 0xad, 0x9c, 0xa3, 0x85, 0x58, 0x3e, 0x92, 0x1d, 0xf4, 0x2a, 0x98, 0x76,
-	0xc3, 0x19, 0x9d, 0x67, 0x86, 0xf0, 0x0c, 0x46, 0xc6, 0x2a, 0x5b, 0x9a, 0x36, 0x83, 0xa9, 0xaf,
-	0x03, 0x7f, 0xcd, 0xcc, 0x65, 0x1e, 0x92, 0x6c, 0x55, 0xf4, 0x60, 0x64, 0xa8, 0x88, 0xc9, 0x38,
-	0x7d, 0xbe, 0x1f, 0xd8, 0xc7, 0x8c, 0x6c, 0x95, 0xfa, 0x05, 0x54, 0x14, 0x79, 0x71, 0x65, 0xa2,
-	0x36, 0x94, 0x3d, 0xf6, 0x5e, 0x02, 0xbc, 0x0e, 0xc3, 0x2e, 0x93, 0xfb, 0x6e, 0xe2, 0x7f, 0xdd,
-	0x3c, 0x0d, 0xf8, 0x4e, 0x05, 0x94, 0x70, 0xae, 0xe6, 0x9f, 0x34, 0xb3, 0x7a, 0x13, 0xa2, 0xd9,
-	0x44, 0x5d, 0x1f, 0xa4, 0xd3, 0x7f, 0x48, 0x3a, 0x83, 0x83, 0x74, 0x9e, 0xaf, 0xe1, 0x64, 0xbf,
-	0x78, 0x9c, 0x02, 0x30, 0x78, 0x7b, 0x5b, 0xaa, 0x64, 0xd6, 0xc3, 0x27, 0x30, 0x61, 0x7c, 0x9d,
-	0xdb, 0x86, 0x12, 0xf8, 0x18, 0x4e, 0x99, 0x92, 0x14, 0x51, 0xa5, 0x67, 0x7d, 0x44, 0x98, 0x76,
-	0x9e, 0x96, 0x1b, 0xbc, 0x79, 0xf6, 0x6b, 0xeb, 0x8a, 0xbb, 0xad, 0x2b, 0xfe, 0x6c, 0x5d, 0xf1,
-	0x63, 0xe7, 0xf6, 0xee, 0x76, 0x6e, 0xef, 0xf7, 0xce, 0xed, 0x7d, 0xec, 0x7e, 0x7b, 0x30, 0xe2,
-	0x7f, 0xf9, 0xea, 0x6f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xf7, 0x1d, 0x58, 0x7d, 0x0e, 0x03, 0x00,
-	0x00,
+func init() { proto.RegisterFile("backend.proto", fileDescriptor_backend_fbfeee24ae8ba7da) }
+
+var fileDescriptor_backend_fbfeee24ae8ba7da = []byte{
+	// 858 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0xcf, 0x6f, 0xdb, 0x36,
+	0x14, 0x36, 0x65, 0xc7, 0xb1, 0x9f, 0x6a, 0xd7, 0x21, 0x72, 0x10, 0x82, 0xc1, 0x73, 0xd5, 0x21,
+	0x10, 0x82, 0xd6, 0xc1, 0x5c, 0x60, 0x77, 0x37, 0xeb, 0x80, 0x0d, 0x4d, 0xda, 0x31, 0xc3, 0x0e,
+	0xdb, 0x89, 0x92, 0x59, 0x59, 0xb0, 0x25, 0x2a, 0x24, 0x95, 0xba, 0xe7, 0x1d, 0x77, 0x19, 0x76,
+	0xde, 0x71, 0x7f, 0xcc, 0x8e, 0x3d, 0xee, 0x38, 0x24, 0xff, 0xc8, 0xc0, 0xa7, 0x1f, 0xb6, 0x7b,
+	0x08, 0xd0, 0xdb, 0xfb, 0xbe, 0xf7, 0x89, 0xfc, 0xf8, 0xf8, 0x1e, 0x05, 0x83, 0x90, 0x47, 0x2b,
+	0x91, 0x2d, 0xa6, 0xb9, 0x92, 0x46, 0xd2, 0xc3, 0x0a, 0x9e, 0x3c, 0x8b, 0x13, 0xb3, 0x2c, 0xc2,
+	0x69, 0x24, 0xd3, 0xf3, 0x90, 0x17, 0x0b, 0x93, 0xa4, 0x62, 0x1b, 0xa4, 0x3a, 0x3e, 0xcf, 0xc3,
+	0xf3, 0x3c, 0x2c, 0x3f, 0x3b, 0x79, 0xbe, 0xa3, 0x8e, 0x65, 0x2c, 0xcf, 0x91, 0x0e, 0x8b, 0x77,
+	0x88, 0x10, 0x60, 0x54, 0xca, 0xfd, 0x5f, 0xe1, 0xf0, 0x92, 0x9b, 0x68, 0x29, 0x14, 0x3d, 0x85,
+	0xce, 0x4f, 0x1f, 0x72, 0xe1, 0x91, 0x09, 0x09, 0x86, 0x33, 0x3a, 0xad, 0xed, 0x60, 0xde, 0x66,
+	0x18, 0xe6, 0x29, 0x85, 0xce, 0x15, 0x4f, 0x85, 0xe7, 0x4c, 0x48, 0xd0, 0x67, 0x18, 0xd3, 0x63,
+	0x38, 0xf8, 0x99, 0xaf, 0x0b, 0xe1, 0xb5, 0x91, 0x2c, 0x81, 0xff, 0x03, 0xb8, 0xf3, 0x38, 0x56,
+	0x22, 0xe6, 0x26, 0x91, 0x19, 0xfd, 0x12, 0x1c, 0x99, 0x57, 0xcb, 0x3f, 0x6e, 0x96, 0xb7, 0x8a,
+	0x37, 0x39, 0x73, 0x64, 0x4e, 0x4f, 0xa0, 0x17, 0x2b, 0x59, 0xe4, 0x49, 0x16, 0x7b, 0xce, 0xa4,
+	0x1d, 0xf4, 0x59, 0x83, 0xfd, 0xaf, 0xc1, 0xc5, 0x45, 0x5f, 0xca, 0x22, 0x5b, 0x68, 0x3a, 0x82,
+	0x76, 0x9a, 0x64, 0xb8, 0x18, 0x61, 0x36, 0x44, 0x86, 0x6f, 0x3c, 0xa7, 0x62, 0xf8, 0xc6, 0xff,
+	0xbd, 0x0d, 0x83, 0x6b, 0xb1, 0x16, 0x91, 0x61, 0xe2, 0xa6, 0x10, 0xda, 0x58, 0xeb, 0x69, 0x92,
+	0x19, 0xfc, 0x8c, 0x32, 0x8c, 0x91, 0xe3, 0x1b, 0xe3, 0x39, 0x15, 0xc7, 0x37, 0xc6, 0x1a, 0x49,
+	0x32, 0x23, 0xd4, 0x2d, 0x5f, 0xe3, 0x89, 0x28, 0x6b, 0x30, 0x7d, 0x06, 0xbd, 0xb4, 0xac, 0x98,
+	0xf6, 0x3a, 0x93, 0x76, 0xe0, 0xce, 0x46, 0xfb, 0xa5, 0x12, 0x8a, 0x35, 0x0a, 0xea, 0xc1, 0xa1,
+	0xce, 0x79, 0x76, 0x61, 0x36, 0xde, 0xc1, 0x84, 0x04, 0x8f, 0x58, 0x0d, 0xe9, 0x29, 0x0c, 0xb5,
+	0x50, 0x89, 0xd0, 0x6f, 0x85, 0xfa, 0x4e, 0xd9, 0x82, 0x76, 0x27, 0x24, 0x18, 0xb0, 0x4f, 0x58,
+	0xfa, 0x15, 0x0c, 0x22, 0x99, 0xe6, 0x3c, 0x32, 0x6f, 0x65, 0x92, 0x19, 0xed, 0x1d, 0x4e, 0x48,
+	0xd0, 0x63, 0xfb, 0x24, 0xfd, 0x06, 0x5c, 0xbe, 0x2d, 0xb5, 0xd7, 0x9b, 0x90, 0xc0, 0x9d, 0x1d,
+	0xef, 0x15, 0xb9, 0xca, 0xb1, 0x5d, 0xa1, 0xfd, 0xee, 0x76, 0x5b, 0x56, 0xaf, 0xff, 0xc9, 0x77,
+	0x3b, 0x25, 0x67, 0xbb, 0x42, 0x5b, 0xb5, 0x25, 0xd7, 0x4b, 0x0f, 0x26, 0x24, 0xe8, 0x30, 0x8c,
+	0xe9, 0x17, 0xd0, 0x57, 0xfc, 0xfd, 0xc5, 0xb2, 0xc8, 0x56, 0xda, 0x73, 0xd1, 0xe5, 0x96, 0xf0,
+	0xff, 0x24, 0x30, 0xac, 0x6f, 0x43, 0xe7, 0x32, 0xd3, 0x82, 0x9e, 0x42, 0x57, 0x1b, 0x6e, 0x0a,
+	0x5d, 0x35, 0xc5, 0x70, 0x9a, 0x87, 0xd3, 0x6b, 0x64, 0x2e, 0xe4, 0x42, 0xb0, 0x2a, 0x4b, 0x7d,
+	0xe8, 0x96, 0x45, 0xc1, 0xae, 0x70, 0x67, 0x80, 0x3a, 0x64, 0x58, 0x95, 0xb1, 0x57, 0x26, 0x94,
+	0x92, 0xea, 0x52, 0xc7, 0x55, 0x13, 0x36, 0xd8, 0x5e, 0xc2, 0x92, 0xeb, 0x4b, 0xa9, 0x84, 0xd7,
+	0x41, 0x5b, 0x35, 0xf4, 0x7f, 0x23, 0x00, 0xf3, 0xc5, 0xa2, 0xee, 0x8f, 0xed, 0x46, 0xe4, 0xa1,
+	0x8d, 0xde, 0xab, 0xc4, 0x08, 0xf5, 0xfd, 0xb7, 0xd5, 0x08, 0x34, 0xd8, 0xf6, 0xa0, 0x16, 0x37,
+	0xb8, 0x7f, 0x87, 0xd9, 0x90, 0x3e, 0x85, 0x36, 0x8f, 0x56, 0xb8, 0xed, 0x70, 0x76, 0xb4, 0xbd,
+	0x8f, 0x68, 0xf5, 0x5a, 0xdc, 0x8a, 0x35, 0xb3, 0x59, 0xff, 0x6f, 0x02, 0x2e, 0xba, 0xf8, 0xcc,
+	0xba, 0x3c, 0x81, 0x4e, 0x24, 0x17, 0xe5, 0x24, 0x0e, 0x67, 0x03, 0xab, 0x7a, 0x65, 0xcf, 0x8c,
+	0x22, 0x4c, 0xd9, 0xa3, 0xa7, 0x42, 0x6b, 0x1e, 0xd7, 0xa3, 0x59, 0x43, 0x3b, 0xb2, 0xd6, 0xcc,
+	0x02, 0xbd, 0x0d, 0x58, 0x09, 0xec, 0xe9, 0x94, 0xc8, 0xd7, 0x49, 0xc4, 0x35, 0x36, 0xec, 0x80,
+	0x35, 0xd8, 0xff, 0x8b, 0x00, 0x7d, 0xcd, 0x43, 0xb1, 0xc6, 0xae, 0xd0, 0x3b, 0x43, 0x95, 0xd9,
+	0xf6, 0x25, 0xe5, 0x7b, 0x60, 0xe3, 0xbd, 0x21, 0x71, 0x3e, 0x67, 0x48, 0xda, 0xfb, 0x43, 0x72,
+	0x0c, 0x07, 0xeb, 0x24, 0x4d, 0x4c, 0x6d, 0x12, 0x01, 0x5a, 0x7f, 0x67, 0x84, 0x42, 0x87, 0x7d,
+	0x56, 0x02, 0xff, 0x39, 0x1c, 0xa1, 0x3b, 0xfb, 0x20, 0x35, 0xe6, 0x76, 0x96, 0x26, 0x7b, 0x4b,
+	0xfb, 0x19, 0xd0, 0x5d, 0x79, 0x55, 0xfa, 0x63, 0x38, 0xb0, 0x07, 0x28, 0x1b, 0xa0, 0xcf, 0x4a,
+	0xb0, 0x73, 0x21, 0xce, 0x83, 0x17, 0xf2, 0x40, 0x13, 0x9e, 0x5d, 0x43, 0xbf, 0x79, 0x49, 0xe9,
+	0x10, 0x00, 0xc1, 0xab, 0x9b, 0x82, 0xaf, 0x47, 0x2d, 0x7a, 0x04, 0x03, 0xc4, 0x57, 0xd2, 0x94,
+	0x14, 0xa1, 0x8f, 0xc1, 0x45, 0x8a, 0x89, 0x58, 0x6c, 0xf2, 0x91, 0x43, 0x29, 0x0c, 0x6b, 0x4d,
+	0xc5, 0xb5, 0xcf, 0x9e, 0x42, 0xb7, 0x7c, 0x3f, 0xe9, 0x23, 0xe8, 0xd9, 0xe8, 0x4a, 0x66, 0x62,
+	0xd4, 0xa2, 0x2e, 0x1c, 0x5a, 0x74, 0x5d, 0xa4, 0x23, 0x72, 0xf6, 0x02, 0x7a, 0x75, 0xbf, 0xa1,
+	0x2c, 0x5a, 0xcd, 0xf5, 0x87, 0x2c, 0x1a, 0xb5, 0x28, 0x40, 0x77, 0x1e, 0xad, 0xde, 0x64, 0x62,
+	0x44, 0xe8, 0x00, 0xfa, 0xf3, 0x68, 0xf5, 0x63, 0x21, 0x55, 0x91, 0x8e, 0x9c, 0x97, 0x4f, 0xfe,
+	0xb9, 0x1b, 0x93, 0x8f, 0x77, 0x63, 0xf2, 0xdf, 0xdd, 0x98, 0xfc, 0x71, 0x3f, 0x6e, 0x7d, 0xbc,
+	0x1f, 0xb7, 0xfe, 0xbd, 0x1f, 0xb7, 0x7e, 0xa9, 0x7f, 0x4c, 0x61, 0x17, 0x7f, 0x21, 0x2f, 0xfe,
+	0x1f, 0x00, 0xe6, 0x69, 0x65, 0x69, 0xb9, 0x06, 0x00, 0x00,
 }
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/prometheus/prometheus/pkg/value"
+)
+
+// UnmarshalPooled is like Unmarshal, but the series are got from the pool by pb.GetSeries
+// and their chunks are expanded into points, call Release to put them back once done with
+// the response.
+func (m *SelectResponse) UnmarshalPooled(dAtA []byte) error {
+	for i := 0; i < len(dAtA); {
+		n, err := skipBackend(dAtA[i:])
+		if err != nil {
+			return err
+		}
+		field := dAtA[i : i+n]
+		i += n
+
+		key, keyLen := binary.Uvarint(field)
+		if keyLen <= 0 {
+			return ErrInvalidLengthBackend
+		}
+
+		// series, length delimited
+		if key>>3 != 2 || key&0x7 != 2 {
+			if err = m.Unmarshal(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		msgLen, lenLen := binary.Uvarint(field[keyLen:])
+		if lenLen <= 0 || keyLen+lenLen+int(msgLen) != len(field) {
+			return io.ErrUnexpectedEOF
+		}
+
+		s := pb.GetSeries()
+		if err = s.Unmarshal(field[keyLen+lenLen:]); err == nil {
+			err = s.Expand()
+		}
+		if err != nil {
+			pb.PutSeries(s)
+			return err
+		}
+		m.Series = append(m.Series, s)
+	}
+
+	return nil
+}
+
+// CountSeries returns how many series the select response marshaled in dAtA holds without unmarshaling
+// any of them, e.g. to refuse a response too large before it's decoded.
+func CountSeries(dAtA []byte) (int, error) {
+	var count int
+	for i := 0; i < len(dAtA); {
+		n, err := skipBackend(dAtA[i:])
+		if err != nil {
+			return count, err
+		}
+
+		key, keyLen := binary.Uvarint(dAtA[i:])
+		if keyLen <= 0 {
+			return count, ErrInvalidLengthBackend
+		}
+		if key>>3 == 2 && key&0x7 == 2 {
+			count++
+		}
+		i += n
+	}
+	return count, nil
+}
+
+// Release puts the series of the response back to the pool, neither the series nor their
+// labels and points may be used after, unless taken out of the response before.
+func (m *SelectResponse) Release() {
+	for i, s := range m.Series {
+		if s != nil {
+			pb.PutSeries(s)
+		}
+		m.Series[i] = nil
+	}
+	m.Series = m.Series[:0]
+}
+
+// Contains tells whether a sample of value v is kept by the bounds, nil bounds keep every sample.
+// An open end is set to an infinity, e.g. {Min: 1, Max: +Inf} keeps the samples not less than 1.
+func (m *ValueBounds) Contains(v float64) bool {
+	if m == nil || value.IsStaleNaN(v) {
+		return true
+	}
+	return v >= m.Min && v <= m.Max
+}
+
+// Filter drops the points out of the bounds in place and returns the ones left.
+func (m *ValueBounds) Filter(points []pb.Point) []pb.Point {
+	if m == nil {
+		return points
+	}
+
+	kept := points[:0]
+	for _, p := range points {
+		if p.H != nil || m.Contains(p.V) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
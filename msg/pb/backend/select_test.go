@@ -0,0 +1,140 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backend
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/baudtime/baudtime/msg/pb"
+)
+
+func selectResponse(seriesNum, pointNum int) *SelectResponse {
+	resp := &SelectResponse{Status: pb.StatusCode_Succeed, ErrorMsg: "partial", HasMore: true}
+	for i := 0; i < seriesNum; i++ {
+		s := &pb.Series{
+			Labels: []pb.Label{
+				{Name: "__name__", Value: "test_metric"},
+				{Name: "instance", Value: strconv.Itoa(i)},
+			},
+			Points: make([]pb.Point, pointNum),
+		}
+		for j := range s.Points {
+			s.Points[j] = pb.Point{T: int64(j) * 15000, V: float64(j)}
+		}
+		resp.Series = append(resp.Series, s)
+	}
+	return resp
+}
+
+func TestSelectResponse_UnmarshalPooled(t *testing.T) {
+	for _, want := range []*SelectResponse{selectResponse(10, 5), selectResponse(3, 8), selectResponse(0, 0)} {
+		b, err := want.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the series released by the previous round are reused
+		got := new(SelectResponse)
+		if err = got.UnmarshalPooled(b); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("want %v, got %v", want, got)
+		}
+		got.Release()
+
+		if len(got.Series) != 0 {
+			t.Fatalf("want no series after release, got %d", len(got.Series))
+		}
+	}
+
+	// the chunks of a compacted response are expanded into points
+	want, compacted := selectResponse(4, 20), selectResponse(4, 20)
+	for _, s := range compacted.Series {
+		if err := s.Compact(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := compacted.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := new(SelectResponse)
+	if err = got.UnmarshalPooled(b); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range got.Series {
+		if !reflect.DeepEqual(s.Points, want.Series[i].Points) || len(s.Chunk) != 0 {
+			t.Fatalf("series %d: want points %v, got %v", i, want.Series[i].Points, s.Points)
+		}
+	}
+	got.Release()
+
+	b, err = selectResponse(2, 2).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = new(SelectResponse).UnmarshalPooled(b[:len(b)-1]); err == nil {
+		t.Fatalf("expected error for truncated response")
+	}
+}
+
+// BenchmarkSelectResponse_Unmarshal decodes a 100k points response, with and without drawing
+// the series from the pool.
+func BenchmarkSelectResponse_Unmarshal(b *testing.B) {
+	data, err := selectResponse(1000, 100).Marshal()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("plain", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			resp := new(SelectResponse)
+			if err := resp.Unmarshal(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			resp := new(SelectResponse)
+			if err := resp.UnmarshalPooled(data); err != nil {
+				b.Fatal(err)
+			}
+			resp.Release()
+		}
+	})
+}
+
+func TestCountSeries(t *testing.T) {
+	for _, num := range []int{0, 1, 7} {
+		b, err := selectResponse(num, 3).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := CountSeries(b); err != nil || got != num {
+			t.Fatalf("want %d series, got %d, err %v", num, got, err)
+		}
+		if _, err = CountSeries(b[:len(b)-1]); num > 0 && err == nil {
+			t.Fatalf("want an error counting a truncated response of %d series", num)
+		}
+	}
+}
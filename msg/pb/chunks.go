@@ -0,0 +1,105 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"sort"
+
+	"github.com/prometheus/tsdb/chunkenc"
+)
+
+// samplesPerChunk is how many samples a chunk encoded from points holds at most, the same as tsdb cuts its chunks at.
+const samplesPerChunk = 120
+
+// EncodeChunks puts the points into chunks in the xor encoding of tsdb, each of which has at most
+// samplesPerChunk samples and the time bounds of them. Only the timestamps and values are kept.
+func EncodeChunks(points []Point) ([]Chunk, error) {
+	chunks := make([]Chunk, 0, (len(points)+samplesPerChunk-1)/samplesPerChunk)
+
+	for len(points) > 0 {
+		n := samplesPerChunk
+		if n > len(points) {
+			n = len(points)
+		}
+
+		c := chunkenc.NewXORChunk()
+		app, err := c.Appender()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range points[:n] {
+			app.Append(p.T, p.V)
+		}
+
+		chunks = append(chunks, Chunk{Mint: points[0].T, Maxt: points[n-1].T, Data: c.Bytes()})
+		points = points[n:]
+	}
+
+	return chunks, nil
+}
+
+// DecodeChunks decodes the samples in [mint, maxt] of the chunks into points in time order.
+// The chunks of a series read from several blocks may overlap, the samples of the same
+// timestamp are deduplicated, the one of the chunk coming first is kept.
+func DecodeChunks(chunks []Chunk, mint, maxt int64) ([]Point, error) {
+	var (
+		points []Point
+		sorted = true
+	)
+
+	for _, chk := range chunks {
+		if chk.Maxt < mint || chk.Mint > maxt {
+			continue
+		}
+
+		c, err := chunkenc.FromData(chunkenc.EncXOR, chk.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		it := c.Iterator(nil)
+		for it.Next() {
+			t, v := it.At()
+			if t < mint || t > maxt {
+				continue
+			}
+			if n := len(points); n > 0 && t <= points[n-1].T {
+				sorted = false
+			}
+			points = append(points, Point{T: t, V: v})
+		}
+		if err = it.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if sorted {
+		return points, nil
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].T < points[j].T
+	})
+	n := 0
+	for i := range points {
+		if n > 0 && points[i].T == points[n-1].T {
+			continue
+		}
+		points[n] = points[i]
+		n++
+	}
+	return points[:n], nil
+}
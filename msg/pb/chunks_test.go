@@ -0,0 +1,116 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeChunks(t *testing.T) {
+	points := regularSeries(300, 15000, func(i int) float64 { return float64(i) }).Points
+
+	chunks, err := EncodeChunks(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("want 300 points cut into 3 chunks, got %d", len(chunks))
+	}
+	for i, chk := range chunks {
+		if first, last := points[i*samplesPerChunk], points[min(i*samplesPerChunk+samplesPerChunk, len(points))-1]; chk.Mint != first.T || chk.Maxt != last.T {
+			t.Fatalf("chunk %d: want the bounds [%d, %d], got [%d, %d]", i, first.T, last.T, chk.Mint, chk.Maxt)
+		}
+	}
+
+	got, err := DecodeChunks(chunks, points[0].T, points[len(points)-1].T)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, points) {
+		t.Fatalf("want the points encoded, got %v", got)
+	}
+
+	// only the samples in the bounds are decoded
+	if got, err = DecodeChunks(chunks, points[100].T, points[250].T); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, points[100:251]) {
+		t.Fatalf("want the points in the bounds, got %d points", len(got))
+	}
+
+	if chunks, err = EncodeChunks(nil); err != nil || len(chunks) != 0 {
+		t.Fatalf("want no chunk of no point, got %v, %v", chunks, err)
+	}
+}
+
+func TestDecodeChunks_Overlapped(t *testing.T) {
+	points := regularSeries(200, 15000, func(i int) float64 { return float64(i) }).Points
+
+	// the chunks of two overlapping blocks, followed by the ones of the head
+	older, err := EncodeChunks(points[:100])
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := EncodeChunks(points[50:150])
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := EncodeChunks(points[140:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DecodeChunks(append(append(newer, older...), head...), points[0].T, points[len(points)-1].T)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, points) {
+		t.Fatalf("want the points deduplicated in time order, got %d points", len(got))
+	}
+}
+
+func TestSeries_ExpandChunks(t *testing.T) {
+	want := regularSeries(130, 15000, func(i int) float64 { return float64(i) })
+
+	chunks, err := EncodeChunks(want.Points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Series{Labels: want.Labels, Chunks: chunks}
+	b, err := s.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := new(Series)
+	if err = got.Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	if err = got.Expand(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Points, want.Points) || len(got.Chunks) != 0 {
+		t.Fatalf("want the chunks expanded into the points, got %v", got)
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"math"
+
+	"github.com/prometheus/tsdb/chunkenc"
+)
+
+// Compact moves the points of m into its chunk in the xor encoding of tsdb, i.e. the first
+// timestamp followed by delta-of-deltas and values xor'ed with the previous ones, which is far
+// smaller than the repeated points for regularly spaced samples. Series with histograms or
+// more points than a chunk can hold are left as they are.
+func (m *Series) Compact() error {
+	if len(m.Points) == 0 || len(m.Points) > math.MaxUint16 {
+		return nil
+	}
+	for i := range m.Points {
+		if m.Points[i].H != nil {
+			return nil
+		}
+	}
+
+	c := chunkenc.NewXORChunk()
+	app, err := c.Appender()
+	if err != nil {
+		return err
+	}
+	for _, p := range m.Points {
+		app.Append(p.T, p.V)
+	}
+
+	m.Chunk = c.Bytes()
+	m.Points = nil
+	return nil
+}
+
+// Expand decodes the chunk of m back into its points, it's a noop if m has no chunk.
+// So are the raw chunks of m, all the samples of which are kept.
+func (m *Series) Expand() error {
+	if len(m.Chunks) > 0 {
+		points, err := DecodeChunks(m.Chunks, math.MinInt64, math.MaxInt64)
+		if err != nil {
+			return err
+		}
+		m.Points = points
+		m.Chunks = m.Chunks[:0]
+		return nil
+	}
+
+	if len(m.Chunk) == 0 {
+		return nil
+	}
+
+	c, err := chunkenc.FromData(chunkenc.EncXOR, m.Chunk)
+	if err != nil {
+		return err
+	}
+
+	points := m.Points[:0]
+	if cap(points) < c.NumSamples() {
+		points = make([]Point, 0, c.NumSamples())
+	}
+
+	it := c.Iterator(nil)
+	for it.Next() {
+		t, v := it.At()
+		points = append(points, Point{T: t, V: v})
+	}
+	if err = it.Err(); err != nil {
+		return err
+	}
+
+	m.Points = points
+	m.Chunk = m.Chunk[:0]
+	return nil
+}
+
+// HasHistograms tells whether any point of series is a native histogram.
+func HasHistograms(series []*Series) bool {
+	for _, s := range series {
+		for i := range s.Points {
+			if s.Points[i].H != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
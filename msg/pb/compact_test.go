@@ -0,0 +1,113 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func regularSeries(pointNum int, interval int64, value func(i int) float64) *Series {
+	s := &Series{Labels: []Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: "10.0.0.1:9100"}}}
+	for i := 0; i < pointNum; i++ {
+		s.Points = append(s.Points, Point{T: 1560000000000 + int64(i)*interval, V: value(i)})
+	}
+	return s
+}
+
+func TestSeries_CompactRoundTrip(t *testing.T) {
+	jittered := regularSeries(50, 15000, func(i int) float64 { return float64(i) * 1.5 })
+	for i := range jittered.Points {
+		jittered.Points[i].T += int64(i*37) % 200
+	}
+
+	tests := []*Series{
+		regularSeries(1, 15000, func(int) float64 { return 1 }),
+		regularSeries(120, 15000, func(int) float64 { return 1 }),
+		regularSeries(120, 60000, func(i int) float64 { return float64(i * i) }),
+		regularSeries(10, 1000, func(i int) float64 { return math.Sin(float64(i)) }),
+		regularSeries(3, 1, func(i int) float64 { return []float64{math.Inf(1), math.NaN(), -0.5}[i] }),
+		jittered,
+	}
+
+	for i, want := range tests {
+		b, err := want.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		compact := new(Series)
+		if err = compact.Unmarshal(b); err != nil {
+			t.Fatal(err)
+		}
+		if err = compact.Compact(); err != nil {
+			t.Fatal(err)
+		}
+		if len(compact.Points) != 0 || len(compact.Chunk) == 0 {
+			t.Fatalf("case %d: expected points moved into the chunk", i)
+		}
+
+		if b, err = compact.Marshal(); err != nil {
+			t.Fatal(err)
+		}
+		got := new(Series)
+		if err = got.Unmarshal(b); err != nil {
+			t.Fatal(err)
+		}
+		if err = got.Expand(); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got.Labels, want.Labels) || len(got.Points) != len(want.Points) || len(got.Chunk) != 0 {
+			t.Fatalf("case %d: want %v, got %v", i, want, got)
+		}
+		for j, p := range want.Points {
+			if got.Points[j].T != p.T || math.Float64bits(got.Points[j].V) != math.Float64bits(p.V) {
+				t.Fatalf("case %d: point %d: want %v, got %v", i, j, p, got.Points[j])
+			}
+		}
+	}
+
+	// histograms can't be put in chunks
+	s := regularSeries(2, 15000, func(int) float64 { return 1 })
+	s.Points[1].H = &Histogram{Count: 1}
+	if err := s.Compact(); err != nil || len(s.Points) != 2 || len(s.Chunk) != 0 {
+		t.Fatalf("expected series with histograms left as they are")
+	}
+}
+
+func TestSeries_CompactSize(t *testing.T) {
+	tests := []struct {
+		series   *Series
+		maxRatio float64
+	}{
+		{series: regularSeries(240, 15000, func(int) float64 { return 1 }), maxRatio: 0.05},
+		{series: regularSeries(240, 15000, func(i int) float64 { return float64(i / 10) }), maxRatio: 0.1},
+		{series: regularSeries(240, 15000, func(i int) float64 { return math.Sin(float64(i)) }), maxRatio: 0.8},
+	}
+
+	for i, test := range tests {
+		plain := test.series.Size()
+		if err := test.series.Compact(); err != nil {
+			t.Fatal(err)
+		}
+		compact := test.series.Size()
+
+		if ratio := float64(compact) / float64(plain); ratio > test.maxRatio {
+			t.Fatalf("case %d: %d bytes compacted to %d, ratio %.3f exceeds %.3f", i, plain, compact, ratio, test.maxRatio)
+		}
+	}
+}
@@ -26,33 +26,45 @@ type CtrlCode int32
 const (
 	CtrlCode_CloseRead  CtrlCode = 0
 	CtrlCode_CloseWrite CtrlCode = 1
+	CtrlCode_Ping       CtrlCode = 2
+	CtrlCode_Pong       CtrlCode = 3
+	CtrlCode_Hello      CtrlCode = 4
 )
 
 var CtrlCode_name = map[int32]string{
 	0: "CloseRead",
 	1: "CloseWrite",
+	2: "Ping",
+	3: "Pong",
+	4: "Hello",
 }
 var CtrlCode_value = map[string]int32{
 	"CloseRead":  0,
 	"CloseWrite": 1,
+	"Ping":       2,
+	"Pong":       3,
+	"Hello":      4,
 }
 
 func (x CtrlCode) String() string {
 	return proto.EnumName(CtrlCode_name, int32(x))
 }
 func (CtrlCode) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_conn_d22d84c1055b8f63, []int{0}
+	return fileDescriptor_conn_ab25cb09fd120083, []int{0}
 }
 
 type ConnCtrl struct {
-	Code CtrlCode `protobuf:"varint,1,opt,name=code,proto3,enum=pb.CtrlCode" json:"code,omitempty"`
+	Code     CtrlCode `protobuf:"varint,1,opt,name=code,proto3,enum=pb.CtrlCode" json:"code,omitempty"`
+	Version  uint32   `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Features uint64   `protobuf:"varint,3,opt,name=features,proto3" json:"features,omitempty"`
+	Compress uint32   `protobuf:"varint,4,opt,name=compress,proto3" json:"compress,omitempty"`
 }
 
 func (m *ConnCtrl) Reset()         { *m = ConnCtrl{} }
 func (m *ConnCtrl) String() string { return proto.CompactTextString(m) }
 func (*ConnCtrl) ProtoMessage()    {}
 func (*ConnCtrl) Descriptor() ([]byte, []int) {
-	return fileDescriptor_conn_d22d84c1055b8f63, []int{0}
+	return fileDescriptor_conn_ab25cb09fd120083, []int{0}
 }
 func (m *ConnCtrl) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -88,6 +100,27 @@ func (m *ConnCtrl) GetCode() CtrlCode {
 	return CtrlCode_CloseRead
 }
 
+func (m *ConnCtrl) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *ConnCtrl) GetFeatures() uint64 {
+	if m != nil {
+		return m.Features
+	}
+	return 0
+}
+
+func (m *ConnCtrl) GetCompress() uint32 {
+	if m != nil {
+		return m.Compress
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*ConnCtrl)(nil), "pb.ConnCtrl")
 	proto.RegisterEnum("pb.CtrlCode", CtrlCode_name, CtrlCode_value)
@@ -112,6 +145,21 @@ func (m *ConnCtrl) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintConn(dAtA, i, uint64(m.Code))
 	}
+	if m.Version != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintConn(dAtA, i, uint64(m.Version))
+	}
+	if m.Features != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintConn(dAtA, i, uint64(m.Features))
+	}
+	if m.Compress != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintConn(dAtA, i, uint64(m.Compress))
+	}
 	return i, nil
 }
 
@@ -133,6 +181,15 @@ func (m *ConnCtrl) Size() (n int) {
 	if m.Code != 0 {
 		n += 1 + sovConn(uint64(m.Code))
 	}
+	if m.Version != 0 {
+		n += 1 + sovConn(uint64(m.Version))
+	}
+	if m.Features != 0 {
+		n += 1 + sovConn(uint64(m.Features))
+	}
+	if m.Compress != 0 {
+		n += 1 + sovConn(uint64(m.Compress))
+	}
 	return n
 }
 
@@ -197,6 +254,63 @@ func (m *ConnCtrl) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+			}
+			m.Version = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConn
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Version |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Features", wireType)
+			}
+			m.Features = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConn
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Features |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compress", wireType)
+			}
+			m.Compress = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConn
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Compress |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipConn(dAtA[iNdEx:])
@@ -323,20 +437,24 @@ var (
 	ErrIntOverflowConn   = fmt.Errorf("proto: integer overflow")
 )
 
-func init() { proto.RegisterFile("conn.proto", fileDescriptor_conn_d22d84c1055b8f63) }
+func init() { proto.RegisterFile("conn.proto", fileDescriptor_conn_ab25cb09fd120083) }
 
-var fileDescriptor_conn_d22d84c1055b8f63 = []byte{
-	// 181 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x4a, 0xce, 0xcf, 0xcb,
-	0xd3, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x2a, 0x48, 0x92, 0xd2, 0x4d, 0xcf, 0x2c, 0xc9,
-	0x28, 0x4d, 0xd2, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xcf, 0x4f, 0xcf, 0xd7, 0x07, 0x4b, 0x25, 0x95,
-	0xa6, 0x81, 0x79, 0x60, 0x0e, 0x98, 0x05, 0xd1, 0xa2, 0xa4, 0xc3, 0xc5, 0xe1, 0x9c, 0x9f, 0x97,
-	0xe7, 0x5c, 0x52, 0x94, 0x23, 0xa4, 0xc0, 0xc5, 0x92, 0x9c, 0x9f, 0x92, 0x2a, 0xc1, 0xa8, 0xc0,
-	0xa8, 0xc1, 0x67, 0xc4, 0xa3, 0x57, 0x90, 0xa4, 0x07, 0x12, 0x77, 0xce, 0x4f, 0x49, 0x0d, 0x02,
-	0xcb, 0x68, 0x69, 0x72, 0x71, 0xc0, 0x44, 0x84, 0x78, 0xb9, 0x38, 0x9d, 0x73, 0xf2, 0x8b, 0x53,
-	0x83, 0x52, 0x13, 0x53, 0x04, 0x18, 0x84, 0xf8, 0xb8, 0xb8, 0xc0, 0xdc, 0xf0, 0xa2, 0xcc, 0x92,
-	0x54, 0x01, 0x46, 0x27, 0x99, 0x13, 0x8f, 0xe4, 0x18, 0x2f, 0x3c, 0x92, 0x63, 0x7c, 0xf0, 0x48,
-	0x8e, 0x71, 0xc2, 0x63, 0x39, 0x86, 0x0b, 0x8f, 0xe5, 0x18, 0x6e, 0x3c, 0x96, 0x63, 0x88, 0x62,
-	0x2a, 0x48, 0x4a, 0x62, 0x03, 0xdb, 0x6e, 0x0c, 0x08, 0x00, 0x00, 0xff, 0xff, 0xb3, 0x9e, 0xd9,
-	0x70, 0xbe, 0x00, 0x00, 0x00,
+var fileDescriptor_conn_ab25cb09fd120083 = []byte{
+	// 253 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x3c, 0xcf, 0x31, 0x6a, 0xc3, 0x30,
+	0x14, 0x06, 0x60, 0xcb, 0x71, 0x5b, 0xe7, 0xd1, 0x04, 0xa3, 0xc9, 0x84, 0x22, 0x4c, 0x27, 0x53,
+	0xa8, 0x03, 0xed, 0x0d, 0xea, 0x25, 0x63, 0xf1, 0x52, 0xe8, 0x16, 0xd9, 0x2f, 0xae, 0xc1, 0xd1,
+	0x13, 0x92, 0xdc, 0xb1, 0x67, 0xe8, 0xb1, 0x3a, 0x66, 0xec, 0x58, 0xec, 0x8b, 0x94, 0x28, 0x24,
+	0xdb, 0xff, 0xe9, 0xe7, 0x47, 0x3c, 0x80, 0x9a, 0x94, 0x2a, 0xb4, 0x21, 0x47, 0x3c, 0xd4, 0x72,
+	0xf5, 0xd8, 0x76, 0xee, 0x63, 0x90, 0x45, 0x4d, 0xfb, 0x75, 0x4b, 0x2d, 0xad, 0x7d, 0x25, 0x87,
+	0x9d, 0x97, 0x87, 0x4f, 0xa7, 0xc9, 0xfd, 0x17, 0xc4, 0x25, 0x29, 0x55, 0x3a, 0xd3, 0xf3, 0x0c,
+	0xa2, 0x9a, 0x1a, 0x4c, 0x59, 0xc6, 0xf2, 0xe5, 0xd3, 0x6d, 0xa1, 0x65, 0x71, 0x7c, 0x2f, 0xa9,
+	0xc1, 0xca, 0x37, 0x3c, 0x85, 0x9b, 0x4f, 0x34, 0xb6, 0x23, 0x95, 0x86, 0x19, 0xcb, 0x17, 0xd5,
+	0x99, 0x7c, 0x05, 0xf1, 0x0e, 0xb7, 0x6e, 0x30, 0x68, 0xd3, 0x59, 0xc6, 0xf2, 0xa8, 0xba, 0xf8,
+	0xd8, 0xd5, 0xb4, 0xd7, 0x06, 0xad, 0x4d, 0x23, 0x3f, 0xbb, 0xf8, 0x61, 0x03, 0xf1, 0xf9, 0x0f,
+	0xbe, 0x80, 0x79, 0xd9, 0x93, 0xc5, 0x0a, 0xb7, 0x4d, 0x12, 0xf0, 0x25, 0x80, 0xe7, 0x9b, 0xe9,
+	0x1c, 0x26, 0x8c, 0xc7, 0x10, 0xbd, 0x76, 0xaa, 0x4d, 0x42, 0x9f, 0x48, 0xb5, 0xc9, 0x8c, 0xcf,
+	0xe1, 0x6a, 0x83, 0x7d, 0x4f, 0x49, 0xf4, 0x72, 0xf7, 0x33, 0x0a, 0x76, 0x18, 0x05, 0xfb, 0x1b,
+	0x05, 0xfb, 0x9e, 0x44, 0x70, 0x98, 0x44, 0xf0, 0x3b, 0x89, 0xe0, 0x3d, 0xd4, 0x52, 0x5e, 0xfb,
+	0x73, 0x9f, 0xff, 0x07, 0x00, 0xed, 0x5c, 0xf8, 0x5c, 0x2f, 0x01, 0x00, 0x00,
 }
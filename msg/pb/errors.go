@@ -0,0 +1,68 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ResponseError is the error carried by a failed GeneralResponse.
+type ResponseError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Code == ErrorCode_Unspecified {
+		return e.Message
+	}
+	return e.Code.String() + ": " + e.Message
+}
+
+// Retryable reports whether the request may succeed if sent again, maybe to another node.
+func (e *ResponseError) Retryable() bool {
+	return e.Code != ErrorCode_BadRequest
+}
+
+// Response returns the failed GeneralResponse carrying e.
+func (e *ResponseError) Response() *GeneralResponse {
+	return &GeneralResponse{Status: StatusCode_Failed, Code: e.Code, Message: e.Message}
+}
+
+// Err returns nil if r succeeded, otherwise a *ResponseError.
+func (r *GeneralResponse) Err() error {
+	if r.Status == StatusCode_Succeed {
+		return nil
+	}
+	return &ResponseError{Code: r.Code, Message: r.Message}
+}
+
+// CodeOf returns the code of err if it's caused by a *ResponseError, otherwise Unspecified.
+func CodeOf(err error) ErrorCode {
+	if e, ok := errors.Cause(err).(*ResponseError); ok {
+		return e.Code
+	}
+	return ErrorCode_Unspecified
+}
+
+// Retryable reports whether the request failed with err may succeed if sent again, errors
+// not carried by a response (e.g. network errors) are retryable.
+func Retryable(err error) bool {
+	if e, ok := errors.Cause(err).(*ResponseError); ok {
+		return e.Retryable()
+	}
+	return true
+}
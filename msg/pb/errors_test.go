@@ -0,0 +1,71 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestGeneralResponse_Err(t *testing.T) {
+	if err := (&GeneralResponse{Status: StatusCode_Succeed}).Err(); err != nil {
+		t.Fatalf("want no error for a succeed response, got %v", err)
+	}
+
+	tests := []struct {
+		resp      *GeneralResponse
+		retryable bool
+	}{
+		{&GeneralResponse{Status: StatusCode_Failed, Message: "disk full"}, true},
+		{&GeneralResponse{Status: StatusCode_Failed, Code: ErrorCode_NotLeader, Message: "left"}, true},
+		{&GeneralResponse{Status: StatusCode_Failed, Code: ErrorCode_Overloaded, Message: "busy"}, true},
+		{&GeneralResponse{Status: StatusCode_Failed, Code: ErrorCode_BadRequest, Message: "bad"}, false},
+	}
+
+	for _, test := range tests {
+		b, err := test.resp.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp := new(GeneralResponse)
+		if err = resp.Unmarshal(b); err != nil {
+			t.Fatal(err)
+		}
+
+		err = errors.Wrap(resp.Err(), "flush")
+		if got := CodeOf(err); got != test.resp.Code {
+			t.Fatalf("want code %v, got %v", test.resp.Code, got)
+		}
+		if got := Retryable(err); got != test.retryable {
+			t.Fatalf("%v: want retryable %v, got %v", test.resp, test.retryable, got)
+		}
+	}
+
+	// a response of an old peer has no code
+	resp := new(GeneralResponse)
+	if err := resp.Unmarshal([]byte{1<<3 | 0, 1, 2<<3 | 2, 3, 'e', 'r', 'r'}); err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Err(); CodeOf(err) != ErrorCode_Unspecified || err.Error() != "err" || !Retryable(err) {
+		t.Fatalf("unexpected error of an old peer's response: %v", err)
+	}
+
+	if !Retryable(errors.New("connection reset")) || CodeOf(errors.New("connection reset")) != ErrorCode_Unspecified {
+		t.Fatalf("want errors not carried by a response retryable")
+	}
+}
@@ -32,7 +32,7 @@ func (m *InstantQueryRequest) Reset()         { *m = InstantQueryRequest{} }
 func (m *InstantQueryRequest) String() string { return proto.CompactTextString(m) }
 func (*InstantQueryRequest) ProtoMessage()    {}
 func (*InstantQueryRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_gateway_9fc146c7b4b0d624, []int{0}
+	return fileDescriptor_gateway_bd59fe0cfe038e02, []int{0}
 }
 func (m *InstantQueryRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -94,7 +94,7 @@ func (m *RangeQueryRequest) Reset()         { *m = RangeQueryRequest{} }
 func (m *RangeQueryRequest) String() string { return proto.CompactTextString(m) }
 func (*RangeQueryRequest) ProtoMessage()    {}
 func (*RangeQueryRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_gateway_9fc146c7b4b0d624, []int{1}
+	return fileDescriptor_gateway_bd59fe0cfe038e02, []int{1}
 }
 func (m *RangeQueryRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -168,7 +168,7 @@ func (m *QueryResponse) Reset()         { *m = QueryResponse{} }
 func (m *QueryResponse) String() string { return proto.CompactTextString(m) }
 func (*QueryResponse) ProtoMessage()    {}
 func (*QueryResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_gateway_9fc146c7b4b0d624, []int{2}
+	return fileDescriptor_gateway_bd59fe0cfe038e02, []int{2}
 }
 func (m *QueryResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -226,7 +226,7 @@ func (m *AddRequest) Reset()         { *m = AddRequest{} }
 func (m *AddRequest) String() string { return proto.CompactTextString(m) }
 func (*AddRequest) ProtoMessage()    {}
 func (*AddRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_gateway_9fc146c7b4b0d624, []int{3}
+	return fileDescriptor_gateway_bd59fe0cfe038e02, []int{3}
 }
 func (m *AddRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -263,16 +263,20 @@ func (m *AddRequest) GetSeries() []*pb.Series {
 }
 
 type LabelValuesRequest struct {
-	Name       string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Constraint string `protobuf:"bytes,2,opt,name=constraint,proto3" json:"constraint,omitempty"`
-	Timeout    string `protobuf:"bytes,3,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	Name         string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Constraint   string `protobuf:"bytes,2,opt,name=constraint,proto3" json:"constraint,omitempty"`
+	Timeout      string `protobuf:"bytes,3,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	Start        string `protobuf:"bytes,4,opt,name=start,proto3" json:"start,omitempty"`
+	End          string `protobuf:"bytes,5,opt,name=end,proto3" json:"end,omitempty"`
+	Limit        uint32 `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+	Continuation string `protobuf:"bytes,7,opt,name=continuation,proto3" json:"continuation,omitempty"`
 }
 
 func (m *LabelValuesRequest) Reset()         { *m = LabelValuesRequest{} }
 func (m *LabelValuesRequest) String() string { return proto.CompactTextString(m) }
 func (*LabelValuesRequest) ProtoMessage()    {}
 func (*LabelValuesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_gateway_9fc146c7b4b0d624, []int{4}
+	return fileDescriptor_gateway_bd59fe0cfe038e02, []int{4}
 }
 func (m *LabelValuesRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -322,6 +326,34 @@ func (m *LabelValuesRequest) GetTimeout() string {
 	return ""
 }
 
+func (m *LabelValuesRequest) GetStart() string {
+	if m != nil {
+		return m.Start
+	}
+	return ""
+}
+
+func (m *LabelValuesRequest) GetEnd() string {
+	if m != nil {
+		return m.End
+	}
+	return ""
+}
+
+func (m *LabelValuesRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *LabelValuesRequest) GetContinuation() string {
+	if m != nil {
+		return m.Continuation
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*InstantQueryRequest)(nil), "gateway.InstantQueryRequest")
 	proto.RegisterType((*RangeQueryRequest)(nil), "gateway.RangeQueryRequest")
@@ -511,6 +543,29 @@ func (m *LabelValuesRequest) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintGateway(dAtA, i, uint64(len(m.Timeout)))
 		i += copy(dAtA[i:], m.Timeout)
 	}
+	if len(m.Start) > 0 {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintGateway(dAtA, i, uint64(len(m.Start)))
+		i += copy(dAtA[i:], m.Start)
+	}
+	if len(m.End) > 0 {
+		dAtA[i] = 0x2a
+		i++
+		i = encodeVarintGateway(dAtA, i, uint64(len(m.End)))
+		i += copy(dAtA[i:], m.End)
+	}
+	if m.Limit != 0 {
+		dAtA[i] = 0x30
+		i++
+		i = encodeVarintGateway(dAtA, i, uint64(m.Limit))
+	}
+	if len(m.Continuation) > 0 {
+		dAtA[i] = 0x3a
+		i++
+		i = encodeVarintGateway(dAtA, i, uint64(len(m.Continuation)))
+		i += copy(dAtA[i:], m.Continuation)
+	}
 	return i, nil
 }
 
@@ -626,6 +681,21 @@ func (m *LabelValuesRequest) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovGateway(uint64(l))
 	}
+	l = len(m.Start)
+	if l > 0 {
+		n += 1 + l + sovGateway(uint64(l))
+	}
+	l = len(m.End)
+	if l > 0 {
+		n += 1 + l + sovGateway(uint64(l))
+	}
+	if m.Limit != 0 {
+		n += 1 + sovGateway(uint64(m.Limit))
+	}
+	l = len(m.Continuation)
+	if l > 0 {
+		n += 1 + l + sovGateway(uint64(l))
+	}
 	return n
 }
 
@@ -1298,6 +1368,112 @@ func (m *LabelValuesRequest) Unmarshal(dAtA []byte) error {
 			}
 			m.Timeout = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Start", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthGateway
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Start = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field End", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthGateway
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.End = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
+			}
+			m.Limit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Limit |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Continuation", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthGateway
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Continuation = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipGateway(dAtA[iNdEx:])
@@ -1424,32 +1600,34 @@ var (
 	ErrIntOverflowGateway   = fmt.Errorf("proto: integer overflow")
 )
 
-func init() { proto.RegisterFile("gateway.proto", fileDescriptor_gateway_9fc146c7b4b0d624) }
+func init() { proto.RegisterFile("gateway.proto", fileDescriptor_gateway_bd59fe0cfe038e02) }
 
-var fileDescriptor_gateway_9fc146c7b4b0d624 = []byte{
-	// 382 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x52, 0xbd, 0xae, 0xd3, 0x30,
-	0x18, 0x6d, 0x48, 0x9b, 0xc2, 0x87, 0x5a, 0x81, 0xa9, 0x50, 0xd4, 0x21, 0x2a, 0x19, 0x50, 0x07,
-	0x48, 0x50, 0x79, 0x02, 0x60, 0x42, 0x82, 0x81, 0x20, 0x21, 0xc1, 0x66, 0x37, 0x1f, 0x26, 0xd0,
-	0xda, 0xa9, 0x7f, 0x84, 0x3a, 0xf1, 0x0a, 0x3c, 0xd6, 0x1d, 0x3b, 0xde, 0xf1, 0xaa, 0x7d, 0x91,
-	0xab, 0x38, 0xce, 0x6d, 0x3a, 0xdc, 0x29, 0xe7, 0x1c, 0xdb, 0xe7, 0x3b, 0x39, 0xfa, 0x60, 0xc2,
-	0xa9, 0xc1, 0xbf, 0x74, 0x9f, 0xd5, 0x4a, 0x1a, 0x49, 0xc6, 0x9e, 0xce, 0x5f, 0xf1, 0xca, 0x64,
-	0xbf, 0xcb, 0x6c, 0x2d, 0xb7, 0x39, 0xa3, 0xb6, 0x34, 0xd5, 0x16, 0xcf, 0x60, 0xab, 0x79, 0x5e,
-	0xb3, 0xbc, 0x66, 0xed, 0xb3, 0xf9, 0x6b, 0x5e, 0x99, 0x5f, 0x96, 0xb9, 0xdb, 0x5c, 0x72, 0x99,
-	0x3b, 0x99, 0xd9, 0x9f, 0x8e, 0x39, 0xe2, 0x50, 0x7b, 0x3d, 0xfd, 0x0e, 0xcf, 0x3e, 0x0a, 0x6d,
-	0xa8, 0x30, 0x5f, 0x2c, 0xaa, 0x7d, 0x81, 0x3b, 0x8b, 0xda, 0x10, 0x02, 0xc3, 0xc6, 0x3d, 0x0e,
-	0x16, 0xc1, 0xf2, 0x51, 0xe1, 0x30, 0x89, 0x61, 0xdc, 0x7c, 0xa5, 0x35, 0xf1, 0x03, 0x27, 0x77,
-	0x94, 0xcc, 0x60, 0xb4, 0x6b, 0x5e, 0xc7, 0xa1, 0xd3, 0x5b, 0x92, 0xfe, 0x83, 0xa7, 0x05, 0x15,
-	0x1c, 0x2f, 0x8c, 0x67, 0x30, 0xd2, 0x86, 0x2a, 0xe3, 0x9d, 0x5b, 0x42, 0x9e, 0x40, 0x88, 0xa2,
-	0xf4, 0xb6, 0x0d, 0x6c, 0x02, 0x68, 0x83, 0xb5, 0x77, 0x74, 0xb8, 0x1f, 0x60, 0x78, 0x4f, 0x80,
-	0x51, 0x3f, 0xc0, 0x1f, 0x98, 0xf8, 0xd9, 0xba, 0x96, 0x42, 0x23, 0x79, 0x0e, 0x91, 0x42, 0x6d,
-	0x37, 0xdd, 0x74, 0xcf, 0xc8, 0x4b, 0x88, 0xb4, 0xa1, 0xc6, 0x6a, 0x97, 0x60, 0xba, 0x9a, 0x66,
-	0x35, 0xcb, 0xbe, 0x3a, 0xe5, 0x83, 0x2c, 0xb1, 0xf0, 0xa7, 0x64, 0x0e, 0x0f, 0x51, 0x29, 0xa9,
-	0x3e, 0x6b, 0xee, 0x83, 0xdd, 0xf1, 0xf4, 0x0d, 0xc0, 0xbb, 0xb2, 0xec, 0x7e, 0x33, 0x85, 0x48,
-	0xa3, 0xaa, 0x50, 0xc7, 0xc1, 0x22, 0x5c, 0x3e, 0x5e, 0x81, 0x73, 0x74, 0x4a, 0xe1, 0x4f, 0x52,
-	0x06, 0xe4, 0x13, 0x65, 0xb8, 0xf9, 0x46, 0x37, 0x16, 0x75, 0xaf, 0x79, 0x41, 0xcf, 0xcd, 0x37,
-	0x98, 0x24, 0x00, 0x6b, 0x29, 0xb4, 0x51, 0xb4, 0x12, 0x5d, 0xf9, 0x3d, 0xa5, 0x5f, 0x4c, 0x78,
-	0x51, 0xcc, 0xfb, 0x17, 0x57, 0xc7, 0x24, 0x38, 0x1c, 0x93, 0xe0, 0xe6, 0x98, 0x04, 0xff, 0x4f,
-	0xc9, 0xe0, 0x70, 0x4a, 0x06, 0xd7, 0xa7, 0x64, 0xf0, 0xa3, 0x5b, 0x2f, 0x16, 0xb9, 0x45, 0x78,
-	0x7b, 0x1b, 0x00, 0x00, 0xff, 0xff, 0x64, 0xe5, 0x00, 0xb5, 0x7f, 0x02, 0x00, 0x00,
+var fileDescriptor_gateway_bd59fe0cfe038e02 = []byte{
+	// 414 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x52, 0xb1, 0xae, 0xd3, 0x30,
+	0x14, 0x6d, 0x68, 0x93, 0xc2, 0x85, 0x3e, 0x81, 0xa9, 0x50, 0xd4, 0x21, 0x2a, 0x19, 0x50, 0x07,
+	0x68, 0xd1, 0xe3, 0x0b, 0x80, 0x09, 0x09, 0x06, 0x82, 0x84, 0x04, 0x9b, 0xdd, 0x5c, 0x82, 0x45,
+	0x63, 0xe7, 0xd9, 0xd7, 0x42, 0x6f, 0xe2, 0x17, 0xf8, 0x28, 0x06, 0xc6, 0x37, 0x32, 0xa2, 0xf6,
+	0x47, 0x50, 0x1c, 0x87, 0xa6, 0x82, 0x37, 0xe5, 0x9c, 0x63, 0xdf, 0xe3, 0x93, 0xa3, 0x0b, 0xb3,
+	0x8a, 0x13, 0x7e, 0xe5, 0x97, 0xeb, 0xc6, 0x68, 0xd2, 0x6c, 0x1a, 0xe8, 0xe2, 0x71, 0x25, 0xe9,
+	0xb3, 0x13, 0xeb, 0xad, 0xae, 0x37, 0x82, 0xbb, 0x92, 0x64, 0x8d, 0x47, 0x50, 0xdb, 0x6a, 0xd3,
+	0x88, 0x4d, 0x23, 0xba, 0xb1, 0xc5, 0x93, 0xc1, 0xed, 0x4a, 0x57, 0x7a, 0xe3, 0x65, 0xe1, 0x3e,
+	0x79, 0xe6, 0x89, 0x47, 0xdd, 0xf5, 0xfc, 0x03, 0xdc, 0x7f, 0xa5, 0x2c, 0x71, 0x45, 0x6f, 0x1d,
+	0x9a, 0xcb, 0x02, 0x2f, 0x1c, 0x5a, 0x62, 0x0c, 0x26, 0xad, 0x7b, 0x1a, 0x2d, 0xa3, 0xd5, 0xad,
+	0xc2, 0x63, 0x96, 0xc2, 0xb4, 0xfd, 0x6a, 0x47, 0xe9, 0x0d, 0x2f, 0xf7, 0x94, 0xcd, 0x21, 0xbe,
+	0x68, 0xa7, 0xd3, 0xb1, 0xd7, 0x3b, 0x92, 0x7f, 0x83, 0x7b, 0x05, 0x57, 0x15, 0x9e, 0x18, 0xcf,
+	0x21, 0xb6, 0xc4, 0x0d, 0x05, 0xe7, 0x8e, 0xb0, 0xbb, 0x30, 0x46, 0x55, 0x06, 0xdb, 0x16, 0xb6,
+	0x01, 0x2c, 0x61, 0x13, 0x1c, 0x3d, 0x1e, 0x06, 0x98, 0x5c, 0x13, 0x20, 0x1e, 0x06, 0xf8, 0x02,
+	0xb3, 0xf0, 0xb6, 0x6d, 0xb4, 0xb2, 0xc8, 0x1e, 0x40, 0x62, 0xd0, 0xba, 0x5d, 0xff, 0x7a, 0x60,
+	0xec, 0x11, 0x24, 0x96, 0x38, 0x39, 0xeb, 0x13, 0x9c, 0x9d, 0x9f, 0xad, 0x1b, 0xb1, 0x7e, 0xe7,
+	0x95, 0x97, 0xba, 0xc4, 0x22, 0x9c, 0xb2, 0x05, 0xdc, 0x44, 0x63, 0xb4, 0x79, 0x63, 0xab, 0x10,
+	0xec, 0x2f, 0xcf, 0x9f, 0x02, 0x3c, 0x2f, 0xcb, 0xfe, 0x37, 0x73, 0x48, 0x2c, 0x1a, 0x89, 0x36,
+	0x8d, 0x96, 0xe3, 0xd5, 0xed, 0x73, 0xf0, 0x8e, 0x5e, 0x29, 0xc2, 0x49, 0xfe, 0x23, 0x02, 0xf6,
+	0x9a, 0x0b, 0xdc, 0xbd, 0xe7, 0x3b, 0x87, 0x76, 0x50, 0xbd, 0xe2, 0xc7, 0xea, 0x5b, 0xcc, 0x32,
+	0x80, 0xad, 0x56, 0x96, 0x0c, 0x97, 0xaa, 0x6f, 0x7f, 0xa0, 0x0c, 0x9b, 0x19, 0xff, 0xd3, 0x4c,
+	0xd7, 0xf7, 0xe4, 0x3f, 0x7d, 0xc7, 0xc7, 0xbe, 0xe7, 0x10, 0xef, 0x64, 0x2d, 0x29, 0x4d, 0x96,
+	0xd1, 0x6a, 0x56, 0x74, 0x84, 0xe5, 0x70, 0x67, 0xab, 0x15, 0x49, 0xe5, 0x38, 0x49, 0xad, 0xd2,
+	0xa9, 0x1f, 0x38, 0xd1, 0x5e, 0x3c, 0xfc, 0xb9, 0xcf, 0xa2, 0xab, 0x7d, 0x16, 0xfd, 0xde, 0x67,
+	0xd1, 0xf7, 0x43, 0x36, 0xba, 0x3a, 0x64, 0xa3, 0x5f, 0x87, 0x6c, 0xf4, 0xb1, 0xdf, 0x60, 0x91,
+	0xf8, 0x5d, 0x7b, 0xf6, 0x67, 0x00, 0xdc, 0x59, 0x3f, 0x0e, 0xe2, 0x02, 0x00, 0x00,
 }
@@ -0,0 +1,32 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+// The hand-written MarshalTo encodes the fields in the order of their numbers, and no message
+// of the package has a map field, so its output is already canonical. proto.Marshal with the
+// deterministic option set takes the reflection path instead, which is much slower.
+
+// MarshalDeterministic returns the canonical encoding of the label, the same bytes for the same
+// name and value whenever and wherever it's marshaled.
+func (m *Label) MarshalDeterministic() ([]byte, error) {
+	return m.Marshal()
+}
+
+// MarshalDeterministic returns the canonical encoding of the series. The labels are encoded in
+// their order, so sort them (see util.SortLabels) first when the bytes identify the series.
+func (m *Series) MarshalDeterministic() ([]byte, error) {
+	return m.Marshal()
+}
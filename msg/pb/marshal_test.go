@@ -0,0 +1,70 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+func TestMarshalDeterministic(t *testing.T) {
+	series := &Series{
+		Labels: []Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "node"}},
+		Points: []Point{{T: 1560000000000, V: 1}, {T: 1560000015000, V: math.Inf(-1)}, {T: 1560000030000, H: &Histogram{Count: 3, Sum: 1.5}}},
+	}
+
+	// the encoding must never change, the bytes may be stored or signed
+	const want = "0a0e0a085f5f6e616d655f5f120275700a0b0a036a6f6212046e6f6465" + // labels
+		"12100880c0f7f3e65a11000000000000f03f121008b0aaf9f3e65a11000000000000f0ff" + // float points
+		"121408e094fbf3e65a1a0b080311000000000000f83f" // histogram point
+
+	first, err := series.MarshalDeterministic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(first); got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+
+	for i := 0; i < 100; i++ {
+		b, err := series.MarshalDeterministic()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, first) {
+			t.Fatalf("marshal %d: want %x, got %x", i, first, b)
+		}
+	}
+
+	// the same bytes as the reflection based deterministic marshaling
+	reflected, err := series.XXX_Marshal(nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reflected, first) {
+		t.Fatalf("want %x as the deterministic marshaling of proto, got %x", reflected, first)
+	}
+
+	label, err := series.Labels[1].MarshalDeterministic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(label) != "0a036a6f6212046e6f6465" {
+		t.Fatalf("unexpected label encoding %x", label)
+	}
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"sort"
+
+	"github.com/prometheus/prometheus/pkg/value"
+)
+
+// SeriesLabelsEqual reports whether a and b are the same series, i.e. they have the same label
+// pairs in the same order. The series returned by the gateways and the backends have their labels
+// sorted by name.
+func SeriesLabelsEqual(a, b *Series) bool {
+	if len(a.Labels) != len(b.Labels) {
+		return false
+	}
+	for i := range a.Labels {
+		if a.Labels[i].Name != b.Labels[i].Name || a.Labels[i].Value != b.Labels[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeSeriesPoints merges the points of src into dst, leaving the points of dst sorted by timestamp
+// with one point per timestamp. Of the points at the same timestamp, a real value wins over a staleness
+// marker, otherwise the one of dst is kept, so that merging the replicas of a series in the order of
+// preference keeps the values of the preferred one. src is left untouched. The series must have their
+// points expanded out of the chunks, see Expand.
+func MergeSeriesPoints(dst, src *Series) {
+	points := append(dst.Points, src.Points...)
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].T < points[j].T
+	})
+
+	merged := points[:0]
+	for _, p := range points {
+		if n := len(merged); n > 0 && merged[n-1].T == p.T {
+			if merged[n-1].stale() && !p.stale() {
+				merged[n-1] = p
+			}
+			continue
+		}
+		merged = append(merged, p)
+	}
+	dst.Points = merged
+}
+
+// stale reports whether p is a staleness marker, the value of a histogram sample is ignored.
+func (p Point) stale() bool {
+	return p.H == nil && value.IsStaleNaN(p.V)
+}
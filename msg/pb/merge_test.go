@@ -0,0 +1,99 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/value"
+)
+
+func TestSeriesLabelsEqual(t *testing.T) {
+	a := &Series{Labels: []Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "node"}}}
+
+	tests := []struct {
+		b     *Series
+		equal bool
+	}{
+		{&Series{Labels: []Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "node"}}, Points: []Point{{T: 1}}}, true},
+		{&Series{Labels: []Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "api"}}}, false},
+		{&Series{Labels: []Label{{Name: "__name__", Value: "up"}}}, false},
+		{&Series{Labels: []Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: "node"}}}, false},
+	}
+
+	for i, test := range tests {
+		if SeriesLabelsEqual(a, test.b) != test.equal || SeriesLabelsEqual(test.b, a) != test.equal {
+			t.Fatalf("case %d: want equal %v", i, test.equal)
+		}
+	}
+}
+
+func TestMergeSeriesPoints(t *testing.T) {
+	stale := math.Float64frombits(value.StaleNaN)
+
+	tests := []struct {
+		dst, src []Point
+		want     []Point
+	}{
+		{
+			dst:  []Point{{T: 0, V: 1}, {T: 30, V: 3}},
+			src:  []Point{{T: 15, V: 2}, {T: 45, V: 4}},
+			want: []Point{{T: 0, V: 1}, {T: 15, V: 2}, {T: 30, V: 3}, {T: 45, V: 4}},
+		},
+		{ // dst wins the conflicts of real values
+			dst:  []Point{{T: 0, V: 1}, {T: 15, V: 2}},
+			src:  []Point{{T: 0, V: 10}, {T: 15, V: 20}, {T: 30, V: 30}},
+			want: []Point{{T: 0, V: 1}, {T: 15, V: 2}, {T: 30, V: 30}},
+		},
+		{ // real values win over staleness markers whichever side they're on
+			dst:  []Point{{T: 0, V: stale}, {T: 15, V: 2}, {T: 30, V: stale}},
+			src:  []Point{{T: 0, V: 1}, {T: 15, V: stale}, {T: 30, V: stale}},
+			want: []Point{{T: 0, V: 1}, {T: 15, V: 2}, {T: 30, V: stale}},
+		},
+		{ // unsorted and duplicated points
+			dst:  []Point{{T: 30, V: 3}, {T: 0, V: 1}, {T: 30, V: 4}},
+			src:  []Point{{T: 15, V: 2}, {T: 0, V: 5}},
+			want: []Point{{T: 0, V: 1}, {T: 15, V: 2}, {T: 30, V: 3}},
+		},
+		{
+			dst:  nil,
+			src:  []Point{{T: 0, V: 1}},
+			want: []Point{{T: 0, V: 1}},
+		},
+	}
+
+	for i, test := range tests {
+		dst, src := &Series{Points: test.dst}, &Series{Points: test.src}
+		srcPoints := append([]Point(nil), test.src...)
+
+		MergeSeriesPoints(dst, src)
+
+		if len(dst.Points) != len(test.want) {
+			t.Fatalf("case %d: want %v, got %v", i, test.want, dst.Points)
+		}
+		for j, p := range test.want {
+			if dst.Points[j].T != p.T || math.Float64bits(dst.Points[j].V) != math.Float64bits(p.V) {
+				t.Fatalf("case %d: want %v, got %v", i, test.want, dst.Points)
+			}
+		}
+		for j, p := range srcPoints {
+			if src.Points[j].T != p.T || math.Float64bits(src.Points[j].V) != math.Float64bits(p.V) {
+				t.Fatalf("case %d: src is modified: %v", i, src.Points)
+			}
+		}
+	}
+}
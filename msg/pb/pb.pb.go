@@ -43,7 +43,42 @@ func (x StatusCode) String() string {
 	return proto.EnumName(StatusCode_name, int32(x))
 }
 func (StatusCode) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_pb_684b3f305cfcd3a3, []int{0}
+	return fileDescriptor_pb_5a6f9f47df98adde, []int{0}
+}
+
+type ErrorCode int32
+
+const (
+	ErrorCode_Unspecified    ErrorCode = 0
+	ErrorCode_NotLeader      ErrorCode = 1
+	ErrorCode_ShardMigrating ErrorCode = 2
+	ErrorCode_Overloaded     ErrorCode = 3
+	ErrorCode_BadRequest     ErrorCode = 4
+	ErrorCode_RateLimited    ErrorCode = 5
+)
+
+var ErrorCode_name = map[int32]string{
+	0: "Unspecified",
+	1: "NotLeader",
+	2: "ShardMigrating",
+	3: "Overloaded",
+	4: "BadRequest",
+	5: "RateLimited",
+}
+var ErrorCode_value = map[string]int32{
+	"Unspecified":    0,
+	"NotLeader":      1,
+	"ShardMigrating": 2,
+	"Overloaded":     3,
+	"BadRequest":     4,
+	"RateLimited":    5,
+}
+
+func (x ErrorCode) String() string {
+	return proto.EnumName(ErrorCode_name, int32(x))
+}
+func (ErrorCode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_pb_5a6f9f47df98adde, []int{1}
 }
 
 type Label struct {
@@ -55,7 +90,7 @@ func (m *Label) Reset()         { *m = Label{} }
 func (m *Label) String() string { return proto.CompactTextString(m) }
 func (*Label) ProtoMessage()    {}
 func (*Label) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_684b3f305cfcd3a3, []int{0}
+	return fileDescriptor_pb_5a6f9f47df98adde, []int{0}
 }
 func (m *Label) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -98,16 +133,239 @@ func (m *Label) GetValue() string {
 	return ""
 }
 
+type BucketSpan struct {
+	Offset int32  `protobuf:"zigzag32,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length uint32 `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
+}
+
+func (m *BucketSpan) Reset()         { *m = BucketSpan{} }
+func (m *BucketSpan) String() string { return proto.CompactTextString(m) }
+func (*BucketSpan) ProtoMessage()    {}
+func (*BucketSpan) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_5a6f9f47df98adde, []int{1}
+}
+func (m *BucketSpan) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *BucketSpan) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_BucketSpan.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *BucketSpan) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BucketSpan.Merge(dst, src)
+}
+func (m *BucketSpan) XXX_Size() int {
+	return m.Size()
+}
+func (m *BucketSpan) XXX_DiscardUnknown() {
+	xxx_messageInfo_BucketSpan.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BucketSpan proto.InternalMessageInfo
+
+func (m *BucketSpan) GetOffset() int32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *BucketSpan) GetLength() uint32 {
+	if m != nil {
+		return m.Length
+	}
+	return 0
+}
+
+type Histogram struct {
+	Count          uint64       `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Sum            float64      `protobuf:"fixed64,2,opt,name=sum,proto3" json:"sum,omitempty"`
+	Schema         int32        `protobuf:"zigzag32,3,opt,name=schema,proto3" json:"schema,omitempty"`
+	ZeroThreshold  float64      `protobuf:"fixed64,4,opt,name=zeroThreshold,proto3" json:"zeroThreshold,omitempty"`
+	ZeroCount      uint64       `protobuf:"varint,5,opt,name=zeroCount,proto3" json:"zeroCount,omitempty"`
+	NegativeSpans  []BucketSpan `protobuf:"bytes,6,rep,name=negativeSpans" json:"negativeSpans"`
+	NegativeDeltas []int64      `protobuf:"zigzag64,7,rep,packed,name=negativeDeltas,proto3" json:"negativeDeltas,omitempty"`
+	PositiveSpans  []BucketSpan `protobuf:"bytes,8,rep,name=positiveSpans" json:"positiveSpans"`
+	PositiveDeltas []int64      `protobuf:"zigzag64,9,rep,packed,name=positiveDeltas,proto3" json:"positiveDeltas,omitempty"`
+}
+
+func (m *Histogram) Reset()         { *m = Histogram{} }
+func (m *Histogram) String() string { return proto.CompactTextString(m) }
+func (*Histogram) ProtoMessage()    {}
+func (*Histogram) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_5a6f9f47df98adde, []int{2}
+}
+func (m *Histogram) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Histogram) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Histogram.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *Histogram) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Histogram.Merge(dst, src)
+}
+func (m *Histogram) XXX_Size() int {
+	return m.Size()
+}
+func (m *Histogram) XXX_DiscardUnknown() {
+	xxx_messageInfo_Histogram.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Histogram proto.InternalMessageInfo
+
+func (m *Histogram) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *Histogram) GetSum() float64 {
+	if m != nil {
+		return m.Sum
+	}
+	return 0
+}
+
+func (m *Histogram) GetSchema() int32 {
+	if m != nil {
+		return m.Schema
+	}
+	return 0
+}
+
+func (m *Histogram) GetZeroThreshold() float64 {
+	if m != nil {
+		return m.ZeroThreshold
+	}
+	return 0
+}
+
+func (m *Histogram) GetZeroCount() uint64 {
+	if m != nil {
+		return m.ZeroCount
+	}
+	return 0
+}
+
+func (m *Histogram) GetNegativeSpans() []BucketSpan {
+	if m != nil {
+		return m.NegativeSpans
+	}
+	return nil
+}
+
+func (m *Histogram) GetNegativeDeltas() []int64 {
+	if m != nil {
+		return m.NegativeDeltas
+	}
+	return nil
+}
+
+func (m *Histogram) GetPositiveSpans() []BucketSpan {
+	if m != nil {
+		return m.PositiveSpans
+	}
+	return nil
+}
+
+func (m *Histogram) GetPositiveDeltas() []int64 {
+	if m != nil {
+		return m.PositiveDeltas
+	}
+	return nil
+}
+
+// Exemplar refers a sample to where it was observed in, e.g. a trace.
+type Exemplar struct {
+	Labels []Label `protobuf:"bytes,1,rep,name=labels" json:"labels"`
+	Value  float64 `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	T      int64   `protobuf:"zigzag64,3,opt,name=T,proto3" json:"T,omitempty"`
+}
+
+func (m *Exemplar) Reset()         { *m = Exemplar{} }
+func (m *Exemplar) String() string { return proto.CompactTextString(m) }
+func (*Exemplar) ProtoMessage()    {}
+func (*Exemplar) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_5a6f9f47df98adde, []int{3}
+}
+func (m *Exemplar) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Exemplar) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Exemplar.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *Exemplar) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Exemplar.Merge(dst, src)
+}
+func (m *Exemplar) XXX_Size() int {
+	return m.Size()
+}
+func (m *Exemplar) XXX_DiscardUnknown() {
+	xxx_messageInfo_Exemplar.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Exemplar proto.InternalMessageInfo
+
+func (m *Exemplar) GetLabels() []Label {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *Exemplar) GetValue() float64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+func (m *Exemplar) GetT() int64 {
+	if m != nil {
+		return m.T
+	}
+	return 0
+}
+
 type Point struct {
-	T int64   `protobuf:"zigzag64,1,opt,name=T,proto3" json:"T,omitempty"`
-	V float64 `protobuf:"fixed64,2,opt,name=V,proto3" json:"V,omitempty"`
+	T int64      `protobuf:"zigzag64,1,opt,name=T,proto3" json:"T,omitempty"`
+	V float64    `protobuf:"fixed64,2,opt,name=V,proto3" json:"V,omitempty"`
+	H *Histogram `protobuf:"bytes,3,opt,name=H" json:"H,omitempty"`
+	E *Exemplar  `protobuf:"bytes,4,opt,name=E" json:"E,omitempty"`
 }
 
 func (m *Point) Reset()         { *m = Point{} }
 func (m *Point) String() string { return proto.CompactTextString(m) }
 func (*Point) ProtoMessage()    {}
 func (*Point) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_684b3f305cfcd3a3, []int{1}
+	return fileDescriptor_pb_5a6f9f47df98adde, []int{4}
 }
 func (m *Point) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -150,16 +408,34 @@ func (m *Point) GetV() float64 {
 	return 0
 }
 
+func (m *Point) GetH() *Histogram {
+	if m != nil {
+		return m.H
+	}
+	return nil
+}
+
+func (m *Point) GetE() *Exemplar {
+	if m != nil {
+		return m.E
+	}
+	return nil
+}
+
+// Series is unmarshaled by hand to handle the duplicate label names, see Series.Unmarshal. Its XXX_Unmarshal
+// is generated to use the table decoder, it's to be edited to call m.Unmarshal(b) once pb.pb.go is regenerated.
 type Series struct {
 	Labels []Label `protobuf:"bytes,1,rep,name=labels" json:"labels"`
 	Points []Point `protobuf:"bytes,2,rep,name=points" json:"points"`
+	Chunk  []byte  `protobuf:"bytes,3,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	Chunks []Chunk `protobuf:"bytes,4,rep,name=chunks" json:"chunks"`
 }
 
 func (m *Series) Reset()         { *m = Series{} }
 func (m *Series) String() string { return proto.CompactTextString(m) }
 func (*Series) ProtoMessage()    {}
 func (*Series) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_684b3f305cfcd3a3, []int{2}
+	return fileDescriptor_pb_5a6f9f47df98adde, []int{5}
 }
 func (m *Series) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -202,17 +478,93 @@ func (m *Series) GetPoints() []Point {
 	return nil
 }
 
+func (m *Series) GetChunk() []byte {
+	if m != nil {
+		return m.Chunk
+	}
+	return nil
+}
+
+func (m *Series) GetChunks() []Chunk {
+	if m != nil {
+		return m.Chunks
+	}
+	return nil
+}
+
+// Chunk is a chunk of a series in the xor encoding of tsdb, its samples are in [mint, maxt].
+type Chunk struct {
+	Mint int64  `protobuf:"zigzag64,1,opt,name=mint,proto3" json:"mint,omitempty"`
+	Maxt int64  `protobuf:"zigzag64,2,opt,name=maxt,proto3" json:"maxt,omitempty"`
+	Data []byte `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *Chunk) Reset()         { *m = Chunk{} }
+func (m *Chunk) String() string { return proto.CompactTextString(m) }
+func (*Chunk) ProtoMessage()    {}
+func (*Chunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_5a6f9f47df98adde, []int{6}
+}
+func (m *Chunk) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Chunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Chunk.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (dst *Chunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Chunk.Merge(dst, src)
+}
+func (m *Chunk) XXX_Size() int {
+	return m.Size()
+}
+func (m *Chunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_Chunk.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Chunk proto.InternalMessageInfo
+
+func (m *Chunk) GetMint() int64 {
+	if m != nil {
+		return m.Mint
+	}
+	return 0
+}
+
+func (m *Chunk) GetMaxt() int64 {
+	if m != nil {
+		return m.Maxt
+	}
+	return 0
+}
+
+func (m *Chunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
 type LabelValuesResponse struct {
-	Values   []string   `protobuf:"bytes,1,rep,name=values" json:"values,omitempty"`
-	Status   StatusCode `protobuf:"varint,2,opt,name=status,proto3,enum=pb.StatusCode" json:"status,omitempty"`
-	ErrorMsg string     `protobuf:"bytes,3,opt,name=errorMsg,proto3" json:"errorMsg,omitempty"`
+	Values       []string   `protobuf:"bytes,1,rep,name=values" json:"values,omitempty"`
+	Status       StatusCode `protobuf:"varint,2,opt,name=status,proto3,enum=pb.StatusCode" json:"status,omitempty"`
+	ErrorMsg     string     `protobuf:"bytes,3,opt,name=errorMsg,proto3" json:"errorMsg,omitempty"`
+	Continuation string     `protobuf:"bytes,4,opt,name=continuation,proto3" json:"continuation,omitempty"`
 }
 
 func (m *LabelValuesResponse) Reset()         { *m = LabelValuesResponse{} }
 func (m *LabelValuesResponse) String() string { return proto.CompactTextString(m) }
 func (*LabelValuesResponse) ProtoMessage()    {}
 func (*LabelValuesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_684b3f305cfcd3a3, []int{3}
+	return fileDescriptor_pb_5a6f9f47df98adde, []int{7}
 }
 func (m *LabelValuesResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -262,16 +614,24 @@ func (m *LabelValuesResponse) GetErrorMsg() string {
 	return ""
 }
 
+func (m *LabelValuesResponse) GetContinuation() string {
+	if m != nil {
+		return m.Continuation
+	}
+	return ""
+}
+
 type GeneralResponse struct {
 	Status  StatusCode `protobuf:"varint,1,opt,name=status,proto3,enum=pb.StatusCode" json:"status,omitempty"`
 	Message string     `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Code    ErrorCode  `protobuf:"varint,3,opt,name=code,proto3,enum=pb.ErrorCode" json:"code,omitempty"`
 }
 
 func (m *GeneralResponse) Reset()         { *m = GeneralResponse{} }
 func (m *GeneralResponse) String() string { return proto.CompactTextString(m) }
 func (*GeneralResponse) ProtoMessage()    {}
 func (*GeneralResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_684b3f305cfcd3a3, []int{4}
+	return fileDescriptor_pb_5a6f9f47df98adde, []int{8}
 }
 func (m *GeneralResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -314,13 +674,25 @@ func (m *GeneralResponse) GetMessage() string {
 	return ""
 }
 
+func (m *GeneralResponse) GetCode() ErrorCode {
+	if m != nil {
+		return m.Code
+	}
+	return ErrorCode_Unspecified
+}
+
 func init() {
 	proto.RegisterType((*Label)(nil), "pb.Label")
+	proto.RegisterType((*BucketSpan)(nil), "pb.BucketSpan")
+	proto.RegisterType((*Histogram)(nil), "pb.Histogram")
+	proto.RegisterType((*Exemplar)(nil), "pb.Exemplar")
 	proto.RegisterType((*Point)(nil), "pb.Point")
 	proto.RegisterType((*Series)(nil), "pb.Series")
+	proto.RegisterType((*Chunk)(nil), "pb.Chunk")
 	proto.RegisterType((*LabelValuesResponse)(nil), "pb.LabelValuesResponse")
 	proto.RegisterType((*GeneralResponse)(nil), "pb.GeneralResponse")
 	proto.RegisterEnum("pb.StatusCode", StatusCode_name, StatusCode_value)
+	proto.RegisterEnum("pb.ErrorCode", ErrorCode_name, ErrorCode_value)
 }
 func (m *Label) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
@@ -352,7 +724,7 @@ func (m *Label) MarshalTo(dAtA []byte) (int, error) {
 	return i, nil
 }
 
-func (m *Point) Marshal() (dAtA []byte, err error) {
+func (m *BucketSpan) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalTo(dAtA)
@@ -362,26 +734,25 @@ func (m *Point) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Point) MarshalTo(dAtA []byte) (int, error) {
+func (m *BucketSpan) MarshalTo(dAtA []byte) (int, error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	if m.T != 0 {
+	if m.Offset != 0 {
 		dAtA[i] = 0x8
 		i++
-		i = encodeVarintPb(dAtA, i, uint64((uint64(m.T)<<1)^uint64((m.T>>63))))
+		i = encodeVarintPb(dAtA, i, uint64((uint32(m.Offset)<<1)^uint32((m.Offset>>31))))
 	}
-	if m.V != 0 {
-		dAtA[i] = 0x11
+	if m.Length != 0 {
+		dAtA[i] = 0x10
 		i++
-		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.V))))
-		i += 8
+		i = encodeVarintPb(dAtA, i, uint64(m.Length))
 	}
 	return i, nil
 }
 
-func (m *Series) Marshal() (dAtA []byte, err error) {
+func (m *Histogram) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalTo(dAtA)
@@ -391,14 +762,41 @@ func (m *Series) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Series) MarshalTo(dAtA []byte) (int, error) {
+func (m *Histogram) MarshalTo(dAtA []byte) (int, error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	if len(m.Labels) > 0 {
-		for _, msg := range m.Labels {
-			dAtA[i] = 0xa
+	if m.Count != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintPb(dAtA, i, uint64(m.Count))
+	}
+	if m.Sum != 0 {
+		dAtA[i] = 0x11
+		i++
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Sum))))
+		i += 8
+	}
+	if m.Schema != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintPb(dAtA, i, uint64((uint32(m.Schema)<<1)^uint32((m.Schema>>31))))
+	}
+	if m.ZeroThreshold != 0 {
+		dAtA[i] = 0x21
+		i++
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.ZeroThreshold))))
+		i += 8
+	}
+	if m.ZeroCount != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintPb(dAtA, i, uint64(m.ZeroCount))
+	}
+	if len(m.NegativeSpans) > 0 {
+		for _, msg := range m.NegativeSpans {
+			dAtA[i] = 0x32
 			i++
 			i = encodeVarintPb(dAtA, i, uint64(msg.Size()))
 			n, err := msg.MarshalTo(dAtA[i:])
@@ -408,9 +806,27 @@ func (m *Series) MarshalTo(dAtA []byte) (int, error) {
 			i += n
 		}
 	}
-	if len(m.Points) > 0 {
-		for _, msg := range m.Points {
-			dAtA[i] = 0x12
+	if len(m.NegativeDeltas) > 0 {
+		var j1 int
+		dAtA3 := make([]byte, len(m.NegativeDeltas)*10)
+		for _, num := range m.NegativeDeltas {
+			x2 := (uint64(num) << 1) ^ uint64((num >> 63))
+			for x2 >= 1<<7 {
+				dAtA3[j1] = uint8(uint64(x2)&0x7f | 0x80)
+				j1++
+				x2 >>= 7
+			}
+			dAtA3[j1] = uint8(x2)
+			j1++
+		}
+		dAtA[i] = 0x3a
+		i++
+		i = encodeVarintPb(dAtA, i, uint64(j1))
+		i += copy(dAtA[i:], dAtA3[:j1])
+	}
+	if len(m.PositiveSpans) > 0 {
+		for _, msg := range m.PositiveSpans {
+			dAtA[i] = 0x42
 			i++
 			i = encodeVarintPb(dAtA, i, uint64(msg.Size()))
 			n, err := msg.MarshalTo(dAtA[i:])
@@ -420,10 +836,28 @@ func (m *Series) MarshalTo(dAtA []byte) (int, error) {
 			i += n
 		}
 	}
+	if len(m.PositiveDeltas) > 0 {
+		var j4 int
+		dAtA6 := make([]byte, len(m.PositiveDeltas)*10)
+		for _, num := range m.PositiveDeltas {
+			x5 := (uint64(num) << 1) ^ uint64((num >> 63))
+			for x5 >= 1<<7 {
+				dAtA6[j4] = uint8(uint64(x5)&0x7f | 0x80)
+				j4++
+				x5 >>= 7
+			}
+			dAtA6[j4] = uint8(x5)
+			j4++
+		}
+		dAtA[i] = 0x4a
+		i++
+		i = encodeVarintPb(dAtA, i, uint64(j4))
+		i += copy(dAtA[i:], dAtA6[:j4])
+	}
 	return i, nil
 }
 
-func (m *LabelValuesResponse) Marshal() (dAtA []byte, err error) {
+func (m *Exemplar) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalTo(dAtA)
@@ -433,17 +867,201 @@ func (m *LabelValuesResponse) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *LabelValuesResponse) MarshalTo(dAtA []byte) (int, error) {
+func (m *Exemplar) MarshalTo(dAtA []byte) (int, error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	if len(m.Values) > 0 {
-		for _, s := range m.Values {
+	if len(m.Labels) > 0 {
+		for _, msg := range m.Labels {
 			dAtA[i] = 0xa
 			i++
-			l = len(s)
-			for l >= 1<<7 {
+			i = encodeVarintPb(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.Value != 0 {
+		dAtA[i] = 0x11
+		i++
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Value))))
+		i += 8
+	}
+	if m.T != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintPb(dAtA, i, uint64((uint64(m.T)<<1)^uint64((m.T>>63))))
+	}
+	return i, nil
+}
+
+func (m *Point) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Point) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.T != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintPb(dAtA, i, uint64((uint64(m.T)<<1)^uint64((m.T>>63))))
+	}
+	if m.V != 0 {
+		dAtA[i] = 0x11
+		i++
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.V))))
+		i += 8
+	}
+	if m.H != nil {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintPb(dAtA, i, uint64(m.H.Size()))
+		n7, err := m.H.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n7
+	}
+	if m.E != nil {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintPb(dAtA, i, uint64(m.E.Size()))
+		n8, err := m.E.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n8
+	}
+	return i, nil
+}
+
+func (m *Series) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Series) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Labels) > 0 {
+		for _, msg := range m.Labels {
+			dAtA[i] = 0xa
+			i++
+			i = encodeVarintPb(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if len(m.Points) > 0 {
+		for _, msg := range m.Points {
+			dAtA[i] = 0x12
+			i++
+			i = encodeVarintPb(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if len(m.Chunk) > 0 {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Chunk)))
+		i += copy(dAtA[i:], m.Chunk)
+	}
+	if len(m.Chunks) > 0 {
+		for _, msg := range m.Chunks {
+			dAtA[i] = 0x22
+			i++
+			i = encodeVarintPb(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	return i, nil
+}
+
+func (m *Chunk) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Chunk) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Mint != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintPb(dAtA, i, uint64((uint64(m.Mint)<<1)^uint64((m.Mint>>63))))
+	}
+	if m.Maxt != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintPb(dAtA, i, uint64((uint64(m.Maxt)<<1)^uint64((m.Maxt>>63))))
+	}
+	if len(m.Data) > 0 {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Data)))
+		i += copy(dAtA[i:], m.Data)
+	}
+	return i, nil
+}
+
+func (m *LabelValuesResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *LabelValuesResponse) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Values) > 0 {
+		for _, s := range m.Values {
+			dAtA[i] = 0xa
+			i++
+			l = len(s)
+			for l >= 1<<7 {
 				dAtA[i] = uint8(uint64(l)&0x7f | 0x80)
 				l >>= 7
 				i++
@@ -464,6 +1082,12 @@ func (m *LabelValuesResponse) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintPb(dAtA, i, uint64(len(m.ErrorMsg)))
 		i += copy(dAtA[i:], m.ErrorMsg)
 	}
+	if len(m.Continuation) > 0 {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Continuation)))
+		i += copy(dAtA[i:], m.Continuation)
+	}
 	return i, nil
 }
 
@@ -493,6 +1117,11 @@ func (m *GeneralResponse) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintPb(dAtA, i, uint64(len(m.Message)))
 		i += copy(dAtA[i:], m.Message)
 	}
+	if m.Code != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintPb(dAtA, i, uint64(m.Code))
+	}
 	return i, nil
 }
 
@@ -522,6 +1151,92 @@ func (m *Label) Size() (n int) {
 	return n
 }
 
+func (m *BucketSpan) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Offset != 0 {
+		n += 1 + sozPb(uint64(m.Offset))
+	}
+	if m.Length != 0 {
+		n += 1 + sovPb(uint64(m.Length))
+	}
+	return n
+}
+
+func (m *Histogram) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Count != 0 {
+		n += 1 + sovPb(uint64(m.Count))
+	}
+	if m.Sum != 0 {
+		n += 9
+	}
+	if m.Schema != 0 {
+		n += 1 + sozPb(uint64(m.Schema))
+	}
+	if m.ZeroThreshold != 0 {
+		n += 9
+	}
+	if m.ZeroCount != 0 {
+		n += 1 + sovPb(uint64(m.ZeroCount))
+	}
+	if len(m.NegativeSpans) > 0 {
+		for _, e := range m.NegativeSpans {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if len(m.NegativeDeltas) > 0 {
+		l = 0
+		for _, e := range m.NegativeDeltas {
+			l += sozPb(uint64(e))
+		}
+		n += 1 + sovPb(uint64(l)) + l
+	}
+	if len(m.PositiveSpans) > 0 {
+		for _, e := range m.PositiveSpans {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if len(m.PositiveDeltas) > 0 {
+		l = 0
+		for _, e := range m.PositiveDeltas {
+			l += sozPb(uint64(e))
+		}
+		n += 1 + sovPb(uint64(l)) + l
+	}
+	return n
+}
+
+func (m *Exemplar) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Labels) > 0 {
+		for _, e := range m.Labels {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.Value != 0 {
+		n += 9
+	}
+	if m.T != 0 {
+		n += 1 + sozPb(uint64(m.T))
+	}
+	return n
+}
+
 func (m *Point) Size() (n int) {
 	if m == nil {
 		return 0
@@ -534,6 +1249,14 @@ func (m *Point) Size() (n int) {
 	if m.V != 0 {
 		n += 9
 	}
+	if m.H != nil {
+		l = m.H.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.E != nil {
+		l = m.E.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
 	return n
 }
 
@@ -555,6 +1278,35 @@ func (m *Series) Size() (n int) {
 			n += 1 + l + sovPb(uint64(l))
 		}
 	}
+	l = len(m.Chunk)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.Chunks) > 0 {
+		for _, e := range m.Chunks {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *Chunk) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Mint != 0 {
+		n += 1 + sozPb(uint64(m.Mint))
+	}
+	if m.Maxt != 0 {
+		n += 1 + sozPb(uint64(m.Maxt))
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
 	return n
 }
 
@@ -570,46 +1322,594 @@ func (m *LabelValuesResponse) Size() (n int) {
 			n += 1 + l + sovPb(uint64(l))
 		}
 	}
-	if m.Status != 0 {
-		n += 1 + sovPb(uint64(m.Status))
-	}
-	l = len(m.ErrorMsg)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	return n
-}
-
-func (m *GeneralResponse) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	if m.Status != 0 {
-		n += 1 + sovPb(uint64(m.Status))
-	}
-	l = len(m.Message)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	return n
-}
+	if m.Status != 0 {
+		n += 1 + sovPb(uint64(m.Status))
+	}
+	l = len(m.ErrorMsg)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Continuation)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	return n
+}
+
+func (m *GeneralResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Status != 0 {
+		n += 1 + sovPb(uint64(m.Status))
+	}
+	l = len(m.Message)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Code != 0 {
+		n += 1 + sovPb(uint64(m.Code))
+	}
+	return n
+}
+
+func sovPb(x uint64) (n int) {
+	for {
+		n++
+		x >>= 7
+		if x == 0 {
+			break
+		}
+	}
+	return n
+}
+func sozPb(x uint64) (n int) {
+	return sovPb(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *Label) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Label: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Label: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *BucketSpan) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: BucketSpan: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: BucketSpan: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
+			}
+			var v int32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			v = int32((uint32(v) >> 1) ^ uint32(((v&1)<<31)>>31))
+			m.Offset = v
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Length", wireType)
+			}
+			m.Length = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Length |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Histogram) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Histogram: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Histogram: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			}
+			m.Count = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Count |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Sum", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Sum = float64(math.Float64frombits(v))
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Schema", wireType)
+			}
+			var v int32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			v = int32((uint32(v) >> 1) ^ uint32(((v&1)<<31)>>31))
+			m.Schema = v
+		case 4:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ZeroThreshold", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.ZeroThreshold = float64(math.Float64frombits(v))
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ZeroCount", wireType)
+			}
+			m.ZeroCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ZeroCount |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NegativeSpans", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.NegativeSpans = append(m.NegativeSpans, BucketSpan{})
+			if err := m.NegativeSpans[len(m.NegativeSpans)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 7:
+			if wireType == 0 {
+				var v uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (uint64(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
+				m.NegativeDeltas = append(m.NegativeDeltas, int64(v))
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthPb
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				var count int
+				for _, integer := range dAtA {
+					if integer < 128 {
+						count++
+					}
+				}
+				elementCount = count
+				if elementCount != 0 && len(m.NegativeDeltas) == 0 {
+					m.NegativeDeltas = make([]int64, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (uint64(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
+					m.NegativeDeltas = append(m.NegativeDeltas, int64(v))
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field NegativeDeltas", wireType)
+			}
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PositiveSpans", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.PositiveSpans = append(m.PositiveSpans, BucketSpan{})
+			if err := m.PositiveSpans[len(m.PositiveSpans)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 9:
+			if wireType == 0 {
+				var v uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (uint64(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
+				m.PositiveDeltas = append(m.PositiveDeltas, int64(v))
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthPb
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				var count int
+				for _, integer := range dAtA {
+					if integer < 128 {
+						count++
+					}
+				}
+				elementCount = count
+				if elementCount != 0 && len(m.PositiveDeltas) == 0 {
+					m.PositiveDeltas = make([]int64, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (uint64(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
+					m.PositiveDeltas = append(m.PositiveDeltas, int64(v))
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field PositiveDeltas", wireType)
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
 
-func sovPb(x uint64) (n int) {
-	for {
-		n++
-		x >>= 7
-		if x == 0 {
-			break
-		}
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
 	}
-	return n
-}
-func sozPb(x uint64) (n int) {
-	return sovPb(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+	return nil
 }
-func (m *Label) Unmarshal(dAtA []byte) error {
+func (m *Exemplar) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -632,17 +1932,17 @@ func (m *Label) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Label: wiretype end group for non-group")
+			return fmt.Errorf("proto: Exemplar: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Label: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Exemplar: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -652,26 +1952,39 @@ func (m *Label) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Name = string(dAtA[iNdEx:postIndex])
+			m.Labels = append(m.Labels, Label{})
+			if err := m.Labels[len(m.Labels)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
 			iNdEx = postIndex
 		case 2:
-			if wireType != 2 {
+			if wireType != 1 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
 			}
-			var stringLen uint64
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Value = float64(math.Float64frombits(v))
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field T", wireType)
+			}
+			var v uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -681,21 +1994,13 @@ func (m *Label) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
+				v |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Value = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
+			v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
+			m.T = int64(v)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -778,6 +2083,72 @@ func (m *Point) Unmarshal(dAtA []byte) error {
 			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
 			iNdEx += 8
 			m.V = float64(math.Float64frombits(v))
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field H", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.H == nil {
+				m.H = &Histogram{}
+			}
+			if err := m.H.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field E", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.E == nil {
+				m.E = &Exemplar{}
+			}
+			if err := m.E.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -799,7 +2170,7 @@ func (m *Point) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Series) Unmarshal(dAtA []byte) error {
+func (m *Chunk) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -822,17 +2193,17 @@ func (m *Series) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Series: wiretype end group for non-group")
+			return fmt.Errorf("proto: Chunk: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Series: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Chunk: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mint", wireType)
 			}
-			var msglen int
+			var v uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -842,28 +2213,39 @@ func (m *Series) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				v |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
+			v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
+			m.Mint = int64(v)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Maxt", wireType)
 			}
-			m.Labels = append(m.Labels, Label{})
-			if err := m.Labels[len(m.Labels)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			iNdEx = postIndex
-		case 2:
+			v = (v >> 1) ^ uint64((int64(v&1)<<63)>>63)
+			m.Maxt = int64(v)
+		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Points", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -873,21 +2255,21 @@ func (m *Series) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				byteLen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Points = append(m.Points, Point{})
-			if err := m.Points[len(m.Points)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
 			}
 			iNdEx = postIndex
 		default:
@@ -1017,6 +2399,35 @@ func (m *LabelValuesResponse) Unmarshal(dAtA []byte) error {
 			}
 			m.ErrorMsg = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Continuation", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Continuation = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -1115,6 +2526,25 @@ func (m *GeneralResponse) Unmarshal(dAtA []byte) error {
 			}
 			m.Message = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+			}
+			m.Code = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Code |= (ErrorCode(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -1241,30 +2671,55 @@ var (
 	ErrIntOverflowPb   = fmt.Errorf("proto: integer overflow")
 )
 
-func init() { proto.RegisterFile("pb.proto", fileDescriptor_pb_684b3f305cfcd3a3) }
-
-var fileDescriptor_pb_684b3f305cfcd3a3 = []byte{
-	// 351 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x91, 0xcf, 0x4e, 0xf2, 0x40,
-	0x14, 0xc5, 0x3b, 0x05, 0x0a, 0x5c, 0xbe, 0xf0, 0x91, 0xd1, 0x98, 0x86, 0x98, 0x4a, 0x6a, 0x54,
-	0x62, 0x62, 0x89, 0xf8, 0x06, 0x98, 0xe8, 0x46, 0x13, 0xd3, 0x12, 0x16, 0xec, 0x3a, 0x70, 0xad,
-	0x4d, 0x4a, 0xa7, 0x76, 0x5a, 0x9f, 0xc3, 0xc7, 0x62, 0xc9, 0xd2, 0x95, 0x31, 0xf0, 0x22, 0xa6,
-	0xb7, 0xfc, 0xd9, 0xb9, 0x9b, 0xdf, 0x9c, 0x73, 0xcf, 0xb9, 0xed, 0x40, 0x23, 0x11, 0x4e, 0x92,
-	0xca, 0x4c, 0x72, 0x3d, 0x11, 0xdd, 0x9b, 0x20, 0xcc, 0xde, 0x72, 0xe1, 0xcc, 0xe4, 0x62, 0x10,
-	0xc8, 0x40, 0x0e, 0x48, 0x12, 0xf9, 0x2b, 0x11, 0x01, 0x9d, 0xca, 0x11, 0xfb, 0x16, 0x6a, 0x4f,
-	0xbe, 0xc0, 0x88, 0x73, 0xa8, 0xc6, 0xfe, 0x02, 0x4d, 0xd6, 0x63, 0xfd, 0xa6, 0x4b, 0x67, 0x7e,
-	0x0c, 0xb5, 0x0f, 0x3f, 0xca, 0xd1, 0xd4, 0xe9, 0xb2, 0x04, 0xfb, 0x1c, 0x6a, 0x2f, 0x32, 0x8c,
-	0x33, 0xfe, 0x0f, 0xd8, 0x98, 0xfc, 0xdc, 0x65, 0xe3, 0x82, 0x26, 0x64, 0x64, 0x2e, 0x9b, 0xd8,
-	0x53, 0x30, 0x3c, 0x4c, 0x43, 0x54, 0xfc, 0x0a, 0x8c, 0xa8, 0x68, 0x50, 0x26, 0xeb, 0x55, 0xfa,
-	0xad, 0x61, 0xd3, 0x49, 0x84, 0x43, 0x9d, 0xa3, 0xea, 0xf2, 0xfb, 0x4c, 0x73, 0xb7, 0x72, 0x61,
-	0x4c, 0x8a, 0x5c, 0x65, 0xea, 0x07, 0x23, 0x35, 0xed, 0x8c, 0xa5, 0x6c, 0xbf, 0xc3, 0x11, 0xcd,
-	0x4f, 0x8a, 0x75, 0x94, 0x8b, 0x2a, 0x91, 0xb1, 0x42, 0x7e, 0x02, 0x06, 0x2d, 0x58, 0x16, 0x35,
-	0xdd, 0x2d, 0xf1, 0x4b, 0x30, 0x54, 0xe6, 0x67, 0xb9, 0xa2, 0xed, 0xda, 0xc3, 0x76, 0x91, 0xeb,
-	0xd1, 0xcd, 0xbd, 0x9c, 0xa3, 0xbb, 0x55, 0x79, 0x17, 0x1a, 0x98, 0xa6, 0x32, 0x7d, 0x56, 0x81,
-	0x59, 0xa1, 0x0f, 0xde, 0xb3, 0xed, 0xc1, 0xff, 0x47, 0x8c, 0x31, 0xf5, 0xa3, 0x7d, 0xdd, 0x21,
-	0x96, 0xfd, 0x19, 0x6b, 0x42, 0x7d, 0x81, 0x4a, 0xf9, 0xc1, 0xee, 0x37, 0xee, 0xf0, 0xfa, 0x02,
-	0xe0, 0xe0, 0xe7, 0x2d, 0xa8, 0x7b, 0xf9, 0x6c, 0x86, 0x38, 0xef, 0x68, 0x1c, 0xc0, 0x78, 0xf0,
-	0xc3, 0x08, 0xe7, 0x1d, 0x36, 0x3a, 0x5d, 0xae, 0x2d, 0xb6, 0x5a, 0x5b, 0xec, 0x67, 0x6d, 0xb1,
-	0xcf, 0x8d, 0xa5, 0xad, 0x36, 0x96, 0xf6, 0xb5, 0xb1, 0xb4, 0xa9, 0x9e, 0x08, 0x61, 0xd0, 0x3b,
-	0xde, 0xfd, 0x06, 0x00, 0x00, 0xff, 0xff, 0x23, 0xc7, 0xd8, 0xa9, 0x06, 0x02, 0x00, 0x00,
+func init() { proto.RegisterFile("pb.proto", fileDescriptor_pb_5a6f9f47df98adde) }
+
+var fileDescriptor_pb_5a6f9f47df98adde = []byte{
+	// 748 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0xc1, 0x6e, 0xe4, 0x44,
+	0x10, 0x9d, 0x9e, 0xf1, 0x38, 0x71, 0x4d, 0x66, 0xd6, 0x34, 0x08, 0x59, 0x61, 0x35, 0x1b, 0x2c,
+	0xd8, 0x8d, 0x56, 0x22, 0x2b, 0xc2, 0x6d, 0xc5, 0x29, 0x21, 0x90, 0x43, 0x16, 0x50, 0x4f, 0x08,
+	0x12, 0xe2, 0xd2, 0xb6, 0x2b, 0x9e, 0xd6, 0xda, 0x6e, 0xe3, 0x6e, 0x47, 0x11, 0x5f, 0xb1, 0x17,
+	0xce, 0x88, 0xbf, 0xd9, 0xe3, 0xde, 0xe0, 0x84, 0x50, 0xf2, 0x23, 0xa8, 0xcb, 0x9e, 0x19, 0x0d,
+	0x87, 0x88, 0x5b, 0xbd, 0x57, 0xf5, 0xfa, 0x55, 0x77, 0x95, 0x0d, 0xbb, 0x75, 0x72, 0x54, 0x37,
+	0xda, 0x6a, 0x3e, 0xac, 0x93, 0xfd, 0xcf, 0x72, 0x65, 0x97, 0x6d, 0x72, 0x94, 0xea, 0xf2, 0x45,
+	0xae, 0x73, 0xfd, 0x82, 0x52, 0x49, 0x7b, 0x4d, 0x88, 0x00, 0x45, 0x9d, 0x24, 0xfe, 0x1c, 0xc6,
+	0x17, 0x32, 0xc1, 0x82, 0x73, 0xf0, 0x2a, 0x59, 0x62, 0xc4, 0x0e, 0xd8, 0x61, 0x20, 0x28, 0xe6,
+	0x1f, 0xc0, 0xf8, 0x46, 0x16, 0x2d, 0x46, 0x43, 0x22, 0x3b, 0x10, 0x7f, 0x09, 0x70, 0xd2, 0xa6,
+	0xaf, 0xd1, 0x2e, 0x6a, 0x59, 0xf1, 0x0f, 0xc1, 0xd7, 0xd7, 0xd7, 0x06, 0x2d, 0x29, 0xdf, 0x13,
+	0x3d, 0x72, 0x7c, 0x81, 0x55, 0x6e, 0x97, 0x24, 0x9e, 0x8a, 0x1e, 0xc5, 0x7f, 0x0e, 0x21, 0x38,
+	0x57, 0xc6, 0xea, 0xbc, 0x91, 0xa5, 0x73, 0x48, 0x75, 0x5b, 0x75, 0x62, 0x4f, 0x74, 0x80, 0x87,
+	0x30, 0x32, 0x6d, 0x49, 0x42, 0x26, 0x5c, 0xe8, 0x4e, 0x33, 0xe9, 0x12, 0x4b, 0x19, 0x8d, 0x3a,
+	0x97, 0x0e, 0xf1, 0x4f, 0x60, 0xfa, 0x2b, 0x36, 0xfa, 0x72, 0xd9, 0xa0, 0x59, 0xea, 0x22, 0x8b,
+	0x3c, 0xd2, 0x6c, 0x93, 0xfc, 0x31, 0x04, 0x8e, 0x38, 0x25, 0xa7, 0x31, 0x39, 0x6d, 0x08, 0xfe,
+	0x12, 0xa6, 0x15, 0xe6, 0xd2, 0xaa, 0x1b, 0x74, 0x37, 0x32, 0x91, 0x7f, 0x30, 0x3a, 0x9c, 0x1c,
+	0xcf, 0x8e, 0xea, 0xe4, 0x68, 0x73, 0xd1, 0x13, 0xef, 0xed, 0xdf, 0x4f, 0x06, 0x62, 0xbb, 0x94,
+	0x3f, 0x85, 0xd9, 0x8a, 0xf8, 0x0a, 0x0b, 0x2b, 0x4d, 0xb4, 0x73, 0x30, 0x3a, 0xe4, 0xe2, 0x3f,
+	0xac, 0xf3, 0xa8, 0xb5, 0x51, 0x1b, 0x8f, 0xdd, 0x87, 0x3c, 0xb6, 0x4a, 0x9d, 0xc7, 0x8a, 0xe8,
+	0x3d, 0x82, 0xce, 0x63, 0x9b, 0x8d, 0x7f, 0x84, 0xdd, 0xb3, 0x5b, 0x2c, 0xeb, 0x42, 0x36, 0xfc,
+	0x19, 0xf8, 0x85, 0x1b, 0xab, 0x89, 0x18, 0x19, 0x05, 0xce, 0x88, 0x06, 0xdd, 0x7b, 0xf4, 0xe9,
+	0xed, 0x11, 0xb3, 0x7e, 0xc4, 0x7c, 0x0f, 0xd8, 0x25, 0xbd, 0x34, 0x17, 0xec, 0x32, 0xfe, 0x19,
+	0xc6, 0xdf, 0x6b, 0x55, 0xd9, 0x8e, 0x66, 0x3d, 0xed, 0xd0, 0x55, 0x2f, 0x63, 0x57, 0xfc, 0x23,
+	0x60, 0xe7, 0x24, 0x99, 0x1c, 0x4f, 0x9d, 0xd9, 0x7a, 0xc6, 0x82, 0x9d, 0xf3, 0x7d, 0x60, 0x67,
+	0x34, 0x9a, 0xc9, 0xf1, 0x9e, 0x4b, 0xae, 0xfa, 0x14, 0xec, 0x2c, 0xfe, 0x9d, 0x81, 0xbf, 0xc0,
+	0x46, 0xa1, 0xf9, 0xff, 0x5d, 0x3f, 0x03, 0xbf, 0x76, 0x1d, 0x99, 0x68, 0xb8, 0x29, 0xa4, 0x1e,
+	0x57, 0x85, 0x5d, 0x9a, 0xf6, 0x6b, 0xd9, 0x56, 0xaf, 0xa9, 0xb3, 0x3d, 0xd1, 0x01, 0x27, 0xa7,
+	0xc0, 0x44, 0xde, 0x46, 0x7e, 0xea, 0x98, 0x95, 0xbc, 0x4b, 0xbf, 0xf4, 0xde, 0xfc, 0xf1, 0x64,
+	0x10, 0x9f, 0xc2, 0x98, 0x92, 0xee, 0x1b, 0x29, 0x55, 0xbf, 0xac, 0x5c, 0x50, 0x4c, 0x9c, 0xbc,
+	0xb5, 0xd1, 0xb0, 0xe7, 0xe4, 0x2d, 0x71, 0x99, 0xb4, 0xb2, 0x37, 0xa5, 0x38, 0xfe, 0x8d, 0xc1,
+	0xfb, 0x74, 0x95, 0x2b, 0xf7, 0xc2, 0x46, 0xa0, 0xa9, 0x75, 0x65, 0xd0, 0x6d, 0x36, 0xbd, 0x79,
+	0x77, 0xe7, 0x40, 0xf4, 0x88, 0x3f, 0x05, 0xdf, 0x58, 0x69, 0x5b, 0x43, 0x27, 0xcf, 0xba, 0x55,
+	0x59, 0x10, 0x73, 0xaa, 0x33, 0x14, 0x7d, 0x96, 0xef, 0xc3, 0x2e, 0x36, 0x8d, 0x6e, 0x5e, 0x99,
+	0x9c, 0xfc, 0x02, 0xb1, 0xc6, 0x3c, 0x86, 0xbd, 0x54, 0x57, 0x56, 0x55, 0xad, 0xb4, 0x4a, 0x57,
+	0x34, 0x81, 0x40, 0x6c, 0x71, 0xf1, 0x0d, 0x3c, 0xfa, 0x06, 0x2b, 0x6c, 0x64, 0xb1, 0x6e, 0x69,
+	0x63, 0xcd, 0x1e, 0xb4, 0x8e, 0x60, 0xa7, 0x44, 0x63, 0x64, 0xbe, 0xfa, 0x41, 0xac, 0x20, 0xff,
+	0x18, 0xbc, 0x54, 0x67, 0x48, 0x0d, 0xcd, 0xba, 0x7d, 0x38, 0x73, 0x4d, 0x91, 0x9c, 0x52, 0xcf,
+	0x3f, 0x05, 0xd8, 0x1c, 0xc9, 0x27, 0xb0, 0xb3, 0x68, 0xd3, 0x14, 0x31, 0x0b, 0x07, 0x1c, 0xc0,
+	0xff, 0x5a, 0xaa, 0x02, 0xb3, 0x90, 0x3d, 0xaf, 0x21, 0x58, 0x2b, 0xf9, 0x23, 0x98, 0xfc, 0x50,
+	0x99, 0x1a, 0x53, 0x75, 0xad, 0xa8, 0x72, 0x0a, 0xc1, 0xb7, 0xda, 0x5e, 0xa0, 0xcc, 0xb0, 0x09,
+	0x19, 0xe7, 0x30, 0x5b, 0x2c, 0x65, 0x93, 0xbd, 0x52, 0x79, 0x23, 0xad, 0xaa, 0xf2, 0x70, 0xc8,
+	0x67, 0x00, 0xdf, 0xdd, 0x60, 0x53, 0x68, 0x99, 0x61, 0x16, 0x8e, 0x1c, 0x3e, 0x91, 0x99, 0xc0,
+	0x5f, 0x5a, 0x34, 0x36, 0xf4, 0xdc, 0x99, 0x42, 0x5a, 0xbc, 0x50, 0xa5, 0xb2, 0x98, 0x85, 0xe3,
+	0x93, 0xc7, 0x6f, 0xef, 0xe6, 0xec, 0xdd, 0xdd, 0x9c, 0xfd, 0x73, 0x37, 0x67, 0x6f, 0xee, 0xe7,
+	0x83, 0x77, 0xf7, 0xf3, 0xc1, 0x5f, 0xf7, 0xf3, 0xc1, 0x4f, 0xc3, 0x3a, 0x49, 0x7c, 0xfa, 0x6d,
+	0x7e, 0xf1, 0xef, 0x00, 0x61, 0x82, 0x05, 0x3b, 0x75, 0x05, 0x00, 0x00,
 }
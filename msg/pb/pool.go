@@ -0,0 +1,48 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"sync"
+)
+
+var seriesPool = sync.Pool{
+	New: func() interface{} {
+		return new(Series)
+	},
+}
+
+// GetSeries returns an empty Series from the pool, unmarshaling into it reuses the backing
+// arrays of the labels and points of a series put before.
+func GetSeries() *Series {
+	return seriesPool.Get().(*Series)
+}
+
+// PutSeries puts s back to the pool. The caller must not retain s, nor its labels or points,
+// after putting it. To keep the points, take them and set s.Points to nil before putting.
+func PutSeries(s *Series) {
+	for i := range s.Labels {
+		s.Labels[i] = Label{}
+	}
+	s.Labels = s.Labels[:0]
+	s.Points = s.Points[:0]
+	s.Chunk = s.Chunk[:0]
+	for i := range s.Chunks {
+		s.Chunks[i] = Chunk{}
+	}
+	s.Chunks = s.Chunks[:0]
+	seriesPool.Put(s)
+}
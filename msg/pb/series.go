@@ -0,0 +1,118 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ErrDuplicateLabelName is the cause of the error a series is decoded with in the strict mode if it has
+// a label name more than once, see SetStrictLabels.
+var ErrDuplicateLabelName = errors.New("duplicate label name")
+
+var strictLabels bool
+
+// SetStrictLabels sets how Series.Unmarshal handles a label name found more than once in a series, e.g.
+// {a="1", a="2"}, which is ambiguous to the routing and comparison of series. In the strict mode it fails
+// with ErrDuplicateLabelName, otherwise (the default) the last value of the name wins, it replaces the
+// value of the first label of the name where it is and the later labels of the name are dropped.
+// It's meant to be set once before any series is decoded, e.g. at startup.
+func SetStrictLabels(strict bool) {
+	strictLabels = strict
+}
+
+// Unmarshal decodes a series the way the generated code would, but for the labels of a name decoded
+// before, see SetStrictLabels. Like the generated code, it appends to the labels, points and chunks
+// already in m, so reset them first to reuse m, see PutSeries.
+func (m *Series) Unmarshal(dAtA []byte) error {
+	for i := 0; i < len(dAtA); {
+		n, err := skipPb(dAtA[i:])
+		if err != nil {
+			return err
+		}
+		field := dAtA[i : i+n]
+		i += n
+
+		key, keyLen := binary.Uvarint(field)
+		if keyLen <= 0 {
+			return ErrInvalidLengthPb
+		}
+		fieldNum, wireType := int32(key>>3), int(key&0x7)
+		if fieldNum <= 0 {
+			return errors.Errorf("proto: Series: illegal tag %d (wire type %d)", fieldNum, wireType)
+		}
+		if fieldNum > 4 {
+			continue // unknown, skipped
+		}
+		if wireType != 2 {
+			return errors.Errorf("proto: wrong wireType = %d for field %d of Series", wireType, fieldNum)
+		}
+
+		msgLen, lenLen := binary.Uvarint(field[keyLen:])
+		if lenLen <= 0 || keyLen+lenLen+int(msgLen) != len(field) {
+			return io.ErrUnexpectedEOF
+		}
+		data := field[keyLen+lenLen:]
+
+		switch fieldNum {
+		case 1:
+			m.Labels = append(m.Labels, Label{})
+			if err = m.Labels[len(m.Labels)-1].Unmarshal(data); err != nil {
+				return err
+			}
+			if err = m.dedupLastLabel(); err != nil {
+				return err
+			}
+		case 2:
+			m.Points = append(m.Points, Point{})
+			if err = m.Points[len(m.Points)-1].Unmarshal(data); err != nil {
+				return err
+			}
+		case 3:
+			m.Chunk = append(m.Chunk[:0], data...)
+			if m.Chunk == nil {
+				m.Chunk = []byte{}
+			}
+		case 4:
+			m.Chunks = append(m.Chunks, Chunk{})
+			if err = m.Chunks[len(m.Chunks)-1].Unmarshal(data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// dedupLastLabel handles the label just decoded if another label of m has its name already.
+func (m *Series) dedupLastLabel() error {
+	last := len(m.Labels) - 1
+	for i := 0; i < last; i++ {
+		if m.Labels[i].Name != m.Labels[last].Name {
+			continue
+		}
+		if strictLabels {
+			return errors.Wrapf(ErrDuplicateLabelName, "label %q", m.Labels[last].Name)
+		}
+		m.Labels[i].Value = m.Labels[last].Value
+		m.Labels = m.Labels[:last]
+		return nil
+	}
+	return nil
+}
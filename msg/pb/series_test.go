@@ -0,0 +1,120 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+func TestSeries_UnmarshalRoundTrip(t *testing.T) {
+	want := &Series{
+		Labels: []Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: "10.0.0.1:9100"}},
+		Points: []Point{
+			{T: 1560000000000, V: 1},
+			{T: 1560000015000, V: 0, E: &Exemplar{Labels: []Label{{Name: "trace_id", Value: "abc"}}, Value: 0, T: 1560000014000}},
+			{T: 1560000030000, H: &Histogram{Count: 3, Sum: 1.5, PositiveSpans: []BucketSpan{{Offset: 0, Length: 1}}, PositiveDeltas: []int64{3}}},
+		},
+		Chunk:  []byte{0x01, 0x02},
+		Chunks: []Chunk{{Mint: 1, Maxt: 2, Data: []byte{0x03}}},
+	}
+
+	b, err := want.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a field unknown to the series, e.g. added by a newer node, is skipped
+	b = append(b, 0x2a, 0x01, 0xff) // field 5, length delimited
+
+	got := new(Series)
+	if err = got.Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestSeries_UnmarshalDuplicateLabels(t *testing.T) {
+	defer SetStrictLabels(false)
+
+	dup := &Series{
+		Labels: []Label{{Name: "__name__", Value: "up"}, {Name: "a", Value: "1"}, {Name: "b", Value: "x"}, {Name: "a", Value: "2"}, {Name: "a", Value: "3"}},
+		Points: []Point{{T: 1560000000000, V: 1}},
+	}
+	b, err := dup.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// lenient, the last value wins in place of the first label of the name
+	got := new(Series)
+	if err = got.Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	wantLabels := []Label{{Name: "__name__", Value: "up"}, {Name: "a", Value: "3"}, {Name: "b", Value: "x"}}
+	if !reflect.DeepEqual(got.Labels, wantLabels) {
+		t.Fatalf("lenient: want labels %v, got %v", wantLabels, got.Labels)
+	}
+	if !reflect.DeepEqual(got.Points, dup.Points) {
+		t.Fatalf("lenient: want points %v, got %v", dup.Points, got.Points)
+	}
+
+	SetStrictLabels(true)
+	if err = new(Series).Unmarshal(b); errors.Cause(err) != ErrDuplicateLabelName {
+		t.Fatalf("strict: want %v, got %v", ErrDuplicateLabelName, err)
+	}
+
+	// no duplicates, decoded as is in the strict mode too
+	dup.Labels = wantLabels
+	if b, err = dup.Marshal(); err != nil {
+		t.Fatal(err)
+	}
+	if err = new(Series).Unmarshal(b); err != nil {
+		t.Fatalf("strict: unexpected error %v", err)
+	}
+}
+
+func TestSeries_ProtoUnmarshal(t *testing.T) {
+	defer SetStrictLabels(false)
+
+	dup := &Series{
+		Labels: []Label{{Name: "__name__", Value: "up"}, {Name: "a", Value: "1"}, {Name: "a", Value: "2"}},
+		Points: []Point{{T: 1560000000000, V: 1}},
+	}
+	b, err := dup.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// proto.Unmarshal goes through XXX_Unmarshal, which must take the duplicates as Series.Unmarshal does
+	got := new(Series)
+	if err = proto.Unmarshal(b, got); err != nil {
+		t.Fatal(err)
+	}
+	wantLabels := []Label{{Name: "__name__", Value: "up"}, {Name: "a", Value: "2"}}
+	if !reflect.DeepEqual(got.Labels, wantLabels) {
+		t.Fatalf("lenient: want labels %v, got %v", wantLabels, got.Labels)
+	}
+
+	SetStrictLabels(true)
+	if err = proto.Unmarshal(b, new(Series)); errors.Cause(err) != ErrDuplicateLabelName {
+		t.Fatalf("strict: want %v, got %v", ErrDuplicateLabelName, err)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/baudtime/baudtime/backend"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
 	"github.com/baudtime/baudtime/vars"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -125,8 +126,8 @@ func (q *query) Close() {
 
 // Exec implements the Query interface.
 func (q *query) Exec(ctx context.Context) *Result {
-	res, err := q.ng.exec(ctx, q)
-	return &Result{Err: err, Value: res}
+	res, warnings, err := q.ng.exec(ctx, q)
+	return &Result{Err: err, Value: res, Warnings: warnings}
 }
 
 // contextDone returns an error if the context was canceled or timed out.
@@ -233,12 +234,12 @@ func (ng *Engine) newTestQuery(f func(context.Context) error) Query {
 //
 // At this point per query only one EvalStmt is evaluated. Alert and record
 // statements are not handled by the Engine.
-func (ng *Engine) exec(ctx context.Context, q *query) (Value, error) {
+func (ng *Engine) exec(ctx context.Context, q *query) (Value, backend.Warnings, error) {
 	ctx, cancel := context.WithTimeout(ctx, ng.timeout)
 	q.cancel = cancel
 
 	if err := ng.gate.Start(ctx); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer ng.gate.Done()
 
@@ -249,14 +250,14 @@ func (ng *Engine) exec(ctx context.Context, q *query) (Value, error) {
 
 	// The base context might already be canceled on the first iteration (e.g. during shutdown).
 	if err := contextDone(ctx, env); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	switch s := q.Statement().(type) {
 	case *EvalStmt:
 		return ng.execEvalStmt(ctx, q, s)
 	case testStmt:
-		return nil, s(ctx)
+		return nil, nil, s(ctx)
 	}
 
 	panic(fmt.Errorf("promql.Engine.exec: unhandled statement of type %T", q.Statement()))
@@ -271,8 +272,8 @@ func durationMilliseconds(d time.Duration) int64 {
 }
 
 // execEvalStmt evaluates the expression of an evaluation statement for the given time range.
-func (ng *Engine) execEvalStmt(ctx context.Context, query *query, s *EvalStmt) (Value, error) {
-	querier, err := ng.populateSeries(ctx, query.queryable, s)
+func (ng *Engine) execEvalStmt(ctx context.Context, query *query, s *EvalStmt) (Value, backend.Warnings, error) {
+	querier, warnings, err := ng.populateSeries(ctx, query.queryable, s)
 
 	// XXX(fabxc): the querier returned by populateSeries might be instantiated
 	// we must not return without closing irrespective of the error.
@@ -282,7 +283,7 @@ func (ng *Engine) execEvalStmt(ctx context.Context, query *query, s *EvalStmt) (
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, warnings, err
 	}
 
 	// Instant evaluation. This is executed as a range evaluation with one step.
@@ -297,7 +298,7 @@ func (ng *Engine) execEvalStmt(ctx context.Context, query *query, s *EvalStmt) (
 		}
 		val, err := evaluator.Eval(s.Expr)
 		if err != nil {
-			return nil, err
+			return nil, warnings, err
 		}
 
 		mat, ok := val.(Matrix)
@@ -314,11 +315,11 @@ func (ng *Engine) execEvalStmt(ctx context.Context, query *query, s *EvalStmt) (
 				// timestamp as that is when we ran the evaluation.
 				vector[i] = Sample{Metric: s.Metric, Point: Point{V: s.Points[0].V, T: start}}
 			}
-			return vector, nil
+			return vector, warnings, nil
 		case ValueTypeScalar:
-			return Scalar{V: mat[0].Points[0].V, T: start}, nil
+			return Scalar{V: mat[0].Points[0].V, T: start}, warnings, nil
 		case ValueTypeMatrix:
-			return mat, nil
+			return mat, warnings, nil
 		default:
 			panic(fmt.Errorf("promql.Engine.exec: unexpected expression type %q", s.Expr.Type()))
 		}
@@ -335,7 +336,7 @@ func (ng *Engine) execEvalStmt(ctx context.Context, query *query, s *EvalStmt) (
 	}
 	val, err := evaluator.Eval(s.Expr)
 	if err != nil {
-		return nil, err
+		return nil, warnings, err
 	}
 
 	mat, ok := val.(Matrix)
@@ -345,25 +346,26 @@ func (ng *Engine) execEvalStmt(ctx context.Context, query *query, s *EvalStmt) (
 	query.matrix = mat
 
 	if err := contextDone(ctx, "expression evaluation"); err != nil {
-		return nil, err
+		return nil, warnings, err
 	}
 
 	// TODO(fabxc): order ensured by dataNodeAPI?
 	// TODO(fabxc): where to ensure metric labels are a copy from the dataNodeAPI internals.
 	sort.Sort(mat)
 
-	return mat, nil
+	return mat, warnings, nil
 }
 
-func (ng *Engine) populateSeries(ctx context.Context, q backend.Queryable, s *EvalStmt) (backend.Querier, error) {
+func (ng *Engine) populateSeries(ctx context.Context, q backend.Queryable, s *EvalStmt) (backend.Querier, backend.Warnings, error) {
 	if parentSpan, ok := ctx.Value("span").(opentracing.Span); ok {
 		span := opentracing.StartSpan("populateSeries", opentracing.ChildOf(parentSpan.Context()))
 		defer span.Finish()
 	}
 
 	var (
-		querier backend.Querier
-		err     error
+		querier  backend.Querier
+		warnings backend.Warnings
+		err      error
 	)
 
 	Inspect(s.Expr, func(node Node, path []Node) error {
@@ -375,6 +377,9 @@ func (ng *Engine) populateSeries(ctx context.Context, q backend.Queryable, s *Ev
 		switch n := node.(type) {
 		case *VectorSelector:
 			params.Func = extractFuncFromPath(path)
+			if vars.Cfg.Gateway != nil && vars.Cfg.Gateway.Query.AggregationPushdown {
+				params.Aggregation = pushdownAggregation(path)
+			}
 
 			mint := s.Start.Add(-n.Offset)
 			maxt := s.End.Add(-n.Offset)
@@ -385,7 +390,9 @@ func (ng *Engine) populateSeries(ctx context.Context, q backend.Queryable, s *Ev
 			}
 			defer querier.Close()
 
-			set, err = querier.Select(params, n.LabelMatchers...)
+			var wrn backend.Warnings
+			set, wrn, err = querier.Select(params, n.LabelMatchers...)
+			warnings = append(warnings, wrn...)
 			if err != nil {
 				level.Error(vars.Logger).Log("msg", "error selecting series set", "err", err)
 				return err
@@ -409,7 +416,9 @@ func (ng *Engine) populateSeries(ctx context.Context, q backend.Queryable, s *Ev
 			}
 			defer querier.Close()
 
-			set, err = querier.Select(params, n.LabelMatchers...)
+			var wrn backend.Warnings
+			set, wrn, err = querier.Select(params, n.LabelMatchers...)
+			warnings = append(warnings, wrn...)
 			if err != nil {
 				level.Error(vars.Logger).Log("msg", "error selecting series set", "err", err)
 				return err
@@ -422,7 +431,7 @@ func (ng *Engine) populateSeries(ctx context.Context, q backend.Queryable, s *Ev
 		}
 		return nil
 	})
-	return querier, err
+	return querier, warnings, err
 }
 
 // extractFuncFromPath walks up the path and searches for the first instance of
@@ -444,6 +453,25 @@ func extractFuncFromPath(p []Node) string {
 	return extractFuncFromPath(p[:len(p)-1])
 }
 
+// pushdownAggregation returns the aggregation to be pushed down to the shards for the vector
+// selector at the end of the path, nil if it's not eligible. Only sum and sum by directly over
+// a vector selector are eligible for now, e.g. sum by (job) (up{env="prod"}), while the ones
+// over functions, e.g. sum(rate(x[5m])), or with without are aggregated by the gateway.
+// The shards sum up the series independently, so a series moved to another shard group by
+// routing may be counted twice in the lookback delta around the moment it moved.
+func pushdownAggregation(path []Node) *backendpb.Aggregation {
+	if len(path) == 0 {
+		return nil
+	}
+
+	aggr, ok := path[len(path)-1].(*AggregateExpr)
+	if !ok || aggr.Op != itemSum || aggr.Without {
+		return nil
+	}
+
+	return &backendpb.Aggregation{Op: backendpb.AggrOp_AggrSum, Grouping: aggr.Grouping}
+}
+
 func expandSeriesSet(ctx context.Context, it backend.SeriesSet) (res []backend.Series, err error) {
 	if parentSpan, ok := ctx.Value("span").(opentracing.Span); ok {
 		span := opentracing.StartSpan("expandSeriesSet", opentracing.ChildOf(parentSpan.Context()))
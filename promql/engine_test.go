@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/baudtime/baudtime/backend"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
 	"github.com/go-kit/kit/log"
 	"github.com/prometheus/prometheus/pkg/labels"
 )
@@ -148,11 +149,12 @@ type errQuerier struct {
 	err error
 }
 
-func (q *errQuerier) Select(*backend.SelectParams, ...*labels.Matcher) (backend.SeriesSet, error) {
-	return errSeriesSet{err: q.err}, q.err
+func (q *errQuerier) Select(*backend.SelectParams, ...*labels.Matcher) (backend.SeriesSet, backend.Warnings, error) {
+	return errSeriesSet{err: q.err}, nil, q.err
 }
 func (*errQuerier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, error) { return nil, nil }
-func (*errQuerier) Close() error                              { return nil }
+func (*errQuerier) LabelNames() ([]string, error)                                          { return nil, nil }
+func (*errQuerier) Close() error                                                           { return nil }
 
 // errSeriesSet implements backend.SeriesSet which always returns error.
 type errSeriesSet struct {
@@ -380,3 +382,48 @@ func TestRecoverEvaluatorError(t *testing.T) {
 
 	panic(e)
 }
+
+func TestPushdownAggregation(t *testing.T) {
+	tests := []struct {
+		expr     string
+		want     bool
+		grouping []string
+	}{
+		{expr: `sum(up)`, want: true},
+		{expr: `sum by (job, idc) (up{env="prod"})`, want: true, grouping: []string{"job", "idc"}},
+		{expr: `sum(up) by (job)`, want: true, grouping: []string{"job"}},
+		{expr: `sum without (instance) (up)`, want: false},
+		{expr: `sum(rate(http_requests_total[5m]))`, want: false},
+		{expr: `sum(abs(up))`, want: false},
+		{expr: `count by (job) (up)`, want: false},
+		{expr: `sum((up))`, want: false},
+		{expr: `up`, want: false},
+	}
+
+	for _, test := range tests {
+		expr, err := ParseExpr(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var selectors int
+		Inspect(expr, func(node Node, path []Node) error {
+			if _, ok := node.(*VectorSelector); !ok {
+				return nil
+			}
+			selectors++
+
+			aggr := pushdownAggregation(path)
+			if (aggr != nil) != test.want {
+				t.Fatalf("%s: want eligible %v, got %v", test.expr, test.want, aggr)
+			}
+			if aggr != nil && (aggr.Op != backendpb.AggrOp_AggrSum || !reflect.DeepEqual(aggr.Grouping, test.grouping)) {
+				t.Fatalf("%s: unexpected aggregation %v", test.expr, aggr)
+			}
+			return nil
+		})
+		if selectors == 0 && test.want {
+			t.Fatalf("%s: no vector selector", test.expr)
+		}
+	}
+}
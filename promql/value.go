@@ -19,6 +19,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/baudtime/baudtime/backend"
 	"github.com/prometheus/prometheus/pkg/labels"
 )
 
@@ -162,8 +163,9 @@ func (m Matrix) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
 // Result holds the resulting value of an execution or an error
 // if any occurred.
 type Result struct {
-	Err   error
-	Value Value
+	Err      error
+	Value    Value
+	Warnings backend.Warnings
 }
 
 // Vector returns a Vector if the result value is one. An error is returned if
@@ -0,0 +1,209 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package baudtime
+
+import (
+	"context"
+	"time"
+
+	"github.com/baudtime/baudtime/backend"
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/msg/pb/remote"
+	"github.com/golang/snappy"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/valyala/fasthttp"
+)
+
+// HttpRemoteRead serves the Prometheus remote read protocol, so that a stock Prometheus can read from
+// baudtime as its remote storage. Only the samples response type is supported, the streamed chunks one isn't.
+func (gateway *Gateway) HttpRemoteRead(c *fasthttp.RequestCtx) {
+	reqBuf, err := snappy.Decode(nil, c.PostBody())
+	if err != nil {
+		c.Error(err.Error(), fasthttp.StatusBadRequest)
+		return
+	}
+
+	var request remote.ReadRequest
+	if err = request.Unmarshal(reqBuf); err != nil {
+		c.Error(err.Error(), fasthttp.StatusBadRequest)
+		return
+	}
+
+	var timeout string
+	if arg := c.QueryArgs().Peek("timeout"); arg != nil {
+		timeout = string(arg)
+	}
+
+	params := backend.SelectParams{SlaveRead: c.QueryArgs().GetBool("slave_read")}
+	if arg := c.QueryArgs().Peek("resolution"); arg != nil {
+		resolution, err := ParseDuration(string(arg))
+		if err != nil {
+			c.Error(err.Error(), fasthttp.StatusBadRequest)
+			return
+		}
+		params.Resolution = int64(resolution / time.Millisecond)
+	}
+	if arg := c.QueryArgs().Peek("downsample"); arg != nil {
+		if params.Downsample, err = backend.ParseDownsample(string(arg)); err != nil {
+			c.Error(err.Error(), fasthttp.StatusBadRequest)
+			return
+		}
+	}
+
+	response, err := gateway.remoteRead(&request, timeout, params)
+	if err != nil {
+		c.Error(err.Error(), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	respBuf, err := response.Marshal()
+	if err != nil {
+		c.Error(err.Error(), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	c.SetContentType("application/x-protobuf")
+	c.Response.Header.Set("Content-Encoding", "snappy")
+	c.SetBody(snappy.Encode(nil, respBuf))
+}
+
+func (gateway *Gateway) remoteRead(request *remote.ReadRequest, timeout string, params backend.SelectParams) (*remote.ReadResponse, error) {
+	span := opentracing.StartSpan("remoteRead", opentracing.Tag{Key: "queries", Value: len(request.Queries)})
+	defer span.Finish()
+
+	if len(request.AcceptedResponseTypes) > 0 {
+		accepted := false
+		for _, t := range request.AcceptedResponseTypes {
+			if t == remote.ResponseType_SAMPLES {
+				accepted = true
+			}
+		}
+		if !accepted {
+			return nil, errors.Errorf("none of the response types %v is supported", request.AcceptedResponseTypes)
+		}
+	}
+
+	ctx := context.WithValue(context.Background(), "span", span)
+	if params.SlaveRead {
+		ctx = backend.WithSlaveRead(ctx)
+	}
+	if timeout != "" {
+		var cancel context.CancelFunc
+		to, err := ParseDuration(timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel = context.WithTimeout(ctx, to)
+		defer cancel()
+	}
+
+	response := &remote.ReadResponse{Results: make([]remote.QueryResult, len(request.Queries))}
+	for i := range request.Queries {
+		result, err := remoteReadQuery(ctx, gateway.Backend, &request.Queries[i], params)
+		if err != nil {
+			return nil, err
+		}
+		response.Results[i] = *result
+	}
+
+	return response, nil
+}
+
+// remoteReadQuery selects the raw samples of a remote read query, only the function of the hints is passed
+// down as a select param, the step is left out as the reader evaluates the query on the samples itself.
+// For the same reason the samples are only down-sampled if the resolution is asked in the url, e.g. by
+// a reader plotting the samples as they are. The protocol has no room for warnings, so a partial response
+// is returned as if it's complete.
+func remoteReadQuery(ctx context.Context, queryable backend.Queryable, query *remote.Query, params backend.SelectParams) (*remote.QueryResult, error) {
+	matchers, err := fromLabelMatchers(query.Matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := queryable.Querier(ctx, query.StartTimestampMs, query.EndTimestampMs)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+
+	if hints := query.Hints; hints != nil {
+		params.Func = hints.Func
+	}
+
+	set, _, err := q.Select(&params, matchers...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(remote.QueryResult)
+	for set.Next() {
+		series := set.At()
+
+		lbls := series.Labels()
+		ts := &remote.TimeSeries{Labels: make([]pb.Label, 0, len(lbls))}
+		for _, l := range lbls {
+			ts.Labels = append(ts.Labels, pb.Label{Name: l.Name, Value: l.Value})
+		}
+
+		it := series.Iterator()
+		for ok := it.Seek(query.StartTimestampMs); ok; ok = it.Next() {
+			t, v := it.At()
+			if t > query.EndTimestampMs {
+				break
+			}
+			ts.Samples = append(ts.Samples, remote.Sample{Timestamp: t, Value: v})
+		}
+		if err = it.Err(); err != nil {
+			return nil, err
+		}
+
+		result.Timeseries = append(result.Timeseries, ts)
+	}
+	if err = set.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func fromLabelMatchers(matchers []remote.LabelMatcher) ([]*labels.Matcher, error) {
+	result := make([]*labels.Matcher, 0, len(matchers))
+	for _, matcher := range matchers {
+		var mtype labels.MatchType
+		switch matcher.Type {
+		case remote.LabelMatcher_EQ:
+			mtype = labels.MatchEqual
+		case remote.LabelMatcher_NEQ:
+			mtype = labels.MatchNotEqual
+		case remote.LabelMatcher_RE:
+			mtype = labels.MatchRegexp
+		case remote.LabelMatcher_NRE:
+			mtype = labels.MatchNotRegexp
+		default:
+			return nil, errors.Errorf("invalid matcher type %v", matcher.Type)
+		}
+
+		m, err := labels.NewMatcher(mtype, matcher.Name, matcher.Value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package baudtime
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/baudtime/baudtime/backend"
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/msg/pb/remote"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/valyala/fasthttp"
+)
+
+// fakeBackend records the queries against it and answers them with canned series.
+type fakeBackend struct {
+	backend.Backend
+	mint, maxt int64
+	params     *backend.SelectParams
+	matchers   []*labels.Matcher
+	series     []*pb.Series
+}
+
+func (b *fakeBackend) Querier(ctx context.Context, mint, maxt int64) (backend.Querier, error) {
+	b.mint, b.maxt = mint, maxt
+	return b, nil
+}
+
+func (b *fakeBackend) Select(params *backend.SelectParams, matchers ...*labels.Matcher) (backend.SeriesSet, backend.Warnings, error) {
+	b.params, b.matchers = params, matchers
+	set, err := backend.FromQueryResult(&backendpb.SelectResponse{Series: b.series})
+	return set, nil, err
+}
+
+func (b *fakeBackend) LabelValues(string, ...*labels.Matcher) ([]string, error) { return nil, nil }
+
+func (b *fakeBackend) LabelNames() ([]string, error) { return nil, nil }
+
+func (b *fakeBackend) Close() error { return nil }
+
+func TestHttpRemoteRead(t *testing.T) {
+	// up{job=~"node|prometheus"} in [1560000000000, 1560003600000] with the hints of rate(...[5m]) at a 15s step,
+	// snappy compressed protobuf encoded the way the remote read client of Prometheus sends it.
+	reqBody, err := ioutil.ReadFile("testdata/remote_read_request.snappy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeBackend{series: []*pb.Series{
+		{
+			Labels: []pb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "node"}},
+			Points: []pb.Point{{T: 1559999990000, V: 0}, {T: 1560000000000, V: 1}, {T: 1560003600000, V: 1}, {T: 1560003610000, V: 0}},
+		},
+		{
+			Labels: []pb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "prometheus"}},
+			Points: []pb.Point{{T: 1560001800000, V: 1}},
+		},
+	}}
+	gateway := &Gateway{Backend: fake}
+
+	var c fasthttp.RequestCtx
+	c.Request.Header.SetMethod("POST")
+	c.Request.SetBody(reqBody)
+	gateway.HttpRemoteRead(&c)
+
+	if c.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("unexpected status %d: %s", c.Response.StatusCode(), c.Response.Body())
+	}
+	if string(c.Response.Header.Peek("Content-Encoding")) != "snappy" {
+		t.Fatalf("want snappy encoded response, got %q", c.Response.Header.Peek("Content-Encoding"))
+	}
+
+	if fake.mint != 1560000000000 || fake.maxt != 1560003600000 {
+		t.Fatalf("unexpected time span [%d, %d]", fake.mint, fake.maxt)
+	}
+	if fake.params.Step != 0 || fake.params.Func != "rate" {
+		t.Fatalf("unexpected select params %+v", fake.params)
+	}
+	if len(fake.matchers) != 2 || fake.matchers[0].String() != `__name__="up"` || fake.matchers[1].String() != `job=~"node|prometheus"` {
+		t.Fatalf("unexpected matchers %v", fake.matchers)
+	}
+
+	respBuf, err := snappy.Decode(nil, c.Response.Body())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response remote.ReadResponse
+	if err = response.Unmarshal(respBuf); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Results) != 1 || len(response.Results[0].Timeseries) != 2 {
+		t.Fatalf("unexpected response %v", response)
+	}
+	ts := response.Results[0].Timeseries[0]
+	if len(ts.Labels) != 2 || ts.Labels[1] != (pb.Label{Name: "job", Value: "node"}) {
+		t.Fatalf("unexpected labels %v", ts.Labels)
+	}
+	if len(ts.Samples) != 2 || ts.Samples[0] != (remote.Sample{Timestamp: 1560000000000, Value: 1}) || ts.Samples[1].Timestamp != 1560003600000 {
+		t.Fatalf("want samples out of the time span dropped, got %v", ts.Samples)
+	}
+}
+
+func TestHttpRemoteRead_Resolution(t *testing.T) {
+	reqBody, err := ioutil.ReadFile("testdata/remote_read_request.snappy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeBackend{}
+	gateway := &Gateway{Backend: fake}
+
+	var c fasthttp.RequestCtx
+	c.Request.Header.SetMethod("POST")
+	c.Request.SetRequestURI("/api/v1/read?resolution=5m&downsample=avg")
+	c.Request.SetBody(reqBody)
+	gateway.HttpRemoteRead(&c)
+
+	if c.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("unexpected status %d: %s", c.Response.StatusCode(), c.Response.Body())
+	}
+	if fake.params.Resolution != 300000 || fake.params.Downsample != backend.DownsampleAvg || fake.params.Func != "rate" {
+		t.Fatalf("unexpected select params %+v", fake.params)
+	}
+
+	c.Request.SetRequestURI("/api/v1/read?resolution=5m&downsample=max")
+	c.Request.SetBody(reqBody)
+	gateway.HttpRemoteRead(&c)
+
+	if c.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Fatalf("want bad request of an unknown downsample, got %d", c.Response.StatusCode())
+	}
+}
+
+func TestRemoteRead_ResponseTypes(t *testing.T) {
+	gateway := &Gateway{Backend: &fakeBackend{}}
+
+	request := &remote.ReadRequest{
+		Queries:               []remote.Query{{StartTimestampMs: 0, EndTimestampMs: 1000}},
+		AcceptedResponseTypes: []remote.ResponseType{remote.ResponseType_STREAMED_XOR_CHUNKS},
+	}
+	if _, err := gateway.remoteRead(request, "", backend.SelectParams{}); err == nil {
+		t.Fatalf("want error of no supported response type")
+	}
+
+	request.AcceptedResponseTypes = append(request.AcceptedResponseTypes, remote.ResponseType_SAMPLES)
+	response, err := gateway.remoteRead(request, "", backend.SelectParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Results) != 1 {
+		t.Fatalf("want a result per query, got %d", len(response.Results))
+	}
+}
@@ -0,0 +1,277 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/baudtime/baudtime/backend"
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/util"
+	ts "github.com/baudtime/baudtime/util/time"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/textparse"
+	"github.com/prometheus/prometheus/pkg/value"
+)
+
+const (
+	acceptHeader = `text/plain;version=0.0.4;q=1,*/*;q=0.1`
+	defaultJob   = "baudtime_scrape"
+)
+
+// sample is a sample scraped, lset is sorted by name and hash is its util.HashLabels.
+type sample struct {
+	lset  []pb.Label
+	hash  uint64
+	point pb.Point
+	ts    bool // whether the timestamp is given by the target
+}
+
+// parse parses the samples out of the exposition b, whose format is told by contentType. The samples
+// without a timestamp are at defTime. The target labels are added to the series not having them.
+func parse(b []byte, contentType string, defTime int64, targetLabels labels.Labels) ([]sample, error) {
+	var (
+		p       = textparse.New(b, contentType)
+		samples []sample
+	)
+
+	for {
+		entry, err := p.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if entry != textparse.EntrySeries {
+			continue
+		}
+
+		_, tp, v := p.Series()
+
+		var lset labels.Labels
+		p.Metric(&lset)
+		for _, l := range targetLabels {
+			if !lset.Has(l.Name) {
+				lset = append(lset, l)
+			}
+		}
+		sort.Sort(lset)
+
+		s := sample{lset: util.LabelsToProto(lset), hash: util.HashPromLabels(lset), point: pb.Point{T: defTime, V: v}}
+		if tp != nil {
+			s.point.T, s.ts = *tp, true
+		}
+		samples = append(samples, s)
+	}
+
+	return samples, nil
+}
+
+// target is an endpoint scraped every interval, whose samples are added to its own appender.
+type target struct {
+	url      string
+	labels   labels.Labels
+	interval time.Duration
+	timeout  time.Duration
+	client   *http.Client
+	appender backend.Appender
+	previous map[uint64][]pb.Label // the series scraped last time without a timestamp
+	logger   log.Logger
+
+	done       chan struct{}
+	terminated chan struct{}
+}
+
+func newTarget(rawURL, job string, interval, timeout time.Duration, appender backend.Appender, logger log.Logger) (*target, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("invalid scrape target %q", rawURL)
+	}
+
+	return &target{
+		url:        rawURL,
+		labels:     labels.FromStrings("instance", u.Host, "job", job),
+		interval:   interval,
+		timeout:    timeout,
+		client:     &http.Client{},
+		appender:   appender,
+		previous:   map[uint64][]pb.Label{},
+		logger:     log.With(logger, "target", rawURL),
+		done:       make(chan struct{}),
+		terminated: make(chan struct{}),
+	}, nil
+}
+
+func (t *target) run() {
+	defer close(t.terminated)
+
+	tick := time.NewTicker(t.interval)
+	defer tick.Stop()
+
+	for {
+		t.scrapeAndAppend(time.Now())
+
+		select {
+		case <-t.done:
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+func (t *target) stop() {
+	close(t.done)
+	<-t.terminated
+}
+
+func (t *target) scrape(ctx context.Context) ([]byte, string, error) {
+	req, err := http.NewRequest("GET", t.url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", acceptHeader)
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", fmt.Sprintf("%f", t.timeout.Seconds()))
+
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", errors.Errorf("server returned HTTP status %s", resp.Status)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	return b, resp.Header.Get("Content-Type"), err
+}
+
+// scrapeAndAppend scrapes the target and adds the samples to the appender. The series scraped last time
+// but gone are marked stale, so are all of them if the scrape fails. The series with a timestamp given by
+// the target are never marked stale, as it's unknown when they end.
+func (t *target) scrapeAndAppend(now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	var samples []sample
+	b, contentType, err := t.scrape(ctx)
+	if err == nil {
+		samples, err = parse(b, contentType, ts.FromTime(now), t.labels)
+	}
+	if err != nil {
+		level.Warn(t.logger).Log("msg", "scrape failed", "err", err)
+	}
+
+	current := make(map[uint64][]pb.Label, len(t.previous))
+	for _, s := range samples {
+		if err := t.appender.Add(s.lset, s.point.T, s.point.V, s.hash); err != nil {
+			level.Warn(t.logger).Log("msg", "sample scraped discarded", "err", err, "series", util.ProtoToLabels(s.lset))
+			continue
+		}
+		if !s.ts {
+			current[s.hash] = s.lset
+		}
+	}
+
+	stale := math.Float64frombits(value.StaleNaN)
+	for hash, lset := range t.previous {
+		if _, found := current[hash]; !found {
+			if err := t.appender.Add(lset, ts.FromTime(now), stale, hash); err != nil {
+				level.Warn(t.logger).Log("msg", "staleness marker discarded", "err", err, "series", util.ProtoToLabels(lset))
+			}
+		}
+	}
+	t.previous = current
+
+	if err := t.appender.Flush(); err != nil {
+		level.Warn(t.logger).Log("msg", "failed to flush samples scraped", "err", err)
+	}
+}
+
+// Manager scrapes the targets of the config every interval, and adds the samples through the
+// appenders of the backend, so that they're routed to the shards like the ones written.
+type Manager struct {
+	targets []*target
+	wg      sync.WaitGroup
+}
+
+// NewManager returns a Manager of the targets of cfg, ready to be started by calling the Run method.
+func NewManager(cfg vars.ScrapeConfig, api backend.Backend, logger log.Logger) (*Manager, error) {
+	interval := time.Duration(cfg.Interval)
+	if interval <= 0 {
+		return nil, errors.New("scrape interval must be positive")
+	}
+	timeout := time.Duration(cfg.Timeout)
+	if timeout <= 0 || timeout > interval {
+		timeout = interval
+	}
+	job := cfg.Job
+	if job == "" {
+		job = defaultJob
+	}
+
+	m := new(Manager)
+	for _, rawURL := range cfg.Targets {
+		app, err := api.Appender()
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := newTarget(rawURL, job, interval, timeout, app, logger)
+		if err != nil {
+			return nil, err
+		}
+		m.targets = append(m.targets, t)
+	}
+
+	return m, nil
+}
+
+// Run starts scraping the targets.
+func (m *Manager) Run() {
+	for _, t := range m.targets {
+		m.wg.Add(1)
+		go func(t *target) {
+			defer m.wg.Done()
+			t.run()
+		}(t)
+	}
+}
+
+// Stop stops scraping the targets.
+func (m *Manager) Stop() {
+	for _, t := range m.targets {
+		t.stop()
+	}
+	m.wg.Wait()
+}
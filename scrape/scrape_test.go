@@ -0,0 +1,161 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scrape
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/util"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/value"
+)
+
+const exposition = `# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+http_requests_total{method="post",code="400"}    3 1395066363000
+# HELP go_goroutines Number of goroutines that currently exist.
+# TYPE go_goroutines gauge
+go_goroutines 42
+# A histogram, which has a pretty complex representation in the text format:
+# HELP http_request_duration_seconds A histogram of the request duration.
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{le="0.05"} 24054
+http_request_duration_seconds_bucket{le="+Inf"} 144320
+http_request_duration_seconds_sum 53423
+http_request_duration_seconds_count 144320
+`
+
+func TestParse(t *testing.T) {
+	targetLabels := labels.FromStrings("instance", "localhost:9100", "job", "node")
+
+	samples, err := parse([]byte(exposition), "text/plain; version=0.0.4", 1000, targetLabels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		lset labels.Labels
+		t    int64
+		v    float64
+	}{
+		{labels.FromStrings("__name__", "http_requests_total", "code", "200", "instance", "localhost:9100", "job", "node", "method", "post"), 1395066363000, 1027},
+		{labels.FromStrings("__name__", "http_requests_total", "code", "400", "instance", "localhost:9100", "job", "node", "method", "post"), 1395066363000, 3},
+		{labels.FromStrings("__name__", "go_goroutines", "instance", "localhost:9100", "job", "node"), 1000, 42},
+		{labels.FromStrings("__name__", "http_request_duration_seconds_bucket", "instance", "localhost:9100", "job", "node", "le", "0.05"), 1000, 24054},
+		{labels.FromStrings("__name__", "http_request_duration_seconds_bucket", "instance", "localhost:9100", "job", "node", "le", "+Inf"), 1000, 144320},
+		{labels.FromStrings("__name__", "http_request_duration_seconds_sum", "instance", "localhost:9100", "job", "node"), 1000, 53423},
+		{labels.FromStrings("__name__", "http_request_duration_seconds_count", "instance", "localhost:9100", "job", "node"), 1000, 144320},
+	}
+
+	if len(samples) != len(want) {
+		t.Fatalf("want %d samples, got %d", len(want), len(samples))
+	}
+	for i, s := range samples {
+		if !reflect.DeepEqual(s.lset, util.LabelsToProto(want[i].lset)) {
+			t.Fatalf("sample %d: want series %v, got %v", i, want[i].lset, s.lset)
+		}
+		if s.hash != util.HashLabels(s.lset) {
+			t.Fatalf("sample %d: unexpected hash", i)
+		}
+		if s.point.T != want[i].t || s.point.V != want[i].v {
+			t.Fatalf("sample %d: want %v at %d, got %v at %d", i, want[i].v, want[i].t, s.point.V, s.point.T)
+		}
+		if s.ts != (want[i].t != 1000) {
+			t.Fatalf("sample %d: the timestamp is given %v", i, !s.ts)
+		}
+	}
+
+	// the labels of the target don't override the ones exposed
+	samples, err = parse([]byte(`up{job="exposed"} 1`), "", 1000, targetLabels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job := util.ProtoToLabels(samples[0].lset).Get("job"); len(samples) != 1 || job != "exposed" {
+		t.Fatalf("want the job exposed, got %v", samples)
+	}
+
+	if _, err = parse([]byte("up{ 1"), "", 1000, targetLabels); err == nil {
+		t.Fatalf("expected error parsing invalid exposition")
+	}
+}
+
+// fakeAppender records the samples flushed.
+type fakeAppender struct {
+	added   []pb.Series
+	flushed []pb.Series
+}
+
+func (app *fakeAppender) Add(l []pb.Label, t int64, v float64, hash uint64) error {
+	app.added = append(app.added, pb.Series{Labels: l, Points: []pb.Point{{T: t, V: v}}})
+	return nil
+}
+
+func (app *fakeAppender) Flush() error {
+	app.flushed = append(app.flushed, app.added...)
+	app.added = nil
+	return nil
+}
+
+func TestTarget_Staleness(t *testing.T) {
+	body := "up 1\ngone 1\nat 1 5000\n"
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	app := &fakeAppender{}
+	target, err := newTarget(srv.URL+"/metrics", "test", time.Minute, time.Second, app, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scrape := func(now int64) map[string]pb.Point {
+		app.flushed = nil
+		target.scrapeAndAppend(time.Unix(0, now*int64(time.Millisecond)))
+
+		got := make(map[string]pb.Point)
+		for _, s := range app.flushed {
+			got[util.ProtoToLabels(s.Labels).Get("__name__")] = s.Points[0]
+		}
+		return got
+	}
+
+	got := scrape(1000)
+	if len(got) != 3 || got["up"].T != 1000 || got["at"].T != 5000 {
+		t.Fatalf("unexpected samples %v", got)
+	}
+
+	body = "up 2\nat 2 6000\n"
+	got = scrape(2000)
+	if len(got) != 3 || got["up"].V != 2 || got["gone"].T != 2000 || !value.IsStaleNaN(got["gone"].V) {
+		t.Fatalf("want gone marked stale, got %v", got)
+	}
+
+	status = http.StatusInternalServerError
+	got = scrape(3000)
+	if len(got) != 1 || !value.IsStaleNaN(got["up"].V) {
+		t.Fatalf("want up marked stale only, got %v", got)
+	}
+}
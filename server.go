@@ -25,11 +25,14 @@ import (
 	gatewaypb "github.com/baudtime/baudtime/msg/pb/gateway"
 	"github.com/baudtime/baudtime/promql"
 	"github.com/baudtime/baudtime/rule"
+	"github.com/baudtime/baudtime/scrape"
 	"github.com/baudtime/baudtime/tcp"
 	osutil "github.com/baudtime/baudtime/util/os"
 	. "github.com/baudtime/baudtime/vars"
 	"github.com/buaazp/fasthttprouter"
 	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/tsdb"
 	"github.com/valyala/fasthttp"
 	"github.com/valyala/fasthttp/pprofhandler"
@@ -81,7 +84,15 @@ func (obs *tcpServerObserver) OnAccept(tcpConn *net.TCPConn) *tcp.ReadWriteLoop
 	tcpConn.SetReadBuffer(1024 * 1024)
 	tcpConn.SetWriteBuffer(1024 * 1024)
 
-	return tcp.NewReadWriteLoop(tcpConn, func(ctx context.Context, req tcp.Message, reqBytes []byte) tcp.Message {
+	conn, err := tcp.WrapConn(tcpConn, true)
+	if err != nil {
+		level.Error(Logger).Log("msg", "failed to wrap conn", "remoteAddr", tcpConn.RemoteAddr(), "err", err)
+		tcpConn.Close()
+		return nil
+	}
+
+	var loop *tcp.ReadWriteLoop
+	loop = tcp.NewReadWriteLoop(conn, tcp.RoleServer, func(ctx context.Context, req tcp.Message, reqBytes []byte) tcp.Message {
 		raw := req.GetRaw()
 		response := tcp.Message{Opaque: req.GetOpaque()}
 
@@ -105,9 +116,16 @@ func (obs *tcpServerObserver) OnAccept(tcpConn *net.TCPConn) *tcp.ReadWriteLoop
 		case *gatewaypb.LabelValuesRequest:
 			response.SetRaw(obs.gateway.LabelValues(request))
 		case *backendpb.AddRequest:
+			if obs.storage.Left() {
+				response.SetRaw(storage.ErrLeftCluster.Response())
+				break
+			}
+
 			err := obs.storage.HandleAddReq(request)
 			obs.storage.ReplicateManager.HandleWriteReq(reqBytes)
-			if err != nil {
+			if request.Ack != backendpb.AckLevel_AckAsync {
+				response.SetRaw(obs.storage.Acknowledge(ctx, request, err))
+			} else if err != nil {
 				response.SetRaw(&pb.GeneralResponse{
 					Status:  pb.StatusCode_Failed,
 					Message: err.Error(),
@@ -116,9 +134,25 @@ func (obs *tcpServerObserver) OnAccept(tcpConn *net.TCPConn) *tcp.ReadWriteLoop
 				return tcp.EmptyMsg
 			}
 		case *backendpb.SelectRequest:
-			response.SetRaw(obs.storage.HandleSelectReq(request))
+			selectResponse := obs.storage.HandleSelectReq(request)
+			if seriesPerFrame := int(request.SeriesPerFrame); seriesPerFrame > 0 {
+				for len(selectResponse.Series) > seriesPerFrame {
+					err := loop.Write(tcp.Message{Opaque: req.GetOpaque(), Message: &backendpb.SelectResponse{
+						Status:  selectResponse.Status,
+						Series:  selectResponse.Series[:seriesPerFrame],
+						HasMore: true,
+					}})
+					if err != nil {
+						return tcp.EmptyMsg
+					}
+					selectResponse.Series = selectResponse.Series[seriesPerFrame:]
+				}
+			}
+			response.SetRaw(selectResponse)
 		case *backendpb.LabelValuesRequest:
 			response.SetRaw(obs.storage.HandleLabelValuesReq(request))
+		case *backendpb.LabelNamesRequest:
+			response.SetRaw(obs.storage.HandleLabelNamesReq(request))
 		case *backendpb.SlaveOfCommand:
 			response.SetRaw(obs.storage.ReplicateManager.HandleSlaveOfCmd(request))
 		case *backendpb.SyncHandshake:
@@ -126,7 +160,11 @@ func (obs *tcpServerObserver) OnAccept(tcpConn *net.TCPConn) *tcp.ReadWriteLoop
 		case *backendpb.SyncHeartbeat:
 			response.SetRaw(obs.storage.ReplicateManager.HandleHeartbeat(request))
 		case *pb.AdminCmdRequest:
-			if infoCmd := request.GetInfo(); infoCmd != nil {
+			if infoCmd := request.GetInfo(); infoCmd != nil && infoCmd.Detailed {
+				response.SetRaw(obs.handleInfoCmd(infoCmd))
+			} else if infoCmd != nil && obs.storage == nil {
+				response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Succeed, Message: meta.HealthStatus().Status})
+			} else if infoCmd != nil {
 				info, _, err := obs.storage.Info()
 				if err != nil {
 					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Failed, Message: err.Error()})
@@ -135,13 +173,86 @@ func (obs *tcpServerObserver) OnAccept(tcpConn *net.TCPConn) *tcp.ReadWriteLoop
 				}
 			}
 			if joinCluster := request.GetJoinCluster(); joinCluster != nil {
-				obs.storage.ReplicateManager.JoinCluster()
-				response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Succeed, Message: obs.storage.ReplicateManager.RelationID()})
+				if obs.storage == nil {
+					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Failed, Message: "not a storage"})
+				} else {
+					obs.storage.ReplicateManager.JoinCluster()
+					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Succeed, Message: obs.storage.ReplicateManager.RelationID()})
+				}
+			}
+			if slaveOf := request.GetSlaveOf(); slaveOf != nil {
+				if obs.storage == nil {
+					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Failed, Message: "not a storage"})
+				} else {
+					response.SetRaw(obs.storage.ReplicateManager.HandleSlaveOfCmd(&backendpb.SlaveOfCommand{MasterAddr: slaveOf.MasterAddr}))
+				}
+			}
+			if route := request.GetRoute(); route != nil {
+				if obs.gateway == nil {
+					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Failed, Message: "not a gateway"})
+				} else {
+					response.SetRaw(obs.gateway.Route(route))
+				}
+			}
+			if rebalance := request.GetRebalance(); rebalance != nil {
+				if obs.gateway == nil {
+					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Failed, Message: "not a gateway"})
+				} else {
+					response.SetRaw(obs.gateway.Rebalance(rebalance))
+				}
+			}
+			if listMetrics := request.GetListMetrics(); listMetrics != nil {
+				if obs.gateway == nil {
+					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Failed, Message: "not a gateway"})
+				} else {
+					response.SetRaw(obs.gateway.ListMetrics(listMetrics))
+				}
+			}
+			if drain := request.GetDrain(); drain != nil {
+				if obs.storage == nil {
+					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Failed, Message: "not a storage"})
+				} else if err := obs.storage.Drain(drain.Undo); err != nil {
+					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Failed, Message: err.Error()})
+				} else {
+					obs.heartbeat.Report()
+					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Succeed})
+				}
+			}
+			if leaveCluster := request.GetLeaveCluster(); leaveCluster != nil {
+				if obs.storage == nil {
+					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Failed, Message: "not a storage"})
+				} else if err := obs.heartbeat.Leave(); err != nil {
+					// still the master, keep taking the writes
+					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Failed, Message: err.Error()})
+				} else {
+					// the mastership is handed over, writes still coming are refused to be retried on the new master
+					obs.storage.LeaveCluster()
+					response.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Succeed})
+				}
 			}
 		}
 
 		return response
 	})
+
+	return loop
+}
+
+// handleInfoCmd answers a detailed info command, which tells the health of the gateway too if the node is one.
+func (obs *tcpServerObserver) handleInfoCmd(cmd *pb.Info) *pb.InfoResponse {
+	info := &pb.InfoResponse{
+		Status:    pb.StatusCode_Succeed,
+		Version:   Version,
+		Role:      "gateway",
+		StartTime: StartTime.UnixNano() / int64(time.Millisecond),
+	}
+	if obs.storage != nil {
+		info = obs.storage.HandleInfoCmd(cmd)
+	}
+	if obs.gateway != nil && info.Status == pb.StatusCode_Succeed {
+		info.Health = obs.gateway.Health()
+	}
+	return info
 }
 
 func Run() {
@@ -152,13 +263,16 @@ func Run() {
 		router       = fasthttprouter.New()
 	)
 
+	pb.SetStrictLabels(Cfg.StrictLabels)
+
 	if Cfg.Storage != nil {
 		walSegmentSize := 0
 		if !Cfg.Storage.TSDB.EnableWal {
 			walSegmentSize = -1
 		}
 
-		db, err := tsdb.Open(Cfg.Storage.TSDB.Path, Logger, nil, &tsdb.Options{
+		compaction := storage.NewCompactionWatcher()
+		db, err := tsdb.Open(Cfg.Storage.TSDB.Path, Logger, compaction, &tsdb.Options{
 			WALSegmentSize:         walSegmentSize,
 			RetentionDuration:      uint64(Cfg.Storage.TSDB.RetentionDuration) / 1e6,
 			BlockRanges:            Cfg.Storage.TSDB.BlockRanges,
@@ -171,6 +285,8 @@ func Run() {
 		}
 
 		localStorage = storage.New(db)
+		localStorage.Compaction = compaction
+		localStorage.KeepRestoringDraining()
 		heartbeat = meta.NewHeartbeat(time.Duration(Cfg.Storage.StatReport.SessionExpireTTL), time.Duration(Cfg.Storage.StatReport.HeartbeartInterval), func() (node meta.Node, err error) {
 			node, _, err = localStorage.Info()
 			return
@@ -204,6 +320,17 @@ func Run() {
 			defer ruleManager.Stop()
 		}
 
+		if Cfg.Gateway.Scrape != nil && len(Cfg.Gateway.Scrape.Targets) > 0 {
+			scrapeManager, err := scrape.NewManager(*Cfg.Gateway.Scrape, fanout, Logger)
+			if err != nil {
+				level.Error(Logger).Log("msg", "failed to init scrape manager", "err", err)
+				return
+			}
+
+			scrapeManager.Run()
+			defer scrapeManager.Stop()
+		}
+
 		gateway = &Gateway{
 			Backend:     fanout,
 			QueryEngine: queryEngine,
@@ -214,8 +341,14 @@ func Run() {
 		router.GET("/api/v1/query_range", gateway.HttpRangeQuery)
 		router.POST("/api/v1/query_range", gateway.HttpRangeQuery)
 		router.GET("/api/v1/label/:name/values", gateway.HttpLabelValues)
+		router.GET("/api/v1/labels", gateway.HttpLabelNames)
+		router.GET("/api/v1/series/:metric/:hash", gateway.HttpSeriesByHash)
+		router.GET("/health", gateway.HttpHealth)
+		router.POST("/api/v1/read", gateway.HttpRemoteRead)
 	}
 
+	router.GET("/metrics", handleHttpMetrics)
+
 	httpServer := &fasthttp.Server{}
 	go func() {
 		httpServer.Handler = func(ctx *fasthttp.RequestCtx) {
@@ -248,3 +381,20 @@ func Run() {
 		return true
 	})
 }
+
+func handleHttpMetrics(ctx *fasthttp.RequestCtx) {
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType(string(expfmt.FmtText))
+	enc := expfmt.NewEncoder(ctx, expfmt.FmtText)
+	for _, mf := range mfs {
+		if err = enc.Encode(mf); err != nil {
+			level.Error(Logger).Log("msg", "failed to encode metrics", "err", err)
+			return
+		}
+	}
+}
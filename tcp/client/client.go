@@ -32,11 +32,23 @@ var timeoutErr = errors.New("timeout")
 
 type Callback func(opaque uint64, response msg.Message)
 
+// FrameCheck checks a response frame of a request before it's decoded, by its type and its proto, see tcp.PeekProto.
+// An error fails the request and closes the connection, so the rest of the response is never read.
+type FrameCheck func(msgType tcp.MsgType, proto []byte) error
+
+type frameCheckKey struct{}
+
+// WithFrameCheck returns a ctx the responses of the sync requests sent with are checked by check before being decoded.
+func WithFrameCheck(ctx context.Context, check FrameCheck) context.Context {
+	return context.WithValue(ctx, frameCheckKey{}, check)
+}
+
 type Future struct {
 	opaque    uint64
 	timestamp time.Time
 	ch        chan msg.Message
 	callback  Callback
+	check     FrameCheck
 	err       error
 }
 
@@ -91,10 +103,20 @@ type Conn struct {
 	nativeConn *net.TCPConn
 	rwLoop     *tcp.ReadWriteLoop
 	futureTab  *futureTable
+	inflight   int  // requests in flight on it, guarded by the HostConnPool it's in
+	pooled     bool // whether it's in a HostConnPool
 }
 
 func newConn(address string) (*Conn, error) {
-	c, err := net.DialTimeout("tcp4", address, 2*time.Second)
+	return newConnWithRole(address, tcp.RoleClient)
+}
+
+func newReplicationConn(address string) (*Conn, error) {
+	return newConnWithRole(address, tcp.RoleReplication)
+}
+
+func newConnWithRole(address string, role string) (*Conn, error) {
+	c, err := net.DialTimeout("tcp4", address, tcp.DialTimeout())
 	if err != nil {
 		return nil, err
 	}
@@ -106,12 +128,18 @@ func newConn(address string) (*Conn, error) {
 	tc.SetReadBuffer(1024 * 1024)
 	tc.SetWriteBuffer(1024 * 1024)
 
+	conn, err := tcp.WrapConn(tc, false)
+	if err != nil {
+		tc.Close()
+		return nil, err
+	}
+
 	cc := &Conn{
 		address:    address,
 		nativeConn: tc,
 		futureTab:  &futureTable{futures: make(map[uint64]*Future)},
 	}
-	cc.rwLoop = tcp.NewReadWriteLoop(tc, func(ctx context.Context, in tcp.Message, b []byte) tcp.Message {
+	cc.rwLoop = tcp.NewReadWriteLoop(conn, role, func(ctx context.Context, in tcp.Message, b []byte) tcp.Message {
 		if f, ok := cc.futureTab.get(in.GetOpaque()); ok {
 			f.ch <- in.GetRaw()
 			if f.callback != nil {
@@ -122,6 +150,24 @@ func newConn(address string) (*Conn, error) {
 		return tcp.EmptyMsg //TODO
 	})
 
+	cc.rwLoop.BeforeDecode(func(msgType tcp.MsgType, b []byte) error {
+		opaque, err := tcp.PeekOpaque(b)
+		if err != nil {
+			return nil // left to the decoding to fail
+		}
+		f, ok := cc.futureTab.get(opaque)
+		if !ok || f.check == nil {
+			return nil
+		}
+		err = tcp.PeekProto(b, func(proto []byte) error {
+			return f.check(msgType, proto)
+		})
+		if err != nil {
+			f.setErr(err) // told by Get once the connection is closed
+		}
+		return err
+	})
+
 	cc.rwLoop.OnExit(func() {
 		cc.futureTab.RLock()
 		for opaque, f := range cc.futureTab.futures {
@@ -134,6 +180,11 @@ func newConn(address string) (*Conn, error) {
 	go cc.rwLoop.LoopRead()
 	go cc.rwLoop.LoopWrite()
 
+	if err = cc.rwLoop.Handshake(); err != nil {
+		cc.rwLoop.Exit()
+		return nil, err
+	}
+
 	return cc, nil
 }
 
@@ -145,6 +196,11 @@ func (c *Conn) close() error {
 	return c.rwLoop.Exit()
 }
 
+// closeGraceful closes c once the requests queued on it are written out.
+func (c *Conn) closeGraceful() error {
+	return c.rwLoop.ExitGraceful(time.Second)
+}
+
 func (c *Conn) isClosed() bool {
 	return !c.rwLoop.IsRunning()
 }
@@ -168,16 +224,28 @@ func NewGatewayClient(name string, addrProvider ServiceAddrProvider) *Client {
 	}
 }
 
-func NewBackendClient(name string, address string, connNumPerHost int) *Client {
-	if connNumPerHost <= 0 {
-		connNumPerHost = 1
+// NewBackendClient returns a client keeping at most maxOpen connections to address, the idle ones
+// over maxIdle are closed (0 keeps them all).
+func NewBackendClient(name string, address string, maxOpen int, maxIdle int) *Client {
+	return newHostClient(name, address, maxOpen, maxIdle, newConn)
+}
+
+// NewReplicationClient returns a client to the master used by the replication of a slave.
+func NewReplicationClient(name string, address string, connNumPerHost int) *Client {
+	return newHostClient(name, address, connNumPerHost, 0, newReplicationConn)
+}
+
+func newHostClient(name string, address string, maxOpen int, maxIdle int, newConn func(string) (*Conn, error)) *Client {
+	if maxOpen <= 0 {
+		maxOpen = 1
 	}
 	return &Client{
 		name: name,
 		connPool: &HostConnPool{
-			conns:   make([]*Conn, connNumPerHost),
-			size:    connNumPerHost,
+			maxOpen: maxOpen,
+			maxIdle: maxIdle,
 			address: address,
+			metrics: newPoolMetrics(address),
 			new:     newConn,
 		},
 	}
@@ -200,6 +268,7 @@ func (cli *Client) SyncRequest(ctx context.Context, request msg.Message) (msg.Me
 	}
 
 	f := newFuture(opaque, nil)
+	f.check, _ = ctx.Value(frameCheckKey{}).(FrameCheck)
 	c.futureTab.add(opaque, f)
 	defer c.futureTab.del(opaque)
 
@@ -210,7 +279,7 @@ func (cli *Client) SyncRequest(ctx context.Context, request msg.Message) (msg.Me
 	}
 
 	resp, err := f.Get(ctx)
-	//cli.connPool.PutConn(c)
+	cli.connPool.PutConn(c)
 
 	if err != nil {
 		return nil, err
@@ -248,7 +317,7 @@ func (cli *Client) AsyncRequest(request msg.Message, callback Callback) error {
 		return err
 	}
 
-	//cli.connPool.PutConn(c)
+	cli.connPool.PutConn(c)
 	return nil
 }
 
@@ -17,12 +17,18 @@ package client
 
 import (
 	"context"
+	"errors"
+	"net"
 	"strconv"
 	"testing"
 	"time"
 
 	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
 	gatewaypb "github.com/baudtime/baudtime/msg/pb/gateway"
+	"github.com/baudtime/baudtime/tcp"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log"
 )
 
 var staticAddrProvider = NewStaticAddrProvider("127.0.0.1:8087", "127.0.0.1:8087")
@@ -109,9 +115,9 @@ func TestBaudClient_Write(t *testing.T) {
 				{"state", "0"},
 			},
 			Points: []pb.Point{
-				{now - 2, 5},
-				{now - 1, 1},
-				{now, 3},
+				{T: now - 2, V: 5},
+				{T: now - 1, V: 1},
+				{T: now, V: 3},
 			},
 		}},
 	}
@@ -135,8 +141,8 @@ func TestBaudClient_WriteAndQuery(t *testing.T) {
 					{"state", "0"},
 				},
 				Points: []pb.Point{
-					{now - 1, 1},
-					{now, 3},
+					{T: now - 1, V: 1},
+					{T: now, V: 3},
 				},
 			}},
 		}
@@ -174,3 +180,65 @@ func TestBaudClient_WriteAndQuery(t *testing.T) {
 
 	t.Log(response.Result)
 }
+
+func TestClient_FrameCheck(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	ln, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			c, err := ln.AcceptTCP()
+			if err != nil {
+				return
+			}
+			conn, _ := tcp.WrapConn(c, true)
+			loop := tcp.NewReadWriteLoop(conn, tcp.RoleServer, func(ctx context.Context, in tcp.Message, b []byte) tcp.Message {
+				resp := &backendpb.SelectResponse{Status: pb.StatusCode_Succeed}
+				for i := 0; i < 10; i++ {
+					resp.Series = append(resp.Series, &pb.Series{Labels: []pb.Label{{Name: "__name__", Value: "up"}}})
+				}
+				return tcp.Message{Opaque: in.GetOpaque(), Message: resp}
+			})
+			go loop.LoopRead()
+			go loop.LoopWrite()
+		}
+	}()
+
+	cli := NewBackendClient("frame_check_test", ln.Addr().String(), 1, 1)
+	defer cli.Close()
+
+	errTooLarge := errors.New("too large")
+	check := func(max int) FrameCheck {
+		return func(msgType tcp.MsgType, proto []byte) error {
+			if n, err := backendpb.CountSeries(proto); err != nil || n > max {
+				return errTooLarge
+			}
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := cli.SyncRequest(WithFrameCheck(ctx, check(10)), &backendpb.SelectRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(resp.(*backendpb.SelectResponse).Series); got != 10 {
+		t.Fatalf("want 10 series, got %d", got)
+	}
+
+	pool := cli.connPool.(*HostConnPool)
+	conn := pool.conns[0]
+	if _, err = cli.SyncRequest(WithFrameCheck(ctx, check(5)), &backendpb.SelectRequest{}); err != errTooLarge {
+		t.Fatalf("want %v, got %v", errTooLarge, err)
+	}
+	if !conn.isClosed() {
+		t.Fatalf("want the connection closed once a frame is refused")
+	}
+}
@@ -17,13 +17,15 @@ package client
 
 import (
 	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
 	"sync"
-	"sync/atomic"
-	"unsafe"
 )
 
+var errPoolClosed = errors.New("connection pool is closed")
+
 type ConnPool interface {
 	GetConn() (*Conn, error)
+	PutConn(c *Conn)
 	Destroy(c *Conn) error
 	Close() error
 }
@@ -64,6 +66,9 @@ func (pool *ServiceConnPool) GetConn() (*Conn, error) {
 	return c.(*Conn), nil
 }
 
+// PutConn does nothing, a connection to a service is shared by all the requests to it.
+func (pool *ServiceConnPool) PutConn(c *Conn) {}
+
 func (pool *ServiceConnPool) Destroy(c *Conn) error {
 	pool.conns.Delete(c.address)
 	if pool.onClose != nil {
@@ -86,12 +91,21 @@ func (pool *ServiceConnPool) Close() error {
 	return multiErr
 }
 
+// HostConnPool keeps at most maxOpen connections to a host, the requests are multiplexed on them.
+// A connection is borrowed by GetConn for a request in flight and given back by PutConn, an idle one
+// (no request in flight) is preferred, and a new one is opened only if none is idle. Once all the
+// maxOpen connections are busy, the least loaded one is shared, which is counted as a wait. A connection is
+// opened without holding the pool, so that the requests sharing the ones open aren't held up by a slow dial.
 type HostConnPool struct {
-	//do not use sync.Pool
-	iter    uint64
-	size    int
-	conns   []*Conn
-	address string
+	mtx      sync.Mutex
+	conns    []*Conn
+	dialing  int        // connections being opened, which count against maxOpen
+	dialDone *sync.Cond // on mtx, see dialed
+	maxOpen  int
+	maxIdle  int // idle connections over it are closed on PutConn, 0 keeps them all
+	address  string
+	closed   bool
+	metrics  *poolMetrics
 
 	new       func(address string) (*Conn, error)
 	onConnect func(*Conn)
@@ -99,42 +113,141 @@ type HostConnPool struct {
 }
 
 func (pool *HostConnPool) GetConn() (*Conn, error) {
-	iter := atomic.AddUint64(&pool.iter, 1) % uint64(pool.size)
-	c := (*Conn)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&pool.conns[iter]))))
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
 
-	if c != nil && !c.isClosed() {
-		return c, nil
-	}
+	dialFailed := false
+	for {
+		if pool.closed {
+			return nil, errPoolClosed
+		}
 
-	newc, err := pool.new(pool.address)
-	if err != nil {
-		return nil, err
+		least := pool.leastLoaded()
+		canDial := !dialFailed && len(pool.conns)+pool.dialing < pool.maxOpen
+		if least != nil && (least.inflight == 0 || !canDial) {
+			pool.borrow(least)
+			return least, nil
+		}
+		if !canDial { // the last connections allowed are being opened by others
+			pool.dialed().Wait()
+			continue
+		}
+
+		// the slot is reserved while the connection is opened without the lock
+		pool.dialing++
+		pool.mtx.Unlock()
+		newc, err := pool.new(pool.address)
+		pool.mtx.Lock()
+		pool.dialing--
+		pool.dialed().Broadcast()
+
+		if err != nil {
+			if least == nil {
+				return nil, err
+			}
+			dialFailed = true // share a busy one
+			continue
+		}
+		if pool.closed {
+			newc.close()
+			return nil, errPoolClosed
+		}
+
+		if pool.onConnect != nil {
+			pool.onConnect(newc)
+		}
+		pool.add(newc)
+		pool.borrow(newc)
+		return newc, nil
 	}
-	if pool.onConnect != nil {
-		pool.onConnect(newc)
+}
+
+// leastLoaded returns the connection having the least requests in flight, the closed ones are dropped.
+func (pool *HostConnPool) leastLoaded() *Conn {
+	var least *Conn
+	for i := 0; i < len(pool.conns); {
+		c := pool.conns[i]
+		if c.isClosed() { // health check, e.g. the peer has closed it
+			pool.remove(c)
+			continue
+		}
+		if least == nil || c.inflight < least.inflight {
+			least = c
+		}
+		i++
 	}
+	return least
+}
 
-	if atomic.CompareAndSwapPointer((*unsafe.Pointer)(unsafe.Pointer(&pool.conns[iter])), unsafe.Pointer(c), unsafe.Pointer(newc)) {
-		c = newc
+func (pool *HostConnPool) borrow(c *Conn) {
+	if c.inflight > 0 {
+		pool.metrics.waits.Inc()
 	} else {
-		newc.close()
-		c = (*Conn)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&pool.conns[iter]))))
+		pool.metrics.idle.Dec()
+		pool.metrics.inUse.Inc()
 	}
+	c.inflight++
+}
 
-	return c, nil
+// dialed returns the cond signaled once a connection being opened is done, whether it's opened or not.
+func (pool *HostConnPool) dialed() *sync.Cond {
+	if pool.dialDone == nil {
+		pool.dialDone = sync.NewCond(&pool.mtx)
+	}
+	return pool.dialDone
+}
+
+func (pool *HostConnPool) PutConn(c *Conn) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	c.inflight--
+	if !c.pooled || c.inflight > 0 {
+		return
+	}
+
+	pool.metrics.inUse.Dec()
+	pool.metrics.idle.Inc()
+
+	if pool.maxIdle > 0 {
+		idle := 0
+		for _, conn := range pool.conns {
+			if conn.inflight == 0 {
+				idle++
+			}
+		}
+		if idle > pool.maxIdle {
+			pool.remove(c)
+			go c.closeGraceful() // an async request may be still queued on it
+		}
+	}
 }
 
 func (pool *HostConnPool) Destroy(c *Conn) (err error) {
+	pool.mtx.Lock()
+	if c.pooled {
+		pool.remove(c)
+	}
+	pool.mtx.Unlock()
+
 	if pool.onClose != nil {
 		pool.onClose(c)
 	}
 	return c.close()
 }
 
+// Close closes all the connections, the pool is not usable any more.
 func (pool *HostConnPool) Close() error {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	pool.closed = true
+
 	var multiErr error
-	for _, c := range pool.conns {
-		if c != nil && !c.isClosed() {
+	for len(pool.conns) > 0 {
+		c := pool.conns[0]
+		pool.remove(c)
+		if !c.isClosed() {
 			if err := c.close(); err != nil {
 				multiErr = multierror.Append(multiErr, err)
 			}
@@ -142,3 +255,25 @@ func (pool *HostConnPool) Close() error {
 	}
 	return multiErr
 }
+
+func (pool *HostConnPool) add(c *Conn) {
+	c.pooled = true
+	pool.conns = append(pool.conns, c)
+	pool.metrics.idle.Inc()
+}
+
+func (pool *HostConnPool) remove(c *Conn) {
+	for i, conn := range pool.conns {
+		if conn == c {
+			pool.conns = append(pool.conns[:i], pool.conns[i+1:]...)
+			break
+		}
+	}
+
+	c.pooled = false
+	if c.inflight > 0 {
+		pool.metrics.inUse.Dec()
+	} else {
+		pool.metrics.idle.Dec()
+	}
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"net"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func metricValue(g interface{ Write(*dto.Metric) error }) float64 {
+	m := new(dto.Metric)
+	g.Write(m)
+	if m.Gauge != nil {
+		return m.Gauge.GetValue()
+	}
+	return m.Counter.GetValue()
+}
+
+func TestHostConnPool(t *testing.T) {
+	ln, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		var accepted []net.Conn
+		defer func() {
+			for _, c := range accepted {
+				c.Close()
+			}
+		}()
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted = append(accepted, c)
+		}
+	}()
+
+	dials := 0
+	pool := &HostConnPool{
+		maxOpen: 2,
+		maxIdle: 1,
+		address: ln.Addr().String(),
+		metrics: newPoolMetrics("pool_test"),
+		new: func(address string) (*Conn, error) {
+			dials++
+			return newConn(address)
+		},
+	}
+	defer pool.Close()
+
+	get := func() *Conn {
+		c, err := pool.GetConn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	c1 := get()
+	c2 := get()
+	if c1 == c2 || dials != 2 {
+		t.Fatalf("want a connection opened for each busy one, got %d dials", dials)
+	}
+
+	// all the connections allowed are busy, the least loaded one is shared
+	c3 := get()
+	if dials != 2 || (c3 != c1 && c3 != c2) {
+		t.Fatalf("want a busy connection shared, got %d dials", dials)
+	}
+	if waits := metricValue(pool.metrics.waits); waits != 1 {
+		t.Fatalf("want 1 wait, got %v", waits)
+	}
+	if inUse, idle := metricValue(pool.metrics.inUse), metricValue(pool.metrics.idle); inUse != 2 || idle != 0 {
+		t.Fatalf("want 2 connections in use and none idle, got %v and %v", inUse, idle)
+	}
+
+	pool.PutConn(c3)
+	pool.PutConn(c2)
+	if c := get(); c != c2 && c != c1 {
+		t.Fatalf("want an idle connection reused")
+	} else {
+		pool.PutConn(c)
+	}
+	pool.PutConn(c1)
+
+	// only maxIdle connections are kept once they are all idle
+	if len(pool.conns) != 1 {
+		t.Fatalf("want 1 idle connection kept, got %d", len(pool.conns))
+	}
+	if inUse, idle := metricValue(pool.metrics.inUse), metricValue(pool.metrics.idle); inUse != 0 || idle != 1 {
+		t.Fatalf("want 1 idle connection, got %v in use and %v idle", inUse, idle)
+	}
+
+	// a broken connection is dropped on borrow
+	broken := pool.conns[0]
+	broken.close()
+	if c := get(); c == broken || dials != 3 {
+		t.Fatalf("want the broken connection replaced, got %d dials", dials)
+	} else {
+		pool.PutConn(c)
+	}
+
+	pool.Close()
+	if _, err = pool.GetConn(); err != errPoolClosed {
+		t.Fatalf("want %v, got %v", errPoolClosed, err)
+	}
+	if inUse, idle := metricValue(pool.metrics.inUse), metricValue(pool.metrics.idle); inUse != 0 || idle != 0 {
+		t.Fatalf("want no connection left, got %v in use and %v idle", inUse, idle)
+	}
+}
+
+func TestHostConnPool_DialWithoutLock(t *testing.T) {
+	ln, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	dialing, unblock := make(chan struct{}, 1), make(chan struct{})
+	slow := false
+	pool := &HostConnPool{
+		maxOpen: 2,
+		address: ln.Addr().String(),
+		metrics: newPoolMetrics("pool_dial_test"),
+		new: func(address string) (*Conn, error) {
+			if slow {
+				dialing <- struct{}{}
+				<-unblock
+			}
+			return newConn(address)
+		},
+	}
+	defer pool.Close()
+
+	c1, err := pool.GetConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slow = true
+	opened := make(chan *Conn)
+	go func() {
+		c, err := pool.GetConn()
+		if err != nil {
+			t.Error(err)
+		}
+		opened <- c
+	}()
+	<-dialing
+
+	// the slot of the connection being opened is reserved, the busy one is shared meanwhile
+	if c, err := pool.GetConn(); err != nil || c != c1 {
+		t.Fatalf("want the busy connection shared while another is opened, got %v, %v", c, err)
+	}
+
+	close(unblock)
+	if c2 := <-opened; c2 == nil || c2 == c1 {
+		t.Fatalf("want a new connection opened, got %v", c2)
+	}
+	if len(pool.conns) != 2 {
+		t.Fatalf("want 2 connections, got %d", len(pool.conns))
+	}
+}
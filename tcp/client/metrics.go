@@ -0,0 +1,55 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	poolConns *prometheus.GaugeVec
+	poolWaits *prometheus.CounterVec
+)
+
+// poolMetrics are the metrics of the connection pool to one host.
+type poolMetrics struct {
+	idle  prometheus.Gauge
+	inUse prometheus.Gauge
+	waits prometheus.Counter
+}
+
+func init() {
+	poolConns = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "baudtime",
+		Subsystem: "conn_pool",
+		Name:      "connections",
+		Help:      "Number of pooled connections, in use ones have requests in flight.",
+	}, []string{"address", "state"})
+	poolWaits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "baudtime",
+		Subsystem: "conn_pool",
+		Name:      "waits_total",
+		Help:      "Total number of requests sharing a busy connection as all the connections allowed are busy.",
+	}, []string{"address"})
+
+	prometheus.MustRegister(poolConns, poolWaits)
+}
+
+func newPoolMetrics(address string) *poolMetrics {
+	return &poolMetrics{
+		idle:  poolConns.WithLabelValues(address, "idle"),
+		inUse: poolConns.WithLabelValues(address, "in_use"),
+		waits: poolWaits.WithLabelValues(address),
+	}
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+type CompressType uint8
+
+const (
+	CompressNone CompressType = iota
+	CompressSnappy
+	CompressGzip
+	CompressZstd
+)
+
+var BadCompressTypeError = errors.New("bad compress type")
+
+func (t CompressType) String() string {
+	switch t {
+	case CompressNone:
+		return "none"
+	case CompressSnappy:
+		return "snappy"
+	case CompressGzip:
+		return "gzip"
+	case CompressZstd:
+		return "zstd"
+	}
+	return "unknown"
+}
+
+func ParseCompressType(s string) (CompressType, error) {
+	switch strings.ToLower(s) {
+	case "", "none":
+		return CompressNone, nil
+	case "snappy":
+		return CompressSnappy, nil
+	case "gzip":
+		return CompressGzip, nil
+	case "zstd":
+		return CompressZstd, nil
+	}
+	return CompressNone, errors.Errorf("unknown compression %q", s)
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+var gzipReaderPool sync.Pool
+
+// zstd encoders by level and decoders by the max decoded size, both can be used concurrently.
+var zstdEncoders, zstdDecoders sync.Map
+
+func zstdEncoder(level int) (*zstd.Encoder, error) {
+	if e, ok := zstdEncoders.Load(level); ok {
+		return e.(*zstd.Encoder), nil
+	}
+
+	opts := []zstd.EOption{zstd.WithEncoderConcurrency(1)}
+	if level > 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	}
+	e, err := zstd.NewWriter(nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, loaded := zstdEncoders.LoadOrStore(level, e)
+	if loaded {
+		e.Close()
+	}
+	return actual.(*zstd.Encoder), nil
+}
+
+func zstdDecoder(maxSize int) (*zstd.Decoder, error) {
+	if d, ok := zstdDecoders.Load(maxSize); ok {
+		return d.(*zstd.Decoder), nil
+	}
+
+	d, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(maxSize)))
+	if err != nil {
+		return nil, err
+	}
+
+	actual, loaded := zstdDecoders.LoadOrStore(maxSize, d)
+	if loaded {
+		d.Close()
+	}
+	return actual.(*zstd.Decoder), nil
+}
+
+// compress appends the compressed form of src to dst[:0], level is only for zstd, 0 takes its default.
+func compress(t CompressType, level int, dst, src []byte) ([]byte, error) {
+	switch t {
+	case CompressNone:
+		return append(dst[:0], src...), nil
+	case CompressSnappy:
+		return snappy.Encode(dst[:cap(dst)], src), nil
+	case CompressGzip:
+		buf := bytes.NewBuffer(dst[:0])
+		w := gzipWriterPool.Get().(*gzip.Writer)
+		defer gzipWriterPool.Put(w)
+
+		w.Reset(buf)
+		if _, err := w.Write(src); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressZstd:
+		e, err := zstdEncoder(level)
+		if err != nil {
+			return nil, err
+		}
+		return e.EncodeAll(src, dst[:0]), nil
+	}
+	return nil, BadCompressTypeError
+}
+
+// decompress appends the decompressed form of src to dst[:0], the result can't exceed the limit of message size.
+func decompress(t CompressType, dst, src []byte) ([]byte, error) {
+	switch t {
+	case CompressNone:
+		return append(dst[:0], src...), nil
+	case CompressSnappy:
+		n, err := snappy.DecodedLen(src)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxMsgSize() {
+			return nil, MsgSizeOverflow
+		}
+		return snappy.Decode(dst[:cap(dst)], src)
+	case CompressGzip:
+		var (
+			r   *gzip.Reader
+			err error
+		)
+		if v := gzipReaderPool.Get(); v != nil {
+			r = v.(*gzip.Reader)
+			err = r.Reset(bytes.NewReader(src))
+		} else {
+			r, err = gzip.NewReader(bytes.NewReader(src))
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReaderPool.Put(r)
+
+		buf := bytes.NewBuffer(dst[:0])
+		n, err := buf.ReadFrom(io.LimitReader(r, int64(maxMsgSize())+1))
+		if err != nil {
+			return nil, err
+		}
+		if n > int64(maxMsgSize()) {
+			return nil, MsgSizeOverflow
+		}
+		return buf.Bytes(), nil
+	case CompressZstd:
+		d, err := zstdDecoder(maxMsgSize())
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.DecodeAll(src, dst[:0])
+		if err == zstd.ErrDecoderSizeExceeded {
+			return nil, MsgSizeOverflow
+		}
+		return b, err
+	}
+	return nil, BadCompressTypeError
+}
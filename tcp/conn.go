@@ -17,20 +17,37 @@ package tcp
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
 	"encoding/binary"
 	"io"
 	"net"
 	"os"
 	"syscall"
 	"time"
+
+	. "github.com/baudtime/baudtime/vars"
 )
 
 type Conn struct {
 	reader *bufio.Reader
 	writer *bufio.Writer
 	*net.TCPConn
-	rBuf []byte
-	wBuf []byte
+	tlsConn *tls.Conn
+	file    *os.File // the dup of the fd read and written by a plain conn, see NewConn
+	rBuf    []byte
+	wBuf    []byte
+}
+
+// remoteAddr returns the address of the peer, empty if it's unknown.
+func (c *Conn) remoteAddr() string {
+	if c == nil || c.TCPConn == nil {
+		return ""
+	}
+	if addr := c.RemoteAddr(); addr != nil {
+		return addr.String()
+	}
+	return ""
 }
 
 func NewConn(c *net.TCPConn) *Conn {
@@ -48,30 +65,112 @@ func NewConn(c *net.TCPConn) *Conn {
 		reader:  bufio.NewReaderSize(rw, 1e5), // We make a buffered reader & writer to reduce syscalls.
 		writer:  bufio.NewWriterSize(rw, 1e4),
 		TCPConn: c,
+		file:    f,
 		rBuf:    make([]byte, 4),
 		wBuf:    make([]byte, 4),
 	}
 }
 
-func Connect(address string) (*Conn, error) {
-	addr, err := net.ResolveTCPAddr("tcp", address)
+// NewTLSConn returns a Conn reading and writing through TLS over c, the handshake
+// is performed on the first read or write.
+func NewTLSConn(c *net.TCPConn, config *tls.Config, server bool) *Conn {
+	var tlsConn *tls.Conn
+	if server {
+		tlsConn = tls.Server(c, config)
+	} else {
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(c.RemoteAddr().String())
+		}
+		tlsConn = tls.Client(c, config)
+	}
+
+	return &Conn{
+		reader:  bufio.NewReaderSize(tlsConn, 1e5),
+		writer:  bufio.NewWriterSize(tlsConn, 1e4),
+		TCPConn: c,
+		tlsConn: tlsConn,
+		rBuf:    make([]byte, 4),
+		wBuf:    make([]byte, 4),
+	}
+}
+
+// WrapConn returns a Conn over c, with TLS if it's configured.
+func WrapConn(c *net.TCPConn, server bool) (*Conn, error) {
+	if Cfg.TLS == nil {
+		return NewConn(c), nil
+	}
+
+	serverConfig, clientConfig, err := loadTLSConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	c, err := net.DialTCP("tcp4", nil, addr)
+	if server {
+		return NewTLSConn(c, serverConfig, true), nil
+	}
+	return NewTLSConn(c, clientConfig, false), nil
+}
+
+// DefaultDialTimeout is how long dialing a peer may take if it's not configured.
+const DefaultDialTimeout = 2 * time.Second
+
+// DialTimeout returns the configured timeout of dialing a peer, DefaultDialTimeout by default.
+func DialTimeout() time.Duration {
+	if Cfg.DialTimeout > 0 {
+		return time.Duration(Cfg.DialTimeout)
+	}
+	return DefaultDialTimeout
+}
+
+// Connect dials address, it gives up after DialTimeout.
+func Connect(address string) (*Conn, error) {
+	return ConnectContext(context.Background(), address)
+}
+
+// ConnectContext dials address, it gives up once ctx is done or after DialTimeout, whichever
+// comes first, so that a black-holed peer doesn't hang the caller until the os gives up.
+func ConnectContext(ctx context.Context, address string) (*Conn, error) {
+	dialer := &net.Dialer{Timeout: DialTimeout()}
+
+	nc, err := dialer.DialContext(ctx, "tcp", address)
 	if err != nil {
 		return nil, err
 	}
 
+	c := nc.(*net.TCPConn)
 	c.SetNoDelay(true)
 	c.SetKeepAlive(true)
 	c.SetKeepAlivePeriod(60 * time.Second)
 
-	return NewConn(c), nil
+	conn, err := WrapConn(c, false)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return conn, nil
 }
 
 func (c *Conn) ReadMsg(buf []byte) (int, error) {
+	msgLen, err := c.readMsgLen()
+	if err != nil {
+		return 0, err
+	}
+	if msgLen > len(buf) {
+		return 0, &ErrMsgTooLarge{Size: msgLen, Limit: len(buf)}
+	}
+
+	_, err = io.ReadFull(c.reader, buf[:msgLen])
+	if err != nil {
+		return 0, err
+	}
+
+	return msgLen, nil
+}
+
+// readMsgLen reads the length prefix of the next message, the message itself is
+// left in the reader, so that the caller can read it into a buffer of the right size.
+func (c *Conn) readMsgLen() (int, error) {
 	_, err := io.ReadFull(c.reader, c.rBuf)
 	if err != nil {
 		return 0, err
@@ -79,16 +178,32 @@ func (c *Conn) ReadMsg(buf []byte) (int, error) {
 
 	//read message length
 	msgLen := int(binary.BigEndian.Uint32(c.rBuf))
-	if msgLen <= 0 || msgLen > MaxMsgSize {
+	if msgLen <= 0 {
 		return 0, io.ErrUnexpectedEOF
 	}
+	if limit := maxMsgSize(); msgLen > limit {
+		return 0, &ErrMsgTooLarge{Size: msgLen, Limit: limit}
+	}
 
-	_, err = io.ReadFull(c.reader, buf[:msgLen])
+	return msgLen, nil
+}
+
+// readMsgPooled reads the next message into a buffer got from bytesPool, it's up to
+// the caller to put the buffer back once done with it.
+func (c *Conn) readMsgPooled() ([]byte, error) {
+	msgLen, err := c.readMsgLen()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return msgLen, nil
+	buf := bytesPool.Get(msgLen).([]byte)[:msgLen]
+	_, err = io.ReadFull(c.reader, buf)
+	if err != nil {
+		bytesPool.Put(buf)
+		return nil, err
+	}
+
+	return buf, nil
 }
 
 func (c *Conn) WriteMsg(msg []byte) error {
@@ -104,10 +219,42 @@ func (c *Conn) WriteMsg(msg []byte) error {
 	return err
 }
 
+// writeFrames writes messages already framed with their length prefixes, e.g. by
+// ReadWriteLoop.WriteBatch, in one write.
+func (c *Conn) writeFrames(frames []byte) error {
+	_, err := c.writer.Write(frames)
+	return err
+}
+
 func (c *Conn) Flush() error {
 	return c.writer.Flush()
 }
 
+// Secured reports whether c is over TLS, a tls conn is not usable any more after
+// an error (e.g. a failed handshake).
+func (c *Conn) Secured() bool {
+	return c.tlsConn != nil
+}
+
+func (c *Conn) CloseWrite() error {
+	if c.tlsConn != nil {
+		return c.tlsConn.CloseWrite()
+	}
+	return c.TCPConn.CloseWrite()
+}
+
+func (c *Conn) Close() error {
+	if c.tlsConn != nil {
+		return c.tlsConn.Close()
+	}
+	// the dup is shut down and closed too, or the socket stays open and a read blocked on it never returns
+	if c.file != nil {
+		syscall.Shutdown(int(c.file.Fd()), syscall.SHUT_RDWR)
+		defer c.file.Close()
+	}
+	return c.TCPConn.Close()
+}
+
 type readWriter struct {
 	fd int
 	f  *os.File
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/util/toml"
+	"github.com/baudtime/baudtime/vars"
+)
+
+func TestConnect_Timeout(t *testing.T) {
+	dialTimeout := vars.Cfg.DialTimeout
+	vars.Cfg.DialTimeout = toml.Duration(200 * time.Millisecond)
+	defer func() {
+		vars.Cfg.DialTimeout = dialTimeout
+	}()
+
+	const unroutable = "[100::1]:8088" // in the discard-only prefix, packets to it are dropped rather than refused
+
+	start := time.Now()
+	if conn, err := Connect(unroutable); err == nil {
+		conn.Close()
+		t.Fatalf("expected dialing an unroutable address to fail")
+	}
+	if took := time.Since(start); took > 2*time.Second {
+		t.Fatalf("want dialing given up after the dial timeout, took %v", took)
+	}
+
+	vars.Cfg.DialTimeout = toml.Duration(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start = time.Now()
+	if conn, err := ConnectContext(ctx, unroutable); err == nil {
+		conn.Close()
+		t.Fatalf("expected dialing an unroutable address to fail")
+	}
+	if took := time.Since(start); took > 2*time.Second {
+		t.Fatalf("want dialing given up on the deadline of the context, took %v", took)
+	}
+
+	if DialTimeout() != time.Minute {
+		t.Fatalf("want the configured dial timeout, got %v", DialTimeout())
+	}
+	vars.Cfg.DialTimeout = 0
+	if DialTimeout() != DefaultDialTimeout {
+		t.Fatalf("want the default dial timeout, got %v", DialTimeout())
+	}
+}
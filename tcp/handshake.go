@@ -0,0 +1,156 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/baudtime/baudtime/msg"
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/msg/pb/gateway"
+	"github.com/pkg/errors"
+)
+
+// ProtocolVersion is the version of the wire protocol announced in a Hello, a peer sending no Hello is of version 0.
+const ProtocolVersion uint32 = 1
+
+// Feature is a bit of the feature set a peer announces in its Hello.
+type Feature uint64
+
+const (
+	// FeatureCompress means the peer decodes compressed messages.
+	FeatureCompress Feature = 1 << iota
+	// FeatureHistogram means the peer understands native histogram samples.
+	FeatureHistogram
+	// FeatureZstd means the peer decodes messages compressed by zstd.
+	FeatureZstd
+)
+
+// localFeatures are the features supported by this side.
+const localFeatures = FeatureCompress | FeatureHistogram | FeatureZstd
+
+// handshakeTimeout is how long the side sending a Hello waits for the answer before taking the peer as version 0.
+var handshakeTimeout = 3 * time.Second
+
+var ErrFeatureUnsupported = errors.New("feature is not supported by the peer")
+
+// Handshake announces the protocol version and features of this side to the peer, which answers with its own,
+// the loop then uses the features supported by both sides. It should be called by the side opening the connection.
+// Until the answer arrives, or if it never does because the peer is of version 0, no optional feature is used.
+func (loop *ReadWriteLoop) Handshake() error {
+	if !atomic.CompareAndSwapUint32(&loop.helloSent, 0, 1) {
+		return nil
+	}
+	time.AfterFunc(handshakeTimeout, loop.settle)
+
+	h := hello()
+	h.Compress = uint32(loop.codec.Compress)
+	return loop.Write(Message{Message: h})
+}
+
+// Features returns the features negotiated with the peer.
+func (loop *ReadWriteLoop) Features() Feature {
+	return Feature(atomic.LoadUint64(&loop.features))
+}
+
+func (loop *ReadWriteLoop) Supports(f Feature) bool {
+	return loop.Features()&f == f
+}
+
+// PeerVersion returns the protocol version of the peer, 0 until its Hello arrives.
+func (loop *ReadWriteLoop) PeerVersion() uint32 {
+	return atomic.LoadUint32(&loop.peerVersion)
+}
+
+func hello() *pb.ConnCtrl {
+	return &pb.ConnCtrl{Code: pb.CtrlCode_Hello, Version: ProtocolVersion, Features: uint64(localFeatures)}
+}
+
+// onHello records the features in common with the peer, and answers with a Hello if this side didn't send one.
+// The answering side also takes the codec preferred by the opening side, e.g. zstd for a replication stream.
+func (loop *ReadWriteLoop) onHello(peer *pb.ConnCtrl) {
+	atomic.StoreUint32(&loop.peerVersion, peer.Version)
+	atomic.StoreUint64(&loop.features, peer.Features&uint64(localFeatures))
+
+	if atomic.CompareAndSwapUint32(&loop.helloSent, 0, 1) {
+		if compress := CompressType(peer.Compress); compress != CompressNone && compress <= CompressZstd && compress != loop.codec.Compress {
+			preferred := loop.codec
+			preferred.Compress = compress
+			loop.preferred.Store(&preferred)
+		}
+		loop.Write(Message{Message: hello()})
+	}
+	loop.settle()
+}
+
+func (loop *ReadWriteLoop) settle() {
+	loop.settleOnce.Do(func() {
+		close(loop.negotiated)
+	})
+}
+
+// encoder returns the codec to encode the outgoing messages with, which compresses only if the peer can decode it.
+func (loop *ReadWriteLoop) encoder() *MsgCodec {
+	codec := &loop.codec
+	if preferred, ok := loop.preferred.Load().(*MsgCodec); ok {
+		codec = preferred
+	}
+
+	switch {
+	case codec.Compress == CompressNone || !loop.Supports(FeatureCompress):
+		return &plainCodec
+	case codec.Compress == CompressZstd && !loop.Supports(FeatureZstd):
+		return &loop.fallback
+	}
+	return codec
+}
+
+// plainCodec frames every message as the peers not knowing of compression do, see MsgCodec.
+var plainCodec = MsgCodec{}
+
+// checkFeatures returns an error if m uses a feature the peer doesn't support, it waits for the answer
+// to the Hello of this side first, so that the messages written right after Handshake aren't refused.
+func (loop *ReadWriteLoop) checkFeatures(m msg.Message) error {
+	if !hasHistograms(m) {
+		return nil
+	}
+
+	if atomic.LoadUint32(&loop.helloSent) == 1 {
+		select {
+		case <-loop.negotiated:
+		case <-loop.done:
+		}
+	}
+
+	if !loop.Supports(FeatureHistogram) {
+		return errors.Wrapf(ErrFeatureUnsupported, "native histograms to %v of protocol version %d", loop.conn.RemoteAddr(), loop.PeerVersion())
+	}
+	return nil
+}
+
+func hasHistograms(m msg.Message) bool {
+	switch m := m.(type) {
+	case *backend.AddRequest:
+		return pb.HasHistograms(m.Series)
+	case *backend.SelectResponse:
+		return pb.HasHistograms(m.Series)
+	case *gateway.AddRequest:
+		return pb.HasHistograms(m.Series)
+	}
+	return false
+}
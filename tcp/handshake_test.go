@@ -0,0 +1,235 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/util/toml"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+)
+
+func histogramAdd() *backendpb.AddRequest {
+	return &backendpb.AddRequest{Series: []*pb.Series{{
+		Labels: []pb.Label{{Name: "__name__", Value: "latency"}},
+		Points: []pb.Point{{T: 1, H: &pb.Histogram{Count: 1, Sum: 0.5}}},
+	}}}
+}
+
+func writeRaw(t *testing.T, conn *Conn, m Message) {
+	codec := MsgCodec{}
+	b := make([]byte, 2+binary.MaxVarintLen64+m.SizeOfRaw())
+	n, err := codec.Encode(m, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.WriteMsg(b[:n]); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readRaw(t *testing.T, conn *Conn) *Message {
+	codec := MsgCodec{}
+	buf := make([]byte, MaxMsgSize)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.ReadMsg(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := codec.Decode(buf[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &m
+}
+
+// readBaseline reads a message framed as by a peer not knowing of compression, [type][opaque][proto].
+func readBaseline(t *testing.T, conn *Conn) *Message {
+	buf := make([]byte, MaxMsgSize)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.ReadMsg(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := Make(MsgType(buf[0]))
+	if raw == nil {
+		t.Fatalf("want a message of a known type in the plain framing, got type %d", buf[0])
+	}
+	opaque, l := binary.Uvarint(buf[1:n])
+	if l <= 0 {
+		t.Fatalf("bad opaque in % x", buf[:n])
+	}
+	if err = raw.Unmarshal(buf[1+l : n]); err != nil {
+		t.Fatalf("want a message in the plain framing, got %v", err)
+	}
+	return &Message{Opaque: opaque, Message: raw}
+}
+
+func TestReadWriteLoop_Handshake(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	compression, threshold := vars.Cfg.Compression, vars.Cfg.CompressionThreshold
+	vars.Cfg.Compression, vars.Cfg.CompressionThreshold = "snappy", toml.Size(1)
+	timeout := handshakeTimeout
+	handshakeTimeout = 50 * time.Millisecond
+	defer func() {
+		vars.Cfg.Compression, vars.Cfg.CompressionThreshold = compression, threshold
+		handshakeTimeout = timeout
+	}()
+
+	received := make(chan Message, 1)
+	handle := func(ctx context.Context, in Message, inBytes []byte) Message {
+		switch req := in.GetRaw().(type) {
+		case *backendpb.AddRequest:
+			received <- in
+		case *backendpb.SelectRequest:
+			return Message{Opaque: in.GetOpaque(), Message: &backendpb.SelectResponse{Status: pb.StatusCode_Succeed, Series: histogramAdd().Series}}
+		default:
+			t.Errorf("unexpected request %T", req)
+		}
+		return EmptyMsg
+	}
+	start := func(loop *ReadWriteLoop) *ReadWriteLoop {
+		go loop.LoopRead()
+		go loop.LoopWrite()
+		return loop
+	}
+
+	// both sides are of the current version
+	clientConn, serverConn := tcpPair(t)
+	client := start(NewReadWriteLoop(NewConn(clientConn), RoleClient, handle))
+	server := start(NewReadWriteLoop(NewConn(serverConn), RoleServer, handle))
+	if err := client.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Write(Message{Opaque: 1, Message: histogramAdd()}); err != nil {
+		t.Fatalf("unexpected error writing histograms to a peer supporting them: %v", err)
+	}
+	select {
+	case in := <-received:
+		if !pb.HasHistograms(in.GetRaw().(*backendpb.AddRequest).Series) {
+			t.Fatalf("histograms are lost on the way")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("request not received")
+	}
+	for _, loop := range []*ReadWriteLoop{client, server} {
+		if loop.Features() != localFeatures || loop.PeerVersion() != ProtocolVersion {
+			t.Fatalf("want features %b of version %d, got %b of version %d", localFeatures, ProtocolVersion, loop.Features(), loop.PeerVersion())
+		}
+		if loop.encoder() != &loop.codec {
+			t.Fatalf("want messages compressed once both sides support it")
+		}
+	}
+	client.Exit()
+	server.Exit()
+
+	// the server is of version 0, it ignores the Hello and never answers
+	clientConn, serverConn = tcpPair(t)
+	client = start(NewReadWriteLoop(NewConn(clientConn), RoleClient, handle))
+	oldServer := NewConn(serverConn)
+	if err := client.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	if hello := readBaseline(t, oldServer).GetRaw().(*pb.ConnCtrl); hello.Code != pb.CtrlCode_Hello {
+		t.Fatalf("want a Hello first, got %v", hello.Code)
+	}
+	if err := client.Write(Message{Opaque: 1, Message: histogramAdd()}); errors.Cause(err) != ErrFeatureUnsupported {
+		t.Fatalf("want histograms refused for a version 0 peer, got %v", err)
+	}
+	if client.Features() != 0 || client.encoder() != &plainCodec {
+		t.Fatalf("want no feature used with a version 0 peer, got %b", client.Features())
+	}
+	// a request large enough to be compressed is sent uncompressed, in the plain framing
+	add := makeAddRequest(100)
+	if err := client.Write(Message{Opaque: 2, Message: add}); err != nil {
+		t.Fatal(err)
+	}
+	if req := readBaseline(t, oldServer); req.GetOpaque() != 2 || len(req.GetRaw().(*backendpb.AddRequest).Series) != len(add.Series) {
+		t.Fatalf("want plain requests still sent, got opaque %d", req.GetOpaque())
+	}
+	client.Exit()
+	oldServer.Close()
+
+	// the client is of version 0, it sends no Hello
+	clientConn, serverConn = tcpPair(t)
+	oldClient := NewConn(clientConn)
+	server = start(NewReadWriteLoop(NewConn(serverConn), RoleServer, handle))
+	writeRaw(t, oldClient, Message{Opaque: 1, Message: &backendpb.SelectRequest{}})
+	if resp, ok := readBaseline(t, oldClient).GetRaw().(*pb.GeneralResponse); !ok || resp.Status != pb.StatusCode_Failed {
+		t.Fatalf("want histograms refused for a version 0 peer, got %v", resp)
+	}
+	oldClient.Close()
+	server.Exit()
+
+	// the client is of a version supporting only compression
+	clientConn, serverConn = tcpPair(t)
+	oldClient = NewConn(clientConn)
+	server = start(NewReadWriteLoop(NewConn(serverConn), RoleServer, handle))
+	writeRaw(t, oldClient, Message{Message: &pb.ConnCtrl{Code: pb.CtrlCode_Hello, Version: 1, Features: uint64(FeatureCompress)}})
+	if hello := readRaw(t, oldClient).GetRaw().(*pb.ConnCtrl); hello.Code != pb.CtrlCode_Hello || hello.Features != uint64(localFeatures) {
+		t.Fatalf("want the Hello answered with the features of the server, got %v", hello)
+	}
+	if server.Features() != FeatureCompress {
+		t.Fatalf("want only compression negotiated, got %b", server.Features())
+	}
+	oldClient.Close()
+	server.Exit()
+
+	// the replication stream prefers zstd, which the server takes for its responses
+	replication := vars.Cfg.ReplicationCompression
+	vars.Cfg.ReplicationCompression = "zstd"
+	defer func() {
+		vars.Cfg.ReplicationCompression = replication
+	}()
+	clientConn, serverConn = tcpPair(t)
+	client = start(NewReadWriteLoop(NewConn(clientConn), RoleReplication, handle))
+	server = start(NewReadWriteLoop(NewConn(serverConn), RoleServer, handle))
+	if err := client.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	<-client.negotiated
+	for _, loop := range []*ReadWriteLoop{client, server} {
+		if got := loop.encoder().Compress; got != CompressZstd {
+			t.Fatalf("want zstd used by both sides of a replication stream, got %v", got)
+		}
+	}
+	client.Exit()
+	server.Exit()
+
+	// the client prefers zstd but can't decode it, snappy is used instead
+	clientConn, serverConn = tcpPair(t)
+	oldClient = NewConn(clientConn)
+	server = start(NewReadWriteLoop(NewConn(serverConn), RoleServer, handle))
+	writeRaw(t, oldClient, Message{Message: &pb.ConnCtrl{Code: pb.CtrlCode_Hello, Version: 1, Features: uint64(FeatureCompress), Compress: uint32(CompressZstd)}})
+	readRaw(t, oldClient)
+	if got := server.encoder().Compress; got != CompressSnappy {
+		t.Fatalf("want snappy for a peer not decoding zstd, got %v", got)
+	}
+	oldClient.Close()
+	server.Exit()
+}
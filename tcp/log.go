@@ -0,0 +1,98 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+const loopLogInterval = 10 * time.Second
+
+// loopSeq numbers the loops of the process, the number identifies the connection of a loop in its log lines.
+var loopSeq uint64
+
+// newLoopLogger returns the logger of a loop, whose lines tell the connection, the peer and the role of it.
+// They're only logged, the metrics of the loop are labeled by the role alone, not to have a series per connection.
+func newLoopLogger(logger log.Logger, remoteAddr string, role string) log.Logger {
+	return log.With(logger, "conn", atomic.AddUint64(&loopSeq, 1), "remoteAddr", remoteAddr, "role", role)
+}
+
+// logLimiter lets the line of a key through once per interval at most.
+type logLimiter struct {
+	interval time.Duration
+	mtx      sync.Mutex
+	lines    map[string]*limitedLine
+}
+
+type limitedLine struct {
+	last    time.Time
+	dropped int
+}
+
+func newLogLimiter(interval time.Duration) *logLimiter {
+	return &logLimiter{interval: interval, lines: make(map[string]*limitedLine)}
+}
+
+// allow tells whether the line of key may be logged now, along with the number of the lines of key dropped
+// since the last one let through. The keys are meant to be a few constants, one per line logged.
+func (l *logLimiter) allow(key string) (bool, int) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := time.Now()
+	line, found := l.lines[key]
+	if !found {
+		l.lines[key] = &limitedLine{last: now}
+		return true, 0
+	}
+	if now.Sub(line.last) < l.interval {
+		line.dropped++
+		return false, 0
+	}
+
+	dropped := line.dropped
+	line.last, line.dropped = now, 0
+	return true, dropped
+}
+
+var nopLogger = log.NewNopLogger()
+
+// limited returns the logger of the loop if the line of key may be logged now, with the number of the lines
+// of key dropped before it if any, otherwise a logger dropping the line. Each loop limits its own lines, so
+// that a peer failing over and over doesn't flood the log, nor silence the lines of the other connections.
+func (loop *ReadWriteLoop) limited(key string) log.Logger {
+	ok, dropped := loop.logs.allow(key)
+	if !ok {
+		return nopLogger
+	}
+	if dropped > 0 {
+		return log.With(loop.logger, "dropped", dropped)
+	}
+	return loop.logger
+}
+
+// String returns the name of the message of t, e.g. "backend.SelectRequest".
+func (t MsgType) String() string {
+	if m := Make(t); m != nil {
+		return reflect.TypeOf(m).Elem().String()
+	}
+	return "bad"
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestLogLimiter(t *testing.T) {
+	l := newLogLimiter(50 * time.Millisecond)
+
+	tests := []struct {
+		key     string
+		sleep   time.Duration
+		allowed bool
+		dropped int
+	}{
+		{key: "read", allowed: true},
+		{key: "read", allowed: false},
+		{key: "read", allowed: false},
+		{key: "decode", allowed: true},
+		{key: "read", sleep: 60 * time.Millisecond, allowed: true, dropped: 2},
+		{key: "read", allowed: false},
+	}
+
+	for i, test := range tests {
+		time.Sleep(test.sleep)
+		if allowed, dropped := l.allow(test.key); allowed != test.allowed || dropped != test.dropped {
+			t.Fatalf("case %d: want allowed %v with %d dropped, got %v with %d", i, test.allowed, test.dropped, allowed, dropped)
+		}
+	}
+}
+
+func TestReadWriteLoop_LimitedPerLoop(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	handle := func(ctx context.Context, in Message, inBytes []byte) Message {
+		return EmptyMsg
+	}
+	var loops []*ReadWriteLoop
+	for i := 0; i < 2; i++ {
+		clientConn, serverConn := tcpPair(t)
+		defer clientConn.Close()
+		loop := NewReadWriteLoop(NewConn(serverConn), RoleServer, handle)
+		defer loop.Exit()
+		loops = append(loops, loop)
+	}
+
+	// a loop logging a line over and over doesn't silence the line of another
+	if loops[0].limited("read") == nopLogger {
+		t.Fatalf("want the first line of the first loop logged")
+	}
+	if loops[0].limited("read") != nopLogger {
+		t.Fatalf("want the second line of the first loop dropped")
+	}
+	if loops[1].limited("read") == nopLogger {
+		t.Fatalf("want the first line of the second loop logged")
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe to be logged to by the loops.
+type syncBuffer struct {
+	mtx sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.String()
+}
+
+func TestReadWriteLoop_LogFields(t *testing.T) {
+	logs := new(syncBuffer)
+	logger := vars.Logger
+	vars.Logger = log.NewLogfmtLogger(logs)
+	defer func() {
+		vars.Logger = logger
+	}()
+
+	clientConn, serverConn := tcpPair(t)
+	client := NewConn(clientConn)
+	defer client.Close()
+
+	loop := NewReadWriteLoop(NewConn(serverConn), RoleServer, func(ctx context.Context, in Message, inBytes []byte) Message {
+		return EmptyMsg
+	})
+	defer loop.Exit()
+
+	exited := make(chan struct{})
+	go func() {
+		loop.LoopRead()
+		close(exited)
+	}()
+
+	// a select request whose proto is garbage
+	if err := client.WriteMsg([]byte{byte(BackendSelectRequestType), byte(CompressNone), 1, 0xff, 0xff}); err != nil {
+		t.Fatal(err)
+	}
+	client.Flush()
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("read loop didn't exit on a message failed to decode")
+	}
+
+	var line string
+	for _, l := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(l, `msg="decode err"`) {
+			line = l
+		}
+	}
+	for _, field := range []string{"conn=", "remoteAddr=" + clientConn.LocalAddr().String(), "role=server", "msgType=backend.SelectRequest"} {
+		if !strings.Contains(line, field) {
+			t.Fatalf("want %s in the line of the decode error, got %q", field, logs.String())
+		}
+	}
+
+	// the fields of the connection are not in the labels of the metrics
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), "baudtime_rwloop_") {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() != "role" {
+					t.Fatalf("want the metrics of the loops labeled by role only, %s has label %s", family.GetName(), label.GetName())
+				}
+			}
+		}
+	}
+}
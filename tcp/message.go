@@ -17,7 +17,12 @@ package tcp
 
 import (
 	"encoding/binary"
+	"fmt"
+	"io"
+
 	"github.com/baudtime/baudtime/msg"
+	. "github.com/baudtime/baudtime/vars"
+	"github.com/golang/snappy"
 	"github.com/pkg/errors"
 )
 
@@ -49,6 +54,9 @@ func (msg *Message) SizeOfRaw() int {
 	return 0
 }
 
+// MsgType tags a message on the wire, it's the first byte of the frame of the message, see MsgCodec.
+// The tags are the order the types are declared in, so a type is only ever appended, never reordered
+// or removed, 255 is never a message. The tags are below 128, the high bit of the byte flags a compressed message.
 type MsgType uint8
 
 const (
@@ -62,7 +70,45 @@ var (
 	BadMsgTypeError = errors.New("bad message type")
 )
 
-type MsgCodec struct{}
+// ErrMsgTooLarge is returned when a peer frames a message larger than the limit, the rest of
+// the stream can't be trusted any more, so the connection should be closed.
+type ErrMsgTooLarge struct {
+	Size  int
+	Limit int
+}
+
+func (e *ErrMsgTooLarge) Error() string {
+	return fmt.Sprintf("message of %d bytes exceeds the limit of %d bytes", e.Size, e.Limit)
+}
+
+// maxMsgSize returns the configured limit of message size, MaxMsgSize by default.
+func maxMsgSize() int {
+	if Cfg.MaxMsgSize > 0 {
+		return int(Cfg.MaxMsgSize)
+	}
+	return MaxMsgSize
+}
+
+// pooledUnmarshaler is implemented by the messages able to draw their parts from pools
+// when being decoded, such as the series of a select response.
+type pooledUnmarshaler interface {
+	UnmarshalPooled(b []byte) error
+}
+
+// compressedFlag is set in the type byte of a compressed message, which is followed by its compress type.
+const compressedFlag = 0x80
+
+// MsgCodec encodes a message as [type][opaque][proto], the proto part is compressed only when
+// Compress is set and its size reaches CompressThreshold, the message is then encoded as
+// [type|compressedFlag][compress type][opaque][compressed proto]. So an uncompressed message is
+// framed as by the peers not knowing of compression, which Compress is only set for once they
+// are negotiated with, see ReadWriteLoop.encoder. A message is decoded by the compress type it
+// carries, whatever Compress is.
+type MsgCodec struct {
+	Compress          CompressType
+	CompressThreshold int
+	CompressLevel     int // only for zstd, 0 takes its default level
+}
 
 func (codec *MsgCodec) Encode(msg Message, b []byte) (int, error) {
 	raw := msg.GetRaw()
@@ -79,6 +125,21 @@ func (codec *MsgCodec) Encode(msg Message, b []byte) (int, error) {
 		if err != nil {
 			return 0, err
 		}
+
+		if codec.shouldCompress(n) {
+			buf := bytesPool.Get(snappy.MaxEncodedLen(n)).([]byte)
+			compressed, err := compress(codec.Compress, codec.CompressLevel, buf, b[written:written+n])
+			// the compress type takes a byte, the opaque is moved on to make room for it
+			if err == nil && len(compressed)+1 < n {
+				copy(b[2:], b[1:written])
+				b[0] |= compressedFlag
+				b[1] = byte(codec.Compress)
+				written++
+				n = copy(b[written:], compressed)
+			}
+			bytesPool.Put(buf)
+		}
+
 		written += n
 	}
 
@@ -86,21 +147,31 @@ func (codec *MsgCodec) Encode(msg Message, b []byte) (int, error) {
 }
 
 func (codec *MsgCodec) Decode(b []byte) (Message, error) {
-	var (
-		err error
-		msg Message
-	)
-
-	//get message type
-	msgType := MsgType(b[0])
+	var msg Message
 
-	//get message opaque
-	opaque, n := binary.Uvarint(b[1 : 1+binary.MaxVarintLen64])
+	msgType, compressType, opaque, data, err := splitFrame(b)
+	if err != nil {
+		return msg, err
+	}
 
 	//get message proto
 	raw := Make(msgType)
 	if raw != nil {
-		err = raw.Unmarshal(b[1+n:])
+		if compressType != CompressNone {
+			buf := bytesPool.Get(len(data)).([]byte)
+			defer bytesPool.Put(buf)
+
+			data, err = decompress(compressType, buf, data)
+			if err != nil {
+				return msg, err
+			}
+		}
+
+		if u, ok := raw.(pooledUnmarshaler); ok {
+			err = u.UnmarshalPooled(data)
+		} else {
+			err = raw.Unmarshal(data)
+		}
 	}
 
 	if err != nil {
@@ -112,3 +183,68 @@ func (codec *MsgCodec) Decode(b []byte) (Message, error) {
 
 	return msg, nil
 }
+
+// PeekType returns the type of the message framed in b without decompressing or unmarshaling it, so that
+// a message may be told apart, e.g. a ConnCtrl from a large AddRequest, before it's decoded, if at all.
+// BadMsgTypeError is returned for a tag not of any type.
+func PeekType(b []byte) (MsgType, error) {
+	if len(b) < 2 {
+		return BadMsgType, io.ErrUnexpectedEOF
+	}
+
+	msgType := MsgType(b[0] &^ compressedFlag)
+	if msgType >= numMsgTypes {
+		return BadMsgType, BadMsgTypeError
+	}
+	return msgType, nil
+}
+
+// PeekOpaque returns the opaque of the message framed in b without decompressing or unmarshaling it, e.g. to
+// tell the request a response frame is of before it's decoded.
+func PeekOpaque(b []byte) (uint64, error) {
+	_, _, opaque, _, err := splitFrame(b)
+	return opaque, err
+}
+
+// PeekProto calls f with the proto part of the message framed in b, decompressed if it's compressed, but not
+// unmarshaled, e.g. to tell how large a message is before it's decoded. f must not retain the proto.
+func PeekProto(b []byte, f func(proto []byte) error) error {
+	_, compressType, _, data, err := splitFrame(b)
+	if err != nil {
+		return err
+	}
+
+	if compressType != CompressNone {
+		buf := bytesPool.Get(len(data)).([]byte)
+		defer bytesPool.Put(buf)
+
+		if data, err = decompress(compressType, buf, data); err != nil {
+			return err
+		}
+	}
+	return f(data)
+}
+
+// splitFrame splits the frame b into the type, the compress type, the opaque and the proto of its message.
+func splitFrame(b []byte) (msgType MsgType, compressType CompressType, opaque uint64, proto []byte, err error) {
+	if len(b) < 2 {
+		return BadMsgType, CompressNone, 0, nil, io.ErrUnexpectedEOF
+	}
+
+	msgType, at := MsgType(b[0]), 1
+	if msgType&compressedFlag != 0 {
+		msgType &^= compressedFlag
+		compressType = CompressType(b[1])
+		at++
+	}
+
+	opaque, n := binary.Uvarint(b[at:])
+	if n <= 0 {
+		return BadMsgType, CompressNone, 0, nil, io.ErrUnexpectedEOF
+	}
+	return msgType, compressType, opaque, b[at+n:], nil
+}
+
+func (codec *MsgCodec) shouldCompress(size int) bool {
+	return codec.Compress != CompressNone && codec.CompressThreshold > 0 && size >= codec.CompressThreshold
+}
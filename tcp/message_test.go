@@ -0,0 +1,168 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+)
+
+func makeAddRequest(seriesNum int) *backendpb.AddRequest {
+	req := &backendpb.AddRequest{}
+	for i := 0; i < seriesNum; i++ {
+		series := &pb.Series{
+			Labels: []pb.Label{
+				{Name: "__name__", Value: "test_metric"},
+				{Name: "instance", Value: fmt.Sprintf("host-%d", i)},
+			},
+		}
+		for t := int64(0); t < 10; t++ {
+			series.Points = append(series.Points, pb.Point{T: 1560000000000 + t*15000, V: float64(t)})
+		}
+		req.Series = append(req.Series, series)
+	}
+	return req
+}
+
+func TestMsgCodec_RoundTrip(t *testing.T) {
+	small, big := makeAddRequest(1), makeAddRequest(100)
+	threshold := small.Size() + 1
+
+	tests := []struct {
+		codec        MsgCodec
+		raw          *backendpb.AddRequest
+		wantCompress CompressType
+	}{
+		{MsgCodec{}, big, CompressNone},
+		{MsgCodec{Compress: CompressSnappy, CompressThreshold: threshold}, small, CompressNone},
+		{MsgCodec{Compress: CompressSnappy, CompressThreshold: threshold}, big, CompressSnappy},
+		{MsgCodec{Compress: CompressGzip, CompressThreshold: threshold}, small, CompressNone},
+		{MsgCodec{Compress: CompressGzip, CompressThreshold: threshold}, big, CompressGzip},
+		{MsgCodec{Compress: CompressZstd, CompressThreshold: threshold}, small, CompressNone},
+		{MsgCodec{Compress: CompressZstd, CompressThreshold: threshold}, big, CompressZstd},
+		{MsgCodec{Compress: CompressZstd, CompressThreshold: threshold, CompressLevel: 19}, big, CompressZstd},
+	}
+
+	for _, test := range tests {
+		in := Message{Message: test.raw, Opaque: 42}
+		b := make([]byte, 2+binary.MaxVarintLen64+in.SizeOfRaw())
+
+		n, err := test.codec.Encode(in, b)
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		_, got, _, _, err := splitFrame(b[:n])
+		if err != nil || got != test.wantCompress {
+			t.Fatalf("compress type: want %v, got %v, err %v", test.wantCompress, got, err)
+		}
+		// an uncompressed message is framed as by the peers not knowing of compression
+		if test.wantCompress == CompressNone {
+			if opaque, _ := binary.Uvarint(b[1:]); b[0] != byte(BackendAddRequestType) || opaque != in.Opaque {
+				t.Fatalf("want an uncompressed message in the plain framing, got % x", b[:4])
+			}
+		}
+		if test.wantCompress != CompressNone && n >= 2+binary.MaxVarintLen64+in.SizeOfRaw() {
+			t.Fatalf("compressed message is not smaller, size %d", n)
+		}
+
+		out, err := test.codec.Decode(b[:n])
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if out.GetOpaque() != in.GetOpaque() {
+			t.Fatalf("opaque: want %d, got %d", in.GetOpaque(), out.GetOpaque())
+		}
+		if !reflect.DeepEqual(out.GetRaw(), in.GetRaw()) {
+			t.Fatalf("message mismatch after round trip with %v", test.wantCompress)
+		}
+
+		// the compress type is told by the message, not by the codec decoding it
+		out, err = plainCodec.Decode(b[:n])
+		if err != nil {
+			t.Fatalf("decode by plain codec: %v", err)
+		}
+		if !reflect.DeepEqual(out.GetRaw(), in.GetRaw()) {
+			t.Fatalf("message mismatch after decoding %v by plain codec", test.wantCompress)
+		}
+	}
+}
+
+func BenchmarkMsgCodec_Encode(b *testing.B) {
+	in := Message{Message: makeAddRequest(1000)}
+	size := 2 + binary.MaxVarintLen64 + in.SizeOfRaw()
+
+	for _, codec := range []MsgCodec{
+		{},
+		{Compress: CompressSnappy, CompressThreshold: 1},
+		{Compress: CompressGzip, CompressThreshold: 1},
+		{Compress: CompressZstd, CompressThreshold: 1, CompressLevel: 1},
+		{Compress: CompressZstd, CompressThreshold: 1},
+		{Compress: CompressZstd, CompressThreshold: 1, CompressLevel: 19},
+	} {
+		b.Run(fmt.Sprintf("%v-%d", codec.Compress, codec.CompressLevel), func(b *testing.B) {
+			buf := make([]byte, size)
+			b.SetBytes(int64(in.SizeOfRaw()))
+			b.ReportAllocs()
+
+			var n int
+			for i := 0; i < b.N; i++ {
+				var err error
+				if n, err = codec.Encode(in, buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(in.SizeOfRaw())/float64(n), "ratio")
+		})
+	}
+}
+
+func TestPeekType(t *testing.T) {
+	codecs := []MsgCodec{{}, {Compress: CompressSnappy, CompressThreshold: 1}, {Compress: CompressZstd, CompressThreshold: 1}}
+
+	for msgType := MsgType(0); msgType < numMsgTypes; msgType++ {
+		raw := Make(msgType)
+		if raw == nil || Type(raw) != msgType {
+			t.Fatalf("type %d: want a message of every tag below %d", msgType, numMsgTypes)
+		}
+
+		for _, codec := range codecs {
+			in := Message{Message: raw, Opaque: 1 << 40}
+			b := make([]byte, 2+binary.MaxVarintLen64+in.SizeOfRaw())
+			n, err := codec.Encode(in, b)
+			if err != nil {
+				t.Fatalf("%v: encode: %v", msgType, err)
+			}
+
+			got, err := PeekType(b[:n])
+			if err != nil || got != msgType {
+				t.Fatalf("%v encoded by %v: want the type peeked, got %v, err %v", msgType, codec.Compress, got, err)
+			}
+		}
+	}
+
+	if _, err := PeekType([]byte{byte(numMsgTypes), byte(CompressNone), 0}); err != BadMsgTypeError {
+		t.Fatalf("want %v for a tag of no type, got %v", BadMsgTypeError, err)
+	}
+	if _, err := PeekType([]byte{byte(ConnCtrlType)}); err != io.ErrUnexpectedEOF {
+		t.Fatalf("want %v for a partial frame, got %v", io.ErrUnexpectedEOF, err)
+	}
+}
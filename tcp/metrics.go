@@ -0,0 +1,93 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Roles of the connection a ReadWriteLoop runs on, used to label the loop metrics.
+const (
+	RoleServer      = "server"
+	RoleClient      = "client"
+	RoleReplication = "replication"
+)
+
+var (
+	loopMsgsRead     *prometheus.CounterVec
+	loopMsgsWritten  *prometheus.CounterVec
+	loopBytesRead    *prometheus.CounterVec
+	loopBytesWritten *prometheus.CounterVec
+	loopQueueDepth   *prometheus.GaugeVec
+	loopEncodeErrors *prometheus.CounterVec
+	loopDecodeErrors *prometheus.CounterVec
+	loopExits        *prometheus.CounterVec
+	loopThrottled    *prometheus.CounterVec
+)
+
+// loopMetrics are the metrics of one ReadWriteLoop, curried with its role.
+type loopMetrics struct {
+	msgsRead     prometheus.Counter
+	msgsWritten  prometheus.Counter
+	bytesRead    prometheus.Counter
+	bytesWritten prometheus.Counter
+	queueDepth   prometheus.Gauge
+	encodeErrors prometheus.Counter
+	decodeErrors prometheus.Counter
+	exits        prometheus.Counter
+	throttled    prometheus.Counter
+}
+
+func init() {
+	newCounterVec := func(name, help string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "baudtime",
+			Subsystem: "rwloop",
+			Name:      name,
+			Help:      help,
+		}, []string{"role"})
+	}
+
+	loopMsgsRead = newCounterVec("messages_read_total", "Total number of messages read.")
+	loopMsgsWritten = newCounterVec("messages_written_total", "Total number of messages written.")
+	loopBytesRead = newCounterVec("bytes_read_total", "Total number of bytes read, including the length prefix.")
+	loopBytesWritten = newCounterVec("bytes_written_total", "Total number of bytes written, including the length prefix.")
+	loopQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "baudtime",
+		Subsystem: "rwloop",
+		Name:      "out_queue_depth",
+		Help:      "Number of encoded messages waiting in the out queues.",
+	}, []string{"role"})
+	loopEncodeErrors = newCounterVec("encode_errors_total", "Total number of messages failed to encode.")
+	loopDecodeErrors = newCounterVec("decode_errors_total", "Total number of messages failed to decode.")
+	loopExits = newCounterVec("exits_total", "Total number of loops exited.")
+	loopThrottled = newCounterVec("requests_throttled_total", "Total number of requests delayed or rejected by the rate limit.")
+
+	prometheus.MustRegister(loopMsgsRead, loopMsgsWritten, loopBytesRead, loopBytesWritten,
+		loopQueueDepth, loopEncodeErrors, loopDecodeErrors, loopExits, loopThrottled)
+}
+
+func newLoopMetrics(role string) *loopMetrics {
+	return &loopMetrics{
+		msgsRead:     loopMsgsRead.WithLabelValues(role),
+		msgsWritten:  loopMsgsWritten.WithLabelValues(role),
+		bytesRead:    loopBytesRead.WithLabelValues(role),
+		bytesWritten: loopBytesWritten.WithLabelValues(role),
+		queueDepth:   loopQueueDepth.WithLabelValues(role),
+		encodeErrors: loopEncodeErrors.WithLabelValues(role),
+		decodeErrors: loopDecodeErrors.WithLabelValues(role),
+		exits:        loopExits.WithLabelValues(role),
+		throttled:    loopThrottled.WithLabelValues(role),
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"math"
+	"net"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	. "github.com/baudtime/baudtime/vars"
+)
+
+// ErrRateLimited answers a request over the rate limit of its connection, see RateLimitConfig.Reject.
+var ErrRateLimited = &pb.ResponseError{Code: pb.ErrorCode_RateLimited, Message: "rate limited"}
+
+// tokenBucket is refilled with rate tokens a second up to burst. A reservation may overdraw it, the
+// following ones wait for the debt to be paid back, so that a request larger than the burst still passes.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: rate, tokens: rate}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// enough tells whether n tokens may be taken at once, a full bucket is enough for any n.
+func (b *tokenBucket) enough(n float64) bool {
+	return b.tokens >= math.Min(n, b.burst)
+}
+
+// reserve takes n tokens and returns how long to wait for them to be due.
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// rateLimiter throttles the requests read by a connection by their number and their bytes.
+type rateLimiter struct {
+	requests *tokenBucket // nil if the number isn't limited
+	bytes    *tokenBucket // nil if the bytes aren't limited
+	reject   bool
+	now      func() time.Time
+	sleep    func(time.Duration)
+}
+
+// newRateLimiter returns the limiter of a connection from remoteAddr, nil if it isn't limited.
+func newRateLimiter(cfg *RateLimitConfig, remoteAddr string) *rateLimiter {
+	if cfg == nil {
+		return nil
+	}
+
+	limit := cfg.RateLimit
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		if l, found := cfg.Clients[host]; found {
+			limit = l
+		}
+	}
+	if limit.RequestsPerSec <= 0 && limit.BytesPerSec <= 0 {
+		return nil
+	}
+
+	l := &rateLimiter{reject: cfg.Reject, now: time.Now, sleep: time.Sleep}
+	if limit.RequestsPerSec > 0 {
+		l.requests = newTokenBucket(float64(limit.RequestsPerSec))
+	}
+	if limit.BytesPerSec > 0 {
+		l.bytes = newTokenBucket(float64(limit.BytesPerSec))
+	}
+	return l
+}
+
+// admit tells whether a request of size bytes may be handled, which is false if it's over the limit
+// and the limiter rejects rather than delays, and whether it's throttled, i.e. rejected or delayed.
+// A delayed request is admitted once it's due.
+func (l *rateLimiter) admit(size int) (ok, throttled bool) {
+	now := l.now()
+	if l.requests != nil {
+		l.requests.refill(now)
+	}
+	if l.bytes != nil {
+		l.bytes.refill(now)
+	}
+
+	if l.reject && !l.enough(size) {
+		return false, true
+	}
+
+	var wait time.Duration
+	if l.requests != nil {
+		wait = l.requests.reserve(1)
+	}
+	if l.bytes != nil {
+		if d := l.bytes.reserve(float64(size)); d > wait {
+			wait = d
+		}
+	}
+
+	// a rejecting limiter never waits, an overdraft only rejects the following requests longer
+	if wait > 0 && !l.reject {
+		l.sleep(wait)
+		return true, true
+	}
+	return true, false
+}
+
+func (l *rateLimiter) enough(size int) bool {
+	return (l.requests == nil || l.requests.enough(1)) && (l.bytes == nil || l.bytes.enough(float64(size)))
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	gatewaypb "github.com/baudtime/baudtime/msg/pb/gateway"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log"
+)
+
+// fakeClock is the clock of a limiter whose sleep advances it rather than waits.
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+	c.slept += d
+}
+
+func TestRateLimiter_Burst(t *testing.T) {
+	tests := []struct {
+		cfg      vars.RateLimitConfig
+		requests int
+		size     int
+		admitted int
+		slept    time.Duration
+	}{
+		// a burst of one second worth passes at once, the rest at the rate
+		{cfg: vars.RateLimitConfig{RateLimit: vars.RateLimit{RequestsPerSec: 10}}, requests: 30, size: 1, admitted: 30, slept: 2 * time.Second},
+		{cfg: vars.RateLimitConfig{RateLimit: vars.RateLimit{BytesPerSec: 1000}}, requests: 10, size: 500, admitted: 10, slept: 4 * time.Second},
+		{cfg: vars.RateLimitConfig{RateLimit: vars.RateLimit{RequestsPerSec: 100, BytesPerSec: 1000}}, requests: 10, size: 500, admitted: 10, slept: 4 * time.Second},
+		// a request larger than the burst passes, the following ones wait for it
+		{cfg: vars.RateLimitConfig{RateLimit: vars.RateLimit{BytesPerSec: 1000}}, requests: 2, size: 3000, admitted: 2, slept: 5 * time.Second},
+		// a rejecting limiter never waits
+		{cfg: vars.RateLimitConfig{RateLimit: vars.RateLimit{RequestsPerSec: 10}, Reject: true}, requests: 30, size: 1, admitted: 10},
+		{cfg: vars.RateLimitConfig{RateLimit: vars.RateLimit{BytesPerSec: 1000}, Reject: true}, requests: 10, size: 300, admitted: 3},
+		{cfg: vars.RateLimitConfig{RateLimit: vars.RateLimit{BytesPerSec: 1000}, Reject: true}, requests: 3, size: 3000, admitted: 1},
+	}
+
+	for i, test := range tests {
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		l := newRateLimiter(&test.cfg, "127.0.0.1:9000")
+		l.now, l.sleep = clock.Now, clock.Sleep
+
+		admitted := 0
+		for j := 0; j < test.requests; j++ {
+			if ok, _ := l.admit(test.size); ok {
+				admitted++
+			}
+		}
+		if admitted != test.admitted || clock.slept != test.slept {
+			t.Fatalf("case %d: want %d admitted in %v, got %d in %v", i, test.admitted, test.slept, admitted, clock.slept)
+		}
+	}
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := newRateLimiter(&vars.RateLimitConfig{RateLimit: vars.RateLimit{RequestsPerSec: 10}, Reject: true}, "")
+	l.now, l.sleep = clock.Now, clock.Sleep
+
+	tests := []struct {
+		elapsed  time.Duration
+		requests int
+		admitted int
+	}{
+		{requests: 20, admitted: 10},
+		{elapsed: 500 * time.Millisecond, requests: 20, admitted: 5},
+		// the bucket holds no more than the burst however long it's idle
+		{elapsed: time.Minute, requests: 20, admitted: 10},
+	}
+
+	for i, test := range tests {
+		clock.now = clock.now.Add(test.elapsed)
+
+		admitted := 0
+		for j := 0; j < test.requests; j++ {
+			if ok, throttled := l.admit(1); ok {
+				admitted++
+			} else if !throttled {
+				t.Fatalf("case %d: want a rejected request throttled", i)
+			}
+		}
+		if admitted != test.admitted {
+			t.Fatalf("case %d: want %d admitted, got %d", i, test.admitted, admitted)
+		}
+	}
+}
+
+func TestRateLimitConfig(t *testing.T) {
+	var cfg vars.Config
+	_, err := toml.Decode(`
+[rate_limit]
+requests_per_sec = 100
+bytes_per_sec = "1m"
+reject = true
+
+[rate_limit.clients."10.0.0.1"]
+requests_per_sec = 1000
+
+[rate_limit.clients."10.0.0.2"]
+`, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		remoteAddr string
+		requests   float64
+		bytes      float64
+	}{
+		{remoteAddr: "10.0.0.3:4321", requests: 100, bytes: 1 << 20},
+		{remoteAddr: "10.0.0.1:4321", requests: 1000},
+		{remoteAddr: "", requests: 100, bytes: 1 << 20},
+	}
+
+	for i, test := range tests {
+		l := newRateLimiter(cfg.RateLimit, test.remoteAddr)
+		if l == nil || !l.reject {
+			t.Fatalf("case %d: want a rejecting limiter, got %+v", i, l)
+		}
+		if got := rateOf(l.requests); got != test.requests {
+			t.Fatalf("case %d: want %v requests a second, got %v", i, test.requests, got)
+		}
+		if got := rateOf(l.bytes); got != test.bytes {
+			t.Fatalf("case %d: want %v bytes a second, got %v", i, test.bytes, got)
+		}
+	}
+
+	// a client whose limits are zero isn't limited
+	if l := newRateLimiter(cfg.RateLimit, "10.0.0.2:4321"); l != nil {
+		t.Fatalf("want no limiter for an unlimited client, got %+v", l)
+	}
+	if l := newRateLimiter(nil, "10.0.0.3:4321"); l != nil {
+		t.Fatalf("want no limiter without config, got %+v", l)
+	}
+}
+
+func rateOf(b *tokenBucket) float64 {
+	if b == nil {
+		return 0
+	}
+	return b.rate
+}
+
+func TestReadWriteLoop_RateLimited(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	rateLimit := vars.Cfg.RateLimit
+	vars.Cfg.RateLimit = &vars.RateLimitConfig{RateLimit: vars.RateLimit{RequestsPerSec: 2}, Reject: true}
+	defer func() {
+		vars.Cfg.RateLimit = rateLimit
+	}()
+
+	clientConn, serverConn := tcpPair(t)
+	client := NewConn(clientConn)
+	defer client.Close()
+
+	var handled int32
+	loop := NewReadWriteLoop(NewConn(serverConn), RoleServer, func(ctx context.Context, in Message, inBytes []byte) Message {
+		atomic.AddInt32(&handled, 1)
+		return Message{Opaque: in.Opaque, Message: &pb.GeneralResponse{Status: pb.StatusCode_Succeed}}
+	})
+	defer loop.Exit()
+	go loop.LoopRead()
+	go loop.LoopWrite()
+
+	const requests = 5
+
+	// the gateway requests are limited, the ones to a storage aren't
+	codec := MsgCodec{}
+	buf := make([]byte, 1024)
+	for i := 1; i <= 2*requests; i++ {
+		var req Message
+		if i%2 == 0 {
+			req = Message{Opaque: uint64(i), Message: &gatewaypb.LabelValuesRequest{Name: "job"}}
+		} else {
+			req = Message{Opaque: uint64(i), Message: &backendpb.LabelNamesRequest{}}
+		}
+		n, err := codec.Encode(req, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = client.WriteMsg(buf[:n]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client.Flush()
+	clientConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	limited := 0
+	for i := 0; i < 2*requests; i++ {
+		n, err := client.ReadMsg(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := codec.Decode(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		general, ok := resp.GetRaw().(*pb.GeneralResponse)
+		if !ok {
+			t.Fatalf("want a general response, got %T", resp.GetRaw())
+		}
+		if general.Status == pb.StatusCode_Failed {
+			if general.Code != pb.ErrorCode_RateLimited {
+				t.Fatalf("want a failed response rate limited, got %v", general.Code)
+			}
+			limited++
+		}
+	}
+
+	if got := atomic.LoadInt32(&handled); got != requests+2 || limited != requests-2 {
+		t.Fatalf("want %d handled and %d rate limited, got %d and %d", requests+2, requests-2, got, limited)
+	}
+}
@@ -44,8 +44,27 @@ const (
 	ConnCtrlType
 	GeneralResponseType
 	LabelValuesResponseType
+	BackendLabelNamesRequestType
+	BackendLabelNamesResponseType
+	InfoResponseType
+	RouteResponseType
+	BackendAddResponseType
+	RebalanceResponseType
+
+	// numMsgTypes is the number of the types above, a new type goes right before it.
+	numMsgTypes
 )
 
+// isGatewayRequest tells whether a message of msgType is a request of a client to a gateway, rather than one
+// of a gateway to a storage or of the replication between the storages.
+func isGatewayRequest(msgType MsgType) bool {
+	switch msgType {
+	case GatewayAddRequestType, GatewayInstantQueryRequestType, GatewayRangeQueryRequestType, GatewayLabelValuesRequestType:
+		return true
+	}
+	return false
+}
+
 func Type(msg msg.Message) MsgType {
 	switch msg.(type) {
 	//gateway
@@ -87,6 +106,18 @@ func Type(msg msg.Message) MsgType {
 		return GeneralResponseType
 	case *pb.LabelValuesResponse:
 		return LabelValuesResponseType
+	case *backend.LabelNamesRequest:
+		return BackendLabelNamesRequestType
+	case *backend.LabelNamesResponse:
+		return BackendLabelNamesResponseType
+	case *pb.InfoResponse:
+		return InfoResponseType
+	case *pb.RouteResponse:
+		return RouteResponseType
+	case *backend.AddResponse:
+		return BackendAddResponseType
+	case *pb.RebalanceResponse:
+		return RebalanceResponseType
 	}
 
 	return BadMsgType
@@ -133,6 +164,18 @@ func Make(msgType MsgType) msg.Message {
 		return new(pb.GeneralResponse)
 	case LabelValuesResponseType:
 		return new(pb.LabelValuesResponse)
+	case BackendLabelNamesRequestType:
+		return new(backend.LabelNamesRequest)
+	case BackendLabelNamesResponseType:
+		return new(backend.LabelNamesResponse)
+	case InfoResponseType:
+		return new(pb.InfoResponse)
+	case RouteResponseType:
+		return new(pb.RouteResponse)
+	case BackendAddResponseType:
+		return new(backend.AddResponse)
+	case RebalanceResponseType:
+		return new(pb.RebalanceResponse)
 	}
 
 	return nil
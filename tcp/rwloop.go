@@ -22,10 +22,12 @@ import (
 	"net"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/baudtime/baudtime/msg/pb"
 	"github.com/baudtime/baudtime/util/syn"
 	. "github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/pkg/errors"
 )
@@ -42,30 +44,95 @@ type ReadWriteLoop struct {
 	rdClosed uint32
 	wrClosed uint32
 	closed   uint32
+	draining uint32
+	done     chan struct{}
+	flushed  chan struct{} // closed once the messages queued before the drain mark are written out, see ExitGraceful
+	written  chan struct{} // closed once LoopWrite has flushed and returned, see Exit
+	writing  uint32        // set once LoopWrite runs
 	onExit   func()
+	queued   int64
+	metrics  *loopMetrics
+	logger   log.Logger   // tells the connection, the peer and the role of the loop
+	logs     *logLimiter  // limits the lines of the loop, see limited
+	limiter  *rateLimiter // throttles the gateway requests read, nil if they aren't limited
+	// LoopRead stops reading once the queued messages reach highWater, until they drain to lowWater.
+	highWater int64
+	lowWater  int64
+	drained   chan struct{}
+	// a ping is sent every pingInterval, the loop exits if no pong arrives in pingTimeout.
+	pingInterval time.Duration
+	pingTimeout  time.Duration
+	pong         chan struct{}
+	busy         uint32 // set while a request is handled, the pongs aren't read meanwhile
+	// the features in common with the peer, negotiated by the Hello messages, see Handshake.
+	features    uint64
+	peerVersion uint32
+	helloSent   uint32
+	negotiated  chan struct{}
+	settleOnce  sync.Once
+	// preferred is the codec the peer asked for in its Hello, fallback replaces a zstd codec the peer can't decode.
+	preferred atomic.Value
+	fallback  MsgCodec
+	// checks a message other than a ConnCtrl before it's decoded, the loop exits on an error, see BeforeDecode.
+	beforeDecode func(msgType MsgType, b []byte) error
 }
 
 func (loop *ReadWriteLoop) LoopWrite() {
-	block := true
+	atomic.StoreUint32(&loop.writing, 1)
 
-	for loop.IsRunning() && !loop.WriteClosed() {
+	var (
+		block   = true
+		drained bool
+		exit    bool
+	)
+
+	for !drained && !exit && loop.IsRunning() && !loop.WriteClosed() {
 		msgV := loop.out.Dequeue(block)
 
 		if msgV != nil {
-			bytes, ok := msgV.([]byte)
-			if !ok {
+			if _, ok := msgV.(drainMark); ok {
+				drained = true
+				continue
+			}
+
+			var (
+				err     error
+				msgType MsgType
+			)
+			switch m := msgV.(type) {
+			case []byte:
+				loop.dequeued()
+				msgType = MsgType(m[0])
+				err = loop.conn.WriteMsg(m)
+				bytesPool.Put(m)
+				if err == nil {
+					loop.metrics.msgsWritten.Inc()
+					loop.metrics.bytesWritten.Add(float64(4 + len(m)))
+				}
+			case frameBatch:
+				loop.dequeued()
+				msgType = MsgType(m.frames[4]) // the first of the batch
+				err = loop.conn.writeFrames(m.frames)
+				bytesPool.Put(m.frames)
+				if err == nil {
+					loop.metrics.msgsWritten.Add(float64(m.n))
+					loop.metrics.bytesWritten.Add(float64(len(m.frames)))
+				}
+			default:
 				continue
 			}
 
-			err := loop.conn.WriteMsg(bytes)
-			bytesPool.Put(bytes)
 			if err != nil {
 				if _, ok := err.(net.Error); ok || err == io.EOF || err == io.ErrUnexpectedEOF {
-					loop.Exit()
-					return
+					exit = true
+					continue
 				}
 
-				level.Error(Logger).Log("msg", "write loop responsing client failed", "err", err)
+				level.Error(loop.limited("write")).Log("msg", "write loop responsing client failed", "msgType", msgType, "err", err)
+				if loop.conn.Secured() {
+					exit = true
+					continue
+				}
 			}
 
 			block = false
@@ -74,56 +141,154 @@ func (loop *ReadWriteLoop) LoopWrite() {
 			block = true
 		}
 	}
+
+	// the final flush is done here rather than by Exit, so that the writer of the conn
+	// is only ever used by this goroutine
+	loop.conn.Flush()
+	if drained {
+		close(loop.flushed)
+	}
+	close(loop.written)
+
+	if exit {
+		loop.Exit()
+	}
 }
 
 func (loop *ReadWriteLoop) LoopRead() {
 	ctx := context.Background()
-	bytes := make([]byte, MaxMsgSize)
 
-	for loop.IsRunning() && !loop.ReadClosed() {
-		n, err := loop.conn.ReadMsg(bytes)
+	if loop.pingInterval > 0 {
+		go loop.keepAlive()
+	}
+
+	for loop.IsRunning() && !loop.ReadClosed() && !loop.Draining() {
+		bytes, err := loop.conn.readMsgPooled()
 		if err != nil {
+			if tooLarge, ok := err.(*ErrMsgTooLarge); ok {
+				level.Error(loop.limited("oversized")).Log("msg", "peer sent an oversized message, closing the connection", "size", tooLarge.Size, "limit", tooLarge.Limit)
+				loop.Exit()
+				return
+			}
 			if _, ok := err.(net.Error); ok || err == io.EOF || err == io.ErrUnexpectedEOF {
 				loop.Exit()
 				return
 			}
 
-			level.Error(Logger).Log("msg", "read loop reading request failed", "err", err)
+			level.Error(loop.limited("read")).Log("msg", "read loop reading request failed", "err", err)
+			if loop.conn.Secured() {
+				loop.Exit()
+				return
+			}
 			continue
 		}
+		loop.metrics.msgsRead.Inc()
+		loop.metrics.bytesRead.Add(float64(4 + len(bytes)))
 
-		in, err := loop.codec.Decode(bytes[:n])
+		// the type is told by the frame, the ConnCtrls are taken by the loop itself and the other messages are
+		// decoded for the handler, a message of a type unknown still is, without its raw
+		msgType, _ := PeekType(bytes)
+		if msgType == ConnCtrlType {
+			err = loop.handleCtrl(bytes)
+			bytesPool.Put(bytes)
+			if err != nil {
+				loop.Exit()
+				return
+			}
+			continue
+		}
+
+		if loop.beforeDecode != nil {
+			if err = loop.beforeDecode(msgType, bytes); err != nil {
+				bytesPool.Put(bytes)
+				level.Warn(loop.limited("refused")).Log("msg", "message refused before being decoded, closing the connection", "msgType", msgType, "err", err)
+				loop.Exit()
+				return
+			}
+		}
+
+		// the ConnCtrls above are taken at once, a request waits while the out queue is over the high water mark
+		loop.waitDrain()
+		atomic.StoreUint32(&loop.busy, 1)
+
+		// a request over the limit is rejected before it's decoded
+		if loop.limiter != nil && isGatewayRequest(msgType) {
+			ok, throttled := loop.limiter.admit(len(bytes))
+			if throttled {
+				loop.metrics.throttled.Inc()
+			}
+			if !ok {
+				opaque, _ := PeekOpaque(bytes)
+				bytesPool.Put(bytes)
+				atomic.StoreUint32(&loop.busy, 0)
+				level.Warn(loop.limited("rateLimited")).Log("msg", "request over the rate limit rejected", "msgType", msgType)
+				loop.Write(Message{Opaque: opaque, Message: ErrRateLimited.Response()})
+				continue
+			}
+		}
+
+		in, err := loop.codec.Decode(bytes)
 		if err != nil {
-			level.Error(Logger).Log("msg", "decode err", "err", err)
+			bytesPool.Put(bytes)
+			loop.metrics.decodeErrors.Inc()
+			level.Error(loop.limited("decode")).Log("msg", "decode err", "msgType", msgType, "err", err)
 			loop.Exit()
 			return
 		}
 
-		if connCtrl, ok := in.Message.(*pb.ConnCtrl); ok {
-			switch connCtrl.Code {
-			case pb.CtrlCode_CloseRead:
-				err = loop.CloseRead()
-			case pb.CtrlCode_CloseWrite:
-				err = loop.CloseWrite()
-			}
-			level.Info(Logger).Log("msg", connCtrl.Code.String(), "err", err)
+		out := loop.handle(ctx, in, bytes)
+		bytesPool.Put(bytes) // handlers must not retain the raw bytes after returning
+		atomic.StoreUint32(&loop.busy, 0)
+		if loop.WriteClosed() || out == EmptyMsg {
 			continue
 		}
 
-		out := loop.handle(ctx, in, bytes[:n])
-		if loop.WriteClosed() || out == EmptyMsg {
-			continue
+		if err = loop.checkFeatures(out.Message); err != nil {
+			out.SetRaw(&pb.GeneralResponse{Status: pb.StatusCode_Failed, Message: err.Error()})
 		}
 
-		outBytes := bytesPool.Get(1 + binary.MaxVarintLen64 + out.SizeOfRaw()).([]byte)
-		n, err = loop.codec.Encode(out, outBytes)
+		outBytes := bytesPool.Get(2 + binary.MaxVarintLen64 + out.SizeOfRaw()).([]byte)
+		n, err := loop.encoder().Encode(out, outBytes)
 		if err != nil {
-			level.Error(Logger).Log("msg", "encode err", "err", err)
+			loop.metrics.encodeErrors.Inc()
+			level.Error(loop.limited("encode")).Log("msg", "encode err", "msgType", Type(out.GetRaw()), "err", err)
 			continue
 		}
 
-		loop.out.Enqueue(outBytes[:n])
+		loop.enqueue(outBytes[:n])
+	}
+}
+
+// handleCtrl decodes the ConnCtrl framed in b and acts on it, an error means the frame can't be decoded.
+func (loop *ReadWriteLoop) handleCtrl(b []byte) error {
+	in, err := loop.codec.Decode(b)
+	if err != nil {
+		loop.metrics.decodeErrors.Inc()
+		level.Error(loop.limited("decode")).Log("msg", "decode err", "msgType", ConnCtrlType, "err", err)
+		return err
+	}
+
+	connCtrl := in.Message.(*pb.ConnCtrl)
+	switch connCtrl.Code {
+	case pb.CtrlCode_CloseRead:
+		err = loop.CloseRead()
+	case pb.CtrlCode_CloseWrite:
+		err = loop.CloseWrite()
+	case pb.CtrlCode_Ping:
+		loop.Write(Message{Opaque: in.GetOpaque(), Message: &pb.ConnCtrl{Code: pb.CtrlCode_Pong}})
+		return nil
+	case pb.CtrlCode_Pong:
+		select {
+		case loop.pong <- struct{}{}:
+		default:
+		}
+		return nil
+	case pb.CtrlCode_Hello:
+		loop.onHello(connCtrl)
+		return nil
 	}
+	level.Info(loop.limited("ctrl")).Log("msg", connCtrl.Code.String(), "msgType", ConnCtrlType, "err", err)
+	return nil
 }
 
 func (loop *ReadWriteLoop) Write(msg Message) error {
@@ -135,18 +300,123 @@ func (loop *ReadWriteLoop) Write(msg Message) error {
 		return errors.New("write is closed")
 	}
 
-	bytes := bytesPool.Get(1 + binary.MaxVarintLen64 + msg.SizeOfRaw()).([]byte)
-	n, err := loop.codec.Encode(msg, bytes)
+	if loop.Draining() {
+		return errors.New("loop is draining")
+	}
+
+	if err := loop.checkFeatures(msg.Message); err != nil {
+		return err
+	}
+
+	bytes := bytesPool.Get(2 + binary.MaxVarintLen64 + msg.SizeOfRaw()).([]byte)
+	n, err := loop.encoder().Encode(msg, bytes)
 	if err != nil {
+		loop.metrics.encodeErrors.Inc()
 		return err
 	}
-	return loop.out.Enqueue(bytes[:n])
+	return loop.enqueue(bytes[:n])
+}
+
+// frameBatch is n messages framed with their length prefixes back to back, LoopWrite writes them at once.
+type frameBatch struct {
+	frames []byte
+	n      int
+}
+
+// WriteBatch encodes msgs into one buffer and queues them as a whole, so that they are written
+// to the connection by a single write rather than one per message. The peer reads them as usual.
+func (loop *ReadWriteLoop) WriteBatch(msgs []Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if !loop.IsRunning() {
+		return errors.New("loop is not running")
+	}
+
+	if loop.WriteClosed() {
+		return errors.New("write is closed")
+	}
+
+	if loop.Draining() {
+		return errors.New("loop is draining")
+	}
+
+	size := 0
+	for _, msg := range msgs {
+		if err := loop.checkFeatures(msg.Message); err != nil {
+			return err
+		}
+		size += 4 + 2 + binary.MaxVarintLen64 + msg.SizeOfRaw()
+	}
+
+	codec := loop.encoder()
+	frames := bytesPool.Get(size).([]byte)
+	off := 0
+	for _, msg := range msgs {
+		n, err := codec.Encode(msg, frames[off+4:])
+		if err != nil {
+			bytesPool.Put(frames)
+			loop.metrics.encodeErrors.Inc()
+			return err
+		}
+		binary.BigEndian.PutUint32(frames[off:], uint32(n))
+		off += 4 + n
+	}
+
+	return loop.enqueue(frameBatch{frames: frames[:off], n: len(msgs)})
+}
+
+func (loop *ReadWriteLoop) enqueue(b interface{}) error {
+	err := loop.out.Enqueue(b)
+	if err == nil {
+		atomic.AddInt64(&loop.queued, 1)
+		loop.metrics.queueDepth.Inc()
+	}
+	return err
+}
+
+func (loop *ReadWriteLoop) dequeued() {
+	for {
+		queued := atomic.LoadInt64(&loop.queued)
+		if queued <= 0 {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&loop.queued, queued, queued-1) {
+			loop.metrics.queueDepth.Dec()
+			if queued-1 <= loop.lowWater {
+				loop.signalDrained()
+			}
+			return
+		}
+	}
+}
+
+// waitDrain blocks while the out queue is over the high water mark, until it drains to
+// the low water mark or the loop can't write any more.
+func (loop *ReadWriteLoop) waitDrain() {
+	if loop.highWater <= 0 || atomic.LoadInt64(&loop.queued) < loop.highWater {
+		return
+	}
+
+	level.Warn(loop.limited("highWater")).Log("msg", "out queue reached high water, stop reading", "queued", atomic.LoadInt64(&loop.queued))
+	for loop.IsRunning() && !loop.WriteClosed() && atomic.LoadInt64(&loop.queued) > loop.lowWater {
+		<-loop.drained
+	}
+}
+
+func (loop *ReadWriteLoop) signalDrained() {
+	select {
+	case loop.drained <- struct{}{}:
+	default:
+	}
 }
 
 func (loop *ReadWriteLoop) CloseWrite() (err error) {
 	if atomic.CompareAndSwapUint32(&loop.wrClosed, 0, 1) {
 		err = loop.conn.CloseWrite()
 		loop.out.Close()
+		loop.signalDrained()
 	}
 	return
 }
@@ -168,9 +438,16 @@ func (loop *ReadWriteLoop) ReadClosed() bool {
 
 func (loop *ReadWriteLoop) Exit() (err error) {
 	if atomic.CompareAndSwapUint32(&loop.closed, 0, 1) {
-		loop.conn.Flush()
-		err = loop.conn.Close()
+		// LoopWrite stops once the queue is closed and flushes what it has written, the conn is
+		// closed after that, or after exitFlushTimeout if the peer doesn't read
 		loop.out.Close()
+		loop.waitWritten()
+		err = loop.conn.Close()
+
+		loop.metrics.exits.Inc()
+		loop.metrics.queueDepth.Sub(float64(atomic.SwapInt64(&loop.queued, 0)))
+		loop.signalDrained()
+		close(loop.done)
 
 		if loop.onExit != nil {
 			loop.onExit()
@@ -179,18 +456,202 @@ func (loop *ReadWriteLoop) Exit() (err error) {
 	return
 }
 
+// exitFlushTimeout bounds how long Exit waits for LoopWrite to flush before closing the conn.
+const exitFlushTimeout = time.Second
+
+func (loop *ReadWriteLoop) waitWritten() {
+	if atomic.LoadUint32(&loop.writing) == 0 {
+		return
+	}
+
+	timer := time.NewTimer(exitFlushTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-loop.written:
+	case <-timer.C:
+		level.Warn(loop.limited("flush")).Log("msg", "write loop not flushed in time, closing the connection", "timeout", exitFlushTimeout)
+	}
+}
+
+// drainMark is queued behind the messages to be written out by ExitGraceful.
+type drainMark struct{}
+
+// ExitGraceful stops reading requests and accepting writes, waits up to timeout for the
+// messages already queued to be written out, then exits. An error is returned if they
+// are not all written out in time, the loop exits anyway.
+func (loop *ReadWriteLoop) ExitGraceful(timeout time.Duration) error {
+	if !loop.IsRunning() || loop.WriteClosed() {
+		return loop.Exit()
+	}
+
+	if atomic.CompareAndSwapUint32(&loop.draining, 0, 1) {
+		// may block while the queue is full, until LoopWrite makes room or Exit drops the queue
+		go loop.out.Enqueue(drainMark{})
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-loop.flushed:
+	case <-loop.done:
+	case <-timer.C:
+		err = errors.Errorf("%d queued messages are not written out in %v", atomic.LoadInt64(&loop.queued), timeout)
+	}
+
+	if exitErr := loop.Exit(); err == nil {
+		err = exitErr
+	}
+	return err
+}
+
+// keepAlive pings the peer periodically and exits the loop if a pong doesn't arrive in time,
+// so a half-open connection (e.g. the peer is partitioned) is detected without waiting for tcp.
+func (loop *ReadWriteLoop) keepAlive() {
+	ticker := time.NewTicker(loop.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-loop.done:
+			return
+		}
+
+		select {
+		case <-loop.pong: //drop the stale one
+		default:
+		}
+
+		if err := loop.Write(Message{Message: &pb.ConnCtrl{Code: pb.CtrlCode_Ping}}); err != nil {
+			return
+		}
+
+		if !loop.waitPong() {
+			return
+		}
+	}
+}
+
+// waitPong waits for the pong of the ping sent, the loop exits if it doesn't arrive in time. The time the loop
+// is busy with a request doesn't count, the pong may be read only once the request is done.
+func (loop *ReadWriteLoop) waitPong() bool {
+	timer := time.NewTimer(loop.pingTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-loop.pong:
+			return true
+		case <-loop.done:
+			return false
+		case <-timer.C:
+			if atomic.LoadUint32(&loop.busy) == 1 {
+				timer.Reset(loop.pingTimeout)
+				continue
+			}
+			level.Warn(loop.limited("pong")).Log("msg", "no pong from peer in time, exit", "timeout", loop.pingTimeout)
+			loop.Exit()
+			return false
+		}
+	}
+}
+
+func (loop *ReadWriteLoop) Draining() bool {
+	return atomic.LoadUint32(&loop.draining) == 1
+}
+
 func (loop *ReadWriteLoop) OnExit(f func()) {
 	loop.onExit = f
 }
 
+// BeforeDecode sets f to check every message read but the ConnCtrls, with its frame b, before it's decoded.
+// An error of f closes the connection, dropping the message and whatever follows it unread.
+func (loop *ReadWriteLoop) BeforeDecode(f func(msgType MsgType, b []byte) error) {
+	loop.beforeDecode = f
+}
+
 func (loop *ReadWriteLoop) IsRunning() bool {
 	return atomic.LoadUint32(&loop.closed) == 0
 }
 
-func NewReadWriteLoop(conn *net.TCPConn, handle func(ctx context.Context, in Message, inBytes []byte) Message) *ReadWriteLoop {
+// NewReadWriteLoop returns a loop over conn, role labels the metrics of the loop (e.g. RoleServer).
+func NewReadWriteLoop(conn *Conn, role string, handle func(ctx context.Context, in Message, inBytes []byte) Message) *ReadWriteLoop {
+	highWater, lowWater := outQueueWaterMarks()
+
+	pingInterval, pingTimeout := time.Duration(Cfg.KeepAliveInterval), time.Duration(Cfg.KeepAliveTimeout)
+	if pingTimeout <= 0 {
+		pingTimeout = pingInterval
+	}
+
+	codec := newMsgCodec(role)
+	remoteAddr := conn.remoteAddr()
+
+	// only the requests are limited, the responses read by a client are not, and of the requests only the ones
+	// of the clients of a gateway are, see isGatewayRequest
+	var limiter *rateLimiter
+	if role == RoleServer {
+		limiter = newRateLimiter(Cfg.RateLimit, remoteAddr)
+	}
+
 	return &ReadWriteLoop{
-		conn:   NewConn(conn),
-		out:    syn.NewQueue(1024 * 8),
-		handle: handle,
+		conn:      conn,
+		codec:     codec,
+		fallback:  MsgCodec{Compress: CompressSnappy, CompressThreshold: codec.CompressThreshold},
+		out:       syn.NewQueue(outQueueSize),
+		handle:    handle,
+		metrics:   newLoopMetrics(role),
+		logger:    newLoopLogger(Logger, remoteAddr, role),
+		logs:      newLogLimiter(loopLogInterval),
+		limiter:   limiter,
+		highWater: highWater,
+		lowWater:  lowWater,
+		drained:   make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		flushed:   make(chan struct{}),
+		written:   make(chan struct{}),
+
+		pingInterval: pingInterval,
+		pingTimeout:  pingTimeout,
+		pong:         make(chan struct{}, 1),
+		negotiated:   make(chan struct{}),
+	}
+}
+
+const outQueueSize = 1024 * 8
+
+func outQueueWaterMarks() (high int64, low int64) {
+	high, low = int64(Cfg.OutQueueHighWater), int64(Cfg.OutQueueLowWater)
+	if high <= 0 {
+		return 0, 0
+	}
+	if high > outQueueSize {
+		high = outQueueSize
+	}
+	if low <= 0 || low >= high {
+		low = high / 2
+	}
+	return
+}
+
+// newMsgCodec returns the codec configured for the loops of role, the replication streams may have their own.
+func newMsgCodec(role string) MsgCodec {
+	name := Cfg.Compression
+	if role == RoleReplication && Cfg.ReplicationCompression != "" {
+		name = Cfg.ReplicationCompression
+	}
+
+	compress, err := ParseCompressType(name)
+	if err != nil {
+		level.Warn(Logger).Log("msg", "compression disabled", "err", err)
+		return MsgCodec{}
+	}
+
+	return MsgCodec{
+		Compress:          compress,
+		CompressThreshold: int(Cfg.CompressionThreshold),
+		CompressLevel:     Cfg.CompressionLevel,
 	}
 }
@@ -0,0 +1,517 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/util/toml"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log"
+)
+
+// tcpPair returns both ends of a loopback tcp connection.
+func tcpPair(t testing.TB) (client *net.TCPConn, server *net.TCPConn) {
+	ln, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client, err = net.DialTCP("tcp4", nil, ln.Addr().(*net.TCPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err = ln.AcceptTCP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestReadWriteLoop_WaterMarks(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	high, low := vars.Cfg.OutQueueHighWater, vars.Cfg.OutQueueLowWater
+	vars.Cfg.OutQueueHighWater, vars.Cfg.OutQueueLowWater = 4, 2
+	defer func() {
+		vars.Cfg.OutQueueHighWater, vars.Cfg.OutQueueLowWater = high, low
+	}()
+
+	const reqNum = 10
+
+	clientConn, serverConn := tcpPair(t)
+	client := NewConn(clientConn)
+	defer client.Close()
+
+	var handled int32
+	loop := NewReadWriteLoop(NewConn(serverConn), RoleServer, func(ctx context.Context, in Message, inBytes []byte) Message {
+		atomic.AddInt32(&handled, 1)
+		return Message{Opaque: in.GetOpaque(), Message: &pb.GeneralResponse{Status: pb.StatusCode_Succeed}}
+	})
+	defer loop.Exit()
+	go loop.LoopRead()
+
+	codec := MsgCodec{}
+	for i := 0; i < reqNum; i++ {
+		req := Message{Opaque: uint64(i), Message: &backendpb.LabelValuesRequest{Name: "instance"}}
+		b := make([]byte, 2+binary.MaxVarintLen64+req.SizeOfRaw())
+		n, err := codec.Encode(req, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = client.WriteMsg(b[:n]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing writes the responses out yet, so reading stops at the high water mark
+	time.Sleep(100 * time.Millisecond)
+	if got := atomic.LoadInt32(&handled); got != 4 {
+		t.Fatalf("want 4 requests handled before draining, got %d", got)
+	}
+
+	go loop.LoopWrite()
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, MaxMsgSize)
+	for i := 0; i < reqNum; i++ {
+		n, err := client.ReadMsg(buf)
+		if err != nil {
+			t.Fatalf("read response %d: %v", i, err)
+		}
+		resp, err := codec.Decode(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetOpaque() != uint64(i) {
+			t.Fatalf("want opaque %d, got %d", i, resp.GetOpaque())
+		}
+	}
+
+	if got := atomic.LoadInt32(&handled); got != reqNum {
+		t.Fatalf("want %d requests handled, got %d", reqNum, got)
+	}
+}
+
+func TestReadWriteLoop_ExitGraceful(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	const msgNum = 100
+
+	newLoop := func() (*ReadWriteLoop, *Conn) {
+		clientConn, serverConn := tcpPair(t)
+		loop := NewReadWriteLoop(NewConn(serverConn), RoleServer, func(ctx context.Context, in Message, inBytes []byte) Message {
+			return EmptyMsg
+		})
+		for i := 0; i < msgNum; i++ {
+			if err := loop.Write(Message{Opaque: uint64(i), Message: &pb.GeneralResponse{Status: pb.StatusCode_Succeed}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return loop, NewConn(clientConn)
+	}
+
+	// the queued messages are all written out before the conn is closed
+	loop, client := newLoop()
+	defer client.Close()
+	go loop.LoopWrite()
+
+	if err := loop.ExitGraceful(5 * time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := loop.Write(Message{Message: &pb.GeneralResponse{}}); err == nil {
+		t.Fatalf("expected write to fail after exit")
+	}
+
+	codec := MsgCodec{}
+	buf := make([]byte, MaxMsgSize)
+	for i := 0; i < msgNum; i++ {
+		n, err := client.ReadMsg(buf)
+		if err != nil {
+			t.Fatalf("read message %d: %v", i, err)
+		}
+		msg, err := codec.Decode(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.GetOpaque() != uint64(i) {
+			t.Fatalf("want opaque %d, got %d", i, msg.GetOpaque())
+		}
+	}
+
+	// nothing writes the queue out, so draining exceeds the deadline
+	loop, client = newLoop()
+	defer client.Close()
+
+	begin := time.Now()
+	if err := loop.ExitGraceful(50 * time.Millisecond); err == nil {
+		t.Fatalf("expected drain timeout error")
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Fatalf("exit took %v", elapsed)
+	}
+	if loop.IsRunning() {
+		t.Fatalf("expected loop to exit after the deadline")
+	}
+}
+
+func TestReadWriteLoop_KeepAlive(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	interval, timeout := vars.Cfg.KeepAliveInterval, vars.Cfg.KeepAliveTimeout
+	vars.Cfg.KeepAliveInterval, vars.Cfg.KeepAliveTimeout = toml.Duration(10*time.Millisecond), toml.Duration(30*time.Millisecond)
+	defer func() {
+		vars.Cfg.KeepAliveInterval, vars.Cfg.KeepAliveTimeout = interval, timeout
+	}()
+
+	handle := func(ctx context.Context, in Message, inBytes []byte) Message {
+		return EmptyMsg
+	}
+	start := func(loop *ReadWriteLoop) {
+		go loop.LoopRead()
+		go loop.LoopWrite()
+	}
+
+	// both peers answer pings
+	clientConn, serverConn := tcpPair(t)
+	client, server := NewReadWriteLoop(NewConn(clientConn), RoleClient, handle), NewReadWriteLoop(NewConn(serverConn), RoleServer, handle)
+	start(client)
+	start(server)
+	defer client.Exit()
+	defer server.Exit()
+
+	time.Sleep(200 * time.Millisecond)
+	if !client.IsRunning() || !server.IsRunning() {
+		t.Fatalf("expected loops with live peers to keep running")
+	}
+
+	// the peer never reads, so no pong arrives
+	clientConn, serverConn = tcpPair(t)
+	defer serverConn.Close()
+	client = NewReadWriteLoop(NewConn(clientConn), RoleClient, handle)
+	start(client)
+
+	time.Sleep(200 * time.Millisecond)
+	if client.IsRunning() {
+		t.Fatalf("expected loop with a dead peer to exit")
+	}
+}
+
+func TestReadWriteLoop_KeepAliveBusy(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	interval, timeout := vars.Cfg.KeepAliveInterval, vars.Cfg.KeepAliveTimeout
+	vars.Cfg.KeepAliveInterval, vars.Cfg.KeepAliveTimeout = toml.Duration(10*time.Millisecond), toml.Duration(30*time.Millisecond)
+	defer func() {
+		vars.Cfg.KeepAliveInterval, vars.Cfg.KeepAliveTimeout = interval, timeout
+	}()
+
+	// the server handles a request longer than the ping timeout, the pongs of the client wait to be read meanwhile
+	handled := make(chan struct{})
+	clientConn, serverConn := tcpPair(t)
+	client := NewReadWriteLoop(NewConn(clientConn), RoleClient, func(ctx context.Context, in Message, inBytes []byte) Message {
+		return EmptyMsg
+	})
+	server := NewReadWriteLoop(NewConn(serverConn), RoleServer, func(ctx context.Context, in Message, inBytes []byte) Message {
+		time.Sleep(200 * time.Millisecond)
+		close(handled)
+		return EmptyMsg
+	})
+	client.pingInterval = 0 // the server is pinged, it can't answer while it's busy
+	// the loops are waited for to stop reading, the next tests change the config they read
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, loop := range []*ReadWriteLoop{client, server} {
+		wg.Add(1)
+		go func(loop *ReadWriteLoop) {
+			defer wg.Done()
+			loop.LoopRead()
+		}(loop)
+		go loop.LoopWrite()
+		defer loop.Exit()
+	}
+
+	if err := client.Write(Message{Opaque: 1, Message: &backendpb.LabelValuesRequest{Name: "instance"}}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("request not handled")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if !server.IsRunning() || !client.IsRunning() {
+		t.Fatalf("expected a loop busy with a request to keep running")
+	}
+}
+
+func TestReadWriteLoop_MsgTooLarge(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	maxMsgSize := vars.Cfg.MaxMsgSize
+	vars.Cfg.MaxMsgSize = 1024
+	defer func() {
+		vars.Cfg.MaxMsgSize = maxMsgSize
+	}()
+
+	clientConn, serverConn := tcpPair(t)
+	client := NewConn(clientConn)
+	defer client.Close()
+
+	var handled int32
+	loop := NewReadWriteLoop(NewConn(serverConn), RoleServer, func(ctx context.Context, in Message, inBytes []byte) Message {
+		atomic.AddInt32(&handled, 1)
+		return EmptyMsg
+	})
+	defer loop.Exit()
+
+	exited := make(chan struct{})
+	go func() {
+		loop.LoopRead()
+		close(exited)
+	}()
+
+	// only the length prefix is sent, the body of an oversized message must not be waited for
+	if _, err := clientConn.Write([]byte{0, 0, 0x10, 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("read loop didn't exit on an oversized message")
+	}
+	if loop.IsRunning() {
+		t.Fatalf("expected loop to exit")
+	}
+	if got := atomic.LoadInt32(&handled); got != 0 {
+		t.Fatalf("want nothing handled, got %d", got)
+	}
+
+	tests := []struct {
+		limit   toml.Size
+		bufSize int
+		msgSize int
+		want    *ErrMsgTooLarge
+	}{
+		{limit: 1024, bufSize: MaxMsgSize, msgSize: 2048, want: &ErrMsgTooLarge{Size: 2048, Limit: 1024}},
+		{limit: 0, bufSize: 512, msgSize: 2048, want: &ErrMsgTooLarge{Size: 2048, Limit: 512}},
+		{limit: 4096, bufSize: 4096, msgSize: 2048, want: nil},
+	}
+
+	for i, test := range tests {
+		vars.Cfg.MaxMsgSize = test.limit
+
+		clientConn, serverConn := tcpPair(t)
+		client, server := NewConn(clientConn), NewConn(serverConn)
+
+		if err := client.WriteMsg(make([]byte, test.msgSize)); err != nil {
+			t.Fatal(err)
+		}
+		client.Flush()
+
+		n, err := server.ReadMsg(make([]byte, test.bufSize))
+		if test.want == nil {
+			if err != nil || n != test.msgSize {
+				t.Fatalf("case %d: want %d bytes read, got %d, err %v", i, test.msgSize, n, err)
+			}
+		} else if tooLarge, ok := err.(*ErrMsgTooLarge); !ok || *tooLarge != *test.want {
+			t.Fatalf("case %d: want %v, got %v", i, test.want, err)
+		}
+
+		client.Close()
+		server.Close()
+	}
+}
+
+func BenchmarkReadWriteLoop_LoopRead(b *testing.B) {
+	vars.Logger = log.NewNopLogger()
+
+	clientConn, serverConn := tcpPair(b)
+	client := NewConn(clientConn)
+	defer client.Close()
+
+	handled := make(chan struct{}, 1)
+	loop := NewReadWriteLoop(NewConn(serverConn), RoleServer, func(ctx context.Context, in Message, inBytes []byte) Message {
+		handled <- struct{}{}
+		return EmptyMsg
+	})
+	defer loop.Exit()
+	go loop.LoopRead()
+
+	req := &backendpb.AddRequest{}
+	for i := 0; i < 100; i++ {
+		req.Series = append(req.Series, &pb.Series{
+			Labels: []pb.Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: "10.0.0.1:" + strconv.Itoa(i)}},
+			Points: []pb.Point{{T: int64(i), V: 1}},
+		})
+	}
+
+	codec := MsgCodec{}
+	msg := Message{Message: req}
+	buf := make([]byte, 2+binary.MaxVarintLen64+msg.SizeOfRaw())
+	n, err := codec.Encode(msg, buf)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(n))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err = client.WriteMsg(buf[:n]); err != nil {
+			b.Fatal(err)
+		}
+		if err = client.Flush(); err != nil {
+			b.Fatal(err)
+		}
+		<-handled
+	}
+}
+
+// countingWriter counts the writes to the connection, each of which is a syscall.
+type countingWriter struct {
+	*net.TCPConn
+	writes int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	atomic.AddInt64(&w.writes, 1)
+	return w.TCPConn.Write(p)
+}
+
+// writerPair returns a loop writing to a connection whose writes are counted and a loop reading
+// from the other end of it, the opaque of each message read is sent to the returned channel.
+func writerPair(t testing.TB) (*ReadWriteLoop, *countingWriter, *ReadWriteLoop, chan uint64) {
+	clientConn, serverConn := tcpPair(t)
+
+	cw := &countingWriter{TCPConn: clientConn}
+	client := NewConn(clientConn)
+	client.writer = bufio.NewWriterSize(cw, 1e4)
+	writer := NewReadWriteLoop(client, RoleClient, func(ctx context.Context, in Message, inBytes []byte) Message {
+		return EmptyMsg
+	})
+
+	read := make(chan uint64, 1000)
+	reader := NewReadWriteLoop(NewConn(serverConn), RoleServer, func(ctx context.Context, in Message, inBytes []byte) Message {
+		read <- in.GetOpaque()
+		return EmptyMsg
+	})
+
+	go writer.LoopWrite()
+	go reader.LoopRead()
+	return writer, cw, reader, read
+}
+
+func TestReadWriteLoop_WriteBatch(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	writer, cw, reader, read := writerPair(t)
+	defer writer.Exit()
+	defer reader.Exit()
+
+	const msgNum = 100
+
+	msgs := make([]Message, msgNum)
+	for i := range msgs {
+		msgs[i] = Message{Opaque: uint64(i + 1), Message: &pb.GeneralResponse{Status: pb.StatusCode_Succeed, Message: strconv.Itoa(i)}}
+	}
+	if err := writer.WriteBatch(msgs); err != nil {
+		t.Fatal(err)
+	}
+
+	// the frames arrive back to back, the reader must split them as if they were written one by one
+	for i := 1; i <= msgNum; i++ {
+		select {
+		case opaque := <-read:
+			if opaque != uint64(i) {
+				t.Fatalf("want message %d, got %d", i, opaque)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d messages read", i-1, msgNum)
+		}
+	}
+
+	if writes := atomic.LoadInt64(&cw.writes); writes != 1 {
+		t.Fatalf("want the batch written at once, got %d writes", writes)
+	}
+
+	if err := writer.WriteBatch(nil); err != nil {
+		t.Fatalf("unexpected error of an empty batch: %v", err)
+	}
+}
+
+func BenchmarkReadWriteLoop_WriteBatch(b *testing.B) {
+	vars.Logger = log.NewNopLogger()
+
+	const msgNum = 1000
+
+	msgs := make([]Message, msgNum)
+	for i := range msgs {
+		msgs[i] = Message{Opaque: uint64(i + 1), Message: &pb.GeneralResponse{Status: pb.StatusCode_Succeed, Message: "10.0.0.1:" + strconv.Itoa(i)}}
+	}
+
+	for _, batch := range []bool{false, true} {
+		name := "one_by_one"
+		if batch {
+			name = "batch"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			writer, cw, reader, read := writerPair(b)
+			defer writer.Exit()
+			defer reader.Exit()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if batch {
+					if err := writer.WriteBatch(msgs); err != nil {
+						b.Fatal(err)
+					}
+				} else {
+					for _, msg := range msgs {
+						if err := writer.Write(msg); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+				for j := 0; j < msgNum; j++ {
+					<-read
+				}
+			}
+
+			b.ReportMetric(float64(atomic.LoadInt64(&cw.writes))/float64(b.N), "syscalls/op")
+		})
+	}
+}
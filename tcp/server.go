@@ -142,8 +142,22 @@ func (s *TcpServer) Shutdown() {
 	if atomic.CompareAndSwapUint32(&s.running, 1, 0) {
 		s.tcpListener.Close()
 		s.mtx.Lock()
-		for loop := range s.loops {
-			loop.Exit()
+		if timeout := time.Duration(Cfg.ShutdownTimeout); timeout > 0 {
+			var wg sync.WaitGroup
+			for loop := range s.loops {
+				wg.Add(1)
+				go func(loop *ReadWriteLoop) {
+					if err := loop.ExitGraceful(timeout); err != nil {
+						level.Warn(loop.logger).Log("msg", "failed to drain conn", "err", err)
+					}
+					wg.Done()
+				}(loop)
+			}
+			wg.Wait()
+		} else {
+			for loop := range s.loops {
+				loop.Exit()
+			}
 		}
 		s.loops = nil
 		s.mtx.Unlock()
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"sync"
+
+	. "github.com/baudtime/baudtime/vars"
+	"github.com/pkg/errors"
+)
+
+var (
+	tlsOnce         sync.Once
+	tlsServerConfig *tls.Config
+	tlsClientConfig *tls.Config
+	tlsErr          error
+)
+
+// loadTLSConfig builds the server and client side tls configs from Cfg.TLS once.
+func loadTLSConfig() (*tls.Config, *tls.Config, error) {
+	tlsOnce.Do(func() {
+		tlsServerConfig, tlsClientConfig, tlsErr = NewTLSConfig(Cfg.TLS)
+	})
+	return tlsServerConfig, tlsClientConfig, tlsErr
+}
+
+// NewTLSConfig returns the server and client side tls configs. Both sides present the
+// certificate of cfg and trust the certificates signed by cfg.CAFile, the server
+// requires client certificates only if cfg.ClientAuth is set.
+func NewTLSConfig(cfg *TLSConfig) (server *tls.Config, client *tls.Config, err error) {
+	if cfg == nil {
+		return nil, nil, errors.New("tls is not configured")
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "load tls key pair")
+	}
+
+	var pool *x509.CertPool
+	if cfg.CAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "read tls ca file")
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, errors.Errorf("no certificate found in %s", cfg.CAFile)
+		}
+	}
+
+	server = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+	}
+	if cfg.ClientAuth {
+		server.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	client = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   cfg.ServerName,
+	}
+
+	return server, client, nil
+}
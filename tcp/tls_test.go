@@ -0,0 +1,172 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	backendpb "github.com/baudtime/baudtime/msg/pb/backend"
+	"github.com/baudtime/baudtime/vars"
+	"github.com/go-kit/kit/log"
+)
+
+// writeSelfSignedCert writes a self-signed certificate for 127.0.0.1 and its key into dir,
+// the certificate is also used as the ca.
+func writeSelfSignedCert(t *testing.T, dir string) *vars.TLSConfig {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "baudtime"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &vars.TLSConfig{
+		CertFile:   filepath.Join(dir, "cert.pem"),
+		KeyFile:    filepath.Join(dir, "key.pem"),
+		CAFile:     filepath.Join(dir, "cert.pem"),
+		ClientAuth: true,
+	}
+	if err = ioutil.WriteFile(cfg.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(cfg.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestTLS_RoundTrip(t *testing.T) {
+	vars.Logger = log.NewNopLogger()
+
+	dir, err := ioutil.TempDir("", "baudtime-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	serverConfig, clientConfig, err := NewTLSConfig(writeSelfSignedCert(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			tcpConn, err := ln.AcceptTCP()
+			if err != nil {
+				return
+			}
+
+			loop := NewReadWriteLoop(NewTLSConn(tcpConn, serverConfig, true), RoleServer, func(ctx context.Context, in Message, inBytes []byte) Message {
+				req := in.GetRaw().(*backendpb.LabelValuesRequest)
+				return Message{Opaque: in.GetOpaque(), Message: &pb.GeneralResponse{Status: pb.StatusCode_Succeed, Message: req.Name}}
+			})
+			go loop.LoopWrite()
+			go loop.LoopRead()
+		}
+	}()
+
+	dial := func(config *tls.Config) (*pb.GeneralResponse, error) {
+		tcpConn, err := net.DialTCP("tcp4", nil, ln.Addr().(*net.TCPAddr))
+		if err != nil {
+			return nil, err
+		}
+		conn := NewTLSConn(tcpConn, config, false)
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		codec := MsgCodec{}
+		req := Message{Opaque: 7, Message: &backendpb.LabelValuesRequest{Name: "over tls"}}
+		b := make([]byte, 2+binary.MaxVarintLen64+req.SizeOfRaw())
+		n, err := codec.Encode(req, b)
+		if err != nil {
+			return nil, err
+		}
+		if err = conn.WriteMsg(b[:n]); err != nil {
+			return nil, err
+		}
+		if err = conn.Flush(); err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, MaxMsgSize)
+		if n, err = conn.ReadMsg(buf); err != nil {
+			return nil, err
+		}
+		resp, err := codec.Decode(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		if resp.GetOpaque() != req.GetOpaque() {
+			t.Fatalf("opaque: want %d, got %d", req.GetOpaque(), resp.GetOpaque())
+		}
+		return resp.GetRaw().(*pb.GeneralResponse), nil
+	}
+
+	resp, err := dial(clientConfig)
+	if err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+	if resp.Message != "over tls" {
+		t.Fatalf("unexpected response %q", resp.Message)
+	}
+
+	noCert := clientConfig.Clone()
+	noCert.Certificates = nil
+	if _, err = dial(noCert); err == nil {
+		t.Fatalf("expected the server to reject a client without certificate")
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// MatchersFromMap returns equality matchers of the label names and values in m, sorted by name. A series selector,
+// e.g. `up{job="node"}`, is parsed into matchers by promql.ParseMetricSelector.
+func MatchersFromMap(m map[string]string) ([]*labels.Matcher, error) {
+	if len(m) == 0 {
+		return nil, errors.New("no label to match")
+	}
+
+	ms := make([]*labels.Matcher, 0, len(m))
+	for name, value := range m {
+		if !isLabelName(name) {
+			return nil, errors.Errorf("invalid label name %q", name)
+		}
+		matcher, err := labels.NewMatcher(labels.MatchEqual, name, value)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, matcher)
+	}
+
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].Name < ms[j].Name
+	})
+	return ms, nil
+}
+
+// NormalizeMatchers drops the duplicate matchers and the ones implied by an equality matcher of the same label,
+// e.g. job=~"a|b" by job="a". It returns false if no series can match them all, e.g. job="a" and job="b", so that
+// such a query needn't be sent to any storage. ms is left untouched.
+func NormalizeMatchers(ms []*labels.Matcher) ([]*labels.Matcher, bool) {
+	equals := make(map[string]string, len(ms))
+	for _, m := range ms {
+		if m.Type != labels.MatchEqual {
+			continue
+		}
+		if v, found := equals[m.Name]; found && v != m.Value {
+			return nil, false
+		}
+		equals[m.Name] = m.Value
+	}
+
+	normalized := make([]*labels.Matcher, 0, len(ms))
+	for i, m := range ms {
+		if v, found := equals[m.Name]; found && m.Type != labels.MatchEqual {
+			if !m.Matches(v) {
+				return nil, false
+			}
+			continue
+		}
+		if duplicated(m, ms[:i]) {
+			continue
+		}
+		normalized = append(normalized, m)
+	}
+	return normalized, true
+}
+
+func duplicated(m *labels.Matcher, ms []*labels.Matcher) bool {
+	for _, other := range ms {
+		if other.Type == m.Type && other.Name == m.Name && other.Value == m.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// RegexLiterals returns the values a regex matches if it's a plain alternation of literals, optionally grouped,
+// e.g. `a|b`, `(a|b\.c)` or `(?:a|b)` as the templated dashboards produce, which match a and b, or a and b.c,
+// as the matchers anchor them. Only the ASCII punctuations may be escaped. ok is false if the regex may match
+// other values or the empty one, e.g. `a.*`, `a|` or `(?i)a`.
+func RegexLiterals(re string) (values []string, ok bool) {
+	switch {
+	case strings.HasPrefix(re, "(?:") && strings.HasSuffix(re, ")"):
+		re = re[3 : len(re)-1]
+	case strings.HasPrefix(re, "(") && strings.HasSuffix(re, ")"):
+		re = re[1 : len(re)-1]
+	}
+
+	var literal strings.Builder
+	add := func() bool {
+		if literal.Len() == 0 {
+			return false
+		}
+		value := literal.String()
+		literal.Reset()
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		values = append(values, value)
+		return true
+	}
+
+	for i := 0; i < len(re); i++ {
+		switch c := re[i]; c {
+		case '|':
+			if !add() {
+				return nil, false
+			}
+		case '\\':
+			if i+1 == len(re) || !isPunct(re[i+1]) {
+				return nil, false
+			}
+			i++
+			literal.WriteByte(re[i])
+		case '.', '+', '*', '?', '(', ')', '[', ']', '{', '}', '^', '$':
+			return nil, false
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	if !add() {
+		return nil, false
+	}
+	return values, true
+}
+
+// isPunct tells whether c escaped stands for itself, as regexp/syntax takes any ASCII but a letter or digit.
+func isPunct(c byte) bool {
+	return c < utf8.RuneSelf && !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z')
+}
+
+func isLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+func TestMatchersFromMap(t *testing.T) {
+	ms, err := MatchersFromMap(map[string]string{"job": "node", "__name__": "up", "env": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{`__name__="up"`, `env=""`, `job="node"`}
+	if len(ms) != len(want) {
+		t.Fatalf("want %d matchers, got %d", len(want), len(ms))
+	}
+	for i, m := range ms {
+		if m.Type != labels.MatchEqual || m.String() != want[i] {
+			t.Fatalf("want %s, got %s", want[i], m)
+		}
+	}
+
+	if _, err = MatchersFromMap(map[string]string{"job-name": "node"}); err == nil {
+		t.Fatalf("expected error for invalid label name")
+	}
+	if _, err = MatchersFromMap(nil); err == nil {
+		t.Fatalf("expected error for no label")
+	}
+}
+
+func TestNormalizeMatchers(t *testing.T) {
+	m := func(matchType labels.MatchType, name, value string) *labels.Matcher {
+		matcher, err := labels.NewMatcher(matchType, name, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return matcher
+	}
+	up := m(labels.MatchEqual, labels.MetricName, "up")
+
+	tests := []struct {
+		matchers []*labels.Matcher
+		want     string // matchers joined by ',', empty if unsatisfiable
+	}{
+		{[]*labels.Matcher{up, m(labels.MatchEqual, "job", "node")}, `__name__="up",job="node"`},
+		{[]*labels.Matcher{up, m(labels.MatchEqual, "job", "node"), m(labels.MatchEqual, "job", "node"), m(labels.MatchNotEqual, "env", "dev"), m(labels.MatchNotEqual, "env", "dev")}, `__name__="up",job="node",env!="dev"`},
+		{[]*labels.Matcher{up, m(labels.MatchRegexp, "job", "node|mysql"), m(labels.MatchEqual, "job", "node"), m(labels.MatchNotEqual, "job", "mysql")}, `__name__="up",job="node"`},
+		{[]*labels.Matcher{up, m(labels.MatchRegexp, "job", "node|mysql"), m(labels.MatchNotEqual, "env", "dev")}, `__name__="up",job=~"node|mysql",env!="dev"`},
+		{[]*labels.Matcher{up, m(labels.MatchEqual, "job", "node"), m(labels.MatchEqual, "job", "mysql")}, ``},
+		{[]*labels.Matcher{up, m(labels.MatchEqual, labels.MetricName, "down")}, ``},
+		{[]*labels.Matcher{up, m(labels.MatchEqual, "job", "node"), m(labels.MatchNotEqual, "job", "node")}, ``},
+		{[]*labels.Matcher{up, m(labels.MatchEqual, "job", "node"), m(labels.MatchRegexp, "job", "mysql.*")}, ``},
+		{[]*labels.Matcher{up, m(labels.MatchEqual, "job", ""), m(labels.MatchRegexp, "job", ".+")}, ``},
+	}
+
+	for _, test := range tests {
+		normalized, satisfiable := NormalizeMatchers(test.matchers)
+		if satisfiable != (test.want != "") {
+			t.Fatalf("%v: want satisfiable %v, got %v", test.matchers, test.want != "", satisfiable)
+		}
+
+		got := make([]string, 0, len(normalized))
+		for _, m := range normalized {
+			got = append(got, m.String())
+		}
+		if strings.Join(got, ",") != test.want {
+			t.Fatalf("%v: want %s, got %s", test.matchers, test.want, strings.Join(got, ","))
+		}
+	}
+}
+
+func TestRegexLiterals(t *testing.T) {
+	tests := []struct {
+		re   string
+		want []string // nil if it's a true regex
+	}{
+		{`node`, []string{"node"}},
+		{`node|mysql|redis`, []string{"node", "mysql", "redis"}},
+		{`(node|mysql)`, []string{"node", "mysql"}},
+		{`(?:node|mysql)`, []string{"node", "mysql"}},
+		{`node|node`, []string{"node"}},
+		{`10\.0\.0\.1:9100|a\|b`, []string{"10.0.0.1:9100", "a|b"}},
+		{`集群-1|集群-2`, []string{"集群-1", "集群-2"}},
+		{`a\-b|c`, []string{"a-b", "c"}},
+		{``, nil},
+		{`node|`, nil},
+		{`|node`, nil},
+		{`node.*`, nil},
+		{`node|mysql.+`, nil},
+		{`nod[ea]`, nil},
+		{`(?i)node`, nil},
+		{`(node)|(mysql)`, nil},
+		{`^node$`, nil},
+		{`node\d`, nil},
+		{`node{2}`, nil},
+		{`node?`, nil},
+	}
+
+	for _, test := range tests {
+		got, ok := RegexLiterals(test.re)
+		if ok != (test.want != nil) {
+			t.Fatalf("%s: want literals %v, got %v", test.re, test.want != nil, ok)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("%s: want %q, got %q", test.re, test.want, got)
+		}
+
+		// the literals are exactly what the matcher matches
+		m, err := labels.NewMatcher(labels.MatchRegexp, "job", test.re)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.re, err)
+		}
+		for _, v := range got {
+			if !m.Matches(v) {
+				t.Fatalf("%s: %q not matched", test.re, v)
+			}
+		}
+		for _, v := range []string{"", "nodes", "Node", "node|mysql", `10\.0\.0\.1:9100`} {
+			if m.Matches(v) && !contains(got, v) && ok {
+				t.Fatalf("%s: %q matched but not among the literals %q", test.re, v, got)
+			}
+		}
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -62,3 +62,20 @@ func Retry(interval time.Duration, count int, f func() (bool, error)) error {
 	}
 	return err
 }
+
+// RetryWithBackoff calls f at most count times, doubling the sleep interval after each failed attempt.
+func RetryWithBackoff(base time.Duration, count int, f func() (bool, error)) error {
+	var retry = true
+	var err error
+	interval := base
+	for i := 0; i < count; i++ {
+		if retry, err = f(); !retry {
+			return err
+		}
+		if i < count-1 {
+			time.Sleep(interval)
+			interval *= 2
+		}
+	}
+	return err
+}
@@ -18,7 +18,11 @@ package util
 import (
 	"github.com/baudtime/baudtime/msg/pb"
 	"github.com/cespare/xxhash"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
 	"net"
+	"sort"
+	"sync"
 	"unsafe"
 )
 
@@ -54,6 +58,13 @@ func Max(a, b int64) int64 {
 	return b
 }
 
+func Min(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 type hasher struct {
 	buf []byte
 }
@@ -74,3 +85,48 @@ func (h *hasher) Hash(ls []pb.Label) uint64 {
 
 	return v
 }
+
+var hasherPool = sync.Pool{
+	New: func() interface{} {
+		return NewHasher()
+	},
+}
+
+// HashLabels returns the hash of a series, which picks its shard out of the shard group of the
+// metric unless the metric has a route key. It's the xxhash64 of the names and values of ls, each
+// followed by a 0xff byte, in the given order, so ls must be sorted by name (see SortLabels) first.
+// It's the same as the Hash of labels.Labels, every writer must hash series by it or HashPromLabels.
+func HashLabels(ls []pb.Label) uint64 {
+	h := hasherPool.Get().(*hasher)
+	v := h.Hash(ls)
+	hasherPool.Put(h)
+	return v
+}
+
+// HashPromLabels is HashLabels of labels.Labels, which are always sorted by name.
+func HashPromLabels(ls labels.Labels) uint64 {
+	return ls.Hash()
+}
+
+type labelsByName []pb.Label
+
+func (ls labelsByName) Len() int           { return len(ls) }
+func (ls labelsByName) Swap(i, j int)      { ls[i], ls[j] = ls[j], ls[i] }
+func (ls labelsByName) Less(i, j int) bool { return ls[i].Name < ls[j].Name }
+
+// SortLabels sorts ls by name in place, which is the canonical form series are hashed
+// and indexed by, it reports whether ls was reordered and fails on duplicated names.
+func SortLabels(ls []pb.Label) (reordered bool, err error) {
+	if !sort.IsSorted(labelsByName(ls)) {
+		sort.Sort(labelsByName(ls))
+		reordered = true
+	}
+
+	for i := 1; i < len(ls); i++ {
+		if ls[i].Name == ls[i-1].Name {
+			return reordered, errors.Errorf("duplicate label name %q", ls[i].Name)
+		}
+	}
+
+	return reordered, nil
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2019 The Baudtime Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"testing"
+
+	"github.com/baudtime/baudtime/msg/pb"
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+func TestHashLabels(t *testing.T) {
+	tests := [][]pb.Label{
+		nil,
+		{{Name: "__name__", Value: "up"}},
+		{{Name: "job", Value: "node"}, {Name: "__name__", Value: "up"}, {Name: "instance", Value: "10.0.0.1:9100"}},
+		{{Name: "__name__", Value: "http_requests_total"}, {Name: "path", Value: "/api/v1/query"}, {Name: "code", Value: ""}},
+		{{Name: "a", Value: "b\xffc"}, {Name: "a\xffb", Value: "c"}},
+	}
+
+	for _, ls := range tests {
+		if _, err := SortLabels(ls); err != nil {
+			t.Fatal(err)
+		}
+
+		promLabels := make(labels.Labels, 0, len(ls))
+		for _, l := range ls {
+			promLabels = append(promLabels, labels.Label{Name: l.Name, Value: l.Value})
+		}
+
+		want := promLabels.Hash()
+		if got := HashLabels(ls); got != want {
+			t.Fatalf("labels %v: want %d as labels.Labels.Hash, got %d", ls, want, got)
+		}
+		if got := HashPromLabels(promLabels); got != want {
+			t.Fatalf("labels %v: want %d of HashPromLabels, got %d", ls, want, got)
+		}
+		if got := NewHasher().Hash(ls); got != want {
+			t.Fatalf("labels %v: want %d of the hasher, got %d", ls, want, got)
+		}
+	}
+}
@@ -15,13 +15,40 @@ type EtcdCommonConfig struct {
 }
 
 type RouteConfig struct {
-	RouteInfoTTL  toml.Duration `toml:"route_info_ttl"`
-	ShardGroupCap int           `toml:"shard_group_cap"`
+	RouteInfoTTL   toml.Duration `toml:"route_info_ttl"`
+	ShardGroupCap  int           `toml:"shard_group_cap"`
+	NegativeTTL    toml.Duration `toml:"negative_ttl,omitempty"`    // How long a failure to init a route is cached, e.g. a few seconds. 0 disables it.
+	Mode           string        `toml:"mode,omitempty"`            // modulo (default) or rendezvous, the latter moves fewer series when shards are added.
+	Weighted       bool          `toml:"weighted,omitempty"`        // Pick the shard groups of new metrics by the weight and the free disk of the shards, spread over the hosts.
+	PartitionLabel string        `toml:"partition_label,omitempty"` // e.g. tenant, the series having it are routed by its value instead of the metric name, so that the ones of a tenant stay on a shard group. Only for a new cluster, the series written before it's set aren't found by the queries scoped by the label.
+}
+
+type FailoverConfig struct {
+	GracePeriod  toml.Duration `toml:"grace_period,omitempty"`  // How long a node must stay down before its shard fails over, 0 fails over at once.
+	ProbeTimeout toml.Duration `toml:"probe_timeout,omitempty"` // Timeout of probing whether a node is alive with an info command.
+}
+
+type LabelLimitConfig struct {
+	MaxLabelNum    int      `toml:"max_label_num,omitempty"`    // Max labels of a series, including the metric name, 0 disables it.
+	MaxNameLength  int      `toml:"max_name_length,omitempty"`  // Max length in bytes of a label name, 0 disables it.
+	MaxValueLength int      `toml:"max_value_length,omitempty"` // Max length in bytes of a label value, 0 disables it.
+	AllowedNames   []string `toml:"allowed_names,omitempty"`    // Only these label names and the metric name are accepted if set.
+	DeniedNames    []string `toml:"denied_names,omitempty"`     // Label names rejected.
 }
 
 type AppenderConfig struct {
-	SampleNumBatchSend int           `toml:"sample_num_batch_send"`
-	MaxIntervalSend    toml.Duration `toml:"max_interval_send"`
+	SampleNumBatchSend int           `toml:"sample_num_batch_send"`        // Not used, kept for the configs having it.
+	MaxIntervalSend    toml.Duration `toml:"max_interval_send"`            // Not used, kept for the configs having it.
+	AutoFlushSamples   int           `toml:"auto_flush_samples,omitempty"` // Samples buffered for a shard before being sent automatically, 0 sends them only on flush.
+	AutoFlushAge       toml.Duration `toml:"auto_flush_age,omitempty"`     // How long a sample may be buffered for a shard before being sent automatically, 0 sends it only on flush.
+	RetryNum           int           `toml:"retry_num,omitempty"`          // Max attempts of flushing a batch to one shard.
+	RetryInterval      toml.Duration `toml:"retry_interval,omitempty"`     // Base backoff between attempts, doubled after each failure.
+	StrictOrder        bool          `toml:"strict_order,omitempty"`       // Reject a sample earlier than the last one added of its series until the next flush.
+	AckLevel           string        `toml:"ack_level,omitempty"`          // Wait for no replica ("async", the default), the master ("one") or the master and a majority of its slaves ("quorum") to apply a batch sent.
+	AckTimeout         toml.Duration `toml:"ack_timeout,omitempty"`        // How long a batch sent waits for its ack, defaults to 10s.
+	QueueSize          int           `toml:"queue_size,omitempty"`         // Batches of a shard failed retryably kept in memory to be sent before the next ones, 0 disables it unless spill_dir is set.
+	SpillDir           string        `toml:"spill_dir,omitempty"`          // Where the batches beyond queue_size are spilled, one dir per shard, replayed after a restart too. Empty drops them.
+	MaxSpillSize       toml.Size     `toml:"max_spill_size,omitempty"`     // Max bytes spilled of a shard, defaults to 1GB, batches beyond it are dropped.
 }
 
 type QueryEngineConfig struct {
@@ -29,17 +56,51 @@ type QueryEngineConfig struct {
 	Timeout     toml.Duration `toml:"timeout"`
 }
 
+type QueryConfig struct {
+	MaxConcurrency       int           `toml:"max_concurrency,omitempty"`        // Max number of shards queried at the same time by one fanout query, 0 means unlimited.
+	PerShardTimeout      toml.Duration `toml:"per_shard_timeout,omitempty"`      // Timeout of querying one shard, 0 means only the query timeout applies.
+	PartialResponse      bool          `toml:"partial_response,omitempty"`       // Return the data of the responded shards with a warning when some of them failed.
+	SlaveMaxLag          toml.Duration `toml:"slave_max_lag,omitempty"`          // Slaves lagging behind the master more than it are not read from, 0 means unlimited.
+	CompactPoints        bool          `toml:"compact_points,omitempty"`         // Ask the storages for points in the xor encoding, the ones not supporting it send them as usual.
+	AggregationPushdown  bool          `toml:"aggregation_pushdown,omitempty"`   // Let the shards sum up the series of sum (by) queries over selectors. All the storages must support it before enabling it.
+	BreakerThreshold     int           `toml:"breaker_threshold,omitempty"`      // Consecutive failures of a shard after which the queries to it fail fast for a cooldown, 0 disables it.
+	BreakerCooldown      toml.Duration `toml:"breaker_cooldown,omitempty"`       // How long the queries to a failing shard fail fast before one is let through as a probe, defaults to 10s.
+	CollapseStaleMarkers bool          `toml:"collapse_stale_markers,omitempty"` // Merge a run of staleness markers of a series into the first one.
+	MaxSelectCost        int64         `toml:"max_select_cost,omitempty"`        // Selects estimated to scan more series hours than it are refused before asking any shard, 0 means unlimited.
+	SnapshotRead         bool          `toml:"snapshot_read,omitempty"`          // Ask all the shards of a select for the data as of the same instant, pinned at the start of the query and no later than the latest data they reported, which may leave out the data since their last report.
+	MaxResponseBytes     toml.Size     `toml:"max_response_bytes,omitempty"`     // A query reading more bytes of series from the shards in all is aborted with a response too large error, 0 means unlimited.
+	MaxResponseSeries    int           `toml:"max_response_series,omitempty"`    // A query reading more series from the shards in all is aborted with a response too large error, 0 means unlimited.
+}
+
+type HealthConfig struct {
+	WatchStallTimeout toml.Duration `toml:"watch_stall_timeout,omitempty"` // The etcd watch not having handled an event or a tick for longer is stalled, defaults to 1m.
+	ProbeShards       int           `toml:"probe_shards,omitempty"`        // How many masters picked at random are probed by a health check, defaults to 3.
+}
+
 type RuleConfig struct {
 	EvalInterval toml.Duration `toml:"eval_interval"`
 	RuleFileDir  string        `toml:"rules_dir"`
 }
 
+type ScrapeConfig struct {
+	Interval toml.Duration `toml:"interval"`          // How often the targets are scraped.
+	Timeout  toml.Duration `toml:"timeout,omitempty"` // Timeout of scraping a target, defaults to the interval.
+	Job      string        `toml:"job,omitempty"`     // The job label of the series scraped not having one, defaults to "baudtime_scrape".
+	Targets  []string      `toml:"targets"`           // URLs of the Prometheus metrics endpoints, e.g. "http://127.0.0.1:9100/metrics".
+}
+
 type GatewayConfig struct {
-	ConnNumPerBackend int                `toml:"conn_num_per_backend"`
-	Route             RouteConfig        `toml:"route"`
-	Appender          *AppenderConfig    `toml:"appender,omitempty"`
-	QueryEngine       *QueryEngineConfig `toml:"query_engine,omitempty"`
-	Rule              *RuleConfig        `toml:"rule,omitempty"`
+	ConnNumPerBackend     int                `toml:"conn_num_per_backend"`                // Max connections open to a backend, the requests are multiplexed on them.
+	MaxIdleConnPerBackend int                `toml:"max_idle_conn_per_backend,omitempty"` // Connections to a backend having no request in flight over it are closed, 0 keeps them all.
+	Route                 RouteConfig        `toml:"route"`
+	Query                 QueryConfig        `toml:"query"`
+	Failover              FailoverConfig     `toml:"failover"`
+	Health                HealthConfig       `toml:"health"`
+	LabelLimit            LabelLimitConfig   `toml:"label_limit"`
+	Appender              *AppenderConfig    `toml:"appender,omitempty"`
+	QueryEngine           *QueryEngineConfig `toml:"query_engine,omitempty"`
+	Rule                  *RuleConfig        `toml:"rule,omitempty"`
+	Scrape                *ScrapeConfig      `toml:"scrape,omitempty"`
 }
 
 type TSDBConfig struct {
@@ -62,9 +123,33 @@ type ReplicationConfig struct {
 }
 
 type StorageConfig struct {
-	TSDB        TSDBConfig         `toml:"tsdb"`
-	StatReport  StatReportConfig   `toml:"stat_report"`
-	Replication *ReplicationConfig `toml:"replication"`
+	TSDB         TSDBConfig         `toml:"tsdb"`
+	StatReport   StatReportConfig   `toml:"stat_report"`
+	Replication  *ReplicationConfig `toml:"replication"`
+	DedupWindow  toml.Duration      `toml:"dedup_window,omitempty"`  // How long the last batch applied of each writer is remembered, so that a retried batch is not written twice. 0 disables it. Set it on the slaves too, they remember the batches fed by the master, but not the ones of the blocks synced when joining.
+	Weight       int                `toml:"weight,omitempty"`        // Capacity of the node relative to the others, see RouteConfig.Weighted. Defaults to 1.
+	MaxExemplars int                `toml:"max_exemplars,omitempty"` // Exemplars written with the samples kept in memory, the oldest are dropped first. 0 drops them all.
+}
+
+type TLSConfig struct {
+	CertFile   string `toml:"cert_file"`
+	KeyFile    string `toml:"key_file"`
+	CAFile     string `toml:"ca_file"`
+	ServerName string `toml:"server_name,omitempty"` // Name to verify the server certificate against, defaults to the dialed host.
+	ClientAuth bool   `toml:"client_auth,omitempty"` // Require and verify client certificates (mutual TLS).
+}
+
+// RateLimit is how fast a connection may send requests, a rate of 0 doesn't limit them. A connection may send
+// a burst of one second worth of either rate at once.
+type RateLimit struct {
+	RequestsPerSec int       `toml:"requests_per_sec,omitempty"`
+	BytesPerSec    toml.Size `toml:"bytes_per_sec,omitempty"`
+}
+
+type RateLimitConfig struct {
+	RateLimit
+	Reject  bool                 `toml:"reject,omitempty"`  // Answer a request over the limit with a failed response of code RateLimited rather than delay reading it.
+	Clients map[string]RateLimit `toml:"clients,omitempty"` // Limits of the connections from an ip, instead of the ones above.
 }
 
 type JaegerConfig struct {
@@ -75,14 +160,29 @@ type JaegerConfig struct {
 }
 
 type Config struct {
-	TcpPort    string           `toml:"tcp_port"`
-	HttpPort   string           `toml:"http_port"`
-	MaxConn    int              `toml:"max_conn"`
-	NameSpace  string           `toml:"namespace,omitempty"`
-	EtcdCommon EtcdCommonConfig `toml:"etcd_common"`
-	Gateway    *GatewayConfig   `toml:"gateway,omitempty"`
-	Storage    *StorageConfig   `toml:"storage,omitempty"`
-	Jaeger     *JaegerConfig    `toml:"jaeger,omitempty"`
+	TcpPort                string           `toml:"tcp_port"`
+	HttpPort               string           `toml:"http_port"`
+	MaxConn                int              `toml:"max_conn"`
+	NameSpace              string           `toml:"namespace,omitempty"`               // Prefixes all the meta keys in etcd, clusters sharing an etcd must have distinct ones, not prefixed by one another and '_'.
+	Compression            string           `toml:"compression,omitempty"`             // none, snappy, gzip or zstd
+	CompressionThreshold   toml.Size        `toml:"compression_threshold,omitempty"`   // Messages smaller than it are sent uncompressed, 0 disables compression.
+	CompressionLevel       int              `toml:"compression_level,omitempty"`       // Level of zstd from 1 (fastest) to 22 (smallest), 0 takes its default.
+	ReplicationCompression string           `toml:"replication_compression,omitempty"` // Codec of the replication streams, e.g. zstd to save bandwidth between idcs, defaults to compression.
+	OutQueueHighWater      int              `toml:"out_queue_high_water,omitempty"`    // A connection stops reading requests once this many responses are queued, 0 disables it.
+	OutQueueLowWater       int              `toml:"out_queue_low_water,omitempty"`     // Reading is resumed once the queued responses drain to it, defaults to half the high water.
+	KeepAliveInterval      toml.Duration    `toml:"keepalive_interval,omitempty"`      // How often a connection pings its peer, 0 disables it. All the nodes must support ping before enabling it.
+	KeepAliveTimeout       toml.Duration    `toml:"keepalive_timeout,omitempty"`       // A connection is closed if no pong arrives in it after a ping, defaults to the interval.
+	ShutdownTimeout        toml.Duration    `toml:"shutdown_timeout,omitempty"`        // How long the queued responses of each connection may take to be written out on shutdown, 0 closes connections at once.
+	MaxMsgSize             toml.Size        `toml:"max_msg_size,omitempty"`            // Connections framing a message larger than it are closed, defaults to 10MB.
+	StrictLabels           bool             `toml:"strict_labels,omitempty"`           // Fail to decode a series having a label name more than once, which closes the connection it's read from, rather than keep the last value of the name.
+	DialTimeout            toml.Duration    `toml:"dial_timeout,omitempty"`            // How long connecting to a node may take, defaults to 2s.
+	TLS                    *TLSConfig       `toml:"tls,omitempty"`
+	RateLimit              *RateLimitConfig `toml:"rate_limit,omitempty"` // Limits the gateway requests read by every connection of the server, nil doesn't. The requests of the gateways to the storages and the replication aren't limited.
+	MetaStore              string           `toml:"meta_store,omitempty"` // etcd (default) or memory, the latter keeps the meta data in the process for a single node running both the gateway and the storage.
+	EtcdCommon             EtcdCommonConfig `toml:"etcd_common"`
+	Gateway                *GatewayConfig   `toml:"gateway,omitempty"`
+	Storage                *StorageConfig   `toml:"storage,omitempty"`
+	Jaeger                 *JaegerConfig    `toml:"jaeger,omitempty"`
 }
 
 var Cfg = &Config{
@@ -91,6 +191,8 @@ var Cfg = &Config{
 	MaxConn:   10000,
 	NameSpace: "baudtime",
 
+	Compression: "none",
+
 	EtcdCommon: EtcdCommonConfig{
 		Endpoints:     []string{"localhost:2379"},
 		DialTimeout:   toml.Duration(5 * time.Second),
@@ -105,6 +207,5 @@ func LoadConfig(tomlFile string) error {
 	if err != nil {
 		return err
 	}
-
 	return nil
 }
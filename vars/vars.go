@@ -23,6 +23,8 @@ var (
 	LogWriter      io.Writer
 	LocalIP        string
 	PageSize       = os.Getpagesize()
+	Version        = "unknown" // set at build time by -ldflags "-X github.com/baudtime/baudtime/vars.Version=..."
+	StartTime      = time.Now()
 )
 
 func Init(appName string) {